@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/internal/ranking"
+	"backend/pkg/store"
+)
+
+// replay rebuilds leaderboard state from an event log and optionally verifies
+// it against a snapshot taken with GET /api/export?format=jsonl
+func main() {
+	logPath := flag.String("log", "", "Path to the event log (EVENT_LOG_PATH of the server)")
+	fromFlag := flag.String("from", "", "Only apply events at or after this RFC3339 timestamp")
+	toFlag := flag.String("to", "", "Only apply events at or before this RFC3339 timestamp")
+	snapshotPath := flag.String("snapshot", "", "Export (JSON Lines) to verify the rebuilt board against")
+	outPath := flag.String("out", "", "Write the rebuilt board as JSON Lines to this path")
+	flag.Parse()
+
+	if *logPath == "" {
+		log.Fatal("-log is required")
+	}
+
+	from, err := parseTime(*fromFlag)
+	if err != nil {
+		log.Fatalf("Invalid -from: %v", err)
+	}
+	to, err := parseTime(*toFlag)
+	if err != nil {
+		log.Fatalf("Invalid -to: %v", err)
+	}
+
+	rebuilt, applied, err := rebuild(*logPath, from, to)
+	if err != nil {
+		log.Fatalf("Replay failed: %v", err)
+	}
+
+	ctx := context.Background()
+	total, minRating, maxRating, avgRating, _ := rebuilt.GetStats(ctx, false)
+	bots, _ := rebuilt.GetBotCount(ctx)
+	log.Printf("✓ Applied %d events: %d users (%d bots), ratings %d-%d, average %.2f",
+		applied, total, bots, minRating, maxRating, avgRating)
+
+	if *outPath != "" {
+		if err := writeSnapshot(*outPath, rebuilt); err != nil {
+			log.Fatalf("Failed to write rebuilt board: %v", err)
+		}
+		log.Printf("✓ Wrote rebuilt board to %s", *outPath)
+	}
+
+	if *snapshotPath != "" {
+		mismatches, err := verify(*snapshotPath, rebuilt)
+		if err != nil {
+			log.Fatalf("Verification failed: %v", err)
+		}
+		if mismatches > 0 {
+			log.Printf("✗ %d mismatches against %s", mismatches, *snapshotPath)
+			os.Exit(1)
+		}
+		log.Printf("✓ Rebuilt board matches %s", *snapshotPath)
+	}
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// rebuild applies every event in [from, to] to a fresh store
+func rebuild(path string, from, to time.Time) (*store.MemoryStore, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	rebuilt := store.NewMemoryStore()
+	applied := 0
+
+	err = events.ReadLog(file, func(e events.Event) error {
+		if !from.IsZero() && e.Timestamp.Before(from) {
+			return nil
+		}
+		if !to.IsZero() && e.Timestamp.After(to) {
+			return nil
+		}
+
+		switch e.Type {
+		case events.TypeUserAdded:
+			if e.Bot {
+				rebuilt.AddBot(e.Username, e.Rating)
+			} else {
+				rebuilt.AddUser(e.Username, e.Rating)
+			}
+		case events.TypeScoreUpdated:
+			rebuilt.AddUser(e.Username, e.Rating)
+		case events.TypeUserEvicted, events.TypeUserExpired:
+			rebuilt.RemoveUser(e.Username)
+		default:
+			return nil
+		}
+
+		applied++
+		return nil
+	})
+
+	return rebuilt, applied, err
+}
+
+// verify compares the rebuilt board with a snapshot and returns the number of mismatches
+func verify(path string, rebuilt *store.MemoryStore) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	mismatches := 0
+	seen := 0
+	report := func(format string, args ...any) {
+		mismatches++
+		if mismatches <= 20 {
+			log.Printf("  "+format, args...)
+		}
+	}
+
+	dec := json.NewDecoder(file)
+	for dec.More() {
+		var entry models.LeaderboardEntry
+		if err := dec.Decode(&entry); err != nil {
+			return mismatches, err
+		}
+		seen++
+
+		user, err := rebuilt.GetUser(entry.Username)
+		if err != nil {
+			report("%s missing from rebuilt board", entry.Username)
+			continue
+		}
+		if user.Rating != entry.Rating {
+			report("%s rating %d, snapshot has %d", entry.Username, user.Rating, entry.Rating)
+		}
+	}
+
+	if count := rebuilt.GetUserCount(); count != seen {
+		report("rebuilt board has %d users, snapshot has %d", count, seen)
+	}
+
+	return mismatches, nil
+}
+
+// writeSnapshot writes the rebuilt board in the export format
+func writeSnapshot(path string, rebuilt *store.MemoryStore) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	enc := json.NewEncoder(file)
+	users, err := rebuilt.GetAllUsers(context.Background())
+	if err != nil {
+		return err
+	}
+	var ranks ranking.Counter
+	for _, user := range users {
+		if err := enc.Encode(models.LeaderboardEntry{
+			Rank:     ranks.Next(user.Key),
+			Username: user.Username,
+			Rating:   user.Rating,
+			Bot:      user.Bot,
+		}); err != nil {
+			return fmt.Errorf("failed to encode %s: %w", user.Username, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,573 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/pkg/blobstore"
+	"backend/pkg/leaderboard"
+	"backend/pkg/store"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// serverConfig holds the settings cmd/server uses itself rather than passing
+// to the leaderboard
+type serverConfig struct {
+	Port         string
+	Socket       string        // Serve on this unix socket instead of Port when set
+	SocketMode   fs.FileMode   // Permissions of the unix sockets
+	AdminAddr    string        // Serve the admin surface on this host:port (or unix:path) instead of Port when set
+	DrainTimeout time.Duration // How long shutdown waits for the drain
+}
+
+// publicAddr is the address the public API listens on
+func (c serverConfig) publicAddr() string {
+	if c.Socket != "" {
+		return unixPrefix + c.Socket
+	}
+	return ":" + c.Port
+}
+
+// configErrors collects invalid environment variables while options are
+// loaded, so they can all be reported at once
+var configErrors []error
+
+// invalid records an environment variable that couldn't be used
+func invalid(key string, err error) {
+	configErrors = append(configErrors, fmt.Errorf("invalid %s: %w", key, err))
+}
+
+// loadConfig reads the configuration from the environment and validates it,
+// returning every problem found
+func loadConfig() (serverConfig, leaderboard.Options, error) {
+	server := serverConfig{
+		Port:         os.Getenv("PORT"),
+		Socket:       os.Getenv("UNIX_SOCKET"),
+		SocketMode:   0o660,
+		AdminAddr:    os.Getenv("ADMIN_ADDR"),
+		DrainTimeout: envDuration("SHUTDOWN_DRAIN_TIMEOUT", 5*time.Second),
+	}
+	if server.Port == "" {
+		server.Port = "8080"
+	}
+	if raw := os.Getenv("UNIX_SOCKET_MODE"); raw != "" {
+		mode, err := strconv.ParseUint(raw, 8, 32)
+		if err != nil || mode > 0o777 {
+			invalid("UNIX_SOCKET_MODE", fmt.Errorf("%q is not an octal permission such as 0660", raw))
+		} else {
+			server.SocketMode = fs.FileMode(mode)
+		}
+	}
+	if path, ok := strings.CutPrefix(server.AdminAddr, unixPrefix); ok {
+		if path == "" || path == server.Socket {
+			invalid("ADMIN_ADDR", errors.New("unix socket path must be set and differ from UNIX_SOCKET"))
+		}
+	} else if server.AdminAddr != "" {
+		if _, port, err := net.SplitHostPort(server.AdminAddr); err != nil {
+			invalid("ADMIN_ADDR", err)
+		} else if port == server.Port && server.Socket == "" {
+			invalid("ADMIN_ADDR", fmt.Errorf("port %s is already the public PORT", port))
+		}
+	}
+
+	// Leaderboard options
+	opts := leaderboard.Options{
+		BoardID:        os.Getenv("BOARD_ID"),
+		RatingStrategy: os.Getenv("RATING_STRATEGY"),
+		ShadowStrategy: os.Getenv("SHADOW_RATING_STRATEGY"),
+		TiePolicy:      os.Getenv("TIE_POLICY"),
+		MaxMembers:     envInt("BOARD_MAX_MEMBERS", 0),
+		MemoryBudget:   envBytes("BOARD_MEMORY_BUDGET"),
+		MemoryPolicy:   os.Getenv("BOARD_MEMORY_POLICY"),
+		EventLogPath:   os.Getenv("EVENT_LOG_PATH"),
+		Guests:         os.Getenv("GUEST_SUBMISSIONS") == "true",
+		GuestLimits: leaderboard.GuestConfig{
+			MaxGuests:        envInt("GUEST_MAX", 0),
+			CreationsPerHour: envInt("GUEST_CREATIONS_PER_HOUR", 0),
+		},
+		SimulateUpdates:        simulatorEnabled(),
+		SimulationInterval:     envDuration("SIMULATOR_INTERVAL", 5*time.Second),
+		SimulationTarget:       os.Getenv("SIMULATOR_TARGET"),
+		SimulationBatchSize:    envInt("SIMULATOR_BATCH_SIZE", 1),
+		SimulationJitter:       envFloat("SIMULATOR_JITTER", 0),
+		ExpirySweepInterval:    envDuration("EXPIRY_SWEEP_INTERVAL", 30*time.Second),
+		InflationInterval:      envDuration("INFLATION_SAMPLE_INTERVAL", time.Hour),
+		HistoryCompactInterval: envDuration("HISTORY_COMPACT_INTERVAL", 10*time.Minute),
+		StatsCacheTTL:          envDuration("STATS_CACHE_TTL", time.Second),
+		SearchCacheTTL:         envDuration("SEARCH_CACHE_TTL", 2*time.Second),
+		Warmup:                 os.Getenv("WARMUP_ON_START") == "true",
+		RealtimeQueueSize:      envInt("WS_QUEUE_SIZE", 0),
+		MaxInFlight:            envInt("MAX_INFLIGHT_REQUESTS", 0),
+		RateLimit: leaderboard.RateLimit{
+			Reads:  envInt("RATE_LIMIT_READS", 0),
+			Writes: envInt("RATE_LIMIT_WRITES", 0),
+			Window: envDuration("RATE_LIMIT_WINDOW", time.Minute),
+		},
+		QueryCostBudget:    envInt("QUERY_COST_BUDGET", 0),
+		ApprovalThreshold:  envInt("ADJUSTMENT_APPROVAL_DELTA", 0),
+		SlowConsumerPolicy: os.Getenv("WS_SLOW_CONSUMER_POLICY"),
+		RandSeed:           int64(envInt("RAND_SEED", 0)),
+	}
+	if opts.StatsCacheTTL == 0 {
+		opts.StatsCacheTTL = -1 // STATS_CACHE_TTL=0 turns the cache off
+	}
+	if opts.SearchCacheTTL == 0 {
+		opts.SearchCacheTTL = -1 // SEARCH_CACHE_TTL=0 turns the cache off
+	}
+
+	// Anomaly detection on rating trajectories
+	if os.Getenv("ANOMALY_DETECTION") != "false" {
+		anomalyConfig := leaderboard.DefaultAnomalyConfig()
+		anomalyConfig.MaxChange = envInt("ANOMALY_MAX_CHANGE", anomalyConfig.MaxChange)
+		anomalyConfig.Window = envDuration("ANOMALY_WINDOW", anomalyConfig.Window)
+		anomalyConfig.AutoFreeze = os.Getenv("ANOMALY_AUTO_FREEZE") == "true"
+		anomalyConfig.IncludeBots = os.Getenv("ANOMALY_INCLUDE_BOTS") == "true"
+		opts.Anomaly = &anomalyConfig
+	}
+
+	// Signed score webhooks from third-party platforms
+	if secrets := envPairs("INTEGRATION_SECRETS"); len(secrets) > 0 {
+		opts.Integrations = &leaderboard.IntegrationConfig{
+			Secrets:   secrets,
+			Tolerance: envDuration("INTEGRATION_TOLERANCE", 5*time.Minute),
+		}
+	}
+
+	// The board sharded across Redis instances, either served from
+	// (STORE_BACKEND=sharded) or double-written to while migrating
+	// (DOUBLE_WRITE_TARGET=sharded)
+	var sharded *store.ShardedStore
+	shardAddrs := envList("REDIS_SHARD_ADDRS")
+	if len(shardAddrs) > 0 {
+		var err error
+		if sharded, err = store.NewShardedStore(shardClients(shardAddrs), os.Getenv("REDIS_KEY_PREFIX")+"leaderboard:"); err != nil {
+			invalid("REDIS_SHARD_ADDRS", err)
+		} else {
+			sharded.SetTopK(envInt("REDIS_SHARD_TOP_K", store.DefaultShardTopK))
+		}
+	}
+	useShards := func(key string) {
+		if len(shardAddrs) == 0 {
+			invalid(key, errors.New("sharded needs REDIS_SHARD_ADDRS"))
+		}
+	}
+	backend, target := os.Getenv("STORE_BACKEND"), os.Getenv("DOUBLE_WRITE_TARGET")
+	switch {
+	case len(shardAddrs) > 0 && backend != "sharded" && target != "sharded":
+		invalid("REDIS_SHARD_ADDRS", errors.New("set, but neither STORE_BACKEND nor DOUBLE_WRITE_TARGET is sharded"))
+	case backend == "sharded" && target == "sharded":
+		invalid("DOUBLE_WRITE_TARGET", errors.New("can't be the sharded board STORE_BACKEND already serves from"))
+	}
+
+	// Double-write verification while migrating stores, to a second
+	// in-memory store (DOUBLE_WRITE_TARGET=memory, the default) or the
+	// sharded board
+	if os.Getenv("DOUBLE_WRITE") == "true" {
+		opts.DoubleWrite = &leaderboard.DoubleWriteConfig{
+			Target:     store.NewMemoryStore(),
+			SampleRate: float64(envInt("DOUBLE_WRITE_SAMPLE_PERCENT", 10)) / 100,
+		}
+		switch target {
+		case "", "memory":
+		case "sharded":
+			useShards("DOUBLE_WRITE_TARGET")
+			if sharded != nil {
+				opts.DoubleWrite.Target = sharded
+			}
+		default:
+			invalid("DOUBLE_WRITE_TARGET", fmt.Errorf("unknown target %q (want memory or sharded)", target))
+		}
+	} else if target != "" {
+		invalid("DOUBLE_WRITE_TARGET", errors.New("set, but DOUBLE_WRITE isn't true"))
+	}
+
+	// Seasons and the webhooks notified when one closes
+	if id := os.Getenv("SEASON_ID"); id != "" {
+		opts.Season = &leaderboard.SeasonConfig{ID: id, EndsAt: envTime("SEASON_ENDS_AT")}
+	}
+	if urls := envList("SEASON_WEBHOOK_URLS"); len(urls) > 0 {
+		opts.SeasonWebhooks = &leaderboard.SeasonWebhookConfig{
+			URLs:   urls,
+			Secret: os.Getenv("SEASON_WEBHOOK_SECRET"),
+			TopN:   envInt("SEASON_WEBHOOK_TOP_N", 100),
+		}
+	}
+
+	// Daily reports
+	if os.Getenv("REPORTS_ENABLED") == "true" {
+		opts.Reports = &leaderboard.ReportConfig{
+			TopMovers:  envInt("REPORT_TOP_MOVERS", 5),
+			WebhookURL: os.Getenv("REPORT_WEBHOOK_URL"),
+		}
+		if dir := os.Getenv("REPORT_DIR"); dir != "" {
+			blobs, err := blobstore.NewDir(dir)
+			if err != nil {
+				invalid("REPORT_DIR", err)
+			}
+			opts.Reports.Blobs = blobs
+		}
+		if to := envList("REPORT_EMAIL_TO"); len(to) > 0 {
+			opts.Reports.Email = &leaderboard.EmailConfig{
+				Addr:     os.Getenv("SMTP_ADDR"),
+				From:     os.Getenv("SMTP_FROM"),
+				To:       to,
+				Username: os.Getenv("SMTP_USERNAME"),
+				Password: os.Getenv("SMTP_PASSWORD"),
+			}
+		}
+	}
+
+	// Partner score file import
+	if source := os.Getenv("IMPORT_URL"); source != "" {
+		opts.Import = &leaderboard.ImportConfig{
+			URL:         source,
+			Format:      os.Getenv("IMPORT_FORMAT"),
+			Secret:      os.Getenv("IMPORT_SECRET"),
+			Interval:    envDuration("IMPORT_INTERVAL", 15*time.Minute),
+			CreateUsers: os.Getenv("IMPORT_CREATE_USERS") == "true",
+		}
+	}
+
+	// Chat bots
+	bots := &leaderboard.BotConfig{SnapshotInterval: envDuration("BOT_SNAPSHOT_INTERVAL", 24*time.Hour)}
+	if token := os.Getenv("DISCORD_BOT_TOKEN"); token != "" {
+		bots.Discord = &leaderboard.DiscordBotConfig{Token: token, ChannelID: os.Getenv("DISCORD_BOT_CHANNEL_ID")}
+	}
+	if token := os.Getenv("TELEGRAM_BOT_TOKEN"); token != "" {
+		bots.Telegram = &leaderboard.TelegramBotConfig{Token: token, ChatID: os.Getenv("TELEGRAM_BOT_CHAT_ID")}
+	}
+	if bots.Discord != nil || bots.Telegram != nil {
+		opts.Bots = bots
+	}
+
+	// Lets environments share one Redis, e.g. REDIS_KEY_PREFIX=app:staging:
+	opts.RedisKeyPrefix = os.Getenv("REDIS_KEY_PREFIX")
+
+	// Where the board is kept: memory (default), redis to outlive restarts,
+	// or sharded across REDIS_SHARD_ADDRS for boards too big for one Redis
+	switch backend {
+	case "", "memory":
+	case "redis":
+		if redisOpts, err := redis.ParseURL(os.Getenv("REDIS_URL")); err != nil {
+			invalid("REDIS_URL", err)
+		} else if board, err := store.NewRedisStore(context.Background(), redis.NewClient(redisOpts), opts.RedisKeyPrefix+"leaderboard:"); err != nil {
+			invalid("STORE_BACKEND", fmt.Errorf("load the board from Redis: %w", err))
+		} else {
+			opts.Store = board
+		}
+	case "sharded":
+		useShards("STORE_BACKEND")
+		if sharded != nil {
+			opts.Store = sharded
+		}
+	default:
+		invalid("STORE_BACKEND", fmt.Errorf("unknown backend %q (want memory, redis or sharded)", backend))
+	}
+
+	// Applied match IDs, shared by every replica on Redis
+	if os.Getenv("REDIS_URL") != "" {
+		if redisOpts, err := redis.ParseURL(os.Getenv("REDIS_URL")); err != nil {
+			invalid("REDIS_URL", err)
+		} else {
+			opts.MatchLedger = leaderboard.NewRedisMatchLedger(redis.NewClient(redisOpts), opts.RedisKeyPrefix+"leaderboard:", envDuration("MATCH_LEDGER_TTL", 24*time.Hour))
+		}
+	}
+
+	// Async score submissions (?async=true)
+	if workers := envInt("SCORE_QUEUE_WORKERS", 0); workers > 0 {
+		opts.ScoreQueue = &leaderboard.ScoreQueueConfig{
+			Workers:   workers,
+			Capacity:  envInt("SCORE_QUEUE_CAPACITY", 10000),
+			LedgerTTL: envDuration("SCORE_QUEUE_LEDGER_TTL", 24*time.Hour),
+		}
+		// Share the queue between replicas on a Redis stream
+		if os.Getenv("SCORE_QUEUE_BACKEND") == "redis" {
+			if redisOpts, err := redis.ParseURL(os.Getenv("REDIS_URL")); err != nil {
+				invalid("REDIS_URL", err)
+			} else {
+				opts.ScoreQueue.Redis = redis.NewClient(redisOpts)
+			}
+			opts.ScoreQueue.Shards = envInt("SCORE_QUEUE_SHARDS", 0)
+			opts.ScoreQueue.MaxRetries = envInt("SCORE_QUEUE_MAX_RETRIES", 5)
+			opts.ScoreQueue.ClaimIdle = envDuration("SCORE_QUEUE_CLAIM_IDLE", time.Minute)
+			opts.ScoreQueue.SlowCommandThreshold = envDuration("REDIS_SLOW_COMMAND_THRESHOLD", 100*time.Millisecond)
+			opts.ScoreQueue.WriteBuffer = envInt("SCORE_QUEUE_WRITE_BUFFER", 1000)
+			opts.ScoreQueue.WriteBufferPolicy = os.Getenv("SCORE_QUEUE_BUFFER_POLICY")
+		}
+	}
+
+	// Daily, country and team boards, e.g. DERIVED_BOARDS=daily,country,team
+	if kinds := envList("DERIVED_BOARDS"); len(kinds) > 0 {
+		opts.DerivedBoards = &leaderboard.DerivedBoardsConfig{
+			Kinds:          kinds,
+			DailyRetention: envInt("DERIVED_BOARDS_DAILY_RETENTION", 7),
+		}
+		// Keep them on Redis, where each update is applied by one script
+		if os.Getenv("DERIVED_BOARDS_BACKEND") == "redis" {
+			if redisOpts, err := redis.ParseURL(os.Getenv("REDIS_URL")); err != nil {
+				invalid("REDIS_URL", err)
+			} else {
+				opts.DerivedBoards.Store = store.NewRedisBoards(redis.NewClient(redisOpts), opts.RedisKeyPrefix+"leaderboard:")
+			}
+		}
+	}
+
+	// Prize bands resolved against final standings
+	if spec := os.Getenv("PRIZE_BANDS"); spec != "" {
+		bands, err := leaderboard.ParsePrizeBands(spec)
+		if err != nil {
+			invalid("PRIZE_BANDS", err)
+		}
+		opts.PrizeBands = bands
+	}
+
+	// Tiers by lowest rating, e.g. TIER_BOUNDARIES=bronze:100,silver:1200,gold:1800
+	if spec := os.Getenv("TIER_BOUNDARIES"); spec != "" {
+		tiers, err := leaderboard.ParseTierBoundaries(spec)
+		if err != nil {
+			invalid("TIER_BOUNDARIES", err)
+		}
+		opts.TierBoundaries = tiers
+	}
+
+	// Ranks whose rating is watched, with the move worth announcing, e.g. CUTOFF_WATCH=100:25,1000:50
+	if spec := os.Getenv("CUTOFF_WATCH"); spec != "" {
+		cutoffs, err := leaderboard.ParseWatchedCutoffs(spec)
+		if err != nil {
+			invalid("CUTOFF_WATCH", err)
+		}
+		opts.Cutoffs = cutoffs
+	}
+
+	// How long CDNs and browsers may reuse responses, e.g. CACHE_MAX_AGES=leaderboard:5s,users:10s
+	for class, value := range envPairs("CACHE_MAX_AGES") {
+		age, err := time.ParseDuration(value)
+		if err != nil {
+			invalid("CACHE_MAX_AGES", err)
+			continue
+		}
+		if opts.CachePolicy == nil {
+			opts.CachePolicy = make(leaderboard.CachePolicy)
+		}
+		opts.CachePolicy[class] = age
+	}
+
+	// Latency objectives per endpoint group, e.g. LATENCY_SLOS=leaderboard:p99<50ms
+	if spec := os.Getenv("LATENCY_SLOS"); spec != "" {
+		objectives, err := leaderboard.ParseLatencySLOs(spec)
+		if err != nil {
+			invalid("LATENCY_SLOS", err)
+		}
+		opts.SLOs = &leaderboard.SLOConfig{
+			Objectives:    objectives,
+			AlertBurnRate: envFloat("SLO_ALERT_BURN_RATE", 14.4),
+			AlertURL:      os.Getenv("SLO_ALERT_URL"),
+		}
+	}
+
+	// Social login and access tokens
+	if secret := os.Getenv("AUTH_JWT_SECRET"); secret != "" {
+		opts.Auth = &leaderboard.AuthConfig{
+			Secret:          []byte(secret),
+			TokenTTL:        envDuration("AUTH_TOKEN_TTL", 15*time.Minute),
+			RefreshTTL:      envDuration("AUTH_REFRESH_TTL", 30*24*time.Hour),
+			Providers:       loginProviders(),
+			SuccessRedirect: os.Getenv("AUTH_SUCCESS_REDIRECT"),
+			APIKeys:         envPairs("API_KEYS"),
+			Lockout: leaderboard.LockoutConfig{
+				Threshold: envInt("AUTH_LOCKOUT_THRESHOLD", 5),
+				BaseDelay: envDuration("AUTH_LOCKOUT_BASE_DELAY", 30*time.Second),
+				MaxDelay:  envDuration("AUTH_LOCKOUT_MAX_DELAY", time.Hour),
+			},
+			TrustProxy: os.Getenv("AUTH_TRUST_PROXY") == "true",
+			ProxyHops:  envInt("AUTH_PROXY_HOPS", 1),
+		}
+		opts.Admins = envList("AUTH_ADMINS")
+	}
+
+	return server, opts, errors.Join(append(configErrors, opts.Validate())...)
+}
+
+// shardClients connects to each Redis shard, given as host:port or a
+// redis:// URL. Shards are named by their address as given, so the list can
+// be reordered without moving users.
+func shardClients(addrs []string) []store.Shard {
+	shards := make([]store.Shard, 0, len(addrs))
+	for _, addr := range addrs {
+		redisOpts := &redis.Options{Addr: addr}
+		if strings.Contains(addr, "://") {
+			parsed, err := redis.ParseURL(addr)
+			if err != nil {
+				invalid("REDIS_SHARD_ADDRS", err)
+				continue
+			}
+			redisOpts = parsed
+		}
+		shards = append(shards, store.Shard{Name: addr, Client: redis.NewClient(redisOpts)})
+	}
+	return shards
+}
+
+// simulatorEnabled honours SIMULATOR_ENABLED, defaulting to off when APP_ENV=production
+func simulatorEnabled() bool {
+	if value := os.Getenv("SIMULATOR_ENABLED"); value != "" {
+		return value == "true"
+	}
+	return os.Getenv("APP_ENV") != "production"
+}
+
+// loginProviders enables each provider whose client credentials are set.
+// Callbacks are served under AUTH_BASE_URL (default http://localhost:$PORT).
+func loginProviders() map[string]leaderboard.AuthProvider {
+	baseURL := os.Getenv("AUTH_BASE_URL")
+	if baseURL == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "8080"
+		}
+		baseURL = "http://localhost:" + port
+	}
+	callback := func(name string) string {
+		return strings.TrimRight(baseURL, "/") + "/api/auth/" + name + "/callback"
+	}
+
+	providers := make(map[string]leaderboard.AuthProvider)
+	if id := os.Getenv("GOOGLE_CLIENT_ID"); id != "" {
+		providers["google"] = leaderboard.GoogleLogin(id, os.Getenv("GOOGLE_CLIENT_SECRET"), callback("google"))
+	}
+	if id := os.Getenv("DISCORD_CLIENT_ID"); id != "" {
+		providers["discord"] = leaderboard.DiscordLogin(id, os.Getenv("DISCORD_CLIENT_SECRET"), callback("discord"))
+	}
+	return providers
+}
+
+// envInt reads an integer environment variable, falling back to def
+func envInt(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		invalid(key, err)
+		return def
+	}
+	return n
+}
+
+// envFloat reads a decimal environment variable, falling back to def
+func envFloat(key string, def float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		invalid(key, err)
+		return def
+	}
+	return f
+}
+
+// byteUnits are the size suffixes envBytes accepts
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9},
+	{"B", 1},
+}
+
+// envBytes reads a size such as "512MiB" or "2GB", or a plain byte count,
+// returning 0 if unset
+func envBytes(key string) int64 {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return 0
+	}
+
+	unit := int64(1)
+	for _, u := range byteUnits {
+		if number, ok := strings.CutSuffix(value, u.suffix); ok {
+			value, unit = strings.TrimSpace(number), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		invalid(key, err)
+		return 0
+	}
+	return n * unit
+}
+
+// envDuration reads a duration environment variable (e.g. "90s"), falling back to def
+func envDuration(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		invalid(key, err)
+		return def
+	}
+	return d
+}
+
+// envTime reads an RFC 3339 timestamp, returning the zero time if unset
+func envTime(key string) time.Time {
+	value := os.Getenv(key)
+	if value == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		invalid(key, err)
+		return time.Time{}
+	}
+	return t
+}
+
+// envList reads a comma-separated list, skipping empty items
+func envList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// envPairs reads a comma-separated list of name:value pairs (e.g. "steam:s3cret,epic:t0ken")
+func envPairs(key string) map[string]string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	pairs := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		name, secret, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok || name == "" || secret == "" {
+			invalid(key, errors.New("expected name:value pairs"))
+			continue
+		}
+		pairs[name] = secret
+	}
+	return pairs
+}
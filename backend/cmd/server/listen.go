@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// sdListenFDsStart is the first file descriptor systemd passes
+const sdListenFDsStart = 3
+
+// unixPrefix marks an address as a unix socket path, as in unix:/run/lb.sock
+const unixPrefix = "unix:"
+
+// listeners opens the public listener and, when the admin surface is split
+// off, the admin one. Sockets inherited from systemd take precedence over the
+// configured addresses.
+func listeners(server serverConfig) (public, admin net.Listener, err error) {
+	public, admin, err = activatedListeners()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if public == nil {
+		if public, err = listen(server.publicAddr(), server.SocketMode); err != nil {
+			return nil, nil, err
+		}
+	}
+	if admin == nil && server.AdminAddr != "" {
+		if admin, err = listen(server.AdminAddr, server.SocketMode); err != nil {
+			public.Close()
+			return nil, nil, err
+		}
+	}
+	return public, admin, nil
+}
+
+// listen opens a TCP listener, or a unix socket for unix: addresses. A stale
+// socket left by a previous run is replaced.
+func listen(addr string, mode fs.FileMode) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, unixPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// The reverse proxy usually runs as another user in the socket's group
+	if err := os.Chmod(path, mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// activatedListeners adopts sockets passed by systemd socket activation
+// (LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES), or by the process a restart
+// replaces, which can't know our PID. A socket named "admin" serves the
+// admin surface and the other one the public API. Both are nil when the
+// process wasn't passed any sockets.
+func activatedListeners() (public, admin net.Listener, err error) {
+	pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	count, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	restarted := os.Getenv(handoverEnv) != ""
+	if (pid != os.Getpid() && !restarted) || count <= 0 {
+		return nil, nil, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	// Children such as a restarted server must not adopt the same sockets
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	for i := range count {
+		fd := sdListenFDsStart + i
+		syscall.CloseOnExec(fd)
+
+		var name string
+		if i < len(names) {
+			name = names[i]
+		}
+		file := os.NewFile(uintptr(fd), name)
+		l, err := net.FileListener(file)
+		file.Close() // FileListener keeps its own copy
+		if err != nil {
+			return nil, nil, fmt.Errorf("socket activation: fd %d: %w", fd, err)
+		}
+
+		switch {
+		case name == "admin" && admin == nil:
+			admin = l
+		case name != "admin" && public == nil:
+			public = l
+		default:
+			l.Close()
+			return nil, nil, fmt.Errorf("socket activation: unexpected extra socket %q (fd %d)", name, fd)
+		}
+	}
+	if public == nil {
+		return nil, nil, errors.New("socket activation: only an admin socket was passed")
+	}
+	return public, admin, nil
+}
+
+// describeListener names a listener for the startup log
+func describeListener(l net.Listener) string {
+	if l.Addr().Network() == "unix" {
+		return "unix socket " + l.Addr().String()
+	}
+	return l.Addr().String()
+}
@@ -2,15 +2,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
-	"backend/internal/handlers"
-	"backend/internal/services"
+	"backend/internal/handlers/ginadapter"
+	"backend/pkg/leaderboard"
 	"backend/pkg/store"
 
 	"github.com/gin-contrib/cors"
@@ -19,22 +24,162 @@ import (
 )
 
 func main() {
+	validateOnly := flag.Bool("validate-config", false, "Print the resolved configuration and any errors, then exit without serving")
+	flag.Parse()
+
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using system environment variables")
 	}
 
-	// Initialize in-memory store
-	memoryStore := store.NewMemoryStore()
-	log.Println("✓ Initialized in-memory store")
+	server, opts, err := loadConfig()
+	if *validateOnly {
+		os.Exit(printConfig(server, opts, err))
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration (check with --validate-config):\n%v", err)
+	}
+
+	// Listen before starting jobs, so a taken port fails fast
+	publicListener, adminListener, err := listeners(server)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+	predecessor, err := predecessorConn()
+	if err != nil {
+		log.Fatalf("Failed to open handover connection: %v", err)
+	}
+
+	// Initialize the leaderboard and its services
+	lb, err := leaderboard.New(opts)
+	if err != nil {
+		log.Fatalf("Failed to initialize leaderboard: %v", err)
+	}
+	defer lb.Close()
+	if redisStore, ok := opts.Store.(*store.RedisStore); ok {
+		defer redisStore.Close()
+		log.Printf("✓ Loaded %d users from the board on Redis", redisStore.GetUserCount())
+	} else if sharded, ok := opts.Store.(*store.ShardedStore); ok {
+		defer sharded.Close()
+		log.Printf("✓ Serving the board from %d Redis shards, keeping a global top %d", sharded.Shards(), sharded.TopK())
+	} else {
+		log.Println("✓ Initialized in-memory store")
+	}
+	logOptions(opts)
+
+	// After a restart, take over the previous process's state once it has drained
+	if predecessor != nil {
+		snap, err := receiveState(predecessor)
+		if err != nil {
+			log.Fatalf("Handover failed: %v", err)
+		}
+		if err := lb.Restore(snap); err != nil {
+			log.Fatalf("Handover failed: %v", err)
+		}
+		if snap.Store != nil {
+			log.Printf("✓ Took over %d users from the previous process", len(snap.Store.Users))
+		} else {
+			log.Println("✓ Took over the previous process's state")
+		}
+	}
+
+	// With an admin listener, the admin surface moves off the public one
+	routes := lb.Routes()
+	if adminListener != nil {
+		routes = lb.PublicRoutes()
+	}
+
+	// Start random score update simulation and the expiry sweeper
+	ctx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go lb.Start(ctx)
+
+	// Graceful shutdown
+	conns := newConnTracker()
+	srv := &http.Server{Handler: newRouter(routes, lb.StoreBackend()), ConnState: conns.track}
+	servers := []*http.Server{srv}
+	accepting := []*pausableListener{newPausableListener(publicListener)}
+
+	go func() {
+		log.Printf("🚀 Server listening on %s", describeListener(publicListener))
+
+		if err := srv.Serve(accepting[0]); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	if adminListener != nil {
+		adminSrv := &http.Server{Handler: newRouter(lb.AdminRoutes(), lb.StoreBackend()), ConnState: conns.track}
+		servers = append(servers, adminSrv)
+		accepting = append(accepting, newPausableListener(adminListener))
+
+		go func() {
+			log.Printf("🔒 Admin API listening on %s", describeListener(adminListener))
+
+			if err := adminSrv.Serve(accepting[1]); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Failed to start admin server: %v", err)
+			}
+		}()
+	}
+
+	// Wait for interrupt signal. SIGUSR2 restarts in place: a new process
+	// inherits the listeners, and this one drains and hands over its state.
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR2)
+	var successor net.Conn
+	for sig := range quit {
+		if sig != syscall.SIGUSR2 {
+			break
+		}
+		log.Println("Restarting...")
+		if successor, err = startSuccessor(publicListener, adminListener); err != nil {
+			log.Printf("Restart failed, still serving: %v", err)
+			continue
+		}
+		break
+	}
+
+	log.Println("Shutting down server...")
 
-	// Initialize services
-	leaderboardService := services.NewLeaderboardService(memoryStore)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), server.DrainTimeout)
+	defer cancel()
 
-	// Initialize handlers
-	leaderboardHandler := handlers.NewLeaderboardHandler(leaderboardService)
+	// Stop background writers, then drain: /readyz fails, streams end, events are flushed
+	stopJobs()
+	if err := lb.Drain(shutdownCtx); err != nil {
+		log.Printf("Drain incomplete: %v", err)
+	}
+
+	// Only now stop accepting connections and wait for in-flight requests.
+	// On a restart, new connections queue on the sockets for the successor.
+	if successor != nil {
+		for _, l := range accepting {
+			l.pause()
+		}
+		if err := conns.waitForRequests(shutdownCtx); err != nil {
+			log.Printf("Connections still waiting for a request: %v", err)
+		}
+	}
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Fatal("Server forced to shutdown:", err)
+		}
+	}
+
+	// No more writes can arrive, so the snapshot is complete
+	if successor != nil {
+		if err := sendState(successor, lb.Snapshot()); err != nil {
+			log.Printf("Handover failed: %v", err)
+		} else {
+			log.Println("✓ Handed state over to the new process")
+		}
+	}
+
+	log.Println("Server exited")
+}
 
-	// Set up Gin router
+// newRouter serves routes and /health through Gin
+func newRouter(routes []leaderboard.Route, storeBackend string) *gin.Engine {
 	router := gin.Default()
 
 	// CORS configuration
@@ -42,7 +187,7 @@ func main() {
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
 		AllowHeaders:     []string{"Origin", "Content-Type"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-Query-Cost"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -51,67 +196,148 @@ func main() {
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"status": "ok",
-			"store":  "in-memory",
+			"store":  leaderboard.HealthStoreName(storeBackend),
 		})
 	})
 
 	// API routes
-	api := router.Group("/api")
-	{
-		// Seed data
-		api.POST("/seed", leaderboardHandler.SeedData)
-
-		// Leaderboard
-		api.GET("/leaderboard", leaderboardHandler.GetLeaderboard)
-
-		// User operations
-		api.GET("/users/:username", leaderboardHandler.GetUserRank)
-		api.POST("/users/:username/score", leaderboardHandler.UpdateScore)
-
-		// Search
-		api.GET("/search", leaderboardHandler.SearchUser)
+	ginadapter.Register(router, routes)
+	return router
+}
 
-		// Stats
-		api.GET("/stats", leaderboardHandler.GetStats)
+// printConfig writes the resolved configuration to stdout and any errors to
+// stderr, returning the exit code for --validate-config
+func printConfig(server serverConfig, opts leaderboard.Options, err error) int {
+	resolved := map[string]any{
+		"server": map[string]any{
+			"port":                   server.Port,
+			"unix_socket":            server.Socket,
+			"unix_socket_mode":       fmt.Sprintf("%#o", server.SocketMode),
+			"admin_addr":             server.AdminAddr,
+			"shutdown_drain_timeout": server.DrainTimeout.String(),
+		},
+		"leaderboard": opts.Resolved(),
 	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	enc.Encode(resolved)
 
-	// Start random score update simulation
-	ctx := context.Background()
-	go leaderboardService.StartRandomUpdates(ctx)
-
-	// Server configuration
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Invalid configuration:\n%v\n", err)
+		return 1
 	}
+	fmt.Fprintln(os.Stderr, "✓ Configuration is valid")
+	return 0
+}
 
-	// Graceful shutdown
-	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: router,
+// logOptions reports the optional features enabled by opts
+func logOptions(opts leaderboard.Options) {
+	strategy := opts.RatingStrategy
+	if strategy == "" {
+		strategy = "absolute"
 	}
+	log.Printf("✓ Using %s rating strategy", strategy)
 
-	go func() {
-		log.Printf("🚀 Server starting on port %s", port)
-
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+	if opts.ShadowStrategy != "" {
+		log.Printf("✓ Shadow-writing with %s rating strategy", opts.ShadowStrategy)
+	}
+	if opts.TiePolicy == "most_recent_first" {
+		log.Printf("✓ Ranking equal ratings most recent first")
+	}
+	if opts.MaxMembers > 0 {
+		log.Printf("✓ Capped leaderboard at %d members", opts.MaxMembers)
+	}
+	if opts.MemoryBudget > 0 {
+		policy := opts.MemoryPolicy
+		if policy == "" {
+			policy = "reject"
 		}
-	}()
-
-	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	log.Println("Shutting down server...")
-
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		log.Printf("✓ Capped leaderboard memory at %d MiB (%s when full)", opts.MemoryBudget>>20, policy)
+	}
+	if opts.EventLogPath != "" {
+		log.Printf("✓ Writing events to %s", opts.EventLogPath)
+	}
+	if opts.Anomaly != nil {
+		log.Println("✓ Enabled anomaly detection")
+	}
+	if opts.Auth != nil {
+		log.Printf("✓ Enabled login with %d provider(s)", len(opts.Auth.Providers))
+		log.Printf("✓ Enforcing roles on write and admin routes (%d API key(s), %d bootstrap admin(s))", len(opts.Auth.APIKeys), len(opts.Admins))
+	}
+	if opts.Warmup {
+		log.Println("✓ Warming up before /readyz reports ready")
+	}
+	if opts.MaxInFlight > 0 {
+		log.Printf("✓ Shedding requests without X-Priority: high beyond %d in flight", opts.MaxInFlight)
+	}
+	if opts.RateLimit.Reads > 0 || opts.RateLimit.Writes > 0 {
+		log.Printf("✓ Rate limiting each caller to %d reads and %d writes per %s (0 is unlimited)",
+			opts.RateLimit.Reads, opts.RateLimit.Writes, opts.RateLimit.Window)
+	}
+	if opts.QueryCostBudget > 0 {
+		log.Printf("✓ Rejecting reads over %d board entries unless the caller is an admin", opts.QueryCostBudget)
+	}
+	if len(opts.CachePolicy) > 0 {
+		log.Printf("✓ Overriding cache max ages: %v", opts.CachePolicy)
+	}
+	if opts.SLOs != nil {
+		log.Printf("✓ Tracking %d latency SLO(s), alerting at %.1fx burn rate (GET /api/admin/overview)",
+			len(opts.SLOs.Objectives), opts.SLOs.AlertBurnRate)
+	}
+	if opts.DerivedBoards != nil {
+		backend := "memory"
+		if _, ok := opts.DerivedBoards.Store.(*store.RedisBoards); ok {
+			backend = "redis"
+		}
+		log.Printf("✓ Keeping %s boards on %s, updated atomically with the main board (GET /api/boards)",
+			strings.Join(opts.DerivedBoards.Kinds, ", "), backend)
+	}
+	if opts.Guests {
+		log.Println("✓ Accepting guest scores keyed by device token (POST /api/guests/score)")
+	}
+	if len(opts.Cutoffs) > 0 {
+		log.Printf("✓ Watching %d rank cutoff(s) (GET /api/leaderboards/{id}/cutoffs)", len(opts.Cutoffs))
+	}
+	if opts.Season != nil {
+		log.Printf("✓ Started season %s", opts.Season.ID)
+	}
+	if opts.SeasonWebhooks != nil {
+		log.Printf("✓ Posting season results to %d webhook(s)", len(opts.SeasonWebhooks.URLs))
+	}
+	if opts.DoubleWrite != nil {
+		if sharded, ok := opts.DoubleWrite.Target.(*store.ShardedStore); ok {
+			log.Printf("✓ Double-writing to a board sharded across %d Redis instances, keeping a global top %d (report at /api/admin/migration/verification)", sharded.Shards(), sharded.TopK())
+		} else {
+			log.Println("✓ Double-writing to a migration target (report at /api/admin/migration/verification)")
+		}
+	}
+	if opts.RedisKeyPrefix != "" {
+		log.Printf("✓ Prefixing Redis keys with %q", opts.RedisKeyPrefix)
+	}
+	if opts.ApprovalThreshold > 0 {
+		log.Printf("✓ Adjustments over %d rating points need a second approver", opts.ApprovalThreshold)
+	}
+	if opts.Integrations != nil {
+		log.Printf("✓ Accepting signed scores from %d platform(s)", len(opts.Integrations.Secrets))
+	}
+	if opts.Reports != nil {
+		log.Println("✓ Generating daily reports (GET /api/reports/{date})")
+	}
+	if opts.Import != nil {
+		log.Printf("✓ Importing scores from %s every %s (GET /api/admin/imports)", opts.Import.URL, opts.Import.Interval)
+	}
+	if opts.Bots != nil {
+		var platforms []string
+		if opts.Bots.Discord != nil {
+			platforms = append(platforms, "Discord")
+		}
+		if opts.Bots.Telegram != nil {
+			platforms = append(platforms, "Telegram")
+		}
+		log.Printf("✓ Connecting chat bots on %s", strings.Join(platforms, " and "))
+	}
+	if !opts.SimulateUpdates {
+		log.Println("✓ Random update simulator disabled (enable with PUT /api/admin/simulation)")
 	}
-
-	log.Println("Server exited")
 }
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"backend/pkg/leaderboard"
+)
+
+// handoverEnv names the inherited descriptor a restarted process receives
+// the previous process's state on
+const handoverEnv = "LEADERBOARD_HANDOVER_FD"
+
+// handoverReadyTimeout bounds how long the old process waits for its
+// replacement to start before giving up and serving on
+const handoverReadyTimeout = time.Minute
+
+// handoverReady is sent by the new process once it can take over
+const handoverReady = "ready\n"
+
+// startSuccessor starts a new server process that inherits the listeners,
+// and waits until it is ready to take over. The returned connection carries
+// the state snapshot once this process has drained. A successor that fails
+// to start is killed, and this process keeps serving.
+func startSuccessor(public, admin net.Listener) (net.Conn, error) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		return nil, fmt.Errorf("handover socket: %w", err)
+	}
+	ours := os.NewFile(uintptr(fds[0]), "handover")
+	theirs := os.NewFile(uintptr(fds[1]), "handover")
+	defer theirs.Close()
+	conn, err := net.FileConn(ours)
+	ours.Close()
+	if err != nil {
+		return nil, fmt.Errorf("handover socket: %w", err)
+	}
+
+	// The successor adopts the listeners as if socket-activated
+	var files []*os.File
+	var names []string
+	for _, l := range []net.Listener{public, admin} {
+		if l == nil {
+			continue
+		}
+		file, err := listenerFile(l)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		defer file.Close()
+		files = append(files, file)
+		names = append(names, "public")
+	}
+	if admin != nil {
+		names[len(names)-1] = "admin"
+	}
+	files = append(files, theirs)
+
+	executable, err := os.Executable()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(os.Environ(),
+		"LISTEN_FDS="+strconv.Itoa(len(names)),
+		"LISTEN_FDNAMES="+strings.Join(names, ":"),
+		handoverEnv+"="+strconv.Itoa(sdListenFDsStart+len(files)-1),
+	)
+	err = cmd.Start()
+	// Passing a descriptor to a child switches the shared socket to blocking
+	// mode, and an Accept blocked here would take the successor's connections
+	for _, l := range []net.Listener{public, admin} {
+		if l != nil {
+			setNonblock(l)
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	log.Printf("Started new process %d, waiting for it to be ready", cmd.Process.Pid)
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	ready := make(chan error, 1)
+	go func() {
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err == nil && line != handoverReady {
+			err = fmt.Errorf("unexpected handover message %q", line)
+		}
+		ready <- err
+	}()
+
+	select {
+	case err = <-ready:
+	case err = <-exited:
+		err = fmt.Errorf("new process exited: %v", err)
+	case <-time.After(handoverReadyTimeout):
+		err = errors.New("new process did not become ready in time")
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// listenerFile duplicates a listener's descriptor for a child process
+func listenerFile(l net.Listener) (*os.File, error) {
+	if pausable, ok := l.(*pausableListener); ok {
+		l = pausable.Listener
+	}
+	filer, ok := l.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, fmt.Errorf("cannot hand over a %T", l)
+	}
+	return filer.File()
+}
+
+// setNonblock puts a listener's socket back in non-blocking mode
+func setNonblock(l net.Listener) {
+	if pausable, ok := l.(*pausableListener); ok {
+		l = pausable.Listener
+	}
+	if sc, ok := l.(syscall.Conn); ok {
+		if raw, err := sc.SyscallConn(); err == nil {
+			raw.Control(func(fd uintptr) { syscall.SetNonblock(int(fd), true) })
+		}
+	}
+}
+
+// pausableListener can stop accepting without closing the socket, so
+// connections arriving during a restart queue for the successor
+type pausableListener struct {
+	net.Listener
+	paused    atomic.Bool
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newPausableListener(l net.Listener) *pausableListener {
+	return &pausableListener{Listener: l, closed: make(chan struct{})}
+}
+
+// Accept blocks until Close once the listener is paused
+func (l *pausableListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil && l.paused.Load() {
+		<-l.closed
+		return nil, net.ErrClosed
+	}
+	return conn, err
+}
+
+// pause stops accepting connections. A unix socket file is kept when the
+// listener closes, because the successor serves on it.
+func (l *pausableListener) pause() {
+	l.paused.Store(true)
+	if unix, ok := l.Listener.(*net.UnixListener); ok {
+		unix.SetUnlinkOnClose(false)
+	}
+	// An accept deadline in the past wakes a blocked Accept
+	if deadliner, ok := l.Listener.(interface{ SetDeadline(time.Time) error }); ok {
+		deadliner.SetDeadline(time.Unix(1, 0))
+	}
+}
+
+func (l *pausableListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}
+
+// connTracker follows connections that haven't sent a request yet.
+// Server.Shutdown hangs up on those once their request arrives, so a
+// restart waits for them first.
+type connTracker struct {
+	mu    sync.Mutex
+	fresh map[net.Conn]struct{}
+}
+
+func newConnTracker() *connTracker {
+	return &connTracker{fresh: make(map[net.Conn]struct{})}
+}
+
+// track is an http.Server ConnState hook
+func (t *connTracker) track(conn net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if state == http.StateNew {
+		t.fresh[conn] = struct{}{}
+	} else {
+		delete(t.fresh, conn)
+	}
+}
+
+// waitForRequests returns once every accepted connection has sent its
+// first request or closed, or when ctx ends
+func (t *connTracker) waitForRequests(ctx context.Context) error {
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		t.mu.Lock()
+		pending := len(t.fresh)
+		t.mu.Unlock()
+		if pending == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendState hands the snapshot to the successor and closes the connection
+func sendState(conn net.Conn, snap *leaderboard.StateSnapshot) error {
+	defer conn.Close()
+	return json.NewEncoder(conn).Encode(snap)
+}
+
+// predecessorConn returns the connection to the process this one replaces,
+// or nil when it wasn't started by a restart
+func predecessorConn() (net.Conn, error) {
+	raw := os.Getenv(handoverEnv)
+	if raw == "" {
+		return nil, nil
+	}
+	os.Unsetenv(handoverEnv)
+
+	fd, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", handoverEnv, err)
+	}
+	syscall.CloseOnExec(fd)
+	file := os.NewFile(uintptr(fd), "handover")
+	conn, err := net.FileConn(file)
+	file.Close() // FileConn keeps its own copy
+	return conn, err
+}
+
+// receiveState tells the predecessor this process is ready, then waits for
+// its snapshot, which comes once the predecessor has drained
+func receiveState(conn net.Conn) (*leaderboard.StateSnapshot, error) {
+	defer conn.Close()
+	if _, err := conn.Write([]byte(handoverReady)); err != nil {
+		return nil, err
+	}
+	var snap leaderboard.StateSnapshot
+	if err := json.NewDecoder(conn).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	return &snap, nil
+}
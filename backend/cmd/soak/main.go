@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"backend/pkg/client"
+)
+
+// soak applies random valid operations against a running server for hours,
+// stopping every -check-every to verify invariants across the API. It exits
+// non-zero on the first divergence, so other writers (the simulator, decay)
+// must be off: the simulator is disabled at start.
+func main() {
+	baseURL := flag.String("url", "http://localhost:8080", "Server to soak")
+	duration := flag.Duration("duration", 4*time.Hour, "How long to run")
+	rate := flag.Float64("rate", 20, "Operations per second")
+	checkEvery := flag.Duration("check-every", 30*time.Second, "Interval between invariant checks")
+	seedCount := flag.Int("seed", 0, "Seed this many users first (0 soaks the existing board)")
+	track := flag.String("track", "", "User whose rank is checked (defaults to a random user)")
+	token := flag.String("token", "", "Bearer token with the admin role, when auth is enabled")
+	randSeed := flag.Uint64("rand-seed", uint64(time.Now().UnixNano()), "Seed for the operation mix, to reproduce a run")
+	keepGoing := flag.Bool("keep-going", false, "Log divergences instead of exiting on the first one")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+
+	s := &soak{
+		baseURL:   strings.TrimRight(*baseURL, "/"),
+		token:     *token,
+		client:    client.New(*baseURL, client.WithToken(*token)),
+		rng:       rand.New(rand.NewPCG(*randSeed, 0)),
+		expected:  make(map[string]int),
+		keepGoing: *keepGoing,
+	}
+	log.Printf("Soaking %s for %s at %.0f ops/s (rand seed %d)", s.baseURL, *duration, *rate, *randSeed)
+
+	if err := s.post(ctx, http.MethodPut, "/api/admin/simulation", map[string]bool{"enabled": false}); err != nil {
+		log.Fatalf("Failed to disable the simulator, which would race the invariant checks: %v", err)
+	}
+	if *seedCount > 0 {
+		if err := s.post(ctx, http.MethodPost, "/api/seed", map[string]any{"count": *seedCount, "wait": true}); err != nil {
+			log.Fatalf("Seeding failed: %v", err)
+		}
+	}
+	if err := s.loadUsers(ctx); err != nil {
+		log.Fatalf("Failed to load users: %v", err)
+	}
+	if len(s.users) == 0 {
+		log.Fatal("The board is empty; use -seed")
+	}
+	s.tracked = *track
+	if s.tracked == "" {
+		s.tracked = s.users[s.rng.IntN(len(s.users))]
+	}
+	log.Printf("✓ %d users, tracking %s", len(s.users), s.tracked)
+
+	opTicker := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+	defer opTicker.Stop()
+	checkTicker := time.NewTicker(*checkEvery)
+	defer checkTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// The final check runs on a fresh context
+			s.check(context.Background())
+			s.report()
+			if s.divergences > 0 {
+				os.Exit(1)
+			}
+			return
+		case <-checkTicker.C:
+			s.check(ctx)
+		case <-opTicker.C:
+			s.operate(ctx)
+		}
+	}
+}
+
+type soak struct {
+	baseURL   string
+	token     string
+	client    *client.Client
+	rng       *rand.Rand
+	users     []string
+	tracked   string
+	expected  map[string]int // Rating each user was last set to by this run
+	keepGoing bool
+
+	ops         map[string]int64
+	errors      int64
+	checks      int64
+	divergences int64
+	startedAt   time.Time
+}
+
+// loadUsers reads every username from a snapshot of the board
+func (s *soak) loadUsers(ctx context.Context) error {
+	s.startedAt = time.Now()
+	s.ops = make(map[string]int64)
+	for entry, err := range s.client.Leaderboard.Export(ctx, false) {
+		if err != nil {
+			return err
+		}
+		s.users = append(s.users, entry.Username)
+	}
+	return nil
+}
+
+// operate applies one random operation: mostly score updates, plus reads
+// that check the last update this run made is visible
+func (s *soak) operate(ctx context.Context) {
+	username := s.users[s.rng.IntN(len(s.users))]
+	var err error
+
+	switch roll := s.rng.IntN(100); {
+	case roll < 60:
+		s.ops["update"]++
+		rating := 100 + s.rng.IntN(4901)
+		var result *client.UpdateResponse
+		result, err = s.client.Users.UpdateScore(ctx, username, client.UpdateRequest{Rating: rating})
+		if err == nil {
+			s.expected[username] = result.Rating
+		}
+	case roll < 75:
+		s.ops["get_user"]++
+		var rank *client.UserRank
+		rank, err = s.client.Users.Get(ctx, username)
+		if want, ok := s.expected[username]; err == nil && ok && rank.Rating != want {
+			s.diverge("user %s has rating %d, last set to %d", username, rank.Rating, want)
+		}
+	case roll < 90:
+		s.ops["page"]++
+		_, err = s.client.Leaderboard.Page(ctx, client.PageOptions{Page: 1 + s.rng.IntN(5), Limit: 50})
+	default:
+		s.ops["search"]++
+		_, err = s.client.Search.All(ctx, username[:min(len(username), 4)], 10)
+	}
+
+	if err != nil && ctx.Err() == nil {
+		s.errors++
+		log.Printf("Operation failed: %v", err)
+	}
+}
+
+// check verifies invariants between a snapshot of the board, its stats and
+// the tracked user. No operations run while it does.
+func (s *soak) check(ctx context.Context) {
+	s.checks++
+	divergences := s.divergences
+
+	var ratings []int
+	var bots int64
+	previousRank := 0
+	for entry, err := range s.client.Leaderboard.Export(ctx, false) {
+		if err != nil {
+			s.fail(ctx, "export", err)
+			return
+		}
+		// Ranks are shared on ties and skip ahead after them
+		n := len(ratings)
+		wantRank := n + 1
+		if n > 0 && entry.Rating == ratings[n-1] {
+			wantRank = previousRank
+		}
+		if n > 0 && entry.Rating > ratings[n-1] {
+			s.diverge("export is not sorted: %s (%d) follows a rating of %d", entry.Username, entry.Rating, ratings[n-1])
+		}
+		if entry.Rank != wantRank {
+			s.diverge("export ranks %s at %d, expected %d", entry.Username, entry.Rank, wantRank)
+		}
+		previousRank = entry.Rank
+		ratings = append(ratings, entry.Rating)
+		if entry.Bot {
+			bots++
+		}
+	}
+	total := int64(len(ratings))
+
+	page, err := s.client.Leaderboard.Page(ctx, client.PageOptions{Limit: 1})
+	if err != nil {
+		s.fail(ctx, "page", err)
+		return
+	}
+	if page.TotalUsers != total {
+		s.diverge("leaderboard reports %d users, export has %d", page.TotalUsers, total)
+	}
+
+	stats, err := s.client.Leaderboard.Stats(ctx, false, true)
+	if err != nil {
+		s.fail(ctx, "stats", err)
+		return
+	}
+	s.checkStats(stats, ratings, bots)
+
+	rank, err := s.client.Users.Get(ctx, s.tracked)
+	if err != nil {
+		s.fail(ctx, "tracked user", err)
+		return
+	}
+	higher := int64(0)
+	for _, rating := range ratings {
+		if rating > rank.Rating {
+			higher++
+		}
+	}
+	if rank.Rank != higher+1 {
+		s.diverge("%s is ranked %d with rating %d, but %d users rate higher", s.tracked, rank.Rank, rank.Rating, higher)
+	}
+
+	if s.divergences == divergences {
+		log.Printf("✓ Check %d passed: %d users, %s ranked %d at %d", s.checks, total, s.tracked, rank.Rank, rank.Rating)
+	}
+}
+
+// checkStats compares the stats endpoint with aggregates recomputed from the export
+func (s *soak) checkStats(stats *client.Stats, ratings []int, bots int64) {
+	total := int64(len(ratings))
+	if stats.TotalUsers != total {
+		s.diverge("stats report %d users, export has %d", stats.TotalUsers, total)
+	}
+	if stats.BotUsers != bots {
+		s.diverge("stats report %d bots, export has %d", stats.BotUsers, bots)
+	}
+	if total == 0 {
+		return
+	}
+
+	sum := 0
+	for _, rating := range ratings {
+		sum += rating
+	}
+	average := float64(sum) / float64(total)
+	maxRating, minRating := float64(ratings[0]), float64(ratings[total-1])
+	if stats.MaxRating != maxRating || stats.MinRating != minRating {
+		s.diverge("stats report ratings %.0f-%.0f, export has %.0f-%.0f", stats.MinRating, stats.MaxRating, minRating, maxRating)
+	}
+	if math.Abs(stats.AverageRating-average) > 0.01 {
+		s.diverge("stats report an average of %.2f, export averages %.2f", stats.AverageRating, average)
+	}
+}
+
+// fail records a check that couldn't run, unless the run is ending
+func (s *soak) fail(ctx context.Context, step string, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	s.errors++
+	log.Printf("Check %d: %s failed: %v", s.checks, step, err)
+}
+
+// diverge reports a broken invariant, exiting unless -keep-going is set
+func (s *soak) diverge(format string, args ...any) {
+	s.divergences++
+	log.Printf("✗ DIVERGENCE: "+format, args...)
+	if !s.keepGoing {
+		s.report()
+		os.Exit(1)
+	}
+}
+
+func (s *soak) report() {
+	log.Printf("Ran for %s: ops %v, %d errors, %d checks, %d divergences",
+		time.Since(s.startedAt).Round(time.Second), s.ops, s.errors, s.checks, s.divergences)
+}
+
+// post sends an admin or seed request, which the SDK doesn't cover
+func (s *soak) post(ctx context.Context, method, path string, body any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: status %d", method, path, resp.StatusCode)
+	}
+	return nil
+}
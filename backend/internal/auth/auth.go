@@ -0,0 +1,144 @@
+// Package auth issues and verifies our access tokens and runs social login
+// against OAuth2/OIDC providers.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidState is returned when an OAuth callback's state is forged, expired
+// or belongs to another provider
+var ErrInvalidState = errors.New("invalid oauth state")
+
+// stateTTL bounds how long a user may take on the provider's consent screen
+const stateTTL = 10 * time.Minute
+
+// Config configures token signing and the enabled login providers
+type Config struct {
+	Secret          []byte              // HMAC key for tokens and OAuth state, at least 32 bytes
+	TokenTTL        time.Duration       // Access token lifetime, defaults to 15m
+	RefreshTTL      time.Duration       // Session and refresh token lifetime, defaults to 30 days
+	Providers       map[string]Provider // Enabled social login providers by name
+	SuccessRedirect string              // Optional frontend URL that receives the token after login
+	APIKeys         map[string]string   // API key secrets by key name, presented in X-API-Key
+	Lockout         LockoutConfig       // Backoff after failed attempts
+	TrustProxy      bool                // Take client IPs from X-Forwarded-For, for lockouts
+	ProxyHops       int                 // Trusted proxies appending to X-Forwarded-For, defaults to 1
+}
+
+// Auth issues tokens and drives OAuth logins
+type Auth struct {
+	config   Config
+	sessions *sessionStore
+	lockouts *lockouts
+	roles    func(username string) []string
+}
+
+// Validate checks the settings New requires
+func (c Config) Validate() error {
+	if len(c.Secret) < 32 {
+		return errors.New("auth secret must be at least 32 bytes")
+	}
+	return nil
+}
+
+// New validates config and creates an Auth
+func New(config Config) (*Auth, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.TokenTTL <= 0 {
+		config.TokenTTL = 15 * time.Minute
+	}
+	if config.RefreshTTL <= 0 {
+		config.RefreshTTL = 30 * 24 * time.Hour
+	}
+	if config.ProxyHops <= 0 {
+		config.ProxyHops = 1
+	}
+	return &Auth{
+		config:   config,
+		sessions: newSessionStore(),
+		lockouts: newLockouts(config.Lockout),
+		roles:    func(string) []string { return nil },
+	}, nil
+}
+
+// UseRoles sets how a user's roles are looked up when issuing tokens. The
+// roles claim is informational; authorization re-checks the current grants.
+func (a *Auth) UseRoles(lookup func(username string) []string) {
+	a.roles = lookup
+}
+
+// APIKey returns the name of the configured API key matching secret
+func (a *Auth) APIKey(secret string) (string, bool) {
+	for name, key := range a.config.APIKeys {
+		if hmac.Equal([]byte(secret), []byte(key)) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Provider returns an enabled login provider
+func (a *Auth) Provider(name string) (Provider, bool) {
+	p, ok := a.config.Providers[name]
+	return p, ok
+}
+
+// TrustProxy reports whether client IPs come from X-Forwarded-For
+func (a *Auth) TrustProxy() bool {
+	return a.config.TrustProxy
+}
+
+// ProxyHops returns how many trusted proxies append to X-Forwarded-For
+func (a *Auth) ProxyHops() int {
+	return a.config.ProxyHops
+}
+
+// SuccessRedirect returns the frontend URL that receives tokens, if configured
+func (a *Auth) SuccessRedirect() string {
+	return a.config.SuccessRedirect
+}
+
+// NewState returns a signed, expiring OAuth state value bound to provider.
+// The caller also stores it in a cookie so the callback can check that the
+// same browser started the login.
+func (a *Auth) NewState(provider string) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(stateTTL).Unix(), 10)
+	unsigned := provider + "." + expires + "." + base64.RawURLEncoding.EncodeToString(nonce)
+	return unsigned + "." + a.sign(unsigned), nil
+}
+
+// CheckState verifies a state value produced by NewState for provider
+func (a *Auth) CheckState(provider, state string) error {
+	i := strings.LastIndex(state, ".")
+	if i < 0 {
+		return ErrInvalidState
+	}
+	unsigned, signature := state[:i], state[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(a.sign(unsigned))) {
+		return ErrInvalidState
+	}
+
+	parts := strings.Split(unsigned, ".")
+	if len(parts) != 3 || parts[0] != provider {
+		return ErrInvalidState
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return ErrInvalidState
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is returned for malformed, forged or expired tokens
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrExpiredToken is the ErrInvalidToken returned for genuine tokens that
+	// expired or whose session was revoked; these don't count as guesses
+	ErrExpiredToken = fmt.Errorf("%w: expired or revoked", ErrInvalidToken)
+)
+
+// jwtHeader is the fixed header of every token we issue (HS256)
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims are the JWT claims carried by access tokens
+type Claims struct {
+	Subject   string   `json:"sub"` // Leaderboard username
+	Roles     []string `json:"roles,omitempty"`
+	SessionID string   `json:"sid,omitempty"` // Server-side session; revoking it invalidates the token
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// HasRole reports whether the claims grant role
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Auth) sign(data string) string {
+	mac := hmac.New(sha256.New, a.config.Secret)
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// IssueToken signs a sessionless access token for subject. Such tokens
+// cannot be revoked; logins use StartSession instead.
+func (a *Auth) IssueToken(subject string, roles []string) (string, time.Time, error) {
+	return a.issue(subject, roles, "")
+}
+
+func (a *Auth) issue(subject string, roles []string, sessionID string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(a.config.TokenTTL)
+
+	payload, err := json.Marshal(Claims{
+		Subject:   subject,
+		Roles:     roles,
+		SessionID: sessionID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + a.sign(unsigned), expiresAt, nil
+}
+
+// VerifyToken checks an access token's signature, expiry and session
+func (a *Auth) VerifyToken(token string) (*Claims, error) {
+	header, rest, ok := strings.Cut(token, ".")
+	if !ok || header != jwtHeader {
+		return nil, ErrInvalidToken
+	}
+	payload, signature, ok := strings.Cut(rest, ".")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(signature), []byte(a.sign(header+"."+payload))) {
+		return nil, ErrInvalidToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	if claims.SessionID != "" && !a.sessionActive(claims.SessionID) {
+		return nil, ErrExpiredToken
+	}
+
+	return &claims, nil
+}
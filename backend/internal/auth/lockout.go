@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LockoutConfig controls backoff after failed authentication attempts
+type LockoutConfig struct {
+	Threshold        int           // Failures allowed before locking out, defaults to 5
+	BaseDelay        time.Duration // First lockout, doubled for each further failure; defaults to 30s
+	MaxDelay         time.Duration // Longest lockout, defaults to 1h
+	Window           time.Duration // Failures older than this are forgotten, defaults to 15m
+	StuffingAccounts int           // Distinct accounts failing from one IP within Window that raise an alert, defaults to 10
+}
+
+func (c LockoutConfig) withDefaults() LockoutConfig {
+	if c.Threshold <= 0 {
+		c.Threshold = 5
+	}
+	if c.BaseDelay <= 0 {
+		c.BaseDelay = 30 * time.Second
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = time.Hour
+	}
+	if c.Window <= 0 {
+		c.Window = 15 * time.Minute
+	}
+	if c.StuffingAccounts <= 0 {
+		c.StuffingAccounts = 10
+	}
+	return c
+}
+
+// Lockout describes a client or account that is failing to authenticate
+type Lockout struct {
+	Key         string     `json:"key"` // "ip:<addr>" or "account:<username>"
+	Failures    int        `json:"failures"`
+	LastFailure time.Time  `json:"last_failure"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
+
+type attempts struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+	accounts    map[string]time.Time // per IP: accounts that failed, for stuffing detection
+	alerted     bool
+}
+
+// lockouts tracks failed attempts in memory, keyed by IP and by account
+type lockouts struct {
+	config LockoutConfig
+	mu     sync.Mutex
+	keys   map[string]*attempts
+	alert  func(detail string)
+}
+
+func newLockouts(config LockoutConfig) *lockouts {
+	return &lockouts{
+		config: config.withDefaults(),
+		keys:   make(map[string]*attempts),
+		alert:  func(string) {},
+	}
+}
+
+func lockoutKeys(ip, account string) []string {
+	keys := []string{"ip:" + ip}
+	if account != "" {
+		keys = append(keys, "account:"+account)
+	}
+	return keys
+}
+
+// OnAlert sets the callback for suspected credential stuffing
+func (a *Auth) OnAlert(alert func(detail string)) {
+	a.lockouts.alert = alert
+}
+
+// LockedOut reports whether ip or account is locked out, and until when
+func (a *Auth) LockedOut(ip, account string) (time.Time, bool) {
+	l := a.lockouts
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	var until time.Time
+	for _, key := range lockoutKeys(ip, account) {
+		if at, ok := l.keys[key]; ok && now.Before(at.lockedUntil) && at.lockedUntil.After(until) {
+			until = at.lockedUntil
+		}
+	}
+	return until, !until.IsZero()
+}
+
+// RecordFailure counts a failed attempt from ip, against account when known.
+// Past the threshold each failure doubles the lockout, up to MaxDelay.
+func (a *Auth) RecordFailure(ip, account string) {
+	l := a.lockouts
+	l.mu.Lock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	var alert string
+	for _, key := range lockoutKeys(ip, account) {
+		at := l.keys[key]
+		if at == nil {
+			at = &attempts{}
+			l.keys[key] = at
+		}
+		at.failures++
+		at.lastFailure = now
+		if over := at.failures - l.config.Threshold; over >= 0 {
+			delay := l.config.MaxDelay
+			if over < 32 {
+				delay = min(l.config.BaseDelay<<over, l.config.MaxDelay)
+			}
+			at.lockedUntil = now.Add(delay)
+		}
+	}
+
+	// Credential stuffing: one IP failing against many different accounts
+	if account != "" {
+		at := l.keys["ip:"+ip]
+		if at.accounts == nil {
+			at.accounts = make(map[string]time.Time)
+		}
+		at.accounts[account] = now
+		for name, last := range at.accounts {
+			if now.Sub(last) > l.config.Window {
+				delete(at.accounts, name)
+			}
+		}
+		if len(at.accounts) >= l.config.StuffingAccounts && !at.alerted {
+			at.alerted = true
+			alert = fmt.Sprintf("possible credential stuffing from %s: failed logins for %d accounts within %s", ip, len(at.accounts), l.config.Window)
+		}
+	}
+	l.mu.Unlock()
+
+	if alert != "" {
+		l.alert(alert)
+	}
+}
+
+// RecordSuccess clears an account's failures after a good attempt. IP
+// failures are left to age out, so one valid credential can't reset the
+// count for guesses made alongside it.
+func (a *Auth) RecordSuccess(account string) {
+	if account == "" {
+		return
+	}
+	l := a.lockouts
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.keys, "account:"+account)
+}
+
+// Lockouts lists keys with recent failures, locked ones first
+func (a *Auth) Lockouts() []Lockout {
+	l := a.lockouts
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	list := make([]Lockout, 0, len(l.keys))
+	for key, at := range l.keys {
+		lockout := Lockout{Key: key, Failures: at.failures, LastFailure: at.lastFailure.UTC()}
+		if now.Before(at.lockedUntil) {
+			until := at.lockedUntil.UTC()
+			lockout.LockedUntil = &until
+		}
+		list = append(list, lockout)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if (list[i].LockedUntil != nil) != (list[j].LockedUntil != nil) {
+			return list[i].LockedUntil != nil
+		}
+		return list[i].LastFailure.After(list[j].LastFailure)
+	})
+	return list
+}
+
+// ClearLockout forgets the failures recorded for key, reporting whether any were
+func (a *Auth) ClearLockout(key string) bool {
+	l := a.lockouts
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, ok := l.keys[key]
+	delete(l.keys, key)
+	return ok
+}
+
+// sweep forgets keys whose last failure and lockout are both behind them; callers hold mu
+func (l *lockouts) sweep(now time.Time) {
+	for key, at := range l.keys {
+		if now.Sub(at.lastFailure) > l.config.Window && !now.Before(at.lockedUntil) {
+			delete(l.keys, key)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Provider is an OAuth2 authorization-code login provider. The user's
+// identity is read from the provider's userinfo endpoint.
+type Provider struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	RedirectURL  string // Our callback, e.g. https://api.example.com/api/auth/google/callback
+	Scopes       []string
+	SubjectField string // Userinfo field holding the stable account ID
+	NameField    string // Userinfo field used to suggest a username
+}
+
+// Identity is the account a provider vouched for
+type Identity struct {
+	Provider string
+	Subject  string
+	Name     string
+}
+
+// Google returns an OpenID Connect provider for Google accounts
+func Google(clientID, clientSecret, redirectURL string) Provider {
+	return Provider{
+		Name:         "google",
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		AuthURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:     "https://oauth2.googleapis.com/token",
+		UserInfoURL:  "https://openidconnect.googleapis.com/v1/userinfo",
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "profile"},
+		SubjectField: "sub",
+		NameField:    "name",
+	}
+}
+
+// Discord returns an OAuth2 provider for Discord accounts
+func Discord(clientID, clientSecret, redirectURL string) Provider {
+	return Provider{
+		Name:         "discord",
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		AuthURL:      "https://discord.com/oauth2/authorize",
+		TokenURL:     "https://discord.com/api/oauth2/token",
+		UserInfoURL:  "https://discord.com/api/users/@me",
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"identify"},
+		SubjectField: "id",
+		NameField:    "username",
+	}
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// AuthCodeURL is where the user is sent to log in
+func (p Provider) AuthCodeURL(state string) string {
+	q := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.ClientID},
+		"redirect_uri":  {p.RedirectURL},
+		"scope":         {strings.Join(p.Scopes, " ")},
+		"state":         {state},
+	}
+	return p.AuthURL + "?" + q.Encode()
+}
+
+// Authenticate exchanges an authorization code and fetches the user's identity
+func (p Provider) Authenticate(ctx context.Context, code string) (*Identity, error) {
+	accessToken, err := p.exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.UserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	var info map[string]any
+	if err := doJSON(req, &info); err != nil {
+		return nil, fmt.Errorf("userinfo: %w", err)
+	}
+
+	subject := fmt.Sprint(info[p.SubjectField])
+	if info[p.SubjectField] == nil || subject == "" {
+		return nil, fmt.Errorf("userinfo: missing %s", p.SubjectField)
+	}
+	name, _ := info[p.NameField].(string)
+
+	return &Identity{Provider: p.Name, Subject: subject, Name: name}, nil
+}
+
+func (p Provider) exchange(ctx context.Context, code string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doJSON(req, &token); err != nil {
+		return "", fmt.Errorf("token exchange: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("token exchange: no access token")
+	}
+	return token.AccessToken, nil
+}
+
+func doJSON(req *http.Request, v any) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,309 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidRefreshToken is returned for unknown, revoked, reused or expired refresh tokens
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// Tokens is the credential pair handed out at login and on refresh
+type Tokens struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+	SessionID        string
+	Username         string
+}
+
+// SessionInfo describes an active session for admins
+type SessionInfo struct {
+	ID         string    `json:"id"`
+	Username   string    `json:"username"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// session is a server-side login that refresh tokens extend. The refresh
+// secret rotates on every use; presenting the previous one again means it
+// leaked, so the whole session is revoked.
+type session struct {
+	info         SessionInfo
+	refreshHash  string
+	previousHash string
+}
+
+// sessionStore keeps sessions in memory, indexed by user for bulk revocation
+type sessionStore struct {
+	mu     sync.Mutex
+	byID   map[string]*session
+	byUser map[string]map[string]struct{}
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{
+		byID:   make(map[string]*session),
+		byUser: make(map[string]map[string]struct{}),
+	}
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// StartSession creates a session for username and issues its first tokens
+func (a *Auth) StartSession(username string) (*Tokens, error) {
+	id, err := randomToken(12)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	s := &session{
+		info: SessionInfo{
+			ID:         id,
+			Username:   username,
+			CreatedAt:  now,
+			LastUsedAt: now,
+			ExpiresAt:  now.Add(a.config.RefreshTTL),
+		},
+		refreshHash: hashSecret(secret),
+	}
+
+	store := a.sessions
+	store.mu.Lock()
+	store.sweep(now)
+	store.byID[id] = s
+	if store.byUser[username] == nil {
+		store.byUser[username] = make(map[string]struct{})
+	}
+	store.byUser[username][id] = struct{}{}
+	store.mu.Unlock()
+
+	return a.tokensFor(s.info, secret)
+}
+
+// Refresh exchanges a refresh token for new tokens, rotating the refresh token
+func (a *Auth) Refresh(refreshToken string) (*Tokens, error) {
+	id, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	store := a.sessions
+	store.mu.Lock()
+
+	now := time.Now().UTC()
+	s, exists := store.byID[id]
+	if !exists || !now.Before(s.info.ExpiresAt) {
+		store.mu.Unlock()
+		return nil, ErrInvalidRefreshToken
+	}
+
+	presented := hashSecret(secret)
+	if s.previousHash != "" && subtle.ConstantTimeCompare([]byte(presented), []byte(s.previousHash)) == 1 {
+		// A rotated-out token came back: someone else holds a copy
+		store.remove(id)
+		store.mu.Unlock()
+		return nil, ErrInvalidRefreshToken
+	}
+	if subtle.ConstantTimeCompare([]byte(presented), []byte(s.refreshHash)) != 1 {
+		store.mu.Unlock()
+		return nil, ErrInvalidRefreshToken
+	}
+
+	next, err := randomToken(32)
+	if err != nil {
+		store.mu.Unlock()
+		return nil, err
+	}
+	s.previousHash = s.refreshHash
+	s.refreshHash = hashSecret(next)
+	s.info.LastUsedAt = now
+	info := s.info
+	store.mu.Unlock()
+
+	return a.tokensFor(info, next)
+}
+
+func (a *Auth) tokensFor(info SessionInfo, secret string) (*Tokens, error) {
+	access, accessExpiresAt, err := a.issue(info.Username, a.roles(info.Username), info.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &Tokens{
+		AccessToken:      access,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     info.ID + "." + secret,
+		RefreshExpiresAt: info.ExpiresAt,
+		SessionID:        info.ID,
+		Username:         info.Username,
+	}, nil
+}
+
+// Logout revokes the session a refresh token belongs to
+func (a *Auth) Logout(refreshToken string) error {
+	id, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok {
+		return ErrInvalidRefreshToken
+	}
+
+	store := a.sessions
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	s, exists := store.byID[id]
+	if !exists || subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(s.refreshHash)) != 1 {
+		return ErrInvalidRefreshToken
+	}
+	store.remove(id)
+	return nil
+}
+
+// RevokeUser ends every session of username and returns how many were ended.
+// Access tokens tied to those sessions stop verifying immediately.
+func (a *Auth) RevokeUser(username string) int {
+	store := a.sessions
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	ids := store.byUser[username]
+	n := len(ids)
+	for id := range ids {
+		store.remove(id)
+	}
+	return n
+}
+
+// ListSessions returns username's active sessions, newest first
+func (a *Auth) ListSessions(username string) []SessionInfo {
+	store := a.sessions
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.sweep(time.Now())
+	sessions := make([]SessionInfo, 0, len(store.byUser[username]))
+	for id := range store.byUser[username] {
+		sessions = append(sessions, store.byID[id].info)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+	return sessions
+}
+
+// RefreshTokenOwner returns the user a refresh token claims to belong to,
+// without checking its secret, so failures can be counted per account
+func (a *Auth) RefreshTokenOwner(refreshToken string) string {
+	id, _, _ := strings.Cut(refreshToken, ".")
+
+	store := a.sessions
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if s, ok := store.byID[id]; ok {
+		return s.info.Username
+	}
+	return ""
+}
+
+// sessionActive reports whether an access token's session is still valid
+func (a *Auth) sessionActive(id string) bool {
+	store := a.sessions
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	s, ok := store.byID[id]
+	return ok && time.Now().Before(s.info.ExpiresAt)
+}
+
+func (s *sessionStore) remove(id string) {
+	sess, ok := s.byID[id]
+	if !ok {
+		return
+	}
+	delete(s.byID, id)
+	delete(s.byUser[sess.info.Username], id)
+	if len(s.byUser[sess.info.Username]) == 0 {
+		delete(s.byUser, sess.info.Username)
+	}
+}
+
+// sweep drops expired sessions; callers hold mu
+func (s *sessionStore) sweep(now time.Time) {
+	for id, sess := range s.byID {
+		if !now.Before(sess.info.ExpiresAt) {
+			s.remove(id)
+		}
+	}
+}
+
+// SessionState is a session as handed to a restarted process: its details
+// and the hashes of its refresh secrets, never the secrets themselves
+type SessionState struct {
+	SessionInfo
+	RefreshHash  string `json:"refresh_hash"`
+	PreviousHash string `json:"previous_hash,omitempty"`
+}
+
+// SessionStates returns every unexpired session, for a restarted process to
+// take over with RestoreSessions
+func (a *Auth) SessionStates() []SessionState {
+	store := a.sessions
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.sweep(time.Now())
+	states := make([]SessionState, 0, len(store.byID))
+	for _, s := range store.byID {
+		states = append(states, SessionState{SessionInfo: s.info, RefreshHash: s.refreshHash, PreviousHash: s.previousHash})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].ID < states[j].ID })
+	return states
+}
+
+// RestoreSessions replaces every session with states, leaving out expired
+// ones. Refresh tokens and the access tokens of the sessions keep working.
+func (a *Auth) RestoreSessions(states []SessionState) {
+	store := a.sessions
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.byID = make(map[string]*session, len(states))
+	store.byUser = make(map[string]map[string]struct{})
+	now := time.Now()
+	for _, state := range states {
+		if !now.Before(state.ExpiresAt) {
+			continue
+		}
+		store.byID[state.ID] = &session{info: state.SessionInfo, refreshHash: state.RefreshHash, previousHash: state.PreviousHash}
+		if store.byUser[state.Username] == nil {
+			store.byUser[state.Username] = make(map[string]struct{})
+		}
+		store.byUser[state.Username][state.ID] = struct{}{}
+	}
+}
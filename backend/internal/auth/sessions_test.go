@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func newTestAuth(t *testing.T) *Auth {
+	t.Helper()
+	a, err := New(Config{Secret: []byte("0123456789abcdef0123456789abcdef")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestRestoreSessions(t *testing.T) {
+	old := newTestAuth(t)
+	tokens, err := old.StartSession("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := old.Refresh(tokens.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := json.Marshal(old.SessionStates())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var states []SessionState
+	if err := json.Unmarshal(raw, &states); err != nil {
+		t.Fatal(err)
+	}
+	restored := newTestAuth(t)
+	restored.RestoreSessions(states)
+
+	if _, err := restored.VerifyToken(rotated.AccessToken); err != nil {
+		t.Errorf("access token of a restored session: %v", err)
+	}
+	if got := restored.ListSessions("alice"); len(got) != 1 || got[0].ID != tokens.SessionID {
+		t.Errorf("restored sessions %+v", got)
+	}
+	// The rotated-out token still counts as reuse, ending the session
+	if _, err := restored.Refresh(tokens.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("reused refresh token = %v", err)
+	}
+	if _, err := restored.Refresh(rotated.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refresh after reuse = %v, want the session revoked", err)
+	}
+}
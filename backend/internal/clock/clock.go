@@ -0,0 +1,147 @@
+// Package clock abstracts the passage of time so time-dependent features
+// can be tested by advancing a fake clock instead of sleeping.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and creates tickers
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks on C like time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Reset(d time.Duration)
+	Stop()
+}
+
+// Real returns the system clock
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// Fake is a Clock that only moves when advanced. Tickers fire, in order,
+// for every period the clock passes; like time.Ticker they drop ticks a
+// slow reader misses, keeping only the latest.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// NewFake returns a fake clock stopped at start
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t := &fakeTicker{clock: f, c: make(chan time.Time, 1), period: d, next: f.now.Add(d)}
+	f.tickers = append(f.tickers, t)
+	return t
+}
+
+// Advance moves the clock forward by d, firing due tickers along the way
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	end := f.now.Add(d)
+	for {
+		due := f.nextDue(end)
+		if due == nil {
+			break
+		}
+		f.now = due.next
+		// Replace an unread tick so a slow reader sees the latest time
+		select {
+		case <-due.c:
+		default:
+		}
+		due.c <- f.now
+		due.next = due.next.Add(due.period)
+	}
+	f.now = end
+}
+
+// nextDue returns the active ticker firing soonest, at or before end
+func (f *Fake) nextDue(end time.Time) *fakeTicker {
+	active := f.tickers[:0]
+	for _, t := range f.tickers {
+		if !t.stopped {
+			active = append(active, t)
+		}
+	}
+	f.tickers = active
+
+	sort.SliceStable(active, func(i, j int) bool { return active[i].next.Before(active[j].next) })
+	if len(active) == 0 || active[0].next.After(end) {
+		return nil
+	}
+	return active[0]
+}
+
+type fakeTicker struct {
+	clock   *Fake
+	c       chan time.Time
+	period  time.Duration
+	next    time.Time
+	stopped bool
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.period = d
+	t.next = t.clock.now.Add(d)
+	t.stopped = false
+	if !t.registered() {
+		t.clock.tickers = append(t.clock.tickers, t)
+	}
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.stopped = true
+}
+
+func (t *fakeTicker) registered() bool {
+	for _, other := range t.clock.tickers {
+		if other == t {
+			return true
+		}
+	}
+	return false
+}
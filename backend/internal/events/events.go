@@ -0,0 +1,120 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"backend/internal/clock"
+)
+
+// Event types emitted by the leaderboard service
+const (
+	TypeUserAdded    = "user_added"
+	TypeScoreUpdated = "score_updated"
+	TypeUserEvicted  = "user_evicted"
+	TypeUserExpired  = "user_expired"
+
+	// Moderation events are audit records; they never reach public subscribers
+	TypeUserFrozen   = "user_frozen"
+	TypeUserUnfrozen = "user_unfrozen"
+	TypeNoteAdded    = "note_added"
+)
+
+// Event describes a single mutation of the leaderboard
+type Event struct {
+	SchemaVersion  int        `json:"schema_version"`
+	Type           string     `json:"type"`
+	Username       string     `json:"username"`
+	Rating         int        `json:"rating"`
+	PreviousRating int        `json:"previous_rating,omitempty"`
+	Bot            bool       `json:"bot,omitempty"`
+	Reason         string     `json:"reason,omitempty"`      // Why a score changed, see models.Reason*
+	Source         string     `json:"source,omitempty"`      // What made the change: api, simulator, import, decay or admin
+	Actor          string     `json:"actor,omitempty"`       // Staff member behind a moderation action
+	Note           string     `json:"note,omitempty"`        // Moderator's reason or staff note
+	Until          *time.Time `json:"until,omitempty"`       // End of a user_frozen freeze
+	OccurredAt     *time.Time `json:"occurred_at,omitempty"` // When an offline client made a score_updated change, synced later
+	SyncedBy       string     `json:"synced_by,omitempty"`   // The offline client that synced it
+	Timestamp      time.Time  `json:"timestamp"`
+}
+
+// Public returns the event as public subscribers may see it: moderation
+// events are withheld and staff-only fields stripped
+func (e Event) Public() (Event, bool) {
+	switch e.Type {
+	case TypeUserFrozen, TypeUserUnfrozen, TypeNoteAdded:
+		return Event{}, false
+	}
+	e.Actor = ""
+	e.Note = ""
+	return e, true
+}
+
+// Handler receives published events
+type Handler func(Event)
+
+// Bus fans events out to subscribers synchronously, in publish order
+type Bus struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers []subscription
+	clock    clock.Clock
+}
+
+type subscription struct {
+	id      int
+	handler Handler
+}
+
+// NewBus creates an event bus with no subscribers
+func NewBus() *Bus {
+	return &Bus{clock: clock.Real()}
+}
+
+// SetClock sets the clock used to stamp events
+func (b *Bus) SetClock(c clock.Clock) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clock = c
+}
+
+// Subscribe registers a handler for every future event. The returned func
+// removes the handler again.
+func (b *Bus) Subscribe(h Handler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.handlers = append(b.handlers, subscription{id: id, handler: h})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		for i, sub := range b.handlers {
+			if sub.id == id {
+				b.handlers = append(b.handlers[:i:i], b.handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers an event to all subscribers, stamping its timestamp and
+// schema version if needed
+func (b *Bus) Publish(e Event) {
+	if e.SchemaVersion == 0 {
+		e.SchemaVersion = SchemaVersion
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if e.Timestamp.IsZero() {
+		e.Timestamp = b.clock.Now().UTC()
+	}
+
+	for _, sub := range b.handlers {
+		sub.handler(e)
+	}
+}
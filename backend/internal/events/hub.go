@@ -0,0 +1,284 @@
+package events
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Slow consumer policies applied when a client's queue is full
+const (
+	PolicyDrop       = "drop"       // Drop the event and count it against the client
+	PolicyDisconnect = "disconnect" // Disconnect the client
+)
+
+// latencySamples is how many recent fan-out latencies are kept for percentiles
+const latencySamples = 1024
+
+// HubConfig controls per-client queues and what happens when they fill up
+type HubConfig struct {
+	InboundSize int    // Events buffered between publishers and the fan-out loop
+	QueueSize   int    // Events buffered per client
+	Policy      string // PolicyDrop or PolicyDisconnect
+}
+
+// DefaultHubConfig buffers 256 events per client and drops on overflow
+func DefaultHubConfig() HubConfig {
+	return HubConfig{
+		InboundSize: 4096,
+		QueueSize:   256,
+		Policy:      PolicyDrop,
+	}
+}
+
+// HubStats reports fan-out health
+type HubStats struct {
+	Clients          int     `json:"clients"`
+	Policy           string  `json:"policy"`
+	QueueSize        int     `json:"queue_size"`
+	Published        int64   `json:"published"`
+	InboundDropped   int64   `json:"inbound_dropped"`
+	ClientDropped    int64   `json:"client_dropped"`
+	SlowDisconnects  int64   `json:"slow_disconnects"`
+	LaggingClients   int     `json:"lagging_clients"`
+	FanoutLatencyP50 float64 `json:"fanout_latency_p50_ms"`
+	FanoutLatencyP99 float64 `json:"fanout_latency_p99_ms"`
+	FanoutLatencyMax float64 `json:"fanout_latency_max_ms"`
+}
+
+type queuedEvent struct {
+	event    Event
+	queuedAt time.Time
+}
+
+// Hub fans events out to many slow, independent consumers (e.g. WebSocket
+// connections). Publishers only pay for a non-blocking enqueue; a single
+// loop copies each event into bounded per-client queues, so one stalled
+// client can never hold up the bus or the other clients.
+type Hub struct {
+	mu      sync.RWMutex
+	config  HubConfig
+	clients map[*HubClient]struct{}
+	inbound chan queuedEvent
+	done    chan struct{}
+	once    sync.Once
+
+	published       atomic.Int64
+	inboundDropped  atomic.Int64
+	clientDropped   atomic.Int64
+	slowDisconnects atomic.Int64
+
+	latencyMu sync.Mutex
+	latencies []time.Duration // ring of recent fan-out latencies
+	next      int
+}
+
+// NewHub starts a hub; call Close to stop its fan-out loop
+func NewHub(config HubConfig) *Hub {
+	defaults := DefaultHubConfig()
+	if config.InboundSize <= 0 {
+		config.InboundSize = defaults.InboundSize
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaults.QueueSize
+	}
+	if config.Policy == "" {
+		config.Policy = defaults.Policy
+	}
+
+	h := &Hub{
+		config:    config,
+		clients:   make(map[*HubClient]struct{}),
+		inbound:   make(chan queuedEvent, config.InboundSize),
+		done:      make(chan struct{}),
+		latencies: make([]time.Duration, 0, latencySamples),
+	}
+	go h.run()
+	return h
+}
+
+// Handle enqueues an event's public view for fan-out; it is meant to be
+// subscribed to a Bus
+func (h *Hub) Handle(e Event) {
+	e, ok := e.Public()
+	if !ok {
+		return
+	}
+	select {
+	case h.inbound <- queuedEvent{event: e, queuedAt: time.Now()}:
+		h.published.Add(1)
+	default:
+		h.inboundDropped.Add(1)
+	}
+}
+
+func (h *Hub) run() {
+	for {
+		select {
+		case <-h.done:
+			return
+		case q := <-h.inbound:
+			h.fanOut(q.event)
+			h.recordLatency(time.Since(q.queuedAt))
+		}
+	}
+}
+
+func (h *Hub) fanOut(e Event) {
+	h.mu.RLock()
+	policy := h.config.Policy
+	var slow []*HubClient
+	for c := range h.clients {
+		select {
+		case c.events <- e:
+		default:
+			h.clientDropped.Add(1)
+			c.dropped.Add(1)
+			if policy == PolicyDisconnect {
+				slow = append(slow, c)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range slow {
+		h.slowDisconnects.Add(1)
+		c.disconnect()
+	}
+}
+
+func (h *Hub) recordLatency(d time.Duration) {
+	h.latencyMu.Lock()
+	defer h.latencyMu.Unlock()
+
+	if len(h.latencies) < latencySamples {
+		h.latencies = append(h.latencies, d)
+		return
+	}
+	h.latencies[h.next] = d
+	h.next = (h.next + 1) % latencySamples
+}
+
+// Join registers a new client with its own bounded queue
+func (h *Hub) Join() *HubClient {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c := &HubClient{
+		hub:    h,
+		events: make(chan Event, h.config.QueueSize),
+		done:   make(chan struct{}),
+	}
+	h.clients[c] = struct{}{}
+	return c
+}
+
+// Configure changes the queue size for new clients and the overflow policy for all
+func (h *Hub) Configure(config HubConfig) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if config.QueueSize > 0 {
+		h.config.QueueSize = config.QueueSize
+	}
+	if config.Policy != "" {
+		h.config.Policy = config.Policy
+	}
+}
+
+// Stats returns counters and recent fan-out latency percentiles
+func (h *Hub) Stats() HubStats {
+	h.mu.RLock()
+	stats := HubStats{
+		Clients:   len(h.clients),
+		Policy:    h.config.Policy,
+		QueueSize: h.config.QueueSize,
+	}
+	for c := range h.clients {
+		if c.dropped.Load() > 0 {
+			stats.LaggingClients++
+		}
+	}
+	h.mu.RUnlock()
+
+	stats.Published = h.published.Load()
+	stats.InboundDropped = h.inboundDropped.Load()
+	stats.ClientDropped = h.clientDropped.Load()
+	stats.SlowDisconnects = h.slowDisconnects.Load()
+
+	h.latencyMu.Lock()
+	samples := slices.Clone(h.latencies)
+	h.latencyMu.Unlock()
+
+	if len(samples) > 0 {
+		slices.Sort(samples)
+		ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+		stats.FanoutLatencyP50 = ms(samples[len(samples)*50/100])
+		stats.FanoutLatencyP99 = ms(samples[len(samples)*99/100])
+		stats.FanoutLatencyMax = ms(samples[len(samples)-1])
+	}
+	return stats
+}
+
+// Close stops the fan-out loop and disconnects every client
+func (h *Hub) Close() {
+	h.once.Do(func() {
+		close(h.done)
+
+		h.mu.RLock()
+		clients := make([]*HubClient, 0, len(h.clients))
+		for c := range h.clients {
+			clients = append(clients, c)
+		}
+		h.mu.RUnlock()
+
+		for _, c := range clients {
+			c.disconnect()
+		}
+	})
+}
+
+// HubClient is one consumer's view of the hub
+type HubClient struct {
+	hub     *Hub
+	events  chan Event
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Int64
+	noticed int64 // dropped count already reported by TakeDropped
+}
+
+// Events delivers the client's queued events
+func (c *HubClient) Events() <-chan Event {
+	return c.events
+}
+
+// Done is closed when the hub disconnects the client as a slow consumer or shuts down
+func (c *HubClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// TakeDropped returns how many events were dropped since the last call, so
+// the consumer can tell its peer it missed updates. It must only be called
+// from the goroutine reading Events.
+func (c *HubClient) TakeDropped() int64 {
+	total := c.dropped.Load()
+	n := total - c.noticed
+	c.noticed = total
+	return n
+}
+
+// Leave unregisters the client
+func (c *HubClient) Leave() {
+	c.disconnect()
+}
+
+func (c *HubClient) disconnect() {
+	c.once.Do(func() {
+		c.hub.mu.Lock()
+		delete(c.hub.clients, c)
+		c.hub.mu.Unlock()
+		close(c.done)
+	})
+}
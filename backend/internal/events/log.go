@@ -0,0 +1,94 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// FileLog appends events to a JSON Lines file
+type FileLog struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// OpenFileLog opens (or creates) an append-only event log at path
+func OpenFileLog(path string) (*FileLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+
+	return &FileLog{
+		file: file,
+		enc:  json.NewEncoder(file),
+	}, nil
+}
+
+// Append writes a single event to the log
+func (l *FileLog) Append(e Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(e)
+}
+
+// Handler returns a bus handler that appends every event, logging write failures
+func (l *FileLog) Handler() Handler {
+	return func(e Event) {
+		if err := l.Append(e); err != nil {
+			log.Printf("Failed to append event: %v", err)
+		}
+	}
+}
+
+// Flush commits appended events to stable storage
+func (l *FileLog) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Sync()
+}
+
+// Close flushes and closes the underlying file
+func (l *FileLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.file.Sync(); err != nil {
+		l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
+
+// ReadLog decodes events from r in order and passes each one to fn. Events
+// written before schema versioning are read as version 1; newer versions
+// than SchemaVersion are rejected.
+func ReadLog(r io.Reader, fn func(Event) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := checkVersion(&e); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
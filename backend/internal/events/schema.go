@@ -0,0 +1,38 @@
+package events
+
+import (
+	"embed"
+	"fmt"
+)
+
+// SchemaVersion is the event payload schema this build writes.
+//
+// Compatibility policy: adding optional fields (e.g. tier or delta) keeps the
+// version, so consumers must ignore fields they don't know. Removing or
+// renaming a field, or changing its type or meaning, bumps the version; the
+// previous version's schema stays published so consumers can migrate.
+const SchemaVersion = 1
+
+//go:embed schema/*.json
+var schemas embed.FS
+
+// Schema returns the JSON Schema for an event payload version
+func Schema(version int) ([]byte, error) {
+	data, err := schemas.ReadFile(fmt.Sprintf("schema/v%d.json", version))
+	if err != nil {
+		return nil, fmt.Errorf("unknown event schema version %d", version)
+	}
+	return data, nil
+}
+
+// checkVersion normalises events written before versioning and rejects
+// versions newer than this build understands
+func checkVersion(e *Event) error {
+	if e.SchemaVersion == 0 {
+		e.SchemaVersion = 1
+	}
+	if e.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("event schema version %d is newer than supported version %d", e.SchemaVersion, SchemaVersion)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"backend/internal/services"
+)
+
+// respondApprovalError maps approval failures to API errors
+func respondApprovalError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrPendingChangeNotFound):
+		writeError(w, http.StatusNotFound, "pending_change_not_found", "No pending change with this ID")
+	case errors.Is(err, services.ErrSelfApproval):
+		writeError(w, http.StatusForbidden, "self_approval", "Changes must be approved by someone other than their requester")
+	default:
+		respondModerationError(w, err)
+	}
+}
+
+// ListPendingChanges lists score adjustments awaiting a second approver
+// GET /api/admin/pending-changes
+func (h *LeaderboardHandler) ListPendingChanges(w http.ResponseWriter, r *http.Request) {
+	changes := h.service.ListPendingChanges(r.Context())
+	writeJSON(w, http.StatusOK, H{
+		"changes": changes,
+		"count":   len(changes),
+	})
+}
+
+// ApproveChange applies a pending score adjustment
+// POST /api/admin/pending-changes/{id}/approve
+func (h *LeaderboardHandler) ApproveChange(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.ApproveChange(r.Context(), pathParam(r, "id"), actor(r))
+	if err != nil {
+		respondApprovalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// RejectChange discards a pending score adjustment
+// DELETE /api/admin/pending-changes/{id}
+func (h *LeaderboardHandler) RejectChange(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.RejectChange(r.Context(), pathParam(r, "id"), actor(r)); err != nil {
+		respondApprovalError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
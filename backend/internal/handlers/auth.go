@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/services"
+)
+
+const stateCookie = "oauth_state"
+
+// EnableAuth turns on social login, token issuance and role checks
+func (h *LeaderboardHandler) EnableAuth(a *auth.Auth) {
+	a.UseRoles(func(username string) []string {
+		return h.service.Roles(services.Principal(services.PrincipalUser, username))
+	})
+	a.OnAlert(func(detail string) {
+		log.Printf("🚨 %s", detail)
+		h.service.RecordIncident("auth", services.IncidentAlert, detail)
+	})
+	h.service.SetSessionRevoker(a)
+	h.auth = a
+}
+
+// loginProvider returns the provider named in the path, answering 404 if
+// auth or the provider isn't enabled
+func (h *LeaderboardHandler) loginProvider(w http.ResponseWriter, r *http.Request) (auth.Provider, bool) {
+	if !h.requireAuth(w) {
+		return auth.Provider{}, false
+	}
+	provider, ok := h.auth.Provider(pathParam(r, "provider"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown_provider", "Login provider is not configured")
+		return auth.Provider{}, false
+	}
+	return provider, true
+}
+
+// Login redirects to the provider's consent screen
+// GET /api/auth/{provider}/login
+func (h *LeaderboardHandler) Login(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.loginProvider(w, r)
+	if !ok {
+		return
+	}
+
+	state, err := h.auth.NewState(provider.Name)
+	if err != nil {
+		writeFailure(w, "login_failed", err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    state,
+		Path:     "/api/auth",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, provider.AuthCodeURL(state), http.StatusFound)
+}
+
+// LoginCallback finishes a login: the user linked to the provider identity
+// (created on first login) receives an access token
+// GET /api/auth/{provider}/callback
+func (h *LeaderboardHandler) LoginCallback(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.loginProvider(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	if denied := query.Get("error"); denied != "" {
+		writeError(w, http.StatusBadRequest, "login_denied", "Provider returned "+denied)
+		return
+	}
+
+	if !h.checkLockout(w, r, "") {
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(stateCookie)
+	if err != nil || cookie.Value != state || h.auth.CheckState(provider.Name, state) != nil {
+		h.recordAuthFailure(r, "", auth.ErrInvalidState)
+		writeError(w, http.StatusBadRequest, "invalid_state", "Login session expired or was started elsewhere, try again")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: stateCookie, Path: "/api/auth", MaxAge: -1})
+
+	identity, err := provider.Authenticate(r.Context(), query.Get("code"))
+	if err != nil {
+		log.Printf("Login with %s failed: %v", provider.Name, err)
+		writeError(w, http.StatusBadGateway, "provider_error", "Could not verify the login with the provider")
+		return
+	}
+
+	username, created, err := h.service.LoginWithIdentity(r.Context(), identity.Provider, identity.Subject, identity.Name)
+	if err != nil {
+		writeFailure(w, "login_failed", err)
+		return
+	}
+
+	h.respondLogin(w, r, username, created)
+}
+
+// respondLogin starts a session for username, answering with its tokens either
+// as JSON or by redirecting to the configured frontend with them in the fragment
+func (h *LeaderboardHandler) respondLogin(w http.ResponseWriter, r *http.Request, username string, created bool) {
+	tokens, err := h.auth.StartSession(username)
+	if err != nil {
+		writeFailure(w, "login_failed", err)
+		return
+	}
+
+	if redirect := h.auth.SuccessRedirect(); redirect != "" {
+		fragment := url.Values{
+			"access_token":  {tokens.AccessToken},
+			"token_type":    {"Bearer"},
+			"expires_in":    {strconv.Itoa(int(time.Until(tokens.AccessExpiresAt).Seconds()))},
+			"refresh_token": {tokens.RefreshToken},
+			"username":      {username},
+		}
+		http.Redirect(w, r, redirect+"#"+fragment.Encode(), http.StatusFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, loginResponse(tokens, created))
+}
+
+func loginResponse(tokens *auth.Tokens, created bool) models.LoginResponse {
+	return models.LoginResponse{
+		AccessToken:      tokens.AccessToken,
+		TokenType:        "Bearer",
+		ExpiresAt:        tokens.AccessExpiresAt,
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+		Username:         tokens.Username,
+		Created:          created,
+	}
+}
+
+// requireAuth answers 404 when auth is disabled
+func (h *LeaderboardHandler) requireAuth(w http.ResponseWriter) bool {
+	if h.auth == nil {
+		writeError(w, http.StatusNotFound, "auth_disabled", "Set AUTH_JWT_SECRET to enable login")
+		return false
+	}
+	return true
+}
+
+// RefreshToken exchanges a refresh token for a new access token. The refresh
+// token is rotated; reusing an old one revokes the session.
+// POST /api/auth/refresh
+func (h *LeaderboardHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAuth(w) {
+		return
+	}
+
+	var req models.RefreshRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	owner := h.auth.RefreshTokenOwner(req.RefreshToken)
+	if !h.checkLockout(w, r, owner) {
+		return
+	}
+
+	tokens, err := h.auth.Refresh(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			h.recordAuthFailure(r, owner, err)
+			writeError(w, http.StatusUnauthorized, "invalid_refresh_token", "Refresh token is invalid, expired or revoked, log in again")
+			return
+		}
+		writeFailure(w, "refresh_failed", err)
+		return
+	}
+
+	h.auth.RecordSuccess(owner)
+	writeJSON(w, http.StatusOK, loginResponse(tokens, false))
+}
+
+// Logout ends the session a refresh token belongs to; its access tokens stop
+// working immediately
+// POST /api/auth/logout
+func (h *LeaderboardHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAuth(w) {
+		return
+	}
+
+	var req models.RefreshRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	owner := h.auth.RefreshTokenOwner(req.RefreshToken)
+	if !h.checkLockout(w, r, owner) {
+		return
+	}
+
+	if err := h.auth.Logout(req.RefreshToken); err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			h.recordAuthFailure(r, owner, err)
+			writeError(w, http.StatusUnauthorized, "invalid_refresh_token", "Refresh token is invalid, expired or revoked")
+			return
+		}
+		writeFailure(w, "logout_failed", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSessions lists a user's active sessions
+// GET /api/admin/users/{username}/sessions
+func (h *LeaderboardHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAuth(w) {
+		return
+	}
+
+	username := pathParam(r, "username")
+	sessions := h.auth.ListSessions(username)
+	writeJSON(w, http.StatusOK, H{
+		"username": username,
+		"sessions": sessions,
+		"count":    len(sessions),
+	})
+}
+
+// RevokeSessions ends every session of a user, e.g. after a ban
+// DELETE /api/admin/users/{username}/sessions
+func (h *LeaderboardHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAuth(w) {
+		return
+	}
+
+	username := pathParam(r, "username")
+	revoked := h.auth.RevokeUser(username)
+	log.Printf("Revoked %d session(s) of %s", revoked, username)
+	writeJSON(w, http.StatusOK, H{
+		"username": username,
+		"revoked":  revoked,
+	})
+}
+
+// bearerClaims verifies the request's bearer token
+func (h *LeaderboardHandler) bearerClaims(r *http.Request) (*auth.Claims, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, errMissingCredentials
+	}
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return nil, auth.ErrInvalidToken
+	}
+	return h.auth.VerifyToken(token)
+}
+
+// Me returns the authenticated user
+// GET /api/auth/me
+func (h *LeaderboardHandler) Me(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.me(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{
+		"username":   claims.Subject,
+		"roles":      h.service.Roles(services.Principal(services.PrincipalUser, claims.Subject)),
+		"privacy":    h.service.Privacy(claims.Subject),
+		"expires_at": time.Unix(claims.ExpiresAt, 0).UTC(),
+	})
+}
+
+// me authenticates the user behind a /api/auth/me request by their access
+// token, answering 401 if it's missing or invalid
+func (h *LeaderboardHandler) me(w http.ResponseWriter, r *http.Request) (*auth.Claims, bool) {
+	if !h.requireAuth(w) || !h.checkLockout(w, r, "") {
+		return nil, false
+	}
+
+	claims, err := h.bearerClaims(r)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			h.recordAuthFailure(r, "", err)
+			writeError(w, http.StatusUnauthorized, "unauthorized", "Missing, invalid or expired access token")
+			return nil, false
+		}
+		writeFailure(w, "auth_failed", err)
+		return nil, false
+	}
+	return claims, true
+}
+
+// viewing marks r as made by the user its bearer token names, if it has a
+// valid one, so they can look themselves up whatever their privacy settings
+func (h *LeaderboardHandler) viewing(r *http.Request) context.Context {
+	if h.auth == nil {
+		return r.Context()
+	}
+	claims, err := h.bearerClaims(r)
+	if err != nil {
+		return r.Context()
+	}
+	return services.WithViewer(r.Context(), claims.Subject)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"backend/internal/models"
+	"backend/internal/services"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxBoardConfigBytes bounds an uploaded board configuration document
+const maxBoardConfigBytes = 1 << 20
+
+// GetResolvedConfig returns the server's configuration with defaults filled
+// in and secrets redacted, as JSON or YAML like ExportBoardConfig
+// GET /api/admin/config
+func (h *LeaderboardHandler) GetResolvedConfig(w http.ResponseWriter, r *http.Request) {
+	config, err := h.service.ResolvedConfig(r.Context())
+	if errors.Is(err, services.ErrConfigUnavailable) {
+		writeError(w, http.StatusNotFound, "config_unavailable", "This server did not record its configuration")
+		return
+	}
+	if err != nil {
+		writeFailure(w, "config_failed", err)
+		return
+	}
+	if !wantsYAML(r) {
+		writeJSON(w, http.StatusOK, config)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(config); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+	enc.Close()
+}
+
+// ExportBoardConfig returns the board's definition as JSON, or YAML with
+// ?format=yaml or Accept: application/yaml
+// GET /api/admin/config/boards
+func (h *LeaderboardHandler) ExportBoardConfig(w http.ResponseWriter, r *http.Request) {
+	doc := h.service.ExportBoardConfig(r.Context())
+	if !wantsYAML(r) {
+		writeJSON(w, http.StatusOK, doc)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+	enc.Close()
+}
+
+// ApplyBoardConfig applies a board definition sent as JSON, or as YAML with
+// Content-Type: application/yaml. ?dry_run=true lists the changes without
+// applying them.
+// PUT /api/admin/config/boards
+func (h *LeaderboardHandler) ApplyBoardConfig(w http.ResponseWriter, r *http.Request) {
+	dryRun, dryRunErr := queryBool(r, "dry_run")
+	if dryRunErr != nil {
+		respondFieldErrors(w, *dryRunErr)
+		return
+	}
+
+	var doc models.BoardConfig
+	if err := decodeBoardConfig(w, r, &doc); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	result, err := h.service.ApplyBoardConfig(r.Context(), doc, dryRun)
+	if err != nil {
+		var fieldErrs models.FieldErrors
+		var fieldErr models.FieldError
+		switch {
+		case errors.As(err, &fieldErrs):
+			respondFieldErrors(w, fieldErrs...)
+		case errors.As(err, &fieldErr):
+			respondFieldErrors(w, fieldErr)
+		case errors.Is(err, services.ErrSeasonActive):
+			writeError(w, http.StatusConflict, "season_active", "Close the running season before applying a different one")
+		default:
+			writeFailure(w, "config_failed", err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// decodeBoardConfig reads a JSON or YAML document, rejecting unknown fields
+// so a typo doesn't silently leave a setting unchanged
+func decodeBoardConfig(w http.ResponseWriter, r *http.Request, doc *models.BoardConfig) error {
+	body := http.MaxBytesReader(w, r.Body, maxBoardConfigBytes)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var err error
+	if isYAML(mediaType) {
+		dec := yaml.NewDecoder(body)
+		dec.KnownFields(true)
+		err = dec.Decode(doc)
+	} else {
+		dec := json.NewDecoder(body)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(doc)
+	}
+	if errors.Is(err, io.EOF) {
+		return errors.New("request body is required")
+	}
+	return err
+}
+
+func wantsYAML(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "yaml"
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(accept))
+		if isYAML(mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+func isYAML(mediaType string) bool {
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml":
+		return true
+	}
+	return false
+}
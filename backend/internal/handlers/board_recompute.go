@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"backend/internal/services"
+)
+
+// respondRecomputeError maps board recompute failures to API errors
+func respondRecomputeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrBoardNotKept):
+		writeError(w, http.StatusNotFound, "board_not_kept", "Board is not a daily, country or team board this server keeps")
+	case errors.Is(err, services.ErrRecomputeRunning):
+		writeError(w, http.StatusConflict, "recompute_running", "A board recompute is already running")
+	case errors.Is(err, services.ErrTiersNotConfigured):
+		writeError(w, http.StatusNotFound, "tiers_not_configured", "Set TIER_BOUNDARIES to configure tiers")
+	case errors.Is(err, services.ErrRecomputeJobNotFound):
+		writeError(w, http.StatusNotFound, "recompute_job_not_found", "Recompute job does not exist or has been forgotten")
+	default:
+		writeBoardsError(w, err)
+	}
+}
+
+// RecomputeBoard starts rebuilding a derived board, or the tier populations,
+// from the main board and score history, as a background job
+// POST /api/admin/boards/{id}/recompute
+func (h *LeaderboardHandler) RecomputeBoard(w http.ResponseWriter, r *http.Request) {
+	job, err := h.service.StartBoardRecompute(pathParam(r, "id"))
+	if err != nil {
+		respondRecomputeError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/api/admin/boards/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// GetBoardRecomputeJob reports a recompute job's progress
+// GET /api/admin/boards/jobs/{id}
+func (h *LeaderboardHandler) GetBoardRecomputeJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.service.BoardRecomputeJob(pathParam(r, "id"))
+	if err != nil {
+		respondRecomputeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+
+	"backend/internal/services"
+)
+
+// ListDerivedBoards lists the daily, country and team boards with members
+// GET /api/boards
+func (h *LeaderboardHandler) ListDerivedBoards(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.ListDerivedBoards(r.Context())
+	if err != nil {
+		writeBoardsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+// GetDerivedBoard retrieves a page of a derived board
+// GET /api/boards/{id}?page=1&limit=50
+func (h *LeaderboardHandler) GetDerivedBoard(w http.ResponseWriter, r *http.Request) {
+	page, pageErr := queryInt(r, "page", 1, 1, math.MaxInt32)
+	limit, limitErr := queryInt(r, "limit", 50, 1, 100)
+	if details := collectFieldErrors(pageErr, limitErr); len(details) > 0 {
+		respondFieldErrors(w, details...)
+		return
+	}
+
+	board, err := h.service.GetDerivedBoard(r.Context(), pathParam(r, "id"), page, limit)
+	if err != nil {
+		writeBoardsError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, board)
+}
+
+func writeBoardsError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrBoardsDisabled):
+		writeError(w, http.StatusNotFound, "boards_disabled", "Set DERIVED_BOARDS to keep daily, country and team boards")
+	case errors.Is(err, services.ErrDerivedBoardNotFound):
+		writeError(w, http.StatusNotFound, "board_not_found", "Board does not exist or has no members")
+	default:
+		writeFailure(w, "boards_failed", err)
+	}
+}
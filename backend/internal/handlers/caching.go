@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache classes group routes whose responses can be reused for as long as
+// each other. Routes outside them send no caching headers of their own.
+const (
+	CacheLeaderboard = "leaderboard" // Leaderboard pages, board metadata, search and exports
+	CacheStats       = "stats"       // Statistics, reports and capabilities
+	CacheUsers       = "users"       // A single user's rank, history and identities
+	// cachePrivate responses depend on who asks, so they are never stored;
+	// it isn't configurable
+	cachePrivate = "private"
+)
+
+// CacheClasses lists the cache classes a CachePolicy can set
+var CacheClasses = []string{CacheLeaderboard, CacheStats, CacheUsers}
+
+// CachePolicy sets how long CDNs and browsers may reuse successful responses
+// in each cache class. A class set to 0 is sent with no-store.
+type CachePolicy map[string]time.Duration
+
+// DefaultCachePolicy keeps leaderboard pages for 2s and stats for 30s, and
+// stores no user's data
+func DefaultCachePolicy() CachePolicy {
+	return CachePolicy{
+		CacheLeaderboard: 2 * time.Second,
+		CacheStats:       30 * time.Second,
+		CacheUsers:       0,
+	}
+}
+
+// Validate reports unknown classes and negative ages
+func (p CachePolicy) Validate() error {
+	for class, age := range p {
+		if !slices.Contains(CacheClasses, class) {
+			return fmt.Errorf("cache policy: unknown class %q (available: %s)", class, strings.Join(CacheClasses, ", "))
+		}
+		if age < 0 {
+			return fmt.Errorf("cache policy: %s max age %s must not be negative", class, age)
+		}
+	}
+	return nil
+}
+
+// cacheHeaders holds the policy routes are served with
+type cacheHeaders struct {
+	mu     sync.RWMutex
+	policy CachePolicy
+}
+
+// SetCachePolicy changes the max age of the classes in policy; classes it
+// leaves out keep their current age
+func (h *LeaderboardHandler) SetCachePolicy(policy CachePolicy) {
+	h.caching.mu.Lock()
+	defer h.caching.mu.Unlock()
+	for class, age := range policy {
+		h.caching.policy[class] = age
+	}
+}
+
+// maxAge returns how long responses in class may be reused, 0 for none,
+// including private responses
+func (c *cacheHeaders) maxAge(class string) time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.policy[class]
+}
+
+// cacheControl wraps every GET route in a cache class so its responses say
+// how long they may be reused. Routes that set Cache-Control themselves,
+// such as the embed widget and feeds, keep their own, but like every other
+// response leave out the caller's headers once it is public.
+func (h *LeaderboardHandler) cacheControl(routes []Route) []Route {
+	for i, route := range routes {
+		switch class := cacheClass(route); {
+		case class != "":
+			routes[i].Handler = h.cacheable(class, route.Handler)
+		case route.Path != "/api/ws":
+			routes[i].Handler = ownCaching(route.Handler)
+		}
+	}
+	return routes
+}
+
+// ownCaching leaves a route's caching headers to the route itself
+func ownCaching(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(&cacheWriter{ResponseWriter: w, own: true}, r)
+	}
+}
+
+func (h *LeaderboardHandler) cacheable(class string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		maxAge := h.caching.maxAge(class)
+		// Whatever a caller's credentials let them see is theirs alone
+		if r.Header.Get("Authorization") != "" || r.Header.Get("X-API-Key") != "" {
+			maxAge = 0
+		}
+		next(&cacheWriter{ResponseWriter: w, maxAge: maxAge}, r)
+	}
+}
+
+// cacheClass names the cache class of a route, or "" for routes that send
+// no caching headers, such as writes and WebSockets
+func cacheClass(route Route) string {
+	if route.Method != http.MethodGet {
+		return ""
+	}
+	switch path := route.Path; {
+	case path == "/api/ws", strings.HasPrefix(path, "/embed/"), strings.HasPrefix(path, "/feeds/"):
+		// WebSockets can't be wrapped, and widgets and feeds set their own
+		return ""
+	case path == "/api/leaderboard", strings.HasPrefix(path, "/api/leaderboards/"), path == "/api/search",
+		path == "/api/users", path == "/api/export", path == "/api/simulation/status", strings.HasPrefix(path, "/api/boards"):
+		return CacheLeaderboard
+	case strings.HasPrefix(path, "/api/stats"), strings.HasPrefix(path, "/api/reports/"),
+		path == "/api/capabilities", strings.HasPrefix(path, "/api/events/schema/"):
+		return CacheStats
+	case path == "/api/users/{username}", path == "/api/users/{username}/history",
+		path == "/api/users/{username}/identities", path == "/api/identities/{provider}/{external_id}":
+		return CacheUsers
+	}
+	return cachePrivate
+}
+
+// perCallerHeaders describe the caller rather than the resource, so they are
+// left out of responses a shared cache may hand to everyone
+var perCallerHeaders = []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Set-Cookie"}
+
+// cacheWriter sets Cache-Control and Surrogate-Control as the response
+// starts, once its status is known. Only successes are reused; errors and
+// everything else are sent with no-store, so a CDN doesn't pin a transient
+// 404 or 429. Every response varies on the credentials that turn caching
+// off, so a cache never answers a caller who sent them with a public copy.
+// Whoever set them, public responses go without perCallerHeaders.
+type cacheWriter struct {
+	http.ResponseWriter
+	maxAge      time.Duration
+	own         bool // The route sets its own caching headers, if any
+	wroteHeader bool
+}
+
+func (cw *cacheWriter) WriteHeader(status int) {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+		header := cw.Header()
+		if !cw.own {
+			header.Add("Vary", "Authorization, X-API-Key")
+		}
+		if !cw.own && header.Get("Cache-Control") == "" {
+			seconds := int(cw.maxAge / time.Second)
+			if seconds > 0 && (status == http.StatusOK || status == http.StatusNotModified) {
+				header.Set("Cache-Control", "public, max-age="+strconv.Itoa(seconds))
+				header.Set("Surrogate-Control", "max-age="+strconv.Itoa(seconds))
+			} else {
+				header.Set("Cache-Control", "no-store")
+			}
+		}
+		if strings.HasPrefix(header.Get("Cache-Control"), "public") {
+			for _, key := range perCallerHeaders {
+				header.Del(key)
+			}
+		}
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *cacheWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming endpoints, such as exports, streaming
+func (cw *cacheWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *cacheWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
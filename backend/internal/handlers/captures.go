@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+)
+
+const (
+	maxCaptures         = 200      // Captures kept in the ring buffer
+	maxCaptureBodyBytes = 64 << 10 // Request and response bodies are cut off here
+)
+
+// redactedHeaders never leave the server in a capture
+var redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Signature"}
+
+// redactedFields are JSON fields and query parameters whose names contain one
+// of these words
+var redactedFields = []string{"token", "secret", "password", "signature"}
+
+// redactedParams are query parameters carrying OAuth credentials
+var redactedParams = []string{"code", "state"}
+
+const redacted = "[REDACTED]"
+
+// captureSampler records a sample of requests to one route, with bodies,
+// for debugging client integrations
+type captureSampler struct {
+	mu       sync.Mutex
+	config   *models.CaptureConfig // nil while capturing is off
+	captures []models.Capture      // ring buffer, oldest first once full
+	next     int
+	nextID   int64
+}
+
+func newCaptureSampler() *captureSampler {
+	return &captureSampler{}
+}
+
+// instrument wraps every route so it can be sampled
+func (c *captureSampler) instrument(routes []Route) []Route {
+	for i, route := range routes {
+		routes[i].Handler = c.wrap(route.Method+" "+route.Path, route.Handler)
+	}
+	return routes
+}
+
+// sample reports whether this request to route should be captured
+func (c *captureSampler) sample(route string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cfg := c.config
+	if cfg == nil || cfg.Route != route {
+		return false
+	}
+	if time.Now().After(cfg.ExpiresAt) {
+		c.config = nil
+		return false
+	}
+	return rand.Float64()*100 < cfg.SamplePercent
+}
+
+func (c *captureSampler) wrap(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// WebSocket upgrades hijack the connection and can't be recorded
+		if r.Header.Get("Upgrade") != "" || !c.sample(route) {
+			next(w, r)
+			return
+		}
+
+		requestBody, requestTruncated := readCaptureBody(r)
+		recorder := &captureRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		next(recorder, r)
+
+		c.record(models.Capture{
+			Route:           route,
+			Method:          r.Method,
+			URL:             redactURL(r.URL),
+			Status:          recorder.status,
+			DurationMs:      float64(time.Since(start)) / float64(time.Millisecond),
+			RequestHeaders:  redactHeaders(r.Header),
+			RequestBody:     redactBody(requestBody),
+			ResponseHeaders: redactHeaders(recorder.Header()),
+			ResponseBody:    redactBody(recorder.body.Bytes()),
+			Truncated:       requestTruncated || recorder.truncated,
+			At:              start.UTC(),
+		})
+	}
+}
+
+// readCaptureBody copies the start of the request body and puts the full body back
+func readCaptureBody(r *http.Request) ([]byte, bool) {
+	if r.Body == nil {
+		return nil, false
+	}
+	head, _ := io.ReadAll(io.LimitReader(r.Body, maxCaptureBodyBytes+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+
+	if len(head) > maxCaptureBodyBytes {
+		return head[:maxCaptureBodyBytes], true
+	}
+	return head, false
+}
+
+// configure starts capturing, replacing any previous setting
+func (c *captureSampler) configure(config models.CaptureConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config = &config
+}
+
+// stop turns capturing off and empties the buffer
+func (c *captureSampler) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config = nil
+	c.captures = nil
+	c.next = 0
+}
+
+// current returns the active setting, or nil
+func (c *captureSampler) current() *models.CaptureConfig {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.config == nil || time.Now().After(c.config.ExpiresAt) {
+		return nil
+	}
+	config := *c.config
+	return &config
+}
+
+func (c *captureSampler) record(capture models.Capture) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	capture.ID = c.nextID
+	if len(c.captures) < maxCaptures {
+		c.captures = append(c.captures, capture)
+		return
+	}
+	c.captures[c.next] = capture
+	c.next = (c.next + 1) % maxCaptures
+}
+
+// list returns up to limit captures, newest first
+func (c *captureSampler) list(limit int) []models.Capture {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := len(c.captures)
+	list := make([]models.Capture, 0, min(limit, n))
+	for i := 0; i < n && len(list) < limit; i++ {
+		// Walk back from the most recent write
+		list = append(list, c.captures[(c.next-1-i+2*n)%n])
+	}
+	return list
+}
+
+// captureRecorder tees the response into a bounded buffer
+type captureRecorder struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (rec *captureRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *captureRecorder) Write(b []byte) (int, error) {
+	if room := maxCaptureBodyBytes - rec.body.Len(); room > 0 {
+		rec.body.Write(b[:min(room, len(b))])
+		rec.truncated = rec.truncated || len(b) > room
+	} else {
+		rec.truncated = true
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming endpoints streaming while captured
+func (rec *captureRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rec *captureRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+func sensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range redactedFields {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactHeaders(header http.Header) map[string]string {
+	out := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		for _, secret := range redactedHeaders {
+			if strings.EqualFold(name, secret) {
+				value = redacted
+			}
+		}
+		// Login redirects carry tokens in the URL fragment
+		if strings.EqualFold(name, "Location") {
+			if target, _, ok := strings.Cut(value, "#"); ok {
+				value = target + "#" + redacted
+			}
+		}
+		out[name] = value
+	}
+	return out
+}
+
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	for name := range query {
+		if sensitive(name) || slices.Contains(redactedParams, name) {
+			query.Set(name, redacted)
+		}
+	}
+	if len(query) == 0 {
+		return u.Path
+	}
+	return u.Path + "?" + query.Encode()
+}
+
+// redactBody blanks sensitive fields of JSON bodies; other bodies are kept
+// as text. JSON Lines bodies (exports) are redacted line by line.
+func redactBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	lines := bytes.Split(body, []byte("\n"))
+	for i, line := range lines {
+		var v any
+		if json.Unmarshal(line, &v) != nil {
+			continue
+		}
+		if out, err := json.Marshal(redactValue(v)); err == nil {
+			lines[i] = out
+		}
+	}
+	return string(bytes.Join(lines, []byte("\n")))
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if sensitive(key) {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(value)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = redactValue(value)
+		}
+	}
+	return v
+}
+
+// ListCaptures returns recorded requests, newest first, and the active setting
+// GET /api/admin/captures?limit=50
+func (h *LeaderboardHandler) ListCaptures(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", 50, 1, maxCaptures)
+	if err != nil {
+		respondFieldErrors(w, *err)
+		return
+	}
+
+	captures := h.captures.list(limit)
+	writeJSON(w, http.StatusOK, H{
+		"config":   h.captures.current(),
+		"captures": captures,
+		"count":    len(captures),
+	})
+}
+
+// ConfigureCaptures starts sampling requests to one route
+// PUT /api/admin/captures
+func (h *LeaderboardHandler) ConfigureCaptures(w http.ResponseWriter, r *http.Request) {
+	var req models.CaptureRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	known := slices.ContainsFunc(h.routeTable(), func(route Route) bool {
+		return route.Method+" "+route.Path == req.Route
+	})
+	if !known {
+		writeError(w, http.StatusBadRequest, "unknown_route", `route must be a method and path pattern from the API, e.g. "POST /api/users/{username}/score"`)
+		return
+	}
+
+	duration := 15 * time.Minute
+	if req.DurationSeconds > 0 {
+		duration = time.Duration(req.DurationSeconds) * time.Second
+	}
+	config := models.CaptureConfig{
+		Route:         req.Route,
+		SamplePercent: req.SamplePercent,
+		ExpiresAt:     time.Now().Add(duration).UTC(),
+	}
+	h.captures.configure(config)
+
+	writeJSON(w, http.StatusOK, config)
+}
+
+// StopCaptures turns capturing off and discards recorded requests
+// DELETE /api/admin/captures
+func (h *LeaderboardHandler) StopCaptures(w http.ResponseWriter, r *http.Request) {
+	h.captures.stop()
+	w.WriteHeader(http.StatusNoContent)
+}
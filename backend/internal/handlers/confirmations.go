@@ -0,0 +1,19 @@
+package handlers
+
+import "net/http"
+
+// confirmed redeems the request's ?confirm token for operation on scope. It
+// answers 202 with a new token when there is none and 409 when it doesn't
+// redeem, returning false in both cases.
+func (h *LeaderboardHandler) confirmed(w http.ResponseWriter, r *http.Request, operation, scope string) bool {
+	token := r.URL.Query().Get("confirm")
+	if token == "" {
+		writeJSON(w, http.StatusAccepted, h.service.RequestConfirmation(operation, scope))
+		return false
+	}
+	if err := h.service.Confirm(token, operation, scope); err != nil {
+		writeError(w, http.StatusConflict, "confirmation_invalid", "Confirmation token is unknown, expired or for another operation; call again without confirm for a new one")
+		return false
+	}
+	return true
+}
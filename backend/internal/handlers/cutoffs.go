@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"backend/internal/services"
+)
+
+// GetCutoffs reports the rating it takes to hold each watched rank, and the
+// recent changes announced on the WebSocket
+// GET /api/leaderboards/{id}/cutoffs
+func (h *LeaderboardHandler) GetCutoffs(w http.ResponseWriter, r *http.Request) {
+	if pathParam(r, "id") != h.service.BoardID() {
+		writeError(w, http.StatusNotFound, "board_not_found", "Leaderboard does not exist")
+		return
+	}
+
+	cutoffs, err := h.service.Cutoffs(r.Context())
+	if errors.Is(err, services.ErrCutoffsNotConfigured) {
+		writeError(w, http.StatusNotFound, "cutoffs_not_configured", "Set CUTOFF_WATCH to watch cutoffs")
+		return
+	}
+	if err != nil {
+		writeFailure(w, "cutoffs_failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cutoffs)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"backend/internal/models"
+	"backend/internal/services"
+)
+
+// respondDeadLetterError maps dead letter failures to API errors
+func respondDeadLetterError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrDeadLetterNotFound):
+		writeError(w, http.StatusNotFound, "dead_letter_not_found", "No dead letter with this ID")
+	case errors.Is(err, services.ErrQueueClosed):
+		writeError(w, http.StatusServiceUnavailable, "queue_draining", "Score queue is draining, retry against another instance")
+	default:
+		writeFailure(w, "dead_letter_failed", err)
+	}
+}
+
+// ListDeadLetters lists failed webhook deliveries and dead-lettered submissions
+// GET /api/admin/dead-letters?kind=webhook
+func (h *LeaderboardHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	kind := r.URL.Query().Get("kind")
+	if kind != "" && kind != models.DeadLetterWebhook && kind != models.DeadLetterScore {
+		respondFieldErrors(w, models.FieldError{Field: "kind", Rule: "oneof", Param: "webhook score", Value: kind})
+		return
+	}
+
+	letters, err := h.service.ListDeadLetters(r.Context(), kind)
+	if err != nil {
+		respondDeadLetterError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, H{
+		"dead_letters": letters,
+		"count":        len(letters),
+	})
+}
+
+// GetDeadLetter returns a dead letter's payload and error history
+// GET /api/admin/dead-letters/{id}
+func (h *LeaderboardHandler) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	letter, err := h.service.GetDeadLetter(r.Context(), pathParam(r, "id"))
+	if err != nil {
+		respondDeadLetterError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, letter)
+}
+
+// RetryDeadLetter delivers a webhook again or requeues a submission
+// POST /api/admin/dead-letters/{id}/retry
+func (h *LeaderboardHandler) RetryDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.RetryDeadLetter(r.Context(), pathParam(r, "id")); err != nil {
+		respondDeadLetterError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// DiscardDeadLetter drops a dead letter for good
+// DELETE /api/admin/dead-letters/{id}
+func (h *LeaderboardHandler) DiscardDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DiscardDeadLetter(r.Context(), pathParam(r, "id")); err != nil {
+		respondDeadLetterError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RetryDeadLetters retries dead letters by ID or kind
+// POST /api/admin/dead-letters/retry
+func (h *LeaderboardHandler) RetryDeadLetters(w http.ResponseWriter, r *http.Request) {
+	h.bulkDeadLetters(w, r, h.service.RetryDeadLetters)
+}
+
+// DiscardDeadLetters discards dead letters by ID or kind
+// POST /api/admin/dead-letters/discard
+func (h *LeaderboardHandler) DiscardDeadLetters(w http.ResponseWriter, r *http.Request) {
+	h.bulkDeadLetters(w, r, h.service.DiscardDeadLetters)
+}
+
+func (h *LeaderboardHandler) bulkDeadLetters(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, ids []string, kind string) (*models.DeadLetterActionResponse, error)) {
+	var req models.DeadLetterActionRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	result, err := action(r.Context(), req.IDs, req.Kind)
+	if err != nil {
+		respondDeadLetterError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"backend/internal/services"
+)
+
+// streamTracker tracks long-lived streaming responses so shutdown can stop
+// accepting new ones and end the active ones cleanly
+type streamTracker struct {
+	mu       sync.Mutex
+	draining bool
+	nextID   int
+	active   map[int]context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func newStreamTracker() *streamTracker {
+	return &streamTracker{active: make(map[int]context.CancelFunc)}
+}
+
+// begin registers a stream. The returned request carries a context that is
+// cancelled when draining starts; ok is false once draining has begun.
+func (t *streamTracker) begin(r *http.Request) (*http.Request, func(), bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.draining {
+		return r, func() {}, false
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	id := t.nextID
+	t.nextID++
+	t.active[id] = cancel
+	t.wg.Add(1)
+
+	var once sync.Once
+	done := func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.active, id)
+			t.mu.Unlock()
+			cancel()
+			t.wg.Done()
+		})
+	}
+	return r.WithContext(ctx), done, true
+}
+
+// drain rejects new streams, asks active ones to finish and waits for them or ctx
+func (t *streamTracker) drain(ctx context.Context) error {
+	t.mu.Lock()
+	t.draining = true
+	for _, cancel := range t.active {
+		cancel()
+	}
+	t.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *streamTracker) isDraining() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.draining
+}
+
+// Drain marks the API unready, stops accepting streaming requests and ends
+// active streams, waiting until they finish or ctx expires
+func (h *LeaderboardHandler) Drain(ctx context.Context) error {
+	h.service.RecordIncident("server", services.IncidentLifecycle, "draining for shutdown")
+	return h.streams.drain(ctx)
+}
+
+// SetWarmingUp holds /readyz at 503 while a startup warm-up runs
+func (h *LeaderboardHandler) SetWarmingUp(warming bool) {
+	h.warming.Store(warming)
+}
+
+// Ready reports whether the instance should receive traffic
+// GET /readyz
+func (h *LeaderboardHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.streams.isDraining() {
+		writeJSON(w, http.StatusServiceUnavailable, H{"status": "draining"})
+		return
+	}
+	if h.warming.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, H{"status": "warming_up"})
+		return
+	}
+	writeJSON(w, http.StatusOK, H{"status": "ready"})
+}
+
+// beginStream registers a streaming response, answering 503 if the server is draining
+func (h *LeaderboardHandler) beginStream(w http.ResponseWriter, r *http.Request) (*http.Request, func(), bool) {
+	r, done, ok := h.streams.begin(r)
+	if !ok {
+		writeError(w, http.StatusServiceUnavailable, "draining", "Server is shutting down, retry against another instance")
+	}
+	return r, done, ok
+}
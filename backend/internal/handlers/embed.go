@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/services"
+)
+
+const (
+	embedCacheAge = time.Minute // How long CDNs and browsers may reuse a widget
+	embedWidth    = 320         // Pixels
+	embedRowPx    = 28          // Height of one standings row
+	embedHeaderPx = 44          // Height of the title row
+)
+
+// embedThemes are the widget's colour schemes
+var embedThemes = map[string]struct{ Background, Text, Muted, Border string }{
+	"light": {Background: "#ffffff", Text: "#1f2328", Muted: "#656d76", Border: "#d0d7de"},
+	"dark":  {Background: "#0d1117", Text: "#e6edf3", Muted: "#8d96a0", Border: "#30363d"},
+}
+
+// embedTemplate renders the widget with inline styles only, so it can be
+// dropped into any page without stylesheets or scripts
+var embedTemplate = template.Must(template.New("embed").Parse(`<div class="lb-embed" style="width:{{.Width}}px;font:14px/1.4 system-ui,sans-serif;background:{{.Theme.Background}};color:{{.Theme.Text}};border:1px solid {{.Theme.Border}};border-radius:8px;overflow:hidden">
+<div style="padding:10px 12px;font-weight:600;border-bottom:1px solid {{.Theme.Border}}">{{.Title}}</div>
+<ol style="list-style:none;margin:0;padding:0">
+{{- range .Entries}}
+<li style="display:flex;gap:8px;padding:4px 12px;border-bottom:1px solid {{$.Theme.Border}}"><span style="width:2.5em;color:{{$.Theme.Muted}}">#{{.Rank}}</span><span style="flex:1;overflow:hidden;text-overflow:ellipsis;white-space:nowrap">{{.Username}}</span><span style="font-variant-numeric:tabular-nums">{{.Rating}}</span></li>
+{{- else}}
+<li style="padding:4px 12px;color:{{.Theme.Muted}}">No ranked players yet</li>
+{{- end}}
+</ol>
+</div>
+`))
+
+// GetEmbed renders the top of the leaderboard as a self-contained HTML
+// snippet, or as oEmbed JSON with format=oembed
+// GET /embed/leaderboard?top=10&theme=dark&format=oembed
+func (h *LeaderboardHandler) GetEmbed(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	top, topErr := queryInt(r, "top", 10, 1, 100)
+	details := collectFieldErrors(topErr)
+	theme := query.Get("theme")
+	if theme == "" {
+		theme = "light"
+	}
+	if _, ok := embedThemes[theme]; !ok {
+		details = append(details, models.FieldError{Field: "theme", Rule: "oneof", Param: "light dark", Value: theme})
+	}
+	format := query.Get("format")
+	if format != "" && format != "html" && format != "oembed" {
+		details = append(details, models.FieldError{Field: "format", Rule: "oneof", Param: "html oembed", Value: format})
+	}
+	if len(details) > 0 {
+		respondFieldErrors(w, details...)
+		return
+	}
+
+	leaderboard, err := h.service.GetLeaderboard(r.Context(), 1, top, services.ListOptions{ExcludeBots: true})
+	if err != nil {
+		writeFailure(w, "fetch_failed", err)
+		return
+	}
+
+	title := "Top " + strconv.Itoa(top)
+	var snippet bytes.Buffer
+	if err := embedTemplate.Execute(&snippet, map[string]any{
+		"Title":   title,
+		"Width":   embedWidth,
+		"Theme":   embedThemes[theme],
+		"Entries": leaderboard.Entries,
+	}); err != nil {
+		writeFailure(w, "render_failed", err)
+		return
+	}
+
+	// Widgets are public and identical for every viewer, so any cache may
+	// keep them, and any site may fetch them
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(embedCacheAge.Seconds())))
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if format == "oembed" {
+		writeJSON(w, http.StatusOK, models.OEmbedResponse{
+			Version:      "1.0",
+			Type:         "rich",
+			Title:        title,
+			ProviderName: "Leaderboard",
+			HTML:         snippet.String(),
+			Width:        embedWidth,
+			Height:       embedHeaderPx + embedRowPx*max(len(leaderboard.Entries), 1),
+			CacheAge:     int(embedCacheAge.Seconds()),
+		})
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(snippet.Bytes())
+}
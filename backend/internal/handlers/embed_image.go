@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+
+	"backend/internal/models"
+	"backend/internal/services"
+)
+
+// Standings cards default to the 1200x630 size social networks preview
+const (
+	cardWidth    = 1200
+	cardHeight   = 630
+	cardMaxTop   = 20
+	cardMargin   = 0.06 // Of the card's height, around the edges
+	cardTitlePct = 0.18 // Of the card's height, for the title
+)
+
+// cardFonts are the Go fonts, compiled into the binary so cards render the
+// same on every host
+var cardFonts = sync.OnceValues(func() ([2]*opentype.Font, error) {
+	regular, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return [2]*opentype.Font{}, err
+	}
+	bold, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return [2]*opentype.Font{}, err
+	}
+	return [2]*opentype.Font{regular, bold}, nil
+})
+
+// maxCachedCards bounds the cards kept. Every top, theme and size is a
+// different card, so callers could otherwise fill memory with them.
+const maxCachedCards = 32
+
+// cardCache keeps rendered cards for embedCacheAge, so a link shared widely
+// is drawn once rather than per crawler. Past maxCachedCards the least
+// recently used card is dropped.
+type cardCache struct {
+	mu    sync.Mutex
+	cards map[string]*list.Element // Of *cachedCard
+	order *list.List               // Most recently used first
+}
+
+type cachedCard struct {
+	key  string
+	card renderedCard
+}
+
+type renderedCard struct {
+	png     []byte
+	etag    string
+	expires time.Time
+}
+
+func newCardCache() *cardCache {
+	return &cardCache{cards: make(map[string]*list.Element), order: list.New()}
+}
+
+func (c *cardCache) get(key string, now time.Time) (renderedCard, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.cards[key]
+	if !ok {
+		return renderedCard{}, false
+	}
+	card := elem.Value.(*cachedCard).card
+	if !now.Before(card.expires) {
+		c.remove(elem)
+		return renderedCard{}, false
+	}
+	c.order.MoveToFront(elem)
+	return card, true
+}
+
+func (c *cardCache) put(key string, card renderedCard) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.cards[key]; ok {
+		elem.Value.(*cachedCard).card = card
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.cards[key] = c.order.PushFront(&cachedCard{key: key, card: card})
+	for c.order.Len() > maxCachedCards {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove drops a card. Must be called with the lock held.
+func (c *cardCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.cards, elem.Value.(*cachedCard).key)
+}
+
+// GetEmbedImage renders the top of the leaderboard as a PNG sharing card
+// GET /embed/leaderboard.png?top=10&theme=dark&width=1200&height=630
+func (h *LeaderboardHandler) GetEmbedImage(w http.ResponseWriter, r *http.Request) {
+	top, topErr := queryInt(r, "top", 10, 1, cardMaxTop)
+	width, widthErr := queryInt(r, "width", cardWidth, 400, 2400)
+	height, heightErr := queryInt(r, "height", cardHeight, 200, 1260)
+	details := collectFieldErrors(topErr, widthErr, heightErr)
+	theme := r.URL.Query().Get("theme")
+	if theme == "" {
+		theme = "light"
+	}
+	if _, ok := embedThemes[theme]; !ok {
+		details = append(details, models.FieldError{Field: "theme", Rule: "oneof", Param: "light dark", Value: theme})
+	}
+	if len(details) > 0 {
+		respondFieldErrors(w, details...)
+		return
+	}
+
+	now := time.Now()
+	key := fmt.Sprintf("%d/%s/%dx%d", top, theme, width, height)
+	card, ok := h.cards.get(key, now)
+	if !ok {
+		leaderboard, err := h.service.GetLeaderboard(r.Context(), 1, top, services.ListOptions{ExcludeBots: true})
+		if err != nil {
+			writeFailure(w, "fetch_failed", err)
+			return
+		}
+		data, err := renderCard(leaderboard.Entries, top, theme, width, height)
+		if err != nil {
+			writeFailure(w, "render_failed", err)
+			return
+		}
+		sum := sha256.Sum256(data)
+		card = renderedCard{png: data, etag: `"` + hex.EncodeToString(sum[:8]) + `"`, expires: now.Add(embedCacheAge)}
+		h.cards.put(key, card)
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(embedCacheAge.Seconds())))
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("ETag", card.etag)
+	if r.Header.Get("If-None-Match") == card.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(card.png)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(card.png)
+}
+
+// renderCard draws the title and one row per entry, scaling the text to the
+// card's size
+func renderCard(entries []models.LeaderboardEntry, top int, theme string, width, height int) ([]byte, error) {
+	fonts, err := cardFonts()
+	if err != nil {
+		return nil, err
+	}
+	palette := embedThemes[theme]
+	background, text, muted, border := hexColor(palette.Background), hexColor(palette.Text), hexColor(palette.Muted), hexColor(palette.Border)
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	margin := int(float64(height) * cardMargin)
+	titleHeight := int(float64(height) * cardTitlePct)
+	rowHeight := float64(height-2*margin-titleHeight) / float64(top)
+
+	titleFace, err := opentype.NewFace(fonts[1], &opentype.FaceOptions{Size: float64(titleHeight) * 0.6, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	defer titleFace.Close()
+	rowFace, err := opentype.NewFace(fonts[0], &opentype.FaceOptions{Size: min(rowHeight*0.6, float64(titleHeight)*0.4), DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	defer rowFace.Close()
+
+	d := &font.Drawer{Dst: img, Face: titleFace, Src: image.NewUniform(text)}
+	d.Dot = fixed.P(margin, margin+int(float64(titleHeight)*0.7))
+	d.DrawString("Top " + strconv.Itoa(top))
+
+	lineY := margin + titleHeight - titleHeight/8
+	draw.Draw(img, image.Rect(margin, lineY, width-margin, lineY+max(height/300, 1)), image.NewUniform(border), image.Point{}, draw.Src)
+
+	d.Face = rowFace
+	rankWidth := d.MeasureString("#" + strconv.Itoa(cardMaxTop*10)).Ceil()
+	if len(entries) == 0 {
+		d.Src = image.NewUniform(muted)
+		d.Dot = fixed.P(margin, margin+titleHeight+int(rowHeight*0.7))
+		d.DrawString("No ranked players yet")
+	}
+	for i, e := range entries {
+		baseline := margin + titleHeight + int(rowHeight*float64(i)+rowHeight*0.7)
+
+		d.Src = image.NewUniform(muted)
+		d.Dot = fixed.P(margin, baseline)
+		d.DrawString("#" + strconv.Itoa(e.Rank))
+
+		rating := strconv.Itoa(e.Rating)
+		ratingWidth := d.MeasureString(rating).Ceil()
+		d.Src = image.NewUniform(text)
+		d.Dot = fixed.P(width-margin-ratingWidth, baseline)
+		d.DrawString(rating)
+
+		nameWidth := width - 2*margin - rankWidth - ratingWidth - margin
+		d.Dot = fixed.P(margin+rankWidth, baseline)
+		d.DrawString(fitText(d, e.Username, nameWidth))
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fitText shortens s with an ellipsis until it is at most width pixels wide
+func fitText(d *font.Drawer, s string, width int) string {
+	if d.MeasureString(s).Ceil() <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if candidate := string(runes) + "…"; d.MeasureString(candidate).Ceil() <= width {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// hexColor parses the "#rrggbb" colours of embedThemes
+func hexColor(s string) color.RGBA {
+	v, _ := strconv.ParseUint(s[1:], 16, 32)
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCardCache(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := newCardCache()
+	card := renderedCard{etag: `"card"`, expires: now.Add(embedCacheAge)}
+	for i := range maxCachedCards {
+		c.put(fmt.Sprintf("card %d", i), card)
+	}
+	// Reading the oldest makes it the most recently used
+	if _, ok := c.get("card 0", now); !ok {
+		t.Fatal("card 0 not cached")
+	}
+	c.put("one more", card)
+
+	if len(c.cards) != maxCachedCards || c.order.Len() != maxCachedCards {
+		t.Errorf("cache holds %d cards, want the bound of %d", len(c.cards), maxCachedCards)
+	}
+	if _, ok := c.get("card 1", now); ok {
+		t.Error("least recently used card kept past the bound")
+	}
+	for _, key := range []string{"card 0", "card 2", "one more"} {
+		if _, ok := c.get(key, now); !ok {
+			t.Errorf("%s dropped", key)
+		}
+	}
+
+	if _, ok := c.get("card 0", now.Add(embedCacheAge)); ok {
+		t.Error("expired card served")
+	}
+	if _, ok := c.cards["card 0"]; ok {
+		t.Error("expired card kept")
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"backend/internal/models"
+)
+
+// feedCacheAge is how long aggregators may reuse the feed
+const feedCacheAge = time.Minute
+
+// atomFeed is an Atom 1.0 document (RFC 4287)
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID       string       `xml:"id"`
+	Title    string       `xml:"title"`
+	Updated  string       `xml:"updated"`
+	Link     atomLink     `xml:"link"`
+	Category atomCategory `xml:"category"`
+	Summary  string       `xml:"summary"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// GetMilestonesFeed publishes recent leaderboard milestones as an Atom feed
+// GET /feeds/milestones.atom
+func (h *LeaderboardHandler) GetMilestonesFeed(w http.ResponseWriter, r *http.Request) {
+	base := requestBaseURL(r)
+	board := h.service.GetBoardMetadata(r.Context())
+	milestones := h.service.Milestones()
+
+	updated := board.CreatedAt
+	if len(milestones) > 0 {
+		updated = milestones[0].At
+	}
+	feed := atomFeed{
+		ID:      "urn:leaderboard:" + url.PathEscape(board.ID) + ":milestones",
+		Title:   "Leaderboard milestones",
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Href: base + "/feeds/milestones.atom"},
+			{Href: base + "/api/leaderboard"},
+		},
+		Author:  atomAuthor{Name: "Leaderboard"},
+		Entries: make([]atomEntry, 0, len(milestones)),
+	}
+	for _, m := range milestones {
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:       feed.ID + ":" + m.ID,
+			Title:    m.Title,
+			Updated:  m.At.UTC().Format(time.RFC3339),
+			Link:     atomLink{Href: base + "/api/users/" + url.PathEscape(m.Username)},
+			Category: atomCategory{Term: m.Type},
+			Summary:  milestoneSummary(m),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(feedCacheAge.Seconds())))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		log.Printf("Failed to write feed: %v", err)
+	}
+}
+
+func milestoneSummary(m models.Milestone) string {
+	if m.PreviousRank == 0 {
+		return fmt.Sprintf("%s joined at #%d with a rating of %d.", m.Username, m.Rank, m.Rating)
+	}
+	return fmt.Sprintf("%s moved from #%d to #%d with a rating of %d.", m.Username, m.PreviousRank, m.Rank, m.Rating)
+}
+
+// requestBaseURL is the scheme and host the client addressed, honouring a
+// TLS-terminating proxy's X-Forwarded-Proto
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "https" || proto == "http" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
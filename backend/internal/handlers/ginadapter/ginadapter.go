@@ -0,0 +1,38 @@
+// Package ginadapter mounts framework-agnostic handler routes on a Gin router.
+// It is the only part of the handler layer that depends on Gin.
+package ginadapter
+
+import (
+	"strings"
+
+	"backend/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Register adds every route to router, translating {name} path parameters to Gin's :name
+func Register(router gin.IRoutes, routes []handlers.Route) {
+	for _, route := range routes {
+		router.Handle(route.Method, ginPath(route.Path), wrap(route))
+	}
+}
+
+// wrap exposes Gin path parameters through http.Request.PathValue
+func wrap(route handlers.Route) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, param := range c.Params {
+			c.Request.SetPathValue(param.Key, param.Value)
+		}
+		route.Handler(c.Writer, c.Request)
+	}
+}
+
+func ginPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}")
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,1033 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/clock"
+	"backend/internal/models"
+	"backend/internal/ranking"
+	"backend/internal/services"
+	"backend/pkg/store"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// goldenCase is one request against a fresh fixture board
+type goldenCase struct {
+	name        string
+	method      string
+	target      string
+	body        string
+	contentType string // Defaults to application/json when body is set
+	header      http.Header
+	showHeaders []string // Response headers to render besides Location and rate limits
+	setup       func(t *testing.T, s *services.LeaderboardService)
+}
+
+// goldenCases covers every route. TestGoldenCoversEveryRoute fails when a
+// route has no case, so new routes come with a golden response.
+var goldenCases = []goldenCase{
+	{name: "readyz", method: "GET", target: "/readyz"},
+
+	{name: "auth_login", method: "GET", target: "/api/auth/github/login"},
+	{name: "auth_callback", method: "GET", target: "/api/auth/github/callback?code=abc&state=xyz"},
+	{name: "auth_refresh", method: "POST", target: "/api/auth/refresh", body: `{"refresh_token":"r"}`},
+	{name: "auth_logout", method: "POST", target: "/api/auth/logout", body: `{"refresh_token":"r"}`},
+	{name: "auth_me", method: "GET", target: "/api/auth/me"},
+	{name: "privacy_auth_disabled", method: "GET", target: "/api/auth/me/privacy"},
+	{name: "set_privacy_auth_disabled", method: "PUT", target: "/api/auth/me/privacy", body: `{"anonymize":true}`},
+
+	{name: "seed", method: "POST", target: "/api/seed", body: `{"count":5}`},
+	{name: "seed_wait", method: "POST", target: "/api/seed", body: `{"count":5,"wait":true}`},
+	{name: "seed_invalid", method: "POST", target: "/api/seed", body: `{"count":0}`},
+	{name: "seed_running", method: "POST", target: "/api/seed", body: `{"count":5}`, setup: func(t *testing.T, s *services.LeaderboardService) {
+		job, err := s.StartSeed(1<<30, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.CancelSeed(context.Background(), job.ID) })
+	}},
+	{name: "seed_jobs", method: "GET", target: "/api/seed/jobs", setup: func(t *testing.T, s *services.LeaderboardService) {
+		if _, err := s.SeedData(context.Background(), 3); err != nil {
+			t.Fatal(err)
+		}
+	}},
+	{name: "seed_job_not_found", method: "GET", target: "/api/seed/jobs/0123456789abcdef01234567"},
+	{name: "seed_job_cancel_not_found", method: "DELETE", target: "/api/seed/jobs/0123456789abcdef01234567"},
+
+	{name: "leaderboard", method: "GET", target: "/api/leaderboard?limit=3"},
+	{name: "leaderboard_private", method: "GET", target: "/api/leaderboard?limit=3", setup: madePrivate},
+	{name: "leaderboard_page_2", method: "GET", target: "/api/leaderboard?page=2&limit=3"},
+	{name: "limits", method: "GET", target: "/api/limits"},
+	{name: "limits_enabled", method: "GET", target: "/api/limits", setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetRateLimit(services.RateLimit{Reads: 100, Writes: 10})
+		s.TakeRateLimit("ip:192.0.2.1", services.BucketReads)
+	}},
+	{name: "leaderboard_rate_limit_headers", method: "GET", target: "/api/leaderboard", showHeaders: cachingHeaders, setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetRateLimit(services.RateLimit{Reads: 100, Writes: 10})
+	}},
+	{name: "user_rank_rate_limit_headers", method: "GET", target: "/api/users/carol", showHeaders: cachingHeaders, setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetRateLimit(services.RateLimit{Reads: 100, Writes: 10})
+	}},
+	{name: "update_score_rate_limit_headers", method: "POST", target: "/api/users/alice/score", body: `{"rating":2500}`, setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetRateLimit(services.RateLimit{Reads: 100, Writes: 10})
+	}},
+	{name: "leaderboard_cache_headers", method: "GET", target: "/api/leaderboard?limit=1", showHeaders: cachingHeaders},
+	{name: "leaderboard_cache_headers_with_api_key", method: "GET", target: "/api/leaderboard?limit=1", header: http.Header{"X-Api-Key": {"k"}}, showHeaders: cachingHeaders},
+	{name: "leaderboard_invalid_limit_cache_headers", method: "GET", target: "/api/leaderboard?limit=0", showHeaders: cachingHeaders},
+	{name: "stats_cache_headers", method: "GET", target: "/api/stats/count?min=2000", showHeaders: cachingHeaders},
+	{name: "user_rank_cache_headers", method: "GET", target: "/api/users/carol", showHeaders: cachingHeaders},
+	{name: "admin_roles_cache_headers", method: "GET", target: "/api/admin/roles", showHeaders: cachingHeaders},
+	{name: "embed_rate_limit_headers", method: "GET", target: "/embed/leaderboard?top=2", showHeaders: cachingHeaders, setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetRateLimit(services.RateLimit{Reads: 100, Writes: 10})
+	}},
+	{name: "embed_image_rate_limit_headers", method: "GET", target: "/embed/leaderboard.png?top=2", showHeaders: cachingHeaders, setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetRateLimit(services.RateLimit{Reads: 100, Writes: 10})
+	}},
+	{name: "milestones_feed_rate_limit_headers", method: "GET", target: "/feeds/milestones.atom", showHeaders: cachingHeaders, setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetRateLimit(services.RateLimit{Reads: 100, Writes: 10})
+	}},
+	{name: "rate_limited", method: "GET", target: "/api/leaderboard", setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetRateLimit(services.RateLimit{Reads: 1})
+		s.TakeRateLimit("ip:192.0.2.1", services.BucketReads)
+	}},
+	{name: "leaderboard_query_cost", method: "GET", target: "/api/leaderboard?limit=4", showHeaders: []string{"X-Query-Cost"}, setup: budgeted},
+	{name: "leaderboard_too_expensive", method: "GET", target: "/api/leaderboard?page=2&limit=5", showHeaders: []string{"X-Query-Cost"}, setup: budgeted},
+	{name: "leaderboard_too_expensive_invalid_limit", method: "GET", target: "/api/leaderboard?page=2&limit=500", setup: budgeted},
+	{name: "search_query_cost", method: "GET", target: "/api/search?q=E", showHeaders: []string{"X-Query-Cost"}, setup: budgeted},
+	{name: "search_too_expensive", method: "GET", target: "/api/search?q=bo", setup: tightlyBudgeted},
+	{name: "search_cursor_too_expensive", method: "GET", target: "/api/search?q=b&limit=1&cursor=MTgwMDpjYXJvbA", showHeaders: []string{"X-Query-Cost"}, setup: tightlyBudgeted},
+	{name: "export_too_expensive", method: "GET", target: "/api/export", setup: budgeted},
+	{name: "limits_query_cost_budget", method: "GET", target: "/api/limits", setup: budgeted},
+	{name: "leaderboard_exclude_bots", method: "GET", target: "/api/leaderboard?exclude_bots=true"},
+	{name: "leaderboard_most_recent_first", method: "GET", target: "/api/leaderboard?limit=4", setup: tieOnAliceRecently},
+	{name: "user_rank_most_recent_first", method: "GET", target: "/api/users/bob", setup: tieOnAliceRecently},
+	{name: "leaderboard_invalid_limit", method: "GET", target: "/api/leaderboard?limit=0"},
+	{name: "capabilities", method: "GET", target: "/api/capabilities"},
+	{name: "capabilities_async", method: "GET", target: "/api/capabilities", setup: enableScoreQueue},
+	{name: "board_metadata", method: "GET", target: "/api/leaderboards/default"},
+	{name: "board_metadata_tiers", method: "GET", target: "/api/leaderboards/default", setup: tiered},
+	{name: "cutoffs", method: "GET", target: "/api/leaderboards/default/cutoffs", setup: watchedCutoffs},
+	{name: "cutoffs_unknown_board", method: "GET", target: "/api/leaderboards/other/cutoffs", setup: watchedCutoffs},
+	{name: "cutoffs_not_configured", method: "GET", target: "/api/leaderboards/default/cutoffs"},
+	{name: "board_metadata_unknown", method: "GET", target: "/api/leaderboards/other"},
+	{name: "prizes_not_configured", method: "GET", target: "/api/leaderboards/default/prizes"},
+	{name: "prizes_preview", method: "GET", target: "/api/leaderboards/default/prizes?preview=true", setup: func(t *testing.T, s *services.LeaderboardService) {
+		bands, err := services.ParsePrizeBands("gold:1,silver:2-3,top:75%")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.SetPrizeBands(bands)
+	}},
+
+	{name: "boards", method: "GET", target: "/api/boards", setup: playedAcrossBoards},
+	{name: "boards_disabled", method: "GET", target: "/api/boards"},
+	{name: "boards_unavailable", method: "GET", target: "/api/boards", setup: enableFailingBoards},
+	{name: "board_daily", method: "GET", target: "/api/boards/daily:2025-01-01", setup: playedAcrossBoards},
+	{name: "board_country", method: "GET", target: "/api/boards/country:US?limit=1&page=2", setup: playedAcrossBoards},
+	{name: "board_teams", method: "GET", target: "/api/boards/teams", setup: playedAcrossBoards},
+	{name: "board_not_found", method: "GET", target: "/api/boards/country:FR", setup: playedAcrossBoards},
+	{name: "board_invalid_page", method: "GET", target: "/api/boards/teams?page=0", setup: playedAcrossBoards},
+	{name: "board_teams_recomputed", method: "GET", target: "/api/boards/teams", setup: recomputedBoard("teams")},
+	{name: "board_daily_recomputed", method: "GET", target: "/api/boards/daily:2025-01-01", setup: recomputedBoard("daily:2025-01-01")},
+	{name: "recompute_board", method: "POST", target: "/api/admin/boards/country:US/recompute", setup: playedAcrossBoards},
+	{name: "recompute_board_not_kept", method: "POST", target: "/api/admin/boards/daily:2024-12-01/recompute", setup: playedAcrossBoards},
+	{name: "recompute_tiers", method: "POST", target: "/api/admin/boards/tiers/recompute", setup: tiered},
+	{name: "recompute_tiers_not_configured", method: "POST", target: "/api/admin/boards/tiers/recompute"},
+	{name: "recompute_boards_disabled", method: "POST", target: "/api/admin/boards/teams/recompute"},
+	{name: "recompute_job_not_found", method: "GET", target: "/api/admin/boards/jobs/0123456789abcdef01234567", setup: enableDerivedBoards},
+
+	{name: "guest_score", method: "POST", target: "/api/guests/score", body: `{"rating":1700}`, header: guestDevice, setup: enableGuests},
+	{name: "guest_score_again", method: "POST", target: "/api/guests/score", body: `{"rating":1900}`, header: guestDevice, setup: playedAsGuest},
+	{name: "guest_score_short_token", method: "POST", target: "/api/guests/score", body: `{"rating":1700}`, header: http.Header{"X-Device-Token": {"short"}}, setup: enableGuests},
+	{name: "guest_score_ignores_board_fields", method: "POST", target: "/api/guests/score", body: `{"rating":1700,"ttl_seconds":60,"country":"US","team":"red"}`, header: guestDevice, setup: enableGuests},
+	{name: "guest_score_creation_limited", method: "POST", target: "/api/guests/score", body: `{"rating":1700}`, header: guestDevice, setup: limitedGuests},
+	{name: "guest_score_again_when_limited", method: "POST", target: "/api/guests/score", body: `{"rating":1900}`, header: http.Header{"X-Device-Token": {"other-device-0123456789"}}, setup: limitedGuests},
+	{name: "guest_score_full", method: "POST", target: "/api/guests/score", body: `{"rating":1700}`, header: guestDevice, setup: fullOfGuests},
+	{name: "guest_score_disabled", method: "POST", target: "/api/guests/score", body: `{"rating":1700}`, header: guestDevice},
+	{name: "claim_guest_auth_disabled", method: "POST", target: "/api/users/claim", body: `{"device_token":"device-0123456789abcdef"}`, setup: enableGuests},
+	{name: "leaderboard_guest_claimed", method: "GET", target: "/api/leaderboard?limit=3", setup: claimedGuest},
+	{name: "history_guest_claimed", method: "GET", target: "/api/users/carol/history", setup: claimedGuest},
+
+	{name: "merge_users", method: "POST", target: "/api/admin/users/merge", body: `{"from":"bob","into":"carol","reason":"duplicate account"}`},
+	{name: "merge_users_invalid_token", method: "POST", target: "/api/admin/users/merge?confirm=0123456789abcdef01234567", body: `{"from":"bob","into":"carol","reason":"duplicate account"}`},
+	{name: "merge_users_self", method: "POST", target: "/api/admin/users/merge", body: `{"from":"bob","into":"bob","reason":"duplicate account"}`},
+	{name: "merge_users_not_found", method: "POST", target: "/api/admin/users/merge", body: `{"from":"nobody","into":"carol","reason":"duplicate account"}`},
+	{name: "history_merged", method: "GET", target: "/api/users/carol/history", setup: mergedUsers(models.MergePolicyBest)},
+	{name: "moderation_record_merged", method: "GET", target: "/api/moderation/users/carol", setup: mergedUsers(models.MergePolicyBest)},
+	{name: "user_rank_merged_latest", method: "GET", target: "/api/users/carol", setup: mergedUsers(models.MergePolicyLatest)},
+	{name: "user_rank_merged_away", method: "GET", target: "/api/users/bob", setup: mergedUsers(models.MergePolicyBest)},
+
+	{name: "users_by_name", method: "GET", target: "/api/users?sort=username&limit=3"},
+	{name: "users_by_name_cursor", method: "GET", target: "/api/users?sort=username&cursor=bot_3&limit=3"},
+	{name: "users_by_name_last_page", method: "GET", target: "/api/users?sort=username&cursor=carol"},
+	{name: "users_invalid_sort", method: "GET", target: "/api/users?sort=rating"},
+	{name: "user_rank", method: "GET", target: "/api/users/carol"},
+	{name: "user_rank_high_priority", method: "GET", target: "/api/users/carol", header: http.Header{"X-Priority": {"high"}}},
+	{name: "user_rank_invalid_priority", method: "GET", target: "/api/users/carol", header: http.Header{"X-Priority": {"urgent"}}},
+	{name: "user_rank_not_found", method: "GET", target: "/api/users/nobody"},
+	{name: "update_score", method: "POST", target: "/api/users/alice/score", body: `{"rating":2500}`},
+	{name: "update_score_invalid", method: "POST", target: "/api/users/alice/score", body: `{"rating":50}`},
+	{name: "update_score_across_boards", method: "POST", target: "/api/users/alice/score", body: `{"rating":2500,"country":"US","team":"red"}`, setup: enableDerivedBoards},
+	{name: "update_score_invalid_country", method: "POST", target: "/api/users/alice/score", body: `{"rating":2500,"country":"usa"}`, setup: enableDerivedBoards},
+	{name: "update_score_boards_unavailable", method: "POST", target: "/api/users/alice/score", body: `{"rating":2500,"country":"US"}`, setup: enableFailingBoards},
+	{name: "offline_sync", method: "POST", target: "/api/users/alice/score/sync", setup: changedAliceOnServer("absolute"),
+		body: `{"events":[{"event_id":"e2","occurred_at":"2025-01-01T12:30:00Z","rating":2600},{"event_id":"e1","occurred_at":"2025-01-01T11:30:00Z","rating":2100},{"event_id":"e2","occurred_at":"2025-01-01T12:40:00Z","rating":2700},{"event_id":"e3","occurred_at":"2025-01-01T14:00:00Z","rating":2800}]}`},
+	{name: "offline_sync_delta", method: "POST", target: "/api/users/alice/score/sync", setup: changedAliceOnServer("delta"),
+		body: `{"events":[{"event_id":"e1","occurred_at":"2025-01-01T11:30:00Z","delta":-50},{"event_id":"e2","occurred_at":"2025-01-01T12:30:00Z","delta":25},{"event_id":"e3","occurred_at":"2025-01-01T12:35:00Z","rating":2000}]}`},
+	{name: "offline_sync_highest", method: "POST", target: "/api/users/alice/score/sync", setup: changedAliceOnServer("highest"),
+		body: `{"events":[{"event_id":"e1","occurred_at":"2025-01-01T11:30:00Z","rating":2350},{"event_id":"e2","occurred_at":"2025-01-01T12:30:00Z","rating":2200}]}`},
+	{name: "offline_sync_invalid", method: "POST", target: "/api/users/alice/score/sync", body: `{"events":[]}`},
+	{name: "offline_sync_not_found", method: "POST", target: "/api/users/nobody/score/sync", body: `{"events":[{"event_id":"e1","occurred_at":"2025-01-01T11:30:00Z","rating":2100}]}`},
+	{name: "report", method: "GET", target: "/api/reports/2025-01-01", setup: generateReport},
+	{name: "report_markdown", method: "GET", target: "/api/reports/2025-01-01?format=markdown", setup: generateReport},
+	{name: "report_html", method: "GET", target: "/api/reports/2025-01-01?format=html", setup: generateReport},
+	{name: "report_not_found", method: "GET", target: "/api/reports/2024-12-31", setup: generateReport},
+	{name: "report_invalid", method: "GET", target: "/api/reports/yesterday?format=pdf", setup: generateReport},
+	{name: "reports_disabled", method: "GET", target: "/api/reports/2025-01-01"},
+	{name: "resolved_config", method: "GET", target: "/api/admin/config", setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetResolvedConfig(map[string]any{
+			"board_id":        "default",
+			"rating_strategy": "elo",
+			"auth":            map[string]any{"secret": "<redacted>", "token_ttl": "15m0s"},
+		})
+	}},
+	{name: "resolved_config_unavailable", method: "GET", target: "/api/admin/config"},
+	{name: "imports_status", method: "GET", target: "/api/admin/imports", setup: func(t *testing.T, s *services.LeaderboardService) {
+		if err := s.EnableScoreImport(services.ImportConfig{URL: "https://partner.example.com/scores.csv", Secret: "partner-secret", CreateUsers: true}); err != nil {
+			t.Fatal(err)
+		}
+	}},
+	{name: "imports_disabled", method: "GET", target: "/api/admin/imports"},
+	{name: "imports_run", method: "POST", target: "/api/admin/imports/run", setup: func(t *testing.T, s *services.LeaderboardService) {
+		if err := s.EnableScoreImport(services.ImportConfig{URL: serveScoreFile(t) + "/scores.csv", Secret: "partner-secret", CreateUsers: true}); err != nil {
+			t.Fatal(err)
+		}
+	}},
+	{name: "imports_run_json", method: "POST", target: "/api/admin/imports/run", setup: func(t *testing.T, s *services.LeaderboardService) {
+		if err := s.EnableScoreImport(services.ImportConfig{URL: serveScoreFile(t) + "/scores.json", Secret: "partner-secret"}); err != nil {
+			t.Fatal(err)
+		}
+	}},
+	{name: "imports_run_not_modified", method: "POST", target: "/api/admin/imports/run", setup: func(t *testing.T, s *services.LeaderboardService) {
+		if err := s.EnableScoreImport(services.ImportConfig{URL: serveScoreFile(t) + "/scores.csv", Secret: "partner-secret"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.RunImport(context.Background(), services.ImportTriggerSchedule); err != nil {
+			t.Fatal(err)
+		}
+	}},
+	{name: "imports_run_bad_signature", method: "POST", target: "/api/admin/imports/run", setup: func(t *testing.T, s *services.LeaderboardService) {
+		if err := s.EnableScoreImport(services.ImportConfig{URL: serveScoreFile(t) + "/scores.csv", Secret: "wrong-secret"}); err != nil {
+			t.Fatal(err)
+		}
+	}},
+	{name: "embed", method: "GET", target: "/embed/leaderboard?top=3&theme=dark"},
+	{name: "embed_oembed", method: "GET", target: "/embed/leaderboard?top=2&format=oembed"},
+	{name: "milestones_feed", method: "GET", target: "/feeds/milestones.atom", setup: func(t *testing.T, s *services.LeaderboardService) {
+		for _, update := range []struct {
+			username string
+			rating   int
+		}{{"bob", 2500}, {"bot_1", 2600}, {"erin", 1900}} {
+			if err := s.UpdateScore(context.Background(), update.username, update.rating); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}},
+	{name: "milestones_feed_empty", method: "GET", target: "/feeds/milestones.atom"},
+	{name: "embed_invalid", method: "GET", target: "/embed/leaderboard?top=0&theme=neon"},
+	{name: "embed_image", method: "GET", target: "/embed/leaderboard.png"},
+	{name: "embed_image_dark_small", method: "GET", target: "/embed/leaderboard.png?top=3&theme=dark&width=400&height=200"},
+	{name: "embed_image_invalid", method: "GET", target: "/embed/leaderboard.png?top=50&width=10000&theme=neon"},
+	{name: "update_score_not_found", method: "POST", target: "/api/users/nobody/score", body: `{"rating":2500}`},
+	{name: "update_score_not_found_spanish", method: "POST", target: "/api/users/nobody/score", body: `{"rating":2500}`, header: http.Header{"Accept-Language": {"es-MX,es;q=0.9,en;q=0.5"}}},
+	{name: "update_score_invalid_french", method: "POST", target: "/api/users/alice/score", body: `{"rating":50}`, header: http.Header{"Accept-Language": {"fr-CH"}}},
+	{name: "user_rank_not_found_unsupported_language", method: "GET", target: "/api/users/nobody", header: http.Header{"Accept-Language": {"ja,en;q=0.8"}}},
+	{name: "update_score_async_disabled", method: "POST", target: "/api/users/alice/score?async=true", body: `{"rating":2500}`},
+	{name: "update_score_async", method: "POST", target: "/api/users/alice/score?async=true", body: `{"rating":2500}`, setup: enableScoreQueue},
+	{name: "score_history", method: "GET", target: "/api/users/alice/history", setup: func(t *testing.T, s *services.LeaderboardService) {
+		if err := s.UpdateScore(context.Background(), "alice", 2450); err != nil {
+			t.Fatal(err)
+		}
+	}},
+	{name: "score_history_hourly", method: "GET", target: "/api/users/alice/history?resolution=hour", setup: func(t *testing.T, s *services.LeaderboardService) {
+		ctx := context.Background()
+		for _, rating := range []int{2450, 2500, 2475} {
+			if err := s.UpdateScore(ctx, "alice", rating); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := s.AdjustScore(ctx, "alice", 2300, "cheating", "key:ops"); err != nil {
+			t.Fatal(err)
+		}
+	}},
+	{name: "score_history_invalid_resolution", method: "GET", target: "/api/users/alice/history?resolution=week"},
+	{name: "submission_async_disabled", method: "GET", target: "/api/submissions/0123456789abcdef01234567"},
+	{name: "submission_not_found", method: "GET", target: "/api/submissions/0123456789abcdef01234567", setup: enableScoreQueue},
+
+	{name: "search", method: "GET", target: "/api/search?q=b"},
+	{name: "search_page", method: "GET", target: "/api/search?q=b&limit=2&page=2"},
+	{name: "search_cursor", method: "GET", target: "/api/search?q=b&limit=1&cursor=MTgwMDpjYXJvbA"},
+	{name: "search_invalid_cursor", method: "GET", target: "/api/search?q=a&cursor=nope"},
+	{name: "search_missing_query", method: "GET", target: "/api/search"},
+	{name: "search_private", method: "GET", target: "/api/search?q=b", setup: madePrivate},
+	{name: "users_by_name_private", method: "GET", target: "/api/users?sort=username&limit=3", setup: madePrivate},
+	{name: "user_rank_anonymized", method: "GET", target: "/api/users/bob", setup: madePrivate},
+	{name: "export_json", method: "GET", target: "/api/export"},
+	{name: "export_jsonl", method: "GET", target: "/api/export?format=jsonl&exclude_bots=true"},
+	{name: "stats", method: "GET", target: "/api/stats"},
+	{name: "stats_count", method: "GET", target: "/api/stats/count?min_rating=2000"},
+	{name: "stats_count_range_humans", method: "GET", target: "/api/stats/count?min_rating=1500&max_rating=2200&exclude_bots=true"},
+	{name: "stats_inflation_empty", method: "GET", target: "/api/stats/inflation"},
+	{name: "stats_inflation", method: "GET", target: "/api/stats/inflation", setup: func(t *testing.T, s *services.LeaderboardService) {
+		ctx := context.Background()
+		s.RecordInflationSample(ctx)
+		if err := s.UpdateScore(ctx, "erin", 2000); err != nil {
+			t.Fatal(err)
+		}
+		s.SetClock(clock.NewFake(fixtureTime.Add(24 * time.Hour)))
+		s.RecordInflationSample(ctx)
+	}},
+	{name: "stats_count_inverted_range", method: "GET", target: "/api/stats/count?min_rating=3000&max_rating=2000"},
+	{name: "stats_exclude_bots", method: "GET", target: "/api/stats?exclude_bots=true"},
+	{name: "stats_by_country_unplaced", method: "GET", target: "/api/stats/by-country"},
+	{name: "stats_by_country", method: "GET", target: "/api/stats/by-country", setup: playedInCountries},
+	{name: "stats_by_country_humans", method: "GET", target: "/api/stats/by-country?exclude_bots=true", setup: playedInCountries},
+	{name: "stats_by_country_invalid", method: "GET", target: "/api/stats/by-country?exclude_bots=maybe"},
+	{name: "stats_by_tier", method: "GET", target: "/api/stats/by-tier", setup: tiered},
+	{name: "stats_by_tier_humans", method: "GET", target: "/api/stats/by-tier?exclude_bots=true", setup: tiered},
+	{name: "stats_by_tier_not_configured", method: "GET", target: "/api/stats/by-tier"},
+	{name: "simulation_status", method: "GET", target: "/api/simulation/status"},
+
+	{name: "integration_scores_disabled", method: "POST", target: "/api/integrations/scores", body: `{"external_id":"p1","rating":2000}`},
+	{name: "identity_not_found", method: "GET", target: "/api/identities/steam/765"},
+	{name: "identity_link", method: "PUT", target: "/api/identities/steam/765", body: `{"username":"alice"}`},
+	{name: "identity_unlink_not_found", method: "DELETE", target: "/api/identities/steam/765"},
+	{name: "user_identities", method: "GET", target: "/api/users/alice/identities"},
+
+	{name: "ws_without_upgrade", method: "GET", target: "/api/ws"},
+	{name: "event_schema", method: "GET", target: "/api/events/schema/1"},
+	{name: "event_schema_unknown", method: "GET", target: "/api/events/schema/99"},
+
+	{name: "moderation_record", method: "GET", target: "/api/moderation/users/alice"},
+	{name: "moderation_adjust", method: "POST", target: "/api/moderation/users/alice/adjust", body: `{"rating":2000,"reason":"chargeback"}`},
+	{name: "moderation_adjust_pending_approval", method: "POST", target: "/api/moderation/users/alice/adjust", body: `{"rating":1000,"reason":"chargeback"}`, setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetApprovalThreshold(500)
+	}},
+	{name: "moderation_freeze", method: "PUT", target: "/api/moderation/users/alice/freeze", body: `{"duration_seconds":3600,"reason":"under review"}`},
+	{name: "moderation_unfreeze_not_frozen", method: "DELETE", target: "/api/moderation/users/alice/freeze"},
+	{name: "moderation_note", method: "POST", target: "/api/moderation/users/alice/notes", body: `{"note":"Contacted support"}`},
+
+	{name: "admin_overview", method: "GET", target: "/api/admin/overview"},
+	{name: "admin_overview_slos", method: "GET", target: "/api/admin/overview", setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.EnableSLOs(services.SLOConfig{Objectives: []services.LatencySLO{
+			{Group: services.GroupLeaderboard, Percentile: 99, Threshold: 50 * time.Millisecond},
+			{Group: services.GroupStats, Percentile: 95, Threshold: 200 * time.Millisecond},
+		}})
+		// A quarter of leaderboard requests are slow, burning the budget 25x too fast
+		for i := range 40 {
+			elapsed := 10 * time.Millisecond
+			if i%4 == 0 {
+				elapsed = 80 * time.Millisecond
+			}
+			s.ObserveLatency(services.GroupLeaderboard, elapsed, http.StatusOK)
+		}
+		s.ObserveLatency(services.GroupStats, 5*time.Millisecond, http.StatusOK)
+	}},
+	{name: "admin_config_export", method: "GET", target: "/api/admin/config/boards"},
+	{name: "admin_config_export_yaml", method: "GET", target: "/api/admin/config/boards?format=yaml", setup: func(t *testing.T, s *services.LeaderboardService) {
+		endsAt := fixtureTime.Add(90 * 24 * time.Hour)
+		if _, err := s.StartSeason(context.Background(), "2025-q1", &endsAt); err != nil {
+			t.Fatal(err)
+		}
+		s.SetPrizeBands([]services.PrizeBand{{Name: "gold", MinRank: 1, MaxRank: 1}, {Name: "top", TopPercent: 10}})
+	}},
+	{name: "admin_config_apply", method: "PUT", target: "/api/admin/config/boards", body: `{"boards":[{"id":"default","rating_strategy":"elo","capacity":1000,"season":{"id":"2025-q1","ends_at":"2025-03-31T23:59:59Z"},"prize_bands":[{"name":"gold","min_rank":1,"max_rank":1}]}]}`},
+	{name: "admin_config_apply_yaml_dry_run", method: "PUT", target: "/api/admin/config/boards?dry_run=true", contentType: "application/yaml", body: "boards:\n  - id: default\n    rating_strategy: delta\n    prize_bands:\n      - name: top\n        top_percent: 10\n"},
+	{name: "admin_config_apply_unchanged", method: "PUT", target: "/api/admin/config/boards", body: `{"boards":[{"id":"default","min_score":100,"max_score":5000,"tie_policy":"shared_rank","rating_strategy":"absolute"}]}`},
+	{name: "admin_config_apply_tie_policy", method: "PUT", target: "/api/admin/config/boards?dry_run=true", body: `{"boards":[{"id":"default","tie_policy":"most_recent_first","rating_strategy":"absolute"}]}`},
+	{name: "admin_config_apply_invalid", method: "PUT", target: "/api/admin/config/boards", body: `{"boards":[{"id":"other","max_score":9000,"rating_strategy":"chess","prize_bands":[{"name":"gold","min_rank":3,"max_rank":1}]}]}`},
+	{name: "admin_config_apply_tiers", method: "PUT", target: "/api/admin/config/boards?dry_run=true", body: `{"boards":[{"id":"default","tier_boundaries":[{"name":"bronze","min_score":1000}],"rating_strategy":"absolute"}]}`, setup: tiered},
+	{name: "admin_config_apply_unknown_field", method: "PUT", target: "/api/admin/config/boards", body: `{"boards":[{"id":"default","capacty":10}]}`},
+	{name: "admin_config_apply_season_conflict", method: "PUT", target: "/api/admin/config/boards", body: `{"boards":[{"id":"default","season":{"id":"2025-q2"}}]}`, setup: func(t *testing.T, s *services.LeaderboardService) {
+		if _, err := s.StartSeason(context.Background(), "2025-q1", nil); err != nil {
+			t.Fatal(err)
+		}
+	}},
+	{name: "admin_maintenance", method: "POST", target: "/api/admin/maintenance", body: `{"mode":"read_only","message":"Migrating to the new store, back by 13:00 UTC"}`},
+	{name: "admin_maintenance_off", method: "POST", target: "/api/admin/maintenance", body: `{"mode":"off"}`, setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetMaintenance(context.Background(), services.MaintenanceDown, "")
+	}},
+	{name: "admin_maintenance_invalid", method: "POST", target: "/api/admin/maintenance", body: `{"mode":"paused"}`},
+	{name: "maintenance_read_only_allows_reads", method: "GET", target: "/api/users/carol", setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetMaintenance(context.Background(), services.MaintenanceReadOnly, "")
+	}},
+	{name: "maintenance_read_only_rejects_writes", method: "POST", target: "/api/users/alice/score", body: `{"rating":2500}`, setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetMaintenance(context.Background(), services.MaintenanceReadOnly, "")
+	}},
+	{name: "maintenance_down", method: "GET", target: "/api/leaderboard?limit=3", setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetMaintenance(context.Background(), services.MaintenanceDown, "Migrating to the new store, back by 13:00 UTC")
+	}},
+	{name: "maintenance_read_only_rejects_admin_writes", method: "DELETE", target: "/api/admin/lockouts/ip:127.0.0.1", setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetMaintenance(context.Background(), services.MaintenanceReadOnly, "")
+	}},
+	{name: "maintenance_read_only_rejects_privacy_writes", method: "PUT", target: "/api/auth/me/privacy", body: `{"hidden":true}`, setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetMaintenance(context.Background(), services.MaintenanceReadOnly, "")
+	}},
+	{name: "maintenance_read_only_allows_admin_reads", method: "GET", target: "/api/admin/roles", setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetMaintenance(context.Background(), services.MaintenanceReadOnly, "")
+	}},
+	{name: "maintenance_down_allows_admin_reads", method: "GET", target: "/api/admin/roles", setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetMaintenance(context.Background(), services.MaintenanceDown, "")
+	}},
+	{name: "maintenance_down_rejects_admin_writes", method: "DELETE", target: "/api/admin/lockouts/ip:127.0.0.1", setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetMaintenance(context.Background(), services.MaintenanceDown, "")
+	}},
+	{name: "admin_simulation", method: "PUT", target: "/api/admin/simulation", body: `{"enabled":false}`},
+	{name: "admin_shadow_disabled", method: "GET", target: "/api/admin/shadow/compare"},
+	{name: "admin_score_queue_disabled", method: "GET", target: "/api/admin/score-queue"},
+	{name: "admin_score_queue", method: "GET", target: "/api/admin/score-queue", setup: enableScoreQueue},
+	{name: "admin_season_none", method: "GET", target: "/api/admin/season"},
+	{name: "admin_season_start", method: "PUT", target: "/api/admin/season", body: `{"id":"s1"}`},
+	{name: "admin_season_close_none", method: "POST", target: "/api/admin/season/close"},
+	{name: "admin_season_close", method: "POST", target: "/api/admin/season/close", setup: startSeason},
+	{name: "admin_season_close_invalid_token", method: "POST", target: "/api/admin/season/close?confirm=0123456789abcdef01234567", setup: startSeason},
+	{name: "admin_dead_letters_empty", method: "GET", target: "/api/admin/dead-letters"},
+	{name: "admin_dead_letters", method: "GET", target: "/api/admin/dead-letters", setup: failWebhook},
+	{name: "admin_dead_letters_invalid_kind", method: "GET", target: "/api/admin/dead-letters?kind=email"},
+	{name: "admin_dead_letter", method: "GET", target: "/api/admin/dead-letters/webhook:s1-1", setup: failWebhook},
+	{name: "admin_dead_letter_not_found", method: "GET", target: "/api/admin/dead-letters/webhook:s1-1"},
+	{name: "admin_dead_letter_retry_not_found", method: "POST", target: "/api/admin/dead-letters/score:1-0/retry"},
+	{name: "admin_dead_letter_discard", method: "DELETE", target: "/api/admin/dead-letters/webhook:s1-1", setup: failWebhook},
+	{name: "admin_dead_letters_retry", method: "POST", target: "/api/admin/dead-letters/retry", body: `{"kind":"webhook"}`, setup: failWebhook},
+	{name: "admin_dead_letters_discard", method: "POST", target: "/api/admin/dead-letters/discard", body: `{"ids":["webhook:s1-1","score:1-0"]}`, setup: failWebhook},
+	{name: "admin_dead_letters_discard_invalid", method: "POST", target: "/api/admin/dead-letters/discard", body: `{}`},
+	{name: "admin_migration_disabled", method: "GET", target: "/api/admin/migration/verification"},
+	{name: "admin_health_history", method: "GET", target: "/api/admin/health/history"},
+	{name: "admin_realtime", method: "GET", target: "/api/admin/realtime"},
+	{name: "admin_pending_changes", method: "GET", target: "/api/admin/pending-changes", setup: func(t *testing.T, s *services.LeaderboardService) {
+		s.SetApprovalThreshold(500)
+		if _, err := s.AdjustScore(context.Background(), "alice", 1000, "chargeback", "user:mod"); err != nil {
+			t.Fatal(err)
+		}
+	}},
+	{name: "admin_pending_change_approve_not_found", method: "POST", target: "/api/admin/pending-changes/0123456789abcdef01234567/approve"},
+	{name: "admin_pending_change_reject_not_found", method: "DELETE", target: "/api/admin/pending-changes/0123456789abcdef01234567"},
+	{name: "admin_anomalies", method: "GET", target: "/api/admin/anomalies"},
+	{name: "admin_anomaly_resolve_not_found", method: "DELETE", target: "/api/admin/anomalies/alice"},
+	{name: "admin_sessions", method: "GET", target: "/api/admin/users/alice/sessions"},
+	{name: "admin_sessions_revoke", method: "DELETE", target: "/api/admin/users/alice/sessions"},
+	{name: "admin_captures", method: "GET", target: "/api/admin/captures"},
+	{name: "admin_captures_configure", method: "PUT", target: "/api/admin/captures", body: `{"route":"GET /api/leaderboard","sample_percent":10}`},
+	{name: "admin_captures_stop", method: "DELETE", target: "/api/admin/captures"},
+	{name: "admin_integrity_check", method: "POST", target: "/api/admin/integrity-check"},
+	{name: "admin_lockouts", method: "GET", target: "/api/admin/lockouts"},
+	{name: "admin_lockout_clear", method: "DELETE", target: "/api/admin/lockouts/ip:127.0.0.1"},
+	{name: "admin_roles", method: "GET", target: "/api/admin/roles"},
+	{name: "admin_grant_user_role", method: "PUT", target: "/api/admin/users/alice/roles/moderator"},
+	{name: "admin_revoke_user_role", method: "DELETE", target: "/api/admin/users/alice/roles/moderator"},
+	{name: "admin_grant_key_role", method: "PUT", target: "/api/admin/keys/ci/roles/writer"},
+	{name: "admin_revoke_key_role", method: "DELETE", target: "/api/admin/keys/ci/roles/writer"},
+	{name: "admin_grant_identity_role", method: "PUT", target: "/api/admin/identities/github/583231/roles/moderator"},
+	{name: "admin_revoke_identity_role", method: "DELETE", target: "/api/admin/identities/github/583231/roles/moderator"},
+	{name: "user_roles_from_identity", method: "PUT", target: "/api/admin/users/alice/roles/writer", setup: func(t *testing.T, s *services.LeaderboardService) {
+		ctx := context.Background()
+		if _, err := s.LinkExternalID(ctx, "github", "583231", "alice"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.GrantRole(ctx, services.IdentityPrincipal("github", "583231"), services.RoleModerator); err != nil {
+			t.Fatal(err)
+		}
+	}},
+	{name: "admin_roles_after_merge", method: "GET", target: "/api/admin/roles", setup: func(t *testing.T, s *services.LeaderboardService) {
+		ctx := context.Background()
+		if err := s.GrantRole(ctx, services.Principal(services.PrincipalUser, "bob"), services.RoleModerator); err != nil {
+			t.Fatal(err)
+		}
+		_, err := s.MergeUsers(ctx, models.MergeUsersRequest{From: "bob", Into: "carol", Reason: "duplicate account"}, "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}},
+}
+
+func startSeason(t *testing.T, s *services.LeaderboardService) {
+	if _, err := s.StartSeason(context.Background(), "s1", nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// failWebhook closes season s1 with a webhook that can't be reached, and
+// waits for its single attempt to fail
+func failWebhook(t *testing.T, s *services.LeaderboardService) {
+	s.EnableSeasonWebhooks(services.SeasonWebhookConfig{URLs: []string{"http://127.0.0.1:1/hook"}, MaxAttempts: 1, TopN: 2})
+	startSeason(t, s)
+	if _, err := s.CloseSeason(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if deliveries := s.ListWebhookDeliveries(); deliveries[0].Status == models.DeliveryFailed {
+			return
+		}
+	}
+	t.Fatal("webhook delivery didn't fail")
+}
+
+func enableScoreQueue(t *testing.T, s *services.LeaderboardService) {
+	if err := s.EnableScoreQueue(services.ScoreQueueConfig{Workers: 1, Capacity: 10}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// scoreFiles are what serveScoreFile publishes: unchanged, updated, new,
+// invalid and duplicate rows
+var scoreFiles = map[string]string{
+	"/scores.csv":  "username,rating,team\nalice,2400,red\nbob,2150,blue\nfrank,1300,red\nerin,abc,blue\n,1500,red\nbob,2200,blue\n",
+	"/scores.json": `[{"username":"carol","rating":1850},{"username":"nobody","rating":1400},{"username":"dave","rating":9000}]`,
+}
+
+// scoreFilesSignedAt is when the partner signed scoreFiles
+var scoreFilesSignedAt = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// serveScoreFile publishes scoreFiles signed with "partner-secret", answering
+// If-None-Match with 304, and returns the server's URL
+func serveScoreFile(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, signature := strings.CutSuffix(r.URL.Path, ".sig")
+		body, ok := scoreFiles[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if signature {
+			io.WriteString(w, services.SignImportFile("partner-secret", scoreFilesSignedAt, "v1", []byte(body))+"\n")
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// generateReport records a few updates and stores the fixture day's report
+func generateReport(t *testing.T, s *services.LeaderboardService) {
+	s.EnableDailyReports(services.ReportConfig{})
+	for _, update := range []struct {
+		username string
+		rating   int
+	}{{"bob", 2500}, {"erin", 1100}, {"dave", 1700}, {"bot_1", 2300}, {"bob", 2450}} {
+		if err := s.UpdateScore(context.Background(), update.username, update.rating); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.GenerateDailyReport(context.Background(), "2025-01-01"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fixtureTime is when the fixture's frozen clock stands
+var fixtureTime = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// newFixture returns a handler over a small fixed board with no background jobs
+func newFixture(t *testing.T, setup func(*testing.T, *services.LeaderboardService)) http.Handler {
+	t.Helper()
+
+	st := store.NewMemoryStore()
+	for _, u := range []struct {
+		name   string
+		rating int
+		bot    bool
+	}{
+		{"alice", 2400, false},
+		{"bot_1", 2250, true},
+		{"bob", 2100, false},
+		{"carol", 1800, false},
+		{"bot_2", 1650, true},
+		{"dave", 1500, false},
+		{"erin", 1200, false},
+		{"bot_3", 900, true},
+	} {
+		add := st.AddUser
+		if u.bot {
+			add = st.AddBot
+		}
+		if err := add(u.name, u.rating); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	service := services.NewLeaderboardService(st)
+	service.SetClock(clock.NewFake(fixtureTime))
+	if setup != nil {
+		setup(t, service)
+	}
+	return NewLeaderboardHandler(service).NewServeMux()
+}
+
+// cachingHeaders are the headers the cache policy sets
+var cachingHeaders = []string{"Cache-Control", "Surrogate-Control", "Vary"}
+
+// changedAliceOnServer rates scores with strategy and sets alice to 2300 at
+// the fixture time, then moves the clock on an hour, as if a client had been
+// offline since before then
+func changedAliceOnServer(strategy string) func(t *testing.T, s *services.LeaderboardService) {
+	return func(t *testing.T, s *services.LeaderboardService) {
+		rating, err := services.RatingStrategyByName(strategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.SetRatingStrategy(rating)
+		fake := clock.NewFake(fixtureTime)
+		s.SetClock(fake)
+		if err := s.UpdateScore(context.Background(), "alice", 2300); err != nil {
+			t.Fatal(err)
+		}
+		fake.Advance(time.Hour)
+	}
+}
+
+// enableDerivedBoards keeps every kind of derived board in memory
+func enableDerivedBoards(t *testing.T, s *services.LeaderboardService) {
+	if err := s.EnableDerivedBoards(services.DerivedBoardsConfig{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// playedAcrossBoards enables derived boards and plays a few matches: alice
+// and bob for US team red, carol for GB team blue, then bob moves to blue
+func playedAcrossBoards(t *testing.T, s *services.LeaderboardService) {
+	enableDerivedBoards(t, s)
+	playAcrossBoards(t, s)
+}
+
+// recomputedBoard plays the matches of playedAcrossBoards, knocks board out
+// of step with them, then waits for a recompute of it
+func recomputedBoard(board string) func(t *testing.T, s *services.LeaderboardService) {
+	return func(t *testing.T, s *services.LeaderboardService) {
+		boards := store.NewMemoryBoards()
+		if err := s.EnableDerivedBoards(services.DerivedBoardsConfig{Store: boards}); err != nil {
+			t.Fatal(err)
+		}
+		playAcrossBoards(t, s)
+		ctx := context.Background()
+		err := boards.Apply(ctx, []store.BoardOp{
+			{Op: store.OpAdd, Board: board, Member: "red", Value: 500},
+			{Op: store.OpSet, Board: board, Member: "bob", Value: 1},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		job, err := s.StartBoardRecompute(board)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job, err = s.WaitBoardRecompute(ctx, job.ID); err != nil || job.Status != models.RecomputeSucceeded {
+			t.Fatalf("recompute %s: %+v, %v", board, job, err)
+		}
+	}
+}
+
+func playAcrossBoards(t *testing.T, s *services.LeaderboardService) {
+	ctx := context.Background()
+	for _, update := range []struct {
+		username string
+		req      models.UpdateScoreRequest
+	}{
+		{"alice", models.UpdateScoreRequest{Rating: 2500, Country: "US", Team: "red"}},
+		{"bob", models.UpdateScoreRequest{Rating: 2150, Country: "US", Team: "red"}},
+		{"carol", models.UpdateScoreRequest{Rating: 1850, Country: "GB", Team: "blue"}},
+		{"bob", models.UpdateScoreRequest{Rating: 2200, Team: "blue"}},
+	} {
+		if _, err := s.SubmitScore(ctx, update.username, update.req); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// playedInCountries plays the matches of playAcrossBoards without derived
+// boards, so only the country stats place bob in the US, then in DE, and a
+// bot in GB
+func playedInCountries(t *testing.T, s *services.LeaderboardService) {
+	playAcrossBoards(t, s)
+	ctx := context.Background()
+	if _, err := s.SubmitScore(ctx, "bob", models.UpdateScoreRequest{Rating: 2250, Country: "DE"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SubmitScore(ctx, "bot_2", models.UpdateScoreRequest{Rating: 1700, Country: "GB"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// budgeted lets each read walk at most 6 of the fixture's 8 entries
+func budgeted(t *testing.T, s *services.LeaderboardService) {
+	s.SetQueryCostBudget(6)
+}
+
+// tightlyBudgeted lets each read walk at most 3 entries, fewer than the
+// fixture's 4 names starting with b
+func tightlyBudgeted(t *testing.T, s *services.LeaderboardService) {
+	s.SetQueryCostBudget(3)
+}
+
+// watchedCutoffs watches rank 3 with a threshold of 50 and the unfilled rank
+// 10 with none, then moves rank 3 from bob's 2100 to carol's 2200, which is
+// announced, and on to erin's 2230, which isn't
+func watchedCutoffs(t *testing.T, s *services.LeaderboardService) {
+	err := s.EnableCutoffWatch([]services.WatchedCutoff{{Rank: 10, Threshold: 0}, {Rank: 3, Threshold: 50}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	fake := clock.NewFake(fixtureTime)
+	s.SetClock(fake)
+	for _, update := range []struct {
+		username string
+		rating   int
+	}{
+		{"carol", 2200},
+		{"dave", 2120},
+		{"erin", 2230},
+	} {
+		fake.Advance(time.Minute)
+		if err := s.UpdateScore(ctx, update.username, update.rating); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// tiered splits the board into bronze, silver and gold, leaving bot_3 below
+// them, then moves erin up from bronze to silver
+func tiered(t *testing.T, s *services.LeaderboardService) {
+	tiers, err := services.ParseTierBoundaries("bronze:1000,silver:1500,gold:2000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetTierBoundaries(tiers); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateScore(context.Background(), "erin", 1600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// madePrivate hides alice from the leaderboard, anonymizes bob and hides
+// carol from search
+func madePrivate(t *testing.T, s *services.LeaderboardService) {
+	for username, req := range map[string]models.PrivacyRequest{
+		"alice": {HideFromLeaderboard: true},
+		"bob":   {Anonymize: true},
+		"carol": {HideFromSearch: true},
+	} {
+		if _, err := s.SetPrivacy(context.Background(), username, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// guestDevice carries the device token guests in the fixtures submit with
+var guestDevice = http.Header{"X-Device-Token": {"device-0123456789abcdef"}}
+
+// enableGuests accepts guest scores, with guest names from a fixed seed
+func enableGuests(t *testing.T, s *services.LeaderboardService) {
+	s.EnableGuests(services.GuestConfig{})
+	s.SetRandSeed(1)
+}
+
+// limitedGuests allows one guest per caller, and the fixtures' caller has
+// already made one from another device
+func limitedGuests(t *testing.T, s *services.LeaderboardService) {
+	s.EnableGuests(services.GuestConfig{CreationsPerHour: 1})
+	s.SetRandSeed(1)
+	_, err := s.SubmitGuestScore(context.Background(), "ip:192.0.2.1", "other-device-0123456789", models.GuestScoreRequest{Rating: 1500})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fullOfGuests keeps one guest at most, and another device made it
+func fullOfGuests(t *testing.T, s *services.LeaderboardService) {
+	s.EnableGuests(services.GuestConfig{MaxGuests: 1})
+	s.SetRandSeed(1)
+	_, err := s.SubmitGuestScore(context.Background(), "ip:198.51.100.7", "other-device-0123456789", models.GuestScoreRequest{Rating: 1500})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// playedAsGuest submits a 2500 for the guestDevice guest
+func playedAsGuest(t *testing.T, s *services.LeaderboardService) {
+	enableGuests(t, s)
+	_, err := s.SubmitGuestScore(context.Background(), "ip:192.0.2.1", guestDevice.Get("X-Device-Token"), models.GuestScoreRequest{Rating: 2500})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// claimedGuest has carol claim the guestDevice guest after it played
+func claimedGuest(t *testing.T, s *services.LeaderboardService) {
+	playedAsGuest(t, s)
+	if _, err := s.ClaimGuest(context.Background(), "carol", guestDevice.Get("X-Device-Token")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// mergedUsers has bob and carol play a match each, carol's a minute after
+// bob's, then merges bob into carol under policy
+func mergedUsers(policy string) func(t *testing.T, s *services.LeaderboardService) {
+	return func(t *testing.T, s *services.LeaderboardService) {
+		ctx := context.Background()
+		fake := clock.NewFake(fixtureTime)
+		s.SetClock(fake)
+		if err := s.UpdateScore(ctx, "bob", 2150); err != nil {
+			t.Fatal(err)
+		}
+		fake.Advance(time.Minute)
+		if err := s.UpdateScore(ctx, "carol", 1750); err != nil {
+			t.Fatal(err)
+		}
+		fake.Advance(time.Minute)
+		_, err := s.MergeUsers(ctx, models.MergeUsersRequest{From: "bob", Into: "carol", Policy: policy, Reason: "duplicate account"}, "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// failingBoards is a derived board store whose updates and listings always
+// fail, as if its server were down
+type failingBoards struct {
+	store.Boards
+}
+
+func (failingBoards) Apply(ctx context.Context, ops []store.BoardOp) error {
+	return errors.New("connection refused")
+}
+
+func (failingBoards) List(ctx context.Context) (map[string]int, error) {
+	return nil, errors.New("connection refused")
+}
+
+// enableFailingBoards keeps derived boards on failingBoards
+func enableFailingBoards(t *testing.T, s *services.LeaderboardService) {
+	if err := s.EnableDerivedBoards(services.DerivedBoardsConfig{Store: failingBoards{store.NewMemoryBoards()}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tieOnAliceRecently ranks equal ratings most recent first and moves bob,
+// then a minute later carol, up to alice's rating
+func tieOnAliceRecently(t *testing.T, s *services.LeaderboardService) {
+	fake := clock.NewFake(fixtureTime)
+	s.SetClock(fake)
+	s.SetTiePolicy(ranking.MostRecentFirst)
+	ctx := context.Background()
+	if err := s.UpdateScore(ctx, "bob", 2400); err != nil {
+		t.Fatal(err)
+	}
+	fake.Advance(time.Minute)
+	if err := s.UpdateScore(ctx, "carol", 2400); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := newFixture(t, tc.setup)
+
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			for key, values := range tc.header {
+				req.Header[key] = values
+			}
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			} else if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			got := renderResponse(t, tc, rec)
+			path := filepath.Join("testdata", "golden", tc.name+".golden")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run go test ./internal/handlers -run TestGolden -update)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("response differs from %s\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+// TestGoldenCoversEveryRoute fails for routes without a golden case
+func TestGoldenCoversEveryRoute(t *testing.T) {
+	h := NewLeaderboardHandler(services.NewLeaderboardService(store.NewMemoryStore()))
+	mux := http.NewServeMux()
+	covered := make(map[string]bool)
+	for _, route := range h.routeTable() {
+		pattern := route.Method + " " + route.Path
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			covered[pattern] = true
+		})
+	}
+	for _, tc := range goldenCases {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, tc.target, nil))
+	}
+
+	for _, route := range h.routeTable() {
+		if pattern := route.Method + " " + route.Path; !covered[pattern] {
+			t.Errorf("no golden case for %s", pattern)
+		}
+	}
+}
+
+// renderResponse writes the status, content type and normalized body
+func renderResponse(t *testing.T, tc goldenCase, rec *httptest.ResponseRecorder) []byte {
+	t.Helper()
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s %s\n", tc.method, tc.target)
+	if len(tc.header) > 0 {
+		tc.header.Write(&out)
+	}
+	if tc.body != "" {
+		fmt.Fprintf(&out, "%s\n", tc.body)
+	}
+	fmt.Fprintf(&out, "\n%d %s\n", rec.Code, rec.Header().Get("Content-Type"))
+	if location := rec.Header().Get("Location"); location != "" {
+		fmt.Fprintf(&out, "Location: %s\n", scrubString(location))
+	}
+	for _, key := range append([]string{"Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"}, tc.showHeaders...) {
+		if value := rec.Header().Get(key); value != "" {
+			fmt.Fprintf(&out, "%s: %s\n", key, value)
+		}
+	}
+	out.WriteString("\n")
+
+	contentType := rec.Header().Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "application/json"):
+		out.Write(normalizeJSON(t, rec.Body.Bytes()))
+	case strings.HasPrefix(contentType, "application/x-ndjson"), strings.HasPrefix(contentType, "application/jsonl"):
+		for _, line := range bytes.Split(bytes.TrimSpace(rec.Body.Bytes()), []byte("\n")) {
+			compact := normalizeJSON(t, line)
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, compact); err != nil {
+				t.Fatal(err)
+			}
+			out.Write(buf.Bytes())
+			out.WriteString("\n")
+		}
+	case strings.HasPrefix(contentType, "image/"):
+		// Rasterized pixels vary with font hinting, so only the shape is pinned
+		config, format, err := image.DecodeConfig(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&out, "%s %dx%d\n", format, config.Width, config.Height)
+	default:
+		out.Write(rec.Body.Bytes())
+	}
+	if !bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+		out.WriteString("\n")
+	}
+	return out.Bytes()
+}
+
+// volatileKeys hold durations measured while the test runs
+var volatileKeys = map[string]bool{
+	"uptime_seconds":   true,
+	"duration_ms":      true,
+	"duration_seconds": true,
+}
+
+// generatedID matches random IDs such as submission IDs
+var generatedID = regexp.MustCompile(`\b[0-9a-f]{24}\b`)
+
+// normalizeJSON pretty-prints body with wall-clock times, durations and
+// generated IDs replaced by placeholders, so the golden files only change with the
+// response shape
+func normalizeJSON(t *testing.T, body []byte) []byte {
+	t.Helper()
+
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("invalid JSON %q: %v", body, err)
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(scrub(v)); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func scrub(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if volatileKeys[key] {
+				v[key] = "<duration>"
+				continue
+			}
+			v[key] = scrub(value)
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = scrub(v[i])
+		}
+		return v
+	case string:
+		return scrubString(v)
+	}
+	return v
+}
+
+// scrubString hides wall-clock times and generated IDs. Times read from the
+// fixture's frozen clock fall within a year of fixtureTime and are kept.
+func scrubString(s string) string {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		if t.Before(fixtureTime.AddDate(1, 0, 0)) {
+			return s
+		}
+		return "<time>"
+	}
+	return generatedID.ReplaceAllString(s, "<id>")
+}
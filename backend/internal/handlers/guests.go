@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"backend/internal/models"
+	"backend/internal/services"
+	"backend/pkg/store"
+)
+
+// Device tokens are generated by the client and kept on the device
+const (
+	minDeviceToken = 16
+	maxDeviceToken = 128
+)
+
+// respondGuestError maps a failed guest submission or claim to its HTTP response
+func respondGuestError(w http.ResponseWriter, err error) {
+	var limited *services.GuestCreationError
+	switch {
+	case errors.As(err, &limited):
+		w.Header().Set("Retry-After", strconv.Itoa(limited.ResetSeconds))
+		writeError(w, http.StatusTooManyRequests, "guest_creation_limited", "Too many new guests from this caller, retry after "+strconv.Itoa(limited.ResetSeconds)+"s")
+	case errors.Is(err, services.ErrGuestsFull):
+		writeError(w, http.StatusServiceUnavailable, "guests_full", "No more guests can be created; register to submit scores")
+	case errors.Is(err, services.ErrGuestsDisabled):
+		writeError(w, http.StatusNotFound, "guests_disabled", "Set GUEST_SUBMISSIONS=true to accept guest scores")
+	case errors.Is(err, services.ErrGuestNotFound):
+		writeError(w, http.StatusNotFound, "guest_not_found", "No guest has submitted with this device token")
+	case errors.Is(err, store.ErrMemoryBudget):
+		writeFailure(w, "update_failed", err)
+	default:
+		respondScoreError(w, err)
+	}
+}
+
+// SubmitGuestScore applies a score for a player who hasn't registered,
+// identified by a token their device keeps. The first submission creates a
+// guest with a generated name, returned with the result. Guests only submit
+// match results: TTLs, countries and teams are for registered users.
+// POST /api/guests/score
+func (h *LeaderboardHandler) SubmitGuestScore(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("X-Device-Token")
+	switch {
+	case token == "":
+		respondFieldErrors(w, models.FieldError{Field: "X-Device-Token", Rule: "required"})
+		return
+	case len(token) < minDeviceToken:
+		respondFieldErrors(w, models.FieldError{Field: "X-Device-Token", Rule: "min", Param: strconv.Itoa(minDeviceToken)})
+		return
+	case len(token) > maxDeviceToken:
+		respondFieldErrors(w, models.FieldError{Field: "X-Device-Token", Rule: "max", Param: strconv.Itoa(maxDeviceToken)})
+		return
+	}
+
+	var req models.GuestScoreRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	result, err := h.service.SubmitGuestScore(r.Context(), h.rateCaller(r), token, req)
+	if err != nil {
+		respondGuestError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ClaimGuest merges the guest a device token belongs to into the
+// authenticated user, who keeps the better score and the guest's history
+// POST /api/users/claim
+func (h *LeaderboardHandler) ClaimGuest(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.me(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ClaimGuestRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	result, err := h.service.ClaimGuest(r.Context(), claims.Subject, req.DeviceToken)
+	if err != nil {
+		respondGuestError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
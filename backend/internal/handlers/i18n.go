@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"embed"
+	"encoding/json"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"backend/internal/models"
+)
+
+// defaultLanguage is what messages are written in before translation
+const defaultLanguage = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// catalog translates error messages for one language. Error codes stay in
+// English so clients can keep matching on them.
+type catalog struct {
+	Errors map[string]string `json:"errors"` // Message per error code
+	Rules  map[string]string `json:"rules"`  // Field error template per rule, with {field} and {param}
+}
+
+// catalogs holds every translation, keyed by language
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[string]catalog {
+	files, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	loaded := make(map[string]catalog, len(files))
+	for _, f := range files {
+		data, err := localeFiles.ReadFile("locales/" + f.Name())
+		if err != nil {
+			panic(err)
+		}
+		var c catalog
+		if err := json.Unmarshal(data, &c); err != nil {
+			panic("locales/" + f.Name() + ": " + err.Error())
+		}
+		loaded[strings.TrimSuffix(f.Name(), path.Ext(f.Name()))] = c
+	}
+	return loaded
+}
+
+// negotiateLanguage picks the supported language the client prefers most,
+// matching on the primary subtag so fr-CH is served French
+func negotiateLanguage(acceptLanguage string) string {
+	type preference struct {
+		lang string
+		q    float64
+	}
+	var prefs []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		lang, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if lang == "*" {
+			lang = defaultLanguage
+		}
+		if _, ok := catalogs[lang]; (ok || lang == defaultLanguage) && q > 0 {
+			prefs = append(prefs, preference{lang: lang, q: q})
+		}
+	}
+	if len(prefs) == 0 {
+		return defaultLanguage
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+	return prefs[0].lang
+}
+
+// localizedWriter carries the negotiated language down to writeError
+type localizedWriter struct {
+	http.ResponseWriter
+	lang string
+}
+
+// Flush keeps streaming endpoints streaming
+func (lw *localizedWriter) Flush() {
+	if f, ok := lw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (lw *localizedWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
+
+// localize negotiates each request's language from Accept-Language
+func localize(routes []Route) []Route {
+	for i, route := range routes {
+		next := route.Handler
+		routes[i].Handler = func(w http.ResponseWriter, r *http.Request) {
+			lang := negotiateLanguage(r.Header.Get("Accept-Language"))
+			// WebSocket upgrades hijack the connection, which the wrapper can't
+			if lang == defaultLanguage || r.Header.Get("Upgrade") != "" {
+				next(w, r)
+				return
+			}
+			next(&localizedWriter{ResponseWriter: w, lang: lang}, r)
+		}
+	}
+	return routes
+}
+
+// languageOf returns the language negotiated for the response
+func languageOf(w http.ResponseWriter) string {
+	for {
+		switch rw := w.(type) {
+		case *localizedWriter:
+			return rw.lang
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return defaultLanguage
+		}
+	}
+}
+
+// translateError rewrites an error body's message in the response's
+// language. Messages without a translation are left in English.
+func translateError(w http.ResponseWriter, body models.ErrorResponse) models.ErrorResponse {
+	lang := languageOf(w)
+	c, ok := catalogs[lang]
+	if !ok {
+		return body
+	}
+	w.Header().Set("Content-Language", lang)
+
+	if len(body.Details) > 0 {
+		parts := make([]string, 0, len(body.Details))
+		for _, d := range body.Details {
+			parts = append(parts, c.fieldMessage(d))
+		}
+		body.Message = strings.Join(parts, "; ")
+		return body
+	}
+	if message, ok := c.Errors[body.Error]; ok {
+		body.Message = message
+	}
+	return body
+}
+
+func (c catalog) fieldMessage(e models.FieldError) string {
+	template, ok := c.Rules[e.Rule]
+	if !ok {
+		return e.String()
+	}
+	param := e.Param
+	if e.Rule == "required_without" || e.Rule == "nefield" {
+		param = strings.ToLower(param)
+	}
+	return strings.NewReplacer("{field}", e.Field, "{param}", param).Replace(template)
+}
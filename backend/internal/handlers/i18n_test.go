@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"backend/internal/models"
+)
+
+// errorCodes finds the error codes the package's handlers write: the codes
+// passed to writeError and set in ErrorResponse literals, and separately
+// those passed to writeFailure. Failures carry the underlying error as their
+// message, so they are left in English.
+func errorCodes(t *testing.T) (codes, failures map[string]bool) {
+	t.Helper()
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, failures = make(map[string]bool), make(map[string]bool)
+	literal := func(e ast.Expr) (string, bool) {
+		lit, ok := e.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(lit.Value)
+		return s, err == nil
+	}
+
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				fn, ok := n.Fun.(*ast.Ident)
+				switch {
+				case !ok:
+				case fn.Name == "writeError" && len(n.Args) == 4:
+					if code, ok := literal(n.Args[2]); ok {
+						codes[code] = true
+					}
+				case fn.Name == "writeFailure" && len(n.Args) == 3:
+					if code, ok := literal(n.Args[1]); ok {
+						failures[code] = true
+					}
+				}
+			case *ast.CompositeLit:
+				if sel, ok := n.Type.(*ast.SelectorExpr); !ok || sel.Sel.Name != "ErrorResponse" {
+					return true
+				}
+				for _, elt := range n.Elts {
+					if kv, ok := elt.(*ast.KeyValueExpr); ok && kv.Key.(*ast.Ident).Name == "Error" {
+						if code, ok := literal(kv.Value); ok {
+							codes[code] = true
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+	return codes, failures
+}
+
+// validationRules finds the rules field errors can name: those in the
+// models' binding tags, and those the query parsers report
+func validationRules(t *testing.T) []string {
+	t.Helper()
+	var rules []string
+	add := func(rule string) {
+		if rule != "" && rule != "omitempty" && rule != "dive" && !slices.Contains(rules, rule) {
+			rules = append(rules, rule)
+		}
+	}
+
+	fset := token.NewFileSet()
+	for _, pattern := range []string{"../models/*.go", "*.go"} {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range files {
+			if strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			file, err := parser.ParseFile(fset, name, nil, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ast.Inspect(file, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.Field:
+					if n.Tag == nil {
+						return true
+					}
+					tag, _ := strconv.Unquote(n.Tag.Value)
+					for _, rule := range strings.Split(reflect.StructTag(tag).Get("binding"), ",") {
+						name, _, _ := strings.Cut(rule, "=")
+						add(name)
+					}
+				case *ast.KeyValueExpr:
+					if key, ok := n.Key.(*ast.Ident); ok && key.Name == "Rule" {
+						if lit, ok := n.Value.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+							rule, _ := strconv.Unquote(lit.Value)
+							add(rule)
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+	return rules
+}
+
+func TestCatalogsCoverEveryErrorCode(t *testing.T) {
+	codes, failures := errorCodes(t)
+	if len(codes) == 0 {
+		t.Fatal("found no error codes")
+	}
+	rules := validationRules(t)
+	for _, c := range catalogs {
+		for rule := range c.Rules {
+			if !slices.Contains(rules, rule) {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	slices.Sort(rules)
+	for _, rule := range rules {
+		// English messages are written by FieldError itself
+		if message := (models.FieldError{Field: "f", Rule: rule}).String(); strings.HasSuffix(message, " validation") {
+			t.Errorf("en: no message for the %s rule", rule)
+		}
+	}
+
+	for _, lang := range slices.Sorted(maps.Keys(catalogs)) {
+		c := catalogs[lang]
+		for _, code := range slices.Sorted(maps.Keys(codes)) {
+			if c.Errors[code] == "" && !failures[code] {
+				t.Errorf("%s: no message for %s", lang, code)
+			}
+		}
+		for code := range c.Errors {
+			if !codes[code] && !failures[code] {
+				t.Errorf("%s: message for %s, which no handler writes", lang, code)
+			}
+		}
+		for _, rule := range rules {
+			if c.Rules[rule] == "" {
+				t.Errorf("%s: no template for the %s rule", lang, rule)
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"backend/internal/models"
+	"backend/internal/services"
+)
+
+const (
+	maxProviderLength   = 64
+	maxExternalIDLength = 256
+)
+
+// identityParams reads and validates the provider and external ID path params
+func identityParams(w http.ResponseWriter, r *http.Request) (provider, externalID string, ok bool) {
+	provider = pathParam(r, "provider")
+	externalID = pathParam(r, "external_id")
+
+	var details []models.FieldError
+	if len(provider) > maxProviderLength {
+		details = append(details, models.FieldError{Field: "provider", Rule: "max", Param: "64", Value: provider})
+	}
+	if len(externalID) > maxExternalIDLength {
+		details = append(details, models.FieldError{Field: "external_id", Rule: "max", Param: "256", Value: externalID})
+	}
+	if len(details) > 0 {
+		respondFieldErrors(w, details...)
+		return "", "", false
+	}
+	return provider, externalID, true
+}
+
+// LinkIdentity maps an external identity to a leaderboard user
+// PUT /api/identities/{provider}/{external_id}
+func (h *LeaderboardHandler) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	provider, externalID, ok := identityParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.LinkIdentityRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	link, err := h.service.LinkExternalID(r.Context(), provider, externalID, req.Username)
+	if err != nil {
+		if errors.Is(err, services.ErrExternalIDTaken) {
+			writeError(w, http.StatusConflict, "external_id_taken", "External ID is linked to another user, unlink it first")
+			return
+		}
+		if err.Error() == "user not found" {
+			writeError(w, http.StatusNotFound, "user_not_found", "User does not exist")
+			return
+		}
+		writeFailure(w, "link_failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, link)
+}
+
+// GetIdentity looks up which user an external identity belongs to
+// GET /api/identities/{provider}/{external_id}
+func (h *LeaderboardHandler) GetIdentity(w http.ResponseWriter, r *http.Request) {
+	provider, externalID, ok := identityParams(w, r)
+	if !ok {
+		return
+	}
+
+	link, err := h.service.GetExternalID(h.viewing(r), provider, externalID)
+	if err != nil {
+		if errors.Is(err, services.ErrExternalIDNotFound) {
+			writeError(w, http.StatusNotFound, "unknown_external_id", "External ID is not mapped to a leaderboard user")
+			return
+		}
+		writeFailure(w, "fetch_failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, link)
+}
+
+// UnlinkIdentity removes an external identity's mapping
+// DELETE /api/identities/{provider}/{external_id}
+func (h *LeaderboardHandler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	provider, externalID, ok := identityParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.UnlinkExternalID(r.Context(), provider, externalID); err != nil {
+		if errors.Is(err, services.ErrExternalIDNotFound) {
+			writeError(w, http.StatusNotFound, "unknown_external_id", "External ID is not mapped to a leaderboard user")
+			return
+		}
+		writeFailure(w, "unlink_failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{
+		"message": "Identity unlinked",
+	})
+}
+
+// ListUserIdentities lists the external identities linked to a user
+// GET /api/users/{username}/identities
+func (h *LeaderboardHandler) ListUserIdentities(w http.ResponseWriter, r *http.Request) {
+	username := pathParam(r, "username")
+
+	identities, err := h.service.ListExternalIDs(h.viewing(r), username)
+	if err != nil {
+		if err.Error() == "user not found" {
+			writeError(w, http.StatusNotFound, "user_not_found", "User does not exist")
+			return
+		}
+		writeFailure(w, "fetch_failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{
+		"username":   username,
+		"identities": identities,
+		"count":      len(identities),
+	})
+}
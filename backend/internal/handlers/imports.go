@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"backend/internal/services"
+)
+
+// respondImportError maps score import failures to API errors
+func respondImportError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrImportDisabled):
+		writeError(w, http.StatusNotFound, "import_disabled", "Score file import is not enabled")
+	case errors.Is(err, services.ErrImportRunning):
+		writeError(w, http.StatusConflict, "import_running", "An import is already running")
+	case errors.Is(err, services.ErrInvalidSignature):
+		writeError(w, http.StatusBadGateway, "import_invalid_signature", "The score file's signature does not match")
+	case errors.Is(err, services.ErrReplayedImport):
+		writeError(w, http.StatusBadGateway, "import_replayed", "The score file is not signed after the last applied one; each file needs a later timestamp and a fresh nonce")
+	default:
+		writeError(w, http.StatusBadGateway, "import_failed", err.Error())
+	}
+}
+
+// GetImportStatus describes the score file import and its recent jobs
+// GET /api/admin/imports
+func (h *LeaderboardHandler) GetImportStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.service.ImportStatus(r.Context())
+	if err != nil {
+		respondImportError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// RunImport checks the score file now rather than waiting for the schedule
+// POST /api/admin/imports/run
+func (h *LeaderboardHandler) RunImport(w http.ResponseWriter, r *http.Request) {
+	job, err := h.service.RunImport(r.Context(), services.ImportTriggerManual)
+	if job == nil && err == nil {
+		writeJSON(w, http.StatusOK, H{"message": "Score file not modified since the last import"})
+		return
+	}
+	if job == nil {
+		respondImportError(w, err)
+		return
+	}
+	// A failed job is still reported, with its error
+	writeJSON(w, http.StatusOK, job)
+}
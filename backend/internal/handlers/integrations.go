@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"backend/internal/models"
+	"backend/internal/services"
+)
+
+// maxIntegrationBody bounds signed webhook payloads
+const maxIntegrationBody = 64 * 1024
+
+// SubmitIntegrationScore accepts a signed score from a third-party game platform.
+// The platform signs "<X-Timestamp>.<X-Nonce>.<body>" with its shared secret.
+// POST /api/integrations/scores
+func (h *LeaderboardHandler) SubmitIntegrationScore(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIntegrationBody))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "body_too_large", "Request body is too large")
+		return
+	}
+
+	platform := r.Header.Get("X-Platform")
+	err = h.service.VerifyIntegration(platform,
+		r.Header.Get("X-Timestamp"),
+		r.Header.Get("X-Nonce"),
+		r.Header.Get("X-Signature"),
+		body)
+	switch {
+	case errors.Is(err, services.ErrIntegrationsDisabled):
+		writeError(w, http.StatusNotFound, "integrations_disabled", "Set INTEGRATION_SECRETS to accept platform scores")
+		return
+	case errors.Is(err, services.ErrInvalidSignature):
+		writeError(w, http.StatusUnauthorized, "invalid_signature", "Unknown platform or bad signature")
+		return
+	case errors.Is(err, services.ErrStaleRequest):
+		writeError(w, http.StatusUnauthorized, "stale_request", "X-Timestamp is outside the allowed clock skew")
+		return
+	case errors.Is(err, services.ErrReplayedRequest):
+		writeError(w, http.StatusConflict, "replayed_request", "X-Nonce has already been used")
+		return
+	case err != nil:
+		writeFailure(w, "verification_failed", err)
+		return
+	}
+
+	// The body has been consumed for the signature; bind from the verified bytes
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	var req models.IntegrationScoreRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	result, err := h.service.SubmitIntegrationScore(r.Context(), platform, req)
+	if err != nil {
+		if errors.Is(err, services.ErrExternalIDNotFound) {
+			writeError(w, http.StatusNotFound, "unknown_external_id", "External ID is not mapped to a leaderboard user")
+			return
+		}
+		respondScoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
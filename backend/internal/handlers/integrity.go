@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+)
+
+// CheckIntegrity scans the board for index inconsistencies and out-of-range
+// ratings, repairing them with ?repair=true
+// POST /api/admin/integrity-check?repair=true
+func (h *LeaderboardHandler) CheckIntegrity(w http.ResponseWriter, r *http.Request) {
+	repair, fieldErr := queryBool(r, "repair")
+	if fieldErr != nil {
+		respondFieldErrors(w, *fieldErr)
+		return
+	}
+
+	report, err := h.service.IntegrityCheck(r.Context(), repair, actor(r))
+	if err != nil {
+		writeFailure(w, "integrity_check_failed", err)
+		return
+	}
+	if repair && report.Repaired > 0 {
+		log.Printf("🛠️  %s repaired %d of %d integrity issue(s)", actor(r), report.Repaired, report.Count)
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -1,184 +1,643 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"math"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"backend/internal/auth"
+	"backend/internal/events"
 	"backend/internal/models"
 	"backend/internal/services"
+)
 
-	"github.com/gin-gonic/gin"
+const (
+	defaultSearchLimit = 10000
+	maxSearchLimit     = 1000000
 )
 
 type LeaderboardHandler struct {
-	service *services.LeaderboardService
+	service  *services.LeaderboardService
+	streams  *streamTracker
+	captures *captureSampler
+	cards    *cardCache
+	shedder  loadShedder
+	caching  *cacheHeaders
+	warming  atomic.Bool // /readyz fails until the startup warm-up finishes
+	auth     *auth.Auth  // nil unless login is enabled
 }
 
 func NewLeaderboardHandler(service *services.LeaderboardService) *LeaderboardHandler {
-	return &LeaderboardHandler{service: service}
+	return &LeaderboardHandler{
+		service:  service,
+		streams:  newStreamTracker(),
+		captures: newCaptureSampler(),
+		cards:    newCardCache(),
+		caching:  &cacheHeaders{policy: DefaultCachePolicy()},
+	}
 }
 
-// SeedData seeds the leaderboard with users
-// POST /api/seed
-func (h *LeaderboardHandler) SeedData(c *gin.Context) {
-	var req models.SeedRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "invalid_request",
-			Message: err.Error(),
-		})
-		return
-	}
+// GetCapabilities reports which optional features this deployment serves
+// GET /api/capabilities
+func (h *LeaderboardHandler) GetCapabilities(w http.ResponseWriter, r *http.Request) {
+	caps := h.service.Capabilities(r.Context())
+	caps.Features.Login = h.auth != nil
+	writeJSON(w, http.StatusOK, caps)
+}
 
-	if err := h.service.SeedData(c.Request.Context(), req.Count); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "seed_failed",
-			Message: err.Error(),
-		})
+// GetBoardMetadata returns a board's configuration
+// GET /api/leaderboards/{id}
+func (h *LeaderboardHandler) GetBoardMetadata(w http.ResponseWriter, r *http.Request) {
+	if pathParam(r, "id") != h.service.BoardID() {
+		writeError(w, http.StatusNotFound, "board_not_found", "Leaderboard does not exist")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Data seeded successfully",
-		"count":   req.Count,
-	})
+	writeJSON(w, http.StatusOK, h.service.GetBoardMetadata(r.Context()))
 }
 
 // GetLeaderboard retrieves paginated leaderboard
-// GET /api/leaderboard?page=1&limit=50
-func (h *LeaderboardHandler) GetLeaderboard(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+// GET /api/leaderboard?page=1&limit=50&exclude_bots=true
+func (h *LeaderboardHandler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	page, pageErr := queryInt(r, "page", 1, 1, math.MaxInt32)
+	limit, limitErr := queryInt(r, "limit", 50, 1, 100)
+	excludeBots, botsErr := queryBool(r, "exclude_bots")
+	if details := collectFieldErrors(pageErr, limitErr, botsErr); len(details) > 0 {
+		respondFieldErrors(w, details...)
+		return
+	}
+
+	opts := services.ListOptions{ExcludeBots: excludeBots}
+	leaderboard, err := h.service.GetLeaderboard(r.Context(), page, limit, opts)
+	if err != nil {
+		writeFailure(w, "fetch_failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, leaderboard)
+}
 
-	if page < 1 {
-		page = 1
+// ListUsers pages through users alphabetically, for admin UIs that browse by name
+// GET /api/users?sort=username&cursor=user_123&limit=50
+func (h *LeaderboardHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var details []models.FieldError
+	if order := query.Get("sort"); order != "" && order != "username" {
+		details = append(details, models.FieldError{Field: "sort", Rule: "oneof", Param: "username", Value: order})
 	}
-	if limit < 1 || limit > 100 {
-		limit = 50
+	limit, limitErr := queryInt(r, "limit", 50, 1, 100)
+	details = append(details, collectFieldErrors(limitErr)...)
+	if len(details) > 0 {
+		respondFieldErrors(w, details...)
+		return
 	}
 
-	leaderboard, err := h.service.GetLeaderboard(c.Request.Context(), page, limit)
+	users, err := h.service.ListUsersByName(r.Context(), query.Get("cursor"), limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "fetch_failed",
-			Message: err.Error(),
-		})
+		writeFailure(w, "fetch_failed", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, leaderboard)
+	writeJSON(w, http.StatusOK, users)
 }
 
-// GetUserRank retrieves a specific user's rank
-// GET /api/users/:username
-func (h *LeaderboardHandler) GetUserRank(c *gin.Context) {
-	username := c.Param("username")
+// GetUserRank retrieves a specific user's rank, now or at a past moment
+// GET /api/users/{username}?at=2025-01-01T00:00:00Z
+func (h *LeaderboardHandler) GetUserRank(w http.ResponseWriter, r *http.Request) {
+	username := pathParam(r, "username")
 	if username == "" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "invalid_username",
-			Message: "Username is required",
-		})
+		respondFieldErrors(w, models.FieldError{Field: "username", Rule: "required"})
+		return
+	}
+
+	at, atErr := queryTime(r, "at")
+	if atErr != nil {
+		respondFieldErrors(w, *atErr)
 		return
 	}
 
-	userRank, err := h.service.GetUserRank(c.Request.Context(), username)
+	var userRank *models.UserRankResponse
+	var err error
+	if at.IsZero() {
+		userRank, err = h.service.GetUserRank(h.viewing(r), username)
+	} else {
+		userRank, err = h.service.GetUserRankAt(h.viewing(r), username, at)
+	}
 	if err != nil {
+		if errors.Is(err, services.ErrNotRankedAt) {
+			writeError(w, http.StatusNotFound, "not_ranked", "User was not on the board at that time")
+			return
+		}
 		if err.Error() == "user not found" {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Error:   "user_not_found",
-				Message: "User does not exist",
-			})
+			writeError(w, http.StatusNotFound, "user_not_found", "User does not exist")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "fetch_failed",
-			Message: err.Error(),
-		})
+		writeFailure(w, "fetch_failed", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, userRank)
+	writeJSON(w, http.StatusOK, userRank)
 }
 
-// UpdateScore updates a user's score
-// POST /api/users/:username/score
-func (h *LeaderboardHandler) UpdateScore(c *gin.Context) {
-	username := c.Param("username")
+// UpdateScore updates a user's score, or queues the update with ?async=true
+// POST /api/users/{username}/score
+func (h *LeaderboardHandler) UpdateScore(w http.ResponseWriter, r *http.Request) {
+	username := pathParam(r, "username")
 	if username == "" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "invalid_username",
-			Message: "Username is required",
-		})
+		respondFieldErrors(w, models.FieldError{Field: "username", Rule: "required"})
+		return
+	}
+
+	async, asyncErr := queryBool(r, "async")
+	if asyncErr != nil {
+		respondFieldErrors(w, *asyncErr)
 		return
 	}
 
 	var req models.UpdateScoreRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	if async {
+		h.enqueueScore(w, r, username, req)
+		return
+	}
+
+	result, err := h.service.SubmitScore(r.Context(), username, req)
+	if err != nil {
+		respondScoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// respondScoreError maps a failed score submission to its HTTP response
+func respondScoreError(w http.ResponseWriter, err error) {
+	status, body := scoreErrorResponse(err)
+	writeErrorResponse(w, status, body)
+}
+
+// scoreErrorResponse maps a failed score update to its status and error body
+func scoreErrorResponse(err error) (int, models.ErrorResponse) {
+	var fieldErr models.FieldError
+	switch {
+	case errors.As(err, &fieldErr):
+		return http.StatusBadRequest, models.ErrorResponse{
 			Error:   "invalid_request",
-			Message: err.Error(),
-		})
+			Message: fieldErr.String(),
+			Details: []models.FieldError{fieldErr},
+		}
+	case errors.Is(err, services.ErrUserFrozen):
+		return http.StatusLocked, models.ErrorResponse{Error: "user_frozen", Message: "Updates for this user are frozen pending review"}
+	case errors.Is(err, services.ErrUserSuspended):
+		return http.StatusLocked, models.ErrorResponse{Error: "user_frozen", Message: "Updates for this user are frozen by a moderator"}
+	case errors.Is(err, services.ErrMatchInProgress):
+		return http.StatusConflict, models.ErrorResponse{Error: "match_in_progress", Message: "This match is already being applied, retry shortly"}
+	case errors.Is(err, services.ErrMatchLedgerUnavailable):
+		return http.StatusServiceUnavailable, models.ErrorResponse{Error: "match_ledger_unavailable", Message: "Applied matches could not be checked, so the match was not applied, retry shortly"}
+	case errors.Is(err, services.ErrSyncInProgress):
+		return http.StatusConflict, models.ErrorResponse{Error: "sync_in_progress", Message: "Another offline sync for this user is being applied, retry shortly"}
+	case errors.Is(err, services.ErrDeadLettered):
+		return http.StatusInternalServerError, models.ErrorResponse{Error: "dead_lettered", Message: "The submission could not be applied after repeated attempts"}
+	case errors.Is(err, services.ErrBoardsUnavailable):
+		return http.StatusServiceUnavailable, models.ErrorResponse{Error: "boards_unavailable", Message: "The update could not be applied to every board, so it was not applied, retry shortly"}
+	case errors.Is(err, services.ErrSubmissionDropped):
+		return http.StatusServiceUnavailable, models.ErrorResponse{Error: "submission_dropped", Message: "The submission was dropped while the queue was unavailable"}
+	case err.Error() == "user not found":
+		return http.StatusNotFound, models.ErrorResponse{Error: "user_not_found", Message: "User does not exist"}
+	case errors.Is(err, context.Canceled):
+		return statusClientClosedRequest, models.ErrorResponse{Error: "client_closed_request", Message: "The client closed the request before it completed"}
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, models.ErrorResponse{Error: "timeout", Message: "The request did not complete before its deadline"}
+	}
+	return http.StatusInternalServerError, models.ErrorResponse{Error: "update_failed", Message: err.Error()}
+}
+
+// GetScoreHistory lists a user's recent score changes, newest first
+// GET /api/users/{username}/history?reason=admin_adjustment&source=api&resolution=hour&limit=50
+func (h *LeaderboardHandler) GetScoreHistory(w http.ResponseWriter, r *http.Request) {
+	username := pathParam(r, "username")
+	limit, limitErr := queryInt(r, "limit", 50, 1, 100)
+
+	var reasonErr *models.FieldError
+	reason := r.URL.Query().Get("reason")
+	switch reason {
+	case "", models.ReasonMatch, models.ReasonAdminAdjustment, models.ReasonDecay, models.ReasonRollback, models.ReasonMerge:
+	default:
+		reasonErr = &models.FieldError{
+			Field: "reason",
+			Rule:  "oneof",
+			Param: "match admin_adjustment decay rollback merge",
+			Value: reason,
+		}
+	}
+
+	var sourceErr *models.FieldError
+	source := r.URL.Query().Get("source")
+	if source != "" && !slices.Contains(services.Sources, source) {
+		sourceErr = &models.FieldError{
+			Field: "source",
+			Rule:  "oneof",
+			Param: strings.Join(services.Sources, " "),
+			Value: source,
+		}
+	}
+
+	var resolutionErr *models.FieldError
+	resolution := r.URL.Query().Get("resolution")
+	if resolution != "" && !slices.Contains(services.HistoryResolutions, resolution) {
+		resolutionErr = &models.FieldError{
+			Field: "resolution",
+			Rule:  "oneof",
+			Param: strings.Join(services.HistoryResolutions, " "),
+			Value: resolution,
+		}
+	}
+
+	if details := collectFieldErrors(limitErr, reasonErr, sourceErr, resolutionErr); len(details) > 0 {
+		respondFieldErrors(w, details...)
 		return
 	}
 
-	if err := h.service.UpdateScore(c.Request.Context(), username, req.Rating); err != nil {
+	filter := services.HistoryFilter{Reason: reason, Source: source, Resolution: resolution}
+	history, err := h.service.GetScoreHistory(h.viewing(r), username, filter, limit)
+	if err != nil {
 		if err.Error() == "user not found" {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Error:   "user_not_found",
-				Message: "User does not exist",
-			})
+			writeError(w, http.StatusNotFound, "user_not_found", "User does not exist")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "update_failed",
-			Message: err.Error(),
-		})
+		writeFailure(w, "fetch_failed", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Score updated successfully",
-	})
+	writeJSON(w, http.StatusOK, history)
 }
 
-// SearchUser searches for users
-// GET /api/search?q=user_123
-func (h *LeaderboardHandler) SearchUser(c *gin.Context) {
-	query := c.Query("q")
+// SearchUser searches for users, a page at a time in rank order
+// GET /api/search?q=user_123&limit=100&page=2&format=jsonl
+// GET /api/search?q=user_123&limit=100&cursor=NDUwMDp1c2VyXzEyMw
+func (h *LeaderboardHandler) SearchUser(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	limit, limitErr := queryInt(r, "limit", defaultSearchLimit, 1, maxSearchLimit)
+	page, pageErr := queryInt(r, "page", 1, 1, math.MaxInt32)
+
+	var queryErr *models.FieldError
 	if query == "" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "invalid_query",
-			Message: "Search query is required",
-		})
+		queryErr = &models.FieldError{Field: "q", Rule: "required"}
+	}
+	if details := collectFieldErrors(queryErr, limitErr, pageErr); len(details) > 0 {
+		respondFieldErrors(w, details...)
+		return
+	}
+
+	r, done, ok := h.beginStream(w, r)
+	if !ok {
 		return
 	}
+	defer done()
 
-	results, err := h.service.SearchUser(c.Request.Context(), query)
+	cursor := r.URL.Query().Get("cursor")
+	opts := services.SearchOptions{Page: page, Limit: limit, Cursor: cursor}
+	stream := newStreamWriter(w, r, "results")
+	results, err := h.service.SearchUser(r.Context(), query, opts)
+	if errors.Is(err, services.ErrInvalidCursor) {
+		respondFieldErrors(w, models.FieldError{Field: "cursor", Rule: "type", Param: "next_cursor", Value: cursor})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "search_failed",
-			Message: err.Error(),
-		})
+		h.finishStream(w, stream, err, "search_failed")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"results": results,
-		"count":   len(results),
+	// JSON Lines has no envelope, so paging is also sent as headers
+	w.Header().Set("X-Total-Count", strconv.Itoa(results.Total))
+	if results.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", results.NextCursor)
+	}
+	for _, result := range results.Results {
+		if err := r.Context().Err(); err != nil {
+			h.finishStream(w, stream, err, "search_failed")
+			return
+		}
+		if err := stream.Write(result); err != nil {
+			return
+		}
+	}
+
+	paging := H{"total": results.Total, "limit": results.Limit, "has_more": results.HasMore}
+	if results.Page > 0 {
+		paging["page"] = results.Page
+	}
+	if results.NextCursor != "" {
+		paging["next_cursor"] = results.NextCursor
+	}
+	stream.Close(paging)
+}
+
+// ExportLeaderboard streams the full leaderboard in rank order
+// GET /api/export?format=jsonl&exclude_bots=true
+func (h *LeaderboardHandler) ExportLeaderboard(w http.ResponseWriter, r *http.Request) {
+	excludeBots, botsErr := queryBool(r, "exclude_bots")
+	if botsErr != nil {
+		respondFieldErrors(w, *botsErr)
+		return
+	}
+
+	r, done, ok := h.beginStream(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
+	opts := services.ListOptions{ExcludeBots: excludeBots}
+	stream := newStreamWriter(w, r, "entries")
+	err := h.service.StreamLeaderboard(r.Context(), opts, func(entry models.LeaderboardEntry) error {
+		return stream.Write(entry)
 	})
+	h.finishStream(w, stream, err, "export_failed")
+}
+
+// finishStream terminates a stream. Streams cut short by draining are closed
+// as valid documents flagged "truncated" so clients know to resume elsewhere.
+func (h *LeaderboardHandler) finishStream(w http.ResponseWriter, stream *streamWriter, err error, code string) {
+	switch {
+	case err == nil:
+		stream.Close(nil)
+	case errors.Is(err, context.Canceled) && h.streams.isDraining():
+		stream.Close(H{"truncated": true})
+	case !stream.Started():
+		writeFailure(w, code, err)
+	}
 }
 
-// GetStats retrieves leaderboard statistics
-// GET /api/stats
-func (h *LeaderboardHandler) GetStats(c *gin.Context) {
-	stats, err := h.service.GetStats(c.Request.Context())
+// GetEventSchema serves the JSON Schema for an event payload version
+// GET /api/events/schema/{version}
+func (h *LeaderboardHandler) GetEventSchema(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.Atoi(pathParam(r, "version"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "stats_failed",
-			Message: err.Error(),
-		})
+		respondFieldErrors(w, models.FieldError{Field: "version", Rule: "type", Param: "int", Value: pathParam(r, "version")})
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	schema, err := events.Schema(version)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "schema_not_found", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Header().Set("X-Schema-Version-Current", strconv.Itoa(events.SchemaVersion))
+	w.WriteHeader(http.StatusOK)
+	w.Write(schema)
+}
+
+// GetStats retrieves leaderboard statistics, cached briefly
+// GET /api/stats?exclude_bots=true&fresh=true
+func (h *LeaderboardHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	excludeBots, botsErr := queryBool(r, "exclude_bots")
+	fresh, freshErr := queryBool(r, "fresh")
+	if details := collectFieldErrors(botsErr, freshErr); len(details) > 0 {
+		respondFieldErrors(w, details...)
+		return
+	}
+
+	opts := services.ListOptions{ExcludeBots: excludeBots}
+	getStats := h.service.GetStats
+	if fresh {
+		// Bypassing the cache costs a full scan, so it is reserved for admins
+		var ok bool
+		if r, ok = h.authorize(w, r, services.RoleAdmin); !ok {
+			return
+		}
+		getStats = h.service.ComputeStats
+	}
+
+	stats, err := getStats(r.Context(), opts)
+	if err != nil {
+		writeFailure(w, "stats_failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// CountUsers counts users rated within a range
+// GET /api/stats/count?min_rating=2500
+func (h *LeaderboardHandler) CountUsers(w http.ResponseWriter, r *http.Request) {
+	minRating, minErr := queryInt(r, "min_rating", services.MinRating, services.MinRating, services.MaxRating)
+	maxRating, maxErr := queryInt(r, "max_rating", services.MaxRating, services.MinRating, services.MaxRating)
+	excludeBots, botsErr := queryBool(r, "exclude_bots")
+	details := collectFieldErrors(minErr, maxErr, botsErr)
+	if len(details) == 0 && minRating > maxRating {
+		details = append(details, models.FieldError{Field: "max_rating", Rule: "gtefield", Param: "min_rating", Value: maxRating})
+	}
+	if len(details) > 0 {
+		respondFieldErrors(w, details...)
+		return
+	}
+
+	opts := services.ListOptions{ExcludeBots: excludeBots}
+	count, err := h.service.CountInRange(r.Context(), minRating, maxRating, opts)
+	if err != nil {
+		writeFailure(w, "stats_failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, count)
+}
+
+// GetCountryStats counts users and averages their ratings per country
+// GET /api/stats/by-country?exclude_bots=true
+func (h *LeaderboardHandler) GetCountryStats(w http.ResponseWriter, r *http.Request) {
+	excludeBots, botsErr := queryBool(r, "exclude_bots")
+	if botsErr != nil {
+		respondFieldErrors(w, *botsErr)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.service.CountryStats(r.Context(), services.ListOptions{ExcludeBots: excludeBots}))
+}
+
+// GetTierStats counts users and averages their ratings per tier
+// GET /api/stats/by-tier?exclude_bots=true
+func (h *LeaderboardHandler) GetTierStats(w http.ResponseWriter, r *http.Request) {
+	excludeBots, botsErr := queryBool(r, "exclude_bots")
+	if botsErr != nil {
+		respondFieldErrors(w, *botsErr)
+		return
+	}
+
+	stats, err := h.service.TierStats(r.Context(), services.ListOptions{ExcludeBots: excludeBots})
+	if errors.Is(err, services.ErrTiersNotConfigured) {
+		writeError(w, http.StatusNotFound, "tiers_not_configured", "Set TIER_BOUNDARIES to configure tiers")
+		return
+	}
+	if err != nil {
+		writeFailure(w, "stats_failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// GetInflation reports how the average rating has drifted over time
+// GET /api/stats/inflation
+func (h *LeaderboardHandler) GetInflation(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.service.GetInflationReport(r.Context()))
+}
+
+// GetSimulationStatus reports whether the random update simulator is running or paused
+// GET /api/simulation/status
+func (h *LeaderboardHandler) GetSimulationStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.service.GetSimulationStatus())
+}
+
+// ConfigureSimulation changes the simulator's interval, target, batch or enabled flag without a restart
+// PUT /api/admin/simulation
+func (h *LeaderboardHandler) ConfigureSimulation(w http.ResponseWriter, r *http.Request) {
+	var req models.SimulationConfigRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	cfg := h.service.SimulationConfig()
+	if req.Enabled != nil {
+		cfg.Enabled = *req.Enabled
+	}
+	if req.IntervalSeconds != nil {
+		cfg.Interval = time.Duration(*req.IntervalSeconds * float64(time.Second))
+	}
+	if req.Target != nil {
+		cfg.Target = *req.Target
+	}
+	if req.BatchSize != nil {
+		cfg.BatchSize = *req.BatchSize
+	}
+	if req.Jitter != nil {
+		cfg.Jitter = *req.Jitter
+	}
+
+	if err := h.service.ConfigureSimulation(cfg); err != nil {
+		if errors.Is(err, services.ErrInvalidSimulationConfig) {
+			writeError(w, http.StatusBadRequest, "invalid_simulation_config", err.Error())
+			return
+		}
+		writeFailure(w, "configure_failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.service.GetSimulationStatus())
+}
+
+// GetRealtimeStats reports WebSocket fan-out health
+// GET /api/admin/realtime
+func (h *LeaderboardHandler) GetRealtimeStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.service.Realtime().Stats())
+}
+
+// CompareShadow shows how the shadow rating strategy would reorder the top of the board
+// GET /api/admin/shadow/compare?limit=50
+func (h *LeaderboardHandler) CompareShadow(w http.ResponseWriter, r *http.Request) {
+	limit, limitErr := queryInt(r, "limit", 50, 1, 1000)
+	if limitErr != nil {
+		respondFieldErrors(w, *limitErr)
+		return
+	}
+
+	comparison, err := h.service.CompareShadow(r.Context(), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrShadowDisabled) {
+			writeError(w, http.StatusNotFound, "shadow_disabled", "Set SHADOW_RATING_STRATEGY to enable the shadow leaderboard")
+			return
+		}
+		writeFailure(w, "compare_failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, comparison)
+}
+
+// GetDoubleWriteReport reports how the migration target store tracks the live store
+// GET /api/admin/migration/verification
+func (h *LeaderboardHandler) GetDoubleWriteReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.GetDoubleWriteReport(r.Context())
+	if err != nil {
+		if errors.Is(err, services.ErrDoubleWriteDisabled) {
+			writeError(w, http.StatusNotFound, "double_write_disabled", "Set DOUBLE_WRITE=true to mirror writes to a migration target")
+			return
+		}
+		writeFailure(w, "report_failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// GetHealthHistory reports uptime, component states and recent incidents
+// GET /api/admin/health/history?limit=50
+func (h *LeaderboardHandler) GetHealthHistory(w http.ResponseWriter, r *http.Request) {
+	limit, limitErr := queryInt(r, "limit", 50, 1, 200)
+	if limitErr != nil {
+		respondFieldErrors(w, *limitErr)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.service.GetHealthHistory(r.Context(), limit))
+}
+
+// GetOverview aggregates what an ops dashboard needs in one call
+// GET /api/admin/overview
+func (h *LeaderboardHandler) GetOverview(w http.ResponseWriter, r *http.Request) {
+	overview, err := h.service.GetOverview(r.Context())
+	if err != nil {
+		writeFailure(w, "fetch_failed", err)
+		return
+	}
+
+	overview.Status = "ready"
+	switch {
+	case h.streams.isDraining():
+		overview.Status = "draining"
+	case h.warming.Load():
+		overview.Status = "warming_up"
+	}
+	writeJSON(w, http.StatusOK, overview)
+}
+
+// ListAnomalies lists users with implausible rating trajectories
+// GET /api/admin/anomalies
+func (h *LeaderboardHandler) ListAnomalies(w http.ResponseWriter, r *http.Request) {
+	anomalies := h.service.ListAnomalies(r.Context())
+	writeJSON(w, http.StatusOK, H{
+		"anomalies": anomalies,
+		"count":     len(anomalies),
+	})
+}
+
+// ResolveAnomaly clears a flagged user after review
+// DELETE /api/admin/anomalies/{username}
+func (h *LeaderboardHandler) ResolveAnomaly(w http.ResponseWriter, r *http.Request) {
+	username := pathParam(r, "username")
+	if err := h.service.ResolveAnomaly(r.Context(), username); err != nil {
+		if errors.Is(err, services.ErrAnomalyNotFound) {
+			writeError(w, http.StatusNotFound, "anomaly_not_found", "User is not flagged")
+			return
+		}
+		writeFailure(w, "resolve_failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{
+		"message": "Anomaly resolved",
+	})
 }
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/auth"
+)
+
+// clientIP returns the caller's address, from X-Forwarded-For when login is
+// enabled and the deployment sits behind trusted proxies. The client can
+// send any entries it likes, so only those the proxies appended count: the
+// address the outermost of them saw is ProxyHops entries from the right.
+func (h *LeaderboardHandler) clientIP(r *http.Request) string {
+	if h.auth != nil && h.auth.TrustProxy() {
+		var entries []string
+		for _, value := range r.Header.Values("X-Forwarded-For") {
+			for _, entry := range strings.Split(value, ",") {
+				if entry = strings.TrimSpace(entry); entry != "" {
+					entries = append(entries, entry)
+				}
+			}
+		}
+		if len(entries) > 0 {
+			return entries[max(len(entries)-h.auth.ProxyHops(), 0)]
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// checkLockout answers 429 with Retry-After while the caller's IP or the
+// account is locked out after repeated failures
+func (h *LeaderboardHandler) checkLockout(w http.ResponseWriter, r *http.Request, account string) bool {
+	until, locked := h.auth.LockedOut(h.clientIP(r), account)
+	if !locked {
+		return true
+	}
+	retryAfter := int(time.Until(until).Seconds()) + 1
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	writeError(w, http.StatusTooManyRequests, "locked_out", "Too many failed attempts, retry after "+strconv.Itoa(retryAfter)+"s")
+	return false
+}
+
+// errMissingCredentials is returned when a request carries no token at all
+var errMissingCredentials = fmt.Errorf("%w: missing credentials", auth.ErrInvalidToken)
+
+// recordAuthFailure counts a failed attempt towards lockout. Requests without
+// credentials, and genuine tokens that merely expired or were revoked, are
+// not guesses and don't count.
+func (h *LeaderboardHandler) recordAuthFailure(r *http.Request, account string, err error) {
+	if errors.Is(err, auth.ErrExpiredToken) || errors.Is(err, errMissingCredentials) {
+		return
+	}
+	h.auth.RecordFailure(h.clientIP(r), account)
+}
+
+// ListLockouts shows IPs and accounts with recent failed attempts
+// GET /api/admin/lockouts
+func (h *LeaderboardHandler) ListLockouts(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAuth(w) {
+		return
+	}
+	lockouts := h.auth.Lockouts()
+	writeJSON(w, http.StatusOK, H{
+		"lockouts": lockouts,
+		"count":    len(lockouts),
+	})
+}
+
+// ClearLockout lifts a lockout early
+// DELETE /api/admin/lockouts/{key}
+func (h *LeaderboardHandler) ClearLockout(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAuth(w) {
+		return
+	}
+	if !h.auth.ClearLockout(pathParam(r, "key")) {
+		writeError(w, http.StatusNotFound, "lockout_not_found", "No failed attempts recorded for this key")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
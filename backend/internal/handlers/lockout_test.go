@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/internal/auth"
+	"backend/internal/services"
+	"backend/pkg/store"
+)
+
+// TestLockoutIgnoresSpoofedForwarding checks that a client behind the proxy
+// can't dodge the lockout by sending its own X-Forwarded-For entries
+func TestLockoutIgnoresSpoofedForwarding(t *testing.T) {
+	for _, hops := range []int{1, 2} {
+		t.Run(fmt.Sprintf("%d hops", hops), func(t *testing.T) {
+			a, err := auth.New(auth.Config{
+				Secret:     []byte(strings.Repeat("k", 32)),
+				APIKeys:    map[string]string{"writer": "writer-secret"},
+				Lockout:    auth.LockoutConfig{Threshold: 3},
+				TrustProxy: true,
+				ProxyHops:  hops,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := NewLeaderboardHandler(services.NewLeaderboardService(store.NewMemoryStore()))
+			h.EnableAuth(a)
+			mux := h.NewServeMux()
+
+			// The proxies append the address each saw, after whatever the client sent
+			appended := []string{"203.0.113.7", "10.0.0.2"}[:hops]
+			for attempt := range 5 {
+				req := httptest.NewRequest("GET", "/api/admin/lockouts", nil)
+				req.Header.Set("X-API-Key", "wrong-secret")
+				req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d, ", attempt)+strings.Join(appended, ", "))
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, req)
+				want := http.StatusUnauthorized
+				if attempt >= 3 {
+					want = http.StatusTooManyRequests
+				}
+				if rec.Code != want {
+					t.Errorf("attempt %d: %d, want %d", attempt, rec.Code, want)
+				}
+			}
+		})
+	}
+}
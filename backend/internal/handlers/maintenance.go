@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"backend/internal/models"
+	"backend/internal/services"
+)
+
+// SetMaintenance switches the API into read-only or down mode, or back
+// POST /api/admin/maintenance
+func (h *LeaderboardHandler) SetMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req models.MaintenanceRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.service.SetMaintenance(r.Context(), req.Mode, req.Message))
+}
+
+// maintenanceGate wraps every route so maintenance mode takes effect. The
+// switch itself stays up so the mode can be switched back, as do reads of
+// the admin and login routes for staff watching over it, and /readyz keeps
+// reporting the instance itself. Admin and login writes wait like the rest.
+func (h *LeaderboardHandler) maintenanceGate(routes []Route) []Route {
+	for i, route := range routes {
+		if route.Path == "/readyz" || route.Path == "/api/admin/maintenance" {
+			continue
+		}
+		staff := strings.HasPrefix(route.Path, "/api/admin/") || strings.HasPrefix(route.Path, "/api/auth/")
+		routes[i].Handler = h.checkMaintenance(route.Method, staff, route.Handler)
+	}
+	return routes
+}
+
+// checkMaintenance turns requests away while the mode calls for it. In
+// read_only mode, reads pass; when down, only staff reads do.
+func (h *LeaderboardHandler) checkMaintenance(method string, staff bool, next http.HandlerFunc) http.HandlerFunc {
+	readOnly := method == http.MethodGet || method == http.MethodHead
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := h.service.Maintenance()
+		switch {
+		case status.Mode == services.MaintenanceDown && !(staff && readOnly):
+			respondMaintenance(w, status, "The API is down for maintenance")
+		case status.Mode == services.MaintenanceReadOnly && !readOnly:
+			respondMaintenance(w, status, "The API is read-only for maintenance")
+		default:
+			next(w, r)
+		}
+	}
+}
+
+func respondMaintenance(w http.ResponseWriter, status models.MaintenanceStatus, message string) {
+	if status.Message != "" {
+		// Admins write their own message in whatever language suits
+		message = status.Message
+	} else {
+		message = translateError(w, models.ErrorResponse{Error: "maintenance", Message: message}).Message
+	}
+	writeJSON(w, http.StatusServiceUnavailable, H{
+		"error":       "maintenance",
+		"message":     message,
+		"maintenance": status,
+	})
+}
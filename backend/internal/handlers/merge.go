@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"backend/internal/models"
+	"backend/internal/services"
+	"backend/pkg/store"
+)
+
+// respondMergeError maps a failed merge to its HTTP response
+func respondMergeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrUserNotFound):
+		writeError(w, http.StatusNotFound, "user_not_found", "User does not exist")
+	case errors.Is(err, services.ErrMergeSelf):
+		writeError(w, http.StatusBadRequest, "merge_self", "A user can't be merged into themselves")
+	default:
+		respondScoreError(w, err)
+	}
+}
+
+// MergeUsers folds a duplicate account into the one that stays. Removing a
+// user can't be undone, so the first call returns a confirmation token for
+// the pair; repeating it with ?confirm=<token> merges them.
+// POST /api/admin/users/merge?confirm=<token>
+func (h *LeaderboardHandler) MergeUsers(w http.ResponseWriter, r *http.Request) {
+	var req models.MergeUsersRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+	if err := h.service.CheckMerge(req.From, req.Into); err != nil {
+		respondMergeError(w, err)
+		return
+	}
+	if !h.confirmed(w, r, services.OperationMergeUsers, req.From+" -> "+req.Into) {
+		return
+	}
+
+	result, err := h.service.MergeUsers(r.Context(), req, actor(r))
+	if err != nil {
+		respondMergeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
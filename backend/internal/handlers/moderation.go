@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/services"
+)
+
+// actor names the staff member making a change for the audit log
+func actor(r *http.Request) string {
+	if principal := principalFromContext(r.Context()); principal != "" {
+		return principal
+	}
+	return services.AnonymousActor
+}
+
+// respondModerationError maps moderation failures to API errors
+func respondModerationError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrNotFrozen) {
+		writeError(w, http.StatusNotFound, "not_frozen", "User has no active freeze")
+		return
+	}
+	if err.Error() == "user not found" {
+		writeError(w, http.StatusNotFound, "user_not_found", "User does not exist")
+		return
+	}
+	writeFailure(w, "moderation_failed", err)
+}
+
+// GetModerationRecord returns a user's active freeze and staff notes
+// GET /api/moderation/users/{username}
+func (h *LeaderboardHandler) GetModerationRecord(w http.ResponseWriter, r *http.Request) {
+	record, err := h.service.GetModerationRecord(r.Context(), pathParam(r, "username"))
+	if err != nil {
+		respondModerationError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, record)
+}
+
+// AdjustScore overrides a user's rating, with a mandatory reason. Changes
+// above the approval threshold answer 202 and wait for a second approver.
+// POST /api/moderation/users/{username}/adjust
+func (h *LeaderboardHandler) AdjustScore(w http.ResponseWriter, r *http.Request) {
+	var req models.ScoreAdjustmentRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	result, err := h.service.AdjustScore(r.Context(), pathParam(r, "username"), req.Rating, req.Reason, actor(r))
+	if err != nil {
+		respondModerationError(w, err)
+		return
+	}
+	if result.Status == models.AdjustmentPendingApproval {
+		writeJSON(w, http.StatusAccepted, result)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// FreezeUser rejects a user's score updates for a while
+// PUT /api/moderation/users/{username}/freeze
+func (h *LeaderboardHandler) FreezeUser(w http.ResponseWriter, r *http.Request) {
+	var req models.FreezeRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	duration := time.Duration(req.DurationSeconds) * time.Second
+	freeze, err := h.service.FreezeUser(r.Context(), pathParam(r, "username"), duration, req.Reason, actor(r))
+	if err != nil {
+		respondModerationError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, freeze)
+}
+
+// UnfreezeUser lifts a freeze early
+// DELETE /api/moderation/users/{username}/freeze
+func (h *LeaderboardHandler) UnfreezeUser(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.UnfreezeUser(r.Context(), pathParam(r, "username"), actor(r)); err != nil {
+		respondModerationError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddStaffNote annotates a user for other staff
+// POST /api/moderation/users/{username}/notes
+func (h *LeaderboardHandler) AddStaffNote(w http.ResponseWriter, r *http.Request) {
+	var req models.StaffNoteRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	note, err := h.service.AddStaffNote(r.Context(), pathParam(r, "username"), req.Note, actor(r))
+	if err != nil {
+		respondModerationError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, note)
+}
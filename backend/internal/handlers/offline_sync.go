@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend/internal/models"
+)
+
+// SyncOfflineScores applies the score events a client journaled while
+// offline and reports how each was reconciled with the board
+// POST /api/users/{username}/score/sync
+func (h *LeaderboardHandler) SyncOfflineScores(w http.ResponseWriter, r *http.Request) {
+	username := pathParam(r, "username")
+	if username == "" {
+		respondFieldErrors(w, models.FieldError{Field: "username", Rule: "required"})
+		return
+	}
+
+	var req models.OfflineSyncRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	sync, err := h.service.SyncOfflineScores(r.Context(), username, req.ClientID, req.Events)
+	if err != nil {
+		respondScoreError(w, err)
+		return
+	}
+
+	response := sync.OfflineSyncResponse
+	response.Events = make([]models.OfflineEventResult, len(sync.Outcomes))
+	for i, outcome := range sync.Outcomes {
+		response.Events[i] = outcome.OfflineEventResult
+		if outcome.Err != nil {
+			_, body := scoreErrorResponse(outcome.Err)
+			body = translateError(w, body)
+			response.Events[i].Error = &body
+		}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
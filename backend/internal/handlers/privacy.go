@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+// GetPrivacy returns the authenticated user's privacy settings
+// GET /api/auth/me/privacy
+func (h *LeaderboardHandler) GetPrivacy(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.me(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.service.Privacy(claims.Subject))
+}
+
+// SetPrivacy changes what the authenticated user shares with everyone else
+// PUT /api/auth/me/privacy
+func (h *LeaderboardHandler) SetPrivacy(w http.ResponseWriter, r *http.Request) {
+	claims, ok := h.me(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.PrivacyRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	settings, err := h.service.SetPrivacy(r.Context(), claims.Subject, req)
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			writeError(w, http.StatusNotFound, "user_not_found", "User does not exist")
+			return
+		}
+		writeFailure(w, "update_failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, settings)
+}
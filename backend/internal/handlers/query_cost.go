@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"backend/internal/services"
+)
+
+// queryCost prices a route in board entries walked, estimated from the
+// same parameters its handler reads, and says how to bring the cost down
+type queryCost struct {
+	estimate func(h *LeaderboardHandler, r *http.Request) int
+	hint     string
+}
+
+// queryCosts lists the routes whose cost grows with their parameters.
+// Parameters the handler would reject cost nothing, so the handler reports
+// them rather than the budget.
+var queryCosts = map[string]queryCost{
+	"/api/leaderboard": {estimate: offsetPageCost(100), hint: "ask for an earlier page or a smaller limit"},
+	"/api/boards/{id}": {estimate: derivedPageCost, hint: "ask for an earlier page or a smaller limit"},
+	"/api/search":      {estimate: searchCost, hint: "type more of the name, as every match is read"},
+	"/api/export":      {estimate: exportCost, hint: "full exports need the admin role"},
+}
+
+// offsetPageCost prices ?page=&limit= paging with limits up to maxLimit
+func offsetPageCost(maxLimit int) func(h *LeaderboardHandler, r *http.Request) int {
+	return func(h *LeaderboardHandler, r *http.Request) int {
+		page, pageErr := queryInt(r, "page", 1, 1, math.MaxInt32)
+		limit, limitErr := queryInt(r, "limit", 50, 1, maxLimit)
+		if pageErr != nil || limitErr != nil {
+			return 0
+		}
+		return h.service.PageCost(page, limit)
+	}
+}
+
+// derivedPageCost prices ?page=&limit= paging of a derived board, whose
+// size and hidden users set the cost rather than the main board's
+func derivedPageCost(h *LeaderboardHandler, r *http.Request) int {
+	page, pageErr := queryInt(r, "page", 1, 1, math.MaxInt32)
+	limit, limitErr := queryInt(r, "limit", 50, 1, 100)
+	if pageErr != nil || limitErr != nil {
+		return 0
+	}
+	return h.service.DerivedPageCost(r.Context(), pathParam(r, "id"), page, limit)
+}
+
+// searchCost prices a search by the matches it reads. Paging parameters are
+// still read so invalid ones are reported rather than priced.
+func searchCost(h *LeaderboardHandler, r *http.Request) int {
+	_, limitErr := queryInt(r, "limit", defaultSearchLimit, 1, maxSearchLimit)
+	_, pageErr := queryInt(r, "page", 1, 1, math.MaxInt32)
+	if limitErr != nil || pageErr != nil {
+		return 0
+	}
+	return h.service.SearchCost(r.URL.Query().Get("q"))
+}
+
+func exportCost(h *LeaderboardHandler, r *http.Request) int {
+	return h.service.ExportCost()
+}
+
+// priceQueries wraps the routes in queryCosts so reads over the query cost
+// budget answer 422 before they run. Priced responses report their
+// estimate in X-Query-Cost while a budget is set.
+func (h *LeaderboardHandler) priceQueries(routes []Route) []Route {
+	for i, route := range routes {
+		if cost, ok := queryCosts[route.Path]; ok {
+			routes[i].Handler = h.price(cost, route.Handler)
+		}
+	}
+	return routes
+}
+
+func (h *LeaderboardHandler) price(cost queryCost, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.service.QueryCostBudget() == 0 {
+			next(w, r)
+			return
+		}
+
+		estimate := cost.estimate(h, r)
+		w.Header().Set("X-Query-Cost", strconv.Itoa(estimate))
+		var tooExpensive *services.QueryCostError
+		if err := h.service.CheckQueryCost(estimate); errors.As(err, &tooExpensive) && !h.unbudgeted(r) {
+			writeError(w, http.StatusUnprocessableEntity, "query_too_expensive", fmt.Sprintf(
+				"Query would read about %d entries, over the budget of %d; %s", tooExpensive.Cost, tooExpensive.Budget, cost.hint))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// unbudgeted reports whether the caller is an admin, whose reads may cost
+// anything. Without auth nobody is, so the budget binds every caller.
+func (h *LeaderboardHandler) unbudgeted(r *http.Request) bool {
+	if h.auth == nil {
+		return false
+	}
+	principal, err := h.authenticate(r)
+	return err == nil && h.service.HasRole(principal, services.RoleAdmin)
+}
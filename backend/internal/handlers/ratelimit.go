@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"backend/internal/models"
+	"backend/internal/services"
+)
+
+// rateLimit wraps every route so callers over their rate limit get 429, and
+// every response from a limited bucket reports it in X-RateLimit-* headers,
+// unless cacheControl sends it as public.
+// /readyz is for load balancers and /api/limits only reports the limits, so
+// neither counts.
+func (h *LeaderboardHandler) rateLimit(routes []Route) []Route {
+	for i, route := range routes {
+		if route.Path == "/readyz" || route.Path == "/api/limits" {
+			continue
+		}
+		routes[i].Handler = h.throttle(route.Handler)
+	}
+	return routes
+}
+
+func (h *LeaderboardHandler) throttle(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucket, allowed := h.service.TakeRateLimit(h.rateCaller(r), rateBucket(r))
+		if bucket.Limit > 0 {
+			// Set before the handler writes, so successes carry them too
+			setRateLimitHeaders(w, bucket)
+		}
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(bucket.ResetSeconds))
+			writeError(w, http.StatusTooManyRequests, "rate_limited", "Rate limit exceeded, retry after "+strconv.Itoa(bucket.ResetSeconds)+"s")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// rateBucket picks the bucket a request draws from
+func rateBucket(r *http.Request) string {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return services.BucketReads
+	}
+	return services.BucketWrites
+}
+
+// rateCaller identifies the caller for rate limiting: the principal behind
+// valid credentials, or the client address. Bad credentials count against
+// the address, so they can't be used to dodge a limit.
+func (h *LeaderboardHandler) rateCaller(r *http.Request) string {
+	if h.auth != nil && (r.Header.Get("X-API-Key") != "" || r.Header.Get("Authorization") != "") {
+		if principal, err := h.authenticate(r); err == nil {
+			return principal
+		}
+	}
+	return "ip:" + h.clientIP(r)
+}
+
+// setRateLimitHeaders reports a bucket in X-RateLimit-* headers.
+// X-RateLimit-Reset is in seconds from now.
+func setRateLimitHeaders(w http.ResponseWriter, bucket models.RateLimitBucket) {
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(bucket.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(bucket.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(bucket.ResetSeconds))
+}
+
+// GetLimits reports the caller's rate limit buckets without counting against
+// them, and the query cost budget
+// GET /api/limits
+func (h *LeaderboardHandler) GetLimits(w http.ResponseWriter, r *http.Request) {
+	caller := h.rateCaller(r)
+	writeJSON(w, http.StatusOK, models.LimitsResponse{
+		Caller:          caller,
+		Enabled:         h.service.RateLimited(),
+		Buckets:         h.service.RateLimits(caller),
+		QueryCostBudget: h.service.QueryCostBudget(),
+	})
+}
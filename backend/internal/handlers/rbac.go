@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"backend/internal/auth"
+	"backend/internal/services"
+)
+
+type principalKey struct{}
+
+// principalFromContext returns the caller authenticated by requireRole, or
+// "" when auth is disabled
+func principalFromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey{}).(string)
+	return principal
+}
+
+// authenticate identifies the caller by X-API-Key or bearer token
+func (h *LeaderboardHandler) authenticate(r *http.Request) (string, error) {
+	if secret := r.Header.Get("X-API-Key"); secret != "" {
+		name, ok := h.auth.APIKey(secret)
+		if !ok {
+			return "", auth.ErrInvalidToken
+		}
+		return services.Principal(services.PrincipalKey, name), nil
+	}
+
+	claims, err := h.bearerClaims(r)
+	if err != nil {
+		return "", err
+	}
+	return services.Principal(services.PrincipalUser, claims.Subject), nil
+}
+
+// requireRole wraps next so it only runs for callers currently holding role.
+// Grants are read on every request, so changes apply without reissuing
+// tokens. Without auth configured the API stays open.
+func (h *LeaderboardHandler) requireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r, ok := h.authorize(w, r, role)
+		if !ok {
+			return
+		}
+		next(w, r)
+	}
+}
+
+// authorize checks that the caller holds role, answering 401/403/429 if not.
+// The returned request carries the caller's principal.
+func (h *LeaderboardHandler) authorize(w http.ResponseWriter, r *http.Request, role string) (*http.Request, bool) {
+	if h.auth == nil {
+		return r, true
+	}
+
+	if !h.checkLockout(w, r, "") {
+		return r, false
+	}
+
+	principal, err := h.authenticate(r)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			h.recordAuthFailure(r, "", err)
+			writeError(w, http.StatusUnauthorized, "unauthorized", "Missing or invalid API key or access token")
+			return r, false
+		}
+		writeFailure(w, "auth_failed", err)
+		return r, false
+	}
+	if !h.service.HasRole(principal, role) {
+		writeError(w, http.StatusForbidden, "forbidden", "Requires the "+role+" role")
+		return r, false
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)), true
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/services"
+)
+
+// reportContentTypes are the response types per report format
+var reportContentTypes = map[string]string{
+	services.ReportJSON:     "application/json; charset=utf-8",
+	services.ReportMarkdown: "text/markdown; charset=utf-8",
+	services.ReportHTML:     "text/html; charset=utf-8",
+}
+
+// GetDailyReport serves a stored daily summary
+// GET /api/reports/{date}?format=markdown
+func (h *LeaderboardHandler) GetDailyReport(w http.ResponseWriter, r *http.Request) {
+	date := pathParam(r, "date")
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.ReportJSON
+	}
+
+	var details []models.FieldError
+	if _, err := time.Parse(time.DateOnly, date); err != nil {
+		details = append(details, models.FieldError{Field: "date", Rule: "type", Param: "date", Value: date})
+	}
+	if _, ok := reportContentTypes[format]; !ok {
+		details = append(details, models.FieldError{Field: "format", Rule: "oneof", Param: "json markdown html", Value: format})
+	}
+	if len(details) > 0 {
+		respondFieldErrors(w, details...)
+		return
+	}
+
+	report, err := h.service.GetDailyReport(r.Context(), date, format)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrReportsDisabled):
+			writeError(w, http.StatusNotFound, "reports_disabled", "Set REPORTS_ENABLED=true to generate daily reports")
+		case errors.Is(err, services.ErrReportNotFound):
+			writeError(w, http.StatusNotFound, "report_not_found", "No report for this date")
+		default:
+			writeFailure(w, "report_failed", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", reportContentTypes[format])
+	w.WriteHeader(http.StatusOK)
+	w.Write(report)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+// H is a shorthand for ad-hoc JSON objects
+type H map[string]any
+
+// writeJSON encodes v as the JSON response body with the given status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// writeError writes an ErrorResponse with the given status
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorResponse(w, status, models.ErrorResponse{
+		Error:   code,
+		Message: message,
+	})
+}
+
+// statusClientClosedRequest is nginx's status for a request the client gave
+// up on before the response was ready
+const statusClientClosedRequest = 499
+
+// writeFailure writes an unexpected failure as a 500 with code, unless the
+// request's context ended first (499 when the client went away, 504 when
+// its deadline passed) or the board is out of memory (507)
+func writeFailure(w http.ResponseWriter, code string, err error) {
+	switch {
+	case errors.Is(err, store.ErrMemoryBudget):
+		writeError(w, http.StatusInsufficientStorage, "memory_budget_exceeded", err.Error())
+	case errors.Is(err, context.Canceled):
+		writeError(w, statusClientClosedRequest, "client_closed_request", "The client closed the request before it completed")
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusGatewayTimeout, "timeout", "The request did not complete before its deadline")
+	default:
+		writeError(w, http.StatusInternalServerError, code, err.Error())
+	}
+}
+
+// writeErrorResponse writes body with its message in the negotiated language
+func writeErrorResponse(w http.ResponseWriter, status int, body models.ErrorResponse) {
+	writeJSON(w, status, translateError(w, body))
+}
+
+// bindJSON decodes the request body into v and validates its binding tags
+func bindJSON(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("request body is required")
+		}
+		return err
+	}
+	return validate.Struct(v)
+}
+
+// pathParam returns a named path parameter, e.g. {username}
+func pathParam(r *http.Request, name string) string {
+	return r.PathValue(name)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"backend/internal/services"
+)
+
+// ListRoles returns every role grant
+// GET /api/admin/roles
+func (h *LeaderboardHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, H{
+		"grants": h.service.ListRoleGrants(r.Context()),
+	})
+}
+
+// GrantUserRole grants a role to a user
+// PUT /api/admin/users/{username}/roles/{role}
+func (h *LeaderboardHandler) GrantUserRole(w http.ResponseWriter, r *http.Request) {
+	h.changeRole(w, r, services.PrincipalUser, pathParam(r, "username"), true)
+}
+
+// RevokeUserRole revokes a role from a user
+// DELETE /api/admin/users/{username}/roles/{role}
+func (h *LeaderboardHandler) RevokeUserRole(w http.ResponseWriter, r *http.Request) {
+	h.changeRole(w, r, services.PrincipalUser, pathParam(r, "username"), false)
+}
+
+// GrantKeyRole grants a role to an API key
+// PUT /api/admin/keys/{name}/roles/{role}
+func (h *LeaderboardHandler) GrantKeyRole(w http.ResponseWriter, r *http.Request) {
+	h.changeRole(w, r, services.PrincipalKey, pathParam(r, "name"), true)
+}
+
+// RevokeKeyRole revokes a role from an API key
+// DELETE /api/admin/keys/{name}/roles/{role}
+func (h *LeaderboardHandler) RevokeKeyRole(w http.ResponseWriter, r *http.Request) {
+	h.changeRole(w, r, services.PrincipalKey, pathParam(r, "name"), false)
+}
+
+// GrantIdentityRole grants a role to a login provider's subject, applying to
+// whichever user it is linked to
+// PUT /api/admin/identities/{provider}/{subject}/roles/{role}
+func (h *LeaderboardHandler) GrantIdentityRole(w http.ResponseWriter, r *http.Request) {
+	h.changeRole(w, r, services.PrincipalIdentity, pathParam(r, "provider")+":"+pathParam(r, "subject"), true)
+}
+
+// RevokeIdentityRole revokes a role from a login provider's subject
+// DELETE /api/admin/identities/{provider}/{subject}/roles/{role}
+func (h *LeaderboardHandler) RevokeIdentityRole(w http.ResponseWriter, r *http.Request) {
+	h.changeRole(w, r, services.PrincipalIdentity, pathParam(r, "provider")+":"+pathParam(r, "subject"), false)
+}
+
+func (h *LeaderboardHandler) changeRole(w http.ResponseWriter, r *http.Request, kind, name string, grant bool) {
+	principal := services.Principal(kind, name)
+	role := pathParam(r, "role")
+
+	var err error
+	if grant {
+		err = h.service.GrantRole(r.Context(), principal, role)
+	} else {
+		err = h.service.RevokeRole(r.Context(), principal, role)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownRole):
+			writeError(w, http.StatusBadRequest, "unknown_role", "Role must be admin, moderator or writer")
+		case errors.Is(err, services.ErrInvalidPrincipal):
+			writeError(w, http.StatusBadRequest, "invalid_principal", err.Error())
+		case err.Error() == "user not found":
+			writeError(w, http.StatusNotFound, "user_not_found", "User does not exist")
+		default:
+			writeFailure(w, "role_change_failed", err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, H{
+		"principal": principal,
+		"roles":     h.service.Roles(principal),
+	})
+}
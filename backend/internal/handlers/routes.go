@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"backend/internal/services"
+)
+
+// Route binds a framework-agnostic handler to a method and path.
+// Paths use net/http ServeMux syntax ({name} for path parameters), which chi
+// understands as-is; other routers can translate them (see ginadapter).
+type Route struct {
+	Method  string
+	Path    string
+	Handler http.HandlerFunc
+}
+
+// Routes lists every leaderboard API route, gated by maintenance mode, load
+// shedding, query costs and rate limits, instrumented for latency SLOs and request
+// capture, and labelled for caching
+func (h *LeaderboardHandler) Routes() []Route {
+	return localize(h.captures.instrument(h.cacheControl(h.rateLimit(h.priceQueries(h.shedLoad(h.maintenanceGate(h.measureLatency(h.routeTable()))))))))
+}
+
+func (h *LeaderboardHandler) routeTable() []Route {
+	return []Route{
+		// Readiness
+		{http.MethodGet, "/readyz", h.Ready},
+
+		// Login
+		{http.MethodGet, "/api/auth/{provider}/login", h.Login},
+		{http.MethodGet, "/api/auth/{provider}/callback", h.LoginCallback},
+		{http.MethodPost, "/api/auth/refresh", h.RefreshToken},
+		{http.MethodPost, "/api/auth/logout", h.Logout},
+		{http.MethodGet, "/api/auth/me", h.Me},
+		{http.MethodGet, "/api/auth/me/privacy", h.GetPrivacy},
+		{http.MethodPut, "/api/auth/me/privacy", h.SetPrivacy},
+
+		// Seed data
+		{http.MethodPost, "/api/seed", h.requireRole(services.RoleWriter, h.SeedData)},
+		{http.MethodGet, "/api/seed/jobs", h.requireRole(services.RoleWriter, h.ListSeedJobs)},
+		{http.MethodGet, "/api/seed/jobs/{id}", h.requireRole(services.RoleWriter, h.GetSeedJob)},
+		{http.MethodDelete, "/api/seed/jobs/{id}", h.requireRole(services.RoleWriter, h.CancelSeedJob)},
+
+		// Leaderboard
+		{http.MethodGet, "/api/leaderboard", h.GetLeaderboard},
+		{http.MethodGet, "/api/capabilities", h.GetCapabilities},
+		{http.MethodGet, "/api/limits", h.GetLimits},
+		{http.MethodGet, "/api/leaderboards/{id}", h.GetBoardMetadata},
+		{http.MethodGet, "/api/leaderboards/{id}/prizes", h.GetPrizes},
+		{http.MethodGet, "/api/leaderboards/{id}/cutoffs", h.GetCutoffs},
+		{http.MethodGet, "/api/boards", h.ListDerivedBoards},
+		{http.MethodGet, "/api/boards/{id}", h.GetDerivedBoard},
+
+		// User operations
+		{http.MethodGet, "/api/users", h.ListUsers},
+		{http.MethodGet, "/api/users/{username}", h.GetUserRank},
+		{http.MethodPost, "/api/users/{username}/score", h.requireRole(services.RoleWriter, h.UpdateScore)},
+		{http.MethodPost, "/api/users/{username}/score/sync", h.requireRole(services.RoleWriter, h.SyncOfflineScores)},
+		{http.MethodGet, "/api/users/{username}/history", h.GetScoreHistory},
+		{http.MethodGet, "/api/submissions/{id}", h.requireRole(services.RoleWriter, h.GetSubmission)},
+		{http.MethodPost, "/api/guests/score", h.SubmitGuestScore},
+		{http.MethodPost, "/api/users/claim", h.ClaimGuest},
+
+		// Search
+		{http.MethodGet, "/api/search", h.SearchUser},
+
+		// Export
+		{http.MethodGet, "/api/export", h.ExportLeaderboard},
+
+		// Stats
+		{http.MethodGet, "/api/stats", h.GetStats},
+		{http.MethodGet, "/api/stats/count", h.CountUsers},
+		{http.MethodGet, "/api/stats/by-country", h.GetCountryStats},
+		{http.MethodGet, "/api/stats/by-tier", h.GetTierStats},
+		{http.MethodGet, "/api/stats/inflation", h.GetInflation},
+		{http.MethodGet, "/api/reports/{date}", h.GetDailyReport},
+
+		// Embeddable widget and feeds
+		{http.MethodGet, "/embed/leaderboard", h.GetEmbed},
+		{http.MethodGet, "/embed/leaderboard.png", h.GetEmbedImage},
+		{http.MethodGet, "/feeds/milestones.atom", h.GetMilestonesFeed},
+
+		// Simulation
+		{http.MethodGet, "/api/simulation/status", h.GetSimulationStatus},
+
+		// Third-party platforms
+		{http.MethodPost, "/api/integrations/scores", h.SubmitIntegrationScore},
+		{http.MethodGet, "/api/identities/{provider}/{external_id}", h.GetIdentity},
+		{http.MethodPut, "/api/identities/{provider}/{external_id}", h.requireRole(services.RoleAdmin, h.LinkIdentity)},
+		{http.MethodDelete, "/api/identities/{provider}/{external_id}", h.requireRole(services.RoleAdmin, h.UnlinkIdentity)},
+		{http.MethodGet, "/api/users/{username}/identities", h.ListUserIdentities},
+
+		// Live updates
+		{http.MethodGet, "/api/ws", h.Subscribe},
+		{http.MethodGet, "/api/events/schema/{version}", h.GetEventSchema},
+
+		// Moderation (moderator role when auth is enabled)
+		{http.MethodGet, "/api/moderation/users/{username}", h.requireRole(services.RoleModerator, h.GetModerationRecord)},
+		{http.MethodPost, "/api/moderation/users/{username}/adjust", h.requireRole(services.RoleModerator, h.AdjustScore)},
+		{http.MethodPut, "/api/moderation/users/{username}/freeze", h.requireRole(services.RoleModerator, h.FreezeUser)},
+		{http.MethodDelete, "/api/moderation/users/{username}/freeze", h.requireRole(services.RoleModerator, h.UnfreezeUser)},
+		{http.MethodPost, "/api/moderation/users/{username}/notes", h.requireRole(services.RoleModerator, h.AddStaffNote)},
+
+		// Admin (admin role when auth is enabled)
+		{http.MethodGet, "/api/admin/overview", h.requireRole(services.RoleAdmin, h.GetOverview)},
+		{http.MethodPut, "/api/admin/simulation", h.requireRole(services.RoleAdmin, h.ConfigureSimulation)},
+		{http.MethodPost, "/api/admin/maintenance", h.requireRole(services.RoleAdmin, h.SetMaintenance)},
+		{http.MethodGet, "/api/admin/config", h.requireRole(services.RoleAdmin, h.GetResolvedConfig)},
+		{http.MethodGet, "/api/admin/config/boards", h.requireRole(services.RoleAdmin, h.ExportBoardConfig)},
+		{http.MethodPut, "/api/admin/config/boards", h.requireRole(services.RoleAdmin, h.ApplyBoardConfig)},
+		{http.MethodGet, "/api/admin/shadow/compare", h.requireRole(services.RoleAdmin, h.CompareShadow)},
+		{http.MethodGet, "/api/admin/score-queue", h.requireRole(services.RoleAdmin, h.GetScoreQueueStats)},
+		{http.MethodGet, "/api/admin/season", h.requireRole(services.RoleAdmin, h.GetSeason)},
+		{http.MethodPut, "/api/admin/season", h.requireRole(services.RoleAdmin, h.StartSeason)},
+		{http.MethodPost, "/api/admin/season/close", h.requireRole(services.RoleAdmin, h.CloseSeason)},
+		{http.MethodGet, "/api/admin/dead-letters", h.requireRole(services.RoleAdmin, h.ListDeadLetters)},
+		{http.MethodGet, "/api/admin/dead-letters/{id}", h.requireRole(services.RoleAdmin, h.GetDeadLetter)},
+		{http.MethodPost, "/api/admin/dead-letters/{id}/retry", h.requireRole(services.RoleAdmin, h.RetryDeadLetter)},
+		{http.MethodDelete, "/api/admin/dead-letters/{id}", h.requireRole(services.RoleAdmin, h.DiscardDeadLetter)},
+		{http.MethodPost, "/api/admin/dead-letters/retry", h.requireRole(services.RoleAdmin, h.RetryDeadLetters)},
+		{http.MethodPost, "/api/admin/dead-letters/discard", h.requireRole(services.RoleAdmin, h.DiscardDeadLetters)},
+		{http.MethodGet, "/api/admin/imports", h.requireRole(services.RoleAdmin, h.GetImportStatus)},
+		{http.MethodPost, "/api/admin/imports/run", h.requireRole(services.RoleAdmin, h.RunImport)},
+		{http.MethodGet, "/api/admin/migration/verification", h.requireRole(services.RoleAdmin, h.GetDoubleWriteReport)},
+		{http.MethodGet, "/api/admin/health/history", h.requireRole(services.RoleAdmin, h.GetHealthHistory)},
+		{http.MethodGet, "/api/admin/realtime", h.requireRole(services.RoleAdmin, h.GetRealtimeStats)},
+		{http.MethodGet, "/api/admin/pending-changes", h.requireRole(services.RoleAdmin, h.ListPendingChanges)},
+		{http.MethodPost, "/api/admin/pending-changes/{id}/approve", h.requireRole(services.RoleAdmin, h.ApproveChange)},
+		{http.MethodDelete, "/api/admin/pending-changes/{id}", h.requireRole(services.RoleAdmin, h.RejectChange)},
+		{http.MethodGet, "/api/admin/anomalies", h.requireRole(services.RoleAdmin, h.ListAnomalies)},
+		{http.MethodDelete, "/api/admin/anomalies/{username}", h.requireRole(services.RoleAdmin, h.ResolveAnomaly)},
+		{http.MethodPost, "/api/admin/users/merge", h.requireRole(services.RoleAdmin, h.MergeUsers)},
+		{http.MethodGet, "/api/admin/users/{username}/sessions", h.requireRole(services.RoleAdmin, h.ListSessions)},
+		{http.MethodDelete, "/api/admin/users/{username}/sessions", h.requireRole(services.RoleAdmin, h.RevokeSessions)},
+		{http.MethodGet, "/api/admin/captures", h.requireRole(services.RoleAdmin, h.ListCaptures)},
+		{http.MethodPut, "/api/admin/captures", h.requireRole(services.RoleAdmin, h.ConfigureCaptures)},
+		{http.MethodDelete, "/api/admin/captures", h.requireRole(services.RoleAdmin, h.StopCaptures)},
+		{http.MethodPost, "/api/admin/integrity-check", h.requireRole(services.RoleAdmin, h.CheckIntegrity)},
+		{http.MethodPost, "/api/admin/boards/{id}/recompute", h.requireRole(services.RoleAdmin, h.RecomputeBoard)},
+		{http.MethodGet, "/api/admin/boards/jobs/{id}", h.requireRole(services.RoleAdmin, h.GetBoardRecomputeJob)},
+		{http.MethodGet, "/api/admin/lockouts", h.requireRole(services.RoleAdmin, h.ListLockouts)},
+		{http.MethodDelete, "/api/admin/lockouts/{key}", h.requireRole(services.RoleAdmin, h.ClearLockout)},
+		{http.MethodGet, "/api/admin/roles", h.requireRole(services.RoleAdmin, h.ListRoles)},
+		{http.MethodPut, "/api/admin/users/{username}/roles/{role}", h.requireRole(services.RoleAdmin, h.GrantUserRole)},
+		{http.MethodDelete, "/api/admin/users/{username}/roles/{role}", h.requireRole(services.RoleAdmin, h.RevokeUserRole)},
+		{http.MethodPut, "/api/admin/keys/{name}/roles/{role}", h.requireRole(services.RoleAdmin, h.GrantKeyRole)},
+		{http.MethodDelete, "/api/admin/keys/{name}/roles/{role}", h.requireRole(services.RoleAdmin, h.RevokeKeyRole)},
+		{http.MethodPut, "/api/admin/identities/{provider}/{subject}/roles/{role}", h.requireRole(services.RoleAdmin, h.GrantIdentityRole)},
+		{http.MethodDelete, "/api/admin/identities/{provider}/{subject}/roles/{role}", h.requireRole(services.RoleAdmin, h.RevokeIdentityRole)},
+	}
+}
+
+// adminPrefixes are the paths served on the admin listener when the API is
+// split across ports
+var adminPrefixes = []string{"/api/admin/", "/api/moderation/", "/metrics", "/debug/"}
+
+// adminRoutes are the admin listener's routes outside adminPrefixes, by
+// method and pattern: identity linking, whose lookups stay public
+var adminRoutes = []string{
+	http.MethodPut + " /api/identities/{provider}/{external_id}",
+	http.MethodDelete + " /api/identities/{provider}/{external_id}",
+}
+
+// IsAdminPath reports whether path belongs to the admin surface rather than
+// the public API
+func IsAdminPath(path string) bool {
+	for _, prefix := range adminPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdminRoute reports whether route belongs to the admin surface, by its
+// path or, for routes sharing a path with public ones, its method
+func IsAdminRoute(route Route) bool {
+	return IsAdminPath(route.Path) || slices.Contains(adminRoutes, route.Method+" "+route.Path)
+}
+
+// SplitRoutes separates the admin surface from the public API
+func SplitRoutes(routes []Route) (public, admin []Route) {
+	for _, route := range routes {
+		if IsAdminRoute(route) {
+			admin = append(admin, route)
+		} else {
+			public = append(public, route)
+		}
+	}
+	return public, admin
+}
+
+// Mount registers routes on a standard library mux
+func Mount(mux *http.ServeMux, routes []Route) {
+	for _, route := range routes {
+		mux.HandleFunc(route.Method+" "+route.Path, route.Handler)
+	}
+}
+
+// NewServeMux returns a net/http handler serving the whole leaderboard API
+func (h *LeaderboardHandler) NewServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	Mount(mux, h.Routes())
+	return mux
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/services"
+	"backend/pkg/store"
+)
+
+// TestAdminSurfaceNeedsTheAdminRole checks the split against the role
+// checks: every route on the admin listener turns away callers without a
+// staff role, and every route that does is on the admin listener
+func TestAdminSurfaceNeedsTheAdminRole(t *testing.T) {
+	st := store.NewMemoryStore()
+	if err := st.AddUser("alice", 1500); err != nil {
+		t.Fatal(err)
+	}
+	service := services.NewLeaderboardService(st)
+	keys := map[string]string{"writer": "writer-secret"}
+	a, err := auth.New(auth.Config{
+		Secret:  []byte(strings.Repeat("k", 32)),
+		APIKeys: keys,
+		Lockout: auth.LockoutConfig{Threshold: 1 << 20},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := service.GrantRole(ctx, services.Principal(services.PrincipalKey, "writer"), services.RoleWriter); err != nil {
+		t.Fatal(err)
+	}
+	h := NewLeaderboardHandler(service)
+	h.EnableAuth(a)
+	mux := h.NewServeMux()
+
+	params := regexp.MustCompile(`\{[^}]*\}`)
+	serve := func(route Route, key string) int {
+		target := params.ReplaceAllString(route.Path, "x")
+		req := httptest.NewRequest(route.Method, target, nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", keys[key])
+		}
+		reqCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req.WithContext(reqCtx))
+		return rec.Code
+	}
+
+	public, admin := SplitRoutes(h.Routes())
+	if len(admin) == 0 {
+		t.Fatal("no admin routes")
+	}
+	for _, route := range admin {
+		if code := serve(route, ""); code != http.StatusUnauthorized {
+			t.Errorf("%s %s without credentials: %d, want 401", route.Method, route.Path, code)
+		}
+		if code := serve(route, "writer"); code != http.StatusForbidden {
+			t.Errorf("%s %s as a writer: %d, want 403", route.Method, route.Path, code)
+		}
+	}
+	for _, route := range public {
+		if code := serve(route, "writer"); code == http.StatusForbidden {
+			t.Errorf("%s %s forbids writers but is served publicly", route.Method, route.Path)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"backend/internal/models"
+	"backend/internal/services"
+)
+
+// maxClosedStandings bounds the standings returned when closing a season
+const maxClosedStandings = 100
+
+// GetSeason returns the running season, the last closed season and webhook deliveries
+// GET /api/admin/season
+func (h *LeaderboardHandler) GetSeason(w http.ResponseWriter, r *http.Request) {
+	var lastClosed *models.SeasonResult
+	if result := h.service.LastSeasonResult(); result != nil {
+		summary := *result
+		summary.Standings = nil
+		lastClosed = &summary
+	}
+
+	writeJSON(w, http.StatusOK, H{
+		"season":      h.service.CurrentSeason(),
+		"last_closed": lastClosed,
+		"deliveries":  h.service.ListWebhookDeliveries(),
+	})
+}
+
+// StartSeason begins a season
+// PUT /api/admin/season
+func (h *LeaderboardHandler) StartSeason(w http.ResponseWriter, r *http.Request) {
+	var req models.SeasonRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	season, err := h.service.StartSeason(r.Context(), req.ID, req.EndsAt)
+	if err != nil {
+		var fieldErr models.FieldError
+		switch {
+		case errors.As(err, &fieldErr):
+			respondFieldErrors(w, fieldErr)
+		case errors.Is(err, services.ErrSeasonActive):
+			writeError(w, http.StatusConflict, "season_active", "Close the running season before starting another")
+		default:
+			writeFailure(w, "season_failed", err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, season)
+}
+
+// CloseSeason freezes the running season's standings and notifies the season
+// webhooks. The first call returns a confirmation token for the running
+// season; repeating it with ?confirm=<token> closes the season.
+// POST /api/admin/season/close?confirm=<token>
+func (h *LeaderboardHandler) CloseSeason(w http.ResponseWriter, r *http.Request) {
+	season := h.service.CurrentSeason()
+	if season == nil {
+		writeError(w, http.StatusNotFound, "no_season", "No season is running")
+		return
+	}
+	if !h.confirmed(w, r, services.OperationCloseSeason, season.ID) {
+		return
+	}
+
+	result, err := h.service.CloseSeason(r.Context())
+	if err != nil {
+		if errors.Is(err, services.ErrNoSeason) {
+			writeError(w, http.StatusNotFound, "no_season", "No season is running")
+			return
+		}
+		writeFailure(w, "season_failed", err)
+		return
+	}
+
+	response := *result
+	response.Standings = result.Standings[:min(maxClosedStandings, len(result.Standings))]
+	writeJSON(w, http.StatusOK, response)
+}
+
+// GetPrizes lists the users qualifying for each prize band at the last season close
+// GET /api/leaderboards/{id}/prizes?preview=true
+func (h *LeaderboardHandler) GetPrizes(w http.ResponseWriter, r *http.Request) {
+	if pathParam(r, "id") != h.service.BoardID() {
+		writeError(w, http.StatusNotFound, "board_not_found", "Leaderboard does not exist")
+		return
+	}
+	preview, previewErr := queryBool(r, "preview")
+	if previewErr != nil {
+		respondFieldErrors(w, *previewErr)
+		return
+	}
+
+	prizes, err := h.service.GetPrizes(r.Context(), preview)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPrizesNotConfigured):
+			writeError(w, http.StatusNotFound, "prizes_not_configured", "Set PRIZE_BANDS to configure prizes")
+		case errors.Is(err, services.ErrNoClosedSeason):
+			writeError(w, http.StatusNotFound, "no_closed_season", "No season has closed yet, use preview=true for live standings")
+		default:
+			writeFailure(w, "prizes_failed", err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, prizes)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"backend/internal/models"
+	"backend/internal/services"
+)
+
+// respondSeedError maps seed job failures to API errors
+func respondSeedError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrSeedRunning):
+		writeError(w, http.StatusConflict, "seed_running", "A seed is already running")
+	case errors.Is(err, services.ErrSeedJobNotFound):
+		writeError(w, http.StatusNotFound, "seed_job_not_found", "Seed job does not exist or has been forgotten")
+	default:
+		writeFailure(w, "seed_failed", err)
+	}
+}
+
+// SeedData starts seeding the leaderboard with generated users. The seed
+// runs as a background job unless the request asks to wait for it.
+// POST /api/seed
+func (h *LeaderboardHandler) SeedData(w http.ResponseWriter, r *http.Request) {
+	var req models.SeedRequest
+	if err := bindJSON(r, &req); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+
+	if req.Wait {
+		job, err := h.service.SeedData(r.Context(), req.Count)
+		if err != nil {
+			respondSeedError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+		return
+	}
+
+	job, err := h.service.StartSeed(req.Count, nil)
+	if err != nil {
+		respondSeedError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/api/seed/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// ListSeedJobs returns recent seed jobs, newest first
+// GET /api/seed/jobs
+func (h *LeaderboardHandler) ListSeedJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, H{"jobs": h.service.SeedJobs()})
+}
+
+// GetSeedJob reports a seed job's progress
+// GET /api/seed/jobs/{id}
+func (h *LeaderboardHandler) GetSeedJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.service.SeedJob(pathParam(r, "id"))
+	if err != nil {
+		respondSeedError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// CancelSeedJob stops a running seed job, keeping the users it wrote
+// DELETE /api/seed/jobs/{id}
+func (h *LeaderboardHandler) CancelSeedJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.service.CancelSeed(r.Context(), pathParam(r, "id"))
+	if err != nil {
+		respondSeedError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"backend/internal/models"
+	"backend/internal/services"
+)
+
+// Request priorities sent in X-Priority
+const (
+	PriorityNormal = "normal"
+	PriorityHigh   = "high" // Never shed; requires the priority role
+)
+
+// loadShedder rejects normal-priority requests while too many requests are
+// in flight, so internal callers sending X-Priority: high keep being served
+// during a read storm from public clients
+type loadShedder struct {
+	limit    atomic.Int64 // 0 disables shedding
+	inFlight atomic.Int64
+}
+
+// SetMaxInFlight sheds normal-priority requests once limit requests are in
+// flight; 0 disables shedding
+func (h *LeaderboardHandler) SetMaxInFlight(limit int) {
+	h.shedder.limit.Store(int64(max(limit, 0)))
+}
+
+// shedLoad wraps every route so it honors X-Priority and the in-flight limit.
+// WebSockets stay open for the whole session, so they aren't counted, and
+// /readyz isn't either.
+func (h *LeaderboardHandler) shedLoad(routes []Route) []Route {
+	for i, route := range routes {
+		if route.Path == "/readyz" || route.Path == "/api/ws" {
+			continue
+		}
+		routes[i].Handler = h.admit(route.Handler)
+	}
+	return routes
+}
+
+func (h *LeaderboardHandler) admit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		priority := r.Header.Get("X-Priority")
+		switch priority {
+		case "", PriorityNormal:
+			priority = PriorityNormal
+		case PriorityHigh:
+			var ok bool
+			if r, ok = h.authorize(w, r, services.RolePriority); !ok {
+				return
+			}
+		default:
+			respondFieldErrors(w, models.FieldError{Field: "X-Priority", Rule: "oneof", Param: PriorityNormal + " " + PriorityHigh, Value: priority})
+			return
+		}
+
+		s := &h.shedder
+		inFlight := s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		if limit := s.limit.Load(); priority == PriorityNormal && limit > 0 && inFlight > limit {
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusServiceUnavailable, "overloaded", "Too many requests in flight, retry shortly")
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/internal/services"
+)
+
+// measureLatency wraps every route in an endpoint group so its latency counts
+// towards the group's SLOs. It sits inside maintenance mode, load shedding
+// and rate limiting, so requests they turn away aren't measured.
+func (h *LeaderboardHandler) measureLatency(routes []Route) []Route {
+	for i, route := range routes {
+		if group := latencyGroup(route); group != "" {
+			routes[i].Handler = h.timed(group, route.Handler)
+		}
+	}
+	return routes
+}
+
+func (h *LeaderboardHandler) timed(group string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next(recorder, r)
+		h.service.ObserveLatency(group, time.Since(start), recorder.status)
+	}
+}
+
+// latencyGroup names the endpoint group a route belongs to, or "" for routes
+// that aren't measured, such as exports, whose latency grows with the board,
+// and offline syncs, whose latency grows with the batch
+func latencyGroup(route Route) string {
+	switch {
+	case IsAdminRoute(route):
+		return services.GroupAdmin
+	case route.Path == "/api/leaderboard", route.Path == "/api/leaderboards/{id}", route.Path == "/api/leaderboards/{id}/prizes",
+		route.Path == "/api/leaderboards/{id}/cutoffs", route.Path == "/api/boards", route.Path == "/api/boards/{id}":
+		return services.GroupLeaderboard
+	case route.Path == "/api/users/{username}" && route.Method == http.MethodGet:
+		return services.GroupUserRank
+	case route.Path == "/api/users/{username}/score", route.Path == "/api/integrations/scores", route.Path == "/api/guests/score":
+		return services.GroupScoreUpdates
+	case route.Path == "/api/search", route.Path == "/api/users":
+		return services.GroupSearch
+	case strings.HasPrefix(route.Path, "/api/stats"):
+		return services.GroupStats
+	case route.Path == "/api/users/{username}/history":
+		return services.GroupHistory
+	}
+	return ""
+}
+
+// statusRecorder remembers the status a handler answered with
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// flushEvery controls how many elements are written before flushing to the client
+const flushEvery = 500
+
+// streamWriter writes a list response one element at a time so large result
+// sets never have to be held in memory as a single encoded payload.
+//
+// In JSON mode the output is an object of the form {"<key>":[...],"count":N}.
+// In JSON Lines mode every element is written on its own line.
+type streamWriter struct {
+	w       http.ResponseWriter
+	enc     *json.Encoder
+	key     string
+	lines   bool
+	started bool
+	count   int
+}
+
+// newStreamWriter creates a stream writer for the given list key.
+// JSON Lines is selected with ?format=jsonl or an Accept: application/x-ndjson header.
+func newStreamWriter(w http.ResponseWriter, r *http.Request, key string) *streamWriter {
+	return &streamWriter{
+		w:     w,
+		enc:   json.NewEncoder(w),
+		key:   key,
+		lines: wantsJSONLines(r),
+	}
+}
+
+func wantsJSONLines(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "jsonl" {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/x-ndjson")
+}
+
+func (s *streamWriter) start() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+
+	if s.lines {
+		s.w.Header().Set("Content-Type", "application/x-ndjson")
+		s.w.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	s.w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	s.w.WriteHeader(http.StatusOK)
+	_, err := fmt.Fprintf(s.w, "{%q:[", s.key)
+	return err
+}
+
+// Write encodes a single element
+func (s *streamWriter) Write(v any) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+
+	if !s.lines && s.count > 0 {
+		if _, err := s.w.Write([]byte{','}); err != nil {
+			return err
+		}
+	}
+
+	if err := s.enc.Encode(v); err != nil {
+		return err
+	}
+
+	s.count++
+	if s.count%flushEvery == 0 {
+		s.flush()
+	}
+	return nil
+}
+
+// Close terminates the stream. In JSON mode the element count and any extra
+// fields are appended to the enclosing object; they are ignored for JSON Lines.
+func (s *streamWriter) Close(extra H) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	defer s.flush()
+
+	if s.lines {
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(s.w, `],"count":%d`, s.count); err != nil {
+		return err
+	}
+	for k, v := range extra {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(s.w, ",%q:%s", k, b); err != nil {
+			return err
+		}
+	}
+	_, err := s.w.Write([]byte{'}'})
+	return err
+}
+
+func (s *streamWriter) flush() {
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Started reports whether any bytes have been written to the client
+func (s *streamWriter) Started() bool {
+	return s.started
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"backend/internal/models"
+	"backend/internal/services"
+)
+
+// enqueueScore answers an async score submission with 202 and its submission ID
+func (h *LeaderboardHandler) enqueueScore(w http.ResponseWriter, r *http.Request, username string, req models.UpdateScoreRequest) {
+	submission, err := h.service.EnqueueScore(r.Context(), username, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrQueueDisabled):
+			writeError(w, http.StatusBadRequest, "async_disabled", "Set SCORE_QUEUE_WORKERS to accept async submissions")
+		case errors.Is(err, services.ErrQueueFull):
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusServiceUnavailable, "queue_full", "Score queue is full, retry shortly")
+		case errors.Is(err, services.ErrQueueClosed):
+			writeError(w, http.StatusServiceUnavailable, "draining", "Server is shutting down, retry against another instance")
+		default:
+			writeFailure(w, "enqueue_failed", err)
+		}
+		return
+	}
+
+	w.Header().Set("Location", "/api/submissions/"+submission.ID)
+	writeJSON(w, http.StatusAccepted, submission)
+}
+
+// GetSubmission reports whether a queued score submission has been applied
+// GET /api/submissions/{id}
+func (h *LeaderboardHandler) GetSubmission(w http.ResponseWriter, r *http.Request) {
+	status, err := h.service.GetSubmission(r.Context(), pathParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, services.ErrSubmissionNotFound) || errors.Is(err, services.ErrQueueDisabled) {
+			writeError(w, http.StatusNotFound, "submission_not_found", "Submission does not exist or has been forgotten")
+			return
+		}
+		writeFailure(w, "fetch_failed", err)
+		return
+	}
+
+	submission := status.Submission
+	if status.Err != nil {
+		_, body := scoreErrorResponse(status.Err)
+		body = translateError(w, body)
+		submission.Error = &body
+	}
+	writeJSON(w, http.StatusOK, submission)
+}
+
+// GetScoreQueueStats reports the async score queue's depth and outcomes
+// GET /api/admin/score-queue
+func (h *LeaderboardHandler) GetScoreQueueStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.ScoreQueueStats(r.Context())
+	if errors.Is(err, services.ErrQueueDisabled) {
+		writeError(w, http.StatusNotFound, "async_disabled", "Set SCORE_QUEUE_WORKERS to accept async submissions")
+		return
+	}
+	if err != nil {
+		writeFailure(w, "fetch_failed", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// validate checks the `binding` tags on request models
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+
+	// Report fields by their wire name (json/form/uri tag) instead of the Go field name
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		for _, tag := range []string{"json", "form", "uri"} {
+			name := strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return f.Name
+	})
+
+	return v
+}
+
+// validationErrors translates binding and validation errors into field-level details
+func validationErrors(err error) []models.FieldError {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		details := make([]models.FieldError, 0, len(verrs))
+		for _, fe := range verrs {
+			details = append(details, models.FieldError{
+				Field: fe.Field(),
+				Rule:  fe.Tag(),
+				Param: fe.Param(),
+				Value: fe.Value(),
+			})
+		}
+		return details
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []models.FieldError{{
+			Field: typeErr.Field,
+			Rule:  "type",
+			Param: typeErr.Type.String(),
+			Value: typeErr.Value,
+		}}
+	}
+
+	return nil
+}
+
+// respondValidationError writes a 400 response with structured field errors
+func respondValidationError(w http.ResponseWriter, err error) {
+	details := validationErrors(err)
+
+	if len(details) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	respondFieldErrors(w, details...)
+}
+
+// respondFieldErrors writes a 400 response for failed field rules
+func respondFieldErrors(w http.ResponseWriter, details ...models.FieldError) {
+	parts := make([]string, 0, len(details))
+	for _, d := range details {
+		parts = append(parts, d.String())
+	}
+
+	writeErrorResponse(w, http.StatusBadRequest, models.ErrorResponse{
+		Error:   "invalid_request",
+		Message: strings.Join(parts, "; "),
+		Details: details,
+	})
+}
+
+// queryInt parses an optional integer query parameter and checks it against [min, max]
+func queryInt(r *http.Request, name string, def, min, max int) (int, *models.FieldError) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, &models.FieldError{Field: name, Rule: "type", Param: "int", Value: raw}
+	}
+	if value < min {
+		return 0, &models.FieldError{Field: name, Rule: "min", Param: strconv.Itoa(min), Value: value}
+	}
+	if value > max {
+		return 0, &models.FieldError{Field: name, Rule: "max", Param: strconv.Itoa(max), Value: value}
+	}
+
+	return value, nil
+}
+
+// queryBool parses an optional boolean query parameter
+func queryBool(r *http.Request, name string) (bool, *models.FieldError) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return false, nil
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, &models.FieldError{Field: name, Rule: "type", Param: "bool", Value: raw}
+	}
+	return value, nil
+}
+
+// queryTime parses an RFC 3339 query parameter, returning the zero time if it is absent
+func queryTime(r *http.Request, name string) (time.Time, *models.FieldError) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+
+	value, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, &models.FieldError{Field: name, Rule: "type", Param: "RFC3339 timestamp", Value: raw}
+	}
+	return value, nil
+}
+
+// collectFieldErrors gathers the non-nil field errors
+func collectFieldErrors(errs ...*models.FieldError) []models.FieldError {
+	details := make([]models.FieldError, 0, len(errs))
+	for _, err := range errs {
+		if err != nil {
+			details = append(details, *err)
+		}
+	}
+	return details
+}
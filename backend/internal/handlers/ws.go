@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/services"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	socketWriteWait  = 10 * time.Second
+	socketPongWait   = 60 * time.Second
+	socketPingPeriod = socketPongWait * 9 / 10
+	socketMaxMessage = 64 * 1024
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// CORS allows every origin, so the socket does too
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// Subscribe streams leaderboard events over a WebSocket. Clients receive
+// nothing until they send a subscribe message with their filters:
+//
+//	{"type":"subscribe","filters":{"usernames":["user_1"],"top":100}}
+//
+// With "cutoffs":true in the filters, watched cutoff changes are sent too.
+//
+// GET /api/ws
+func (h *LeaderboardHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	r, done, ok := h.beginStream(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already written an error response
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	matchers := make(chan *services.EventMatcher, 1)
+	replies := make(chan models.SocketMessage, 8)
+	go h.readSocket(ctx, cancel, conn, matchers, replies)
+
+	// The hub queues events per connection; a stalled socket only hurts itself
+	client := h.service.Realtime().Join()
+	defer client.Leave()
+
+	// Cutoff changes are announced while an event is published, so they're
+	// queued rather than written there; a full queue drops them
+	cutoffs := make(chan models.CutoffChange, 8)
+	stopCutoffs := h.service.OnCutoffChange(func(c models.CutoffChange) {
+		select {
+		case cutoffs <- c:
+		default:
+		}
+	})
+	defer stopCutoffs()
+
+	ping := time.NewTicker(socketPingPeriod)
+	defer ping.Stop()
+
+	var matcher *services.EventMatcher
+	for {
+		select {
+		case <-ctx.Done():
+			// Draining or the client went away: say goodbye if the socket is still open
+			closeSocket(conn, websocket.CloseGoingAway, "server shutting down")
+			return
+		case <-client.Done():
+			closeSocket(conn, websocket.CloseTryAgainLater, "slow consumer")
+			return
+		case matcher = <-matchers:
+		case reply := <-replies:
+			if err := writeSocket(conn, reply); err != nil {
+				return
+			}
+		case e := <-client.Events():
+			// Queued events don't outlive a slow consumer disconnect
+			select {
+			case <-client.Done():
+				closeSocket(conn, websocket.CloseTryAgainLater, "slow consumer")
+				return
+			default:
+			}
+			if dropped := client.TakeDropped(); dropped > 0 {
+				if err := writeSocket(conn, models.SocketMessage{Type: "lagged", Dropped: dropped}); err != nil {
+					return
+				}
+			}
+			if matcher == nil || !matcher.Match(e) {
+				continue
+			}
+			if err := writeSocket(conn, models.SocketMessage{Type: "event", Event: e}); err != nil {
+				return
+			}
+		case c := <-cutoffs:
+			if matcher == nil || !matcher.WantsCutoffs() {
+				continue
+			}
+			if err := writeSocket(conn, models.SocketMessage{Type: "cutoff", Cutoff: &c}); err != nil {
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readSocket handles client messages until the connection fails, passing
+// new filters and replies to the writer
+func (h *LeaderboardHandler) readSocket(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, matchers chan<- *services.EventMatcher, replies chan<- models.SocketMessage) {
+	defer cancel()
+
+	conn.SetReadLimit(socketMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(socketPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(socketPongWait))
+	})
+
+	for {
+		var msg models.SocketMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("WebSocket read failed: %v", err)
+			}
+			return
+		}
+
+		var reply models.SocketMessage
+		switch msg.Type {
+		case "subscribe":
+			filter := models.SubscriptionFilter{}
+			if msg.Filters != nil {
+				filter = *msg.Filters
+			}
+			if err := validate.Struct(filter); err != nil {
+				reply = socketError("invalid_request", "Invalid subscription filters", validationErrors(err))
+				break
+			}
+			if !sendMatcher(ctx, matchers, h.service.NewEventMatcher(filter)) {
+				return
+			}
+			reply = models.SocketMessage{Type: "subscribed", Filters: &filter}
+		case "unsubscribe":
+			if !sendMatcher(ctx, matchers, nil) {
+				return
+			}
+			reply = models.SocketMessage{Type: "unsubscribed"}
+		default:
+			reply = socketError("unknown_message", `Message type must be "subscribe" or "unsubscribe"`, nil)
+		}
+
+		select {
+		case replies <- reply:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func sendMatcher(ctx context.Context, matchers chan<- *services.EventMatcher, matcher *services.EventMatcher) bool {
+	select {
+	case matchers <- matcher:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func socketError(code, message string, details []models.FieldError) models.SocketMessage {
+	return models.SocketMessage{
+		Type:  "error",
+		Error: &models.ErrorResponse{Error: code, Message: message, Details: details},
+	}
+}
+
+func closeSocket(conn *websocket.Conn, code int, reason string) {
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(socketWriteWait))
+}
+
+func writeSocket(conn *websocket.Conn, msg models.SocketMessage) error {
+	conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+	return conn.WriteJSON(msg)
+}
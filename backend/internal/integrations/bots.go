@@ -0,0 +1,216 @@
+// Package integrations connects the leaderboard to chat platforms. Its bots
+// answer rank queries such as "!rank user_42", post the top 10 on a schedule
+// and announce milestones as they happen.
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/services"
+	"backend/pkg/store"
+)
+
+// Config selects the chat platforms to connect to. Platforms left nil are skipped.
+type Config struct {
+	Discord          *DiscordConfig
+	Telegram         *TelegramConfig
+	SnapshotInterval time.Duration // Post the top 10 this often, 0 disables
+}
+
+const (
+	snapshotSize       = 10
+	announcementBuffer = 64 // Milestones waiting to be posted before new ones are dropped
+	reconnectMinDelay  = time.Second
+	reconnectMaxDelay  = 5 * time.Minute
+	requestTimeout     = 10 * time.Second
+)
+
+// chat is one connected platform
+type chat interface {
+	name() string
+	// listen delivers incoming messages to reply until ctx ends or the
+	// connection fails
+	listen(ctx context.Context, reply replyFunc) error
+	// announce posts to the configured announcement channel
+	announce(ctx context.Context, text string) error
+}
+
+// replyFunc answers an incoming message; "" means no answer
+type replyFunc func(ctx context.Context, text string) string
+
+// Run connects the configured bots and serves them until ctx is cancelled
+func Run(ctx context.Context, service *services.LeaderboardService, config Config) {
+	var chats []chat
+	if config.Discord != nil {
+		chats = append(chats, newDiscordBot(*config.Discord))
+	}
+	if config.Telegram != nil {
+		chats = append(chats, newTelegramBot(*config.Telegram))
+	}
+	if len(chats) == 0 {
+		return
+	}
+
+	commands := &commands{service: service}
+	var wg sync.WaitGroup
+	for _, c := range chats {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			keepListening(ctx, c, commands.reply)
+		}()
+	}
+
+	// Milestones arrive while events are published, so they are handed off
+	// rather than posted inline
+	milestones := make(chan models.Milestone, announcementBuffer)
+	stop := service.OnMilestone(func(m models.Milestone) {
+		select {
+		case milestones <- m:
+		default:
+			log.Printf("Bot announcement queue full, dropped milestone %s", m.ID)
+		}
+	})
+	defer stop()
+
+	var snapshots <-chan time.Time
+	if config.SnapshotInterval > 0 {
+		ticker := time.NewTicker(config.SnapshotInterval)
+		defer ticker.Stop()
+		snapshots = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case m := <-milestones:
+			broadcast(ctx, chats, milestoneIcon(m.Type)+" "+m.Title)
+		case <-snapshots:
+			text, err := commands.top(ctx)
+			if err != nil {
+				log.Printf("Failed to build top %d snapshot: %v", snapshotSize, err)
+				continue
+			}
+			broadcast(ctx, chats, text)
+		}
+	}
+}
+
+// keepListening reconnects c with exponential backoff until ctx ends
+func keepListening(ctx context.Context, c chat, reply replyFunc) {
+	delay := reconnectMinDelay
+	for {
+		started := time.Now()
+		err := c.listen(ctx, reply)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > reconnectMaxDelay {
+			delay = reconnectMinDelay // It was up for a while, so this is a fresh failure
+		}
+		log.Printf("%s bot disconnected, reconnecting in %s: %v", c.name(), delay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, reconnectMaxDelay)
+	}
+}
+
+func broadcast(ctx context.Context, chats []chat, text string) {
+	for _, c := range chats {
+		ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+		if err := c.announce(ctx, text); err != nil {
+			log.Printf("Failed to post to %s: %v", c.name(), err)
+		}
+		cancel()
+	}
+}
+
+func milestoneIcon(kind string) string {
+	switch kind {
+	case models.MilestoneNewLeader:
+		return "👑"
+	case models.MilestoneRecord:
+		return "🚀"
+	default:
+		return "📈"
+	}
+}
+
+// commands answers chat commands, which start with ! or / as is usual on
+// Discord and Telegram respectively
+type commands struct {
+	service *services.LeaderboardService
+}
+
+const helpText = "Commands: !rank <username>, !top, !help"
+
+func (c *commands) reply(ctx context.Context, text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || (fields[0][0] != '!' && fields[0][0] != '/') {
+		return ""
+	}
+	// Telegram addresses commands in groups as /rank@SomeBot
+	command, _, _ := strings.Cut(strings.ToLower(fields[0][1:]), "@")
+	args := fields[1:]
+
+	switch command {
+	case "rank":
+		if len(args) != 1 {
+			return "Usage: !rank <username>"
+		}
+		return c.rank(ctx, args[0])
+	case "top":
+		text, err := c.top(ctx)
+		if err != nil {
+			log.Printf("Bot failed to answer !top: %v", err)
+			return "Could not load the leaderboard, try again later"
+		}
+		return text
+	case "help":
+		return helpText
+	}
+	return ""
+}
+
+func (c *commands) rank(ctx context.Context, username string) string {
+	user, err := c.service.GetUserRank(ctx, username)
+	if errors.Is(err, store.ErrUserNotFound) {
+		return fmt.Sprintf("%s is not on the leaderboard", username)
+	}
+	if err != nil {
+		log.Printf("Bot failed to look up %s: %v", username, err)
+		return "Could not look up that player, try again later"
+	}
+	return fmt.Sprintf("%s is #%d with a rating of %d", user.Username, user.Rank, user.Rating)
+}
+
+// top renders the leading players, bots left out
+func (c *commands) top(ctx context.Context) (string, error) {
+	leaderboard, err := c.service.GetLeaderboard(ctx, 1, snapshotSize, services.ListOptions{ExcludeBots: true})
+	if err != nil {
+		return "", err
+	}
+	if len(leaderboard.Entries) == 0 {
+		return "🏆 No ranked players yet", nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🏆 Top %d", len(leaderboard.Entries))
+	for _, e := range leaderboard.Entries {
+		fmt.Fprintf(&b, "\n%d. %s: %d", e.Rank, e.Username, e.Rating)
+	}
+	return b.String(), nil
+}
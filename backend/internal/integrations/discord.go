@@ -0,0 +1,211 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DiscordConfig connects a Discord bot. The bot needs the Message Content
+// intent enabled in the developer portal to read commands.
+type DiscordConfig struct {
+	Token     string // Bot token
+	ChannelID string // Where snapshots and milestones are posted, empty to only answer commands
+}
+
+var (
+	discordGateway = "wss://gateway.discord.gg/?v=10&encoding=json"
+	discordAPI     = "https://discord.com/api/v10"
+)
+
+// Gateway opcodes and intents, see https://discord.com/developers/docs/topics/gateway
+const (
+	discordOpDispatch       = 0
+	discordOpHeartbeat      = 1
+	discordOpIdentify       = 2
+	discordOpReconnect      = 7
+	discordOpInvalidSession = 9
+	discordOpHello          = 10
+
+	discordIntents = 1<<9 | 1<<12 | 1<<15 // Guild messages, direct messages, message content
+)
+
+type discordPayload struct {
+	Op   int             `json:"op"`
+	Data json.RawMessage `json:"d"`
+	Seq  *int64          `json:"s,omitempty"`
+	Type string          `json:"t,omitempty"`
+}
+
+type discordMessage struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	Content   string `json:"content"`
+	Author    struct {
+		Bot bool `json:"bot"`
+	} `json:"author"`
+}
+
+// discordBot reads commands from the gateway and posts through the REST API
+type discordBot struct {
+	config DiscordConfig
+	client *http.Client
+}
+
+func newDiscordBot(config DiscordConfig) *discordBot {
+	return &discordBot{config: config, client: &http.Client{Timeout: requestTimeout}}
+}
+
+func (b *discordBot) name() string { return "Discord" }
+
+// listen holds one gateway session. Sessions aren't resumed: a reconnect
+// identifies afresh, and commands sent in between are missed.
+func (b *discordBot) listen(ctx context.Context, reply replyFunc) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, discordGateway, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var hello discordPayload
+	if err := conn.ReadJSON(&hello); err != nil {
+		return err
+	}
+	var helloData struct {
+		HeartbeatInterval int64 `json:"heartbeat_interval"`
+	}
+	if err := json.Unmarshal(hello.Data, &helloData); hello.Op != discordOpHello || err != nil || helloData.HeartbeatInterval <= 0 {
+		return fmt.Errorf("expected hello, got op %d", hello.Op)
+	}
+
+	var (
+		writeMu sync.Mutex
+		seqMu   sync.Mutex
+		seq     *int64
+	)
+	send := func(op int, data any) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(requestTimeout))
+		return conn.WriteJSON(map[string]any{"op": op, "d": data})
+	}
+
+	if err := send(discordOpIdentify, map[string]any{
+		"token":   b.config.Token,
+		"intents": discordIntents,
+		"properties": map[string]string{
+			"os":      "linux",
+			"browser": "leaderboard",
+			"device":  "leaderboard",
+		},
+	}); err != nil {
+		return err
+	}
+
+	heartbeatDone := make(chan struct{})
+	defer close(heartbeatDone)
+	go func() {
+		ticker := time.NewTicker(time.Duration(helloData.HeartbeatInterval) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-heartbeatDone:
+				return
+			case <-ticker.C:
+				seqMu.Lock()
+				last := seq
+				seqMu.Unlock()
+				if err := send(discordOpHeartbeat, last); err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		var payload discordPayload
+		if err := conn.ReadJSON(&payload); err != nil {
+			return err
+		}
+		if payload.Seq != nil {
+			seqMu.Lock()
+			seq = payload.Seq
+			seqMu.Unlock()
+		}
+
+		switch payload.Op {
+		case discordOpReconnect:
+			return errors.New("gateway asked to reconnect")
+		case discordOpInvalidSession:
+			return errors.New("gateway invalidated the session")
+		case discordOpDispatch:
+			if payload.Type != "MESSAGE_CREATE" {
+				continue
+			}
+			var msg discordMessage
+			if err := json.Unmarshal(payload.Data, &msg); err != nil || msg.Author.Bot {
+				continue
+			}
+			go func() {
+				ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+				defer cancel()
+				answer := reply(ctx, msg.Content)
+				if answer == "" {
+					return
+				}
+				if err := b.post(ctx, msg.ChannelID, answer, msg.ID); err != nil {
+					log.Printf("Failed to reply on Discord: %v", err)
+				}
+			}()
+		}
+	}
+}
+
+func (b *discordBot) announce(ctx context.Context, text string) error {
+	if b.config.ChannelID == "" {
+		return nil
+	}
+	return b.post(ctx, b.config.ChannelID, text, "")
+}
+
+// post sends a message to a channel, as a reply when replyTo is set
+func (b *discordBot) post(ctx context.Context, channelID, text, replyTo string) error {
+	body := map[string]any{"content": text}
+	if replyTo != "" {
+		body["message_reference"] = map[string]string{"message_id": replyTo}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discordAPI+"/channels/"+channelID+"/messages", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+b.config.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("discord answered %s: %s", resp.Status, detail)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// TelegramConfig connects a Telegram bot. In groups the bot only sees
+// commands unless its privacy mode is turned off with BotFather.
+type TelegramConfig struct {
+	Token  string // Bot token from BotFather
+	ChatID string // Where snapshots and milestones are posted, empty to only answer commands
+}
+
+var telegramAPI = "https://api.telegram.org"
+
+// telegramPollTimeout is how long getUpdates waits for new messages
+const telegramPollTimeout = 30 * time.Second
+
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		MessageID int64  `json:"message_id"`
+		Text      string `json:"text"`
+		Chat      struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+	} `json:"message"`
+}
+
+// telegramBot long-polls the Bot API for commands
+type telegramBot struct {
+	config TelegramConfig
+	client *http.Client
+	offset int64 // Next update to fetch, kept across reconnects
+}
+
+func newTelegramBot(config TelegramConfig) *telegramBot {
+	return &telegramBot{config: config, client: &http.Client{Timeout: telegramPollTimeout + requestTimeout}}
+}
+
+func (b *telegramBot) name() string { return "Telegram" }
+
+func (b *telegramBot) listen(ctx context.Context, reply replyFunc) error {
+	for {
+		query := url.Values{
+			"timeout":         {strconv.Itoa(int(telegramPollTimeout.Seconds()))},
+			"offset":          {strconv.FormatInt(b.offset, 10)},
+			"allowed_updates": {`["message"]`},
+		}
+		var updates []telegramUpdate
+		if err := b.call(ctx, http.MethodGet, "getUpdates?"+query.Encode(), nil, &updates); err != nil {
+			return err
+		}
+
+		for _, update := range updates {
+			b.offset = update.UpdateID + 1
+			msg := update.Message
+			if msg == nil {
+				continue
+			}
+			answer := reply(ctx, msg.Text)
+			if answer == "" {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+			err := b.send(ctx, strconv.FormatInt(msg.Chat.ID, 10), answer, msg.MessageID)
+			cancel()
+			if err != nil {
+				log.Printf("Failed to reply on Telegram: %v", err)
+			}
+		}
+	}
+}
+
+func (b *telegramBot) announce(ctx context.Context, text string) error {
+	if b.config.ChatID == "" {
+		return nil
+	}
+	return b.send(ctx, b.config.ChatID, text, 0)
+}
+
+// send posts a message to a chat, as a reply when replyTo is set
+func (b *telegramBot) send(ctx context.Context, chatID, text string, replyTo int64) error {
+	body := map[string]any{"chat_id": chatID, "text": text}
+	if replyTo != 0 {
+		body["reply_parameters"] = map[string]int64{"message_id": replyTo}
+	}
+	return b.call(ctx, http.MethodPost, "sendMessage", body, nil)
+}
+
+// call invokes a Bot API method, decoding its result into out when set
+func (b *telegramBot) call(ctx context.Context, method, path string, body, out any) error {
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, telegramAPI+"/bot"+b.config.Token+"/"+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		// The URL embeds the token, so don't let it reach the logs
+		return fmt.Errorf("telegram request failed: %w", errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("telegram answered %s", resp.Status)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram answered %s: %s", resp.Status, result.Description)
+	}
+	if out != nil {
+		return json.Unmarshal(result.Result, out)
+	}
+	return nil
+}
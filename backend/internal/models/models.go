@@ -1,5 +1,12 @@
 package models
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
 // User represents a user in the leaderboard
 type User struct {
 	Username string `json:"username"`
@@ -8,9 +15,22 @@ type User struct {
 
 // LeaderboardEntry represents an entry in the leaderboard with rank
 type LeaderboardEntry struct {
-	Rank     int    `json:"rank"`
-	Username string `json:"username"`
-	Rating   int    `json:"rating"`
+	Rank       int            `json:"rank"`
+	Username   string         `json:"username"`
+	Rating     int            `json:"rating"`
+	Bot        bool           `json:"bot,omitempty"`
+	Anonymous  bool           `json:"anonymous,omitempty"` // Username is an alias the user asked to be listed under
+	ExpiresAt  *time.Time     `json:"expires_at,omitempty"`
+	AchievedAt *time.Time     `json:"achieved_at,omitempty"` // When the rating was reached, under most_recent_first
+	Meta       map[string]any `json:"meta,omitempty"`        // Set by enrichers (badges, avatars, ...)
+}
+
+// SetMeta attaches an enrichment value to the entry
+func (e *LeaderboardEntry) SetMeta(key string, value any) {
+	if e.Meta == nil {
+		e.Meta = make(map[string]any)
+	}
+	e.Meta[key] = value
 }
 
 // LeaderboardResponse represents the paginated leaderboard response
@@ -22,33 +42,1276 @@ type LeaderboardResponse struct {
 	HasMore    bool               `json:"has_more"`
 }
 
+// Derived board kinds
+const (
+	BoardKindDaily   = "daily"   // Net rating gained per UTC day, as daily:2025-01-01
+	BoardKindCountry = "country" // Ratings per country, as country:US
+	BoardKindTeam    = "team"    // Summed ratings of each team's members, as teams
+)
+
+// DerivedBoard is a board kept alongside the main one from the same score
+// updates
+type DerivedBoard struct {
+	ID      string `json:"id"`
+	Kind    string `json:"kind"`
+	Members int    `json:"members"`
+}
+
+// DerivedBoardList lists the derived boards, by ID
+type DerivedBoardList struct {
+	Boards []DerivedBoard `json:"boards"`
+}
+
+// DerivedBoardEntry is one member of a derived board: a user, or a team on
+// the team board
+type DerivedBoardEntry struct {
+	Rank      int    `json:"rank"`
+	Member    string `json:"member"`
+	Score     int    `json:"score"`
+	Anonymous bool   `json:"anonymous,omitempty"` // Member is an alias the user asked to be listed under
+}
+
+// DerivedBoardResponse is a page of a derived board
+type DerivedBoardResponse struct {
+	DerivedBoard
+	Entries []DerivedBoardEntry `json:"entries"`
+	Page    int                 `json:"page"`
+	Limit   int                 `json:"limit"`
+	HasMore bool                `json:"has_more"`
+}
+
+// Board recompute job states
+const (
+	RecomputeRunning   = "running"
+	RecomputeSucceeded = "succeeded"
+	RecomputeFailed    = "failed" // Stopped by an error; corrections written before it are kept
+)
+
+// Board recompute phases
+const (
+	RecomputeScanning = "scanning" // Reading the source of truth
+	RecomputeWriting  = "writing"  // Correcting the board
+)
+
+// BoardRecomputeJob rebuilds a derived board from the source of truth
+type BoardRecomputeJob struct {
+	ID          string     `json:"id"`
+	Board       string     `json:"board"`
+	Status      string     `json:"status"`
+	Phase       string     `json:"phase"`
+	Scanned     int        `json:"scanned"`     // Users read so far
+	Total       int        `json:"total"`       // Users to read
+	Corrections int        `json:"corrections"` // Members found to add, change or remove, growing as each batch is checked
+	Written     int        `json:"written"`     // Corrections made so far
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// Milestone types
+const (
+	MilestoneNewLeader = "new_leader" // A player took #1
+	MilestoneTopTen    = "top_ten"    // A player entered the top 10
+	MilestoneRecord    = "record"     // A player beat the board's highest rating
+)
+
+// Milestone is a notable moment on the leaderboard, published in the
+// milestones feed
+type Milestone struct {
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`
+	Title        string    `json:"title"`
+	Username     string    `json:"username"`
+	Rating       int       `json:"rating"`
+	Rank         int       `json:"rank"`
+	PreviousRank int       `json:"previous_rank,omitempty"` // 0 for players new to the board
+	At           time.Time `json:"at"`
+}
+
+// Cutoff is the rating it takes to hold a watched rank
+type Cutoff struct {
+	Rank            int        `json:"rank"`
+	Rating          int        `json:"rating"`               // Rating of the user at Rank, 0 while fewer users are on the board
+	Threshold       int        `json:"threshold"`            // Moves larger than this are announced
+	AnnouncedRating int        `json:"announced_rating"`     // Rating last announced, which Rating may drift from by up to Threshold
+	ChangedAt       *time.Time `json:"changed_at,omitempty"` // When a change was last announced
+}
+
+// CutoffChange announces a watched cutoff moving by more than its threshold
+type CutoffChange struct {
+	Rank           int       `json:"rank"`
+	Rating         int       `json:"rating"`
+	PreviousRating int       `json:"previous_rating"` // The rating announced before
+	Delta          int       `json:"delta"`
+	At             time.Time `json:"at"`
+}
+
+// CutoffsResponse lists the watched cutoffs and their recent changes
+type CutoffsResponse struct {
+	Cutoffs []Cutoff       `json:"cutoffs"` // By rank
+	Changes []CutoffChange `json:"changes"` // Newest first
+}
+
+// DailyReport summarises one UTC day on the leaderboard
+type DailyReport struct {
+	Date        string         `json:"date"` // YYYY-MM-DD
+	GeneratedAt time.Time      `json:"generated_at"`
+	Partial     bool           `json:"partial,omitempty"` // The server started during the day, so earlier activity is missing
+	Activity    ReportActivity `json:"activity"`
+	TopGainers  []ReportMover  `json:"top_gainers"`
+	TopLosers   []ReportMover  `json:"top_losers"`
+	Records     []Milestone    `json:"records"`
+}
+
+// ReportActivity counts a day's changes, bots included
+type ReportActivity struct {
+	ScoreUpdates int64            `json:"score_updates"`
+	NewUsers     int64            `json:"new_users"`
+	ActiveUsers  int64            `json:"active_users"` // Users whose score changed
+	BySource     map[string]int64 `json:"by_source"`
+}
+
+// ReportMover is a player's net rating change over a day
+type ReportMover struct {
+	Username string `json:"username"`
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+	Change   int    `json:"change"`
+}
+
+// Import job statuses
+const (
+	ImportRunning   = "running"
+	ImportSucceeded = "succeeded"
+	ImportFailed    = "failed" // Nothing was applied
+)
+
+// ImportJob is one run of the scheduled score file import
+type ImportJob struct {
+	ID         string           `json:"id"`
+	Status     string           `json:"status"`
+	Trigger    string           `json:"trigger"` // schedule or manual
+	ETag       string           `json:"etag,omitempty"`
+	Rows       int              `json:"rows"`
+	Created    int              `json:"created"`
+	Updated    int              `json:"updated"`
+	Unchanged  int              `json:"unchanged"`
+	Skipped    int              `json:"skipped"`
+	RowErrors  []ImportRowError `json:"row_errors,omitempty"` // The first few skipped rows
+	Error      string           `json:"error,omitempty"`      // Why a failed job applied nothing
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+}
+
+// ImportRowError explains why a row of an imported file was skipped
+type ImportRowError struct {
+	Row      int    `json:"row"` // 1-based, not counting a CSV header
+	Username string `json:"username,omitempty"`
+	Error    string `json:"error"`
+}
+
+// ImportStatus describes the score file import and its recent jobs
+type ImportStatus struct {
+	URL             string      `json:"url"`
+	Format          string      `json:"format"`
+	IntervalSeconds float64     `json:"interval_seconds"`
+	ETag            string      `json:"etag,omitempty"`      // Of the last applied file
+	SignedAt        *time.Time  `json:"signed_at,omitempty"` // Of the last applied file
+	LastCheckedAt   *time.Time  `json:"last_checked_at,omitempty"`
+	Jobs            []ImportJob `json:"jobs"` // Newest first
+}
+
+// OEmbedResponse describes the embeddable leaderboard widget in oEmbed's
+// "rich" form, for sites that discover embeds automatically
+type OEmbedResponse struct {
+	Version      string `json:"version"` // Always "1.0"
+	Type         string `json:"type"`    // Always "rich"
+	Title        string `json:"title"`
+	ProviderName string `json:"provider_name"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	CacheAge     int    `json:"cache_age"` // Seconds
+}
+
+// UserListResponse is a page of users in username order. Pass NextCursor as
+// cursor to fetch the following page.
+type UserListResponse struct {
+	Entries    []LeaderboardEntry `json:"entries"`
+	Sort       string             `json:"sort"`
+	Limit      int                `json:"limit"`
+	TotalUsers int64              `json:"total_users"`
+	HasMore    bool               `json:"has_more"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// SearchResponse is a page of users matching a search, in rank order. Pass
+// NextCursor as cursor to fetch the following page.
+type SearchResponse struct {
+	Results    []UserRankResponse `json:"results"`
+	Total      int                `json:"total"`          // Matches across all pages
+	Page       int                `json:"page,omitempty"` // When paging by page number
+	Limit      int                `json:"limit"`
+	HasMore    bool               `json:"has_more"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
 // UserRankResponse represents a user's rank information
 type UserRankResponse struct {
-	Username string `json:"username"`
-	Rating   int    `json:"rating"`
-	Rank     int64  `json:"rank"`
+	Username    string     `json:"username"`
+	Rating      int        `json:"rating"`
+	Rank        int64      `json:"rank"`
+	Bot         bool       `json:"bot,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	AchievedAt  *time.Time `json:"achieved_at,omitempty"` // When the rating was reached, under most_recent_first
+	At          *time.Time `json:"at,omitempty"`          // Set when reconstructed for a past moment
+	Approximate bool       `json:"approximate,omitempty"` // History was downsampled around At, so the rank may be off
 }
 
-// UpdateScoreRequest represents a request to update user score
+// UpdateScoreRequest represents a request to update user score.
+// Which fields are required depends on the leaderboard's rating strategy.
 type UpdateScoreRequest struct {
-	Rating int `json:"rating" binding:"required,min=100,max=5000"`
+	Rating         int    `json:"rating,omitempty" binding:"omitempty,min=100,max=5000"`
+	Delta          int    `json:"delta,omitempty" binding:"omitempty,min=-4900,max=4900"`
+	OpponentRating int    `json:"opponent_rating,omitempty" binding:"omitempty,min=100,max=5000"`
+	Result         string `json:"result,omitempty" binding:"omitempty,oneof=win loss draw"`
+	MatchID        string `json:"match_id,omitempty" binding:"omitempty,max=128"`
+	TTLSeconds     int    `json:"ttl_seconds,omitempty" binding:"omitempty,min=1,max=31536000"`                     // Drop the entry after this long
+	Reason         string `json:"reason,omitempty" binding:"omitempty,oneof=match admin_adjustment decay rollback"` // Defaults to match
+	Country        string `json:"country,omitempty" binding:"omitempty,iso3166_1_alpha2"`                           // Moves the user to this country's board
+	Team           string `json:"team,omitempty" binding:"omitempty,max=64"`                                        // Counts the user's rating toward this team
+}
+
+// GuestScoreRequest is a score submission by a player who hasn't
+// registered. Guests only play matches: their entries can't be given a TTL
+// or placed on a country or team board until they register.
+type GuestScoreRequest struct {
+	Rating         int    `json:"rating,omitempty" binding:"omitempty,min=100,max=5000"`
+	Delta          int    `json:"delta,omitempty" binding:"omitempty,min=-4900,max=4900"`
+	OpponentRating int    `json:"opponent_rating,omitempty" binding:"omitempty,min=100,max=5000"`
+	Result         string `json:"result,omitempty" binding:"omitempty,oneof=win loss draw"`
+	MatchID        string `json:"match_id,omitempty" binding:"omitempty,max=128"`
+}
+
+// Async score submission states
+const (
+	SubmissionQueued  = "queued"
+	SubmissionApplied = "applied"
+	SubmissionFailed  = "failed"
+)
+
+// Submission is a score update queued with ?async=true
+type Submission struct {
+	ID          string               `json:"id"`
+	Username    string               `json:"username"`
+	Status      string               `json:"status"`
+	QueuedAt    time.Time            `json:"queued_at"`
+	CompletedAt *time.Time           `json:"completed_at,omitempty"`
+	Result      *UpdateScoreResponse `json:"result,omitempty"` // Set once applied
+	Error       *ErrorResponse       `json:"error,omitempty"`  // Set if the update was rejected
+}
+
+// ScoreQueueStats reports the async score queue's depth and outcomes
+type ScoreQueueStats struct {
+	Backend      string `json:"backend"` // memory or redis
+	Workers      int    `json:"workers"` // On this replica
+	Capacity     int    `json:"capacity"`
+	Depth        int    `json:"depth"`
+	Pending      int64  `json:"pending,omitempty"` // Read by a redis worker but not yet acknowledged
+	Applied      int64  `json:"applied"`
+	Failed       int64  `json:"failed"`
+	Rejected     int64  `json:"rejected"`                // Turned away because the queue was full
+	Recovered    int64  `json:"recovered,omitempty"`     // Reclaimed from stalled replicas
+	DeadLettered int64  `json:"dead_lettered,omitempty"` // Moved to the dead-letter stream by this replica
+	DeadLetters  int64  `json:"dead_letters,omitempty"`  // In the dead-letter stream across replicas
+	Draining     bool   `json:"draining"`
+
+	Ledger       *SubmissionLedgerStats `json:"ledger"`
+	SlowCommands map[string]int64       `json:"slow_commands,omitempty"` // Redis commands over the slow threshold, by command and key pattern
+	WriteBuffer  *WriteBufferStats      `json:"write_buffer,omitempty"`
+	RedisError   string                 `json:"redis_error,omitempty"` // Set when Redis couldn't be reached for the stream counters
+}
+
+// WriteBufferStats reports submissions held in memory while Redis was
+// unreachable on this replica
+type WriteBufferStats struct {
+	Capacity int    `json:"capacity"`
+	Policy   string `json:"policy"`
+	Depth    int    `json:"depth"`    // Waiting for Redis now
+	Buffered int64  `json:"buffered"` // Since startup
+	Flushed  int64  `json:"flushed"`
+	Dropped  int64  `json:"dropped"` // Dropped to make room under the drop_oldest policy
+}
+
+// SubmissionLedgerStats counts how the submission ledger treated queued
+// submissions on this replica
+type SubmissionLedgerStats struct {
+	Claimed    int64 `json:"claimed"`    // First attempts, applied by this replica
+	Duplicates int64 `json:"duplicates"` // Redeliveries of applied submissions, answered from the ledger
+	Deferred   int64 `json:"deferred"`   // Left queued while another worker held the claim
+	Released   int64 `json:"released"`   // Claims dropped after the update failed
+	Errors     int64 `json:"errors"`     // Ledger calls that failed
+	Unrecorded int64 `json:"unrecorded"` // Applied but never recorded, so a redelivery may apply them again
+}
+
+// ScoreAdjustmentRequest overrides a user's rating as a moderator
+type ScoreAdjustmentRequest struct {
+	Rating int    `json:"rating" binding:"required,min=100,max=5000"`
+	Reason string `json:"reason" binding:"required,min=3,max=500"`
+}
+
+// Adjustment statuses
+const (
+	AdjustmentApplied         = "applied"
+	AdjustmentPendingApproval = "pending_approval" // Staged until a second staff member approves
+)
+
+// ScoreAdjustmentResponse reports a moderator's rating override
+type ScoreAdjustmentResponse struct {
+	Username        string `json:"username"`
+	Rating          int    `json:"rating"`
+	PreviousRating  int    `json:"previous_rating"`
+	Reason          string `json:"reason"`
+	Actor           string `json:"actor"`
+	Status          string `json:"status"`
+	PendingChangeID string `json:"pending_change_id,omitempty"`
+	ApprovedBy      string `json:"approved_by,omitempty"`
+}
+
+// PendingChange is an adjustment awaiting a second approver
+type PendingChange struct {
+	ID                  string    `json:"id"`
+	Username            string    `json:"username"`
+	Rating              int       `json:"rating"`
+	PreviousRating      int       `json:"previous_rating"` // When the change was requested
+	Reason              string    `json:"reason"`
+	RequestedBy         string    `json:"requested_by"`
+	RequesterIdentities []string  `json:"requester_identities,omitempty"` // Linked to the requester when staged
+	RequestedAt         time.Time `json:"requested_at"`
+}
+
+// FreezeRequest temporarily stops score updates for a user
+type FreezeRequest struct {
+	DurationSeconds int    `json:"duration_seconds" binding:"required,min=60,max=2592000"`
+	Reason          string `json:"reason" binding:"required,min=3,max=500"`
+}
+
+// UserFreeze is a moderator's temporary freeze on a user's score updates
+type UserFreeze struct {
+	Until    time.Time `json:"until"`
+	Reason   string    `json:"reason"`
+	Actor    string    `json:"actor"`
+	FrozenAt time.Time `json:"frozen_at"`
+}
+
+// StaffNoteRequest annotates a user for other staff
+type StaffNoteRequest struct {
+	Note string `json:"note" binding:"required,max=2000"`
+}
+
+// StaffNote is a note on a user visible only to staff
+type StaffNote struct {
+	Note      string    `json:"note"`
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ModerationRecord is everything staff know about a user
+type ModerationRecord struct {
+	Username string      `json:"username"`
+	Freeze   *UserFreeze `json:"freeze"` // Active freeze, if any
+	Notes    []StaffNote `json:"notes"`  // Oldest first
+}
+
+// CaptureRequest turns on request capture for one route
+type CaptureRequest struct {
+	Route           string  `json:"route" binding:"required"`                       // Method and path pattern, e.g. "POST /api/users/{username}/score"
+	SamplePercent   float64 `json:"sample_percent" binding:"required,gt=0,max=100"` // Share of requests to record
+	DurationSeconds int     `json:"duration_seconds,omitempty" binding:"omitempty,min=1,max=86400"`
+}
+
+// CaptureConfig is the active capture setting; capturing stops at ExpiresAt
+type CaptureConfig struct {
+	Route         string    `json:"route"`
+	SamplePercent float64   `json:"sample_percent"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// Capture is a recorded request and response with secrets redacted
+type Capture struct {
+	ID              int64             `json:"id"`
+	Route           string            `json:"route"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	Status          int               `json:"status"`
+	DurationMs      float64           `json:"duration_ms"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	RequestBody     string            `json:"request_body,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers"`
+	ResponseBody    string            `json:"response_body,omitempty"`
+	Truncated       bool              `json:"truncated,omitempty"` // A body exceeded 64KB
+	At              time.Time         `json:"at"`
+}
+
+// ExternalIdentity links an identity on another platform to a leaderboard user
+type ExternalIdentity struct {
+	Provider   string    `json:"provider"`
+	ExternalID string    `json:"external_id"`
+	Username   string    `json:"username"`
+	LinkedAt   time.Time `json:"linked_at"`
+}
+
+// LinkIdentityRequest links an external identity to a user
+type LinkIdentityRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+// PrivacyRequest sets what a user shares with everyone else
+type PrivacyRequest struct {
+	HideFromLeaderboard bool `json:"hide_from_leaderboard"`
+	HideFromSearch      bool `json:"hide_from_search"`
+	Anonymize           bool `json:"anonymize"`
+}
+
+// PrivacySettings are a user's privacy choices. Hidden and anonymized users
+// are still counted in totals and ranks.
+type PrivacySettings struct {
+	HideFromLeaderboard bool   `json:"hide_from_leaderboard"` // Left out of leaderboard pages, exports, feeds and live updates
+	HideFromSearch      bool   `json:"hide_from_search"`      // Left out of search, and lookups by name answer 404
+	Anonymize           bool   `json:"anonymize"`             // Listed under Alias, and treated as hidden from search
+	Alias               string `json:"alias,omitempty"`       // Assigned the first time the user anonymizes
+}
+
+// GuestScoreResponse is the result of a guest's score submission
+type GuestScoreResponse struct {
+	Username string `json:"username"`          // Generated name the guest is listed under
+	Created  bool   `json:"created,omitempty"` // Whether this submission created the guest
+	UpdateScoreResponse
+}
+
+// ClaimGuestRequest names the device whose guest a registered user claims
+type ClaimGuestRequest struct {
+	DeviceToken string `json:"device_token" binding:"required,min=16,max=128"`
+}
+
+// ClaimGuestResponse reports a guest merged into a registered user
+type ClaimGuestResponse struct {
+	Username     string `json:"username"`
+	Guest        string `json:"guest"`         // Removed once merged
+	Rating       int    `json:"rating"`        // Username's rating after the merge
+	GuestRating  int    `json:"guest_rating"`  // The guest's rating before the merge
+	Kept         string `json:"kept"`          // Whose score was kept, see MergeKept*
+	HistoryMoved int    `json:"history_moved"` // Score changes moved to Username's history
+}
+
+// MergeUsersRequest folds a duplicate account into the one that stays
+type MergeUsersRequest struct {
+	From   string `json:"from" binding:"required"`                      // Removed once merged
+	Into   string `json:"into" binding:"required,nefield=From"`         // Kept
+	Policy string `json:"policy" binding:"omitempty,oneof=best latest"` // Whose score is kept, defaults to best
+	Reason string `json:"reason" binding:"required,min=3,max=500"`      // Recorded in the audit trail
+}
+
+// MergeUsersResponse reports one user merged into another
+type MergeUsersResponse struct {
+	From            string   `json:"from"`
+	Into            string   `json:"into"`
+	Policy          string   `json:"policy"`
+	Rating          int      `json:"rating"`          // Into's rating after the merge
+	PreviousRating  int      `json:"previous_rating"` // Into's rating before it
+	FromRating      int      `json:"from_rating"`
+	Kept            string   `json:"kept"` // Whose score was kept, see MergeKept*
+	HistoryMoved    int      `json:"history_moved"`
+	MatchesMoved    int      `json:"matches_moved"`
+	MilestonesMoved int      `json:"milestones_moved"`
+	IdentitiesMoved int      `json:"identities_moved"`
+	RolesRevoked    []string `json:"roles_revoked"`    // Roles granted to From, ended as From is gone
+	SessionsRevoked int      `json:"sessions_revoked"` // From's logins, ended likewise
+	Actor           string   `json:"actor"`
+	Reason          string   `json:"reason"`
+}
+
+// Merge policies: whose score the user kept ends up with
+const (
+	MergePolicyBest   = "best"   // The higher rating, or the kept user's own on a tie
+	MergePolicyLatest = "latest" // The rating set most recently
+)
+
+// Whose score a merge kept
+const (
+	MergeKeptUser  = "user"
+	MergeKeptGuest = "guest"
+	MergeKeptFrom  = "from"
+	MergeKeptInto  = "into"
+)
+
+// LoginResponse carries the tokens issued after a login or refresh
+type LoginResponse struct {
+	AccessToken      string    `json:"access_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	Username         string    `json:"username"`
+	Created          bool      `json:"created,omitempty"` // The user was created by this login
+}
+
+// RefreshRequest presents a refresh token to renew or end a session
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required,max=256"`
+}
+
+// IntegrationScoreRequest is a signed score submission from a third-party
+// platform. The player is identified by their ID on that platform.
+type IntegrationScoreRequest struct {
+	ExternalID string `json:"external_id" binding:"required,max=256"`
+	UpdateScoreRequest
+}
+
+// Reasons a score can change, recorded in the score history
+const (
+	ReasonMatch           = "match"
+	ReasonAdminAdjustment = "admin_adjustment"
+	ReasonDecay           = "decay"
+	ReasonRollback        = "rollback"
+	ReasonImport          = "import"
+	ReasonMerge           = "merge" // Another user's better score, kept when they were merged in
+)
+
+// HistoryEntry is a recorded score change, or a run of changes with the same
+// reason and source downsampled into one entry. A downsampled entry goes from
+// PreviousRating before its first change, at Since, to Rating after its last
+// change, at Timestamp.
+type HistoryEntry struct {
+	Rating         int        `json:"rating"`
+	PreviousRating int        `json:"previous_rating"`
+	Reason         string     `json:"reason,omitempty"`
+	Source         string     `json:"source,omitempty"` // What made the change, e.g. api or simulator
+	Timestamp      time.Time  `json:"timestamp"`
+	Resolution     string     `json:"resolution,omitempty"`  // Set once downsampled, e.g. hour
+	Changes        int        `json:"changes,omitempty"`     // Changes merged, when more than one
+	Since          *time.Time `json:"since,omitempty"`       // First merged change, when more than one
+	OccurredAt     *time.Time `json:"occurred_at,omitempty"` // When an offline client made the change, if synced later
+	SyncedBy       string     `json:"synced_by,omitempty"`   // The offline client that synced it
+}
+
+// History resolutions, finest first. Entries are downsampled to a coarser
+// resolution as they age.
+const (
+	ResolutionMinute = "minute"
+	ResolutionHour   = "hour"
+	ResolutionDay    = "day"
+)
+
+// HistoryResponse lists a user's recent score changes, newest first
+type HistoryResponse struct {
+	Username   string         `json:"username"`
+	Resolution string         `json:"resolution,omitempty"` // The requested resolution, if any
+	Entries    []HistoryEntry `json:"entries"`
+	Count      int            `json:"count"`
+}
+
+// UpdateScoreResponse represents the result of a score submission
+type UpdateScoreResponse struct {
+	Message   string     `json:"message"`
+	Rating    int        `json:"rating"`
+	MatchID   string     `json:"match_id,omitempty"`
+	Duplicate bool       `json:"duplicate,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// OfflineScoreEvent is a score update a client recorded while offline. Its
+// fields are those of a score submission under the board's rating strategy.
+type OfflineScoreEvent struct {
+	EventID    string    `json:"event_id" binding:"required,max=128"` // Unique per client, so a retried sync applies it once
+	OccurredAt time.Time `json:"occurred_at" binding:"required"`
+	UpdateScoreRequest
+}
+
+// OfflineSyncRequest uploads the score events a client journaled offline
+type OfflineSyncRequest struct {
+	ClientID string              `json:"client_id" binding:"max=128"` // The device syncing; its own earlier syncs aren't server changes
+	Events   []OfflineScoreEvent `json:"events" binding:"required,min=1,max=500,dive"`
+}
+
+// How an offline score event was reconciled with the board
+const (
+	OfflineApplied    = "applied"    // Applied; the server hadn't changed the rating since it happened
+	OfflineRebased    = "rebased"    // Applied on top of server changes made since it happened
+	OfflineSuperseded = "superseded" // Not applied: a later server change or a better score wins
+	OfflineDuplicate  = "duplicate"  // Already applied by an earlier sync or submission
+	OfflineRejected   = "rejected"   // Invalid, or the user's scores can't be changed now
+)
+
+// OfflineEventResult reports how one offline event was reconciled
+type OfflineEventResult struct {
+	EventID            string         `json:"event_id"`
+	OccurredAt         time.Time      `json:"occurred_at"`
+	Status             string         `json:"status"` // applied, rebased, superseded, duplicate or rejected
+	RatingBefore       int            `json:"rating_before"`
+	RatingAfter        int            `json:"rating_after"`
+	ServerChanges      int            `json:"server_changes,omitempty"`       // Server-side changes made after the event happened
+	LatestServerChange *HistoryEntry  `json:"latest_server_change,omitempty"` // The newest of them
+	Error              *ErrorResponse `json:"error,omitempty"`                // Why the event was rejected
+}
+
+// OfflineSyncResponse is the reconciliation report of an offline sync, with
+// events in the order they were applied: by when they happened
+type OfflineSyncResponse struct {
+	Username       string               `json:"username"`
+	RatingStrategy string               `json:"rating_strategy"` // The update policy events were reconciled under
+	Rating         int                  `json:"rating"`          // After the sync
+	Applied        int                  `json:"applied"`         // Applied or rebased
+	Conflicts      int                  `json:"conflicts"`       // Events the server had changed the rating since
+	Events         []OfflineEventResult `json:"events"`
 }
 
 // SeedRequest represents a request to seed data
 type SeedRequest struct {
-	Count int `json:"count" binding:"required,min=1"`
+	Count int  `json:"count" binding:"required,min=1"`
+	Wait  bool `json:"wait"` // Respond once the seed finishes rather than when it starts
+}
+
+// Seed job statuses
+const (
+	SeedRunning   = "running"
+	SeedSucceeded = "succeeded"
+	SeedFailed    = "failed"    // Stopped by an error; users written before it are kept
+	SeedCancelled = "cancelled" // Users written before cancellation are kept
+)
+
+// SeedJob is a background seed of generated bot users
+type SeedJob struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	Requested  int        `json:"requested"`
+	Generated  int        `json:"generated"`
+	Written    int        `json:"written"`
+	Skipped    int        `json:"skipped"` // Invalid, or below a capped board's cutoff
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
 }
 
 // StatsResponse represents system statistics
 type StatsResponse struct {
 	TotalUsers    int64   `json:"total_users"`
+	BotUsers      int64   `json:"bot_users"`
 	MinRating     float64 `json:"min_rating"`
 	MaxRating     float64 `json:"max_rating"`
 	AverageRating float64 `json:"average_rating"`
+	MedianRating  float64 `json:"median_rating"`
+	ModeRating    int     `json:"mode_rating"` // Most common rating, the lowest on ties
+	StdDevRating  float64 `json:"stddev_rating"`
+	ExcludesBots  bool    `json:"excludes_bots,omitempty"`
+	Capacity      int64   `json:"capacity,omitempty"`  // Member cap, when configured
+	Occupancy     float64 `json:"occupancy,omitempty"` // Fraction of the cap in use
+
+	UpdatesBySource map[string]int64 `json:"updates_by_source"` // Score updates since startup, by source
+	ComputedAt      time.Time        `json:"computed_at"`       // Stats may be served from cache until a TTL passes
+}
+
+// CountResponse is the number of users rated within a range
+type CountResponse struct {
+	MinRating    int   `json:"min_rating"`
+	MaxRating    int   `json:"max_rating"`
+	Count        int64 `json:"count"`
+	TotalUsers   int64 `json:"total_users"`
+	ExcludesBots bool  `json:"excludes_bots,omitempty"`
+}
+
+// SegmentStats are the members of one country or tier and their average rating
+type SegmentStats struct {
+	Users         int64   `json:"users"`
+	AverageRating float64 `json:"average_rating"` // 0 without users
+}
+
+// CountryStats are one country's members
+type CountryStats struct {
+	Country string `json:"country"` // ISO 3166-1 alpha-2 code
+	SegmentStats
+}
+
+// CountryStatsResponse breaks the board down by the country users last
+// submitted a score with
+type CountryStatsResponse struct {
+	Countries    []CountryStats `json:"countries"` // Most users first
+	Unplaced     SegmentStats   `json:"unplaced"`  // Users who never gave a country
+	TotalUsers   int64          `json:"total_users"`
+	ExcludesBots bool           `json:"excludes_bots,omitempty"`
+}
+
+// TierStats are the members of one tier
+type TierStats struct {
+	Tier     string `json:"tier"`
+	MinScore int    `json:"min_score"`
+	SegmentStats
+}
+
+// TierStatsResponse breaks the board down by tier
+type TierStatsResponse struct {
+	Tiers        []TierStats  `json:"tiers"`    // In the order of the tier boundaries, lowest first
+	Unplaced     SegmentStats `json:"unplaced"` // Users rated below the lowest tier
+	TotalUsers   int64        `json:"total_users"`
+	ExcludesBots bool         `json:"excludes_bots,omitempty"`
+}
+
+// InflationSample is the board's central ratings at one moment
+type InflationSample struct {
+	At            time.Time `json:"at"`
+	Users         int64     `json:"users"`
+	AverageRating float64   `json:"average_rating"`
+	MedianRating  float64   `json:"median_rating"`
+	Index         float64   `json:"index"` // Average rating relative to the oldest sample, which is 100
+}
+
+// InflationReport tracks how the board's ratings drift over time
+type InflationReport struct {
+	IntervalSeconds     float64           `json:"interval_seconds"` // 0 when the monitor isn't running
+	Index               float64           `json:"index"`            // Latest sample's index, 0 without samples
+	AverageChangePerDay float64           `json:"average_change_per_day"`
+	Samples             []InflationSample `json:"samples"` // Oldest first
+}
+
+// BoardMetadata describes a leaderboard's configuration
+type BoardMetadata struct {
+	ID             string         `json:"id"`
+	SortDirection  string         `json:"sort_direction"` // desc: higher scores rank first
+	MinScore       int            `json:"min_score"`
+	MaxScore       int            `json:"max_score"`
+	TiePolicy      string         `json:"tie_policy"` // shared_rank: equal scores share a rank, the next rank is skipped; most_recent_first: the latest to reach a score ranks first
+	TierBoundaries []TierBoundary `json:"tier_boundaries"`
+	Season         *SeasonInfo    `json:"season"`
+	RatingStrategy string         `json:"rating_strategy"`
+	Capacity       int            `json:"capacity,omitempty"`
+	MemberCount    int            `json:"member_count"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// Capabilities describes which optional features a deployment serves
+type Capabilities struct {
+	BoardID        string             `json:"board_id"`
+	StoreBackend   string             `json:"store_backend"`
+	RatingStrategy string             `json:"rating_strategy"`
+	AsyncBackend   string             `json:"async_backend,omitempty"` // memory or redis, when async writes are on
+	Features       CapabilityFeatures `json:"features"`
+}
+
+// CapabilityFeatures flags optional features a frontend may show or hide
+type CapabilityFeatures struct {
+	WebSockets    bool `json:"websockets"`     // Live updates at /api/ws
+	History       bool `json:"history"`        // Score history at /api/users/{username}/history
+	Seasons       bool `json:"seasons"`        // Season standings and results
+	Tiers         bool `json:"tiers"`          // Tier boundaries in board metadata
+	MatchEngine   bool `json:"match_engine"`   // Scores are match results against an opponent rating
+	AsyncWrites   bool `json:"async_writes"`   // ?async=true score submissions
+	Login         bool `json:"login"`          // Social login and roles
+	Reports       bool `json:"reports"`        // Daily reports at /api/reports/{date}
+	Imports       bool `json:"imports"`        // A partner score file is imported on a schedule
+	SignedScores  bool `json:"signed_scores"`  // Platform integrations post signed scores
+	RateLimits    bool `json:"rate_limits"`    // Callers are rate limited; see /api/limits
+	OfflineSync   bool `json:"offline_sync"`   // Offline score journals at /api/users/{username}/score/sync
+	DerivedBoards bool `json:"derived_boards"` // Daily, country and team boards at /api/boards
+	Guests        bool `json:"guests"`         // Guest scores at /api/guests/score, claimed at /api/users/claim
+	Cutoffs       bool `json:"cutoffs"`        // Watched rank cutoffs at /api/leaderboards/{id}/cutoffs
+}
+
+// RateLimitBucket is a caller's standing in one rate limit bucket
+type RateLimitBucket struct {
+	Name          string    `json:"name"` // reads or writes
+	Limit         int       `json:"limit"`
+	Remaining     int       `json:"remaining"`
+	ResetAt       time.Time `json:"reset_at"` // When the current window ends and the bucket refills
+	ResetSeconds  int       `json:"reset_seconds"`
+	WindowSeconds int       `json:"window_seconds"`
+}
+
+// LimitsResponse lists the caller's rate limit buckets and the query cost budget
+type LimitsResponse struct {
+	Caller          string            `json:"caller"` // The principal, or ip:<address> for anonymous callers
+	Enabled         bool              `json:"enabled"`
+	Buckets         []RateLimitBucket `json:"buckets"`
+	QueryCostBudget int               `json:"query_cost_budget,omitempty"` // Board entries one read may walk, left out when unpriced
+}
+
+// TierBoundary is the lowest score that places a user in a tier
+type TierBoundary struct {
+	Name     string `json:"name" yaml:"name"`
+	MinScore int    `json:"min_score" yaml:"min_score"`
+}
+
+// SeasonInfo describes the current season of a board
+type SeasonInfo struct {
+	ID       string     `json:"id"`
+	StartsAt time.Time  `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+}
+
+// SeasonRequest starts a season
+type SeasonRequest struct {
+	ID     string     `json:"id" binding:"required,max=64"`
+	EndsAt *time.Time `json:"ends_at,omitempty"` // Close automatically at this time
+}
+
+// ConfirmationRequired is returned by a destructive action called without a
+// confirmation token. Repeating the call with ?confirm=<token> carries it out.
+type ConfirmationRequired struct {
+	Operation         string    `json:"operation"`
+	Scope             string    `json:"scope"`
+	ConfirmationToken string    `json:"confirmation_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// BoardConfig is a document of leaderboard definitions exported and
+// applied through the admin API, as JSON or YAML
+type BoardConfig struct {
+	Boards []BoardDefinition `json:"boards" yaml:"boards"`
+}
+
+// BoardDefinition is the declarative configuration of one board. Empty
+// fields take the board's defaults.
+type BoardDefinition struct {
+	ID             string                `json:"id" yaml:"id"`
+	MinScore       int                   `json:"min_score,omitempty" yaml:"min_score,omitempty"`
+	MaxScore       int                   `json:"max_score,omitempty" yaml:"max_score,omitempty"`
+	TiePolicy      string                `json:"tie_policy,omitempty" yaml:"tie_policy,omitempty"`
+	TierBoundaries []TierBoundary        `json:"tier_boundaries,omitempty" yaml:"tier_boundaries,omitempty"`
+	RatingStrategy string                `json:"rating_strategy,omitempty" yaml:"rating_strategy,omitempty"`
+	Capacity       int                   `json:"capacity,omitempty" yaml:"capacity,omitempty"` // 0 for no cap
+	Season         *SeasonDefinition     `json:"season,omitempty" yaml:"season,omitempty"`
+	PrizeBands     []PrizeBandDefinition `json:"prize_bands,omitempty" yaml:"prize_bands,omitempty"`
+}
+
+// SeasonDefinition is the season a board should be running
+type SeasonDefinition struct {
+	ID     string     `json:"id" yaml:"id"`
+	EndsAt *time.Time `json:"ends_at,omitempty" yaml:"ends_at,omitempty"`
+}
+
+// PrizeBandDefinition is a prize band: a rank range, or the top percentage of the board
+type PrizeBandDefinition struct {
+	Name       string  `json:"name" yaml:"name"`
+	MinRank    int     `json:"min_rank,omitempty" yaml:"min_rank,omitempty"`
+	MaxRank    int     `json:"max_rank,omitempty" yaml:"max_rank,omitempty"`
+	TopPercent float64 `json:"top_percent,omitempty" yaml:"top_percent,omitempty"`
+}
+
+// BoardConfigResult lists what applying a board configuration changed, or
+// would change on a dry run
+type BoardConfigResult struct {
+	DryRun  bool     `json:"dry_run"`
+	Changes []string `json:"changes"`
+}
+
+// SeasonResult holds a closed season's final standings
+type SeasonResult struct {
+	BoardID    string             `json:"board_id"`
+	Season     SeasonInfo         `json:"season"`
+	ClosedAt   time.Time          `json:"closed_at"`
+	TotalUsers int                `json:"total_users"`
+	Standings  []LeaderboardEntry `json:"standings,omitempty"`
+}
+
+// PrizeBandResult lists the users qualifying for one prize band
+type PrizeBandResult struct {
+	Name       string             `json:"name"`
+	MinRank    int                `json:"min_rank"`
+	MaxRank    int                `json:"max_rank"`              // For percentile bands, the cutoff rank
+	TopPercent float64            `json:"top_percent,omitempty"` // Set for percentile bands
+	Winners    []LeaderboardEntry `json:"winners"`
+	Count      int                `json:"count"`
+}
+
+// PrizesResponse resolves the configured prize bands against final standings
+type PrizesResponse struct {
+	BoardID    string            `json:"board_id"`
+	Season     *SeasonInfo       `json:"season"`
+	FrozenAt   time.Time         `json:"frozen_at"`
+	Preview    bool              `json:"preview,omitempty"` // Computed from live standings, not a closed season
+	TiePolicy  string            `json:"tie_policy"`
+	TotalUsers int               `json:"total_users"`
+	Bands      []PrizeBandResult `json:"bands"`
+}
+
+// Webhook delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery tracks posting a season result to one webhook URL
+type WebhookDelivery struct {
+	ID         string    `json:"id"` // Sent as X-Delivery-ID on every attempt
+	URL        string    `json:"url"`
+	Season     string    `json:"season"`
+	Status     string    `json:"status"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"status_code,omitempty"` // Of the last attempt
+	LastError  string    `json:"last_error,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Dead letter kinds
+const (
+	DeadLetterWebhook = "webhook" // A season webhook delivery that ran out of attempts
+	DeadLetterScore   = "score"   // An async submission moved to the Redis dead-letter stream
+)
+
+// DeadLetter is a webhook delivery or queued submission that was given up on.
+// Payload and Errors are only included when one dead letter is fetched.
+type DeadLetter struct {
+	ID       string            `json:"id"` // <kind>:<delivery or stream entry ID>
+	Kind     string            `json:"kind"`
+	Target   string            `json:"target"` // Webhook URL or submission ID
+	Reason   string            `json:"reason"` // The last error
+	Attempts int               `json:"attempts,omitempty"`
+	FailedAt time.Time         `json:"failed_at"`
+	Payload  json.RawMessage   `json:"payload,omitempty"`
+	Errors   []DeliveryAttempt `json:"errors,omitempty"` // Oldest first
+}
+
+// DeliveryAttempt is one failed attempt in a dead letter's error history
+type DeliveryAttempt struct {
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error"`
+	At         time.Time `json:"at"`
+}
+
+// DeadLetterActionRequest selects dead letters to retry or discard, by ID
+// or by kind ("all" for every kind)
+type DeadLetterActionRequest struct {
+	IDs  []string `json:"ids,omitempty" binding:"required_without=Kind,max=1000"`
+	Kind string   `json:"kind,omitempty" binding:"omitempty,oneof=webhook score all"`
+}
+
+// DeadLetterActionResponse reports a bulk retry or discard
+type DeadLetterActionResponse struct {
+	Succeeded int      `json:"succeeded"`
+	NotFound  []string `json:"not_found"`
+}
+
+// SimulationStatusResponse represents the state of the random update simulator
+type SimulationStatusResponse struct {
+	Running         bool       `json:"running"`
+	Enabled         bool       `json:"enabled"`
+	Target          string     `json:"target"`
+	Paused          bool       `json:"paused"`
+	PausedBy        []string   `json:"paused_by"`
+	IntervalSeconds float64    `json:"interval_seconds"`
+	BatchSize       int        `json:"batch_size"`
+	Jitter          float64    `json:"jitter"`
+	UpdatesApplied  int64      `json:"updates_applied"`
+	BatchesApplied  int64      `json:"batches_applied"`
+	LastBatch       int        `json:"last_batch"` // Members the last cycle updated
+	LastUpdateAt    *time.Time `json:"last_update_at,omitempty"`
+}
+
+// SimulationConfigRequest changes the random update simulator at runtime.
+// Omitted fields keep their current value.
+type SimulationConfigRequest struct {
+	Enabled         *bool    `json:"enabled"`
+	IntervalSeconds *float64 `json:"interval_seconds" binding:"omitempty,min=0.1,max=3600"`
+	Target          *string  `json:"target" binding:"omitempty,oneof=uniform top humans bots"`
+	BatchSize       *int     `json:"batch_size" binding:"omitempty,min=1,max=1000"`
+	Jitter          *float64 `json:"jitter" binding:"omitempty,min=0,max=0.5"`
+}
+
+// ShadowComparisonEntry compares a user's live and shadow standing
+type ShadowComparisonEntry struct {
+	Username     string `json:"username"`
+	LiveRating   int    `json:"live_rating"`
+	LiveRank     int    `json:"live_rank"`
+	ShadowRating int    `json:"shadow_rating"`
+	ShadowRank   int    `json:"shadow_rank"`
+	RankDelta    int    `json:"rank_delta"`
+}
+
+// ShadowComparisonResponse summarises rank divergence between live and shadow boards
+type ShadowComparisonResponse struct {
+	Strategy      string                  `json:"strategy"`
+	UsersCompared int64                   `json:"users_compared"`
+	UsersDiverged int64                   `json:"users_diverged"`
+	MeanRankShift float64                 `json:"mean_rank_shift"`
+	MaxRankShift  int                     `json:"max_rank_shift"`
+	Entries       []ShadowComparisonEntry `json:"entries"`
+}
+
+// Divergence is a sampled disagreement between the live and migration target stores
+type Divergence struct {
+	Username     string    `json:"username"`
+	Kind         string    `json:"kind"` // missing, score or rank
+	LiveRating   int       `json:"live_rating,omitempty"`
+	TargetRating int       `json:"target_rating,omitempty"`
+	LiveRank     int       `json:"live_rank,omitempty"`
+	TargetRank   int       `json:"target_rank,omitempty"`
+	At           time.Time `json:"at"`
+}
+
+// DoubleWriteReport summarises double-write verification during a store migration
+type DoubleWriteReport struct {
+	StartedAt      time.Time        `json:"started_at"`
+	SampleRate     float64          `json:"sample_rate"`
+	MirroredWrites int64            `json:"mirrored_writes"`
+	MirrorErrors   int64            `json:"mirror_errors"`
+	Compared       int64            `json:"compared"`
+	Mismatches     map[string]int64 `json:"mismatches"` // By divergence kind
+	MismatchRate   float64          `json:"mismatch_rate"`
+	LiveUsers      int              `json:"live_users"`
+	TargetUsers    int              `json:"target_users"`
+	Recent         []Divergence     `json:"recent"` // Newest first, at most 100
+}
+
+// Anomaly describes a user whose rating trajectory looks implausible
+type Anomaly struct {
+	Username      string    `json:"username"`
+	Reason        string    `json:"reason"`
+	Detail        string    `json:"detail"`
+	RatingChange  int       `json:"rating_change"`
+	WindowSeconds float64   `json:"window_seconds"`
+	Rating        int       `json:"rating"`
+	FlaggedAt     time.Time `json:"flagged_at"`
+	Frozen        bool      `json:"frozen"`
+}
+
+// HealthIncident is a recorded health transition or failure
+type HealthIncident struct {
+	Component       string    `json:"component"`
+	Kind            string    `json:"kind"`
+	Detail          string    `json:"detail"`
+	At              time.Time `json:"at"`
+	DurationSeconds float64   `json:"duration_seconds,omitempty"` // Time spent degraded, on recovery
+}
+
+// ComponentHealth is the current state of a tracked component
+type ComponentHealth struct {
+	Component string    `json:"component"`
+	Status    string    `json:"status"`
+	Since     time.Time `json:"since"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// HealthHistoryResponse reports uptime and recent health incidents
+type HealthHistoryResponse struct {
+	Status        string            `json:"status"`
+	StartedAt     time.Time         `json:"started_at"`
+	UptimeSeconds float64           `json:"uptime_seconds"`
+	Components    []ComponentHealth `json:"components"`
+	Incidents     []HealthIncident  `json:"incidents"`
+	Count         int               `json:"count"`
+}
+
+// IntegrityIssue is an inconsistency found in the stored board
+type IntegrityIssue struct {
+	Kind     string `json:"kind"`
+	Username string `json:"username"`
+	Rating   int    `json:"rating"`
+	Repaired bool   `json:"repaired,omitempty"`
+}
+
+// IntegrityReport is the result of an on-demand integrity check
+type IntegrityReport struct {
+	CheckedAt  time.Time        `json:"checked_at"`
+	DurationMs float64          `json:"duration_ms"`
+	Users      int              `json:"users"`
+	Repair     bool             `json:"repair"`
+	Issues     []IntegrityIssue `json:"issues"`
+	Count      int              `json:"count"`
+	Repaired   int              `json:"repaired"`
+}
+
+// WarmupReport summarises the startup warm-up phase
+type WarmupReport struct {
+	StartedAt    time.Time        `json:"started_at"`
+	DurationMs   float64          `json:"duration_ms"`
+	Users        int              `json:"users"`
+	CachesWarmed []string         `json:"caches_warmed"`
+	Issues       []IntegrityIssue `json:"issues"`
+}
+
+// SubscriptionFilter narrows the events a WebSocket client receives.
+// Empty fields match everything.
+type SubscriptionFilter struct {
+	Usernames []string `json:"usernames,omitempty" binding:"omitempty,max=100"`  // Only events for these users
+	Top       int      `json:"top,omitempty" binding:"omitempty,min=1,max=1000"` // Only changes entering, leaving or within the top N
+	Board     string   `json:"board,omitempty"`                                  // Only events for this leaderboard
+	Window    string   `json:"window,omitempty" binding:"omitempty,oneof=all_time"`
+	Types     []string `json:"types,omitempty" binding:"omitempty,dive,oneof=user_added score_updated user_evicted user_expired"`
+	Cutoffs   bool     `json:"cutoffs,omitempty"` // Also send watched cutoff changes
+}
+
+// SocketMessage is exchanged over the WebSocket connection.
+// Clients send "subscribe" and "unsubscribe"; the server sends "subscribed",
+// "unsubscribed", "event", "cutoff", "lagged" and "error".
+type SocketMessage struct {
+	Type    string              `json:"type"`
+	Filters *SubscriptionFilter `json:"filters,omitempty"`
+	Event   any                 `json:"event,omitempty"`
+	Cutoff  *CutoffChange       `json:"cutoff,omitempty"`
+	Dropped int64               `json:"dropped,omitempty"` // Events missed while the client was lagging
+	Error   *ErrorResponse      `json:"error,omitempty"`
 }
 
 // ErrorResponse represents an error response
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
+	Error   string       `json:"error"`
+	Message string       `json:"message,omitempty"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// FieldError describes a single field that failed validation
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// Error implements the error interface so services can report invalid input
+func (e FieldError) Error() string {
+	return e.String()
+}
+
+// FieldErrors reports several failed field rules at once
+type FieldErrors []FieldError
+
+func (errs FieldErrors) Error() string {
+	parts := make([]string, 0, len(errs))
+	for _, e := range errs {
+		parts = append(parts, e.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// String returns a human readable description of the failed rule
+func (e FieldError) String() string {
+	switch e.Rule {
+	case "required":
+		return fmt.Sprintf("%s is required", e.Field)
+	case "min":
+		return fmt.Sprintf("%s must be at least %s", e.Field, e.Param)
+	case "max":
+		return fmt.Sprintf("%s must be at most %s", e.Field, e.Param)
+	case "type":
+		return fmt.Sprintf("%s must be of type %s", e.Field, e.Param)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of [%s]", e.Field, e.Param)
+	case "gtefield":
+		return fmt.Sprintf("%s must be at least %s", e.Field, e.Param)
+	case "eq":
+		return fmt.Sprintf("%s must be %s", e.Field, e.Param)
+	case "len":
+		return fmt.Sprintf("%s must have %s items", e.Field, e.Param)
+	case "past":
+		return fmt.Sprintf("%s must not be in the future", e.Field)
+	case "iso3166_1_alpha2":
+		return fmt.Sprintf("%s must be a two-letter ISO 3166-1 country code", e.Field)
+	case "required_without":
+		return fmt.Sprintf("%s is required when %s is not set", e.Field, strings.ToLower(e.Param))
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", e.Field, e.Param)
+	case "nefield":
+		return fmt.Sprintf("%s must differ from %s", e.Field, strings.ToLower(e.Param))
+	default:
+		return fmt.Sprintf("%s failed %s validation", e.Field, e.Rule)
+	}
+}
+
+// CacheStats reports how often a cache answered a lookup
+type CacheStats struct {
+	Name    string  `json:"name"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"` // 0 until the first lookup
+}
+
+// BoardOverview counts a board's members
+type BoardOverview struct {
+	ID           string `json:"id"`
+	Members      int64  `json:"members"`
+	Bots         int64  `json:"bots"`
+	Capacity     int64  `json:"capacity,omitempty"`
+	MemoryBytes  int64  `json:"memory_bytes"`            // Approximate, members and score history
+	MemoryBudget int64  `json:"memory_budget,omitempty"` // When configured
+}
+
+// UpdateRates reports how fast scores are changing
+type UpdateRates struct {
+	PerSecond1m float64          `json:"per_second_1m"`
+	PerSecond5m float64          `json:"per_second_5m"`
+	BySource    map[string]int64 `json:"by_source"` // Since startup
+}
+
+// JobStatus is the state of a background job
+type JobStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // running, paused, stopped, draining or idle
+	Detail string `json:"detail,omitempty"`
+}
+
+// MaintenanceRequest switches the API's maintenance mode
+type MaintenanceRequest struct {
+	Mode    string `json:"mode" binding:"required,oneof=off read_only down"`
+	Message string `json:"message,omitempty" binding:"max=200"` // Shown to clients while the mode lasts
+}
+
+// MaintenanceStatus is the API's maintenance mode
+type MaintenanceStatus struct {
+	Mode    string     `json:"mode"` // off, read_only or down
+	Message string     `json:"message,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+// AdminOverview aggregates what an operations dashboard shows in one response
+type AdminOverview struct {
+	Status        string            `json:"status"` // ready, warming_up or draining
+	Maintenance   MaintenanceStatus `json:"maintenance"`
+	StoreMode     string            `json:"store_mode"`
+	QueueBackend  string            `json:"queue_backend,omitempty"` // When async submissions are enabled
+	UptimeSeconds float64           `json:"uptime_seconds"`
+	Boards        []BoardOverview   `json:"boards"`
+	Updates       UpdateRates       `json:"updates"`
+	Caches        []CacheStats      `json:"caches"`
+	Jobs          []JobStatus       `json:"jobs"`
+	Components    []ComponentHealth `json:"components"`     // Degraded components stop being trusted until they recover
+	RecentErrors  []HealthIncident  `json:"recent_errors"`  // Newest first
+	SLOs          []SLOStatus       `json:"slos,omitempty"` // When latency SLOs are configured
+	GeneratedAt   time.Time         `json:"generated_at"`
+}
+
+// SLOStatus is how an endpoint group is doing against a latency objective
+// over the last five minutes and hour. A breach is a request slower than the
+// threshold or failing with a server error.
+type SLOStatus struct {
+	Group         string     `json:"group"`
+	Objective     string     `json:"objective"` // e.g. "p99<50ms"
+	Percentile    float64    `json:"percentile"`
+	ThresholdMs   float64    `json:"threshold_ms"`
+	Requests5m    int64      `json:"requests_5m"`
+	Breaches5m    int64      `json:"breaches_5m"`
+	Requests1h    int64      `json:"requests_1h"`
+	Breaches1h    int64      `json:"breaches_1h"`
+	BurnRate5m    float64    `json:"burn_rate_5m"` // 1 spends the error budget exactly as fast as allowed
+	BurnRate1h    float64    `json:"burn_rate_1h"`
+	Alerting      bool       `json:"alerting"`
+	AlertingSince *time.Time `json:"alerting_since,omitempty"`
+}
+
+// SLO alert states
+const (
+	SLOFiring   = "firing"
+	SLOResolved = "resolved"
+)
+
+// SLOAlert is raised when an endpoint group starts or stops burning its
+// error budget faster than the alert threshold
+type SLOAlert struct {
+	Group           string    `json:"group"`
+	Objective       string    `json:"objective"`
+	State           string    `json:"state"` // firing or resolved
+	BurnRate5m      float64   `json:"burn_rate_5m"`
+	BurnRate1h      float64   `json:"burn_rate_1h"`
+	Threshold       float64   `json:"threshold"`
+	DurationSeconds float64   `json:"duration_seconds,omitempty"` // How long it fired, when resolved
+	At              time.Time `json:"at"`
 }
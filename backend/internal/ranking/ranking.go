@@ -0,0 +1,102 @@
+// Package ranking defines how the leaderboard orders users and ranks ties, so
+// pages, user ranks, search and snapshots all report the same rank.
+//
+// Users are ordered by sort key, highest first, then by username. A key packs
+// the rating into its high bits and, under the most_recent_first tie policy,
+// when the rating was achieved into its low bits, so equal ratings are
+// ordered most recent first without a second lookup. Users with equal keys
+// share a rank and the ranks after them are skipped (1, 2, 2, 4), so a user's
+// rank is one more than the number of users with a strictly higher key.
+package ranking
+
+import (
+	"strings"
+	"time"
+)
+
+// Tie policies: how users with equal ratings are ordered and ranked
+const (
+	SharedRank      = "shared_rank"       // Equal ratings share a rank, ordered by username
+	MostRecentFirst = "most_recent_first" // Equal ratings are ranked by who reached them last
+)
+
+// Policies lists the supported tie policies
+var Policies = []string{SharedRank, MostRecentFirst}
+
+// recencyBits of a key hold when its rating was achieved, in Unix
+// milliseconds, which lasts until the year 10889. The time isn't inverted
+// as it would be for an earliest-first board: higher keys rank first, so the
+// latest achiever of a rating comes first.
+const (
+	recencyBits = 48
+	recencyMask = 1<<recencyBits - 1
+)
+
+// Key encodes rating, and achieved unless it is zero, as a sort key. Keys
+// without an achieved time tie with every other key of the same rating that
+// lacks one, and rank below those that have one.
+func Key(rating int, achieved time.Time) int64 {
+	key := int64(rating) << recencyBits
+	if !achieved.IsZero() {
+		key |= min(max(achieved.UnixMilli(), 1), recencyMask)
+	}
+	return key
+}
+
+// RatingKey is the key of rating with no achieved time, under which users
+// are ranked by rating alone
+func RatingKey(rating int) int64 {
+	return Key(rating, time.Time{})
+}
+
+// Decode returns the rating encoded in key and when it was achieved, or the
+// zero time if key has none
+func Decode(key int64) (rating int, achieved time.Time) {
+	rating = int(key >> recencyBits)
+	if ms := key & recencyMask; ms != 0 {
+		achieved = time.UnixMilli(ms).UTC()
+	}
+	return rating, achieved
+}
+
+// Compare orders two users in leaderboard order by their keys. It returns a
+// negative number when the first user comes before the second, a positive
+// number when it comes after and zero when they are the same user.
+func Compare(aKey int64, aUsername string, bKey int64, bUsername string) int {
+	if aKey != bKey {
+		if aKey > bKey {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(aUsername, bUsername)
+}
+
+// Before reports whether the first user comes before the second in
+// leaderboard order
+func Before(aKey int64, aUsername string, bKey int64, bUsername string) bool {
+	return Compare(aKey, aUsername, bKey, bUsername) < 0
+}
+
+// FromHigher returns the rank of a user with higher users ahead of them
+func FromHigher(higher int) int {
+	return higher + 1
+}
+
+// Counter assigns ranks to users visited in leaderboard order. The zero
+// value is ready to use.
+type Counter struct {
+	seen int // Users visited so far
+	rank int
+	key  int64
+}
+
+// Next returns the rank of the next user, whose key is key
+func (c *Counter) Next(key int64) int {
+	if c.seen == 0 || key != c.key {
+		c.rank = FromHigher(c.seen)
+		c.key = key
+	}
+	c.seen++
+	return c.rank
+}
@@ -0,0 +1,154 @@
+package ranking
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+type user struct {
+	username string
+	rating   int
+}
+
+func (u user) key() int64 {
+	return RatingKey(u.rating)
+}
+
+func TestCompare(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b user
+		want int
+	}{
+		{"higher rating first", user{"zed", 1500}, user{"amy", 1400}, -1},
+		{"lower rating last", user{"amy", 1400}, user{"zed", 1500}, 1},
+		{"ties by username", user{"amy", 1500}, user{"bob", 1500}, -1},
+		{"ties by username reversed", user{"bob", 1500}, user{"amy", 1500}, 1},
+		{"same user", user{"amy", 1500}, user{"amy", 1500}, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := Compare(c.a.key(), c.a.username, c.b.key(), c.b.username)
+			if sign(got) != c.want {
+				t.Errorf("Compare(%v, %v) = %d, want sign %d", c.a, c.b, got, c.want)
+			}
+			if before := Before(c.a.key(), c.a.username, c.b.key(), c.b.username); before != (c.want < 0) {
+				t.Errorf("Before(%v, %v) = %v", c.a, c.b, before)
+			}
+		})
+	}
+}
+
+func TestCounterSharesTiedRanks(t *testing.T) {
+	ratings := []int{1800, 1700, 1700, 1600, 1500, 1500, 1500, 1400}
+	want := []int{1, 2, 2, 4, 5, 5, 5, 8}
+
+	var c Counter
+	for i, rating := range ratings {
+		if got := c.Next(RatingKey(rating)); got != want[i] {
+			t.Errorf("user %d rated %d: rank %d, want %d", i, rating, got, want[i])
+		}
+	}
+}
+
+func TestCounterAllTied(t *testing.T) {
+	var c Counter
+	for i := range 5 {
+		if got := c.Next(RatingKey(1000)); got != 1 {
+			t.Errorf("user %d: rank %d, want 1", i, got)
+		}
+	}
+}
+
+func TestCounterZeroRating(t *testing.T) {
+	// A zero first rating must still start at rank 1, not match the zero value
+	var c Counter
+	if got := c.Next(0); got != 1 {
+		t.Errorf("first rank %d, want 1", got)
+	}
+	if got := c.Next(0); got != 1 {
+		t.Errorf("second rank %d, want 1", got)
+	}
+	if got := c.Next(RatingKey(-5)); got != 3 {
+		t.Errorf("third rank %d, want 3", got)
+	}
+}
+
+// TestCounterAgreesWithFromHigher checks that walking a board in order gives
+// every user the rank a point lookup would, so pages and user ranks agree
+func TestCounterAgreesWithFromHigher(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for round := range 50 {
+		board := make([]user, r.Intn(200))
+		for i := range board {
+			board[i] = user{fmt.Sprintf("user_%d", i), 1000 + r.Intn(20)*10}
+		}
+		slices.SortFunc(board, func(a, b user) int {
+			return Compare(a.key(), a.username, b.key(), b.username)
+		})
+
+		var c Counter
+		for _, u := range board {
+			higher := 0
+			for _, other := range board {
+				if other.rating > u.rating {
+					higher++
+				}
+			}
+			if got, want := c.Next(u.key()), FromHigher(higher); got != want {
+				t.Fatalf("round %d, %s rated %d: counter rank %d, lookup rank %d", round, u.username, u.rating, got, want)
+			}
+		}
+	}
+}
+
+func TestKeyRoundTrip(t *testing.T) {
+	achieved := time.Date(2025, 1, 1, 12, 0, 0, 123e6, time.UTC)
+	for _, rating := range []int{100, 1500, 5000} {
+		rating2, achieved2 := Decode(Key(rating, achieved))
+		if rating2 != rating || !achieved2.Equal(achieved) {
+			t.Errorf("Decode(Key(%d, %s)) = %d, %s", rating, achieved, rating2, achieved2)
+		}
+		if rating2, achieved2 := Decode(RatingKey(rating)); rating2 != rating || !achieved2.IsZero() {
+			t.Errorf("Decode(RatingKey(%d)) = %d, %s", rating, rating2, achieved2)
+		}
+	}
+}
+
+func TestKeyOrdersMostRecentFirst(t *testing.T) {
+	earlier := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Millisecond)
+
+	ordered := []int64{
+		Key(1600, earlier), // A higher rating wins however old
+		Key(1500, later),
+		Key(1500, earlier),
+		RatingKey(1500), // Unstamped ratings come after stamped ones
+		Key(1400, later),
+	}
+	for i := 1; i < len(ordered); i++ {
+		if !Before(ordered[i-1], "a", ordered[i], "a") {
+			t.Errorf("key %d should come before key %d", i-1, i)
+		}
+	}
+
+	var c Counter
+	for i, want := range []int{1, 2, 3, 4, 5} {
+		if got := c.Next(ordered[i]); got != want {
+			t.Errorf("key %d: rank %d, want %d", i, got, want)
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
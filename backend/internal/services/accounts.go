@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"backend/internal/events"
+	"backend/pkg/store"
+)
+
+// DefaultRating is the starting rating of users who join by logging in
+const DefaultRating = 1000
+
+// maxUsernameLength bounds usernames derived from provider display names
+const maxUsernameLength = 32
+
+// LoginWithIdentity returns the user linked to an external identity,
+// creating and linking a new user on first login. created reports whether
+// the user was created.
+func (s *LeaderboardService) LoginWithIdentity(ctx context.Context, provider, subject, displayName string) (username string, created bool, err error) {
+	// Serialise first logins so one identity can't create two users
+	s.identities.loginMu.Lock()
+	defer s.identities.loginMu.Unlock()
+
+	username, err = s.ResolveExternalID(ctx, provider, subject)
+	if err == nil {
+		return username, false, nil
+	}
+	if !errors.Is(err, ErrExternalIDNotFound) {
+		return "", false, err
+	}
+
+	username, err = s.createUniqueUser(usernameFromDisplayName(displayName))
+	if err != nil {
+		return "", false, err
+	}
+	if _, err := s.LinkExternalID(ctx, provider, subject, username); err != nil {
+		return "", false, err
+	}
+
+	return username, true, nil
+}
+
+// createUniqueUser creates a user named base, adding a numeric suffix if the
+// name is taken or still holds role grants
+func (s *LeaderboardService) createUniqueUser(base string) (string, error) {
+	username := base
+	for attempt := 0; attempt < 10; attempt++ {
+		err := store.ErrUserExists
+		if !s.nameHeld(username) {
+			err = s.store.CreateUser(username, DefaultRating)
+		}
+		if err == nil {
+			s.mirrorShadow(username, DefaultRating, false)
+			s.events.Publish(events.Event{
+				Type:     events.TypeUserAdded,
+				Username: username,
+				Rating:   DefaultRating,
+				Source:   SourceAPI,
+			})
+			return username, nil
+		}
+		if !errors.Is(err, store.ErrUserExists) {
+			return "", fmt.Errorf("failed to create user: %w", err)
+		}
+		username = fmt.Sprintf("%s_%04d", base, rand.Intn(10000))
+	}
+	return "", fmt.Errorf("failed to find a free username for %q", base)
+}
+
+// usernameFromDisplayName keeps letters, digits and underscores from a
+// provider's display name, falling back to "player"
+func usernameFromDisplayName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.':
+			b.WriteRune('_')
+		}
+		if b.Len() >= maxUsernameLength-5 { // Leave room for a suffix
+			break
+		}
+	}
+
+	username := strings.Trim(b.String(), "_")
+	if username == "" {
+		return "player"
+	}
+	return username
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/models"
+)
+
+// ErrUserFrozen is returned when updates for a flagged user are frozen pending review
+var ErrUserFrozen = errors.New("user updates are frozen pending review")
+
+// ErrAnomalyNotFound is returned when resolving a user that isn't flagged
+var ErrAnomalyNotFound = errors.New("anomaly not found")
+
+// AnomalyConfig controls when a rating trajectory is considered implausible
+type AnomalyConfig struct {
+	Window              time.Duration // Trajectory window that is inspected
+	MaxChange           int           // Largest net change allowed within Window
+	OscillationSwing    int           // Minimum swing counted as an oscillation
+	OscillationLimit    int           // Direction reversals within Window that flag a user
+	AutoFreeze          bool          // Freeze flagged users' updates until resolved
+	IncludeBots         bool          // Also inspect seeded/simulated users
+	MaxPointsPerUser    int           // Bound on the trajectory kept per user
+	MaxTrackedAnomalies int           // Bound on flagged users kept for review
+}
+
+// DefaultAnomalyConfig flags +/-3000 within a minute or four large reversals
+func DefaultAnomalyConfig() AnomalyConfig {
+	return AnomalyConfig{
+		Window:              time.Minute,
+		MaxChange:           3000,
+		OscillationSwing:    500,
+		OscillationLimit:    4,
+		MaxPointsPerUser:    64,
+		MaxTrackedAnomalies: 10000,
+	}
+}
+
+type ratingPoint struct {
+	rating int
+	at     time.Time
+}
+
+// AnomalyDetector watches score updates for implausible rating trajectories
+type AnomalyDetector struct {
+	mu           sync.Mutex
+	config       AnomalyConfig
+	trajectories map[string][]ratingPoint
+	flagged      map[string]*models.Anomaly
+	lastSweep    time.Time
+}
+
+// NewAnomalyDetector creates a detector with the given thresholds
+func NewAnomalyDetector(config AnomalyConfig) *AnomalyDetector {
+	return &AnomalyDetector{
+		config:       config,
+		trajectories: make(map[string][]ratingPoint),
+		flagged:      make(map[string]*models.Anomaly),
+	}
+}
+
+// Handle inspects a published event; it is meant to be subscribed to the event bus
+func (d *AnomalyDetector) Handle(e events.Event) {
+	// Moderator corrections carry an actor; they are deliberate jumps, not suspicious ones
+	if e.Type != events.TypeScoreUpdated || e.Actor != "" {
+		return
+	}
+	if e.Bot && !d.config.IncludeBots {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sweep(e.Timestamp)
+
+	points := d.trajectories[e.Username]
+	if len(points) == 0 {
+		points = append(points, ratingPoint{rating: e.PreviousRating, at: e.Timestamp})
+	}
+	points = append(points, ratingPoint{rating: e.Rating, at: e.Timestamp})
+
+	// Drop points that fell out of the window, keeping one as the baseline
+	cutoff := e.Timestamp.Add(-d.config.Window)
+	start := 0
+	for start < len(points)-1 && points[start+1].at.Before(cutoff) {
+		start++
+	}
+	if len(points)-start > d.config.MaxPointsPerUser {
+		start = len(points) - d.config.MaxPointsPerUser
+	}
+	points = append([]ratingPoint(nil), points[start:]...)
+	d.trajectories[e.Username] = points
+
+	if _, already := d.flagged[e.Username]; already {
+		return
+	}
+	if len(d.flagged) >= d.config.MaxTrackedAnomalies {
+		return
+	}
+
+	if reason, detail, change := d.inspect(points); reason != "" {
+		d.flagged[e.Username] = &models.Anomaly{
+			Username:      e.Username,
+			Reason:        reason,
+			Detail:        detail,
+			RatingChange:  change,
+			WindowSeconds: d.config.Window.Seconds(),
+			Rating:        e.Rating,
+			FlaggedAt:     e.Timestamp,
+			Frozen:        d.config.AutoFreeze,
+		}
+	}
+}
+
+// sweep forgets trajectories of users that have been idle for a full window
+func (d *AnomalyDetector) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.config.Window {
+		return
+	}
+	d.lastSweep = now
+
+	cutoff := now.Add(-d.config.Window)
+	for username, points := range d.trajectories {
+		if points[len(points)-1].at.Before(cutoff) {
+			delete(d.trajectories, username)
+		}
+	}
+}
+
+// inspect checks a trajectory for a rapid net change or repeated large reversals
+func (d *AnomalyDetector) inspect(points []ratingPoint) (reason, detail string, change int) {
+	first, last := points[0], points[len(points)-1]
+
+	// Largest rise or drop between any two points in the window
+	low, high := first, first
+	for _, p := range points[1:] {
+		if rise := p.rating - low.rating; rise >= d.config.MaxChange {
+			return "rapid_change", fmt.Sprintf("rating rose by %d in %s", rise, p.at.Sub(low.at).Round(time.Second)), rise
+		}
+		if drop := high.rating - p.rating; drop >= d.config.MaxChange {
+			return "rapid_change", fmt.Sprintf("rating dropped by %d in %s", drop, p.at.Sub(high.at).Round(time.Second)), -drop
+		}
+		if p.rating < low.rating {
+			low = p
+		}
+		if p.rating > high.rating {
+			high = p
+		}
+	}
+	change = last.rating - first.rating
+
+	reversals := 0
+	direction := 0
+	for i := 1; i < len(points); i++ {
+		delta := points[i].rating - points[i-1].rating
+		if abs(delta) < d.config.OscillationSwing {
+			continue
+		}
+		next := 1
+		if delta < 0 {
+			next = -1
+		}
+		if direction != 0 && next != direction {
+			reversals++
+		}
+		direction = next
+	}
+	if reversals >= d.config.OscillationLimit {
+		return "oscillation", fmt.Sprintf("%d large direction reversals in %s", reversals, last.at.Sub(first.at).Round(time.Second)), change
+	}
+
+	return "", "", change
+}
+
+// IsFrozen reports whether updates for username are frozen pending review
+func (d *AnomalyDetector) IsFrozen(username string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	anomaly, ok := d.flagged[username]
+	return ok && anomaly.Frozen
+}
+
+// List returns flagged users, most recent first
+func (d *AnomalyDetector) List() []models.Anomaly {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	anomalies := make([]models.Anomaly, 0, len(d.flagged))
+	for _, anomaly := range d.flagged {
+		anomalies = append(anomalies, *anomaly)
+	}
+	sort.Slice(anomalies, func(i, j int) bool {
+		return anomalies[i].FlaggedAt.After(anomalies[j].FlaggedAt)
+	})
+	return anomalies
+}
+
+// Resolve clears a flag after review, unfreezing the user
+func (d *AnomalyDetector) Resolve(username string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.flagged[username]; !ok {
+		return ErrAnomalyNotFound
+	}
+	delete(d.flagged, username)
+	delete(d.trajectories, username)
+	return nil
+}
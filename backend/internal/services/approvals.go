@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/models"
+)
+
+// AnonymousActor is the actor recorded when auth is disabled
+const AnonymousActor = "anonymous"
+
+var (
+	// ErrPendingChangeNotFound is returned for an unknown or already decided change
+	ErrPendingChangeNotFound = errors.New("pending change not found")
+	// ErrSelfApproval is returned when the staff member who requested a change approves it
+	ErrSelfApproval = errors.New("a change must be approved by someone other than its requester")
+)
+
+// approvalWindow is how long an adjustment applied without approval counts
+// toward the next ones for the same user, so a large change split into
+// small steps still reaches the threshold
+const approvalWindow = 24 * time.Hour
+
+// approvals holds adjustments staged until a second staff member approves
+// them. A threshold of 0 applies every adjustment immediately.
+type approvals struct {
+	mu        sync.Mutex
+	threshold int
+	pending   map[string]models.PendingChange
+	recent    map[string][]recentAdjustment // By username, within approvalWindow
+}
+
+// recentAdjustment is a rating change applied without approval
+type recentAdjustment struct {
+	at    time.Time
+	delta int
+}
+
+func newApprovals() *approvals {
+	return &approvals{
+		pending: make(map[string]models.PendingChange),
+		recent:  make(map[string][]recentAdjustment),
+	}
+}
+
+// SetApprovalThreshold stages adjustments that move a rating by more than
+// delta until a second staff member approves them; 0 turns approval off.
+// Changes already staged stay pending.
+func (s *LeaderboardService) SetApprovalThreshold(delta int) {
+	a := s.approvals
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.threshold = max(delta, 0)
+}
+
+// needsApproval reports whether moving username's rating from previous to
+// rating must wait for a second approver: whether it, together with the
+// adjustments applied to them without approval in the last approvalWindow,
+// moves their rating by more than the threshold. An adjustment that doesn't
+// is counted toward the next ones straight away, even if applying it then
+// fails.
+func (a *approvals) needsApproval(username string, previous, rating int, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.threshold == 0 {
+		return false
+	}
+
+	recent := slices.DeleteFunc(a.recent[username], func(r recentAdjustment) bool {
+		return now.Sub(r.at) >= approvalWindow
+	})
+	delta := rating - previous
+	moved := delta
+	for _, r := range recent {
+		moved += r.delta
+	}
+	if max(moved, -moved) > a.threshold {
+		a.recent[username] = recent
+		return true
+	}
+	a.recent[username] = append(recent, recentAdjustment{at: now, delta: delta})
+	return false
+}
+
+// identitiesOf returns principal with the identities linked to it, if it's
+// a user
+func (s *LeaderboardService) identitiesOf(principal string) []string {
+	kind, name, err := splitPrincipal(principal)
+	if err != nil || kind != PrincipalUser {
+		return []string{principal}
+	}
+	return append([]string{principal}, s.identities.principals(name)...)
+}
+
+// selfApproval reports whether approver is change's requester: the same
+// principal, or a user holding one of the identities linked to the
+// requester when the change was staged or now, such as the account the
+// requester was merged into
+func (s *LeaderboardService) selfApproval(change models.PendingChange, approver string) bool {
+	if approver == AnonymousActor {
+		return false
+	}
+	requester := append(s.identitiesOf(change.RequestedBy), change.RequesterIdentities...)
+	for _, identity := range s.identitiesOf(approver) {
+		if slices.Contains(requester, identity) {
+			return true
+		}
+	}
+	return false
+}
+
+// stageAdjustment holds an adjustment for approval
+func (s *LeaderboardService) stageAdjustment(username string, rating, previous int, reason, actor string) *models.ScoreAdjustmentResponse {
+	change := models.PendingChange{
+		ID:             newSubmissionID(),
+		Username:       username,
+		Rating:         rating,
+		PreviousRating: previous,
+		Reason:         reason,
+		RequestedBy:    actor,
+		RequestedAt:    s.clock.Now().UTC(),
+	}
+	if identities := s.identitiesOf(actor)[1:]; len(identities) > 0 {
+		slices.Sort(identities)
+		change.RequesterIdentities = identities
+	}
+
+	a := s.approvals
+	a.mu.Lock()
+	a.pending[change.ID] = change
+	a.mu.Unlock()
+
+	log.Printf("🛡️  %s staged %s: %d -> %d for approval (%s)", actor, username, previous, rating, reason)
+	return &models.ScoreAdjustmentResponse{
+		Username:        username,
+		Rating:          rating,
+		PreviousRating:  previous,
+		Reason:          reason,
+		Actor:           actor,
+		Status:          models.AdjustmentPendingApproval,
+		PendingChangeID: change.ID,
+	}
+}
+
+// ListPendingChanges returns the adjustments awaiting approval, oldest first
+func (s *LeaderboardService) ListPendingChanges(ctx context.Context) []models.PendingChange {
+	a := s.approvals
+	a.mu.Lock()
+	changes := make([]models.PendingChange, 0, len(a.pending))
+	for _, change := range a.pending {
+		changes = append(changes, change)
+	}
+	a.mu.Unlock()
+
+	slices.SortFunc(changes, func(x, y models.PendingChange) int {
+		if c := x.RequestedAt.Compare(y.RequestedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(x.ID, y.ID)
+	})
+	return changes
+}
+
+// ApproveChange applies a staged adjustment on approver's authority. The
+// staged rating is applied even if the user's rating moved since. Without
+// auth every caller is anonymous, so anyone may approve.
+func (s *LeaderboardService) ApproveChange(ctx context.Context, id, approver string) (*models.ScoreAdjustmentResponse, error) {
+	a := s.approvals
+	a.mu.Lock()
+	change, ok := a.pending[id]
+	a.mu.Unlock()
+	if !ok {
+		return nil, ErrPendingChangeNotFound
+	}
+	if s.selfApproval(change, approver) {
+		return nil, ErrSelfApproval
+	}
+	a.mu.Lock()
+	_, ok = a.pending[id]
+	delete(a.pending, id)
+	a.mu.Unlock()
+	if !ok {
+		return nil, ErrPendingChangeNotFound
+	}
+
+	user, err := s.store.GetUser(change.Username)
+	if err != nil {
+		return nil, err
+	}
+	previous := user.Rating
+	if err := s.commitScore(WithSource(ctx, SourceAdmin), user, change.Rating, events.Event{
+		Reason: models.ReasonAdminAdjustment,
+		Actor:  change.RequestedBy,
+		Note:   fmt.Sprintf("%s (approved by %s)", change.Reason, approver),
+	}); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🛡️  %s approved %s's adjustment of %s: %d -> %d", approver, change.RequestedBy, change.Username, previous, change.Rating)
+	return &models.ScoreAdjustmentResponse{
+		Username:       change.Username,
+		Rating:         change.Rating,
+		PreviousRating: previous,
+		Reason:         change.Reason,
+		Actor:          change.RequestedBy,
+		Status:         models.AdjustmentApplied,
+		ApprovedBy:     approver,
+	}, nil
+}
+
+// RejectChange discards a staged adjustment
+func (s *LeaderboardService) RejectChange(ctx context.Context, id, actor string) error {
+	a := s.approvals
+	a.mu.Lock()
+	change, ok := a.pending[id]
+	delete(a.pending, id)
+	a.mu.Unlock()
+	if !ok {
+		return ErrPendingChangeNotFound
+	}
+
+	log.Printf("🛡️  %s rejected %s's adjustment of %s to %d", actor, change.RequestedBy, change.Username, change.Rating)
+	return nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"backend/internal/clock"
+	"backend/internal/models"
+)
+
+func TestApprovalCountsRecentAdjustments(t *testing.T) {
+	s := NewLeaderboardService(playersStore(t, 1))
+	s.SetApprovalThreshold(100)
+	fake := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	s.SetClock(fake)
+	ctx := context.Background()
+
+	steps := []struct {
+		after  time.Duration // Since the previous step
+		rating int
+		status string
+	}{
+		{rating: 160, status: models.AdjustmentApplied},
+		{after: time.Hour, rating: 190, status: models.AdjustmentApplied},
+		// 100 -> 160 -> 190 -> 230 splits a 130 move
+		{after: time.Hour, rating: 230, status: models.AdjustmentPendingApproval},
+		// Moving back counts against the earlier steps
+		{rating: 120, status: models.AdjustmentApplied},
+		// The first two steps have left the window, the move back still counts
+		{after: approvalWindow - time.Hour, rating: 20, status: models.AdjustmentPendingApproval},
+		{after: time.Hour, rating: 20, status: models.AdjustmentApplied},
+	}
+	for i, step := range steps {
+		fake.Advance(step.after)
+		result, err := s.AdjustScore(ctx, "player_0", step.rating, "correction", "user:mod")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Status != step.status {
+			t.Errorf("step %d to %d: %s, want %s", i, step.rating, result.Status, step.status)
+		}
+	}
+}
+
+func TestSelfApprovalComparesIdentities(t *testing.T) {
+	cases := []struct {
+		name     string
+		approver string
+		before   func(s *LeaderboardService) error // After staging, before approving
+		self     bool
+	}{
+		{name: "same principal", approver: "user:player_1", self: true},
+		{name: "someone else", approver: "user:player_2"},
+		{name: "another key", approver: "key:ops"},
+		{
+			name:     "the requester's identity linked to another account",
+			approver: "user:player_2",
+			before: func(s *LeaderboardService) error {
+				if err := s.UnlinkExternalID(context.Background(), "github", "42"); err != nil {
+					return err
+				}
+				_, err := s.LinkExternalID(context.Background(), "github", "42", "player_2")
+				return err
+			},
+			self: true,
+		},
+		{
+			name:     "the account the requester was merged into",
+			approver: "user:player_2",
+			before: func(s *LeaderboardService) error {
+				_, err := s.MergeUsers(context.Background(), models.MergeUsersRequest{From: "player_1", Into: "player_2", Reason: "duplicate account"}, "admin")
+				return err
+			},
+			self: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := NewLeaderboardService(playersStore(t, 3))
+			s.SetApprovalThreshold(100)
+			ctx := context.Background()
+			if _, err := s.LinkExternalID(ctx, "github", "42", "player_1"); err != nil {
+				t.Fatal(err)
+			}
+			staged, err := s.AdjustScore(ctx, "player_0", 500, "correction", "user:player_1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.before != nil {
+				if err := c.before(s); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err = s.ApproveChange(ctx, staged.PendingChangeID, c.approver)
+			if got := errors.Is(err, ErrSelfApproval); got != c.self {
+				t.Errorf("approval by %s: %v, want self-approval %t", c.approver, err, c.self)
+			}
+			if !c.self && err != nil {
+				t.Errorf("approval by %s: %v", c.approver, err)
+			}
+		})
+	}
+}
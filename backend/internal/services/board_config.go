@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/ranking"
+)
+
+// ExportBoardConfig describes this board as a configuration document that
+// ApplyBoardConfig accepts, so environments can be kept in sync
+func (s *LeaderboardService) ExportBoardConfig(ctx context.Context) *models.BoardConfig {
+	def := models.BoardDefinition{
+		ID:             s.boardID,
+		MinScore:       MinRating,
+		MaxScore:       MaxRating,
+		TiePolicy:      s.TiePolicy(),
+		RatingStrategy: s.ratingStrategy().Name(),
+		Capacity:       s.store.Capacity(),
+	}
+	if tiers := s.TierBoundaries(); len(tiers) > 0 {
+		def.TierBoundaries = tiers
+	}
+	if season := s.CurrentSeason(); season != nil {
+		def.Season = &models.SeasonDefinition{ID: season.ID, EndsAt: season.EndsAt}
+	}
+	for _, band := range s.currentPrizeBands() {
+		def.PrizeBands = append(def.PrizeBands, models.PrizeBandDefinition(band))
+	}
+	return &models.BoardConfig{Boards: []models.BoardDefinition{def}}
+}
+
+// ApplyBoardConfig brings the board in line with its definition in doc and
+// lists what changed. The whole document is validated before anything is
+// applied; with dryRun nothing is. Score bounds and tiers are fixed in this
+// build, so a definition may only restate them. An
+// omitted season leaves a running season alone: seasons are closed through
+// CloseSeason, which freezes standings and notifies webhooks.
+func (s *LeaderboardService) ApplyBoardConfig(ctx context.Context, doc models.BoardConfig, dryRun bool) (*models.BoardConfigResult, error) {
+	def, strategy, err := s.validateBoardConfig(doc)
+	if err != nil {
+		return nil, err
+	}
+	if def.Season != nil {
+		if current := s.CurrentSeason(); current != nil && current.ID != def.Season.ID {
+			return nil, ErrSeasonActive
+		}
+	}
+
+	result := &models.BoardConfigResult{DryRun: dryRun, Changes: []string{}}
+	change := func(format string, args ...any) {
+		result.Changes = append(result.Changes, fmt.Sprintf(format, args...))
+	}
+
+	if current := s.ratingStrategy(); current.Name() != strategy.Name() {
+		change("rating_strategy: %s -> %s", current.Name(), strategy.Name())
+		if !dryRun {
+			s.SetRatingStrategy(strategy)
+		}
+	}
+
+	if current := s.TiePolicy(); def.TiePolicy != "" && current != def.TiePolicy {
+		change("tie_policy: %s -> %s", current, def.TiePolicy)
+		if !dryRun {
+			s.SetTiePolicy(def.TiePolicy)
+		}
+	}
+
+	if current := s.store.Capacity(); current != def.Capacity {
+		change("capacity: %d -> %d", current, def.Capacity)
+		if !dryRun {
+			s.SetCapacity(def.Capacity)
+		}
+	}
+
+	bands := make([]PrizeBand, 0, len(def.PrizeBands))
+	for _, band := range def.PrizeBands {
+		bands = append(bands, PrizeBand(band))
+	}
+	if current := s.currentPrizeBands(); !slices.Equal(current, bands) {
+		change("prize_bands: %d -> %d bands", len(current), len(bands))
+		if !dryRun {
+			s.SetPrizeBands(bands)
+		}
+	}
+
+	if def.Season != nil {
+		if err := s.applySeasonDefinition(ctx, *def.Season, dryRun, change); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// validateBoardConfig checks doc holds exactly this board and that every
+// field can be applied, collecting every failure
+func (s *LeaderboardService) validateBoardConfig(doc models.BoardConfig) (models.BoardDefinition, RatingStrategy, error) {
+	if len(doc.Boards) != 1 {
+		return models.BoardDefinition{}, nil, models.FieldErrors{{Field: "boards", Rule: "len", Param: "1", Value: len(doc.Boards)}}
+	}
+	def := doc.Boards[0]
+
+	var errs models.FieldErrors
+	field := func(name string) string { return "boards[0]." + name }
+	if def.ID != s.boardID {
+		errs = append(errs, models.FieldError{Field: field("id"), Rule: "eq", Param: s.boardID, Value: def.ID})
+	}
+	if def.MinScore != 0 && def.MinScore != MinRating {
+		errs = append(errs, models.FieldError{Field: field("min_score"), Rule: "eq", Param: fmt.Sprint(MinRating), Value: def.MinScore})
+	}
+	if def.MaxScore != 0 && def.MaxScore != MaxRating {
+		errs = append(errs, models.FieldError{Field: field("max_score"), Rule: "eq", Param: fmt.Sprint(MaxRating), Value: def.MaxScore})
+	}
+	if def.TiePolicy != "" && !slices.Contains(ranking.Policies, def.TiePolicy) {
+		errs = append(errs, models.FieldError{Field: field("tie_policy"), Rule: "oneof", Param: strings.Join(ranking.Policies, " "), Value: def.TiePolicy})
+	}
+	if tiers := s.TierBoundaries(); len(def.TierBoundaries) > 0 && !slices.Equal(def.TierBoundaries, tiers) {
+		errs = append(errs, models.FieldError{Field: field("tier_boundaries"), Rule: "eq", Param: formatTierBoundaries(tiers), Value: formatTierBoundaries(def.TierBoundaries)})
+	}
+	if def.Capacity < 0 {
+		errs = append(errs, models.FieldError{Field: field("capacity"), Rule: "min", Param: "0", Value: def.Capacity})
+	}
+
+	strategy, err := RatingStrategyByName(def.RatingStrategy)
+	if err != nil {
+		errs = append(errs, models.FieldError{Field: field("rating_strategy"), Rule: "oneof", Param: "absolute delta elo glicko trueskill", Value: def.RatingStrategy})
+	}
+
+	for i, band := range def.PrizeBands {
+		name := field(fmt.Sprintf("prize_bands[%d]", i))
+		switch {
+		case band.Name == "":
+			errs = append(errs, models.FieldError{Field: name + ".name", Rule: "required"})
+		case band.TopPercent != 0 && (band.MinRank != 0 || band.MaxRank != 0):
+			errs = append(errs, models.FieldError{Field: name + ".top_percent", Rule: "excluded_with", Param: "min_rank max_rank", Value: band.TopPercent})
+		case band.TopPercent != 0 && (band.TopPercent < 0 || band.TopPercent > 100):
+			errs = append(errs, models.FieldError{Field: name + ".top_percent", Rule: "max", Param: "100", Value: band.TopPercent})
+		case band.TopPercent == 0 && (band.MinRank < 1 || band.MaxRank < band.MinRank):
+			errs = append(errs, models.FieldError{Field: name + ".max_rank", Rule: "gtefield", Param: "min_rank", Value: band.MaxRank})
+		}
+	}
+
+	if def.Season != nil {
+		switch {
+		case def.Season.ID == "":
+			errs = append(errs, models.FieldError{Field: field("season.id"), Rule: "required"})
+		case len(def.Season.ID) > 64:
+			errs = append(errs, models.FieldError{Field: field("season.id"), Rule: "max", Param: "64", Value: def.Season.ID})
+		case def.Season.EndsAt != nil && !def.Season.EndsAt.After(s.clock.Now()):
+			errs = append(errs, models.FieldError{Field: field("season.ends_at"), Rule: "gt", Param: "now", Value: def.Season.EndsAt})
+		}
+	}
+
+	if len(errs) > 0 {
+		return def, nil, errs
+	}
+	return def, strategy, nil
+}
+
+// applySeasonDefinition starts the defined season, or moves the running
+// one's end time. A different season already running is ErrSeasonActive.
+func (s *LeaderboardService) applySeasonDefinition(ctx context.Context, def models.SeasonDefinition, dryRun bool, change func(string, ...any)) error {
+	current := s.CurrentSeason()
+	switch {
+	case current == nil:
+		change("season: none -> %s", def.ID)
+		if !dryRun {
+			_, err := s.StartSeason(ctx, def.ID, def.EndsAt)
+			return err
+		}
+	case current.ID != def.ID:
+		return ErrSeasonActive
+	case !equalTimes(current.EndsAt, def.EndsAt):
+		change("season %s ends_at: %s -> %s", def.ID, formatEnd(current.EndsAt), formatEnd(def.EndsAt))
+		if !dryRun {
+			return s.RescheduleSeason(ctx, def.ID, def.EndsAt)
+		}
+	}
+	return nil
+}
+
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func formatEnd(t *time.Time) string {
+	if t == nil {
+		return "none"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,421 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+var (
+	// ErrBoardNotKept is returned for recomputes of boards that aren't
+	// derived boards this deployment keeps, such as a kind that's off or a
+	// day past retention
+	ErrBoardNotKept = errors.New("board is not a derived board that is kept")
+	// ErrRecomputeRunning is returned when a recompute is started while another runs
+	ErrRecomputeRunning = errors.New("a board recompute is already running")
+	// ErrRecomputeJobNotFound is returned for unknown or forgotten recompute jobs
+	ErrRecomputeJobNotFound = errors.New("board recompute job not found")
+)
+
+const (
+	// recomputeBatchSize is how many users are read, or corrections written,
+	// between progress updates
+	recomputeBatchSize = 500
+	recomputeJobsKept  = 20
+
+	// tiersBoard names the tier populations to recompute
+	tiersBoard = "tiers"
+)
+
+// boardRecomputes runs one recompute at a time and keeps recent jobs
+type boardRecomputes struct {
+	mu      sync.Mutex
+	jobs    []models.BoardRecomputeJob // Newest first
+	running string                     // ID of the running job, if any
+	done    map[string]chan struct{}   // Closed when a job finishes
+}
+
+func newBoardRecomputes() *boardRecomputes {
+	return &boardRecomputes{done: make(map[string]chan struct{})}
+}
+
+// StartBoardRecompute starts rebuilding a derived board in the background
+// and returns the job as it starts. The board is rebuilt from the source of
+// truth, for after a bug or a policy change left it wrong:
+//   - a country board from the main board's ratings of the users placed in
+//     that country
+//   - the team board from the summed ratings of each team's members
+//   - a daily board from the score history of that day, leaving out the
+//     simulator's changes as score updates do
+//
+// Score updates go on while the job reads; they only wait while a batch of
+// corrections is written. The tier populations, board "tiers", are recounted
+// from the main board too, with or without derived boards.
+func (s *LeaderboardService) StartBoardRecompute(board string) (models.BoardRecomputeJob, error) {
+	switch d := s.derived; {
+	case board == tiersBoard:
+		if len(s.TierBoundaries()) == 0 {
+			return models.BoardRecomputeJob{}, ErrTiersNotConfigured
+		}
+	case d == nil:
+		return models.BoardRecomputeJob{}, ErrBoardsDisabled
+	case !d.keeps(board, s.clock.Now().UTC().Format(time.DateOnly)):
+		return models.BoardRecomputeJob{}, ErrBoardNotKept
+	}
+
+	rj := s.recomputes
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+	if rj.running != "" {
+		return models.BoardRecomputeJob{}, ErrRecomputeRunning
+	}
+
+	job := models.BoardRecomputeJob{
+		ID:        newSubmissionID(),
+		Board:     board,
+		Status:    models.RecomputeRunning,
+		Phase:     models.RecomputeScanning,
+		StartedAt: s.clock.Now().UTC(),
+	}
+	done := make(chan struct{})
+	rj.running = job.ID
+	rj.done[job.ID] = done
+	rj.recordLocked(job)
+
+	go func() {
+		defer close(done)
+		s.runRecompute(job)
+	}()
+	return job, nil
+}
+
+// WaitBoardRecompute waits for a recompute job to finish and returns it, or
+// returns ctx's error if ctx ends first
+func (s *LeaderboardService) WaitBoardRecompute(ctx context.Context, id string) (models.BoardRecomputeJob, error) {
+	rj := s.recomputes
+	rj.mu.Lock()
+	done := rj.done[id]
+	rj.mu.Unlock()
+
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			job, _ := s.BoardRecomputeJob(id)
+			return job, ctx.Err()
+		}
+	}
+	return s.BoardRecomputeJob(id)
+}
+
+// BoardRecomputeJob returns a recent recompute job
+func (s *LeaderboardService) BoardRecomputeJob(id string) (models.BoardRecomputeJob, error) {
+	rj := s.recomputes
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+
+	for _, job := range rj.jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+	return models.BoardRecomputeJob{}, ErrRecomputeJobNotFound
+}
+
+// record adds job to the history, replacing its earlier snapshot
+func (rj *boardRecomputes) record(job models.BoardRecomputeJob) {
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+	rj.recordLocked(job)
+}
+
+func (rj *boardRecomputes) recordLocked(job models.BoardRecomputeJob) {
+	for i := range rj.jobs {
+		if rj.jobs[i].ID == job.ID {
+			rj.jobs[i] = job
+			return
+		}
+	}
+	rj.jobs = append([]models.BoardRecomputeJob{job}, rj.jobs...)
+	if len(rj.jobs) > recomputeJobsKept {
+		for _, old := range rj.jobs[recomputeJobsKept:] {
+			delete(rj.done, old.ID)
+		}
+		rj.jobs = rj.jobs[:recomputeJobsKept]
+	}
+}
+
+// finish records a job's final state and lets the next recompute start
+func (rj *boardRecomputes) finish(job models.BoardRecomputeJob) {
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+	rj.recordLocked(job)
+	if rj.running == job.ID {
+		rj.running = ""
+	}
+}
+
+// keeps reports whether board is a derived board that's kept on today
+func (d *derivedBoards) keeps(board, today string) bool {
+	switch kind := boardKind(board); {
+	case !d.kinds[kind]:
+		return false
+	case kind == models.BoardKindTeam:
+		return board == teamsBoard
+	case kind == models.BoardKindCountry:
+		country := board[len(models.BoardKindCountry)+1:]
+		return len(country) == 2 && 'A' <= country[0] && country[0] <= 'Z' && 'A' <= country[1] && country[1] <= 'Z'
+	case kind == models.BoardKindDaily:
+		day := board[len(models.BoardKindDaily)+1:]
+		if _, err := time.Parse(time.DateOnly, day); err != nil {
+			return false
+		}
+		return day <= today && day > d.cutoff(today)
+	}
+	return false
+}
+
+// runRecompute rebuilds the job's board, recording progress after each batch
+func (s *LeaderboardService) runRecompute(job models.BoardRecomputeJob) {
+	log.Printf("Recompute %s: rebuilding %s...", job.ID, job.Board)
+
+	report := func() { s.recomputes.record(job) }
+	var err error
+	if job.Board == tiersBoard {
+		s.recomputeTiers(&job, report)
+	} else {
+		err = s.recompute(context.Background(), &job, report)
+	}
+
+	job.Status = models.RecomputeSucceeded
+	if err != nil {
+		job.Status = models.RecomputeFailed
+		job.Error = err.Error()
+	}
+	finished := s.clock.Now().UTC()
+	job.FinishedAt = &finished
+	s.recomputes.finish(job)
+
+	log.Printf("Recompute %s %s: %d users read, %d of %d corrections written",
+		job.ID, job.Status, job.Scanned, job.Written, job.Corrections)
+	if err != nil {
+		s.RecordIncident("board_recompute", IncidentFailure, err.Error())
+	}
+}
+
+// recomputeTiers recounts the tier populations from the main board
+func (s *LeaderboardService) recomputeTiers(job *models.BoardRecomputeJob, report func()) {
+	counted := s.segments.rebuild(s.store, s.TierBoundaries())
+	job.Phase = models.RecomputeWriting
+	job.Scanned, job.Total = counted, counted
+	report()
+}
+
+// contribution is what one user adds to a derived board: value on the member
+// key, or nothing if key is ""
+type contribution struct {
+	key   string
+	value int
+}
+
+// boardTotal is a member's true score and how many users it sums
+type boardTotal struct {
+	value, users int
+}
+
+// add counts c in, or out with sign -1
+func (t *boardTotal) add(c contribution, sign int) {
+	t.value += sign * c.value
+	t.users += sign
+}
+
+// recompute reads the board's true scores without holding score updates back,
+// then corrects the members that differ a batch at a time. Each batch is
+// checked and written under the derived boards' lock, reading again the
+// users whose score or placement changed since the job began, so a
+// correction never undoes an update made while the job ran.
+func (s *LeaderboardService) recompute(ctx context.Context, job *models.BoardRecomputeJob, report func()) error {
+	d := s.derived
+	d.mu.Lock()
+	d.touched = make(map[string]bool)
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		d.touched = nil
+		d.mu.Unlock()
+	}()
+
+	var users []string
+	var profiles map[string]store.BoardProfile
+	if boardKind(job.Board) == models.BoardKindDaily {
+		users = s.history.users()
+	} else {
+		var err error
+		if profiles, err = d.store.Profiles(ctx); err != nil {
+			return fmt.Errorf("read placements: %w", err)
+		}
+		users = slices.Sorted(maps.Keys(profiles))
+	}
+
+	contribute := s.contributor(job.Board)
+	scanned := make(map[string]contribution, len(users))
+	want := make(map[string]boardTotal)
+	scan(users, job, report, func(username string) {
+		c := contribute(username, profiles[username])
+		scanned[username] = c
+		if c.key != "" {
+			total := want[c.key]
+			total.add(c, 1)
+			want[c.key] = total
+		}
+	})
+
+	// Members only on the board are checked too, to be taken off
+	members, _, err := d.store.Page(ctx, job.Board, 0, math.MaxInt32)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", job.Board, err)
+	}
+	keys := make(map[string]bool, len(want)+len(members))
+	for key := range want {
+		keys[key] = true
+	}
+	for _, member := range members {
+		keys[member.User.Username] = true
+	}
+
+	job.Phase = models.RecomputeWriting
+	report()
+	for batch := range slices.Chunk(slices.Sorted(maps.Keys(keys)), recomputeBatchSize) {
+		corrections, err := s.correct(ctx, job.Board, batch, want, scanned, contribute)
+		if err != nil {
+			return fmt.Errorf("correct %s: %w", job.Board, err)
+		}
+		job.Corrections += corrections
+		job.Written += corrections
+		report()
+	}
+	return nil
+}
+
+// correct writes the true scores of a batch of the board's members that
+// differ from them, and returns how many it wrote. want holds the totals
+// scanned from each user's scanned contribution; users touched since are
+// read again. It holds the derived boards' lock, so no update lands between
+// the board being read and corrected.
+func (s *LeaderboardService) correct(ctx context.Context, board string, batch []string, want map[string]boardTotal, scanned map[string]contribution, contribute func(string, store.BoardProfile) contribution) (int, error) {
+	d := s.derived
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	truth := make(map[string]boardTotal, len(batch))
+	for _, key := range batch {
+		truth[key] = want[key]
+	}
+	move := func(c contribution, sign int) {
+		if total, ok := truth[c.key]; ok && c.key != "" {
+			total.add(c, sign)
+			truth[c.key] = total
+		}
+	}
+	for username := range d.touched {
+		var profile store.BoardProfile
+		if boardKind(board) != models.BoardKindDaily {
+			var err error
+			if profile, err = d.store.Profile(ctx, username); err != nil {
+				return 0, err
+			}
+		}
+		move(scanned[username], -1)
+		move(contribute(username, profile), 1)
+	}
+
+	var ops []store.BoardOp
+	for _, key := range batch {
+		have, err := d.store.Rank(ctx, board, key)
+		onBoard := err == nil
+		if err != nil && !errors.Is(err, store.ErrUserNotFound) {
+			return 0, err
+		}
+		switch total := truth[key]; {
+		case total.users == 0 && onBoard:
+			ops = append(ops, store.BoardOp{Op: store.OpRemove, Board: board, Member: key})
+		case total.users > 0 && (!onBoard || have.User.Rating != total.value):
+			ops = append(ops, store.BoardOp{Op: store.OpSet, Board: board, Member: key, Value: total.value})
+		}
+	}
+	if err := d.store.Apply(ctx, ops); err != nil {
+		return 0, err
+	}
+	return len(ops), nil
+}
+
+// scan calls read for each user, counting them in job's progress
+func scan(users []string, job *models.BoardRecomputeJob, report func(), read func(username string)) {
+	job.Total = len(users)
+	report()
+	for i, username := range users {
+		read(username)
+		job.Scanned++
+		if (i+1)%recomputeBatchSize == 0 {
+			report()
+		}
+	}
+	report()
+}
+
+// contributor returns what a user placed on profile truly adds to board:
+//   - to a daily board, their net rating change on that day from their score
+//     history, leaving out the simulator's changes as score updates do
+//   - to a country board, their rating if they're placed in that country
+//   - to the team board, their rating toward their team's sum
+func (s *LeaderboardService) contributor(board string) func(username string, profile store.BoardProfile) contribution {
+	switch boardKind(board) {
+	case models.BoardKindDaily:
+		day := board[len(models.BoardKindDaily)+1:]
+		return func(username string, _ store.BoardProfile) contribution {
+			var c contribution
+			for _, entry := range s.history.changes(username) {
+				if entry.Source == SourceSimulator || effectiveTime(entry).UTC().Format(time.DateOnly) != day {
+					continue
+				}
+				c.key = username
+				c.value += entry.Rating - entry.PreviousRating
+			}
+			return c
+		}
+	case models.BoardKindCountry:
+		country := board[len(models.BoardKindCountry)+1:]
+		return func(username string, profile store.BoardProfile) contribution {
+			if profile.Country != country {
+				return contribution{}
+			}
+			return s.ratingToward(username, username)
+		}
+	default:
+		return func(username string, profile store.BoardProfile) contribution {
+			if profile.Team == "" {
+				return contribution{}
+			}
+			return s.ratingToward(username, profile.Team)
+		}
+	}
+}
+
+// ratingToward is username's rating counted on member key, or nothing if
+// they've left the main board
+func (s *LeaderboardService) ratingToward(username, key string) contribution {
+	user, err := s.store.GetUser(username)
+	if err != nil {
+		return contribution{}
+	}
+	return contribution{key: key, value: user.Rating}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"testing"
+
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+// boardScores returns every member of a derived board with their score
+func boardScores(t *testing.T, boards store.Boards, board string) map[string]int {
+	t.Helper()
+	page, _, err := boards.Page(context.Background(), board, 0, math.MaxInt32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scores := make(map[string]int, len(page))
+	for _, member := range page {
+		scores[member.User.Username] = member.User.Rating
+	}
+	return scores
+}
+
+// playersStore holds n players, named player_0 and on, rated 100
+func playersStore(t *testing.T, n int) *store.MemoryStore {
+	t.Helper()
+	st := store.NewMemoryStore()
+	for i := range n {
+		if err := st.AddUser(fmt.Sprintf("player_%d", i), 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return st
+}
+
+func TestRecomputeKeepsUpdatesMadeWhileItRuns(t *testing.T) {
+	const players = 3 * recomputeBatchSize
+	s := NewLeaderboardService(playersStore(t, players))
+	boards := store.NewMemoryBoards()
+	if err := s.EnableDerivedBoards(DerivedBoardsConfig{Store: boards}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	teams := []string{"red", "blue", "green"}
+	for i := range players {
+		req := models.UpdateScoreRequest{Rating: 1000 + i, Country: "US", Team: teams[i%len(teams)]}
+		if _, err := s.SubmitScore(ctx, fmt.Sprintf("player_%d", i), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := boards.Apply(ctx, []store.BoardOp{
+		{Op: store.OpAdd, Board: teamsBoard, Member: "red", Value: 500},
+		{Op: store.OpSet, Board: teamsBoard, Member: "ghosts", Value: 1},
+		{Op: store.OpSet, Board: "country:US", Member: "player_7", Value: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Players move team and score after the job read them, part way through
+	// the reading and between the reading and the corrections
+	move := func(from, to int) {
+		for i := from; i < to; i++ {
+			req := models.UpdateScoreRequest{Rating: 2000 + i, Team: teams[(i+1)%len(teams)]}
+			if _, err := s.SubmitScore(ctx, fmt.Sprintf("player_%d", i), req); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for _, board := range []string{teamsBoard, "country:US"} {
+		moved := 0
+		job := models.BoardRecomputeJob{Board: board}
+		err := s.recompute(ctx, &job, func() {
+			switch {
+			case job.Phase == "" && job.Scanned == recomputeBatchSize && moved == 0:
+				move(0, recomputeBatchSize)
+				moved = recomputeBatchSize
+			case job.Phase == models.RecomputeWriting && moved == recomputeBatchSize:
+				move(recomputeBatchSize, players)
+				moved = players
+			}
+		})
+		if err != nil || moved != players {
+			t.Fatalf("recompute %s: %v, %d players moved", board, err, moved)
+		}
+	}
+
+	wantTeams := make(map[string]int)
+	wantUS := make(map[string]int)
+	for i := range players {
+		wantTeams[teams[(i+1)%len(teams)]] += 2000 + i
+		wantUS[fmt.Sprintf("player_%d", i)] = 2000 + i
+	}
+	if got := boardScores(t, boards, teamsBoard); !maps.Equal(got, wantTeams) {
+		t.Errorf("teams board is %v, want %v", got, wantTeams)
+	}
+	if got := boardScores(t, boards, "country:US"); !maps.Equal(got, wantUS) {
+		t.Errorf("country:US has %d members, want %d with their latest ratings", len(got), len(wantUS))
+	}
+}
+
+func TestRecomputeTiers(t *testing.T) {
+	s := NewLeaderboardService(playersStore(t, 3))
+	ctx := context.Background()
+	if _, err := s.StartBoardRecompute(tiersBoard); !errors.Is(err, ErrTiersNotConfigured) {
+		t.Fatalf("recompute without tiers = %v, want ErrTiersNotConfigured", err)
+	}
+	tiers, err := ParseTierBoundaries("bronze:1000,silver:2000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetTierBoundaries(tiers); err != nil {
+		t.Fatal(err)
+	}
+	for i, rating := range []int{1200, 1500, 2500} {
+		if _, err := s.SubmitScore(ctx, fmt.Sprintf("player_%d", i), models.UpdateScoreRequest{Rating: rating}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Knock the populations out of step with the board
+	s.segments.mu.Lock()
+	s.segments.byTier[0].add(3000, false, 1)
+	s.segments.mu.Unlock()
+
+	job, err := s.StartBoardRecompute(tiersBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job, err = s.WaitBoardRecompute(ctx, job.ID); err != nil || job.Status != models.RecomputeSucceeded || job.Scanned != 3 {
+		t.Fatalf("recompute tiers: %+v, %v", job, err)
+	}
+	stats, err := s.TierStats(ctx, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := []int64{stats.Tiers[0].Users, stats.Tiers[1].Users}; got[0] != 2 || got[1] != 1 {
+		t.Errorf("tier populations %v, want [2 1]", got)
+	}
+}
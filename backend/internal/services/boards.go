@@ -0,0 +1,494 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+var (
+	// ErrBoardsDisabled is returned for derived board lookups without derived boards enabled
+	ErrBoardsDisabled = errors.New("derived boards are not enabled")
+	// ErrDerivedBoardNotFound is returned for derived boards without members
+	ErrDerivedBoardNotFound = errors.New("derived board not found")
+	// ErrBoardsUnavailable is returned when a score update couldn't be applied
+	// to the derived boards, and so wasn't applied at all
+	ErrBoardsUnavailable = errors.New("derived boards are unavailable")
+)
+
+// DefaultDailyBoardRetention is how many daily boards are kept, today included
+const DefaultDailyBoardRetention = 7
+
+// BoardKinds are the derived boards that can be kept
+var BoardKinds = []string{models.BoardKindDaily, models.BoardKindCountry, models.BoardKindTeam}
+
+// teamsBoard is the team aggregate board's ID
+const teamsBoard = "teams"
+
+// DerivedBoardsConfig keeps boards derived from the same score updates as the
+// main board
+type DerivedBoardsConfig struct {
+	Kinds          []string     // Defaults to every kind
+	Store          store.Boards // Defaults to memory
+	DailyRetention int          // Daily boards kept, defaults to 7
+}
+
+// derivedBoards moves the derived boards with every score update made
+// through the service. The lock is held across the main board write and the
+// derived ops, so updates from this process reach the derived boards in the
+// order they reached the main board. Where each user is placed is kept in
+// the boards' store, beside the boards, and moved by the same ops.
+type derivedBoards struct {
+	mu        sync.Mutex
+	store     store.Boards
+	kinds     map[string]bool
+	retention int
+	today     string // Daily boards past retention were dropped when this day began
+
+	touched map[string]bool // Users written while a recompute runs, nil otherwise
+}
+
+// EnableDerivedBoards starts keeping daily, country and team boards. Score
+// updates land on the main board and every derived board they touch, or on
+// none of them.
+func (s *LeaderboardService) EnableDerivedBoards(config DerivedBoardsConfig) error {
+	if len(config.Kinds) == 0 {
+		config.Kinds = BoardKinds
+	}
+	if config.Store == nil {
+		config.Store = store.NewMemoryBoards()
+	}
+	if config.DailyRetention == 0 {
+		config.DailyRetention = DefaultDailyBoardRetention
+	}
+	if config.DailyRetention < 1 {
+		return fmt.Errorf("daily board retention %d must be at least 1", config.DailyRetention)
+	}
+
+	kinds := make(map[string]bool, len(config.Kinds))
+	for _, kind := range config.Kinds {
+		if !slices.Contains(BoardKinds, kind) {
+			return fmt.Errorf("unknown derived board %q, expected one of %s", kind, strings.Join(BoardKinds, ", "))
+		}
+		kinds[kind] = true
+	}
+
+	d := &derivedBoards{
+		store:     config.Store,
+		kinds:     kinds,
+		retention: config.DailyRetention,
+	}
+	s.derived = d
+	s.segments.use(profilePlacements{boards: d.store}, s.store)
+	s.events.Subscribe(func(e events.Event) {
+		switch e.Type {
+		case events.TypeUserEvicted, events.TypeUserExpired:
+			d.leave(e.Username, e.Rating)
+		}
+	})
+	return nil
+}
+
+// DerivedBoardKinds returns the derived boards kept, nil if they're off
+func (s *LeaderboardService) DerivedBoardKinds() []string {
+	if s.derived == nil {
+		return nil
+	}
+	var kinds []string
+	for _, kind := range BoardKinds {
+		if s.derived.kinds[kind] {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+type segmentsKey struct{}
+
+// withSegments places the user whose score is updated with ctx on a country
+// and team's boards; empty values keep the user's current ones
+func withSegments(ctx context.Context, country, team string) context.Context {
+	if country == "" && team == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, segmentsKey{}, store.BoardProfile{Country: country, Team: team})
+}
+
+// writeAcrossBoards runs write, which stores username's new rating on the
+// main board and returns the rating before it and whether it changed, and
+// applies the derived board ops for the change in one Boards.Apply. If the
+// derived boards reject them, the main board write is reverted and
+// ErrBoardsUnavailable returned, so the update lands everywhere or nowhere.
+func (s *LeaderboardService) writeAcrossBoards(ctx context.Context, username string, rating int, write func() (int, bool, error)) (int, bool, error) {
+	d := s.derived
+	if d == nil {
+		return write()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.touch(username)
+
+	// Read what the write may need to put back before making it
+	before, err := d.store.Profile(ctx, username)
+	if err != nil {
+		s.ReportHealth("derived_boards", err)
+		return 0, false, fmt.Errorf("%w: %v", ErrBoardsUnavailable, err)
+	}
+	after := before
+	if segments, ok := ctx.Value(segmentsKey{}).(store.BoardProfile); ok {
+		if segments.Country != "" {
+			after.Country = segments.Country
+		}
+		if segments.Team != "" {
+			after.Team = segments.Team
+		}
+	}
+	written, _ := s.store.GetUser(username)
+
+	previous, changed, err := write()
+	if err != nil || !changed {
+		return previous, changed, err
+	}
+
+	at := s.clock.Now()
+	if t := occurredAtFrom(ctx); t != nil {
+		at = *t
+	}
+	today := s.clock.Now().UTC().Format(time.DateOnly)
+
+	// The ops are applied even if the caller goes away, so the result
+	// doesn't depend on when a cancellation reached the store
+	ctx = context.WithoutCancel(ctx)
+	ops, err := d.ops(ctx, username, previous, rating, before, after, at, today)
+	if err == nil {
+		err = d.store.Apply(ctx, ops)
+	}
+	if err != nil {
+		undo := store.User{Username: username}
+		if written != nil {
+			undo = *written
+		}
+		undo.Rating = previous
+		s.revertRating(&undo, rating)
+		s.ReportHealth("derived_boards", err)
+		return previous, false, fmt.Errorf("%w: %v", ErrBoardsUnavailable, err)
+	}
+	s.ReportHealth("derived_boards", nil)
+	if written != nil {
+		s.segments.move(before.Country, after.Country, previous, written.Bot)
+	}
+	d.expire(today)
+	return previous, true, nil
+}
+
+// revertRating puts a user's rating back as before holds it, after the
+// derived boards refused a write that set it to written. A rating changed
+// again since is left alone, as is the stamp the user reached it with.
+func (s *LeaderboardService) revertRating(before *store.User, written int) {
+	reverted, err := s.store.RevertRating(before, written)
+	switch {
+	case err != nil:
+		log.Printf("⚠️  Failed to undo %s -> %d after the derived boards failed: %v", before.Username, written, err)
+	case !reverted:
+		log.Printf("⚠️  Left %s's rating as it is: it changed again before %d could be undone", before.Username, written)
+	}
+}
+
+// writeRatingsAcrossBoards stores a batch of ratings with update, which
+// returns the changes it made, and applies the derived board ops for every
+// change in one Boards.Apply, leaving out the daily boards. If the derived
+// boards reject them, every change is reverted and none returned. Nobody
+// moves country or team.
+func (s *LeaderboardService) writeRatingsAcrossBoards(ctx context.Context, update func() []store.RatingChange) []store.RatingChange {
+	d := s.derived
+	if d == nil {
+		return update()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	changes := update()
+	if len(changes) == 0 {
+		return nil
+	}
+	for _, change := range changes {
+		d.touch(change.Username)
+	}
+	at := s.clock.Now()
+	today := at.UTC().Format(time.DateOnly)
+
+	var ops []store.BoardOp
+	var err error
+	for _, change := range changes {
+		var profile store.BoardProfile
+		if profile, err = d.store.Profile(ctx, change.Username); err != nil {
+			break
+		}
+		changeOps, opsErr := d.ops(ctx, change.Username, change.Previous, change.Rating, profile, profile, at, today)
+		if err = opsErr; err != nil {
+			break
+		}
+		// Daily boards count what players did, so leave background jobs out
+		ops = append(ops, slices.DeleteFunc(changeOps, func(op store.BoardOp) bool {
+			return boardKind(op.Board) == models.BoardKindDaily
+		})...)
+	}
+	if err == nil {
+		err = d.store.Apply(ctx, ops)
+	}
+	if err != nil {
+		for _, change := range changes {
+			s.revertRating(&store.User{Username: change.Username, Rating: change.Previous, Key: change.PreviousKey}, change.Rating)
+		}
+		s.ReportHealth("derived_boards", err)
+		log.Printf("⚠️  Dropped %d rating change(s) the derived boards refused: %v", len(changes), err)
+		return nil
+	}
+	s.ReportHealth("derived_boards", nil)
+	d.expire(today)
+	return changes
+}
+
+// ops are the derived board changes for username moving from previous to
+// rating at the given time, and from one profile to another
+func (d *derivedBoards) ops(ctx context.Context, username string, previous, rating int, before, after store.BoardProfile, at time.Time, today string) ([]store.BoardOp, error) {
+	var ops []store.BoardOp
+	if d.kinds[models.BoardKindDaily] {
+		// Offline changes count toward the day they happened, unless it's
+		// already past retention
+		day := at.UTC().Format(time.DateOnly)
+		if day <= today && day > d.cutoff(today) {
+			ops = append(ops, store.BoardOp{Op: store.OpAdd, Board: dailyBoard(day), Member: username, Value: rating - previous})
+		}
+	}
+	if d.kinds[models.BoardKindCountry] {
+		if before.Country != "" && before.Country != after.Country {
+			ops = append(ops, store.BoardOp{Op: store.OpRemove, Board: countryBoard(before.Country), Member: username})
+		}
+		if after.Country != "" {
+			ops = append(ops, store.BoardOp{Op: store.OpSet, Board: countryBoard(after.Country), Member: username, Value: rating})
+		}
+	}
+	if d.kinds[models.BoardKindTeam] {
+		if before.Team == after.Team {
+			if after.Team != "" {
+				ops = append(ops, store.BoardOp{Op: store.OpAdd, Board: teamsBoard, Member: after.Team, Value: rating - previous})
+			}
+		} else {
+			if before.Team != "" {
+				op, err := d.leaveTeam(ctx, before.Team, previous)
+				if err != nil {
+					return nil, err
+				}
+				ops = append(ops, op)
+			}
+			if after.Team != "" {
+				ops = append(ops, store.BoardOp{Op: store.OpAdd, Board: teamsBoard, Member: after.Team, Value: rating})
+			}
+		}
+	}
+	if after != before {
+		ops = append(ops, store.BoardOp{Op: store.OpPlace, Member: username, Profile: after})
+	}
+	return ops, nil
+}
+
+// leaveTeam takes a member's rating off their team, or the team off the board
+// with its last member
+func (d *derivedBoards) leaveTeam(ctx context.Context, team string, rating int) (store.BoardOp, error) {
+	size, err := d.store.TeamSize(ctx, team)
+	if err != nil {
+		return store.BoardOp{}, err
+	}
+	if size <= 1 {
+		return store.BoardOp{Op: store.OpRemove, Board: teamsBoard, Member: team}, nil
+	}
+	return store.BoardOp{Op: store.OpAdd, Board: teamsBoard, Member: team, Value: -rating}, nil
+}
+
+// leave takes a user who left the main board off their country and team.
+// Their daily boards keep what they gained.
+func (d *derivedBoards) leave(username string, rating int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.touch(username)
+
+	ctx := context.Background()
+	err := func() error {
+		profile, err := d.store.Profile(ctx, username)
+		if err != nil || profile == (store.BoardProfile{}) {
+			return err
+		}
+		var ops []store.BoardOp
+		if d.kinds[models.BoardKindCountry] && profile.Country != "" {
+			ops = append(ops, store.BoardOp{Op: store.OpRemove, Board: countryBoard(profile.Country), Member: username})
+		}
+		if d.kinds[models.BoardKindTeam] && profile.Team != "" {
+			op, err := d.leaveTeam(ctx, profile.Team, rating)
+			if err != nil {
+				return err
+			}
+			ops = append(ops, op)
+		}
+		ops = append(ops, store.BoardOp{Op: store.OpPlace, Member: username})
+		return d.store.Apply(ctx, ops)
+	}()
+	if err != nil {
+		log.Printf("⚠️  Failed to take %s off the derived boards: %v", username, err)
+	}
+}
+
+// touch notes that username's scores or placement may change, so a running
+// recompute reads them again. Must be called with the lock held.
+func (d *derivedBoards) touch(username string) {
+	if d.touched != nil {
+		d.touched[username] = true
+	}
+}
+
+// cutoff is the newest day whose board is past retention on today
+func (d *derivedBoards) cutoff(today string) string {
+	t, _ := time.Parse(time.DateOnly, today)
+	return t.AddDate(0, 0, -d.retention).Format(time.DateOnly)
+}
+
+// expire drops daily boards past retention the first time it's called each
+// day. Must be called with the lock held.
+func (d *derivedBoards) expire(today string) {
+	if !d.kinds[models.BoardKindDaily] || today == d.today {
+		return
+	}
+	boards, err := d.store.List(context.Background())
+	if err != nil {
+		log.Printf("⚠️  Failed to list derived boards for retention: %v", err)
+		return
+	}
+	cutoff := d.cutoff(today)
+	var expired []string
+	for id := range boards {
+		if day, ok := strings.CutPrefix(id, models.BoardKindDaily+":"); ok && day <= cutoff {
+			expired = append(expired, id)
+		}
+	}
+	if err := d.store.Drop(context.Background(), expired...); err != nil {
+		log.Printf("⚠️  Failed to drop daily boards past retention: %v", err)
+		return
+	}
+	d.today = today
+}
+
+func dailyBoard(day string) string {
+	return models.BoardKindDaily + ":" + day
+}
+
+func countryBoard(country string) string {
+	return models.BoardKindCountry + ":" + country
+}
+
+// boardKind returns the kind of derived board id
+func boardKind(id string) string {
+	if id == teamsBoard {
+		return models.BoardKindTeam
+	}
+	kind, _, _ := strings.Cut(id, ":")
+	return kind
+}
+
+// ListDerivedBoards lists the derived boards that have members
+func (s *LeaderboardService) ListDerivedBoards(ctx context.Context) (*models.DerivedBoardList, error) {
+	if s.derived == nil {
+		return nil, ErrBoardsDisabled
+	}
+	boards, err := s.derived.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := &models.DerivedBoardList{Boards: make([]models.DerivedBoard, 0, len(boards))}
+	for id, members := range boards {
+		list.Boards = append(list.Boards, models.DerivedBoard{ID: id, Kind: boardKind(id), Members: members})
+	}
+	slices.SortFunc(list.Boards, func(a, b models.DerivedBoard) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return list, nil
+}
+
+// GetDerivedBoard returns a page of a derived board, ties sharing a rank.
+// Users hidden from the leaderboard are left out of daily and country
+// boards, still counted in the ranks below them, and anonymized ones listed
+// under their alias.
+func (s *LeaderboardService) GetDerivedBoard(ctx context.Context, id string, page, limit int) (*models.DerivedBoardResponse, error) {
+	if s.derived == nil {
+		return nil, ErrBoardsDisabled
+	}
+	offset := (page - 1) * limit
+	members, more, total, err := s.derivedPage(ctx, id, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return nil, ErrDerivedBoardNotFound
+	}
+
+	entries := make([]models.DerivedBoardEntry, 0, len(members))
+	for _, member := range members {
+		entry := models.DerivedBoardEntry{
+			Rank:   member.Rank,
+			Member: member.User.Username,
+			Score:  member.User.Rating,
+		}
+		if boardKind(id) != models.BoardKindTeam {
+			entry.Member, entry.Anonymous, _ = s.privacy.listed(member.User.Username)
+		}
+		entries = append(entries, entry)
+	}
+	return &models.DerivedBoardResponse{
+		DerivedBoard: models.DerivedBoard{ID: id, Kind: boardKind(id), Members: total},
+		Entries:      entries,
+		Page:         page,
+		Limit:        limit,
+		HasMore:      more,
+	}, nil
+}
+
+// derivedPage returns limit listed members of a derived board from offset,
+// whether more are listed past them, and the board's size. Once anyone has
+// privacy settings, pages of user boards are walked to from the top, a
+// stretch at a time, with hidden users left out, so they stay full.
+func (s *LeaderboardService) derivedPage(ctx context.Context, id string, offset, limit int) (page []store.RankedUser, more bool, total int, err error) {
+	if boardKind(id) == models.BoardKindTeam || !s.privacy.any() {
+		page, total, err = s.derived.store.Page(ctx, id, offset, limit)
+		return page, offset+len(page) < total, total, err
+	}
+	stretch := offset + limit + 1
+	skipped := 0
+	for from := 0; ; from += stretch {
+		var members []store.RankedUser
+		if members, total, err = s.derived.store.Page(ctx, id, from, stretch); err != nil {
+			return nil, false, 0, err
+		}
+		for _, member := range members {
+			switch _, _, ok := s.privacy.listed(member.User.Username); {
+			case !ok:
+			case skipped < offset:
+				skipped++
+			case len(page) == limit:
+				return page, true, total, nil
+			default:
+				page = append(page, member)
+			}
+		}
+		if from+stretch >= total {
+			return page, false, total, nil
+		}
+	}
+}
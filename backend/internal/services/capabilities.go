@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+// Capabilities reports which optional features this deployment serves, so
+// one frontend build can adapt to differently configured backends. Login is
+// filled in by the handler, which owns authentication.
+func (s *LeaderboardService) Capabilities(ctx context.Context) *models.Capabilities {
+	strategy := s.ratingStrategy()
+	caps := &models.Capabilities{
+		BoardID:        s.boardID,
+		StoreBackend:   s.StoreBackend(),
+		RatingStrategy: strategy.Name(),
+		Features: models.CapabilityFeatures{
+			WebSockets:    true,
+			History:       true,
+			Seasons:       true,
+			Tiers:         len(s.TierBoundaries()) > 0,
+			MatchEngine:   usesMatchResults(strategy),
+			AsyncWrites:   s.scoreQueue != nil,
+			Reports:       s.reports != nil,
+			Imports:       s.imports != nil,
+			SignedScores:  s.integrations != nil,
+			RateLimits:    s.RateLimited(),
+			OfflineSync:   true,
+			DerivedBoards: s.derived != nil,
+			Guests:        s.guests != nil,
+			Cutoffs:       s.cutoffs != nil,
+		},
+	}
+	if s.scoreQueue != nil {
+		caps.AsyncBackend = "memory"
+		if _, ok := s.scoreQueue.(*redisScoreQueue); ok {
+			caps.AsyncBackend = "redis"
+		}
+	}
+	return caps
+}
+
+// StoreBackend names where the board is kept: "redis" for a store.RedisStore,
+// "sharded" for a store.ShardedStore, else "memory"
+func (s *LeaderboardService) StoreBackend() string {
+	switch s.store.(type) {
+	case *store.RedisStore:
+		return "redis"
+	case *store.ShardedStore:
+		return "sharded"
+	}
+	return "memory"
+}
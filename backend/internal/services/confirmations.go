@@ -0,0 +1,91 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+)
+
+// ConfirmationTTL is how long a confirmation token can be redeemed
+const ConfirmationTTL = time.Minute
+
+// ErrConfirmationInvalid is returned for a confirmation token that is
+// unknown, expired, already used or issued for another operation or scope
+var ErrConfirmationInvalid = errors.New("confirmation token is invalid")
+
+// Destructive operations that require a confirmation token
+const (
+	OperationCloseSeason = "season.close"
+	OperationMergeUsers  = "users.merge"
+)
+
+type pendingConfirmation struct {
+	operation string
+	scope     string
+	expiresAt time.Time
+}
+
+// confirmations holds the tokens issued for destructive operations. A token
+// is bound to what it would act on, so a token issued for one season can't
+// close the next.
+type confirmations struct {
+	mu      sync.Mutex
+	pending map[string]pendingConfirmation
+}
+
+func newConfirmations() *confirmations {
+	return &confirmations{pending: make(map[string]pendingConfirmation)}
+}
+
+// RequestConfirmation issues a token that confirms operation on scope once
+// within ConfirmationTTL
+func (s *LeaderboardService) RequestConfirmation(operation, scope string) models.ConfirmationRequired {
+	c := s.confirmations
+	now := s.clock.Now()
+	token := newConfirmationToken()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for t, p := range c.pending {
+		if !now.Before(p.expiresAt) {
+			delete(c.pending, t)
+		}
+	}
+	expiresAt := now.Add(ConfirmationTTL).UTC()
+	c.pending[token] = pendingConfirmation{operation: operation, scope: scope, expiresAt: expiresAt}
+
+	return models.ConfirmationRequired{
+		Operation:         operation,
+		Scope:             scope,
+		ConfirmationToken: token,
+		ExpiresAt:         expiresAt,
+	}
+}
+
+// Confirm redeems token for operation on scope. A token is used up by its
+// first redemption, whether or not it matches.
+func (s *LeaderboardService) Confirm(token, operation, scope string) error {
+	c := s.confirmations
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.pending[token]
+	if !ok {
+		return ErrConfirmationInvalid
+	}
+	delete(c.pending, token)
+	if p.operation != operation || p.scope != scope || !s.clock.Now().Before(p.expiresAt) {
+		return ErrConfirmationInvalid
+	}
+	return nil
+}
+
+func newConfirmationToken() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+// maxCutoffChanges bounds the announced cutoff changes kept for late readers
+const maxCutoffChanges = 50
+
+// ErrCutoffsNotConfigured is returned when cutoffs are requested without any being watched
+var ErrCutoffsNotConfigured = errors.New("no cutoffs are watched")
+
+// WatchedCutoff is a rank whose rating is tracked, such as the last place
+// that qualifies for a tournament. A move of more than Threshold from the
+// rating last announced is announced.
+type WatchedCutoff struct {
+	Rank      int
+	Threshold int
+}
+
+// ParseWatchedCutoffs reads watched cutoffs such as "100:25,1000:50", each
+// a rank and the move in rating worth announcing
+func ParseWatchedCutoffs(spec string) ([]WatchedCutoff, error) {
+	var cutoffs []WatchedCutoff
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		rank, threshold, ok := strings.Cut(item, ":")
+		r, err1 := strconv.Atoi(rank)
+		t, err2 := strconv.Atoi(threshold)
+		if !ok || err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("cutoff %q: expected rank:threshold", item)
+		}
+		cutoffs = append(cutoffs, WatchedCutoff{Rank: r, Threshold: t})
+	}
+	return cutoffs, nil
+}
+
+// watchedCutoffState is a watched cutoff's current and announced rating
+type watchedCutoffState struct {
+	WatchedCutoff
+	rating    int // 0 while the rank is unfilled
+	announced int
+	changedAt *time.Time
+}
+
+// cutoffWatcher keeps the rating at each watched rank current as events
+// arrive, and announces the moves that pass a cutoff's threshold
+type cutoffWatcher struct {
+	mu        sync.Mutex
+	store     store.Store
+	cutoffs   []*watchedCutoffState // By rank
+	changes   []models.CutoffChange // newest last
+	nextID    int
+	listeners map[int]func(models.CutoffChange)
+}
+
+// EnableCutoffWatch starts tracking the rating at each of the given ranks.
+// Ranks must be distinct and thresholds can't be negative; a threshold of 0
+// announces every move.
+func (s *LeaderboardService) EnableCutoffWatch(cutoffs []WatchedCutoff) error {
+	if len(cutoffs) == 0 {
+		return errors.New("at least one cutoff must be watched")
+	}
+	w := &cutoffWatcher{store: s.store, listeners: make(map[int]func(models.CutoffChange))}
+	for _, cutoff := range cutoffs {
+		switch {
+		case cutoff.Rank < 1:
+			return fmt.Errorf("cutoff rank %d must be at least 1", cutoff.Rank)
+		case cutoff.Threshold < 0:
+			return fmt.Errorf("cutoff at rank %d: threshold %d can't be negative", cutoff.Rank, cutoff.Threshold)
+		case slices.ContainsFunc(w.cutoffs, func(c *watchedCutoffState) bool { return c.Rank == cutoff.Rank }):
+			return fmt.Errorf("cutoff rank %d is watched twice", cutoff.Rank)
+		}
+		rating := s.store.RatingAt(cutoff.Rank)
+		w.cutoffs = append(w.cutoffs, &watchedCutoffState{WatchedCutoff: cutoff, rating: rating, announced: rating})
+	}
+	slices.SortFunc(w.cutoffs, func(a, b *watchedCutoffState) int { return a.Rank - b.Rank })
+
+	s.cutoffs = w
+	s.events.Subscribe(w.Handle)
+	return nil
+}
+
+// reset reads every cutoff from the board again, as announced, after the
+// board was replaced without events
+func (w *cutoffWatcher) reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, c := range w.cutoffs {
+		c.rating = w.store.RatingAt(c.Rank)
+		c.announced = c.rating
+	}
+}
+
+// Handle moves the cutoffs; it is subscribed to the service's event bus
+func (w *cutoffWatcher) Handle(e events.Event) {
+	switch e.Type {
+	case events.TypeUserAdded, events.TypeScoreUpdated, events.TypeUserEvicted, events.TypeUserExpired:
+	default:
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, c := range w.cutoffs {
+		// Ratings below a filled cutoff only move places under it
+		moved := c.rating == 0 || e.Rating >= c.rating ||
+			(e.Type == events.TypeScoreUpdated && e.PreviousRating >= c.rating)
+		if !moved {
+			continue
+		}
+		c.rating = w.store.RatingAt(c.Rank)
+
+		delta := c.rating - c.announced
+		if max(delta, -delta) <= c.Threshold {
+			continue
+		}
+		change := models.CutoffChange{Rank: c.Rank, Rating: c.rating, PreviousRating: c.announced, Delta: delta, At: e.Timestamp}
+		at := e.Timestamp
+		c.announced, c.changedAt = c.rating, &at
+		w.changes = append(w.changes, change)
+		if len(w.changes) > maxCutoffChanges {
+			w.changes = append([]models.CutoffChange(nil), w.changes[len(w.changes)-maxCutoffChanges:]...)
+		}
+		for _, fn := range w.listeners {
+			fn(change)
+		}
+	}
+}
+
+// Cutoffs returns the watched cutoffs and their recent announced changes
+func (s *LeaderboardService) Cutoffs(ctx context.Context) (*models.CutoffsResponse, error) {
+	w := s.cutoffs
+	if w == nil {
+		return nil, ErrCutoffsNotConfigured
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	response := &models.CutoffsResponse{
+		Cutoffs: make([]models.Cutoff, 0, len(w.cutoffs)),
+		Changes: make([]models.CutoffChange, 0, len(w.changes)),
+	}
+	for _, c := range w.cutoffs {
+		response.Cutoffs = append(response.Cutoffs, models.Cutoff{
+			Rank:            c.Rank,
+			Rating:          c.rating,
+			Threshold:       c.Threshold,
+			AnnouncedRating: c.announced,
+			ChangedAt:       c.changedAt,
+		})
+	}
+	for i := len(w.changes) - 1; i >= 0; i-- {
+		response.Changes = append(response.Changes, w.changes[i])
+	}
+	return response, nil
+}
+
+// OnCutoffChange calls fn with every announced cutoff change until the
+// returned func is called. fn runs while the event is published, so it must
+// not block. Without watched cutoffs fn is never called.
+func (s *LeaderboardService) OnCutoffChange(fn func(models.CutoffChange)) func() {
+	w := s.cutoffs
+	if w == nil {
+		return func() {}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	id := w.nextID
+	w.nextID++
+	w.listeners[id] = fn
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.listeners, id)
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+
+	"backend/internal/models"
+)
+
+// ErrDeadLetterNotFound is returned for an unknown dead letter, or one
+// already retried or discarded
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// ListDeadLetters returns failed webhook deliveries and dead-lettered
+// submissions of kind ("" for every kind), newest first, without payloads
+func (s *LeaderboardService) ListDeadLetters(ctx context.Context, kind string) ([]models.DeadLetter, error) {
+	letters := []models.DeadLetter{}
+	if kind == "" || kind == models.DeadLetterWebhook {
+		letters = append(letters, s.webhookDeadLetters(false)...)
+	}
+	if (kind == "" || kind == models.DeadLetterScore) && s.scoreQueue != nil {
+		scores, err := s.scoreQueue.listDeadLetters(ctx)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, scores...)
+	}
+
+	slices.SortStableFunc(letters, func(a, b models.DeadLetter) int {
+		return b.FailedAt.Compare(a.FailedAt)
+	})
+	return letters, nil
+}
+
+// GetDeadLetter returns a dead letter with its payload and error history
+func (s *LeaderboardService) GetDeadLetter(ctx context.Context, id string) (*models.DeadLetter, error) {
+	kind, key, _ := strings.Cut(id, ":")
+	switch kind {
+	case models.DeadLetterWebhook:
+		for _, letter := range s.webhookDeadLetters(true) {
+			if letter.ID == id {
+				return &letter, nil
+			}
+		}
+	case models.DeadLetterScore:
+		if s.scoreQueue != nil {
+			return s.scoreQueue.getDeadLetter(ctx, key)
+		}
+	}
+	return nil, ErrDeadLetterNotFound
+}
+
+// RetryDeadLetter delivers a failed webhook again, with a fresh set of
+// attempts, or puts a dead-lettered submission back on the queue
+func (s *LeaderboardService) RetryDeadLetter(ctx context.Context, id string) error {
+	kind, key, _ := strings.Cut(id, ":")
+	switch kind {
+	case models.DeadLetterWebhook:
+		if wh := s.webhooks; wh != nil {
+			if delivery := wh.takeFailed(key, true); delivery != nil {
+				go s.deliver(wh, delivery)
+				return nil
+			}
+		}
+	case models.DeadLetterScore:
+		if s.scoreQueue != nil {
+			return s.scoreQueue.retryDeadLetter(ctx, key)
+		}
+	}
+	return ErrDeadLetterNotFound
+}
+
+// DiscardDeadLetter forgets a failed webhook delivery or removes a
+// submission from the dead-letter stream
+func (s *LeaderboardService) DiscardDeadLetter(ctx context.Context, id string) error {
+	kind, key, _ := strings.Cut(id, ":")
+	switch kind {
+	case models.DeadLetterWebhook:
+		if wh := s.webhooks; wh != nil && wh.takeFailed(key, false) != nil {
+			return nil
+		}
+	case models.DeadLetterScore:
+		if s.scoreQueue != nil {
+			return s.scoreQueue.discardDeadLetter(ctx, key)
+		}
+	}
+	return ErrDeadLetterNotFound
+}
+
+// RetryDeadLetters retries the dead letters listed in ids, or every one of
+// kind ("all" for every kind)
+func (s *LeaderboardService) RetryDeadLetters(ctx context.Context, ids []string, kind string) (*models.DeadLetterActionResponse, error) {
+	return s.eachDeadLetter(ctx, ids, kind, s.RetryDeadLetter)
+}
+
+// DiscardDeadLetters discards the dead letters listed in ids, or every one
+// of kind ("all" for every kind)
+func (s *LeaderboardService) DiscardDeadLetters(ctx context.Context, ids []string, kind string) (*models.DeadLetterActionResponse, error) {
+	return s.eachDeadLetter(ctx, ids, kind, s.DiscardDeadLetter)
+}
+
+func (s *LeaderboardService) eachDeadLetter(ctx context.Context, ids []string, kind string, action func(context.Context, string) error) (*models.DeadLetterActionResponse, error) {
+	if len(ids) == 0 {
+		if kind == "all" {
+			kind = ""
+		}
+		letters, err := s.ListDeadLetters(ctx, kind)
+		if err != nil {
+			return nil, err
+		}
+		for _, letter := range letters {
+			ids = append(ids, letter.ID)
+		}
+	}
+
+	result := &models.DeadLetterActionResponse{NotFound: []string{}}
+	for _, id := range ids {
+		switch err := action(ctx, id); {
+		case err == nil:
+			result.Succeeded++
+		case errors.Is(err, ErrDeadLetterNotFound):
+			result.NotFound = append(result.NotFound, id)
+		default:
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// webhookDeadLetters describes the deliveries that ran out of attempts
+func (s *LeaderboardService) webhookDeadLetters(detail bool) []models.DeadLetter {
+	wh := s.webhooks
+	if wh == nil {
+		return nil
+	}
+
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+	var letters []models.DeadLetter
+	for _, delivery := range wh.deliveries {
+		if delivery.Status != models.DeliveryFailed {
+			continue
+		}
+		letter := models.DeadLetter{
+			ID:       models.DeadLetterWebhook + ":" + delivery.ID,
+			Kind:     models.DeadLetterWebhook,
+			Target:   delivery.URL,
+			Reason:   delivery.LastError,
+			Attempts: delivery.Attempts,
+			FailedAt: delivery.UpdatedAt,
+		}
+		if detail {
+			letter.Payload = json.RawMessage(delivery.body)
+			letter.Errors = slices.Clone(delivery.history)
+		}
+		letters = append(letters, letter)
+	}
+	return letters
+}
+
+// takeFailed finds a failed delivery by ID and either marks it pending again
+// for a retry or forgets it
+func (wh *seasonWebhooks) takeFailed(id string, retry bool) *webhookDelivery {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	for i, delivery := range wh.deliveries {
+		if delivery.ID != id || delivery.Status != models.DeliveryFailed {
+			continue
+		}
+		if retry {
+			delivery.Status = models.DeliveryPending
+		} else {
+			wh.deliveries = slices.Delete(wh.deliveries, i, i+1)
+		}
+		return delivery
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrMatchInProgress is returned when the same match is submitted again
+	// while the first submission is still being applied
+	ErrMatchInProgress = errors.New("match submission in progress")
+	// ErrMatchLedgerUnavailable is returned when the match ledger can't be
+	// consulted, so the submission wasn't applied
+	ErrMatchLedgerUnavailable = errors.New("match ledger unavailable")
+)
+
+// matchClaimLease bounds how long a claim outlives a replica that dies
+// while applying the match, after which a retry may apply it
+const matchClaimLease = time.Minute
+
+// MatchLedger remembers which match IDs have already been applied so
+// game-server retries don't change a rating twice. Claim behaves like SETNX:
+// only the first caller for a key gets to apply the update.
+type MatchLedger interface {
+	// Claim reserves key. If it was already claimed, the stored rating is
+	// returned with claimed=false (done=false while still in progress).
+	Claim(ctx context.Context, key string) (rating int, done bool, claimed bool, err error)
+	// Complete records the result of a claimed key
+	Complete(ctx context.Context, key string, rating int) error
+	// Release drops a claim whose update failed so it can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// MatchMover is implemented by ledgers that can hand one user's applied
+// matches to another when the two are merged, so retries of those matches
+// are still recognised. Ledgers without it keep them under the merged user,
+// where they expire unused.
+type MatchMover interface {
+	// MoveMatches moves from's keys to into, keeping into's own where both
+	// have one, and returns how many moved
+	MoveMatches(from, into string) int
+}
+
+type ledgerEntry struct {
+	rating    int
+	done      bool
+	expiresAt time.Time
+}
+
+// memoryMatchLedger is a process-local MatchLedger with per-entry TTL
+type memoryMatchLedger struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[string]*ledgerEntry
+	lastSweep time.Time
+}
+
+// NewMemoryMatchLedger creates an in-memory ledger that forgets match IDs after ttl
+func NewMemoryMatchLedger(ttl time.Duration) MatchLedger {
+	return &memoryMatchLedger{
+		ttl:       ttl,
+		entries:   make(map[string]*ledgerEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *memoryMatchLedger) Claim(ctx context.Context, key string) (int, bool, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	if entry, ok := l.entries[key]; ok && now.Before(entry.expiresAt) {
+		return entry.rating, entry.done, false, nil
+	}
+
+	l.entries[key] = &ledgerEntry{expiresAt: now.Add(l.ttl)}
+	return 0, false, true, nil
+}
+
+func (l *memoryMatchLedger) Complete(ctx context.Context, key string, rating int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if entry, ok := l.entries[key]; ok {
+		entry.rating = rating
+		entry.done = true
+	}
+	return nil
+}
+
+func (l *memoryMatchLedger) Release(ctx context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+	return nil
+}
+
+func (l *memoryMatchLedger) MoveMatches(from, into string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var keys []string
+	for key := range l.entries {
+		if strings.HasPrefix(key, from+":") {
+			keys = append(keys, key)
+		}
+	}
+	moved := 0
+	for _, key := range keys {
+		entry := l.entries[key]
+		delete(l.entries, key)
+		target := into + strings.TrimPrefix(key, from)
+		if _, taken := l.entries[target]; !taken {
+			l.entries[target] = entry
+			moved++
+		}
+	}
+	return moved
+}
+
+// sweep drops expired entries at most once per TTL
+func (l *memoryMatchLedger) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.ttl {
+		return
+	}
+	l.lastSweep = now
+
+	for key, entry := range l.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(l.entries, key)
+		}
+	}
+}
+
+// completeMatch records an applied match. The update stands either way;
+// without the record, a retry is only caught until the claim lapses.
+func (s *LeaderboardService) completeMatch(ctx context.Context, key string, rating int) {
+	if err := s.matches.Complete(context.WithoutCancel(ctx), key, rating); err != nil {
+		log.Printf("⚠️  Applied match %s but couldn't record it, so a retry may apply it again: %v", key, err)
+	}
+}
+
+// releaseMatch drops the claim on a match that wasn't applied, so a retry
+// can apply it. Should this fail, the retry waits for the claim to lapse.
+func (s *LeaderboardService) releaseMatch(ctx context.Context, key string) {
+	if err := s.matches.Release(context.WithoutCancel(ctx), key); err != nil {
+		log.Printf("⚠️  Failed to release the claim on match %s: %v", key, err)
+	}
+}
+
+// redisMatchLedger keeps the ledger in Redis so every replica, and a
+// restarted one, sees it. A claim is an empty value set with NX for
+// matchClaimLease; a completed entry holds the rating for ttl.
+type redisMatchLedger struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisMatchLedger creates a ledger under "<prefix>matches:<user>:<match>"
+// keys that forgets match IDs after ttl
+func NewRedisMatchLedger(client redis.UniversalClient, prefix string, ttl time.Duration) MatchLedger {
+	return &redisMatchLedger{client: client, prefix: prefix + "matches:", ttl: ttl}
+}
+
+func (l *redisMatchLedger) Claim(ctx context.Context, key string) (int, bool, bool, error) {
+	claimed, err := l.client.SetNX(ctx, l.prefix+key, "", matchClaimLease).Result()
+	if err != nil || claimed {
+		return 0, false, claimed, err
+	}
+
+	value, err := l.client.Get(ctx, l.prefix+key).Result()
+	if errors.Is(err, redis.Nil) || (err == nil && value == "") {
+		// Being applied elsewhere, or its claim lapsed between the two calls
+		return 0, false, false, nil
+	}
+	if err != nil {
+		return 0, false, false, err
+	}
+	rating, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false, false, err
+	}
+	return rating, true, false, nil
+}
+
+func (l *redisMatchLedger) Complete(ctx context.Context, key string, rating int) error {
+	return l.client.Set(ctx, l.prefix+key, strconv.Itoa(rating), l.ttl).Err()
+}
+
+func (l *redisMatchLedger) Release(ctx context.Context, key string) error {
+	return l.client.Del(ctx, l.prefix+key).Err()
+}
+
+// MoveMatches renames from's keys to into's, leaving into's own in place.
+// Keys that fail to move stay under from, where they expire unused.
+func (l *redisMatchLedger) MoveMatches(from, into string) int {
+	ctx := context.Background()
+	fromPrefix := l.prefix + from + ":"
+	iter := l.client.Scan(ctx, 0, escapeGlob(fromPrefix)+"*", 100).Iterator()
+	moved := 0
+	for iter.Next(ctx) {
+		key := iter.Val()
+		target := l.prefix + into + ":" + strings.TrimPrefix(key, fromPrefix)
+		renamed, err := l.client.RenameNX(ctx, key, target).Result()
+		if err != nil {
+			log.Printf("⚠️  Failed to move match %s to %s: %v", key, into, err)
+			continue
+		}
+		if renamed {
+			moved++
+		} else {
+			l.client.Del(ctx, key)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		log.Printf("⚠️  Failed to list the matches of %s: %v", from, err)
+	}
+	return moved
+}
+
+// escapeGlob quotes the characters SCAN's MATCH pattern treats specially
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
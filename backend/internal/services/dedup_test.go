@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisMatchLedgerIsSharedByReplicas(t *testing.T) {
+	mr := miniredis.RunT(t)
+	st := playersStore(t, 1)
+	ctx := context.Background()
+	// Each replica, and the one started after them, has its own connection
+	replica := func() *LeaderboardService {
+		s := NewLeaderboardService(st)
+		s.SetMatchLedger(NewRedisMatchLedger(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "test:", time.Hour))
+		return s
+	}
+	first, second := replica(), replica()
+
+	if result, err := first.SubmitScore(ctx, "player_0", models.UpdateScoreRequest{Rating: 200, MatchID: "m1"}); err != nil || result.Duplicate {
+		t.Fatalf("first submission: %+v, %v; want it applied", result, err)
+	}
+	for name, s := range map[string]*LeaderboardService{"another replica": second, "a restarted replica": replica()} {
+		result, err := s.SubmitScore(ctx, "player_0", models.UpdateScoreRequest{Rating: 300, MatchID: "m1"})
+		if err != nil || !result.Duplicate || result.Rating != 200 {
+			t.Errorf("retry on %s: %+v, %v; want the first result as a duplicate", name, result, err)
+		}
+	}
+
+	// A match being applied elsewhere is in progress, not applied again
+	if _, _, claimed, err := NewRedisMatchLedger(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "test:", time.Hour).Claim(ctx, "player_0:m2"); err != nil || !claimed {
+		t.Fatalf("claim: %t, %v", claimed, err)
+	}
+	if _, err := second.SubmitScore(ctx, "player_0", models.UpdateScoreRequest{Rating: 400, MatchID: "m2"}); !errors.Is(err, ErrMatchInProgress) {
+		t.Errorf("match claimed elsewhere: %v, want ErrMatchInProgress", err)
+	}
+	mr.FastForward(matchClaimLease)
+	if result, err := second.SubmitScore(ctx, "player_0", models.UpdateScoreRequest{Rating: 400, MatchID: "m2"}); err != nil || result.Duplicate {
+		t.Errorf("after the claim lapsed: %+v, %v; want it applied", result, err)
+	}
+
+	// Without the ledger, a match is refused rather than risk applying it twice
+	mr.Close()
+	if _, err := first.SubmitScore(ctx, "player_0", models.UpdateScoreRequest{Rating: 500, MatchID: "m3"}); !errors.Is(err, ErrMatchLedgerUnavailable) {
+		t.Errorf("Redis down: %v, want ErrMatchLedgerUnavailable", err)
+	}
+	if user, _ := st.GetUser("player_0"); user.Rating != 400 {
+		t.Errorf("player_0 at %d, want 400", user.Rating)
+	}
+}
+
+func TestRedisMatchLedgerMovesMatches(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+	ledger := NewRedisMatchLedger(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "test:", time.Hour)
+	for key, rating := range map[string]int{"alice:m1": 110, "alice:m2": 120, "bob:m1": 210, "alice_2:m3": 130} {
+		if _, _, _, err := ledger.Claim(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+		if err := ledger.Complete(ctx, key, rating); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if moved := ledger.(MatchMover).MoveMatches("alice", "bob"); moved != 1 {
+		t.Errorf("moved %d, want only alice:m2 as bob has m1", moved)
+	}
+	for key, want := range map[string]int{"bob:m1": 210, "bob:m2": 120, "alice_2:m3": 130} {
+		if rating, done, _, err := ledger.Claim(ctx, key); err != nil || !done || rating != want {
+			t.Errorf("%s: rating %d, done %t, %v; want %d", key, rating, done, err, want)
+		}
+	}
+	if _, _, claimed, err := ledger.Claim(ctx, "alice:m1"); err != nil || !claimed {
+		t.Errorf("alice:m1 still applied after the move: claimed %t, %v", claimed, err)
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+// ErrDoubleWriteDisabled is returned when verification is requested without a target store
+var ErrDoubleWriteDisabled = errors.New("double-write verification is not enabled")
+
+// DefaultDoubleWriteSampleRate is the share of mirrored writes compared when unset
+const DefaultDoubleWriteSampleRate = 0.1
+
+// maxDivergences bounds the recent divergences kept for the admin endpoint
+const maxDivergences = 100
+
+// Divergence kinds reported by the double-write comparator
+const (
+	DivergenceMissing = "missing" // The user exists in only one store
+	DivergenceScore   = "score"   // The stores disagree on the rating
+	DivergenceRank    = "rank"    // Same rating, different rank
+)
+
+// DoubleWriteConfig mirrors every write to a second store during a migration
+type DoubleWriteConfig struct {
+	Target     store.Mirror // Store being migrated to
+	SampleRate float64      // Share of mirrored writes compared against the live store, defaults to 0.1
+}
+
+// doubleWrite mirrors board writes to a migration target and compares a
+// sample of them. The live store stays authoritative; mirror failures are
+// counted and never affect the live write.
+type doubleWrite struct {
+	mu           sync.Mutex
+	target       store.Mirror
+	sampleRate   float64
+	startedAt    time.Time
+	mirrored     int64
+	mirrorErrors int64
+	compared     int64
+	mismatches   map[string]int64    // kind -> count
+	recent       []models.Divergence // oldest first
+}
+
+// EnableDoubleWrite starts mirroring writes to cfg.Target. The target is
+// backfilled with the current board first, so enable it at startup to avoid
+// racing live writes.
+func (s *LeaderboardService) EnableDoubleWrite(cfg DoubleWriteConfig) error {
+	if cfg.Target == nil {
+		return errors.New("double-write target store is required")
+	}
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		return fmt.Errorf("double-write sample rate %v must be between 0 and 1", cfg.SampleRate)
+	}
+	if cfg.SampleRate == 0 {
+		cfg.SampleRate = DefaultDoubleWriteSampleRate
+	}
+
+	users, err := s.store.GetAllUsers(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if err := mirrorUser(cfg.Target, user.Username, user.Rating, user.Bot); err != nil {
+			return fmt.Errorf("backfill %s: %w", user.Username, err)
+		}
+	}
+
+	d := &doubleWrite{
+		target:     cfg.Target,
+		sampleRate: cfg.SampleRate,
+		startedAt:  time.Now().UTC(),
+		mismatches: make(map[string]int64),
+	}
+	s.doubleWrite = d
+	s.events.Subscribe(func(e events.Event) {
+		s.mirrorWrite(d, e)
+	})
+
+	log.Printf("🔁 Double-writing to migration target (%d users backfilled, comparing %.0f%% of writes)",
+		cfg.Target.GetUserCount(), cfg.SampleRate*100)
+	return nil
+}
+
+func mirrorUser(target store.Mirror, username string, rating int, bot bool) error {
+	if bot {
+		return target.AddBot(username, rating)
+	}
+	return target.AddUser(username, rating)
+}
+
+// mirrorWrite replays a board mutation on the target and samples a comparison
+func (s *LeaderboardService) mirrorWrite(d *doubleWrite, e events.Event) {
+	var err error
+	switch e.Type {
+	case events.TypeUserAdded, events.TypeScoreUpdated:
+		err = mirrorUser(d.target, e.Username, e.Rating, e.Bot)
+	case events.TypeUserExpired, events.TypeUserEvicted:
+		if err = d.target.RemoveUser(e.Username); errors.Is(err, store.ErrUserNotFound) {
+			err = nil
+		}
+	default:
+		return
+	}
+	s.ReportHealth("double_write", err)
+
+	d.mu.Lock()
+	d.mirrored++
+	if err != nil {
+		d.mirrorErrors++
+	}
+	sample := rand.Float64() < d.sampleRate
+	d.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Double-write of %s for %s failed: %v", e.Type, e.Username, err)
+		return
+	}
+	if sample {
+		s.compareMirrored(d, e.Username)
+	}
+}
+
+// compareMirrored checks one user's rating and rank in both stores
+func (s *LeaderboardService) compareMirrored(d *doubleWrite, username string) {
+	divergence := models.Divergence{Username: username, At: time.Now().UTC()}
+
+	live, liveErr := s.store.GetUser(username)
+	target, targetErr := d.target.GetUser(username)
+	if liveErr == nil {
+		divergence.LiveRating = live.Rating
+	}
+	if targetErr == nil {
+		divergence.TargetRating = target.Rating
+	}
+
+	switch {
+	case liveErr != nil && targetErr != nil:
+		// Removed from both
+	case liveErr != nil || targetErr != nil:
+		divergence.Kind = DivergenceMissing
+	case live.Rating != target.Rating:
+		divergence.Kind = DivergenceScore
+	default:
+		divergence.LiveRank, _ = s.store.GetUserRank(context.Background(), username)
+		divergence.TargetRank, _ = d.target.GetUserRank(context.Background(), username)
+		if divergence.LiveRank != divergence.TargetRank {
+			divergence.Kind = DivergenceRank
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.compared++
+	if divergence.Kind == "" {
+		return
+	}
+	d.mismatches[divergence.Kind]++
+	d.recent = append(d.recent, divergence)
+	if len(d.recent) > maxDivergences {
+		d.recent = append([]models.Divergence(nil), d.recent[len(d.recent)-maxDivergences:]...)
+	}
+}
+
+// GetDoubleWriteReport summarises mirrored writes and sampled divergences
+func (s *LeaderboardService) GetDoubleWriteReport(ctx context.Context) (*models.DoubleWriteReport, error) {
+	d := s.doubleWrite
+	if d == nil {
+		return nil, ErrDoubleWriteDisabled
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	report := &models.DoubleWriteReport{
+		StartedAt:      d.startedAt,
+		SampleRate:     d.sampleRate,
+		MirroredWrites: d.mirrored,
+		MirrorErrors:   d.mirrorErrors,
+		Compared:       d.compared,
+		Mismatches:     make(map[string]int64, len(d.mismatches)),
+		Recent:         make([]models.Divergence, 0, len(d.recent)),
+		LiveUsers:      s.store.GetUserCount(),
+		TargetUsers:    d.target.GetUserCount(),
+	}
+	var total int64
+	for kind, count := range d.mismatches {
+		report.Mismatches[kind] = count
+		total += count
+	}
+	if d.compared > 0 {
+		report.MismatchRate = float64(total) / float64(d.compared)
+	}
+	// Newest first
+	for i := len(d.recent) - 1; i >= 0; i-- {
+		report.Recent = append(report.Recent, d.recent[i])
+	}
+	return report, nil
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"backend/internal/models"
+)
+
+// Enricher decorates leaderboard entries (badges, avatars, clan tags, ...)
+// before they are serialized. Enrichers receive whole pages so they can batch
+// any lookups they need.
+type Enricher interface {
+	Enrich(ctx context.Context, entries []models.LeaderboardEntry) error
+}
+
+// EnricherFunc adapts an ordinary function to the Enricher interface
+type EnricherFunc func(ctx context.Context, entries []models.LeaderboardEntry) error
+
+// Enrich calls f(ctx, entries)
+func (f EnricherFunc) Enrich(ctx context.Context, entries []models.LeaderboardEntry) error {
+	return f(ctx, entries)
+}
+
+// enricherChain runs registered enrichers in registration order
+type enricherChain struct {
+	mu        sync.RWMutex
+	enrichers []Enricher
+}
+
+// RegisterEnricher adds an enricher that runs on every leaderboard entry
+func (s *LeaderboardService) RegisterEnricher(e Enricher) {
+	s.enrichers.mu.Lock()
+	defer s.enrichers.mu.Unlock()
+	s.enrichers.enrichers = append(s.enrichers.enrichers, e)
+}
+
+// enrich applies all registered enrichers. A failing enricher is logged and
+// skipped so a broken decoration never takes the leaderboard down.
+func (s *LeaderboardService) enrich(ctx context.Context, entries []models.LeaderboardEntry) {
+	s.enrichers.mu.RLock()
+	defer s.enrichers.mu.RUnlock()
+
+	if len(entries) == 0 {
+		return
+	}
+
+	for _, e := range s.enrichers.enrichers {
+		if err := e.Enrich(ctx, entries); err != nil {
+			log.Printf("Leaderboard enricher failed: %v", err)
+		}
+	}
+}
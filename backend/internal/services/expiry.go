@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/ranking"
+	"backend/pkg/store"
+)
+
+// StartExpirySweeper periodically removes entries whose TTL has passed
+func (s *LeaderboardService) StartExpirySweeper(ctx context.Context, interval time.Duration) {
+	ticker := s.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("⏳ Started expiry sweeper (every %s)", interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C():
+			s.sweepExpired(now)
+		}
+	}
+}
+
+// sweepExpired removes entries whose TTL passed by now. Sweeps are skipped
+// while maintenance mode is on; expired entries go on the first one after.
+func (s *LeaderboardService) sweepExpired(now time.Time) {
+	if s.writesPaused() {
+		return
+	}
+	expired := s.store.RemoveExpired(now)
+	for _, user := range expired {
+		s.events.Publish(events.Event{
+			Type:     events.TypeUserExpired,
+			Username: user.Username,
+			Rating:   user.Rating,
+			Bot:      user.Bot,
+		})
+	}
+	if len(expired) > 0 {
+		log.Printf("Expired %d entries", len(expired))
+	}
+}
+
+// expiresAt returns the user's expiry for API payloads, or nil if it never expires
+func expiresAt(user *store.User) *time.Time {
+	if user.ExpiresAt.IsZero() {
+		return nil
+	}
+	t := user.ExpiresAt
+	return &t
+}
+
+// achievedAt returns when the user reached their rating for API payloads, or
+// nil if their sort key doesn't record it
+func achievedAt(user *store.User) *time.Time {
+	_, achieved := ranking.Decode(user.Key)
+	if achieved.IsZero() {
+		return nil
+	}
+	return &achieved
+}
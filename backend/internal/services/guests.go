@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+var (
+	// ErrGuestsDisabled is returned when guest submissions aren't enabled
+	ErrGuestsDisabled = errors.New("guest submissions are disabled")
+	// ErrGuestNotFound is returned when no guest belongs to a device token
+	ErrGuestNotFound = errors.New("no guest for this device token")
+	// ErrGuestsFull is returned when a new guest would pass GuestConfig.MaxGuests
+	ErrGuestsFull = errors.New("no more guests can be created")
+)
+
+// Guest creation defaults
+const (
+	DefaultMaxGuests             = 100_000
+	DefaultGuestCreationsPerHour = 10
+)
+
+// GuestConfig caps how many guests are created. Every first submission from
+// a new device token makes a user, so without caps one client could fill
+// the board with throwaway tokens.
+type GuestConfig struct {
+	MaxGuests        int // Guests kept at once, defaults to DefaultMaxGuests
+	CreationsPerHour int // New guests one caller may create per hour, defaults to DefaultGuestCreationsPerHour
+}
+
+// GuestCreationError rejects a new guest from a caller who created too many
+// within the hour
+type GuestCreationError struct {
+	Limit        int
+	ResetSeconds int // Until the caller may create another
+}
+
+func (e *GuestCreationError) Error() string {
+	return fmt.Sprintf("over %d new guests per hour, retry after %ds", e.Limit, e.ResetSeconds)
+}
+
+// guests maps the device tokens of players who haven't registered to the
+// users made for them. Only a hash of each token is kept, so the map can't
+// be used to submit as a guest. The map is kept as store records, so a
+// guest can submit through any process on a shared store.
+type guests struct {
+	claimMu   sync.Mutex // held while a submission creates a guest, or a claim merges one
+	store     store.Store
+	max       int
+	creations *rateLimiter // New guests per caller, in the writes bucket
+}
+
+const (
+	// guestDeviceRecords maps token hashes to guest usernames
+	guestDeviceRecords = "guest_devices"
+	// guestUserRecords maps guest usernames back to token hashes
+	guestUserRecords = "guest_users"
+)
+
+func newGuests(st store.Store, config GuestConfig) *guests {
+	if config.MaxGuests <= 0 {
+		config.MaxGuests = DefaultMaxGuests
+	}
+	if config.CreationsPerHour <= 0 {
+		config.CreationsPerHour = DefaultGuestCreationsPerHour
+	}
+	creations := newRateLimiter()
+	creations.config = RateLimit{Writes: config.CreationsPerHour, Window: time.Hour}
+	return &guests{store: st, max: config.MaxGuests, creations: creations}
+}
+
+func hashDeviceToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// lookup returns the guest a token hash belongs to
+func (g *guests) lookup(hash string) (string, bool) {
+	return g.store.Record(guestDeviceRecords, hash)
+}
+
+// add gives a token hash to username, unless another process gave it a
+// guest first, and returns the guest the hash now belongs to
+func (g *guests) add(hash, username string) (string, error) {
+	owner, err := g.store.UpdateRecord(guestDeviceRecords, hash, func(current string) (string, error) {
+		if current != "" {
+			return current, nil
+		}
+		return username, nil
+	})
+	if err != nil || owner != username {
+		return owner, err
+	}
+	_, err = g.store.UpdateRecord(guestUserRecords, username, func(string) (string, error) {
+		return hash, nil
+	})
+	return owner, err
+}
+
+func (g *guests) forget(username string) error {
+	hash, ok := g.store.Record(guestUserRecords, username)
+	if !ok {
+		return nil
+	}
+	_, err := g.store.UpdateRecord(guestDeviceRecords, hash, func(current string) (string, error) {
+		if current != username {
+			return current, nil
+		}
+		return "", nil
+	})
+	if err != nil {
+		return err
+	}
+	_, err = g.store.UpdateRecord(guestUserRecords, username, func(string) (string, error) {
+		return "", nil
+	})
+	return err
+}
+
+// Handle forgets guests who leave the board, so their device's next
+// submission starts a new guest; it is subscribed to the service's event bus
+func (g *guests) Handle(e events.Event) {
+	switch e.Type {
+	case events.TypeUserEvicted, events.TypeUserExpired:
+		if err := g.forget(e.Username); err != nil {
+			log.Printf("⚠️  Failed to forget guest %s: %v", e.Username, err)
+		}
+	}
+}
+
+// EnableGuests accepts scores from players who haven't registered. Each
+// device token gets a user with a generated name on its first submission,
+// which a registered user can later claim. config caps how many are made.
+func (s *LeaderboardService) EnableGuests(config GuestConfig) {
+	s.guests = newGuests(s.store, config)
+	s.events.Subscribe(s.guests.Handle)
+}
+
+// SubmitGuestScore applies a score for the guest a device token belongs to,
+// creating the guest on the token's first submission. caller identifies who
+// submits, such as their address, for the cap on new guests per caller.
+func (s *LeaderboardService) SubmitGuestScore(ctx context.Context, caller, token string, req models.GuestScoreRequest) (*models.GuestScoreResponse, error) {
+	username, created, err := s.guestFor(caller, token)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.SubmitScore(ctx, username, models.UpdateScoreRequest{
+		Rating:         req.Rating,
+		Delta:          req.Delta,
+		OpponentRating: req.OpponentRating,
+		Result:         req.Result,
+		MatchID:        req.MatchID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.GuestScoreResponse{
+		Username:            username,
+		Created:             created,
+		UpdateScoreResponse: *result,
+	}, nil
+}
+
+// guestFor returns the guest a device token belongs to, creating one with a
+// generated name if it has none and caller may create one
+func (s *LeaderboardService) guestFor(caller, token string) (username string, created bool, err error) {
+	g := s.guests
+	if g == nil {
+		return "", false, ErrGuestsDisabled
+	}
+	hash := hashDeviceToken(token)
+	if username, ok := g.lookup(hash); ok {
+		return username, false, nil
+	}
+
+	// Serialise first submissions so one token can't create two guests
+	g.claimMu.Lock()
+	defer g.claimMu.Unlock()
+	if username, ok := g.lookup(hash); ok {
+		return username, false, nil
+	}
+	// The cap is read, not reserved, so processes sharing a store can pass
+	// it by one guest each
+	if g.store.RecordCount(guestUserRecords) >= g.max {
+		return "", false, ErrGuestsFull
+	}
+	if bucket, ok := g.creations.take(caller, BucketWrites, s.clock.Now()); !ok {
+		return "", false, &GuestCreationError{Limit: bucket.Limit, ResetSeconds: bucket.ResetSeconds}
+	}
+	username, err = s.createUniqueUser(s.random.guestName())
+	if err != nil {
+		return "", false, err
+	}
+	owner, err := g.add(hash, username)
+	if err != nil || owner != username {
+		// Another process made this token's guest first, or the token
+		// couldn't be recorded: the user just made is nobody's
+		if removeErr := s.store.RemoveUser(username); removeErr != nil {
+			log.Printf("⚠️  Failed to remove unused guest %s: %v", username, removeErr)
+		} else {
+			s.publishEviction(&store.User{Username: username, Rating: DefaultRating})
+		}
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return owner, owner == username, nil
+}
+
+// ClaimGuest merges the guest a device token belongs to into a registered
+// user, who keeps the better of the two scores and takes over the guest's
+// score history. The guest is removed, and the token's next submission
+// starts a new guest.
+func (s *LeaderboardService) ClaimGuest(ctx context.Context, username, token string) (*models.ClaimGuestResponse, error) {
+	g := s.guests
+	if g == nil {
+		return nil, ErrGuestsDisabled
+	}
+	if _, err := s.store.GetUser(username); err != nil {
+		return nil, err
+	}
+
+	g.claimMu.Lock()
+	defer g.claimMu.Unlock()
+	guest, ok := g.lookup(hashDeviceToken(token))
+	if !ok {
+		return nil, ErrGuestNotFound
+	}
+	if _, err := s.store.GetUser(guest); err != nil {
+		return nil, ErrGuestNotFound
+	}
+
+	merge, err := s.mergeUser(ctx, guest, username, mergeOptions{policy: models.MergePolicyBest})
+	if err != nil {
+		return nil, err
+	}
+	if err := g.forget(guest); err != nil {
+		log.Printf("⚠️  Failed to forget claimed guest %s: %v", guest, err)
+	}
+
+	kept := models.MergeKeptUser
+	if merge.keptMerged {
+		kept = models.MergeKeptGuest
+	}
+	return &models.ClaimGuestResponse{
+		Username:     username,
+		Guest:        guest,
+		Rating:       merge.rating,
+		GuestRating:  merge.mergedRating,
+		Kept:         kept,
+		HistoryMoved: merge.historyMoved,
+	}, nil
+}
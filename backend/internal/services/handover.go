@@ -0,0 +1,216 @@
+package services
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+// stateSnapshotVersion is bumped when StateSnapshot changes incompatibly
+const stateSnapshotVersion = 1
+
+// StateSnapshot is the state a restarted process takes over. The board and
+// the records kept beside it, such as privacy settings and guest devices,
+// are carried in Store only when kept in memory, as are derived boards; Redis
+// and sharded boards are shared by other replicas and outlive the process.
+// Caches, counters, health history and queued jobs start afresh.
+type StateSnapshot struct {
+	Version      int                              `json:"version"`
+	BoardID      string                           `json:"board_id"`
+	TakenAt      time.Time                        `json:"taken_at"`
+	Store        *store.Snapshot                  `json:"store,omitempty"` // Only of a memory board
+	History      map[string][]models.HistoryEntry `json:"history,omitempty"`
+	Joined       map[string]time.Time             `json:"joined,omitempty"`
+	Trimmed      map[string]time.Time             `json:"trimmed,omitempty"` // Newest history change dropped per user
+	Identities   []models.ExternalIdentity        `json:"identities,omitempty"`
+	Freezes      map[string]models.UserFreeze     `json:"freezes,omitempty"`
+	Notes        map[string][]models.StaffNote    `json:"notes,omitempty"`
+	Season       *models.SeasonInfo               `json:"season,omitempty"`
+	SeasonResult *models.SeasonResult             `json:"season_result,omitempty"`
+	Approvals    []models.PendingChange           `json:"approvals,omitempty"`
+	Boards       *store.BoardsSnapshot            `json:"boards,omitempty"`
+	Sessions     []auth.SessionState              `json:"sessions,omitempty"` // Filled in by whoever holds the auth
+}
+
+// Snapshot copies the service's state for a restarted process. Take it once
+// writes have stopped, or updates made meanwhile are missed.
+func (s *LeaderboardService) Snapshot() *StateSnapshot {
+	snap := &StateSnapshot{
+		Version: stateSnapshotVersion,
+		BoardID: s.boardID,
+		TakenAt: s.clock.Now().UTC(),
+		History: make(map[string][]models.HistoryEntry),
+		Joined:  make(map[string]time.Time),
+		Trimmed: make(map[string]time.Time),
+		Freezes: make(map[string]models.UserFreeze),
+		Notes:   make(map[string][]models.StaffNote),
+	}
+	if s.boardInProcess() {
+		board := s.store.Snapshot()
+		snap.Store = &board
+	}
+
+	h := s.history
+	h.mu.RLock()
+	for username, entries := range h.entries {
+		snap.History[username] = append([]models.HistoryEntry(nil), entries...)
+	}
+	for username, joined := range h.joined {
+		snap.Joined[username] = joined
+	}
+	for username, trimmed := range h.trimmed {
+		snap.Trimmed[username] = trimmed
+	}
+	h.mu.RUnlock()
+
+	m := s.identities
+	m.mu.RLock()
+	for _, link := range m.links {
+		snap.Identities = append(snap.Identities, *link)
+	}
+	m.mu.RUnlock()
+
+	mod := s.moderation
+	mod.mu.RLock()
+	for username, freeze := range mod.frozen {
+		snap.Freezes[username] = freeze
+	}
+	for username, notes := range mod.notes {
+		snap.Notes[username] = append([]models.StaffNote(nil), notes...)
+	}
+	mod.mu.RUnlock()
+
+	ss := s.season
+	ss.mu.Lock()
+	if ss.current != nil {
+		season := *ss.current
+		snap.Season = &season
+	}
+	snap.SeasonResult = ss.final
+	ss.mu.Unlock()
+
+	a := s.approvals
+	a.mu.Lock()
+	for _, id := range slices.Sorted(maps.Keys(a.pending)) {
+		snap.Approvals = append(snap.Approvals, a.pending[id])
+	}
+	a.mu.Unlock()
+
+	if s.derived != nil {
+		if boards, ok := s.derived.store.(*store.MemoryBoards); ok {
+			d := s.derived
+			d.mu.Lock()
+			copied := boards.Snapshot()
+			d.mu.Unlock()
+			snap.Boards = &copied
+		}
+	}
+	return snap
+}
+
+// boardInProcess reports whether the board lives in this process, so a
+// restarted process has to take it over
+func (s *LeaderboardService) boardInProcess() bool {
+	return s.StoreBackend() == "memory"
+}
+
+// RestoreSnapshot replaces the service's state with snap. A shared board is
+// left as it is, since other replicas may have written to it since snap was
+// taken. Call it before the service is used; nothing is published on the
+// event bus.
+func (s *LeaderboardService) RestoreSnapshot(snap *StateSnapshot) error {
+	if snap.Version != stateSnapshotVersion {
+		return fmt.Errorf("snapshot version %d is not supported (expected %d)", snap.Version, stateSnapshotVersion)
+	}
+	if snap.BoardID != s.boardID {
+		return fmt.Errorf("snapshot is of board %q, not %q", snap.BoardID, s.boardID)
+	}
+
+	if snap.Store != nil && s.boardInProcess() {
+		s.store.Restore(*snap.Store)
+	}
+
+	h := s.history
+	h.mu.Lock()
+	h.entries = make(map[string][]models.HistoryEntry, len(snap.History))
+	for username, entries := range snap.History {
+		h.entries[username] = entries
+	}
+	h.joined = make(map[string]time.Time, len(snap.Joined))
+	for username, joined := range snap.Joined {
+		h.joined[username] = joined
+	}
+	h.trimmed = make(map[string]time.Time, len(snap.Trimmed))
+	for username, trimmed := range snap.Trimmed {
+		h.trimmed[username] = trimmed
+	}
+	h.recount()
+	h.mu.Unlock()
+
+	m := s.identities
+	m.mu.Lock()
+	m.links = make(map[string]*models.ExternalIdentity, len(snap.Identities))
+	m.byUsername = make(map[string]map[string]struct{})
+	for _, restored := range snap.Identities {
+		link := restored
+		key := identityKey(link.Provider, link.ExternalID)
+		m.links[key] = &link
+		if m.byUsername[link.Username] == nil {
+			m.byUsername[link.Username] = make(map[string]struct{})
+		}
+		m.byUsername[link.Username][key] = struct{}{}
+	}
+	m.mu.Unlock()
+
+	mod := s.moderation
+	mod.mu.Lock()
+	mod.frozen = make(map[string]models.UserFreeze, len(snap.Freezes))
+	for username, freeze := range snap.Freezes {
+		mod.frozen[username] = freeze
+	}
+	mod.notes = make(map[string][]models.StaffNote, len(snap.Notes))
+	for username, notes := range snap.Notes {
+		mod.notes[username] = notes
+	}
+	mod.mu.Unlock()
+
+	// The running season carries on with its original start time
+	ss := s.season
+	ss.mu.Lock()
+	ss.current = snap.Season
+	ss.final = snap.SeasonResult
+	ss.mu.Unlock()
+
+	a := s.approvals
+	a.mu.Lock()
+	a.pending = make(map[string]models.PendingChange, len(snap.Approvals))
+	for _, change := range snap.Approvals {
+		a.pending[change.ID] = change
+	}
+	a.mu.Unlock()
+
+	if s.derived != nil && snap.Boards != nil {
+		if boards, ok := s.derived.store.(*store.MemoryBoards); ok {
+			if err := boards.Restore(*snap.Boards); err != nil {
+				return fmt.Errorf("restore derived boards: %w", err)
+			}
+		}
+	}
+
+	// Nothing has been served yet, but restoring doesn't publish the events
+	// the caches are invalidated by
+	s.statsCache.mu.Lock()
+	clear(s.statsCache.entries)
+	s.statsCache.mu.Unlock()
+	s.searchCache.reset()
+	s.segments.rebuild(s.store, s.TierBoundaries())
+	if s.cutoffs != nil {
+		s.cutoffs.reset()
+	}
+	return nil
+}
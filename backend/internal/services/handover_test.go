@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+	"backend/pkg/store"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// handoverService is a service with memory derived boards, guests and
+// approvals of adjustments over 100, as both sides of a handover run it
+func handoverService(t *testing.T, st store.Store) *LeaderboardService {
+	t.Helper()
+	s := NewLeaderboardService(st)
+	if err := s.EnableDerivedBoards(DerivedBoardsConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	s.EnableGuests(GuestConfig{})
+	s.SetApprovalThreshold(100)
+	return s
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s := handoverService(t, playersStore(t, 3))
+	for i, req := range []models.UpdateScoreRequest{
+		{Rating: 1500, Country: "US", Team: "red"},
+		{Rating: 1600, Country: "GB", Team: "red"},
+		{Rating: 1700, Team: "blue"},
+	} {
+		if _, err := s.SubmitScore(ctx, fmt.Sprintf("player_%d", i), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.SubmitGuestScore(ctx, "ip:192.0.2.1", "device-token", models.GuestScoreRequest{Rating: 1200}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SetPrivacy(ctx, "player_1", models.PrivacyRequest{Anonymize: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AdjustScore(ctx, "player_2", 2500, "appeal upheld", "mod"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.FreezeUser(ctx, "player_0", time.Hour, "investigation", "mod"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddStaffNote(ctx, "player_0", "asked for logs", "mod"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The snapshot crosses the handover as JSON
+	raw, err := json.Marshal(s.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snap StateSnapshot
+	if err := json.Unmarshal(raw, &snap); err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Approvals) != 1 || snap.Boards == nil || snap.Boards.Profiles["player_0"].Country != "US" {
+		t.Fatalf("snapshot misses approvals, boards or placements: %s", raw)
+	}
+
+	restored := handoverService(t, store.NewMemoryStore())
+	if err := restored.RestoreSnapshot(&snap); err != nil {
+		t.Fatal(err)
+	}
+	again := restored.Snapshot()
+	again.TakenAt = snap.TakenAt
+	// The store lists users in no particular order
+	for _, state := range []*StateSnapshot{again, &snap} {
+		slices.SortFunc(state.Store.Users, func(a, b store.User) int { return strings.Compare(a.Username, b.Username) })
+	}
+	got, _ := json.Marshal(again)
+	want, _ := json.Marshal(snap)
+	if string(got) != string(want) {
+		t.Errorf("restored state differs:\n got %s\nwant %s", got, want)
+	}
+
+	// What was restored is used, not just carried
+	if !restored.privacy.get("player_1").Anonymize {
+		t.Error("privacy settings were lost")
+	}
+	if _, ok := restored.guests.lookup(hashDeviceToken("device-token")); !ok {
+		t.Error("guest device was lost")
+	}
+	if size, _ := restored.derived.store.TeamSize(ctx, "red"); size != 2 {
+		t.Errorf("team red has %d members after restore, want 2", size)
+	}
+	stats := restored.CountryStats(ctx, ListOptions{})
+	if len(stats.Countries) != 2 {
+		t.Errorf("country stats lost placements: %+v", stats.Countries)
+	}
+}
+
+func TestRestoreLeavesASharedBoard(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	replica := func() (*LeaderboardService, *store.RedisStore) {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { client.Close() })
+		st, err := store.NewRedisStore(ctx, client, "lb:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return handoverService(t, st), st
+	}
+
+	// Replicas follow each other's writes through the change stream
+	caughtUp := func(st *store.RedisStore, username string, rating int) {
+		t.Helper()
+		for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(time.Millisecond) {
+			if user, err := st.GetUser(username); err == nil && user.Rating == rating {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("replica never saw %s at %d", username, rating)
+			}
+		}
+	}
+
+	old, st := replica()
+	other, otherStore := replica()
+	for _, username := range []string{"alice", "bob"} {
+		if err := st.AddUser(username, 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := old.SubmitScore(ctx, "alice", models.UpdateScoreRequest{Rating: 1500}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.FreezeUser(ctx, "alice", time.Hour, "investigation", "mod"); err != nil {
+		t.Fatal(err)
+	}
+	snap := old.Snapshot()
+	if snap.Store != nil {
+		t.Fatalf("snapshot of a Redis board carries %d users", len(snap.Store.Users))
+	}
+	// Even a snapshot of a memory board isn't written over a shared one
+	snap.Store = &store.Snapshot{Users: []store.User{{Username: "alice", Rating: 1500}}}
+
+	// Another replica writes after the snapshot was taken
+	caughtUp(otherStore, "alice", 1500)
+	if _, err := other.SubmitScore(ctx, "bob", models.UpdateScoreRequest{Rating: 1800}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.AdjustScore(ctx, "alice", 1550, "appeal upheld", "mod"); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, restoredStore := replica()
+	if err := restored.RestoreSnapshot(snap); err != nil {
+		t.Fatal(err)
+	}
+	for username, want := range map[string]int{"alice": 1550, "bob": 1800} {
+		caughtUp(restoredStore, username, want)
+		user, err := restored.GetUserRank(ctx, username)
+		if err != nil || user.Rating != want {
+			t.Errorf("%s after the restore: %+v, %v; want rating %d", username, user, err, want)
+		}
+	}
+	// Process-local state is still handed over
+	if record, err := restored.GetModerationRecord(ctx, "alice"); err != nil || record.Freeze == nil {
+		t.Errorf("alice's freeze wasn't handed over: %+v, %v", record, err)
+	}
+}
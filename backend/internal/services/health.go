@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+)
+
+// maxHealthIncidents bounds the incidents kept for GET /api/admin/health/history
+const maxHealthIncidents = 200
+
+// Incident kinds recorded by the health tracker
+const (
+	IncidentDegraded  = "degraded"  // A component started failing
+	IncidentRecovered = "recovered" // A degraded component succeeded again
+	IncidentFailure   = "failure"   // A one-off failure, e.g. a flush that failed
+	IncidentLifecycle = "lifecycle" // Startup, drain and similar transitions
+	IncidentAlert     = "alert"     // Suspected abuse, e.g. credential stuffing
+)
+
+type componentHealth struct {
+	degraded bool
+	since    time.Time
+	lastErr  string
+}
+
+// healthTracker records component transitions and incidents in memory so
+// postmortems don't need an external monitoring stack
+type healthTracker struct {
+	mu         sync.Mutex
+	startedAt  time.Time
+	components map[string]*componentHealth
+	incidents  []models.HealthIncident // oldest first
+}
+
+func newHealthTracker() *healthTracker {
+	return &healthTracker{
+		startedAt:  time.Now().UTC(),
+		components: make(map[string]*componentHealth),
+	}
+}
+
+func (t *healthTracker) record(incident models.HealthIncident) {
+	t.incidents = append(t.incidents, incident)
+	if len(t.incidents) > maxHealthIncidents {
+		t.incidents = append([]models.HealthIncident(nil), t.incidents[len(t.incidents)-maxHealthIncidents:]...)
+	}
+}
+
+// ReportHealth records the outcome of an operation against component. The
+// first failure marks the component degraded and the next success recovers
+// it; repeated outcomes in the same state are not recorded again.
+func (s *LeaderboardService) ReportHealth(component string, err error) {
+	t := s.health
+	now := time.Now().UTC()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.components[component]
+	if !ok {
+		c = &componentHealth{since: t.startedAt}
+		t.components[component] = c
+	}
+
+	switch {
+	case err != nil && !c.degraded:
+		c.degraded = true
+		c.since = now
+		c.lastErr = err.Error()
+		t.record(models.HealthIncident{
+			Component: component,
+			Kind:      IncidentDegraded,
+			Detail:    err.Error(),
+			At:        now,
+		})
+	case err != nil:
+		c.lastErr = err.Error()
+	case c.degraded:
+		t.record(models.HealthIncident{
+			Component:       component,
+			Kind:            IncidentRecovered,
+			Detail:          "recovered after: " + c.lastErr,
+			At:              now,
+			DurationSeconds: now.Sub(c.since).Seconds(),
+		})
+		c.degraded = false
+		c.since = now
+		c.lastErr = ""
+	}
+}
+
+// RecordIncident records a one-off incident that doesn't change a component's state
+func (s *LeaderboardService) RecordIncident(component, kind, detail string) {
+	t := s.health
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.record(models.HealthIncident{
+		Component: component,
+		Kind:      kind,
+		Detail:    detail,
+		At:        time.Now().UTC(),
+	})
+}
+
+// GetHealthHistory returns uptime, current component states and the last
+// limit incidents, newest first
+func (s *LeaderboardService) GetHealthHistory(ctx context.Context, limit int) *models.HealthHistoryResponse {
+	t := s.health
+	now := time.Now().UTC()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	names := make([]string, 0, len(t.components))
+	for name := range t.components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	status := "ok"
+	components := make([]models.ComponentHealth, 0, len(names))
+	for _, name := range names {
+		c := t.components[name]
+		state := "ok"
+		if c.degraded {
+			state = "degraded"
+			status = "degraded"
+		}
+		components = append(components, models.ComponentHealth{
+			Component: name,
+			Status:    state,
+			Since:     c.since,
+			LastError: c.lastErr,
+		})
+	}
+
+	incidents := make([]models.HealthIncident, 0, min(limit, len(t.incidents)))
+	for i := len(t.incidents) - 1; i >= 0 && len(incidents) < limit; i-- {
+		incidents = append(incidents, t.incidents[i])
+	}
+
+	return &models.HealthHistoryResponse{
+		Status:        status,
+		StartedAt:     t.startedAt,
+		UptimeSeconds: now.Sub(t.startedAt).Seconds(),
+		Components:    components,
+		Incidents:     incidents,
+		Count:         len(incidents),
+	}
+}
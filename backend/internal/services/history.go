@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/internal/ranking"
+	"backend/pkg/store"
+)
+
+// maxHistoryPerUser bounds the score changes kept for each user before
+// they are downsampled. Downsampled history is mostly bounded by its age,
+// at one entry per minute, hour or day for each run of a reason and source,
+// but changes that alternate reasons or sources don't merge, and daily
+// entries are kept forever, so maxHistoryEntries caps the whole of it.
+const (
+	maxHistoryPerUser = 100
+	maxHistoryEntries = 1000
+)
+
+// Approximate memory held by score history, counted against the board's
+// memory budget. Reasons and sources are shared constants and not counted.
+const (
+	historyEntryBytes = 112 // One models.HistoryEntry
+	historyListBytes  = 72  // A user's slot in the entries map
+	historyJoinBytes  = 72  // A user's slot in the joined or trimmed map
+)
+
+// ErrNotRankedAt is returned when a user had not joined the board at the requested time
+var ErrNotRankedAt = errors.New("user was not on the board at that time")
+
+// scoreHistory keeps the most recent score changes per user for support and audit
+type scoreHistory struct {
+	mu      sync.RWMutex
+	entries map[string][]models.HistoryEntry // oldest first
+	joined  map[string]time.Time             // first user_added per user
+	trimmed map[string]time.Time             // Newest change dropped past maxHistoryEntries, per user
+	bytes   atomic.Int64                     // Approximate memory held, readable without the lock
+}
+
+func newScoreHistory() *scoreHistory {
+	return &scoreHistory{
+		entries: make(map[string][]models.HistoryEntry),
+		joined:  make(map[string]time.Time),
+		trimmed: make(map[string]time.Time),
+	}
+}
+
+// Handle records score changes; it is subscribed to the service's event bus
+func (h *scoreHistory) Handle(e events.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	before := h.footprint(e.Username)
+	defer func() { h.bytes.Add(h.footprint(e.Username) - before) }()
+
+	switch e.Type {
+	case events.TypeUserAdded:
+		if _, ok := h.joined[e.Username]; !ok {
+			h.joined[e.Username] = e.Timestamp
+		}
+	case events.TypeScoreUpdated:
+		entries := append(h.entries[e.Username], models.HistoryEntry{
+			Rating:         e.Rating,
+			PreviousRating: e.PreviousRating,
+			Reason:         e.Reason,
+			Source:         e.Source,
+			Timestamp:      e.Timestamp,
+			OccurredAt:     e.OccurredAt,
+			SyncedBy:       e.SyncedBy,
+		})
+		h.entries[e.Username] = h.bound(e.Username, entries, e.Timestamp)
+	case events.TypeUserEvicted, events.TypeUserExpired:
+		delete(h.entries, e.Username)
+		delete(h.joined, e.Username)
+		delete(h.trimmed, e.Username)
+	}
+}
+
+// bound keeps username's entries within the caps: more than
+// maxHistoryPerUser changes not yet downsampled are downsampled straight
+// away rather than waiting for the compactor, and past maxHistoryEntries the
+// oldest are dropped. Must be called with the lock held.
+func (h *scoreHistory) bound(username string, entries []models.HistoryEntry, now time.Time) []models.HistoryEntry {
+	if rawHistory(entries) > maxHistoryPerUser {
+		entries = downsample(entries, now, "")
+	}
+	return h.trim(username, entries)
+}
+
+// trim drops username's oldest entries past maxHistoryEntries, remembering
+// the newest dropped so ratings before it are known to be approximate. Must
+// be called with the lock held.
+func (h *scoreHistory) trim(username string, entries []models.HistoryEntry) []models.HistoryEntry {
+	drop := len(entries) - maxHistoryEntries
+	if drop <= 0 {
+		return entries
+	}
+	if last := entries[drop-1].Timestamp; last.After(h.trimmed[username]) {
+		h.trimmed[username] = last
+	}
+	return append(entries[:0], entries[drop:]...)
+}
+
+// footprint approximates the memory held for username. Must be called with
+// the lock held.
+func (h *scoreHistory) footprint(username string) int64 {
+	var n int64
+	if entries, ok := h.entries[username]; ok {
+		n += historyListBytes + int64(len(entries))*historyEntryBytes
+	}
+	if _, ok := h.joined[username]; ok {
+		n += historyJoinBytes
+	}
+	if _, ok := h.trimmed[username]; ok {
+		n += historyJoinBytes
+	}
+	return n
+}
+
+// recount recomputes the memory held after the maps were replaced. Must be
+// called with the lock held.
+func (h *scoreHistory) recount() {
+	var n int64
+	for _, entries := range h.entries {
+		n += historyListBytes + int64(len(entries))*historyEntryBytes
+	}
+	n += int64(len(h.joined)+len(h.trimmed)) * historyJoinBytes
+	h.bytes.Store(n)
+}
+
+// changes returns a copy of username's score history, oldest first
+func (h *scoreHistory) changes(username string) []models.HistoryEntry {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return slices.Clone(h.entries[username])
+}
+
+// users returns everyone with score history, in no particular order
+func (h *scoreHistory) users() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	users := make([]string, 0, len(h.entries))
+	for username := range h.entries {
+		users = append(users, username)
+	}
+	return users
+}
+
+// lastChange returns when username's rating last changed, or when they
+// joined if it never has
+func (h *scoreHistory) lastChange(username string) time.Time {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if entries := h.entries[username]; len(entries) > 0 {
+		return entries[len(entries)-1].Timestamp
+	}
+	return h.joined[username]
+}
+
+// absorb moves from's score history into into's, in time order, when from is
+// merged into into, and returns how many changes were moved. into counts as
+// having joined when the earlier of the two did.
+func (h *scoreHistory) absorb(from, into string, now time.Time) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	before := h.footprint(from) + h.footprint(into)
+	defer func() { h.bytes.Add(h.footprint(from) + h.footprint(into) - before) }()
+
+	moved := h.entries[from]
+	if len(moved) > 0 {
+		// On equal times into's own changes, such as the merge, come last
+		entries := append(slices.Clone(moved), h.entries[into]...)
+		slices.SortStableFunc(entries, func(a, b models.HistoryEntry) int { return a.Timestamp.Compare(b.Timestamp) })
+		if trimmed, ok := h.trimmed[from]; ok && trimmed.After(h.trimmed[into]) {
+			h.trimmed[into] = trimmed
+		}
+		h.entries[into] = h.bound(into, entries, now)
+	}
+	if joined, ok := h.joined[from]; ok {
+		if existing, ok := h.joined[into]; !ok || joined.Before(existing) {
+			h.joined[into] = joined
+		}
+	}
+	delete(h.entries, from)
+	delete(h.joined, from)
+	delete(h.trimmed, from)
+	return len(moved)
+}
+
+// HistoryFilter restricts which score changes are listed; empty fields match all
+type HistoryFilter struct {
+	Reason     string // e.g. models.ReasonMatch
+	Source     string // e.g. SourceAPI, to leave out simulated updates
+	Resolution string // Downsample to at least this, e.g. models.ResolutionHour
+}
+
+func (f HistoryFilter) match(entry models.HistoryEntry) bool {
+	return (f.Reason == "" || entry.Reason == f.Reason) &&
+		(f.Source == "" || entry.Source == f.Source)
+}
+
+// list returns up to limit entries for username matching filter, newest first
+func (h *scoreHistory) list(username string, filter HistoryFilter, limit int, now time.Time) []models.HistoryEntry {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	entries := h.entries[username]
+	if filter.Resolution != "" {
+		entries = downsample(entries, now, filter.Resolution)
+	}
+	result := make([]models.HistoryEntry, 0, min(limit, len(entries)))
+	for i := len(entries) - 1; i >= 0 && len(result) < limit; i-- {
+		if !filter.match(entries[i]) {
+			continue
+		}
+		result = append(result, entries[i])
+	}
+	return result
+}
+
+// GetScoreHistory returns a user's recent score changes matching filter,
+// newest first. Older changes may already be downsampled; filter.Resolution
+// downsamples the rest to at least that resolution.
+func (s *LeaderboardService) GetScoreHistory(ctx context.Context, username string, filter HistoryFilter, limit int) (*models.HistoryResponse, error) {
+	if !s.findable(ctx, username) {
+		return nil, store.ErrUserNotFound
+	}
+	if _, err := s.store.GetUser(username); err != nil {
+		return nil, err
+	}
+
+	entries := s.history.list(username, filter, limit, s.clock.Now())
+	return &models.HistoryResponse{
+		Username:   username,
+		Resolution: filter.Resolution,
+		Entries:    entries,
+		Count:      len(entries),
+	}, nil
+}
+
+// ratingAt reconstructs username's rating at t from its history. ok is false
+// if the user joined after t; exact is false when t falls inside a
+// downsampled entry, whose intermediate ratings are gone, or before changes
+// trimmed past the cap. Must be called with the read lock held.
+func (h *scoreHistory) ratingAt(username string, current int, t time.Time) (rating int, ok, exact bool) {
+	if joined, known := h.joined[username]; known && joined.After(t) {
+		return 0, false, true
+	}
+
+	entries := h.entries[username]
+	// Index of the first change after t
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].Timestamp.After(t) })
+	if i == len(entries) {
+		return current, true, true
+	}
+	exact = entries[i].Since == nil || entries[i].Since.After(t)
+	if i > 0 {
+		return entries[i-1].Rating, true, exact
+	}
+	// The oldest kept rating, which changes dropped since t may have moved
+	trimmed, wasTrimmed := h.trimmed[username]
+	return entries[0].PreviousRating, true, exact && !(wasTrimmed && !t.After(trimmed))
+}
+
+// GetUserRankAt reconstructs a user's rating and rank at t from score
+// history. Ranks are approximate: users who left the board since t are not
+// counted, and downsampled history only has ratings at bucket boundaries.
+func (s *LeaderboardService) GetUserRankAt(ctx context.Context, username string, t time.Time) (*models.UserRankResponse, error) {
+	if !s.findable(ctx, username) {
+		return nil, store.ErrUserNotFound
+	}
+	user, err := s.store.GetUser(username)
+	if err != nil {
+		return nil, err
+	}
+
+	h := s.history
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	rating, ok, exact := h.ratingAt(username, user.Rating, t)
+	if !ok {
+		return nil, ErrNotRankedAt
+	}
+
+	users, err := s.store.GetAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	higher := 0
+	for _, other := range users {
+		if other.Username == username {
+			continue
+		}
+		otherRating, ok, otherExact := h.ratingAt(other.Username, other.Rating, t)
+		if !ok {
+			continue
+		}
+		exact = exact && otherExact
+		if otherRating > rating {
+			higher++
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	at := t.UTC()
+	return &models.UserRankResponse{
+		Username:    username,
+		Rating:      rating,
+		Rank:        int64(ranking.FromHigher(higher)),
+		Bot:         user.Bot,
+		At:          &at,
+		Approximate: !exact,
+	}, nil
+}
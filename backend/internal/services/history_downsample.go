@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"backend/internal/models"
+)
+
+// DefaultHistoryCompactInterval is how often aged score history is downsampled
+const DefaultHistoryCompactInterval = 10 * time.Minute
+
+// historyTier is the resolution score history is kept at once it is age old
+type historyTier struct {
+	age        time.Duration
+	resolution string
+	step       time.Duration
+}
+
+// historyTiers, coarsest first: per-day forever past 30 days, per-hour past a
+// day, and per-minute before that
+var historyTiers = []historyTier{
+	{age: 30 * 24 * time.Hour, resolution: models.ResolutionDay, step: 24 * time.Hour},
+	{age: 24 * time.Hour, resolution: models.ResolutionHour, step: time.Hour},
+	{age: 0, resolution: models.ResolutionMinute, step: time.Minute},
+}
+
+// HistoryResolutions lists the resolutions history can be queried at, finest first
+var HistoryResolutions = []string{models.ResolutionMinute, models.ResolutionHour, models.ResolutionDay}
+
+// tierFor returns the tier of a resolution; ok is false for unknown ones,
+// including the empty resolution of changes not yet downsampled
+func tierFor(resolution string) (tier historyTier, ok bool) {
+	for _, tier := range historyTiers {
+		if tier.resolution == resolution {
+			return tier, true
+		}
+	}
+	return historyTier{}, false
+}
+
+// tierAt returns the tier a change made at t is kept at by now
+func tierAt(t, now time.Time) historyTier {
+	age := now.Sub(t)
+	for _, tier := range historyTiers {
+		if age >= tier.age {
+			return tier
+		}
+	}
+	// Changes stamped after now, e.g. by a clock step, are the newest there are
+	return historyTiers[len(historyTiers)-1]
+}
+
+// downsample merges runs of changes with the same reason and source that
+// fall in the same bucket. Buckets are at least as coarse as floor, if set,
+// and coarser as changes age past historyTiers. Runs aren't merged across
+// other reasons or sources, so filtering downsampled history stays exact.
+// entries are oldest first and aren't modified.
+func downsample(entries []models.HistoryEntry, now time.Time, floor string) []models.HistoryEntry {
+	floorTier, hasFloor := tierFor(floor)
+
+	result := make([]models.HistoryEntry, 0, len(entries))
+	var bucket time.Time // Start of the last result entry's bucket
+	for _, entry := range entries {
+		tier := tierAt(entry.Timestamp, now)
+		if hasFloor && floorTier.step > tier.step {
+			tier = floorTier
+		}
+		// Never make an entry finer than it already is
+		if own, ok := tierFor(entry.Resolution); ok && own.step > tier.step {
+			tier = own
+		}
+		start := entry.Timestamp.Truncate(tier.step)
+
+		if n := len(result); n > 0 {
+			last := &result[n-1]
+			if last.Resolution == tier.resolution && start.Equal(bucket) &&
+				last.Reason == entry.Reason && last.Source == entry.Source {
+				*last = mergeHistory(*last, entry)
+				continue
+			}
+		}
+		entry.Resolution = tier.resolution
+		result = append(result, entry)
+		bucket = start
+	}
+	return result
+}
+
+// mergeHistory folds newer into older, both in the same bucket
+func mergeHistory(older, newer models.HistoryEntry) models.HistoryEntry {
+	since := older.Timestamp
+	if older.Since != nil {
+		since = *older.Since
+	}
+	older.Rating = newer.Rating
+	older.Timestamp = newer.Timestamp
+	older.Changes = max(older.Changes, 1) + max(newer.Changes, 1)
+	older.Since = &since
+	// A merged entry spans its changes; Since and Timestamp bound when they happened
+	older.OccurredAt = nil
+	if older.SyncedBy != newer.SyncedBy {
+		older.SyncedBy = ""
+	}
+	return older
+}
+
+// rawHistory counts the changes at the end of entries not yet downsampled
+func rawHistory(entries []models.HistoryEntry) int {
+	n := 0
+	for i := len(entries) - 1; i >= 0 && entries[i].Resolution == ""; i-- {
+		n++
+	}
+	return n
+}
+
+// StartHistoryCompactor downsamples aged score history every interval until
+// ctx is cancelled
+func (s *LeaderboardService) StartHistoryCompactor(ctx context.Context, interval time.Duration) {
+	ticker := s.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("🗜️ Started history compactor (every %s)", interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if merged := s.CompactHistory(); merged > 0 {
+				log.Printf("Compacted score history: %d entries merged or trimmed", merged)
+			}
+		}
+	}
+}
+
+// CompactHistory downsamples every user's score history to the resolution
+// its age calls for, and returns how many entries were merged away or
+// trimmed past the cap. Users are compacted one at a time, so score changes
+// aren't held up for long.
+func (s *LeaderboardService) CompactHistory() int {
+	h := s.history
+	now := s.clock.Now()
+
+	h.mu.RLock()
+	usernames := make([]string, 0, len(h.entries))
+	for username := range h.entries {
+		usernames = append(usernames, username)
+	}
+	h.mu.RUnlock()
+
+	merged := 0
+	for _, username := range usernames {
+		h.mu.Lock()
+		if entries, ok := h.entries[username]; ok {
+			before := h.footprint(username)
+			compacted := h.trim(username, downsample(entries, now, ""))
+			h.entries[username] = compacted
+			h.bytes.Add(h.footprint(username) - before)
+			merged += len(entries) - len(compacted)
+		}
+		h.mu.Unlock()
+	}
+	return merged
+}
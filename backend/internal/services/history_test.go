@@ -0,0 +1,192 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/models"
+)
+
+var historyNow = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestTierAt(t *testing.T) {
+	cases := []struct {
+		age  time.Duration
+		want string
+	}{
+		{-time.Minute, models.ResolutionMinute}, // Stamped after now
+		{0, models.ResolutionMinute},
+		{24*time.Hour - time.Second, models.ResolutionMinute},
+		{24 * time.Hour, models.ResolutionHour},
+		{30*24*time.Hour - time.Second, models.ResolutionHour},
+		{30 * 24 * time.Hour, models.ResolutionDay},
+		{400 * 24 * time.Hour, models.ResolutionDay},
+	}
+	for _, c := range cases {
+		if got := tierAt(historyNow.Add(-c.age), historyNow); got.resolution != c.want {
+			t.Errorf("tierAt(now - %s) = %s, want %s", c.age, got.resolution, c.want)
+		}
+	}
+}
+
+func TestMergeHistory(t *testing.T) {
+	first := historyNow.Add(-3 * time.Minute)
+	occurred := historyNow.Add(-time.Hour)
+	older := models.HistoryEntry{Rating: 1010, PreviousRating: 1000, Reason: models.ReasonMatch, Timestamp: first, OccurredAt: &occurred}
+	newer := models.HistoryEntry{Rating: 1030, PreviousRating: 1010, Reason: models.ReasonMatch, Timestamp: historyNow}
+
+	merged := mergeHistory(older, newer)
+	if merged.Rating != 1030 || merged.PreviousRating != 1000 || !merged.Timestamp.Equal(historyNow) {
+		t.Errorf("merged %d -> %d at %s, want 1000 -> 1030 at now", merged.PreviousRating, merged.Rating, merged.Timestamp)
+	}
+	if merged.Changes != 2 || merged.Since == nil || !merged.Since.Equal(first) || merged.OccurredAt != nil {
+		t.Errorf("merged %+v, want 2 changes since the first and no occurred_at", merged)
+	}
+
+	// Merging on keeps the first change's time and counts what each stands for
+	merged = mergeHistory(merged, models.HistoryEntry{Rating: 1050, Timestamp: historyNow, Changes: 3})
+	if merged.Changes != 5 || !merged.Since.Equal(first) {
+		t.Errorf("merged %d changes since %s, want 5 since %s", merged.Changes, merged.Since, first)
+	}
+	if older.Since != nil || older.Changes != 0 {
+		t.Error("mergeHistory modified its argument")
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	entry := func(age time.Duration, reason string, rating int) models.HistoryEntry {
+		return models.HistoryEntry{Rating: rating, Reason: reason, Source: SourceAPI, Timestamp: historyNow.Add(-age)}
+	}
+	type want struct {
+		resolution string
+		changes    int
+		rating     int
+	}
+	cases := []struct {
+		name    string
+		entries []models.HistoryEntry
+		floor   string
+		want    []want
+	}{
+		{
+			name:    "recent changes in one minute",
+			entries: []models.HistoryEntry{entry(50*time.Second, models.ReasonMatch, 1), entry(40*time.Second, models.ReasonMatch, 2)},
+			want:    []want{{models.ResolutionMinute, 2, 2}},
+		},
+		{
+			name:    "recent changes in different minutes",
+			entries: []models.HistoryEntry{entry(5*time.Minute, models.ReasonMatch, 1), entry(time.Minute+30*time.Second, models.ReasonMatch, 2)},
+			want:    []want{{models.ResolutionMinute, 0, 1}, {models.ResolutionMinute, 0, 2}},
+		},
+		{
+			name:    "day old changes in one hour",
+			entries: []models.HistoryEntry{entry(25*time.Hour, models.ReasonMatch, 1), entry(25*time.Hour-10*time.Minute, models.ReasonMatch, 2)},
+			want:    []want{{models.ResolutionHour, 2, 2}},
+		},
+		{
+			name:    "other reasons aren't merged",
+			entries: []models.HistoryEntry{entry(40*24*time.Hour, models.ReasonMatch, 1), entry(40*24*time.Hour, models.ReasonAdminAdjustment, 2), entry(40*24*time.Hour, models.ReasonMatch, 3)},
+			want:    []want{{models.ResolutionDay, 0, 1}, {models.ResolutionDay, 0, 2}, {models.ResolutionDay, 0, 3}},
+		},
+		{
+			name:    "floor coarsens recent changes",
+			entries: []models.HistoryEntry{entry(5*time.Minute, models.ReasonMatch, 1), entry(time.Minute+30*time.Second, models.ReasonMatch, 2)},
+			floor:   models.ResolutionHour,
+			want:    []want{{models.ResolutionHour, 2, 2}},
+		},
+		{
+			name: "entries aren't made finer",
+			entries: []models.HistoryEntry{
+				{Rating: 1, Reason: models.ReasonMatch, Source: SourceAPI, Timestamp: historyNow.Add(-30 * time.Minute), Resolution: models.ResolutionHour},
+				entry(20*time.Minute, models.ReasonMatch, 2),
+			},
+			want: []want{{models.ResolutionHour, 0, 1}, {models.ResolutionMinute, 0, 2}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := downsample(c.entries, historyNow, c.floor)
+			if len(got) != len(c.want) {
+				t.Fatalf("downsampled to %d entries, want %d: %+v", len(got), len(c.want), got)
+			}
+			for i, w := range c.want {
+				if got[i].Resolution != w.resolution || got[i].Changes != w.changes || got[i].Rating != w.rating {
+					t.Errorf("entry %d is %s, %d changes, rating %d; want %s, %d, %d",
+						i, got[i].Resolution, got[i].Changes, got[i].Rating, w.resolution, w.changes, w.rating)
+				}
+			}
+			if c.entries[0].Changes != 0 {
+				t.Error("downsample modified its input")
+			}
+		})
+	}
+}
+
+func TestHistoryCap(t *testing.T) {
+	h := newScoreHistory()
+	joined := historyNow.Add(-5 * maxHistoryEntries * time.Hour)
+	h.Handle(events.Event{Type: events.TypeUserAdded, Username: "alice", Timestamp: joined})
+
+	// Alternating reasons never merge, so only the cap bounds them
+	reasons := []string{models.ReasonMatch, models.ReasonAdminAdjustment}
+	changes := 2 * maxHistoryEntries
+	for i := range changes {
+		h.Handle(events.Event{
+			Type:           events.TypeScoreUpdated,
+			Username:       "alice",
+			Rating:         1001 + i,
+			PreviousRating: 1000 + i,
+			Reason:         reasons[i%len(reasons)],
+			Timestamp:      joined.Add(time.Duration(i+1) * time.Hour),
+		})
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	entries := h.entries["alice"]
+	if len(entries) != maxHistoryEntries {
+		t.Fatalf("kept %d entries, want the cap of %d", len(entries), maxHistoryEntries)
+	}
+	if last := entries[len(entries)-1]; last.Rating != 1000+changes {
+		t.Errorf("newest entry rated %d, want %d", last.Rating, 1000+changes)
+	}
+	if want := int64(historyListBytes + maxHistoryEntries*historyEntryBytes + 2*historyJoinBytes); h.bytes.Load() != want {
+		t.Errorf("footprint %d, want %d", h.bytes.Load(), want)
+	}
+
+	// Ratings before the oldest kept change are a guess once older ones are gone
+	if _, ok, exact := h.ratingAt("alice", 0, joined.Add(time.Minute)); !ok || exact {
+		t.Errorf("rating before trimmed changes: ok %v, exact %v; want ok and approximate", ok, exact)
+	}
+	if rating, ok, exact := h.ratingAt("alice", 0, entries[10].Timestamp); !ok || !exact || rating != entries[10].Rating {
+		t.Errorf("rating after trimmed changes = %d, ok %v, exact %v; want %d exactly", rating, ok, exact, entries[10].Rating)
+	}
+}
+
+func TestHistoryCapOnMerge(t *testing.T) {
+	h := newScoreHistory()
+	for _, username := range []string{"alice", "bob"} {
+		for i := range maxHistoryEntries - 1 {
+			h.Handle(events.Event{
+				Type:      events.TypeScoreUpdated,
+				Username:  username,
+				Rating:    i,
+				Reason:    fmt.Sprintf("%s-%d", username, i%2),
+				Timestamp: historyNow.Add(-time.Duration(maxHistoryEntries-i) * 24 * time.Hour),
+			})
+		}
+	}
+	if moved := h.absorb("bob", "alice", historyNow); moved != maxHistoryEntries-1 {
+		t.Errorf("moved %d, want %d", moved, maxHistoryEntries-1)
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if n := len(h.entries["alice"]); n != maxHistoryEntries {
+		t.Errorf("merged history has %d entries, want the cap of %d", n, maxHistoryEntries)
+	}
+	if _, ok := h.trimmed["alice"]; !ok {
+		t.Error("merged history trimmed without noting it")
+	}
+}
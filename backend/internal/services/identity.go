@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+var (
+	// ErrExternalIDNotFound is returned when an external identity isn't mapped to a user
+	ErrExternalIDNotFound = errors.New("external id not mapped")
+	// ErrExternalIDTaken is returned when linking an external identity that belongs to another user
+	ErrExternalIDTaken = errors.New("external id linked to another user")
+)
+
+// identityMap maps external identities (platform player IDs, OAuth subjects)
+// to leaderboard usernames so integrations don't need to know our usernames
+type identityMap struct {
+	loginMu    sync.Mutex // held while a login creates and links a user
+	mu         sync.RWMutex
+	links      map[string]*models.ExternalIdentity // provider + "\x00" + external ID -> link
+	byUsername map[string]map[string]struct{}      // username -> link keys
+}
+
+func newIdentityMap() *identityMap {
+	return &identityMap{
+		links:      make(map[string]*models.ExternalIdentity),
+		byUsername: make(map[string]map[string]struct{}),
+	}
+}
+
+func identityKey(provider, externalID string) string {
+	return provider + "\x00" + externalID
+}
+
+// LinkExternalID maps an external identity to an existing user. Linking an
+// identity that already belongs to another user fails with ErrExternalIDTaken;
+// relinking to the same user is a no-op.
+func (s *LeaderboardService) LinkExternalID(ctx context.Context, provider, externalID, username string) (*models.ExternalIdentity, error) {
+	if _, err := s.store.GetUser(username); err != nil {
+		return nil, err
+	}
+
+	m := s.identities
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := identityKey(provider, externalID)
+	if existing, ok := m.links[key]; ok {
+		if existing.Username != username {
+			return nil, ErrExternalIDTaken
+		}
+		link := *existing
+		return &link, nil
+	}
+
+	link := &models.ExternalIdentity{
+		Provider:   provider,
+		ExternalID: externalID,
+		Username:   username,
+		LinkedAt:   time.Now().UTC(),
+	}
+	m.links[key] = link
+	if m.byUsername[username] == nil {
+		m.byUsername[username] = make(map[string]struct{})
+	}
+	m.byUsername[username][key] = struct{}{}
+
+	result := *link
+	return &result, nil
+}
+
+// UnlinkExternalID removes an external identity's mapping
+func (s *LeaderboardService) UnlinkExternalID(ctx context.Context, provider, externalID string) error {
+	m := s.identities
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := identityKey(provider, externalID)
+	link, ok := m.links[key]
+	if !ok {
+		return ErrExternalIDNotFound
+	}
+
+	delete(m.links, key)
+	delete(m.byUsername[link.Username], key)
+	if len(m.byUsername[link.Username]) == 0 {
+		delete(m.byUsername, link.Username)
+	}
+	return nil
+}
+
+// relink moves every identity linked to from over to into, when from is
+// merged into into, and returns how many moved
+func (m *identityMap) relink(from, into string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	moved := len(m.byUsername[from])
+	for key := range m.byUsername[from] {
+		link := *m.links[key]
+		link.Username = into
+		m.links[key] = &link
+		if m.byUsername[into] == nil {
+			m.byUsername[into] = make(map[string]struct{})
+		}
+		m.byUsername[into][key] = struct{}{}
+	}
+	delete(m.byUsername, from)
+	return moved
+}
+
+// principals returns the role principals of the identities linked to username
+func (m *identityMap) principals(username string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	principals := make([]string, 0, len(m.byUsername[username]))
+	for key := range m.byUsername[username] {
+		link := m.links[key]
+		principals = append(principals, IdentityPrincipal(link.Provider, link.ExternalID))
+	}
+	return principals
+}
+
+// GetExternalID returns the mapping for an external identity
+func (s *LeaderboardService) GetExternalID(ctx context.Context, provider, externalID string) (*models.ExternalIdentity, error) {
+	link, err := s.lookupExternalID(provider, externalID)
+	if err != nil {
+		return nil, err
+	}
+	// Users hidden from search can't be found through their identities either
+	if !s.findable(ctx, link.Username) {
+		return nil, ErrExternalIDNotFound
+	}
+	return link, nil
+}
+
+func (s *LeaderboardService) lookupExternalID(provider, externalID string) (*models.ExternalIdentity, error) {
+	m := s.identities
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	link, ok := m.links[identityKey(provider, externalID)]
+	if !ok {
+		return nil, ErrExternalIDNotFound
+	}
+	result := *link
+	return &result, nil
+}
+
+// ResolveExternalID returns the username an external identity is mapped to
+func (s *LeaderboardService) ResolveExternalID(ctx context.Context, provider, externalID string) (string, error) {
+	link, err := s.lookupExternalID(provider, externalID)
+	if err != nil {
+		return "", err
+	}
+	return link.Username, nil
+}
+
+// ListExternalIDs returns every external identity linked to username, by provider
+func (s *LeaderboardService) ListExternalIDs(ctx context.Context, username string) ([]models.ExternalIdentity, error) {
+	if !s.findable(ctx, username) {
+		return nil, store.ErrUserNotFound
+	}
+	if _, err := s.store.GetUser(username); err != nil {
+		return nil, err
+	}
+
+	m := s.identities
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]models.ExternalIdentity, 0, len(m.byUsername[username]))
+	for key := range m.byUsername[username] {
+		result = append(result, *m.links[key])
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Provider != result[j].Provider {
+			return result[i].Provider < result[j].Provider
+		}
+		return result[i].ExternalID < result[j].ExternalID
+	})
+	return result, nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+)
+
+// maxInflationSamples bounds the rating history kept for the inflation
+// index: a month of hourly samples
+const maxInflationSamples = 720
+
+// DefaultInflationInterval is how often the inflation monitor samples the board
+const DefaultInflationInterval = time.Hour
+
+// inflationTracker keeps periodic samples of the board's central ratings
+type inflationTracker struct {
+	mu       sync.Mutex
+	interval time.Duration
+	samples  []models.InflationSample
+}
+
+func newInflationTracker() *inflationTracker {
+	return &inflationTracker{}
+}
+
+// StartInflationMonitor samples the average and median rating every
+// interval, starting immediately, until ctx is cancelled
+func (s *LeaderboardService) StartInflationMonitor(ctx context.Context, interval time.Duration) {
+	ticker := s.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.inflation.mu.Lock()
+	s.inflation.interval = interval
+	s.inflation.mu.Unlock()
+
+	log.Printf("📈 Started rating inflation monitor (every %s)", interval)
+	s.RecordInflationSample(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			s.RecordInflationSample(ctx)
+		}
+	}
+}
+
+// RecordInflationSample records the board's current average and median
+// rating. Empty boards are skipped since they have neither.
+func (s *LeaderboardService) RecordInflationSample(ctx context.Context) {
+	total, _, _, average, err := s.store.GetStats(ctx, false)
+	if err != nil || total == 0 {
+		return
+	}
+	counts, err := s.store.RatingCounts(ctx, false)
+	if err != nil {
+		return
+	}
+	median, _, _ := distribution(counts)
+
+	t := s.inflation
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.samples = append(t.samples, models.InflationSample{
+		At:            s.clock.Now().UTC(),
+		Users:         int64(total),
+		AverageRating: average,
+		MedianRating:  median,
+	})
+	if len(t.samples) > maxInflationSamples {
+		t.samples = t.samples[len(t.samples)-maxInflationSamples:]
+	}
+}
+
+// GetInflationReport indexes every retained sample's average rating against
+// the oldest one (100 means no change) and reports the average drift per day
+func (s *LeaderboardService) GetInflationReport(ctx context.Context) *models.InflationReport {
+	t := s.inflation
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := &models.InflationReport{
+		IntervalSeconds: t.interval.Seconds(),
+		Samples:         make([]models.InflationSample, len(t.samples)),
+	}
+	copy(report.Samples, t.samples)
+	if len(report.Samples) == 0 {
+		return report
+	}
+
+	baseline := report.Samples[0]
+	for i := range report.Samples {
+		report.Samples[i].Index = 100 * report.Samples[i].AverageRating / baseline.AverageRating
+	}
+	latest := report.Samples[len(report.Samples)-1]
+	report.Index = latest.Index
+	if days := latest.At.Sub(baseline.At).Hours() / 24; days > 0 {
+		report.AverageChangePerDay = (latest.AverageRating - baseline.AverageRating) / days
+	}
+	return report
+}
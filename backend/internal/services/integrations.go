@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+)
+
+var (
+	// ErrIntegrationsDisabled is returned when no integration secrets are configured
+	ErrIntegrationsDisabled = errors.New("integrations are disabled")
+	// ErrInvalidSignature is returned for unknown platforms and bad signatures
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrStaleRequest is returned when the signed timestamp is outside the tolerance
+	ErrStaleRequest = errors.New("request timestamp outside tolerance")
+	// ErrReplayedRequest is returned when a nonce is seen twice within the tolerance
+	ErrReplayedRequest = errors.New("request nonce already used")
+)
+
+// IntegrationConfig configures signed inbound score webhooks
+type IntegrationConfig struct {
+	Secrets   map[string]string // Platform name -> shared HMAC secret
+	Tolerance time.Duration     // Allowed clock skew for signed timestamps, defaults to 5m
+}
+
+// integrationNonceRecords keeps each webhook nonce, "<platform>:<nonce>",
+// until the unix time it can be forgotten, so that a replay is refused by
+// every replica on the store and after a restart
+const integrationNonceRecords = "integration_nonces"
+
+// integrationVerifier checks webhook signatures; nonces are kept in the store
+type integrationVerifier struct {
+	config    IntegrationConfig
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// EnableIntegrations accepts signed score submissions from the platforms in config
+func (s *LeaderboardService) EnableIntegrations(config IntegrationConfig) {
+	if config.Tolerance <= 0 {
+		config.Tolerance = 5 * time.Minute
+	}
+	s.integrations = &integrationVerifier{config: config}
+}
+
+// SignIntegrationRequest returns the hex HMAC-SHA256 a platform sends in
+// X-Signature: the secret signs "<timestamp>.<nonce>.<body>"
+func SignIntegrationRequest(secret, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + nonce + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyIntegration checks a webhook's signature, timestamp and nonce. A
+// nonce is only recorded once the signature is valid, so forged requests
+// can't burn legitimate nonces.
+func (s *LeaderboardService) VerifyIntegration(platform, timestamp, nonce, signature string, body []byte) error {
+	v := s.integrations
+	if v == nil {
+		return ErrIntegrationsDisabled
+	}
+
+	secret, ok := v.config.Secrets[platform]
+	if !ok || nonce == "" {
+		return ErrInvalidSignature
+	}
+	expected := SignIntegrationRequest(secret, timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrStaleRequest
+	}
+	now := s.clock.Now()
+	if skew := now.Sub(time.Unix(unix, 0)); skew > v.config.Tolerance || skew < -v.config.Tolerance {
+		return ErrStaleRequest
+	}
+
+	s.sweepNonces(now)
+	// Anything older than the tolerance is rejected as stale anyway
+	forgetAt := strconv.FormatInt(now.Add(2*v.config.Tolerance).Unix(), 10)
+	_, err = s.store.UpdateRecord(integrationNonceRecords, platform+":"+nonce, func(current string) (string, error) {
+		if nonceRemembered(current, now) {
+			return "", ErrReplayedRequest
+		}
+		return forgetAt, nil
+	})
+	return err
+}
+
+// nonceRemembered reports whether a stored nonce record still holds at now
+func nonceRemembered(record string, now time.Time) bool {
+	forgetAt, err := strconv.ParseInt(record, 10, 64)
+	return err == nil && now.Before(time.Unix(forgetAt, 0))
+}
+
+// sweepNonces drops forgotten nonces at most once per tolerance. Replicas
+// sweeping together only each delete what is still forgotten.
+func (s *LeaderboardService) sweepNonces(now time.Time) {
+	v := s.integrations
+	v.mu.Lock()
+	due := now.Sub(v.lastSweep) >= v.config.Tolerance
+	if due {
+		v.lastSweep = now
+	}
+	v.mu.Unlock()
+	if !due {
+		return
+	}
+
+	for key, record := range s.store.Records(integrationNonceRecords) {
+		if nonceRemembered(record, now) {
+			continue
+		}
+		s.store.UpdateRecord(integrationNonceRecords, key, func(current string) (string, error) {
+			if nonceRemembered(current, now) {
+				return current, nil
+			}
+			return "", nil
+		})
+	}
+}
+
+// SubmitIntegrationScore applies a verified webhook submission to the user
+// its external ID is mapped to
+func (s *LeaderboardService) SubmitIntegrationScore(ctx context.Context, platform string, req models.IntegrationScoreRequest) (*models.UpdateScoreResponse, error) {
+	username, err := s.ResolveExternalID(ctx, platform, req.ExternalID)
+	if err != nil {
+		return nil, err
+	}
+	return s.SubmitScore(ctx, username, req.UpdateScoreRequest)
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"backend/internal/clock"
+)
+
+func TestVerifyIntegrationRefusesReplays(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	st := playersStore(t, 1)
+	config := IntegrationConfig{Secrets: map[string]string{"steam": "steam-secret"}, Tolerance: time.Minute}
+	replica := func() *LeaderboardService {
+		s := NewLeaderboardService(st)
+		s.SetClock(fake)
+		s.EnableIntegrations(config)
+		return s
+	}
+	first := replica()
+	body := []byte(`{"external_id":"p1","rating":2000}`)
+	verify := func(s *LeaderboardService, signedAt time.Time, nonce string) error {
+		timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+		return s.VerifyIntegration("steam", timestamp, nonce, SignIntegrationRequest("steam-secret", timestamp, nonce, body), body)
+	}
+
+	steps := []struct {
+		name     string
+		s        *LeaderboardService
+		signedAt time.Time
+		nonce    string
+		want     error
+	}{
+		{name: "a fresh request", s: first, signedAt: start, nonce: "n1"},
+		{name: "the same request again", s: first, signedAt: start, nonce: "n1", want: ErrReplayedRequest},
+		{name: "the same request on another replica", s: replica(), signedAt: start, nonce: "n1", want: ErrReplayedRequest},
+		{name: "the same request after a restart", s: replica(), signedAt: start, nonce: "n1", want: ErrReplayedRequest},
+		{name: "another nonce", s: first, signedAt: start, nonce: "n2"},
+		{name: "signed past the tolerance", s: first, signedAt: start.Add(-2 * time.Minute), nonce: "n3", want: ErrStaleRequest},
+		{name: "signed ahead past the tolerance", s: first, signedAt: start.Add(2 * time.Minute), nonce: "n3", want: ErrStaleRequest},
+	}
+	for _, step := range steps {
+		if err := verify(step.s, step.signedAt, step.nonce); !errors.Is(err, step.want) {
+			t.Errorf("%s: %v, want %v", step.name, err, step.want)
+		}
+	}
+
+	// The skew is measured on the service clock
+	fake.Advance(2 * time.Minute)
+	if err := verify(first, start, "n4"); !errors.Is(err, ErrStaleRequest) {
+		t.Errorf("request signed before the clock moved on: %v, want ErrStaleRequest", err)
+	}
+
+	// Nonces are forgotten once their requests would be stale anyway
+	fake.Advance(time.Minute)
+	if err := verify(first, fake.Now(), "n5"); err != nil {
+		t.Fatal(err)
+	}
+	if got := st.RecordCount(integrationNonceRecords); got != 1 {
+		t.Errorf("%d nonces kept, want only the latest", got)
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+// IssueOutOfRange marks a stored rating outside MinRating..MaxRating
+const IssueOutOfRange = "out_of_range"
+
+// CheckIntegrity validates the stored board: the user table against the
+// rating index, and every rating against the board's score bounds
+func (s *LeaderboardService) CheckIntegrity(ctx context.Context) ([]models.IntegrityIssue, error) {
+	issues := integrityIssues(s.store.CheckIntegrity(), false)
+	users, err := s.store.GetAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if user.Rating < MinRating || user.Rating > MaxRating {
+			issues = append(issues, models.IntegrityIssue{Kind: IssueOutOfRange, Username: user.Username, Rating: user.Rating})
+		}
+	}
+	return issues, nil
+}
+
+// RepairIntegrity finds the same issues as CheckIntegrity and fixes them.
+// Index problems are repaired in the store; out-of-range ratings are clamped
+// through a regular admin_adjustment, so the repair shows up in history.
+func (s *LeaderboardService) RepairIntegrity(ctx context.Context, actor string) ([]models.IntegrityIssue, error) {
+	issues := integrityIssues(s.store.RepairIntegrity(), true)
+	users, err := s.store.GetAllUsers(ctx)
+	if err != nil {
+		return issues, err
+	}
+	for _, user := range users {
+		if user.Rating >= MinRating && user.Rating <= MaxRating {
+			continue
+		}
+		err := s.commitScore(WithSource(ctx, SourceAdmin), user, clampRating(user.Rating), events.Event{
+			Reason: models.ReasonAdminAdjustment,
+			Actor:  actor,
+			Note:   "integrity repair: rating out of range",
+		})
+		issues = append(issues, models.IntegrityIssue{
+			Kind:     IssueOutOfRange,
+			Username: user.Username,
+			Rating:   user.Rating,
+			Repaired: err == nil,
+		})
+	}
+	return issues, nil
+}
+
+// IntegrityCheck runs CheckIntegrity, or RepairIntegrity when repair is
+// set, and reports the outcome
+func (s *LeaderboardService) IntegrityCheck(ctx context.Context, repair bool, actor string) (*models.IntegrityReport, error) {
+	start := time.Now()
+	report := &models.IntegrityReport{CheckedAt: start.UTC(), Repair: repair}
+
+	var err error
+	if repair {
+		report.Issues, err = s.RepairIntegrity(ctx, actor)
+	} else {
+		report.Issues, err = s.CheckIntegrity(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, issue := range report.Issues {
+		if issue.Repaired {
+			report.Repaired++
+		}
+	}
+	report.Count = len(report.Issues)
+	report.Users = s.store.GetUserCount()
+	report.DurationMs = float64(time.Since(start)) / float64(time.Millisecond)
+	return report, nil
+}
+
+func integrityIssues(found []store.IntegrityIssue, repaired bool) []models.IntegrityIssue {
+	issues := make([]models.IntegrityIssue, 0, len(found))
+	for _, issue := range found {
+		issues = append(issues, models.IntegrityIssue{
+			Kind:     issue.Kind,
+			Username: issue.Username,
+			Rating:   issue.Rating,
+			Repaired: repaired,
+		})
+	}
+	return issues
+}
@@ -2,88 +2,362 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
-	"math/rand"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"backend/internal/clock"
+	"backend/internal/events"
 	"backend/internal/models"
+	"backend/internal/ranking"
 	"backend/pkg/store"
 )
 
+// streamBatchSize is the number of entries enriched together while streaming
+const streamBatchSize = 500
+
+// scoreLockStripes is how many locks submissions are spread over by username
+const scoreLockStripes = 64
+
 type LeaderboardService struct {
-	store *store.MemoryStore
+	store         store.Store
+	simulation    *simulationState
+	enrichers     enricherChain
+	configMu      sync.RWMutex // Guards strategy and prizeBands, which the board config API swaps
+	strategy      RatingStrategy
+	matches       MatchLedger
+	shadow        shadowBoard
+	events        *events.Bus
+	realtime      *events.Hub
+	anomalies     *AnomalyDetector
+	history       *scoreHistory
+	milestones    *milestoneTracker
+	health        *healthTracker
+	identities    *identityMap
+	integrations  *integrationVerifier
+	moderation    *moderation
+	approvals     *approvals
+	sources       *sourceCounters
+	statsCache    *statsCache
+	searchCache   *searchCache
+	privacy       *privacySettings
+	doubleWrite   *doubleWrite // nil unless migrating to another store
+	season        *seasonState
+	confirmations *confirmations
+	seeds         *seedJobs
+	webhooks      *seasonWebhooks // nil unless season webhooks are configured
+	reports       *dailyReports   // nil unless daily reports are enabled
+	imports       *scoreImporter  // nil unless a score file is configured
+	prizeBands    []PrizeBand
+	scoreQueue    scoreQueueBackend // nil unless async submissions are enabled
+	submissions   *submissionDedup  // set with scoreQueue
+	boardID       string
+	resolved      map[string]any // Configuration served at /api/admin/config
+	createdAt     time.Time
+	clock         clock.Clock
+	random        *randomSource
+	inflation     *inflationTracker
+	rateLimits    *rateLimiter
+	slos          *sloTracker    // nil unless latency SLOs are configured
+	derived       *derivedBoards // nil unless derived boards are enabled
+	guests        *guests        // nil unless guest submissions are enabled
+	cutoffs       *cutoffWatcher // nil unless cutoffs are watched
+	mergeMu       sync.Mutex     // Held while one user is merged into another
+	sessions      SessionRevoker // nil unless logins are enabled
+	segments      *segmentStats
+	recomputes    *boardRecomputes
+	queryBudget   atomic.Int64                 // 0 leaves reads unpriced
+	scoreLocks    [scoreLockStripes]sync.Mutex // Held from reading a user's rating to writing the one computed from it
 }
 
-func NewLeaderboardService(store *store.MemoryStore) *LeaderboardService {
-	return &LeaderboardService{store: store}
+func NewLeaderboardService(store store.Store) *LeaderboardService {
+	privacy := newPrivacySettings(store)
+	s := &LeaderboardService{
+		store:         store,
+		simulation:    newSimulationState(),
+		strategy:      AbsoluteStrategy{},
+		matches:       NewMemoryMatchLedger(24 * time.Hour),
+		events:        events.NewBus(),
+		realtime:      events.NewHub(events.DefaultHubConfig()),
+		history:       newScoreHistory(),
+		milestones:    newMilestoneTracker(store, privacy),
+		health:        newHealthTracker(),
+		identities:    newIdentityMap(),
+		moderation:    newModeration(),
+		approvals:     newApprovals(),
+		sources:       newSourceCounters(),
+		statsCache:    newStatsCache(),
+		searchCache:   newSearchCache(),
+		privacy:       privacy,
+		season:        &seasonState{},
+		confirmations: newConfirmations(),
+		seeds:         newSeedJobs(),
+		boardID:       DefaultBoardID,
+		createdAt:     time.Now().UTC(),
+		clock:         clock.Real(),
+		random:        newRandomSource(defaultRandSeed()),
+		inflation:     newInflationTracker(),
+		rateLimits:    newRateLimiter(),
+		segments:      newSegmentStats(store),
+		recomputes:    newBoardRecomputes(),
+	}
+	s.events.Subscribe(s.history.Handle)
+	s.events.Subscribe(s.realtime.Handle)
+	s.events.Subscribe(s.sources.Handle)
+	s.events.Subscribe(s.searchCache.Handle)
+	s.events.Subscribe(s.milestones.Handle)
+	s.events.Subscribe(s.segments.Handle)
+	s.events.Subscribe(s.dropLeaverRoles)
+	return s
 }
 
-// SeedData seeds the leaderboard with random users
-func (s *LeaderboardService) SeedData(ctx context.Context, count int) error {
-	log.Printf("Seeding %d users...", count)
+// SetClock replaces the clock used by the simulator, seasons, freezes, entry
+// expiry and event timestamps. Call it before the service is used.
+func (s *LeaderboardService) SetClock(c clock.Clock) {
+	s.clock = c
+	s.createdAt = c.Now().UTC()
+	s.events.SetClock(c)
+}
 
-	for i := 0; i < count; i++ {
-		username := fmt.Sprintf("user_%d", i+1)
-		rating := rand.Intn(4901) + 100 // Random rating between 100 and 5000
+// Events returns the bus every leaderboard mutation is published on
+func (s *LeaderboardService) Events() *events.Bus {
+	return s.events
+}
 
-		if err := s.store.AddUser(username, rating); err != nil {
-			return fmt.Errorf("failed to add user: %w", err)
-		}
+// Realtime returns the hub that fans events out to live subscribers
+func (s *LeaderboardService) Realtime() *events.Hub {
+	return s.realtime
+}
 
-		if (i+1)%1000 == 0 {
-			log.Printf("Seeded %d users...", i+1)
-		}
+// SetCapacity caps the board at max members, evicting the lowest-ranked
+// member whenever a new user joins a full board
+func (s *LeaderboardService) SetCapacity(max int) {
+	s.store.SetCapacity(max, s.publishEviction)
+}
+
+// SetTiePolicy sets how users with equal ratings are ordered and ranked, one
+// of ranking.Policies. Under ranking.MostRecentFirst the store stamps each
+// new rating with the service clock, so the latest achiever ranks first.
+func (s *LeaderboardService) SetTiePolicy(policy string) {
+	s.store.SetTiePolicy(policy, func() time.Time { return s.clock.Now() })
+
+	// Cached ranks were worked out under the old policy
+	s.searchCache.mu.Lock()
+	s.searchCache.reset()
+	s.searchCache.mu.Unlock()
+}
+
+// TiePolicy returns how users with equal ratings are ordered and ranked
+func (s *LeaderboardService) TiePolicy() string {
+	return s.store.TiePolicy()
+}
+
+// SetMemoryBudget bounds the approximate memory held for the board's members,
+// score history included. Past it, new members are rejected with
+// store.ErrMemoryBudget or, under store.MemoryEvictLowest, the lowest-ranked
+// members are evicted.
+func (s *LeaderboardService) SetMemoryBudget(limit int64, policy string) {
+	s.store.SetMemoryBudget(store.MemoryBudget{
+		Limit:    limit,
+		Policy:   policy,
+		External: s.history.bytes.Load,
+	}, s.publishEviction)
+}
+
+// MemoryUsage returns the approximate bytes held for the board's members and
+// their score history, and the budget (0 when unlimited)
+func (s *LeaderboardService) MemoryUsage() (used, limit int64) {
+	used, limit = s.store.MemoryUsage()
+	return used + s.history.bytes.Load(), limit
+}
+
+// publishEviction announces a member evicted to make room
+func (s *LeaderboardService) publishEviction(user *store.User) {
+	s.events.Publish(events.Event{
+		Type:     events.TypeUserEvicted,
+		Username: user.Username,
+		Rating:   user.Rating,
+		Bot:      user.Bot,
+	})
+}
+
+// EnableAnomalyDetection starts watching score updates for implausible trajectories
+func (s *LeaderboardService) EnableAnomalyDetection(config AnomalyConfig) {
+	s.anomalies = NewAnomalyDetector(config)
+	s.events.Subscribe(s.anomalies.Handle)
+}
+
+// ListAnomalies returns users flagged by the anomaly detector
+func (s *LeaderboardService) ListAnomalies(ctx context.Context) []models.Anomaly {
+	if s.anomalies == nil {
+		return []models.Anomaly{}
 	}
+	return s.anomalies.List()
+}
 
-	log.Printf("✓ Successfully seeded %d users", count)
-	return nil
+// ResolveAnomaly clears a user's flag after review and unfreezes their updates
+func (s *LeaderboardService) ResolveAnomaly(ctx context.Context, username string) error {
+	if s.anomalies == nil {
+		return ErrAnomalyNotFound
+	}
+	return s.anomalies.Resolve(username)
+}
+
+// SetMatchLedger replaces the ledger used to deduplicate match submissions
+func (s *LeaderboardService) SetMatchLedger(ledger MatchLedger) {
+	s.matches = ledger
 }
 
-// GetLeaderboard retrieves paginated leaderboard with correct ranks
-func (s *LeaderboardService) GetLeaderboard(ctx context.Context, page, limit int) (*models.LeaderboardResponse, error) {
+// SetSessionRevoker sets what ends the logins of users merged away
+func (s *LeaderboardService) SetSessionRevoker(sessions SessionRevoker) {
+	s.sessions = sessions
+}
+
+// SetRatingStrategy selects the calculator used for score submissions
+func (s *LeaderboardService) SetRatingStrategy(strategy RatingStrategy) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	s.strategy = strategy
+}
+
+// ratingStrategy returns the calculator for score submissions, which the
+// board config API may swap at runtime
+func (s *LeaderboardService) ratingStrategy() RatingStrategy {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.strategy
+}
+
+// ListOptions filters which users appear in leaderboard views
+type ListOptions struct {
+	ExcludeBots bool
+}
+
+// rankedUsers returns the users visible under opts, sorted by rating
+func (s *LeaderboardService) rankedUsers(ctx context.Context, opts ListOptions) ([]*store.User, error) {
+	allUsers, err := s.store.GetAllUsers(ctx)
+	if err != nil || !opts.ExcludeBots {
+		return allUsers, err
+	}
+
+	filtered := make([]*store.User, 0, len(allUsers))
+	for _, user := range allUsers {
+		if !user.Bot {
+			filtered = append(filtered, user)
+		}
+	}
+	return filtered, nil
+}
+
+// GetLeaderboard retrieves paginated leaderboard with correct ranks. Users
+// hidden from the leaderboard are left out of the pages but still counted,
+// in the total and in the ranks of the users below them.
+func (s *LeaderboardService) GetLeaderboard(ctx context.Context, page, limit int, opts ListOptions) (*models.LeaderboardResponse, error) {
+	if pager, ok := s.store.(store.Pager); ok && !opts.ExcludeBots {
+		return s.pagedLeaderboard(ctx, pager, page, limit)
+	}
+
 	// Get all users sorted
-	allUsers := s.store.GetAllUsers()
+	allUsers, err := s.rankedUsers(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
 	total := len(allUsers)
 
-	// Calculate pagination
+	// Calculate pagination over the listed users
 	offset := (page - 1) * limit
-	if offset >= total {
-		return &models.LeaderboardResponse{
-			Entries:    []models.LeaderboardEntry{},
-			Page:       page,
-			Limit:      limit,
-			TotalUsers: int64(total),
-			HasMore:    false,
-		}, nil
-	}
-
 	end := offset + limit
-	if end > total {
-		end = total
-	}
 
-	// Calculate ranks considering ties
-	entries := make([]models.LeaderboardEntry, 0, end-offset)
-	currentRank := 1
+	// Ranks count every user above the page, ties and hidden users included
+	entries := make([]models.LeaderboardEntry, 0, min(limit, max(total-offset, 0)))
+	var ranks ranking.Counter
+	listed, hasMore := 0, false
 
-	for i := 0; i < end; i++ {
-		// Update rank when score changes
-		if i > 0 && allUsers[i].Rating != allUsers[i-1].Rating {
-			currentRank = i + 1
+	for _, user := range allUsers {
+		currentRank := ranks.Next(user.Key)
+		entry, ok := s.listEntry(user, currentRank)
+		if !ok {
+			continue
+		}
+		if listed == end {
+			hasMore = true
+			break
 		}
 
 		// Only add entries within the requested page
-		if i >= offset {
-			entries = append(entries, models.LeaderboardEntry{
-				Rank:     currentRank,
-				Username: allUsers[i].Username,
-				Rating:   allUsers[i].Rating,
-			})
+		if listed >= offset {
+			entries = append(entries, entry)
+		}
+		listed++
+	}
+
+	s.enrich(ctx, entries)
+
+	return &models.LeaderboardResponse{
+		Entries:    entries,
+		Page:       page,
+		Limit:      limit,
+		TotalUsers: int64(total),
+		HasMore:    hasMore,
+	}, nil
+}
+
+// pagedLeaderboard is GetLeaderboard for stores that read pages by rank.
+// Once anyone has privacy settings, the board is walked to from the top, a
+// stretch at a time, with hidden users left out, as derived boards are.
+func (s *LeaderboardService) pagedLeaderboard(ctx context.Context, pager store.Pager, page, limit int) (*models.LeaderboardResponse, error) {
+	offset := (page - 1) * limit
+	var users []store.RankedUser
+	var total int
+	var err error
+	hasMore := false
+	if !s.privacy.any() {
+		if users, total, err = pager.Page(ctx, offset, limit); err != nil {
+			return nil, err
+		}
+		hasMore = offset+len(users) < total
+	} else {
+		stretch := offset + limit + 1
+		skipped := 0
+	walk:
+		for from := 0; ; from += stretch {
+			var batch []store.RankedUser
+			if batch, total, err = pager.Page(ctx, from, stretch); err != nil {
+				return nil, err
+			}
+			for _, user := range batch {
+				switch _, _, ok := s.privacy.listed(user.User.Username); {
+				case !ok:
+				case skipped < offset:
+					skipped++
+				case len(users) == limit:
+					hasMore = true
+					break walk
+				default:
+					users = append(users, user)
+				}
+			}
+			if from+stretch >= total {
+				break
+			}
 		}
 	}
 
-	hasMore := end < total
+	entries := make([]models.LeaderboardEntry, 0, len(users))
+	for _, user := range users {
+		if entry, ok := s.listEntry(user.User, user.Rank); ok {
+			entries = append(entries, entry)
+		}
+	}
+	s.enrich(ctx, entries)
 
 	return &models.LeaderboardResponse{
 		Entries:    entries,
@@ -94,103 +368,569 @@ func (s *LeaderboardService) GetLeaderboard(ctx context.Context, page, limit int
 	}, nil
 }
 
-// GetUserRank retrieves a specific user's rank
+// ListUsersByName returns up to limit users whose usernames sort after
+// cursor, for browsing the board alphabetically rather than by rank. Users
+// hidden from the leaderboard or anonymized are left out, since the order
+// would give their names away.
+func (s *LeaderboardService) ListUsersByName(ctx context.Context, cursor string, limit int) (*models.UserListResponse, error) {
+	// One extra user tells whether another page follows
+	var users []*store.User
+	for after := cursor; len(users) <= limit; {
+		batch := s.store.UsersAfter(after, limit+1)
+		for _, user := range batch {
+			if s.privacy.public(user.Username) {
+				users = append(users, user)
+			}
+		}
+		if len(batch) <= limit {
+			break
+		}
+		after = batch[len(batch)-1].Username
+	}
+	hasMore := len(users) > limit
+	if hasMore {
+		users = users[:limit]
+	}
+
+	entries := make([]models.LeaderboardEntry, 0, len(users))
+	for _, user := range users {
+		entry, _ := s.listEntry(user, s.store.RankForKey(user.Key))
+		entries = append(entries, entry)
+	}
+	s.enrich(ctx, entries)
+
+	response := &models.UserListResponse{
+		Entries:    entries,
+		Sort:       "username",
+		Limit:      limit,
+		TotalUsers: int64(s.store.GetUserCount()),
+		HasMore:    hasMore,
+	}
+	if hasMore {
+		response.NextCursor = entries[len(entries)-1].Username
+	}
+	return response, nil
+}
+
+// CountInRange counts users rated between minRating and maxRating inclusive,
+// so populations above a threshold can be sized without exporting the board
+func (s *LeaderboardService) CountInRange(ctx context.Context, minRating, maxRating int, opts ListOptions) (*models.CountResponse, error) {
+	total := s.store.GetUserCount()
+	if opts.ExcludeBots {
+		bots, err := s.store.GetBotCount(ctx)
+		if err != nil {
+			return nil, err
+		}
+		total -= bots
+	}
+	return &models.CountResponse{
+		MinRating:    minRating,
+		MaxRating:    maxRating,
+		Count:        int64(s.store.CountInRange(minRating, maxRating, opts.ExcludeBots)),
+		TotalUsers:   int64(total),
+		ExcludesBots: opts.ExcludeBots,
+	}, nil
+}
+
+// GetUserRank retrieves a specific user's rank. Users hidden from search
+// are only found by themselves (see WithViewer).
 func (s *LeaderboardService) GetUserRank(ctx context.Context, username string) (*models.UserRankResponse, error) {
+	if !s.findable(ctx, username) {
+		return nil, store.ErrUserNotFound
+	}
 	user, err := s.store.GetUser(username)
 	if err != nil {
 		return nil, err
 	}
 
-	rank, err := s.store.GetUserRank(username)
+	rank, err := s.store.GetUserRank(ctx, username)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.UserRankResponse{
-		Username: username,
-		Rating:   user.Rating,
-		Rank:     int64(rank),
+		Username:   username,
+		Rating:     user.Rating,
+		Rank:       int64(rank),
+		Bot:        user.Bot,
+		ExpiresAt:  expiresAt(user),
+		AchievedAt: achievedAt(user),
 	}, nil
 }
 
 // UpdateScore updates a user's score
 func (s *LeaderboardService) UpdateScore(ctx context.Context, username string, newRating int) error {
+	return s.updateScore(ctx, username, newRating, "")
+}
+
+// updateScore stores newRating and records why it changed
+func (s *LeaderboardService) updateScore(ctx context.Context, username string, newRating int, reason string) error {
 	// Check if user exists
 	user, err := s.store.GetUser(username)
 	if err != nil {
 		return err
 	}
 
-	oldRating := user.Rating
+	if s.anomalies != nil && s.anomalies.IsFrozen(username) {
+		return ErrUserFrozen
+	}
+	if s.moderation.isFrozen(username, s.clock.Now()) {
+		return ErrUserSuspended
+	}
+
+	return s.commitScore(ctx, user, newRating, events.Event{Reason: reason})
+}
+
+// commitScore stores a new rating and publishes its score_updated event,
+// which carries any extra fields set on e and the source from ctx
+func (s *LeaderboardService) commitScore(ctx context.Context, user *store.User, newRating int, e events.Event) error {
+	// Update score, and the derived boards with it. The rating replaced is
+	// read again here, as user may be stale by the time the write's turn comes.
+	oldRating, _, err := s.writeAcrossBoards(ctx, user.Username, newRating, func() (int, bool, error) {
+		previous := user.Rating
+		if current, err := s.store.GetUser(user.Username); err == nil {
+			previous = current.Rating
+		}
+		if err := s.store.AddUser(user.Username, newRating); err != nil {
+			return previous, false, fmt.Errorf("failed to update score: %w", err)
+		}
+		return previous, true, nil
+	})
+	if err != nil {
+		return err
+	}
+	s.mirrorShadow(user.Username, newRating, user.Bot)
+	s.placeSegments(ctx, user.Username, oldRating, user.Bot)
+
+	e.Type = events.TypeScoreUpdated
+	e.Username = user.Username
+	e.Rating = newRating
+	e.PreviousRating = oldRating
+	e.Bot = user.Bot
+	e.Source = SourceFrom(ctx)
+	e.OccurredAt = occurredAtFrom(ctx)
+	e.SyncedBy = syncedByFrom(ctx)
+	s.events.Publish(e)
+
+	log.Printf("Updated %s: %d -> %d", user.Username, oldRating, newRating)
+	return nil
+}
 
-	// Update score
-	if err := s.store.AddUser(username, newRating); err != nil {
-		return fmt.Errorf("failed to update score: %w", err)
+// raiseScore stores rating only if it beats the user's current one. The
+// comparison happens in the store, so a concurrent lower submission can't
+// overwrite it; a submission that doesn't raise the rating publishes nothing.
+func (s *LeaderboardService) raiseScore(ctx context.Context, username string, rating int, reason string) error {
+	user, err := s.store.GetUser(username)
+	if err != nil {
+		return err
+	}
+	if s.anomalies != nil && s.anomalies.IsFrozen(username) {
+		return ErrUserFrozen
+	}
+	if s.moderation.isFrozen(username, s.clock.Now()) {
+		return ErrUserSuspended
 	}
 
-	log.Printf("Updated %s: %d -> %d", username, oldRating, newRating)
+	previous, raised, err := s.writeAcrossBoards(ctx, username, rating, func() (int, bool, error) {
+		previous, raised, err := s.store.RaiseRating(username, rating)
+		if err != nil {
+			return previous, false, fmt.Errorf("failed to update score: %w", err)
+		}
+		return previous, raised, nil
+	})
+	if err != nil {
+		return err
+	}
+	if !raised {
+		return nil
+	}
+	s.mirrorShadow(username, rating, user.Bot)
+	s.placeSegments(ctx, username, previous, user.Bot)
+	s.events.Publish(events.Event{
+		Type:           events.TypeScoreUpdated,
+		Username:       username,
+		Rating:         rating,
+		PreviousRating: previous,
+		Bot:            user.Bot,
+		Source:         SourceFrom(ctx),
+		Reason:         reason,
+		OccurredAt:     occurredAtFrom(ctx),
+		SyncedBy:       syncedByFrom(ctx),
+	})
+
+	log.Printf("Updated %s: %d -> %d", username, previous, rating)
 	return nil
 }
 
-// SearchUser searches for users by username prefix
-func (s *LeaderboardService) SearchUser(ctx context.Context, query string) ([]models.UserRankResponse, error) {
-	users := s.store.SearchUsers(query, 10000)
+// SubmitScore calculates a user's new rating with the leaderboard's rating
+// strategy and stores it. Submissions carrying a match ID that was already
+// applied return the original result instead of being applied again.
+func (s *LeaderboardService) SubmitScore(ctx context.Context, username string, req models.UpdateScoreRequest) (*models.UpdateScoreResponse, error) {
+	if req.MatchID == "" {
+		updated, err := s.applyScore(ctx, username, req)
+		if err != nil {
+			return nil, err
+		}
+		return &models.UpdateScoreResponse{
+			Message:   "Score updated successfully",
+			Rating:    updated.Rating,
+			ExpiresAt: expiresAt(updated),
+		}, nil
+	}
 
-	results := make([]models.UserRankResponse, 0, len(users))
-	for _, user := range users {
-		rank, _ := s.store.GetUserRank(user.Username)
-		results = append(results, models.UserRankResponse{
-			Username: user.Username,
-			Rating:   user.Rating,
-			Rank:     int64(rank),
+	key := username + ":" + req.MatchID
+	rating, done, claimed, err := s.matches.Claim(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMatchLedgerUnavailable, err)
+	}
+	if !claimed {
+		if !done {
+			return nil, ErrMatchInProgress
+		}
+		return &models.UpdateScoreResponse{
+			Message:   "Match already applied",
+			Rating:    rating,
+			MatchID:   req.MatchID,
+			Duplicate: true,
+		}, nil
+	}
+
+	updated, err := s.applyScore(ctx, username, req)
+	if err != nil {
+		s.releaseMatch(ctx, key)
+		return nil, err
+	}
+	s.completeMatch(ctx, key, updated.Rating)
+
+	return &models.UpdateScoreResponse{
+		Message:   "Score updated successfully",
+		Rating:    updated.Rating,
+		MatchID:   req.MatchID,
+		ExpiresAt: expiresAt(updated),
+	}, nil
+}
+
+// applyScore runs the rating strategy, stores the result and returns the
+// updated user. The user's submissions are applied one at a time, so
+// strategies that build on the current rating don't lose concurrent updates.
+func (s *LeaderboardService) applyScore(ctx context.Context, username string, req models.UpdateScoreRequest) (*store.User, error) {
+	lock := &s.scoreLocks[shardFor(username, scoreLockStripes)]
+	lock.Lock()
+	defer lock.Unlock()
+
+	user, err := s.store.GetUser(username)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy := s.ratingStrategy()
+	newRating, err := strategy.Calculate(user.Rating, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The candidate formula sees the shadow board's rating from before this write
+	shadowRating, shadowOK := s.calculateShadow(username, user, req)
+
+	reason := req.Reason
+	if reason == "" {
+		reason = models.ReasonMatch
+	}
+	ctx = withSegments(ctx, req.Country, req.Team)
+	if _, ok := strategy.(highestWins); ok {
+		err = s.raiseScore(ctx, username, req.Rating, reason)
+	} else {
+		err = s.updateScore(ctx, username, newRating, reason)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if shadowOK {
+		s.mirrorShadow(username, shadowRating, user.Bot)
+	}
+
+	// Scores for timed events drop off the board once their TTL passes
+	if req.TTLSeconds > 0 {
+		return s.store.SetExpiry(username, s.clock.Now().Add(time.Duration(req.TTLSeconds)*time.Second))
+	}
+
+	return s.store.GetUser(username)
+}
+
+// SearchOptions pages through search results
+type SearchOptions struct {
+	Page   int    // 1-based, ignored when Cursor is set
+	Limit  int    // Results per page
+	Cursor string // NextCursor of the previous page
+}
+
+// SearchUser returns a page of users whose names start with query, in rank
+// order, with the total number of matches. Results of recent queries are
+// served from the search cache, ranked afresh. Users hidden from search or anonymized are
+// left out, though still counted in the others' ranks.
+func (s *LeaderboardService) SearchUser(ctx context.Context, query string, opts SearchOptions) (*models.SearchResponse, error) {
+	q := store.SearchQuery{
+		Text:    normalizeSearchQuery(query),
+		Limit:   opts.Limit + 1,
+		Exclude: func(username string) bool { return !s.privacy.named(username) },
+	}
+	if opts.Cursor != "" {
+		after, err := decodeSearchCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		q.After = &after
+		opts.Page = 0
+	} else {
+		opts.Page = max(opts.Page, 1)
+		q.Offset = (opts.Page - 1) * opts.Limit
+	}
+
+	key := searchCacheKey{query: q.Text, limit: opts.Limit, page: opts.Page, cursor: opts.Cursor}
+	cached, generation, ok := s.searchCache.lookup(key, s.clock.Now())
+	if ok {
+		return s.rerank(cached), nil
+	}
+
+	// One extra match tells whether another page follows
+	matches, total, err := s.store.SearchRanked(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	response := &models.SearchResponse{
+		Results: make([]models.UserRankResponse, 0, min(len(matches), opts.Limit)),
+		Total:   total,
+		Page:    opts.Page,
+		Limit:   opts.Limit,
+		HasMore: len(matches) > opts.Limit,
+	}
+	keys := make([]int64, 0, len(response.Results))
+	for _, match := range matches[:min(len(matches), opts.Limit)] {
+		keys = append(keys, match.User.Key)
+		response.Results = append(response.Results, models.UserRankResponse{
+			Username:   match.User.Username,
+			Rating:     match.User.Rating,
+			Rank:       int64(match.Rank),
+			Bot:        match.User.Bot,
+			ExpiresAt:  expiresAt(match.User),
+			AchievedAt: achievedAt(match.User),
 		})
 	}
+	if response.HasMore {
+		last := matches[len(response.Results)-1].User
+		response.NextCursor = encodeSearchCursor(last.Key, last.Username)
+	}
 
-	return results, nil
+	s.searchCache.store(key, response, keys, generation, s.clock.Now())
+	return response, nil
 }
 
-// GetStats returns leaderboard statistics
-func (s *LeaderboardService) GetStats(ctx context.Context) (*models.StatsResponse, error) {
-	total, minRating, maxRating, avgRating := s.store.GetStats()
+// ErrInvalidCursor is returned for search cursors this server didn't issue
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// encodeSearchCursor makes an opaque cursor for the position after a result:
+// "rating:username", with the achieved time in Unix milliseconds as
+// "rating.ms:username" when the key has one
+func encodeSearchCursor(key int64, username string) string {
+	rating, achieved := ranking.Decode(key)
+	position := strconv.Itoa(rating)
+	if !achieved.IsZero() {
+		position += "." + strconv.FormatInt(achieved.UnixMilli(), 10)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(position + ":" + username))
+}
 
-	return &models.StatsResponse{
-		TotalUsers:    int64(total),
-		MinRating:     float64(minRating),
-		MaxRating:     float64(maxRating),
-		AverageRating: avgRating,
-	}, nil
+func decodeSearchCursor(cursor string) (store.SearchPosition, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return store.SearchPosition{}, ErrInvalidCursor
+	}
+	position, username, ok := strings.Cut(string(raw), ":")
+	if !ok || username == "" {
+		return store.SearchPosition{}, ErrInvalidCursor
+	}
+	rating, ms, stamped := strings.Cut(position, ".")
+	value, err := strconv.Atoi(rating)
+	if err != nil {
+		return store.SearchPosition{}, ErrInvalidCursor
+	}
+	var achieved time.Time
+	if stamped {
+		n, err := strconv.ParseInt(ms, 10, 64)
+		if err != nil || n <= 0 {
+			return store.SearchPosition{}, ErrInvalidCursor
+		}
+		achieved = time.UnixMilli(n)
+	}
+	return store.SearchPosition{Key: ranking.Key(value, achieved), Username: username}, nil
+}
+
+// StreamLeaderboard walks the full leaderboard in rank order and passes each
+// listed entry to fn
+func (s *LeaderboardService) StreamLeaderboard(ctx context.Context, opts ListOptions, fn func(models.LeaderboardEntry) error) error {
+	allUsers, err := s.rankedUsers(ctx, opts)
+	if err != nil {
+		return err
+	}
+	var ranks ranking.Counter
+
+	// Entries are enriched in batches so enrichers can amortise their lookups
+	batch := make([]models.LeaderboardEntry, 0, streamBatchSize)
+	flush := func() error {
+		s.enrich(ctx, batch)
+		for _, entry := range batch {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for _, user := range allUsers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		entry, ok := s.listEntry(user, ranks.Next(user.Key))
+		if !ok {
+			continue
+		}
+		batch = append(batch, entry)
+		if len(batch) == streamBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return flush()
 }
 
-// StartRandomUpdates simulates random score updates
+// computeStats scans the board for statistics
+func (s *LeaderboardService) computeStats(ctx context.Context, opts ListOptions) (*models.StatsResponse, error) {
+	total, minRating, maxRating, avgRating, err := s.store.GetStats(ctx, opts.ExcludeBots)
+	if err != nil {
+		return nil, err
+	}
+
+	botUsers := 0
+	if !opts.ExcludeBots {
+		if botUsers, err = s.store.GetBotCount(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	stats := &models.StatsResponse{
+		TotalUsers:      int64(total),
+		BotUsers:        int64(botUsers),
+		MinRating:       float64(minRating),
+		MaxRating:       float64(maxRating),
+		AverageRating:   avgRating,
+		ExcludesBots:    opts.ExcludeBots,
+		UpdatesBySource: s.sources.snapshot(),
+		ComputedAt:      s.clock.Now().UTC(),
+	}
+	counts, err := s.store.RatingCounts(ctx, opts.ExcludeBots)
+	if err != nil {
+		return nil, err
+	}
+	stats.MedianRating, stats.ModeRating, stats.StdDevRating = distribution(counts)
+
+	if capacity := s.store.Capacity(); capacity > 0 {
+		stats.Capacity = int64(capacity)
+		stats.Occupancy = float64(s.store.GetUserCount()) / float64(capacity)
+	}
+
+	return stats, nil
+}
+
+// StartRandomUpdates simulates random score updates while the simulator is
+// enabled, applying each cycle's picks as one batch. Interval, target and
+// batch changes apply without restarting the loop.
 func (s *LeaderboardService) StartRandomUpdates(ctx context.Context) {
-	ticker := time.NewTicker(5 * time.Second)
+	cfg := s.SimulationConfig()
+	ticker := s.clock.NewTicker(cfg.Interval)
 	defer ticker.Stop()
 
-	log.Println("🎲 Started random score updates (every 5 seconds)")
+	s.setSimulationRunning(true)
+	defer s.setSimulationRunning(false)
+
+	log.Printf("🎲 Started random score updates (every %s, target %s, batch %d, enabled %t, rand seed %d)", cfg.Interval, cfg.Target, cfg.BatchSize, cfg.Enabled, s.RandSeed())
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
-			count := s.store.GetUserCount()
-			if count == 0 {
+		case <-s.simulation.changed:
+			next := s.SimulationConfig()
+			if next.Interval != cfg.Interval {
+				ticker.Reset(next.Interval)
+			}
+			cfg = next
+			log.Printf("🎲 Reconfigured random score updates (every %s, target %s, batch %d, enabled %t)", cfg.Interval, cfg.Target, cfg.BatchSize, cfg.Enabled)
+		case <-ticker.C():
+			// Stand still while disabled or while a bulk job is rewriting the board
+			if !cfg.Enabled || s.simulationPaused() {
 				continue
 			}
 
-			// Get random user
-			randomIndex := rand.Intn(count)
-			allUsers := s.store.GetAllUsers()
-			if randomIndex >= len(allUsers) {
+			order, ratings := s.pickSimulationBatch(ctx, cfg.Target, cfg.BatchSize)
+			if len(order) == 0 {
 				continue
 			}
+			s.recordSimulatedBatch(s.applySimulatedBatch(order, ratings))
 
-			username := allUsers[randomIndex].Username
-			newRating := rand.Intn(4901) + 100
-
-			if err := s.UpdateScore(ctx, username, newRating); err != nil {
-				log.Printf("Failed to update random score: %v", err)
+			// Replicas started together drift apart instead of writing in lockstep
+			if cfg.Jitter > 0 {
+				ticker.Reset(s.random.jitter(cfg.Interval, cfg.Jitter))
 			}
 		}
 	}
 }
+
+// distribution derives the median, mode and population standard deviation
+// from per-rating counts, walking distinct ratings rather than users
+func distribution(counts map[int]int) (median float64, mode int, stddev float64) {
+	ratings := make([]int, 0, len(counts))
+	total, sum := 0, 0.0
+	for rating, n := range counts {
+		ratings = append(ratings, rating)
+		total += n
+		sum += float64(rating) * float64(n)
+	}
+	if total == 0 {
+		return 0, 0, 0
+	}
+	sort.Ints(ratings)
+
+	// The median is the middle rating, or the mean of the middle two
+	lowMid, highMid := (total-1)/2, total/2
+	var lowValue, highValue, seen int
+	for _, rating := range ratings {
+		n := counts[rating]
+		if seen <= lowMid && lowMid < seen+n {
+			lowValue = rating
+		}
+		if seen <= highMid && highMid < seen+n {
+			highValue = rating
+			break
+		}
+		seen += n
+	}
+	median = float64(lowValue+highValue) / 2
+
+	mean := sum / float64(total)
+	variance := 0.0
+	for _, rating := range ratings {
+		n := counts[rating]
+		if n > counts[mode] {
+			mode = rating
+		}
+		d := float64(rating) - mean
+		variance += d * d * float64(n)
+	}
+	stddev = math.Sqrt(variance / float64(total))
+	return median, mode, stddev
+}
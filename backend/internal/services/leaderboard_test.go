@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+)
+
+// slowDelta is the delta strategy taking its time, so concurrent
+// submissions overlap between reading the rating and writing the new one
+type slowDelta struct{ DeltaStrategy }
+
+func (d slowDelta) Calculate(current int, req models.UpdateScoreRequest) (int, error) {
+	time.Sleep(100 * time.Microsecond)
+	return d.DeltaStrategy.Calculate(current, req)
+}
+
+func TestConcurrentDeltasAllApply(t *testing.T) {
+	s := NewLeaderboardService(playersStore(t, 1))
+	s.SetRatingStrategy(slowDelta{})
+	const submitters, each = 8, 25
+
+	var wg sync.WaitGroup
+	for range submitters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range each {
+				if _, err := s.SubmitScore(context.Background(), "player_0", models.UpdateScoreRequest{Delta: 1}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	user, err := s.store.GetUser("player_0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 100 + submitters*each; user.Rating != want {
+		t.Errorf("rating %d after %d deltas of 1 from 100, want %d", user.Rating, submitters*each, want)
+	}
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"backend/internal/clock"
+)
+
+// leaderRecords holds a lease per job that runs on one replica at a time:
+// the runner's token and when the lease runs out
+const leaderRecords = "leaders"
+
+// leaderLease bounds how long a runner that dies keeps its job from the
+// other replicas. The runner renews it, and the others try to take it, at a
+// third of that.
+const leaderLease = 30 * time.Second
+
+// errLeaseHeld is returned when another replica holds a job's lease
+var errLeaseHeld = errors.New("lease held by another replica")
+
+// RunElected runs job on one replica at a time, across every replica on the
+// store, until ctx is cancelled or job returns by itself. The others keep
+// trying and take over within a lease of the runner stopping or dying. A
+// runner that loses its lease cancels job's context and waits for it to
+// return before trying again.
+func (s *LeaderboardService) RunElected(ctx context.Context, name string, job func(ctx context.Context)) {
+	token := newToken()
+	ticker := s.clock.NewTicker(leaderLease / 3)
+	defer ticker.Stop()
+
+	for {
+		until, err := s.holdLease(name, token, s.clock.Now())
+		switch {
+		case err == nil:
+			if s.runWhileLeased(ctx, name, token, until, ticker, job) {
+				return
+			}
+		case !errors.Is(err, errLeaseHeld):
+			log.Printf("Failed to take the %s lease: %v", name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// runWhileLeased runs job, renewing name's lease, until ctx is cancelled,
+// job returns or the lease is lost, then lets the lease go. It reports
+// whether job returned by itself.
+func (s *LeaderboardService) runWhileLeased(ctx context.Context, name, token string, until time.Time, ticker clock.Ticker, job func(ctx context.Context)) bool {
+	log.Printf("Running %s on this replica", name)
+	jobCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		job(jobCtx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+		s.releaseLease(name, token)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-done:
+			return true
+		case <-ticker.C():
+		}
+		now := s.clock.Now()
+		renewed, err := s.holdLease(name, token, now)
+		switch {
+		case err == nil:
+			until = renewed
+		case errors.Is(err, errLeaseHeld), !now.Before(until):
+			log.Printf("Lost the %s lease, stopping: %v", name, err)
+			return false
+		default:
+			// The lease still keeps the others out until it runs out
+			log.Printf("Failed to renew the %s lease: %v", name, err)
+		}
+	}
+}
+
+// holdLease takes or renews name's lease for token, returning when it runs
+// out. The lease is a store record, so it holds across replicas.
+func (s *LeaderboardService) holdLease(name, token string, now time.Time) (time.Time, error) {
+	until := now.Add(leaderLease)
+	_, err := s.store.UpdateRecord(leaderRecords, name, func(current string) (string, error) {
+		if holder, expiry, ok := strings.Cut(current, " "); ok && holder != token {
+			if expires, err := time.Parse(time.RFC3339Nano, expiry); err == nil && now.Before(expires) {
+				return "", errLeaseHeld
+			}
+		}
+		return token + " " + until.Format(time.RFC3339Nano), nil
+	})
+	return until, err
+}
+
+// releaseLease lets name's lease go if token still holds it. Should this
+// fail, the lease runs out instead.
+func (s *LeaderboardService) releaseLease(name, token string) {
+	s.store.UpdateRecord(leaderRecords, name, func(current string) (string, error) {
+		if holder, _, _ := strings.Cut(current, " "); holder != token {
+			return current, nil
+		}
+		return "", nil
+	})
+}
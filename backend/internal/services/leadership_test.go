@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"backend/internal/clock"
+)
+
+func TestLeaseElection(t *testing.T) {
+	s := NewLeaderboardService(playersStore(t, 1))
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name  string
+		run   func() (time.Time, error)
+		until time.Time // When the lease runs out, if taken
+		want  error
+	}{
+		{
+			name:  "the first replica takes the lease",
+			run:   func() (time.Time, error) { return s.holdLease("bots", "a", start) },
+			until: start.Add(leaderLease),
+		},
+		{
+			name: "another replica can't while it holds",
+			run:  func() (time.Time, error) { return s.holdLease("bots", "b", start.Add(time.Second)) },
+			want: errLeaseHeld,
+		},
+		{
+			name:  "other jobs are leased apart",
+			run:   func() (time.Time, error) { return s.holdLease("reports", "b", start) },
+			until: start.Add(leaderLease),
+		},
+		{
+			name:  "the runner renews",
+			run:   func() (time.Time, error) { return s.holdLease("bots", "a", start.Add(leaderLease/3)) },
+			until: start.Add(leaderLease/3 + leaderLease),
+		},
+		{
+			name: "a renewed lease keeps the others out past the first expiry",
+			run:  func() (time.Time, error) { return s.holdLease("bots", "b", start.Add(leaderLease)) },
+			want: errLeaseHeld,
+		},
+		{
+			name:  "another replica takes over once it runs out",
+			run:   func() (time.Time, error) { return s.holdLease("bots", "b", start.Add(leaderLease/3+leaderLease)) },
+			until: start.Add(leaderLease/3 + 2*leaderLease),
+		},
+		{
+			name: "the old runner can't renew",
+			run: func() (time.Time, error) {
+				return s.holdLease("bots", "a", start.Add(leaderLease/3+leaderLease+time.Second))
+			},
+			want: errLeaseHeld,
+		},
+		{
+			name: "releasing a lease held by another leaves it",
+			run: func() (time.Time, error) {
+				s.releaseLease("bots", "a")
+				return s.holdLease("bots", "a", start.Add(2*leaderLease))
+			},
+			want: errLeaseHeld,
+		},
+		{
+			name: "a released lease can be taken at once",
+			run: func() (time.Time, error) {
+				s.releaseLease("bots", "b")
+				return s.holdLease("bots", "a", start.Add(2*leaderLease))
+			},
+			until: start.Add(3 * leaderLease),
+		},
+	}
+	for _, step := range steps {
+		until, err := step.run()
+		if !errors.Is(err, step.want) || (err == nil && !until.Equal(step.until)) {
+			t.Errorf("%s: lease until %s, error %v; want until %s, error %v", step.name, until, err, step.until, step.want)
+		}
+	}
+}
+
+// electedJob counts the replicas running a job that runs until cancelled
+type electedJob struct {
+	running atomic.Int64
+	started atomic.Int64
+}
+
+func (j *electedJob) run(ctx context.Context) {
+	j.running.Add(1)
+	j.started.Add(1)
+	defer j.running.Add(-1)
+	<-ctx.Done()
+}
+
+// advanceUntil moves the clock a renewal at a time until done reports true
+func advanceUntil(t *testing.T, fake *clock.Fake, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		fake.Advance(leaderLease / 3)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRunElectedRunsOneReplicaAtATime(t *testing.T) {
+	st := playersStore(t, 1)
+	fake := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	replicas := make([]*LeaderboardService, 2)
+	jobs := make([]*electedJob, 2)
+	cancels := make([]context.CancelFunc, 2)
+	stopped := make([]chan struct{}, 2)
+	for i := range replicas {
+		replicas[i] = NewLeaderboardService(st)
+		replicas[i].SetClock(fake)
+		jobs[i] = &electedJob{}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancels[i], stopped[i] = cancel, make(chan struct{})
+		go func() {
+			defer close(stopped[i])
+			replicas[i].RunElected(ctx, "bots", jobs[i].run)
+		}()
+		if i == 0 {
+			// The first replica is elected before the second starts
+			advanceUntil(t, fake, "the first replica to run the job", func() bool { return jobs[0].running.Load() == 1 })
+		}
+	}
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	for range 10 {
+		fake.Advance(leaderLease / 3)
+		time.Sleep(time.Millisecond)
+	}
+	if got := jobs[1].started.Load(); got != 0 {
+		t.Fatalf("second replica ran the job %d times while the first held the lease", got)
+	}
+
+	// A runner that stops lets its lease go, and the other takes over
+	cancels[0]()
+	<-stopped[0]
+	if got := jobs[0].running.Load(); got != 0 {
+		t.Errorf("stopped replica still running the job")
+	}
+	advanceUntil(t, fake, "the second replica to take over", func() bool { return jobs[1].running.Load() == 1 })
+
+	// A runner whose lease is taken stops the job
+	if _, err := st.UpdateRecord(leaderRecords, "bots", func(string) (string, error) {
+		return "intruder " + fake.Now().Add(time.Hour).Format(time.RFC3339Nano), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	advanceUntil(t, fake, "the second replica to stop", func() bool { return jobs[1].running.Load() == 0 })
+	if got := jobs[1].started.Load(); got != 1 {
+		t.Errorf("second replica started the job %d times, want once", got)
+	}
+}
+
+func TestRunElectedReturnsWhenTheJobDoes(t *testing.T) {
+	s := NewLeaderboardService(playersStore(t, 1))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.RunElected(context.Background(), "bots", func(context.Context) {})
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("RunElected still running after the job returned")
+	}
+	if _, err := s.holdLease("bots", "other", time.Now()); err != nil {
+		t.Errorf("lease not released after the job returned: %v", err)
+	}
+}
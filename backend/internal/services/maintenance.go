@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+// Maintenance modes
+const (
+	MaintenanceOff      = "off"
+	MaintenanceReadOnly = "read_only" // Reads are served, writes answer 503
+	MaintenanceDown     = "down"      // Everything but the switch and staff reads answers 503
+)
+
+// writesPausedPoll is how often a background writer held back by
+// maintenance mode checks whether it is over
+const writesPausedPoll = time.Second
+
+// SetMaintenance switches the API's maintenance mode. The mode is kept in
+// the store, so every instance sharing it follows without a restart.
+func (s *LeaderboardService) SetMaintenance(ctx context.Context, mode, message string) models.MaintenanceStatus {
+	m := store.Maintenance{}
+	if mode != MaintenanceOff {
+		m = store.Maintenance{Mode: mode, Message: message, Since: s.clock.Now().UTC()}
+	}
+	s.store.SetMaintenance(m)
+
+	log.Printf("🚧 Maintenance mode %s", mode)
+	s.RecordIncident("maintenance", IncidentLifecycle, fmt.Sprintf("maintenance mode %s", mode))
+	return s.Maintenance()
+}
+
+// writesPaused reports whether maintenance mode holds back the background
+// writers: the simulator, scheduled imports, async submission workers, the
+// expiry sweeper and season closes. Any mode but off does.
+func (s *LeaderboardService) writesPaused() bool {
+	return s.store.Maintenance().Mode != ""
+}
+
+// Maintenance returns the API's maintenance mode
+func (s *LeaderboardService) Maintenance() models.MaintenanceStatus {
+	m := s.store.Maintenance()
+	if m.Mode == "" {
+		return models.MaintenanceStatus{Mode: MaintenanceOff}
+	}
+	since := m.Since
+	return models.MaintenanceStatus{Mode: m.Mode, Message: m.Message, Since: &since}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"backend/internal/clock"
+	"backend/internal/models"
+)
+
+func TestMaintenancePausesBackgroundWriters(t *testing.T) {
+	cases := []struct {
+		mode     string
+		bulkJob  bool
+		paused   bool
+		pausedBy []string
+		fetches  int64 // Score files fetched by a scheduled check
+	}{
+		{mode: MaintenanceOff, paused: false, pausedBy: []string{}, fetches: 1},
+		{mode: MaintenanceOff, bulkJob: true, paused: true, pausedBy: []string{"seed"}, fetches: 1},
+		{mode: MaintenanceReadOnly, paused: true, pausedBy: []string{"maintenance"}},
+		{mode: MaintenanceDown, bulkJob: true, paused: true, pausedBy: []string{"maintenance", "seed"}},
+	}
+	for _, c := range cases {
+		t.Run(c.mode, func(t *testing.T) {
+			var fetches atomic.Int64
+			partner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasSuffix(r.URL.Path, ".sig") {
+					fetches.Add(1)
+				}
+				w.Write([]byte("username,rating\n"))
+			}))
+			defer partner.Close()
+
+			s := NewLeaderboardService(playersStore(t, 1))
+			if err := s.EnableScoreImport(ImportConfig{URL: partner.URL + "/scores.csv", Secret: "partner-secret"}); err != nil {
+				t.Fatal(err)
+			}
+			s.SetMaintenance(context.Background(), c.mode, "")
+			if c.bulkJob {
+				defer s.beginBulkJob("seed")()
+			}
+
+			if got := s.simulationPaused(); got != c.paused {
+				t.Errorf("simulator paused %t, want %t", got, c.paused)
+			}
+			if got := s.GetSimulationStatus().PausedBy; !slices.Equal(got, c.pausedBy) {
+				t.Errorf("paused by %v, want %v", got, c.pausedBy)
+			}
+
+			s.scheduledImport(context.Background())
+			if got := fetches.Load(); got != c.fetches {
+				t.Errorf("scheduler fetched the score file %d times, want %d", got, c.fetches)
+			}
+		})
+	}
+}
+
+func TestMaintenanceHoldsBackQueueSweepsAndSeasons(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	st := playersStore(t, 2)
+	s := NewLeaderboardService(st)
+	s.SetClock(clock.NewFake(start))
+	if _, err := s.SubmitScore(ctx, "player_1", models.UpdateScoreRequest{Rating: 200, TTLSeconds: 60}); err != nil {
+		t.Fatal(err)
+	}
+	ends := start.Add(time.Hour)
+	if _, err := s.StartSeason(ctx, "s1", &ends); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.EnableScoreQueue(ScoreQueueConfig{Workers: 1}); err != nil {
+		t.Fatal(err)
+	}
+	s.StartScoreWorkers()
+	defer s.DrainScoreQueue(ctx)
+
+	s.SetMaintenance(ctx, MaintenanceReadOnly, "")
+	if _, err := s.EnqueueScore(ctx, "player_0", models.UpdateScoreRequest{Rating: 300}); err != nil {
+		t.Fatal(err)
+	}
+	later := start.Add(2 * time.Hour)
+	s.sweepExpired(later)
+	s.closeEndedSeason(ctx, later)
+	time.Sleep(100 * time.Millisecond)
+	if user, _ := st.GetUser("player_0"); user.Rating != 100 {
+		t.Errorf("queued submission applied during maintenance: player_0 at %d", user.Rating)
+	}
+	if _, err := st.GetUser("player_1"); err != nil {
+		t.Errorf("expired entry swept during maintenance: %v", err)
+	}
+	if s.CurrentSeason() == nil {
+		t.Errorf("season closed during maintenance")
+	}
+
+	s.SetMaintenance(ctx, MaintenanceOff, "")
+	s.sweepExpired(later)
+	s.closeEndedSeason(ctx, later)
+	if _, err := st.GetUser("player_1"); err == nil {
+		t.Errorf("expired entry kept once maintenance is off")
+	}
+	if s.CurrentSeason() != nil {
+		t.Errorf("ended season kept open once maintenance is off")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for user, _ := st.GetUser("player_0"); user.Rating != 300; user, _ = st.GetUser("player_0") {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued submission not applied once maintenance is off: player_0 at %d", user.Rating)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+// ErrMergeSelf is returned when a user would be merged into themselves
+var ErrMergeSelf = errors.New("a user can't be merged into themselves")
+
+// SessionRevoker ends a user's logins, as auth.Auth does
+type SessionRevoker interface {
+	// RevokeUser ends every session of username and returns how many
+	RevokeUser(username string) int
+}
+
+// mergeOptions say whose score a merge keeps and who asked for it
+type mergeOptions struct {
+	policy string // One of models.MergePolicy*, best if empty
+	actor  string // Staff member behind an admin merge; empty for a guest claim
+	reason string // Staff member's reason, recorded with the merge
+}
+
+// userMerge describes one user folded into another
+type userMerge struct {
+	previous        int      // The kept user's rating before the merge
+	rating          int      // The kept user's rating after it
+	mergedRating    int      // The merged user's rating
+	keptMerged      bool     // Whether the merged user's score was kept
+	historyMoved    int      // Score changes moved to the kept user's history
+	matchesMoved    int      // Applied match IDs moved to the kept user
+	milestonesMoved int      // Milestones credited to the kept user
+	identitiesMoved int      // External identities relinked to the kept user
+	rolesRevoked    []string // Roles granted to the merged user, now revoked
+	sessionsRevoked int      // The merged user's logins, now ended
+}
+
+// CheckMerge reports whether from can be merged into into, changing nothing
+func (s *LeaderboardService) CheckMerge(from, into string) error {
+	_, _, err := s.checkMerge(from, into)
+	return err
+}
+
+func (s *LeaderboardService) checkMerge(from, into string) (fromUser, intoUser *store.User, err error) {
+	if from == into {
+		return nil, nil, ErrMergeSelf
+	}
+	if fromUser, err = s.store.GetUser(from); err != nil {
+		return nil, nil, err
+	}
+	if intoUser, err = s.store.GetUser(into); err != nil {
+		return nil, nil, err
+	}
+	// A frozen user's score can't move, nor be moved onto someone else
+	for _, username := range []string{from, into} {
+		if s.anomalies != nil && s.anomalies.IsFrozen(username) {
+			return nil, nil, ErrUserFrozen
+		}
+		if s.moderation.isFrozen(username, s.clock.Now()) {
+			return nil, nil, ErrUserSuspended
+		}
+	}
+	return fromUser, intoUser, nil
+}
+
+// MergeUsers folds a duplicate account into the one that stays, on a staff
+// member's authority. See mergeUser for what moves; the merge is recorded
+// as a staff note on into, and its events carry the actor and reason.
+func (s *LeaderboardService) MergeUsers(ctx context.Context, req models.MergeUsersRequest, actor string) (*models.MergeUsersResponse, error) {
+	policy := req.Policy
+	if policy == "" {
+		policy = models.MergePolicyBest
+	}
+	merge, err := s.mergeUser(WithSource(ctx, SourceAdmin), req.From, req.Into, mergeOptions{
+		policy: policy,
+		actor:  actor,
+		reason: req.Reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	kept := models.MergeKeptInto
+	if merge.keptMerged {
+		kept = models.MergeKeptFrom
+	}
+	return &models.MergeUsersResponse{
+		From:            req.From,
+		Into:            req.Into,
+		Policy:          policy,
+		Rating:          merge.rating,
+		PreviousRating:  merge.previous,
+		FromRating:      merge.mergedRating,
+		Kept:            kept,
+		HistoryMoved:    merge.historyMoved,
+		MatchesMoved:    merge.matchesMoved,
+		MilestonesMoved: merge.milestonesMoved,
+		IdentitiesMoved: merge.identitiesMoved,
+		RolesRevoked:    merge.rolesRevoked,
+		SessionsRevoked: merge.sessionsRevoked,
+		Actor:           actor,
+		Reason:          req.Reason,
+	}, nil
+}
+
+// mergeUser folds from into into and removes from, as one transaction:
+//   - into keeps the score opts.policy picks, its own on a tie
+//   - into's new rating and from's removal land in one store write, with the
+//     derived boards updated alongside; if they can't be, both are undone
+//   - into then takes over from's score history, applied match IDs,
+//     milestones, staff notes and linked identities
+//   - from's role grants and logins end as soon as from is gone, so nobody
+//     acts as from in the meantime or once the name is registered again
+//
+// from leaves every board as an eviction with reason merge. Merges run one
+// at a time, so two can't move the same user.
+func (s *LeaderboardService) mergeUser(ctx context.Context, from, into string, opts mergeOptions) (userMerge, error) {
+	s.mergeMu.Lock()
+	defer s.mergeMu.Unlock()
+
+	fromUser, intoUser, err := s.checkMerge(from, into)
+	if err != nil {
+		return userMerge{}, err
+	}
+
+	merge := userMerge{mergedRating: fromUser.Rating}
+	switch opts.policy {
+	case models.MergePolicyLatest:
+		merge.keptMerged = s.history.lastChange(from).After(s.history.lastChange(into))
+	default:
+		merge.keptMerged = fromUser.Rating > intoUser.Rating
+	}
+	rating := intoUser.Rating
+	if merge.keptMerged {
+		rating = fromUser.Rating
+	}
+
+	var removed *store.User
+	previous, changed, err := s.writeAcrossBoards(ctx, into, rating, func() (int, bool, error) {
+		previous, user, err := s.store.MergeUsers(from, into, rating)
+		if err != nil {
+			return previous, false, fmt.Errorf("failed to merge: %w", err)
+		}
+		removed = user
+		return previous, previous != rating, nil
+	})
+	if err != nil {
+		// The derived boards undid into's write; put from back with it
+		if removed != nil {
+			if undoErr := s.store.RestoreUser(removed); undoErr != nil {
+				log.Printf("⚠️  Failed to restore %s after a failed merge: %v", from, undoErr)
+			}
+		}
+		return userMerge{}, err
+	}
+	merge.previous, merge.rating = previous, rating
+
+	// Grants to from's identities follow them to into, below; grants to the
+	// name itself end with it
+	merge.rolesRevoked = s.revokeAllRoles(Principal(PrincipalUser, from))
+	if merge.rolesRevoked == nil {
+		merge.rolesRevoked = []string{}
+	}
+	if s.sessions != nil {
+		merge.sessionsRevoked = s.sessions.RevokeUser(from)
+	}
+	revoked := merge.revoked(from)
+
+	note := ""
+	if opts.actor != "" {
+		note = fmt.Sprintf("merged %s into %s: %s", from, into, opts.reason)
+	}
+	if changed {
+		s.mirrorShadow(into, rating, intoUser.Bot)
+		s.events.Publish(events.Event{
+			Type:           events.TypeScoreUpdated,
+			Username:       into,
+			Rating:         rating,
+			PreviousRating: previous,
+			Bot:            intoUser.Bot,
+			Reason:         models.ReasonMerge,
+			Source:         SourceFrom(ctx),
+			Actor:          opts.actor,
+			Note:           note,
+		})
+	}
+
+	merge.historyMoved = s.history.absorb(from, into, s.clock.Now())
+	if mover, ok := s.matches.(MatchMover); ok {
+		merge.matchesMoved = mover.MoveMatches(from, into)
+	}
+	merge.milestonesMoved = s.milestones.rename(from, into)
+	merge.identitiesMoved = s.identities.relink(from, into)
+	s.moderation.absorb(from, into)
+	if err := s.privacy.forget(from); err != nil {
+		log.Printf("⚠️  Failed to drop the privacy settings of merged %s: %v", from, err)
+	}
+
+	s.events.Publish(events.Event{
+		Type:     events.TypeUserEvicted,
+		Username: from,
+		Rating:   removed.Rating,
+		Bot:      removed.Bot,
+		Reason:   models.ReasonMerge,
+		Source:   SourceFrom(ctx),
+		Actor:    opts.actor,
+		Note:     note,
+	})
+
+	if opts.actor != "" {
+		text := fmt.Sprintf("Merged %s (rating %d) into this account under the %s policy, keeping %d%s: %s",
+			from, fromUser.Rating, opts.policy, rating, revoked, opts.reason)
+		if _, err := s.AddStaffNote(ctx, into, text, opts.actor); err != nil {
+			log.Printf("⚠️  Failed to note the merge of %s on %s: %v", from, into, err)
+		}
+		log.Printf("🛡️  %s merged %s into %s: %d -> %d (%s)", opts.actor, from, into, previous, rating, opts.reason)
+	} else {
+		log.Printf("Merged %s into %s: %d -> %d%s", from, into, previous, rating, revoked)
+	}
+	return merge, nil
+}
+
+// revoked describes what a merge took from the merged user, for the audit
+// note, e.g. "; revoked moderator from bob and ended 2 session(s)"
+func (m userMerge) revoked(from string) string {
+	var taken []string
+	if len(m.rolesRevoked) > 0 {
+		taken = append(taken, fmt.Sprintf("revoked %s from %s", strings.Join(m.rolesRevoked, ", "), from))
+	}
+	if m.sessionsRevoked > 0 {
+		taken = append(taken, fmt.Sprintf("ended %d session(s)", m.sessionsRevoked))
+	}
+	if len(taken) == 0 {
+		return ""
+	}
+	return "; " + strings.Join(taken, " and ")
+}
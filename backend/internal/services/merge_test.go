@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"backend/internal/models"
+)
+
+// fakeSessions counts the logins of each user
+type fakeSessions map[string]int
+
+func (f fakeSessions) RevokeUser(username string) int {
+	n := f[username]
+	delete(f, username)
+	return n
+}
+
+func TestMergeRevokesGrantsAndSessions(t *testing.T) {
+	s := NewLeaderboardService(playersStore(t, 2))
+	sessions := fakeSessions{"player_0": 2, "player_1": 1}
+	s.SetSessionRevoker(sessions)
+	ctx := context.Background()
+	from := Principal(PrincipalUser, "player_0")
+	for _, role := range []string{RoleModerator, RoleWriter} {
+		if err := s.GrantRole(ctx, from, role); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	merge, err := s.MergeUsers(ctx, models.MergeUsersRequest{From: "player_0", Into: "player_1", Reason: "duplicate account"}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{RoleModerator, RoleWriter}; !slices.Equal(merge.RolesRevoked, want) || merge.SessionsRevoked != 2 {
+		t.Errorf("revoked %v and %d session(s), want %v and 2", merge.RolesRevoked, merge.SessionsRevoked, want)
+	}
+	// Gone by the time the merge returns, not once the eviction is handled
+	if roles := s.store.Roles(from); len(roles) > 0 {
+		t.Errorf("%s still holds %v", from, roles)
+	}
+	if sessions["player_1"] != 1 {
+		t.Error("the kept user's sessions were ended")
+	}
+
+	record, err := s.GetModerationRecord(ctx, "player_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(record.Notes) != 1 || !strings.Contains(record.Notes[0].Note, "revoked moderator, writer from player_0 and ended 2 session(s)") {
+		t.Errorf("merge note %+v doesn't record what was revoked", record.Notes)
+	}
+}
+
+func TestMergeWithNothingToRevoke(t *testing.T) {
+	s := NewLeaderboardService(playersStore(t, 2))
+	merge, err := s.MergeUsers(context.Background(), models.MergeUsersRequest{From: "player_0", Into: "player_1", Reason: "duplicate account"}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merge.RolesRevoked == nil || len(merge.RolesRevoked) > 0 || merge.SessionsRevoked != 0 {
+		t.Errorf("revoked %#v and %d session(s), want none", merge.RolesRevoked, merge.SessionsRevoked)
+	}
+}
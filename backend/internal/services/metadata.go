@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"backend/internal/models"
+)
+
+// ErrConfigUnavailable is returned when no resolved configuration was
+// recorded, e.g. for a service built without pkg/leaderboard
+var ErrConfigUnavailable = errors.New("configuration not recorded")
+
+// DefaultBoardID is the ID of the leaderboard unless configured otherwise
+const DefaultBoardID = "default"
+
+// SetBoardID names the board served by this service
+func (s *LeaderboardService) SetBoardID(id string) {
+	s.boardID = id
+}
+
+// BoardID returns the ID of the board served by this service
+func (s *LeaderboardService) BoardID() string {
+	return s.boardID
+}
+
+// SetResolvedConfig records the configuration the service was built with,
+// secrets already redacted. Call it before the service is used.
+func (s *LeaderboardService) SetResolvedConfig(config map[string]any) {
+	s.resolved = config
+}
+
+// ResolvedConfig returns the configuration recorded with SetResolvedConfig
+func (s *LeaderboardService) ResolvedConfig(ctx context.Context) (map[string]any, error) {
+	if s.resolved == nil {
+		return nil, ErrConfigUnavailable
+	}
+	return s.resolved, nil
+}
+
+// GetBoardMetadata describes how the board ranks and bounds scores so clients
+// don't have to hardcode these assumptions
+func (s *LeaderboardService) GetBoardMetadata(ctx context.Context) *models.BoardMetadata {
+	return &models.BoardMetadata{
+		ID:             s.boardID,
+		SortDirection:  "desc",
+		MinScore:       MinRating,
+		MaxScore:       MaxRating,
+		TiePolicy:      s.TiePolicy(),
+		TierBoundaries: s.TierBoundaries(),
+		Season:         s.CurrentSeason(),
+		RatingStrategy: s.ratingStrategy().Name(),
+		Capacity:       s.store.Capacity(),
+		MemberCount:    s.store.GetUserCount(),
+		CreatedAt:      s.createdAt,
+	}
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+const (
+	// maxMilestones bounds the milestones kept for the feed
+	maxMilestones = 50
+	// milestoneTopN is the leading group whose new entrants are announced
+	milestoneTopN = 10
+)
+
+// milestoneTracker turns score events into notable moments: a new #1, a
+// player entering the top 10 and a new highest rating. Bots, moderator
+// corrections and users who don't want to be named never make milestones.
+type milestoneTracker struct {
+	mu         sync.Mutex
+	store      store.Store
+	privacy    *privacySettings
+	record     int                // Highest rating the board has seen
+	milestones []models.Milestone // newest last
+	nextID     int
+	listeners  map[int]func(models.Milestone)
+}
+
+func newMilestoneTracker(st store.Store, privacy *privacySettings) *milestoneTracker {
+	_, _, highest, _, _ := st.GetStats(context.Background(), false)
+	return &milestoneTracker{store: st, privacy: privacy, record: highest, listeners: make(map[int]func(models.Milestone))}
+}
+
+// Handle records milestones; it is subscribed to the service's event bus
+func (t *milestoneTracker) Handle(e events.Event) {
+	if e.Type != events.TypeScoreUpdated && e.Type != events.TypeUserAdded {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previousRecord := t.record
+	t.record = max(t.record, e.Rating)
+	if e.Bot || e.Actor != "" || !t.privacy.public(e.Username) {
+		return
+	}
+
+	rank := t.store.RankForRating(e.Rating)
+	previousRank := 0 // Not on the board before
+	if e.Type == events.TypeScoreUpdated {
+		previousRank = t.store.RankForRating(e.PreviousRating)
+		if e.Rating > e.PreviousRating {
+			previousRank-- // The user's new rating is counted above the old one
+		}
+	}
+	entered := func(n int) bool { return rank <= n && (previousRank == 0 || previousRank > n) }
+
+	switch {
+	case entered(1):
+		t.add(e, models.MilestoneNewLeader, rank, previousRank, fmt.Sprintf("%s is the new #1 with %d", e.Username, e.Rating))
+	case entered(milestoneTopN):
+		t.add(e, models.MilestoneTopTen, rank, previousRank, fmt.Sprintf("%s entered the top %d at #%d", e.Username, milestoneTopN, rank))
+	}
+	if e.Rating > previousRecord && previousRecord > 0 {
+		t.add(e, models.MilestoneRecord, rank, previousRank, fmt.Sprintf("%s set a new record of %d, beating %d", e.Username, e.Rating, previousRecord))
+	}
+}
+
+// add appends a milestone; callers hold mu
+func (t *milestoneTracker) add(e events.Event, kind string, rank, previousRank int, title string) {
+	m := models.Milestone{
+		ID:           fmt.Sprintf("%s:%s:%d", kind, e.Username, e.Timestamp.UnixNano()),
+		Type:         kind,
+		Title:        title,
+		Username:     e.Username,
+		Rating:       e.Rating,
+		Rank:         rank,
+		PreviousRank: previousRank,
+		At:           e.Timestamp,
+	}
+	t.milestones = append(t.milestones, m)
+	for _, fn := range t.listeners {
+		fn(m)
+	}
+	if len(t.milestones) > maxMilestones {
+		t.milestones = append([]models.Milestone(nil), t.milestones[len(t.milestones)-maxMilestones:]...)
+	}
+}
+
+// rename credits from's milestones to into when from is merged into into,
+// and returns how many there were. IDs are kept so feed readers don't see
+// them as new.
+func (t *milestoneTracker) rename(from, into string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	renamed := 0
+	for i := range t.milestones {
+		m := &t.milestones[i]
+		if m.Username != from {
+			continue
+		}
+		m.Username = into
+		if rest, ok := strings.CutPrefix(m.Title, from+" "); ok {
+			m.Title = into + " " + rest
+		}
+		renamed++
+	}
+	return renamed
+}
+
+// Milestones returns the most recent milestones, newest first, leaving out
+// users who have since hidden or anonymized themselves
+func (s *LeaderboardService) Milestones() []models.Milestone {
+	t := s.milestones
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	milestones := make([]models.Milestone, 0, len(t.milestones))
+	for i := len(t.milestones) - 1; i >= 0; i-- {
+		if m := t.milestones[i]; s.privacy.public(m.Username) {
+			milestones = append(milestones, m)
+		}
+	}
+	return milestones
+}
+
+// OnMilestone calls fn with every new milestone until the returned func is
+// called. fn runs while the event is published, so it must not block.
+func (s *LeaderboardService) OnMilestone(fn func(models.Milestone)) func() {
+	t := s.milestones
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	id := t.nextID
+	t.nextID++
+	t.listeners[id] = fn
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.listeners, id)
+	}
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/models"
+)
+
+// ErrUserSuspended is returned when a moderator has frozen a user's updates
+var ErrUserSuspended = errors.New("user updates are frozen by a moderator")
+
+// ErrNotFrozen is returned when unfreezing a user without an active freeze
+var ErrNotFrozen = errors.New("user is not frozen")
+
+// moderation holds moderator freezes and staff notes. Every change is also
+// published as an event, so the event log doubles as the audit trail.
+type moderation struct {
+	mu     sync.RWMutex
+	frozen map[string]models.UserFreeze // username -> freeze, lapses at Until
+	notes  map[string][]models.StaffNote
+}
+
+func newModeration() *moderation {
+	return &moderation{
+		frozen: make(map[string]models.UserFreeze),
+		notes:  make(map[string][]models.StaffNote),
+	}
+}
+
+// activeFreeze returns username's freeze if it hasn't lapsed by now
+func (m *moderation) activeFreeze(username string, now time.Time) (models.UserFreeze, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	freeze, ok := m.frozen[username]
+	return freeze, ok && now.Before(freeze.Until)
+}
+
+func (m *moderation) isFrozen(username string, now time.Time) bool {
+	_, ok := m.activeFreeze(username, now)
+	return ok
+}
+
+// absorb moves from's staff notes to into when from is merged into into,
+// in the order they were written. from's freeze, which lapsed or would have
+// stopped the merge, is dropped.
+func (m *moderation) absorb(from, into string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if notes := m.notes[from]; len(notes) > 0 {
+		merged := append(slices.Clone(m.notes[into]), notes...)
+		slices.SortStableFunc(merged, func(a, b models.StaffNote) int { return a.CreatedAt.Compare(b.CreatedAt) })
+		m.notes[into] = merged
+	}
+	delete(m.notes, from)
+	delete(m.frozen, from)
+}
+
+// AdjustScore sets a user's rating on a moderator's authority. It bypasses
+// freezes and is recorded as an admin_adjustment carrying the reason and actor.
+// A change that takes the user's recent adjustments above the approval
+// threshold is staged instead, with status pending_approval.
+func (s *LeaderboardService) AdjustScore(ctx context.Context, username string, rating int, reason, actor string) (*models.ScoreAdjustmentResponse, error) {
+	user, err := s.store.GetUser(username)
+	if err != nil {
+		return nil, err
+	}
+	previous := user.Rating
+	if s.approvals.needsApproval(username, previous, rating, s.clock.Now()) {
+		return s.stageAdjustment(username, rating, previous, reason, actor), nil
+	}
+
+	if err := s.commitScore(WithSource(ctx, SourceAdmin), user, rating, events.Event{
+		Reason: models.ReasonAdminAdjustment,
+		Actor:  actor,
+		Note:   reason,
+	}); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🛡️  %s adjusted %s: %d -> %d (%s)", actor, username, previous, rating, reason)
+	return &models.ScoreAdjustmentResponse{
+		Username:       username,
+		Rating:         rating,
+		PreviousRating: previous,
+		Reason:         reason,
+		Actor:          actor,
+		Status:         models.AdjustmentApplied,
+	}, nil
+}
+
+// FreezeUser rejects a user's score updates for duration. Freezing an already
+// frozen user replaces the freeze.
+func (s *LeaderboardService) FreezeUser(ctx context.Context, username string, duration time.Duration, reason, actor string) (*models.UserFreeze, error) {
+	user, err := s.store.GetUser(username)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now().UTC()
+	freeze := models.UserFreeze{
+		Until:    now.Add(duration),
+		Reason:   reason,
+		Actor:    actor,
+		FrozenAt: now,
+	}
+
+	m := s.moderation
+	m.mu.Lock()
+	m.frozen[username] = freeze
+	m.mu.Unlock()
+
+	s.events.Publish(events.Event{
+		Type:     events.TypeUserFrozen,
+		Username: username,
+		Rating:   user.Rating,
+		Bot:      user.Bot,
+		Actor:    actor,
+		Note:     reason,
+		Until:    &freeze.Until,
+		Source:   SourceAdmin,
+	})
+
+	log.Printf("🛡️  %s froze %s until %s (%s)", actor, username, freeze.Until.Format(time.RFC3339), reason)
+	return &freeze, nil
+}
+
+// UnfreezeUser lifts a moderator freeze before it lapses
+func (s *LeaderboardService) UnfreezeUser(ctx context.Context, username, actor string) error {
+	m := s.moderation
+	m.mu.Lock()
+	freeze, ok := m.frozen[username]
+	delete(m.frozen, username)
+	m.mu.Unlock()
+
+	if !ok || !s.clock.Now().Before(freeze.Until) {
+		return ErrNotFrozen
+	}
+
+	e := events.Event{
+		Type:     events.TypeUserUnfrozen,
+		Username: username,
+		Actor:    actor,
+		Source:   SourceAdmin,
+	}
+	if user, err := s.store.GetUser(username); err == nil {
+		e.Rating = user.Rating
+		e.Bot = user.Bot
+	}
+	s.events.Publish(e)
+
+	log.Printf("🛡️  %s unfroze %s", actor, username)
+	return nil
+}
+
+// AddStaffNote annotates a user for other staff
+func (s *LeaderboardService) AddStaffNote(ctx context.Context, username, note, actor string) (*models.StaffNote, error) {
+	user, err := s.store.GetUser(username)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := models.StaffNote{
+		Note:      note,
+		Actor:     actor,
+		CreatedAt: s.clock.Now().UTC(),
+	}
+
+	m := s.moderation
+	m.mu.Lock()
+	m.notes[username] = append(m.notes[username], entry)
+	m.mu.Unlock()
+
+	s.events.Publish(events.Event{
+		Type:     events.TypeNoteAdded,
+		Username: username,
+		Rating:   user.Rating,
+		Bot:      user.Bot,
+		Actor:    actor,
+		Note:     note,
+		Source:   SourceAdmin,
+	})
+	return &entry, nil
+}
+
+// GetModerationRecord returns a user's active freeze and staff notes
+func (s *LeaderboardService) GetModerationRecord(ctx context.Context, username string) (*models.ModerationRecord, error) {
+	if _, err := s.store.GetUser(username); err != nil {
+		return nil, err
+	}
+
+	record := &models.ModerationRecord{Username: username}
+	if freeze, ok := s.moderation.activeFreeze(username, s.clock.Now()); ok {
+		record.Freeze = &freeze
+	}
+
+	m := s.moderation
+	m.mu.RLock()
+	record.Notes = slices.Clone(m.notes[username])
+	m.mu.RUnlock()
+	if record.Notes == nil {
+		record.Notes = []models.StaffNote{}
+	}
+	return record, nil
+}
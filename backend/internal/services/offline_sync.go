@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+)
+
+// offlineClockSkew is how far in the future an offline event may say it
+// happened, for clients whose clocks run a little fast
+const offlineClockSkew = 5 * time.Minute
+
+// offlineSyncLease bounds how long a sync holds its user, so a replica that
+// dies mid-sync doesn't block the user's next one for longer
+const offlineSyncLease = time.Minute
+
+// syncRecords holds a lease per user with a sync under way: the holder's
+// token and when the lease runs out
+const syncRecords = "offline_syncs"
+
+// ErrSyncInProgress is returned when a user's scores are already being
+// synced, on this replica or another
+var ErrSyncInProgress = errors.New("offline sync in progress")
+
+type offlineChangeKey struct{}
+
+// offlineChange is when an offline client made a change, and which client
+// synced it
+type offlineChange struct {
+	occurredAt time.Time
+	client     string
+}
+
+// withOfflineChange marks score changes made with ctx as having happened at
+// t on an offline client, synced by client
+func withOfflineChange(ctx context.Context, t time.Time, client string) context.Context {
+	return context.WithValue(ctx, offlineChangeKey{}, offlineChange{occurredAt: t.UTC(), client: client})
+}
+
+// occurredAtFrom returns when the offline change made with ctx happened, or
+// nil for changes made as they happen
+func occurredAtFrom(ctx context.Context) *time.Time {
+	if change, ok := ctx.Value(offlineChangeKey{}).(offlineChange); ok {
+		return &change.occurredAt
+	}
+	return nil
+}
+
+// syncedByFrom returns the offline client that synced the change made with
+// ctx, "" for changes made as they happen
+func syncedByFrom(ctx context.Context) string {
+	change, _ := ctx.Value(offlineChangeKey{}).(offlineChange)
+	return change.client
+}
+
+// OfflineEventOutcome is an offline event's reconciliation plus the error it
+// was rejected with
+type OfflineEventOutcome struct {
+	models.OfflineEventResult
+	Err error // Why a rejected event couldn't be applied
+}
+
+// OfflineSync is the reconciliation report of an offline sync. Its events
+// are in Outcomes, with their errors, rather than in Events.
+type OfflineSync struct {
+	models.OfflineSyncResponse
+	Outcomes []OfflineEventOutcome
+}
+
+// SyncOfflineScores applies score events client journaled while offline, in
+// the order they happened, under the board's rating strategy. Each event is
+// checked against the server-side changes made after it happened, read
+// again as the event is applied; changes client synced earlier don't count:
+//   - highest keeps the best rating, so an event that doesn't beat the
+//     current one is superseded, whenever it happened
+//   - absolute lets the last write win, so an event the server has changed
+//     the rating since is superseded
+//   - strategies that adjust the current rating, such as delta and elo, apply
+//     every event on top of it, rebasing events the server has changed since
+//
+// Event IDs are claimed like match IDs, so a retried sync applies each event
+// once. A sync without a client counts as its own client. One sync per user
+// runs at a time, across replicas; another answers ErrSyncInProgress.
+func (s *LeaderboardService) SyncOfflineScores(ctx context.Context, username, client string, events []models.OfflineScoreEvent) (*OfflineSync, error) {
+	user, err := s.store.GetUser(username)
+	if err != nil {
+		return nil, err
+	}
+	strategy := s.ratingStrategy()
+	now := s.clock.Now()
+	release, err := s.leaseSync(username, now)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if client == "" {
+		client = "sync:" + newToken()
+	}
+
+	ordered := slices.Clone(events)
+	slices.SortStableFunc(ordered, func(a, b models.OfflineScoreEvent) int {
+		return a.OccurredAt.Compare(b.OccurredAt)
+	})
+
+	sync := &OfflineSync{
+		OfflineSyncResponse: models.OfflineSyncResponse{
+			Username:       username,
+			RatingStrategy: strategy.Name(),
+			Rating:         user.Rating,
+		},
+		Outcomes: make([]OfflineEventOutcome, 0, len(ordered)),
+	}
+	for _, event := range ordered {
+		outcome := s.reconcileOffline(ctx, username, client, event, strategy, now)
+		switch outcome.Status {
+		case models.OfflineApplied, models.OfflineRebased:
+			sync.Applied++
+		}
+		if outcome.ServerChanges > 0 {
+			sync.Conflicts++
+		}
+		if outcome.Status != models.OfflineRejected {
+			sync.Rating = outcome.RatingAfter
+		}
+		sync.Outcomes = append(sync.Outcomes, outcome)
+	}
+	return sync, nil
+}
+
+// reconcileOffline applies one offline event client synced, or reports why
+// it wasn't
+func (s *LeaderboardService) reconcileOffline(ctx context.Context, username, client string, event models.OfflineScoreEvent, strategy RatingStrategy, now time.Time) OfflineEventOutcome {
+	outcome := OfflineEventOutcome{OfflineEventResult: models.OfflineEventResult{
+		EventID:    event.EventID,
+		OccurredAt: event.OccurredAt,
+	}}
+	reject := func(err error) OfflineEventOutcome {
+		outcome.Status = models.OfflineRejected
+		outcome.Err = err
+		return outcome
+	}
+
+	user, err := s.store.GetUser(username)
+	if err != nil {
+		return reject(err)
+	}
+	outcome.RatingBefore, outcome.RatingAfter = user.Rating, user.Rating
+	if event.OccurredAt.After(now.Add(offlineClockSkew)) {
+		return reject(models.FieldError{Field: "occurred_at", Rule: "past", Value: event.OccurredAt})
+	}
+	if _, err := strategy.Calculate(user.Rating, event.UpdateScoreRequest); err != nil {
+		return reject(err)
+	}
+
+	// Offline events without a match ID are deduplicated by their event ID
+	key := username + ":" + event.MatchID
+	if event.MatchID == "" {
+		key = username + ":offline:" + event.EventID
+	}
+	_, done, claimed, err := s.matches.Claim(ctx, key)
+	if err != nil {
+		return reject(fmt.Errorf("%w: %v", ErrMatchLedgerUnavailable, err))
+	}
+	if !claimed {
+		if !done {
+			return reject(ErrMatchInProgress)
+		}
+		outcome.Status = models.OfflineDuplicate
+		return outcome
+	}
+
+	// Read again once claimed, so changes made since the sync began count
+	if user, err = s.store.GetUser(username); err != nil {
+		s.releaseMatch(ctx, key)
+		return reject(err)
+	}
+	outcome.RatingBefore, outcome.RatingAfter = user.Rating, user.Rating
+	outcome.ServerChanges, outcome.LatestServerChange = changesSince(s.history.changes(username), event.OccurredAt, client)
+	if offlineSuperseded(strategy, event, user.Rating, outcome.ServerChanges) {
+		s.completeMatch(ctx, key, user.Rating)
+		outcome.Status = models.OfflineSuperseded
+		return outcome
+	}
+
+	updated, err := s.applyScore(withOfflineChange(ctx, event.OccurredAt, client), username, event.UpdateScoreRequest)
+	if err != nil {
+		s.releaseMatch(ctx, key)
+		return reject(err)
+	}
+	s.completeMatch(ctx, key, updated.Rating)
+	outcome.RatingAfter = updated.Rating
+	outcome.Status = models.OfflineApplied
+	if outcome.ServerChanges > 0 {
+		outcome.Status = models.OfflineRebased
+	}
+	return outcome
+}
+
+// offlineSuperseded reports whether the board keeps its current rating over
+// an offline event: under highest when the event doesn't beat it, and under
+// absolute when the server changed it after the event happened
+func offlineSuperseded(strategy RatingStrategy, event models.OfflineScoreEvent, current, serverChanges int) bool {
+	switch strategy.(type) {
+	case highestWins:
+		return event.Rating <= current
+	case AbsoluteStrategy:
+		return serverChanges > 0
+	}
+	return false
+}
+
+// changesSince counts the entries of history made after t, other than those
+// client synced, and returns the newest. Changes synced from offline clients
+// count from when they happened.
+func changesSince(history []models.HistoryEntry, t time.Time, client string) (int, *models.HistoryEntry) {
+	var count int
+	var latest *models.HistoryEntry
+	for i := range history {
+		at := effectiveTime(history[i])
+		if !at.After(t) || history[i].SyncedBy == client {
+			continue
+		}
+		count++
+		if latest == nil || at.After(effectiveTime(*latest)) {
+			latest = &history[i]
+		}
+	}
+	if latest != nil {
+		entry := *latest
+		latest = &entry
+	}
+	return count, latest
+}
+
+// effectiveTime is when a history entry's change happened
+func effectiveTime(entry models.HistoryEntry) time.Time {
+	if entry.OccurredAt != nil {
+		return *entry.OccurredAt
+	}
+	return entry.Timestamp
+}
+
+// leaseSync holds username for a sync until release is called or the lease
+// runs out. The lease is a store record, so it holds across replicas.
+func (s *LeaderboardService) leaseSync(username string, now time.Time) (release func(), err error) {
+	token := newToken()
+	_, err = s.store.UpdateRecord(syncRecords, username, func(current string) (string, error) {
+		if holder, until, ok := strings.Cut(current, " "); ok && holder != token {
+			if expires, err := time.Parse(time.RFC3339Nano, until); err == nil && now.Before(expires) {
+				return "", ErrSyncInProgress
+			}
+		}
+		return token + " " + now.Add(offlineSyncLease).Format(time.RFC3339Nano), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		// Should this fail, the lease runs out instead
+		s.store.UpdateRecord(syncRecords, username, func(current string) (string, error) {
+			if holder, _, _ := strings.Cut(current, " "); holder != token {
+				return current, nil // Ran out and taken by another sync
+			}
+			return "", nil
+		})
+	}, nil
+}
+
+func newToken() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
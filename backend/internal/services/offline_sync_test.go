@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"backend/internal/clock"
+	"backend/internal/models"
+)
+
+// offlineService keeps player_0 under the absolute strategy on a fake clock
+func offlineService(t *testing.T) (*LeaderboardService, *clock.Fake) {
+	t.Helper()
+	s := NewLeaderboardService(playersStore(t, 1))
+	s.SetRatingStrategy(AbsoluteStrategy{})
+	fake := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	s.SetClock(fake)
+	return s, fake
+}
+
+func offlineEvent(id string, ago time.Duration, now time.Time, rating int) models.OfflineScoreEvent {
+	return models.OfflineScoreEvent{EventID: id, OccurredAt: now.Add(-ago), UpdateScoreRequest: models.UpdateScoreRequest{Rating: rating}}
+}
+
+func TestOfflineSyncLeavesOutTheClientsOwnSyncs(t *testing.T) {
+	s, fake := offlineService(t)
+	ctx := context.Background()
+	now := fake.Now()
+	sync := func(client string, event models.OfflineScoreEvent) OfflineEventOutcome {
+		t.Helper()
+		result, err := s.SyncOfflineScores(ctx, "player_0", client, []models.OfflineScoreEvent{event})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Outcomes[0]
+	}
+
+	if got := sync("phone", offlineEvent("e1", 30*time.Minute, now, 1200)); got.Status != models.OfflineApplied {
+		t.Fatalf("first event %s, want applied", got.Status)
+	}
+	// An older event from the same phone isn't beaten by the phone's own sync
+	got := sync("phone", offlineEvent("e2", time.Hour, now, 1100))
+	if got.Status != models.OfflineApplied || got.ServerChanges != 0 {
+		t.Errorf("the phone's older event %s with %d server changes, want applied with none", got.Status, got.ServerChanges)
+	}
+	// Another device sees both phone syncs, and the one made after its event wins
+	got = sync("tablet", offlineEvent("e3", 45*time.Minute, now, 1000))
+	if got.Status != models.OfflineSuperseded || got.ServerChanges != 1 || got.LatestServerChange.SyncedBy != "phone" {
+		t.Errorf("the tablet's event %s with %d server changes (%+v), want superseded by the phone's", got.Status, got.ServerChanges, got.LatestServerChange)
+	}
+	// So does a sync that doesn't name its client
+	if got = sync("", offlineEvent("e4", 45*time.Minute, now, 1000)); got.Status != models.OfflineSuperseded {
+		t.Errorf("an unnamed client's event %s, want superseded", got.Status)
+	}
+}
+
+func TestOfflineSyncHoldsTheUser(t *testing.T) {
+	s, fake := offlineService(t)
+	ctx := context.Background()
+	events := []models.OfflineScoreEvent{offlineEvent("e1", time.Minute, fake.Now(), 1200)}
+
+	release, err := s.leaseSync("player_0", fake.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SyncOfflineScores(ctx, "player_0", "phone", events); !errors.Is(err, ErrSyncInProgress) {
+		t.Fatalf("sync while another holds the user: %v, want ErrSyncInProgress", err)
+	}
+	release()
+	if _, err := s.SyncOfflineScores(ctx, "player_0", "phone", events); err != nil {
+		t.Fatalf("sync once released: %v", err)
+	}
+	if _, ok := s.store.Record(syncRecords, "player_0"); ok {
+		t.Error("the sync's lease outlived it")
+	}
+
+	// A lease whose holder never released it runs out
+	if _, err := s.leaseSync("player_0", fake.Now()); err != nil {
+		t.Fatal(err)
+	}
+	fake.Advance(offlineSyncLease)
+	if _, err := s.SyncOfflineScores(ctx, "player_0", "phone", events); err != nil {
+		t.Errorf("sync after the lease ran out: %v", err)
+	}
+}
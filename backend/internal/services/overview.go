@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+)
+
+// maxOverviewErrors bounds the recent errors included in the admin overview
+const maxOverviewErrors = 10
+
+// GetOverview aggregates board sizes, update rates, cache hit rates, job
+// states, component health and recent errors for an ops dashboard
+func (s *LeaderboardService) GetOverview(ctx context.Context) (*models.AdminOverview, error) {
+	stats, err := s.GetStats(ctx, ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	health := s.GetHealthHistory(ctx, maxHealthIncidents)
+	memoryBytes, memoryBudget := s.MemoryUsage()
+
+	overview := &models.AdminOverview{
+		Maintenance:   s.Maintenance(),
+		StoreMode:     "memory",
+		UptimeSeconds: health.UptimeSeconds,
+		Boards: []models.BoardOverview{{
+			ID:           s.boardID,
+			Members:      stats.TotalUsers,
+			Bots:         stats.BotUsers,
+			Capacity:     stats.Capacity,
+			MemoryBytes:  memoryBytes,
+			MemoryBudget: memoryBudget,
+		}},
+		Updates: models.UpdateRates{
+			PerSecond1m: s.sources.rate(time.Minute),
+			PerSecond5m: s.sources.rate(5 * time.Minute),
+			BySource:    s.sources.snapshot(),
+		},
+		Caches:       []models.CacheStats{s.statsCache.cacheStats(), s.searchCache.cacheStats()},
+		Jobs:         s.jobStatuses(ctx),
+		Components:   health.Components,
+		RecentErrors: []models.HealthIncident{},
+		SLOs:         s.SLOStatuses(),
+		GeneratedAt:  time.Now().UTC(),
+	}
+	if s.doubleWrite != nil {
+		overview.StoreMode = "memory+double_write"
+	}
+	if queue, err := s.ScoreQueueStats(ctx); err == nil {
+		overview.QueueBackend = queue.Backend
+	}
+
+	for _, incident := range health.Incidents {
+		if len(overview.RecentErrors) == maxOverviewErrors {
+			break
+		}
+		switch incident.Kind {
+		case IncidentDegraded, IncidentFailure, IncidentAlert:
+			overview.RecentErrors = append(overview.RecentErrors, incident)
+		}
+	}
+	return overview, nil
+}
+
+// jobStatuses reports the background jobs this service runs
+func (s *LeaderboardService) jobStatuses(ctx context.Context) []models.JobStatus {
+	sim := s.GetSimulationStatus()
+	simulator := models.JobStatus{Name: "simulator", Status: "stopped"}
+	switch {
+	case sim.Paused:
+		simulator.Status = "paused"
+		simulator.Detail = "paused by " + strings.Join(sim.PausedBy, ", ")
+	case sim.Running:
+		simulator.Status = "running"
+		simulator.Detail = fmt.Sprintf("%d updates applied", sim.UpdatesApplied)
+	}
+	jobs := []models.JobStatus{simulator}
+
+	season := models.JobStatus{Name: "season", Status: "idle"}
+	if current := s.CurrentSeason(); current != nil {
+		season.Status = "running"
+		season.Detail = "season " + current.ID
+		if current.EndsAt != nil {
+			season.Detail += " ends " + current.EndsAt.Format(time.RFC3339)
+		}
+	}
+	jobs = append(jobs, season)
+
+	inflation := models.JobStatus{Name: "inflation", Status: "stopped"}
+	if report := s.GetInflationReport(ctx); report.IntervalSeconds > 0 {
+		inflation.Status = "running"
+		inflation.Detail = fmt.Sprintf("%d samples, index %.1f", len(report.Samples), report.Index)
+	}
+	jobs = append(jobs, inflation)
+
+	if queue, err := s.ScoreQueueStats(ctx); err == nil {
+		status := "running"
+		if queue.Draining {
+			status = "draining"
+		}
+		jobs = append(jobs, models.JobStatus{
+			Name:   "score_queue",
+			Status: status,
+			Detail: fmt.Sprintf("%d queued, %d applied, %d failed", queue.Depth, queue.Applied, queue.Failed),
+		})
+	}
+
+	if s.webhooks != nil {
+		pending := 0
+		for _, delivery := range s.ListWebhookDeliveries() {
+			if delivery.Status == models.DeliveryPending {
+				pending++
+			}
+		}
+		status := "idle"
+		if pending > 0 {
+			status = "running"
+		}
+		jobs = append(jobs, models.JobStatus{
+			Name:   "season_webhooks",
+			Status: status,
+			Detail: fmt.Sprintf("%d deliveries pending", pending),
+		})
+	}
+
+	if status, err := s.ImportStatus(ctx); err == nil {
+		job := models.JobStatus{Name: "import", Status: "idle", Detail: "no file imported yet"}
+		if len(status.Jobs) > 0 {
+			last := status.Jobs[0]
+			if last.Status == models.ImportRunning {
+				job.Status = "running"
+			}
+			job.Detail = fmt.Sprintf("last job %s: %d created, %d updated, %d skipped", last.Status, last.Created, last.Updated, last.Skipped)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if s.doubleWrite != nil {
+		if report, err := s.GetDoubleWriteReport(ctx); err == nil {
+			jobs = append(jobs, models.JobStatus{
+				Name:   "double_write",
+				Status: "running",
+				Detail: fmt.Sprintf("%d mirrored, %.2f%% mismatched", report.MirroredWrites, report.MismatchRate*100),
+			})
+		}
+	}
+	return jobs
+}
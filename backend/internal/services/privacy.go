@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+// privacySettings holds the users who asked to share less; users without
+// settings share everything. Settings are kept as store records, so every
+// process on a shared store applies them, and they outlive evictions and
+// expiry, so a returning user keeps them.
+type privacySettings struct {
+	store store.Store
+}
+
+const (
+	// privacyRecords is the kind of store record holding users' settings
+	privacyRecords = "privacy"
+	// counterRecords is the kind of store record holding counters, such as
+	// the aliases handed out so far
+	counterRecords  = "counters"
+	privacyAliasKey = "privacy_aliases"
+)
+
+func newPrivacySettings(st store.Store) *privacySettings {
+	return &privacySettings{store: st}
+}
+
+// get returns username's settings, the zero value sharing everything
+func (p *privacySettings) get(username string) models.PrivacySettings {
+	var settings models.PrivacySettings
+	if encoded, ok := p.store.Record(privacyRecords, username); ok {
+		// Records are only written by set, so they always decode
+		_ = json.Unmarshal([]byte(encoded), &settings)
+	}
+	return settings
+}
+
+// set replaces username's settings, dropping them once they share everything
+func (p *privacySettings) set(username string, settings models.PrivacySettings) error {
+	encoded := ""
+	if settings != (models.PrivacySettings{}) {
+		data, err := json.Marshal(settings)
+		if err != nil {
+			return err
+		}
+		encoded = string(data)
+	}
+	_, err := p.store.UpdateRecord(privacyRecords, username, func(string) (string, error) {
+		return encoded, nil
+	})
+	return err
+}
+
+// nextAlias hands out the next anonymous alias. Its counter is a store
+// record, so processes sharing a store never hand out the same one.
+func (p *privacySettings) nextAlias() (string, error) {
+	count, err := p.store.UpdateRecord(counterRecords, privacyAliasKey, func(current string) (string, error) {
+		n, _ := strconv.Atoi(current)
+		return strconv.Itoa(n + 1), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return "Anonymous #" + count, nil
+}
+
+// any reports whether anyone has settings, for reads that only need to
+// filter once someone does
+func (p *privacySettings) any() bool {
+	return p.store.RecordCount(privacyRecords) > 0
+}
+
+// listed returns the name username is listed under, and whether they are
+// listed at all. Anonymized users are listed under their alias.
+func (p *privacySettings) listed(username string) (name string, anonymous, ok bool) {
+	settings := p.get(username)
+	switch {
+	case settings.HideFromLeaderboard:
+		return "", false, false
+	case settings.Anonymize:
+		return settings.Alias, true, true
+	}
+	return username, false, true
+}
+
+// named reports whether username may be found by name, through search or a
+// lookup. Anonymized users can't be, or their alias would be tied to them.
+func (p *privacySettings) named(username string) bool {
+	settings := p.get(username)
+	return !settings.HideFromSearch && !settings.Anonymize
+}
+
+// public reports whether username may be shown by their own name: in live
+// updates, the milestone feed and the alphabetical user list
+func (p *privacySettings) public(username string) bool {
+	settings := p.get(username)
+	return !settings.HideFromLeaderboard && !settings.Anonymize
+}
+
+// forget drops username's settings, once they are merged into another user
+// who keeps their own
+func (p *privacySettings) forget(username string) error {
+	return p.set(username, models.PrivacySettings{})
+}
+
+type viewerKey struct{}
+
+// WithViewer marks ctx as a request by username, who can always look
+// themselves up whatever their privacy settings
+func WithViewer(ctx context.Context, username string) context.Context {
+	if username == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, viewerKey{}, username)
+}
+
+// findable reports whether the caller may look username up by name.
+// Users hidden from search are reported as not found.
+func (s *LeaderboardService) findable(ctx context.Context, username string) bool {
+	if viewer, _ := ctx.Value(viewerKey{}).(string); viewer == username {
+		return true
+	}
+	return s.privacy.named(username)
+}
+
+// Privacy returns a user's privacy settings
+func (s *LeaderboardService) Privacy(username string) models.PrivacySettings {
+	return s.privacy.get(username)
+}
+
+// SetPrivacy changes what a user shares. Users are hidden from, or
+// anonymized in, every public read, but still counted in totals and ranks.
+// The first time a user anonymizes they are given an alias, which they keep.
+func (s *LeaderboardService) SetPrivacy(ctx context.Context, username string, req models.PrivacyRequest) (models.PrivacySettings, error) {
+	if _, err := s.store.GetUser(username); err != nil {
+		return models.PrivacySettings{}, err
+	}
+
+	p := s.privacy
+	settings := p.get(username)
+	settings.HideFromLeaderboard = req.HideFromLeaderboard
+	settings.HideFromSearch = req.HideFromSearch
+	settings.Anonymize = req.Anonymize
+	if settings.Anonymize && settings.Alias == "" {
+		alias, err := p.nextAlias()
+		if err != nil {
+			return models.PrivacySettings{}, err
+		}
+		settings.Alias = alias
+	}
+	if err := p.set(username, settings); err != nil {
+		return models.PrivacySettings{}, err
+	}
+
+	// Cached search pages may name the user
+	s.searchCache.reset()
+	return settings, nil
+}
+
+// listEntry makes the public leaderboard entry for user at rank, and reports
+// whether they are listed
+func (s *LeaderboardService) listEntry(user *store.User, rank int) (models.LeaderboardEntry, bool) {
+	entry := models.LeaderboardEntry{
+		Rank:       rank,
+		Username:   user.Username,
+		Rating:     user.Rating,
+		Bot:        user.Bot,
+		ExpiresAt:  expiresAt(user),
+		AchievedAt: achievedAt(user),
+	}
+	name, anonymous, ok := s.privacy.listed(user.Username)
+	entry.Username, entry.Anonymous = name, anonymous
+	return entry, ok
+}
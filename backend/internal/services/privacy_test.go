@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+func TestPrivacySettings(t *testing.T) {
+	st := store.NewMemoryStore()
+	p := newPrivacySettings(st)
+	if p.any() {
+		t.Fatal("any() with no settings")
+	}
+
+	hidden := models.PrivacySettings{HideFromSearch: true}
+	if err := p.set("alice", hidden); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.get("alice"); got != hidden {
+		t.Errorf("get = %+v, want %+v", got, hidden)
+	}
+	if !p.any() {
+		t.Error("any() after a user set settings")
+	}
+	// Another process on the same store sees them
+	if got := newPrivacySettings(st).get("alice"); got != hidden {
+		t.Errorf("settings not shared through the store: %+v", got)
+	}
+
+	// Sharing everything again drops the record
+	if err := p.set("alice", models.PrivacySettings{}); err != nil {
+		t.Fatal(err)
+	}
+	if p.any() {
+		t.Error("settings kept after the user shares everything")
+	}
+
+	for want := 1; want <= 3; want++ {
+		alias, err := p.nextAlias()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if alias != fmt.Sprintf("Anonymous #%d", want) {
+			t.Errorf("alias %q, want Anonymous #%d", alias, want)
+		}
+	}
+
+	if err := p.set("bob", models.PrivacySettings{Anonymize: true, Alias: "Anonymous #1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.forget("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.get("bob"); got != (models.PrivacySettings{}) {
+		t.Errorf("forgotten settings still read %+v", got)
+	}
+}
+
+func TestPrivacyListing(t *testing.T) {
+	cases := []struct {
+		name     string
+		settings models.PrivacySettings
+		listedAs string
+		listed   bool
+		named    bool
+		public   bool
+	}{
+		{"shares everything", models.PrivacySettings{}, "alice", true, true, true},
+		{"hidden from search", models.PrivacySettings{HideFromSearch: true}, "alice", true, false, true},
+		{"hidden from leaderboard", models.PrivacySettings{HideFromLeaderboard: true}, "", false, true, false},
+		{"anonymized", models.PrivacySettings{Anonymize: true, Alias: "Anonymous #4"}, "Anonymous #4", true, false, false},
+		{"hidden and anonymized", models.PrivacySettings{HideFromLeaderboard: true, Anonymize: true, Alias: "Anonymous #5"}, "", false, false, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := newPrivacySettings(store.NewMemoryStore())
+			if err := p.set("alice", c.settings); err != nil {
+				t.Fatal(err)
+			}
+			name, anonymous, listed := p.listed("alice")
+			if name != c.listedAs || listed != c.listed || anonymous != (c.listed && c.settings.Anonymize) {
+				t.Errorf("listed = %q, %v, %v; want %q, listed %v", name, anonymous, listed, c.listedAs, c.listed)
+			}
+			if got := p.named("alice"); got != c.named {
+				t.Errorf("named = %v, want %v", got, c.named)
+			}
+			if got := p.public("alice"); got != c.public {
+				t.Errorf("public = %v, want %v", got, c.public)
+			}
+		})
+	}
+}
+
+// privateService has players 0 to n-1 rated 1000 plus ten times their
+// number on a country board, with player_1 hidden and player_2 anonymized
+func privateService(t *testing.T, n int) *LeaderboardService {
+	t.Helper()
+	ctx := context.Background()
+	s := NewLeaderboardService(playersStore(t, n))
+	if err := s.EnableDerivedBoards(DerivedBoardsConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := range n {
+		if _, err := s.SubmitScore(ctx, fmt.Sprintf("player_%d", i), models.UpdateScoreRequest{Rating: 1000 + 10*i, Country: "US"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.SetPrivacy(ctx, "player_1", models.PrivacyRequest{HideFromLeaderboard: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SetPrivacy(ctx, "player_2", models.PrivacyRequest{Anonymize: true}); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestDerivedBoardLeavesOutHiddenUsers(t *testing.T) {
+	s := privateService(t, 10)
+	ctx := context.Background()
+
+	// Ratings run down from player_9, so player_1 is ranked 9th
+	var members []string
+	var ranks []int
+	for page := 1; ; page++ {
+		board, err := s.GetDerivedBoard(ctx, "country:US", page, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range board.Entries {
+			members = append(members, entry.Member)
+			ranks = append(ranks, entry.Rank)
+		}
+		if !board.HasMore {
+			break
+		}
+		if len(board.Entries) != 3 {
+			t.Errorf("page %d has %d entries but more follow", page, len(board.Entries))
+		}
+	}
+	want := []string{"player_9", "player_8", "player_7", "player_6", "player_5", "player_4", "player_3", "Anonymous #1", "player_0"}
+	if !slices.Equal(members, want) {
+		t.Errorf("members %v, want %v", members, want)
+	}
+	if ranks[len(ranks)-1] != 10 {
+		t.Errorf("player_0 ranked %d, want 10 with the hidden user still counted", ranks[len(ranks)-1])
+	}
+
+	// Pages walk past hidden users, so they are priced for them
+	if got := s.DerivedPageCost(ctx, "country:US", 1, 3); got != 5 {
+		t.Errorf("first page costs %d, want 3 entries and 2 users with settings", got)
+	}
+	if got := s.DerivedPageCost(ctx, "country:US", 5, 3); got != 10 {
+		t.Errorf("last page costs %d, want the whole board of 10", got)
+	}
+}
+
+func TestSeasonWebhookListsOnlyListedUsers(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer hook.Close()
+
+	s := privateService(t, 4)
+	s.EnableSeasonWebhooks(SeasonWebhookConfig{URLs: []string{hook.URL}, TopN: 3, MaxAttempts: 1})
+	ctx := context.Background()
+	if _, err := s.StartSeason(ctx, "s1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CloseSeason(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var payload models.SeasonResult
+	select {
+	case body := <-bodies:
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook delivered")
+	}
+	var names []string
+	for _, entry := range payload.Standings {
+		names = append(names, entry.Username)
+	}
+	if want := []string{"player_3", "Anonymous #1", "player_0"}; !slices.Equal(names, want) {
+		t.Errorf("webhook standings %v, want %v", names, want)
+	}
+	if last := payload.Standings[len(payload.Standings)-1]; last.Rank != 4 {
+		t.Errorf("player_0 ranked %d, want 4", last.Rank)
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"backend/internal/models"
+)
+
+var (
+	// ErrPrizesNotConfigured is returned when prizes are requested without prize bands
+	ErrPrizesNotConfigured = errors.New("no prize bands are configured")
+	// ErrNoClosedSeason is returned when prizes are requested before any season has closed
+	ErrNoClosedSeason = errors.New("no season has closed yet")
+)
+
+// PrizeBand awards a prize to a rank range or to the top percentage of the
+// board. Set either MinRank and MaxRank, or TopPercent.
+type PrizeBand struct {
+	Name       string
+	MinRank    int
+	MaxRank    int
+	TopPercent float64
+}
+
+// ParsePrizeBands reads bands written as "name:1-3" (ranks 1 to 3), "name:5"
+// (rank 5 only) or "name:10%" (top 10%), separated by commas. Earlier bands
+// take precedence.
+func ParsePrizeBands(spec string) ([]PrizeBand, error) {
+	var bands []PrizeBand
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, rule, ok := strings.Cut(item, ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("prize band %q: expected name:ranks or name:percent%%", item)
+		}
+
+		band := PrizeBand{Name: name}
+		if percent, isPercent := strings.CutSuffix(rule, "%"); isPercent {
+			value, err := strconv.ParseFloat(percent, 64)
+			if err != nil || value <= 0 || value > 100 {
+				return nil, fmt.Errorf("prize band %q: percent must be between 0 and 100", item)
+			}
+			band.TopPercent = value
+		} else {
+			from, to, isRange := strings.Cut(rule, "-")
+			if !isRange {
+				to = from
+			}
+			minRank, err1 := strconv.Atoi(from)
+			maxRank, err2 := strconv.Atoi(to)
+			if err1 != nil || err2 != nil || minRank < 1 || maxRank < minRank {
+				return nil, fmt.Errorf("prize band %q: expected a rank range like 1-3", item)
+			}
+			band.MinRank, band.MaxRank = minRank, maxRank
+		}
+		bands = append(bands, band)
+	}
+	return bands, nil
+}
+
+// SetPrizeBands configures the prize bands awarded from final standings
+func (s *LeaderboardService) SetPrizeBands(bands []PrizeBand) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	s.prizeBands = bands
+}
+
+func (s *LeaderboardService) currentPrizeBands() []PrizeBand {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.prizeBands
+}
+
+// GetPrizes lists the users qualifying for each prize band in the standings
+// frozen when the last season closed, or in the live standings with preview.
+// Under the shared_rank tie policy users tied across a band boundary share
+// the better rank, so a band can hold more users than its rank range; under
+// most_recent_first the latest to reach the rating takes the place.
+func (s *LeaderboardService) GetPrizes(ctx context.Context, preview bool) (*models.PrizesResponse, error) {
+	bands := s.currentPrizeBands()
+	if len(bands) == 0 {
+		return nil, ErrPrizesNotConfigured
+	}
+
+	response := &models.PrizesResponse{
+		BoardID:   s.boardID,
+		TiePolicy: s.TiePolicy(),
+		Preview:   preview,
+	}
+	var standings []models.LeaderboardEntry
+	if preview {
+		users, err := s.store.GetAllUsers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		standings = rankEntries(users)
+		response.Season = s.CurrentSeason()
+		response.FrozenAt = s.clock.Now().UTC()
+	} else {
+		result := s.LastSeasonResult()
+		if result == nil {
+			return nil, ErrNoClosedSeason
+		}
+		standings = result.Standings
+		season := result.Season
+		response.Season = &season
+		response.FrozenAt = result.ClosedAt
+	}
+	response.TotalUsers = len(standings)
+
+	// Each user wins at most one prize: the first band they qualify for
+	response.Bands = make([]models.PrizeBandResult, len(bands))
+	for i, band := range bands {
+		response.Bands[i] = models.PrizeBandResult{
+			Name:       band.Name,
+			MinRank:    band.MinRank,
+			MaxRank:    band.MaxRank,
+			TopPercent: band.TopPercent,
+			Winners:    []models.LeaderboardEntry{},
+		}
+		if band.TopPercent > 0 {
+			// Top 10% of 95 users is ranks 1-10
+			response.Bands[i].MinRank = 1
+			response.Bands[i].MaxRank = int(math.Ceil(float64(len(standings)) * band.TopPercent / 100))
+		}
+	}
+	for _, entry := range standings {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// Winners hidden from the leaderboard are counted but not listed
+		name, anonymous, listed := s.privacy.listed(entry.Username)
+		entry.Username, entry.Anonymous = name, anonymous
+		for i := range response.Bands {
+			band := &response.Bands[i]
+			if entry.Rank >= band.MinRank && entry.Rank <= band.MaxRank {
+				band.Count++
+				if listed {
+					band.Winners = append(band.Winners, entry)
+				}
+				break
+			}
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+// QueryCostError rejects a read estimated to walk more board entries than
+// the query cost budget allows
+type QueryCostError struct {
+	Cost   int
+	Budget int
+}
+
+func (e *QueryCostError) Error() string {
+	return fmt.Sprintf("query would read about %d entries, over the budget of %d", e.Cost, e.Budget)
+}
+
+// SetQueryCostBudget caps how many board entries one read may walk, so a
+// deep page, a huge search or a full export can't hog a shared backend; 0
+// leaves reads unpriced. Enforcing it, and exempting admins, is up to the
+// caller.
+func (s *LeaderboardService) SetQueryCostBudget(budget int) {
+	s.queryBudget.Store(int64(max(budget, 0)))
+}
+
+// QueryCostBudget returns the budget set with SetQueryCostBudget, 0 if reads
+// are unpriced
+func (s *LeaderboardService) QueryCostBudget() int {
+	return int(s.queryBudget.Load())
+}
+
+// PageCost estimates a page read by offset: every entry above the page is
+// walked to reach it, so the cost grows with depth as well as size. No read
+// walks more than the whole board.
+func (s *LeaderboardService) PageCost(page, limit int) int {
+	return min(page*limit, s.store.GetUserCount())
+}
+
+// DerivedPageCost estimates a page read of a derived board like PageCost.
+// Once anyone has privacy settings, pages of user boards walk past the
+// hidden users above them too, so up to one more entry per user with
+// settings is counted. No read walks more than the board.
+func (s *LeaderboardService) DerivedPageCost(ctx context.Context, id string, page, limit int) int {
+	if s.derived == nil {
+		return 0
+	}
+	walked := page * limit
+	if boardKind(id) != models.BoardKindTeam && s.privacy.any() {
+		walked += s.store.RecordCount(privacyRecords)
+	}
+	boards, err := s.derived.store.List(ctx)
+	if err != nil {
+		return walked
+	}
+	return min(walked, boards[id])
+}
+
+// SearchCost estimates a search for query, whatever the page, cursor or
+// limit: every match is read to count and order them. Stores that can count
+// the matches from a name index are priced by them, others by the board they
+// walk.
+func (s *LeaderboardService) SearchCost(query string) int {
+	if counter, ok := s.store.(store.PrefixCounter); ok {
+		return counter.CountPrefix(normalizeSearchQuery(query))
+	}
+	return s.store.GetUserCount()
+}
+
+// ExportCost estimates streaming the whole board, which walks every entry
+func (s *LeaderboardService) ExportCost() int {
+	return s.store.GetUserCount()
+}
+
+// CheckQueryCost returns a *QueryCostError if cost is over the budget
+func (s *LeaderboardService) CheckQueryCost(cost int) error {
+	if budget := s.QueryCostBudget(); budget > 0 && cost > budget {
+		return &QueryCostError{Cost: cost, Budget: budget}
+	}
+	return nil
+}
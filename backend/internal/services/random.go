@@ -0,0 +1,91 @@
+package services
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// randomSource holds the seeded generators behind seed data, the simulator
+// and guest names. Each has its own stream so a running simulator doesn't
+// shift the ratings a seed produces.
+type randomSource struct {
+	mu        sync.Mutex
+	seed      int64
+	seeding   *rand.Rand
+	simulator *rand.Rand
+	guests    *rand.Rand
+}
+
+func newRandomSource(seed int64) *randomSource {
+	r := &randomSource{}
+	r.reset(seed)
+	return r
+}
+
+func (r *randomSource) reset(seed int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seed = seed
+	r.seeding = rand.New(rand.NewSource(seed))
+	r.simulator = rand.New(rand.NewSource(seed + 1))
+	r.guests = rand.New(rand.NewSource(seed + 2))
+}
+
+// seedRating returns a rating between 100 and 5000 for a seeded user
+func (r *randomSource) seedRating() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.seeding.Intn(4901) + 100
+}
+
+// simulatorRating returns a rating between 100 and 5000 for a simulated update
+func (r *randomSource) simulatorRating() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.simulator.Intn(4901) + 100
+}
+
+// jitter returns d varied randomly by up to fraction either way
+func (r *randomSource) jitter(d time.Duration, fraction float64) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return time.Duration(float64(d) * (1 + fraction*(2*r.simulator.Float64()-1)))
+}
+
+// simulatorPick returns an index below n for the simulator's next target
+func (r *randomSource) simulatorPick(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.simulator.Intn(n)
+}
+
+// guestName returns a generated name for a new guest, e.g. guest_swift_otter
+func (r *randomSource) guestName() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return "guest_" + guestAdjectives[r.guests.Intn(len(guestAdjectives))] + "_" + guestAnimals[r.guests.Intn(len(guestAnimals))]
+}
+
+var (
+	guestAdjectives = []string{"brave", "calm", "clever", "eager", "fierce", "gentle", "happy", "jolly", "keen", "lucky", "mighty", "nimble", "quick", "quiet", "swift", "witty"}
+	guestAnimals    = []string{"badger", "crane", "falcon", "fox", "gecko", "heron", "koala", "lynx", "marten", "otter", "owl", "panda", "puffin", "raven", "tiger", "wolf"}
+)
+
+// SetRandSeed reseeds seed data, the simulator and guest names so a run with
+// the same seed produces the same users and updates
+func (s *LeaderboardService) SetRandSeed(seed int64) {
+	s.random.reset(seed)
+}
+
+// RandSeed returns the seed behind seed data and the simulator
+func (s *LeaderboardService) RandSeed() int64 {
+	s.random.mu.Lock()
+	defer s.random.mu.Unlock()
+	return s.random.seed
+}
+
+// defaultRandSeed picks a seed when none is configured
+func defaultRandSeed() int64 {
+	return time.Now().UnixNano()
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"backend/internal/models"
+)
+
+// Rate limit buckets. Every request draws from one, chosen by its method.
+const (
+	BucketReads  = "reads"  // GET and HEAD
+	BucketWrites = "writes" // Every other method
+)
+
+// DefaultRateLimitWindow is how long a rate limit window lasts when unset
+const DefaultRateLimitWindow = time.Minute
+
+// RateLimit caps how many requests each caller makes per window in each
+// bucket. A zero limit leaves that bucket unlimited.
+type RateLimit struct {
+	Reads  int
+	Writes int
+	Window time.Duration // Defaults to DefaultRateLimitWindow
+}
+
+// limit returns the cap for bucket, 0 if it is unlimited
+func (c RateLimit) limit(bucket string) int {
+	if bucket == BucketReads {
+		return c.Reads
+	}
+	return c.Writes
+}
+
+// rateLimiter counts requests per caller and bucket in fixed windows, which
+// start with a caller's first request in the bucket
+type rateLimiter struct {
+	mu      sync.Mutex
+	config  RateLimit
+	windows map[rateKey]*rateWindow
+	swept   time.Time // When ended windows were last dropped
+}
+
+type rateKey struct {
+	caller string
+	bucket string
+}
+
+type rateWindow struct {
+	start time.Time
+	used  int
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{windows: make(map[rateKey]*rateWindow)}
+}
+
+// SetRateLimit sets per-caller request limits; a zero RateLimit turns rate
+// limiting off. Windows already running keep their counts.
+func (s *LeaderboardService) SetRateLimit(config RateLimit) {
+	if config.Window <= 0 {
+		config.Window = DefaultRateLimitWindow
+	}
+	l := s.rateLimits
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.config = config
+}
+
+// RateLimited reports whether any bucket is limited
+func (s *LeaderboardService) RateLimited() bool {
+	l := s.rateLimits
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.config.Reads > 0 || l.config.Writes > 0
+}
+
+// TakeRateLimit counts a request by caller against bucket. It returns the
+// bucket's state after the request and whether the request is allowed; an
+// unlimited bucket returns a zero Limit and always allows.
+func (s *LeaderboardService) TakeRateLimit(caller, bucket string) (models.RateLimitBucket, bool) {
+	return s.rateLimits.take(caller, bucket, s.clock.Now())
+}
+
+// take counts a request by caller against bucket at now
+func (l *rateLimiter) take(caller, bucket string, now time.Time) (models.RateLimitBucket, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := l.config.limit(bucket)
+	if limit <= 0 {
+		return models.RateLimitBucket{Name: bucket}, true
+	}
+	l.sweepLocked(now)
+
+	key := rateKey{caller: caller, bucket: bucket}
+	window := l.windows[key]
+	if window == nil || !now.Before(window.start.Add(l.config.Window)) {
+		window = &rateWindow{start: now}
+		l.windows[key] = window
+	}
+	allowed := window.used < limit
+	if allowed {
+		window.used++
+	}
+	return l.stateLocked(bucket, window, now), allowed
+}
+
+// RateLimits returns caller's standing in every limited bucket without
+// counting a request
+func (s *LeaderboardService) RateLimits(caller string) []models.RateLimitBucket {
+	l := s.rateLimits
+	now := s.clock.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	buckets := []models.RateLimitBucket{}
+	for _, bucket := range []string{BucketReads, BucketWrites} {
+		if l.config.limit(bucket) <= 0 {
+			continue
+		}
+		window := l.windows[rateKey{caller: caller, bucket: bucket}]
+		if window == nil || !now.Before(window.start.Add(l.config.Window)) {
+			// A fresh window would start with the next request
+			window = &rateWindow{start: now}
+		}
+		buckets = append(buckets, l.stateLocked(bucket, window, now))
+	}
+	return buckets
+}
+
+// stateLocked describes window as a bucket. Must be called with the lock held.
+func (l *rateLimiter) stateLocked(bucket string, window *rateWindow, now time.Time) models.RateLimitBucket {
+	limit := l.config.limit(bucket)
+	resetAt := window.start.Add(l.config.Window)
+	return models.RateLimitBucket{
+		Name:          bucket,
+		Limit:         limit,
+		Remaining:     max(limit-window.used, 0),
+		ResetAt:       resetAt.UTC(),
+		ResetSeconds:  int((resetAt.Sub(now) + time.Second - 1) / time.Second),
+		WindowSeconds: int(l.config.Window / time.Second),
+	}
+}
+
+// sweepLocked drops ended windows, at most once per window length, so
+// callers who went away don't hold memory. Must be called with the lock held.
+func (l *rateLimiter) sweepLocked(now time.Time) {
+	if now.Sub(l.swept) < l.config.Window {
+		return
+	}
+	l.swept = now
+	for key, window := range l.windows {
+		if !now.Before(window.start.Add(l.config.Window)) {
+			delete(l.windows, key)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	type request struct {
+		at        time.Duration // Since start
+		caller    string
+		bucket    string
+		allowed   bool
+		remaining int
+		reset     int // Seconds until the window ends
+	}
+	cases := []struct {
+		name     string
+		config   RateLimit
+		requests []request
+	}{
+		{
+			name:   "unlimited bucket",
+			config: RateLimit{Writes: 1, Window: time.Minute},
+			requests: []request{
+				{caller: "a", bucket: BucketReads, allowed: true},
+				{caller: "a", bucket: BucketReads, allowed: true},
+			},
+		},
+		{
+			name:   "limit within a window",
+			config: RateLimit{Reads: 2, Window: time.Minute},
+			requests: []request{
+				{caller: "a", bucket: BucketReads, allowed: true, remaining: 1, reset: 60},
+				{at: 10 * time.Second, caller: "a", bucket: BucketReads, allowed: true, remaining: 0, reset: 50},
+				{at: 59 * time.Second, caller: "a", bucket: BucketReads, allowed: false, remaining: 0, reset: 1},
+			},
+		},
+		{
+			name:   "window starts at the first request and resets at its end",
+			config: RateLimit{Reads: 1, Window: time.Minute},
+			requests: []request{
+				{at: 30 * time.Second, caller: "a", bucket: BucketReads, allowed: true, remaining: 0, reset: 60},
+				{at: 89 * time.Second, caller: "a", bucket: BucketReads, allowed: false, remaining: 0, reset: 1},
+				{at: 90 * time.Second, caller: "a", bucket: BucketReads, allowed: true, remaining: 0, reset: 60},
+			},
+		},
+		{
+			name:   "callers and buckets count apart",
+			config: RateLimit{Reads: 1, Writes: 1, Window: time.Minute},
+			requests: []request{
+				{caller: "a", bucket: BucketReads, allowed: true, reset: 60},
+				{caller: "b", bucket: BucketReads, allowed: true, reset: 60},
+				{caller: "a", bucket: BucketWrites, allowed: true, reset: 60},
+				{caller: "a", bucket: BucketReads, allowed: false, reset: 60},
+			},
+		},
+		{
+			name:   "partial seconds round up",
+			config: RateLimit{Writes: 5, Window: time.Second},
+			requests: []request{
+				{caller: "a", bucket: BucketWrites, allowed: true, remaining: 4, reset: 1},
+				{at: 999 * time.Millisecond, caller: "a", bucket: BucketWrites, allowed: true, remaining: 3, reset: 1},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			l := newRateLimiter()
+			l.config = c.config
+			for i, r := range c.requests {
+				state, allowed := l.take(r.caller, r.bucket, start.Add(r.at))
+				if allowed != r.allowed || state.Remaining != r.remaining || state.ResetSeconds != r.reset {
+					t.Errorf("request %d: allowed %t, %d remaining, reset in %ds; want %t, %d, %ds",
+						i, allowed, state.Remaining, state.ResetSeconds, r.allowed, r.remaining, r.reset)
+				}
+				if state.Name != r.bucket {
+					t.Errorf("request %d: bucket %q, want %q", i, state.Name, r.bucket)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterSweepsEndedWindows(t *testing.T) {
+	l := newRateLimiter()
+	l.config = RateLimit{Reads: 1, Window: time.Minute}
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	l.take("a", BucketReads, start)
+	l.take("b", BucketReads, start.Add(30*time.Second))
+	// a's window has ended but the last sweep was under a window ago
+	l.take("c", BucketReads, start.Add(50*time.Second))
+	if len(l.windows) != 3 {
+		t.Fatalf("%d windows before the sweep, want 3", len(l.windows))
+	}
+	l.take("c", BucketReads, start.Add(80*time.Second))
+	if _, ok := l.windows[rateKey{caller: "a", bucket: BucketReads}]; ok || len(l.windows) != 2 {
+		t.Errorf("windows after the sweep %v, want a's dropped", l.windows)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	s := NewLeaderboardService(playersStore(t, 1))
+	s.SetRateLimit(RateLimit{Reads: 3})
+	s.TakeRateLimit("a", BucketReads)
+
+	buckets := s.RateLimits("a")
+	if len(buckets) != 1 || buckets[0].Name != BucketReads || buckets[0].Remaining != 2 || buckets[0].WindowSeconds != 60 {
+		t.Errorf("a's limits %+v, want 2 of 3 reads left in a 60s window", buckets)
+	}
+	// Inspecting doesn't count as a request
+	if again := s.RateLimits("a"); again[0].Remaining != 2 {
+		t.Errorf("inspecting counted: %d remaining", again[0].Remaining)
+	}
+	if fresh := s.RateLimits("b"); fresh[0].Remaining != 3 {
+		t.Errorf("a caller without requests has %d remaining, want 3", fresh[0].Remaining)
+	}
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"backend/internal/models"
+)
+
+// Rating bounds enforced on every calculated rating
+const (
+	MinRating = 100
+	MaxRating = 5000
+)
+
+// RatingStrategy calculates a user's new rating from a score submission.
+// Strategies only see the user's current rating and the submitted request, so
+// new game modes can plug in a calculator without touching the handlers.
+type RatingStrategy interface {
+	Name() string
+	Calculate(current int, req models.UpdateScoreRequest) (int, error)
+}
+
+// ratingStrategies holds the built-in calculators by name
+var ratingStrategies = map[string]RatingStrategy{
+	"absolute":  AbsoluteStrategy{},
+	"delta":     DeltaStrategy{},
+	"highest":   HighestStrategy{},
+	"elo":       EloStrategy{K: 32},
+	"glicko":    GlickoStrategy{Deviation: 50},
+	"trueskill": TrueSkillStrategy{Sigma: 100, Beta: 150, DrawMargin: 20},
+}
+
+// RatingStrategyByName returns a built-in calculator. An empty name selects "absolute".
+func RatingStrategyByName(name string) (RatingStrategy, error) {
+	if name == "" {
+		name = "absolute"
+	}
+
+	strategy, ok := ratingStrategies[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(ratingStrategies))
+		for n := range ratingStrategies {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown rating strategy %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return strategy, nil
+}
+
+// AbsoluteStrategy sets the rating to the submitted value
+type AbsoluteStrategy struct{}
+
+func (AbsoluteStrategy) Name() string { return "absolute" }
+
+func (AbsoluteStrategy) Calculate(current int, req models.UpdateScoreRequest) (int, error) {
+	if req.Rating == 0 {
+		return 0, models.FieldError{Field: "rating", Rule: "required"}
+	}
+	return req.Rating, nil
+}
+
+// HighestStrategy keeps a user's best submitted rating. Submissions are
+// written with a compare-and-raise in the store rather than read-then-write,
+// so concurrent submissions can't lower a best score.
+type HighestStrategy struct{}
+
+func (HighestStrategy) Name() string { return "highest" }
+
+func (HighestStrategy) Calculate(current int, req models.UpdateScoreRequest) (int, error) {
+	if req.Rating == 0 {
+		return 0, models.FieldError{Field: "rating", Rule: "required"}
+	}
+	return max(current, req.Rating), nil
+}
+
+// keepsHighest marks strategies whose submissions may only raise a rating
+func (HighestStrategy) keepsHighest() {}
+
+// highestWins is implemented by strategies that only raise ratings
+type highestWins interface {
+	keepsHighest()
+}
+
+// usesMatchResults reports whether a strategy rates players from match
+// results against an opponent rather than from a submitted rating
+func usesMatchResults(strategy RatingStrategy) bool {
+	switch strategy.(type) {
+	case EloStrategy, GlickoStrategy, TrueSkillStrategy:
+		return true
+	}
+	return false
+}
+
+// DeltaStrategy adds the submitted delta to the current rating
+type DeltaStrategy struct{}
+
+func (DeltaStrategy) Name() string { return "delta" }
+
+func (DeltaStrategy) Calculate(current int, req models.UpdateScoreRequest) (int, error) {
+	if req.Delta == 0 {
+		return 0, models.FieldError{Field: "delta", Rule: "required"}
+	}
+	return clampRating(current + req.Delta), nil
+}
+
+// EloStrategy applies a standard Elo update against the opponent's rating
+type EloStrategy struct {
+	K float64
+}
+
+func (EloStrategy) Name() string { return "elo" }
+
+func (e EloStrategy) Calculate(current int, req models.UpdateScoreRequest) (int, error) {
+	score, err := matchInputs(req)
+	if err != nil {
+		return 0, err
+	}
+
+	expected := 1 / (1 + math.Pow(10, float64(req.OpponentRating-current)/400))
+	return clampRating(current + int(math.Round(e.K*(score-expected)))), nil
+}
+
+// GlickoStrategy applies a single-game Glicko update. The store only keeps a
+// rating per user, so both players are assumed to have the same fixed deviation.
+type GlickoStrategy struct {
+	Deviation float64
+}
+
+func (GlickoStrategy) Name() string { return "glicko" }
+
+func (g GlickoStrategy) Calculate(current int, req models.UpdateScoreRequest) (int, error) {
+	score, err := matchInputs(req)
+	if err != nil {
+		return 0, err
+	}
+
+	q := math.Ln10 / 400
+	rd := g.Deviation
+	gRD := 1 / math.Sqrt(1+3*q*q*rd*rd/(math.Pi*math.Pi))
+	expected := 1 / (1 + math.Pow(10, -gRD*float64(current-req.OpponentRating)/400))
+	dSquared := 1 / (q * q * gRD * gRD * expected * (1 - expected))
+
+	change := q / (1/(rd*rd) + 1/dSquared) * gRD * (score - expected)
+	return clampRating(current + int(math.Round(change))), nil
+}
+
+// TrueSkillStrategy applies a simplified two-player TrueSkill mean update
+// with a fixed uncertainty, scaled to the leaderboard's rating range.
+type TrueSkillStrategy struct {
+	Sigma      float64
+	Beta       float64
+	DrawMargin float64
+}
+
+func (TrueSkillStrategy) Name() string { return "trueskill" }
+
+func (t TrueSkillStrategy) Calculate(current int, req models.UpdateScoreRequest) (int, error) {
+	score, err := matchInputs(req)
+	if err != nil {
+		return 0, err
+	}
+
+	c := math.Sqrt(2*t.Beta*t.Beta + 2*t.Sigma*t.Sigma)
+	diff := float64(current-req.OpponentRating) / c
+	eps := t.DrawMargin / c
+
+	var v float64
+	switch score {
+	case 1:
+		v = vWin(diff - eps)
+	case 0:
+		v = -vWin(-diff - eps)
+	default:
+		v = vDraw(diff, eps)
+	}
+
+	change := t.Sigma * t.Sigma / c * v
+	return clampRating(current + int(math.Round(change))), nil
+}
+
+func vWin(x float64) float64 {
+	denom := normCDF(x)
+	if denom < 1e-12 {
+		return -x
+	}
+	return normPDF(x) / denom
+}
+
+func vDraw(x, eps float64) float64 {
+	denom := normCDF(eps-x) - normCDF(-eps-x)
+	if denom < 1e-12 {
+		if x < 0 {
+			return -x - eps
+		}
+		return -x + eps
+	}
+	return (normPDF(-eps-x) - normPDF(eps-x)) / denom
+}
+
+func normPDF(x float64) float64 {
+	return math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+}
+
+func normCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// matchInputs validates the fields head-to-head strategies need and returns
+// the match score from the submitting user's perspective
+func matchInputs(req models.UpdateScoreRequest) (float64, error) {
+	if req.OpponentRating == 0 {
+		return 0, models.FieldError{Field: "opponent_rating", Rule: "required"}
+	}
+
+	switch req.Result {
+	case "win":
+		return 1, nil
+	case "loss":
+		return 0, nil
+	case "draw":
+		return 0.5, nil
+	default:
+		return 0, models.FieldError{Field: "result", Rule: "required"}
+	}
+}
+
+func clampRating(rating int) int {
+	if rating < MinRating {
+		return MinRating
+	}
+	if rating > MaxRating {
+		return MaxRating
+	}
+	return rating
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// maxSlowCommandPatterns bounds the distinct command patterns counted;
+// further patterns are counted under "other"
+const maxSlowCommandPatterns = 100
+
+// slowCommandLog is a go-redis hook that logs and counts commands slower
+// than a threshold, so accidental O(N) calls show up in production
+type slowCommandLog struct {
+	threshold time.Duration
+
+	mu     sync.Mutex
+	counts map[string]int64 // "COMMAND key:pattern" -> slow calls
+}
+
+func newSlowCommandLog(threshold time.Duration) *slowCommandLog {
+	return &slowCommandLog{threshold: threshold, counts: make(map[string]int64)}
+}
+
+func (l *slowCommandLog) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (l *slowCommandLog) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		if elapsed := time.Since(start); elapsed >= l.threshold && !blocking(cmd) {
+			l.record(commandPattern(cmd), elapsed)
+		}
+		return err
+	}
+}
+
+func (l *slowCommandLog) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		if elapsed := time.Since(start); elapsed >= l.threshold {
+			patterns := make([]string, 0, len(cmds))
+			for _, cmd := range cmds {
+				// Transactions arrive wrapped in MULTI/EXEC
+				if name := cmd.Name(); name == "multi" || name == "exec" {
+					continue
+				}
+				patterns = append(patterns, commandPattern(cmd))
+			}
+			l.record("pipeline["+strings.Join(patterns, ", ")+"]", elapsed)
+		}
+		return err
+	}
+}
+
+func (l *slowCommandLog) record(pattern string, elapsed time.Duration) {
+	log.Printf("🐢 Slow Redis command %s took %s (threshold %s)", pattern, elapsed.Round(time.Microsecond), l.threshold)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.counts[pattern]; !ok && len(l.counts) >= maxSlowCommandPatterns {
+		pattern = "other"
+	}
+	l.counts[pattern]++
+}
+
+// snapshot returns the slow call counts, or nil if there are none
+func (l *slowCommandLog) snapshot() map[string]int64 {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.counts) == 0 {
+		return nil
+	}
+	counts := make(map[string]int64, len(l.counts))
+	for pattern, n := range l.counts {
+		counts[pattern] = n
+	}
+	return counts
+}
+
+// blocking reports whether cmd waits for data by design, like XREADGROUP
+// with BLOCK, so its latency says nothing about the server
+func blocking(cmd redis.Cmder) bool {
+	switch strings.ToLower(cmd.Name()) {
+	case "blpop", "brpop", "blmove", "bzpopmin", "bzpopmax":
+		return true
+	}
+	for _, arg := range cmd.Args() {
+		if s, ok := arg.(string); ok && strings.EqualFold(s, "block") {
+			return true
+		}
+	}
+	return false
+}
+
+// commandPattern names a command and its key, with ID-like key segments
+// (any containing a digit) replaced by "*" to bound cardinality
+func commandPattern(cmd redis.Cmder) string {
+	name := strings.ToUpper(cmd.Name())
+	args := cmd.Args()
+	if len(args) < 2 {
+		return name
+	}
+	key, ok := args[1].(string)
+	if !ok {
+		return name
+	}
+
+	segments := strings.Split(key, ":")
+	for i, segment := range segments {
+		if strings.ContainsAny(segment, "0123456789") {
+			segments[i] = "*"
+		}
+	}
+	return fmt.Sprintf("%s %s", name, strings.Join(segments, ":"))
+}
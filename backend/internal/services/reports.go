@@ -0,0 +1,473 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/pkg/blobstore"
+)
+
+var (
+	// ErrReportsDisabled is returned for report lookups without daily reports enabled
+	ErrReportsDisabled = errors.New("daily reports are not enabled")
+	// ErrReportNotFound is returned for days without a stored report
+	ErrReportNotFound = errors.New("report not found")
+)
+
+// Report formats, each stored as its own blob
+const (
+	ReportJSON     = "json"
+	ReportMarkdown = "markdown"
+	ReportHTML     = "html"
+)
+
+// reportExtensions are the blob key extensions per format
+var reportExtensions = map[string]string{
+	ReportJSON:     "json",
+	ReportMarkdown: "md",
+	ReportHTML:     "html",
+}
+
+// ReportConfig enables the daily summary job
+type ReportConfig struct {
+	Blobs      blobstore.Store // Where reports are kept, defaults to memory
+	TopMovers  int             // Gainers and losers listed, defaults to 5
+	WebhookURL string          // POST each report's JSON here when set
+	Email      *EmailConfig    // Mail each report's HTML when set
+}
+
+// EmailConfig sends reports through an SMTP relay
+type EmailConfig struct {
+	Addr     string // host:port
+	From     string
+	To       []string
+	Username string // PLAIN auth when set
+	Password string
+}
+
+// dayActivity accumulates one day's events until its report is generated
+type dayActivity struct {
+	scoreUpdates int64
+	newUsers     int64
+	bySource     map[string]int64
+	active       map[string]struct{}
+	movers       map[string]*models.ReportMover // Humans only
+	records      []models.Milestone
+}
+
+func newDayActivity() *dayActivity {
+	return &dayActivity{
+		bySource: make(map[string]int64),
+		active:   make(map[string]struct{}),
+		movers:   make(map[string]*models.ReportMover),
+	}
+}
+
+// dailyReports collects activity per UTC day and renders it once the day is over
+type dailyReports struct {
+	mu      sync.Mutex
+	config  ReportConfig
+	started time.Time // Days that began earlier are reported as partial
+	days    map[string]*dayActivity
+	client  *http.Client
+}
+
+// EnableDailyReports starts collecting activity for daily summaries; call
+// StartReportScheduler to generate them
+func (s *LeaderboardService) EnableDailyReports(config ReportConfig) {
+	if config.Blobs == nil {
+		config.Blobs = blobstore.NewMemory()
+	}
+	if config.TopMovers <= 0 {
+		config.TopMovers = 5
+	}
+	s.reports = &dailyReports{
+		config:  config,
+		started: s.clock.Now().UTC(),
+		days:    make(map[string]*dayActivity),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+	s.events.Subscribe(s.reports.Handle)
+	s.OnMilestone(s.reports.handleMilestone)
+}
+
+// Handle counts score changes; it is subscribed to the service's event bus
+func (r *dailyReports) Handle(e events.Event) {
+	if e.Type != events.TypeScoreUpdated && e.Type != events.TypeUserAdded {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	day := r.day(e.Timestamp.UTC().Format(time.DateOnly))
+	if e.Type == events.TypeUserAdded {
+		day.newUsers++
+		return
+	}
+	day.scoreUpdates++
+	if e.Source != "" {
+		day.bySource[e.Source]++
+	}
+	day.active[e.Username] = struct{}{}
+	if e.Bot {
+		return
+	}
+	mover, ok := day.movers[e.Username]
+	if !ok {
+		mover = &models.ReportMover{Username: e.Username, From: e.PreviousRating}
+		day.movers[e.Username] = mover
+	}
+	mover.To = e.Rating
+	mover.Change = mover.To - mover.From
+}
+
+func (r *dailyReports) handleMilestone(m models.Milestone) {
+	if m.Type != models.MilestoneRecord {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	day := r.day(m.At.UTC().Format(time.DateOnly))
+	day.records = append(day.records, m)
+}
+
+// day returns the activity for date, creating it; callers hold mu
+func (r *dailyReports) day(date string) *dayActivity {
+	day, ok := r.days[date]
+	if !ok {
+		day = newDayActivity()
+		r.days[date] = day
+	}
+	return day
+}
+
+// StartReportScheduler generates each day's report once the day has ended,
+// checking every interval
+func (s *LeaderboardService) StartReportScheduler(ctx context.Context, interval time.Duration) {
+	if s.reports == nil {
+		return
+	}
+	ticker := s.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C():
+			today := now.UTC().Format(time.DateOnly)
+			for _, date := range s.reports.endedDays(today) {
+				if _, err := s.GenerateDailyReport(ctx, date); err != nil {
+					log.Printf("Failed to generate report for %s: %v", date, err)
+					s.RecordIncident("reports", IncidentFailure, fmt.Sprintf("report for %s: %v", date, err))
+				}
+			}
+		}
+	}
+}
+
+// endedDays lists collected days before today, oldest first. Today is
+// created so that quiet days still get a report.
+func (r *dailyReports) endedDays(today string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.day(today)
+	var ended []string
+	for date := range r.days {
+		if date < today {
+			ended = append(ended, date)
+		}
+	}
+	sort.Strings(ended)
+	return ended
+}
+
+// GenerateDailyReport renders the report for date (YYYY-MM-DD) from the
+// activity collected so far, stores it in every format and delivers it.
+// Days that have ended are then forgotten.
+func (s *LeaderboardService) GenerateDailyReport(ctx context.Context, date string) (*models.DailyReport, error) {
+	r := s.reports
+	if r == nil {
+		return nil, ErrReportsDisabled
+	}
+	dayStart, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return nil, models.FieldError{Field: "date", Rule: "type", Param: "date", Value: date}
+	}
+
+	now := s.clock.Now().UTC()
+	report := r.summarize(date, dayStart, now, s.privacy)
+
+	rendered, err := renderReport(report)
+	if err != nil {
+		return nil, err
+	}
+	for format, data := range rendered {
+		if err := r.config.Blobs.Put(ctx, reportKey(date, format), data); err != nil {
+			return nil, fmt.Errorf("store %s report: %w", format, err)
+		}
+	}
+
+	if !now.Before(dayStart.AddDate(0, 0, 1)) {
+		r.mu.Lock()
+		delete(r.days, date)
+		r.mu.Unlock()
+	}
+	log.Printf("📰 Generated report for %s", date)
+
+	s.deliverReport(ctx, report, rendered)
+	return report, nil
+}
+
+// summarize builds the report from a day's activity. Movers hidden from the
+// leaderboard are left out and anonymized ones listed under their alias.
+func (r *dailyReports) summarize(date string, dayStart, now time.Time, privacy *privacySettings) *models.DailyReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	day, ok := r.days[date]
+	if !ok {
+		day = newDayActivity()
+	}
+	report := &models.DailyReport{
+		Date:        date,
+		GeneratedAt: now,
+		Partial:     r.started.After(dayStart),
+		Activity: models.ReportActivity{
+			ScoreUpdates: day.scoreUpdates,
+			NewUsers:     day.newUsers,
+			ActiveUsers:  int64(len(day.active)),
+			BySource:     make(map[string]int64, len(day.bySource)),
+		},
+		TopGainers: []models.ReportMover{},
+		TopLosers:  []models.ReportMover{},
+		Records:    []models.Milestone{},
+	}
+	for _, m := range day.records {
+		if privacy.public(m.Username) {
+			report.Records = append(report.Records, m)
+		}
+	}
+	for source, n := range day.bySource {
+		report.Activity.BySource[source] = n
+	}
+
+	movers := make([]models.ReportMover, 0, len(day.movers))
+	for _, m := range day.movers {
+		name, _, ok := privacy.listed(m.Username)
+		if !ok {
+			continue
+		}
+		mover := *m
+		mover.Username = name
+		movers = append(movers, mover)
+	}
+	sort.Slice(movers, func(i, j int) bool {
+		if movers[i].Change != movers[j].Change {
+			return movers[i].Change > movers[j].Change
+		}
+		return movers[i].Username < movers[j].Username
+	})
+	for _, m := range movers {
+		if m.Change <= 0 || len(report.TopGainers) == r.config.TopMovers {
+			break
+		}
+		report.TopGainers = append(report.TopGainers, m)
+	}
+	for i := len(movers) - 1; i >= 0 && movers[i].Change < 0 && len(report.TopLosers) < r.config.TopMovers; i-- {
+		report.TopLosers = append(report.TopLosers, movers[i])
+	}
+	return report
+}
+
+// GetDailyReport returns a stored report in format (json, markdown or html)
+func (s *LeaderboardService) GetDailyReport(ctx context.Context, date, format string) ([]byte, error) {
+	if s.reports == nil {
+		return nil, ErrReportsDisabled
+	}
+	data, err := s.reports.config.Blobs.Get(ctx, reportKey(date, format))
+	if errors.Is(err, blobstore.ErrNotFound) {
+		return nil, ErrReportNotFound
+	}
+	return data, err
+}
+
+func reportKey(date, format string) string {
+	return "reports/" + date + "." + reportExtensions[format]
+}
+
+// deliverReport posts and mails a generated report; failures are logged
+// and recorded as incidents since the report itself is already stored
+func (s *LeaderboardService) deliverReport(ctx context.Context, report *models.DailyReport, rendered map[string][]byte) {
+	config := s.reports.config
+	if config.WebhookURL != "" {
+		if err := s.reports.post(ctx, config.WebhookURL, rendered[ReportJSON]); err != nil {
+			log.Printf("Failed to post report for %s: %v", report.Date, err)
+			s.RecordIncident("reports", IncidentFailure, "webhook: "+err.Error())
+		}
+	}
+	if config.Email != nil {
+		if err := mailReport(*config.Email, report, rendered[ReportHTML]); err != nil {
+			log.Printf("Failed to mail report for %s: %v", report.Date, err)
+			s.RecordIncident("reports", IncidentFailure, "email: "+err.Error())
+		}
+	}
+}
+
+func (r *dailyReports) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func mailReport(config EmailConfig, report *models.DailyReport, html []byte) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: Leaderboard report for %s\r\n", report.Date)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=utf-8\r\n\r\n")
+	msg.Write(html)
+
+	var auth smtp.Auth
+	if config.Username != "" {
+		host, _, _ := strings.Cut(config.Addr, ":")
+		auth = smtp.PlainAuth("", config.Username, config.Password, host)
+	}
+	return smtp.SendMail(config.Addr, auth, config.From, config.To, msg.Bytes())
+}
+
+// renderReport renders the report in every format
+func renderReport(report *models.DailyReport) (map[string][]byte, error) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	var html bytes.Buffer
+	if err := reportTemplate.Execute(&html, report); err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+		ReportJSON:     append(data, '\n'),
+		ReportMarkdown: []byte(reportMarkdown(report)),
+		ReportHTML:     html.Bytes(),
+	}, nil
+}
+
+func reportMarkdown(report *models.DailyReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Leaderboard report for %s\n\n", report.Date)
+	if report.Partial {
+		b.WriteString("_Partial: the server started during this day._\n\n")
+	}
+
+	a := report.Activity
+	b.WriteString("## Activity\n\n")
+	fmt.Fprintf(&b, "- Score updates: %d\n- New users: %d\n- Active users: %d\n", a.ScoreUpdates, a.NewUsers, a.ActiveUsers)
+	sources := make([]string, 0, len(a.BySource))
+	for source := range a.BySource {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		fmt.Fprintf(&b, "  - %s: %d\n", source, a.BySource[source])
+	}
+
+	moverTable := func(title string, movers []models.ReportMover) {
+		fmt.Fprintf(&b, "\n## %s\n\n", title)
+		if len(movers) == 0 {
+			b.WriteString("None\n")
+			return
+		}
+		b.WriteString("| Player | From | To | Change |\n|---|---:|---:|---:|\n")
+		for _, m := range movers {
+			fmt.Fprintf(&b, "| %s | %d | %d | %+d |\n", m.Username, m.From, m.To, m.Change)
+		}
+	}
+	moverTable("Top gainers", report.TopGainers)
+	moverTable("Top losers", report.TopLosers)
+
+	b.WriteString("\n## New records\n\n")
+	if len(report.Records) == 0 {
+		b.WriteString("None\n")
+	}
+	for _, r := range report.Records {
+		fmt.Fprintf(&b, "- %s\n", r.Title)
+	}
+	return b.String()
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"signed": func(n int) template.HTML { return template.HTML(fmt.Sprintf("%+d", n)) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Leaderboard report for {{.Date}}</title></head>
+<body style="font-family:system-ui,sans-serif">
+<h1>Leaderboard report for {{.Date}}</h1>
+{{- if .Partial}}
+<p><em>Partial: the server started during this day.</em></p>
+{{- end}}
+<h2>Activity</h2>
+<ul>
+<li>Score updates: {{.Activity.ScoreUpdates}}</li>
+<li>New users: {{.Activity.NewUsers}}</li>
+<li>Active users: {{.Activity.ActiveUsers}}</li>
+{{- range $source, $n := .Activity.BySource}}
+<li>{{$source}}: {{$n}}</li>
+{{- end}}
+</ul>
+{{- define "movers"}}
+{{- if .}}
+<table>
+<tr><th>Player</th><th>From</th><th>To</th><th>Change</th></tr>
+{{- range .}}
+<tr><td>{{.Username}}</td><td>{{.From}}</td><td>{{.To}}</td><td>{{signed .Change}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>None</p>
+{{- end}}
+{{- end}}
+<h2>Top gainers</h2>
+{{- template "movers" .TopGainers}}
+<h2>Top losers</h2>
+{{- template "movers" .TopLosers}}
+<h2>New records</h2>
+{{- if .Records}}
+<ul>
+{{- range .Records}}
+<li>{{.Title}}</li>
+{{- end}}
+</ul>
+{{- else}}
+<p>None</p>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"backend/internal/events"
+)
+
+// Roles checked by the API. Admins implicitly hold every other role.
+const (
+	RoleAdmin     = "admin"     // Manage roles, sessions and board settings
+	RoleModerator = "moderator" // Moderate users and their scores
+	RoleWriter    = "writer"    // Submit scores and seed data
+	RolePriority  = "priority"  // Send X-Priority: high to skip load shedding
+)
+
+var knownRoles = []string{RoleAdmin, RoleModerator, RoleWriter, RolePriority}
+
+// Principal kinds that roles are granted to. Grants to a user are dropped
+// when the user leaves the board; grants to an identity follow whichever
+// user it is linked to, so they can be made before that user's first login.
+const (
+	PrincipalUser     = "user"     // A leaderboard user who logs in
+	PrincipalKey      = "key"      // A named API key
+	PrincipalIdentity = "identity" // A login provider's subject, <provider>:<subject>
+)
+
+var (
+	// ErrUnknownRole is returned when granting a role the API doesn't check
+	ErrUnknownRole = errors.New("unknown role")
+	// ErrInvalidPrincipal is returned for principals not of the form
+	// user:<name>, key:<name> or identity:<provider>:<subject>
+	ErrInvalidPrincipal = errors.New("invalid principal")
+)
+
+// Principal formats a principal, e.g. Principal(PrincipalUser, "ada") is "user:ada"
+func Principal(kind, name string) string {
+	return kind + ":" + name
+}
+
+// IdentityPrincipal formats the principal of a login provider's subject,
+// e.g. IdentityPrincipal("github", "583231") is "identity:github:583231"
+func IdentityPrincipal(provider, subject string) string {
+	return Principal(PrincipalIdentity, provider+":"+subject)
+}
+
+func splitPrincipal(principal string) (kind, name string, err error) {
+	kind, name, ok := strings.Cut(principal, ":")
+	if !ok || name == "" {
+		return "", "", ErrInvalidPrincipal
+	}
+	switch kind {
+	case PrincipalUser, PrincipalKey:
+	case PrincipalIdentity:
+		if provider, subject, ok := strings.Cut(name, ":"); !ok || provider == "" || subject == "" {
+			return "", "", ErrInvalidPrincipal
+		}
+	default:
+		return "", "", ErrInvalidPrincipal
+	}
+	return kind, name, nil
+}
+
+// CheckBootstrapAdmin checks a principal granted admin at startup. Users
+// aren't accepted, as a name granted ahead of its first login goes to
+// whoever registers it first; identities are bound to the provider's
+// account instead.
+func CheckBootstrapAdmin(principal string) error {
+	kind, _, err := splitPrincipal(principal)
+	if err != nil || kind == PrincipalUser {
+		return fmt.Errorf("admin %q: expected key:<name> or identity:<provider>:<subject>", principal)
+	}
+	return nil
+}
+
+// GrantRole grants role to principal. Users must exist; API keys and
+// identities are granted whether or not the key is configured or the
+// identity has logged in.
+func (s *LeaderboardService) GrantRole(ctx context.Context, principal, role string) error {
+	if !slices.Contains(knownRoles, role) {
+		return ErrUnknownRole
+	}
+	kind, name, err := splitPrincipal(principal)
+	if err != nil {
+		return err
+	}
+	if kind == PrincipalUser {
+		if _, err := s.store.GetUser(name); err != nil {
+			return err
+		}
+	}
+
+	if s.store.GrantRole(principal, role) {
+		log.Printf("Granted %s to %s", role, principal)
+	}
+	return nil
+}
+
+// RevokeRole removes role from principal
+func (s *LeaderboardService) RevokeRole(ctx context.Context, principal, role string) error {
+	if !slices.Contains(knownRoles, role) {
+		return ErrUnknownRole
+	}
+	if _, _, err := splitPrincipal(principal); err != nil {
+		return err
+	}
+
+	if s.store.RevokeRole(principal, role) {
+		log.Printf("Revoked %s from %s", role, principal)
+	}
+	return nil
+}
+
+// Roles returns the roles granted to principal. A user also holds the roles
+// of the identities linked to them.
+func (s *LeaderboardService) Roles(principal string) []string {
+	roles := s.store.Roles(principal)
+	kind, name, err := splitPrincipal(principal)
+	if err != nil || kind != PrincipalUser {
+		return roles
+	}
+	for _, identity := range s.identities.principals(name) {
+		for _, role := range s.store.Roles(identity) {
+			if !slices.Contains(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+	}
+	slices.Sort(roles)
+	return roles
+}
+
+// HasRole reports whether principal holds role, directly or as an admin
+func (s *LeaderboardService) HasRole(principal, role string) bool {
+	roles := s.Roles(principal)
+	return slices.Contains(roles, role) || slices.Contains(roles, RoleAdmin)
+}
+
+// revokeAllRoles drops every role granted to principal and returns them
+func (s *LeaderboardService) revokeAllRoles(principal string) []string {
+	roles := s.store.Roles(principal)
+	for _, role := range roles {
+		s.store.RevokeRole(principal, role)
+	}
+	if len(roles) > 0 {
+		log.Printf("Revoked %s from %s", strings.Join(roles, ", "), principal)
+	}
+	return roles
+}
+
+// dropLeaverRoles revokes the grants of users who leave the board, so the
+// next user to register the name doesn't inherit them; it is subscribed to
+// the service's event bus
+func (s *LeaderboardService) dropLeaverRoles(e events.Event) {
+	switch e.Type {
+	case events.TypeUserEvicted, events.TypeUserExpired:
+		s.revokeAllRoles(Principal(PrincipalUser, e.Username))
+	}
+}
+
+// nameHeld reports whether username still holds role grants, as one
+// restored from an older snapshot may, so it must not be given to a new user
+func (s *LeaderboardService) nameHeld(username string) bool {
+	return len(s.store.Roles(Principal(PrincipalUser, username))) > 0
+}
+
+// ListRoleGrants returns every principal's roles
+func (s *LeaderboardService) ListRoleGrants(ctx context.Context) map[string][]string {
+	return s.store.RoleGrants()
+}
@@ -0,0 +1,571 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+var (
+	// ErrImportDisabled is returned when no score file is configured
+	ErrImportDisabled = errors.New("score import is not enabled")
+	// ErrImportRunning is returned when an import is started while another runs
+	ErrImportRunning = errors.New("an import is already running")
+	// ErrImportUnsigned is returned when the import is enabled without a secret
+	ErrImportUnsigned = errors.New("score import needs a secret to verify the file's signature")
+	// ErrReplayedImport is returned for a score file signed no later than the
+	// last applied one, or with its nonce
+	ErrReplayedImport = errors.New("score file is not signed after the last applied one")
+)
+
+// Score file formats
+const (
+	ImportCSV  = "csv"
+	ImportJSON = "json"
+)
+
+// What started an import job
+const (
+	ImportTriggerSchedule = "schedule"
+	ImportTriggerManual   = "manual"
+)
+
+const (
+	importJobsKept      = 20
+	importRowErrorsKept = 20
+	// importClockSkew is how far in the future a file may be signed. A file
+	// signed further ahead would hold back every file after it.
+	importClockSkew = 5 * time.Minute
+)
+
+// importSignatureRecords keeps the signature of the last applied file per
+// URL, "<unix> <nonce>", so that it survives restarts and is shared by
+// every replica on the store
+const importSignatureRecords = "import_signatures"
+
+// ImportConfig pulls a score file that a partner publishes, for partners who
+// can't call the API. Each changed file is diffed against the board and the
+// differences applied as an import job.
+type ImportConfig struct {
+	URL         string
+	Format      string        // csv or json, guessed from the URL's extension when empty
+	Secret      string        // HMAC-SHA256 key, required; the file's signature is published at URL + ".sig"
+	Interval    time.Duration // How often to check for a new file, defaults to 15m
+	CreateUsers bool          // Add players missing from the board, otherwise their rows are skipped
+	MaxBytes    int64         // Larger files are rejected, defaults to 10 MiB
+}
+
+// importSignature is a score file's signed timestamp and nonce
+type importSignature struct {
+	signedAt time.Time
+	nonce    string
+}
+
+// parseImportSignature reads a stored "<unix> <nonce>" signature
+func parseImportSignature(value string) (importSignature, bool) {
+	unix, nonce, ok := strings.Cut(value, " ")
+	if !ok {
+		return importSignature{}, false
+	}
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return importSignature{}, false
+	}
+	return importSignature{signedAt: time.Unix(seconds, 0).UTC(), nonce: nonce}, true
+}
+
+func (sig importSignature) String() string {
+	return strconv.FormatInt(sig.signedAt.Unix(), 10) + " " + sig.nonce
+}
+
+// importRow is a username and rating read from the score file
+type importRow struct {
+	row      int
+	username string
+	rating   int
+	invalid  string // Why the row couldn't be read
+}
+
+// scoreImporter fetches the score file and keeps recent jobs
+type scoreImporter struct {
+	config ImportConfig
+	client *http.Client
+	run    sync.Mutex // Held for the whole of a job
+
+	mu           sync.Mutex // Guards the fields below
+	etag         string     // Validators of the last applied file
+	lastModified string
+	lastChecked  time.Time
+	jobs         []models.ImportJob // Newest first
+}
+
+// EnableScoreImport configures the score file import; call
+// StartImportScheduler to poll it. Files must be signed, so a secret is
+// required.
+func (s *LeaderboardService) EnableScoreImport(config ImportConfig) error {
+	if config.Secret == "" {
+		return ErrImportUnsigned
+	}
+	if config.Format == "" {
+		config.Format = ImportCSV
+		if u, err := url.Parse(config.URL); err == nil && strings.EqualFold(path.Ext(u.Path), ".json") {
+			config.Format = ImportJSON
+		}
+	}
+	if config.Interval <= 0 {
+		config.Interval = 15 * time.Minute
+	}
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = 10 << 20
+	}
+	s.imports = &scoreImporter{
+		config: config,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	return nil
+}
+
+// SignImportFile returns the signature a partner publishes next to their
+// score file: "t=<unix>,nonce=<nonce>,sig=<hex>", where sig is the
+// HMAC-SHA256 of "<unix>.<nonce>.<file>". Each file needs a later
+// timestamp and a fresh nonce.
+func SignImportFile(secret string, signedAt time.Time, nonce string, body []byte) string {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + nonce + "."))
+	mac.Write(body)
+	return "t=" + timestamp + ",nonce=" + nonce + ",sig=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// StartImportScheduler checks the score file at startup and then on the
+// configured interval until ctx is cancelled. Checks are skipped while
+// maintenance mode is on; an admin can still run one by hand.
+func (s *LeaderboardService) StartImportScheduler(ctx context.Context) {
+	if s.imports == nil {
+		return
+	}
+	ticker := s.clock.NewTicker(s.imports.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.scheduledImport(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// scheduledImport makes one of the scheduler's checks
+func (s *LeaderboardService) scheduledImport(ctx context.Context) {
+	if s.writesPaused() {
+		log.Printf("Score import skipped for maintenance")
+		return
+	}
+	if _, err := s.RunImport(ctx, ImportTriggerSchedule); err != nil && !errors.Is(err, ErrImportRunning) && ctx.Err() == nil {
+		log.Printf("Score import failed: %v", err)
+		s.RecordIncident("import", IncidentFailure, err.Error())
+	}
+}
+
+// ImportStatus describes the score file import and its recent jobs
+func (s *LeaderboardService) ImportStatus(ctx context.Context) (*models.ImportStatus, error) {
+	im := s.imports
+	if im == nil {
+		return nil, ErrImportDisabled
+	}
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	status := &models.ImportStatus{
+		URL:             im.config.URL,
+		Format:          im.config.Format,
+		IntervalSeconds: im.config.Interval.Seconds(),
+		ETag:            im.etag,
+		Jobs:            append([]models.ImportJob{}, im.jobs...),
+	}
+	if !im.lastChecked.IsZero() {
+		checked := im.lastChecked
+		status.LastCheckedAt = &checked
+	}
+	if value, ok := s.store.Record(importSignatureRecords, im.config.URL); ok {
+		if applied, ok := parseImportSignature(value); ok {
+			status.SignedAt = &applied.signedAt
+		}
+	}
+	return status, nil
+}
+
+// RunImport fetches the score file and applies it if it changed since the
+// last applied version. It returns a nil job when the file is unchanged.
+// Files that can't be fetched, verified or parsed fail as a whole, as do
+// files signed no later than the last applied one; rows that can't be
+// applied are skipped and reported on the job.
+func (s *LeaderboardService) RunImport(ctx context.Context, trigger string) (*models.ImportJob, error) {
+	im := s.imports
+	if im == nil {
+		return nil, ErrImportDisabled
+	}
+	if !im.run.TryLock() {
+		return nil, ErrImportRunning
+	}
+	defer im.run.Unlock()
+
+	im.mu.Lock()
+	etag, lastModified := im.etag, im.lastModified
+	im.lastChecked = s.clock.Now()
+	im.mu.Unlock()
+
+	body, validators, err := im.fetch(ctx, etag, lastModified)
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, nil
+	}
+	sig, err := im.verify(ctx, body, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := s.store.Record(importSignatureRecords, im.config.URL); ok {
+		if applied, ok := parseImportSignature(value); ok {
+			if applied == sig {
+				// The same file again, from a server without validators
+				return nil, nil
+			}
+			if !sig.signedAt.After(applied.signedAt) || sig.nonce == applied.nonce {
+				return nil, ErrReplayedImport
+			}
+		}
+	}
+
+	job := models.ImportJob{
+		ID:        newSubmissionID(),
+		Status:    models.ImportRunning,
+		Trigger:   trigger,
+		ETag:      validators.Get("ETag"),
+		StartedAt: s.clock.Now(),
+	}
+	im.record(job)
+
+	if err := s.applyImport(ctx, body, &job); err != nil {
+		job.Status = models.ImportFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = models.ImportSucceeded
+		// Only a file that was applied is skipped next time
+		im.mu.Lock()
+		im.etag, im.lastModified = validators.Get("ETag"), validators.Get("Last-Modified")
+		im.mu.Unlock()
+		s.recordImportSignature(sig)
+	}
+	finished := s.clock.Now()
+	job.FinishedAt = &finished
+	im.record(job)
+
+	log.Printf("Import %s %s: %d rows, %d created, %d updated, %d unchanged, %d skipped",
+		job.ID, job.Status, job.Rows, job.Created, job.Updated, job.Unchanged, job.Skipped)
+	if job.Status == models.ImportFailed {
+		return &job, fmt.Errorf("import %s: %s", job.ID, job.Error)
+	}
+	return &job, nil
+}
+
+// record adds job to the history, replacing its running entry
+func (im *scoreImporter) record(job models.ImportJob) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	if len(im.jobs) > 0 && im.jobs[0].ID == job.ID {
+		im.jobs[0] = job
+		return
+	}
+	im.jobs = append([]models.ImportJob{job}, im.jobs...)
+	if len(im.jobs) > importJobsKept {
+		im.jobs = im.jobs[:importJobsKept]
+	}
+}
+
+// recordImportSignature keeps sig as the last applied file's, unless
+// another replica has since applied a later one
+func (s *LeaderboardService) recordImportSignature(sig importSignature) {
+	_, err := s.store.UpdateRecord(importSignatureRecords, s.imports.config.URL, func(current string) (string, error) {
+		if applied, ok := parseImportSignature(current); ok && applied.signedAt.After(sig.signedAt) {
+			return current, nil
+		}
+		return sig.String(), nil
+	})
+	if err != nil {
+		log.Printf("Score import: recording the applied signature: %v", err)
+	}
+}
+
+// fetch downloads the score file. A nil body means the server answered 304
+// Not Modified.
+func (im *scoreImporter) fetch(ctx context.Context, etag, lastModified string) ([]byte, http.Header, error) {
+	header := http.Header{}
+	if etag != "" {
+		header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		header.Set("If-Modified-Since", lastModified)
+	}
+	body, resp, err := im.get(ctx, im.config.URL, header)
+	if err != nil || resp.StatusCode == http.StatusNotModified {
+		return nil, nil, err
+	}
+	return body, resp.Header, nil
+}
+
+// verify fetches the file's signature from URL + ".sig" and checks it
+// against body, returning the signed timestamp and nonce
+func (im *scoreImporter) verify(ctx context.Context, body []byte, now time.Time) (importSignature, error) {
+	u, err := url.Parse(im.config.URL)
+	if err != nil {
+		return importSignature{}, err
+	}
+	u.Path += ".sig"
+	published, _, err := im.get(ctx, u.String(), nil)
+	if err != nil {
+		return importSignature{}, fmt.Errorf("fetch signature: %w", err)
+	}
+
+	var timestamp, nonce string
+	for _, field := range strings.Split(string(bytes.TrimSpace(published)), ",") {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "nonce":
+			nonce = value
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || nonce == "" || strings.ContainsAny(nonce, " .") {
+		return importSignature{}, ErrInvalidSignature
+	}
+	expected := SignImportFile(im.config.Secret, time.Unix(unix, 0), nonce, body)
+	if !hmac.Equal([]byte(expected), bytes.TrimSpace(published)) {
+		return importSignature{}, ErrInvalidSignature
+	}
+	signedAt := time.Unix(unix, 0).UTC()
+	if signedAt.Sub(now) > importClockSkew {
+		return importSignature{}, fmt.Errorf("%w: signed %s in the future", ErrInvalidSignature, signedAt.Sub(now).Round(time.Second))
+	}
+	return importSignature{signedAt: signedAt, nonce: nonce}, nil
+}
+
+func (im *scoreImporter) get(ctx context.Context, target string, header http.Header) ([]byte, *http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := im.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, resp, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("GET %s answered %s", req.URL.Redacted(), resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, im.config.MaxBytes+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if int64(len(body)) > im.config.MaxBytes {
+		return nil, nil, fmt.Errorf("GET %s is larger than %d bytes", req.URL.Redacted(), im.config.MaxBytes)
+	}
+	return body, resp, nil
+}
+
+// applyImport diffs the file's rows against the board and applies the
+// differences. Players missing from the file are left as they are.
+func (s *LeaderboardService) applyImport(ctx context.Context, body []byte, job *models.ImportJob) error {
+	var (
+		rows []importRow
+		err  error
+	)
+	switch s.imports.config.Format {
+	case ImportJSON:
+		rows, err = parseImportJSON(body)
+	default:
+		rows, err = parseImportCSV(body)
+	}
+	if err != nil {
+		return err
+	}
+	job.Rows = len(rows)
+
+	skip := func(r importRow, reason string) {
+		job.Skipped++
+		if len(job.RowErrors) < importRowErrorsKept {
+			job.RowErrors = append(job.RowErrors, models.ImportRowError{Row: r.row, Username: r.username, Error: reason})
+		}
+	}
+
+	// Validate and diff everything before writing anything
+	var changes []importRow
+	seen := make(map[string]int, len(rows))
+	for _, r := range rows {
+		switch {
+		case r.invalid != "":
+			skip(r, r.invalid)
+			continue
+		case r.username == "":
+			skip(r, "username is required")
+			continue
+		case r.rating < MinRating || r.rating > MaxRating:
+			skip(r, fmt.Sprintf("rating must be between %d and %d", MinRating, MaxRating))
+			continue
+		}
+		if first, ok := seen[r.username]; ok {
+			skip(r, fmt.Sprintf("duplicate of row %d", first))
+			continue
+		}
+		seen[r.username] = r.row
+
+		user, err := s.store.GetUser(r.username)
+		switch {
+		case errors.Is(err, store.ErrUserNotFound) && !s.imports.config.CreateUsers:
+			skip(r, "not on the board")
+		case err != nil && !errors.Is(err, store.ErrUserNotFound):
+			return err
+		case err == nil && user.Rating == r.rating:
+			job.Unchanged++
+		default:
+			changes = append(changes, r)
+		}
+	}
+
+	// Keep the simulator from racing with the bulk write
+	endJob := s.beginBulkJob("import")
+	defer endJob()
+
+	ctx = WithSource(ctx, SourceImport)
+	for _, r := range changes {
+		err := s.updateScore(ctx, r.username, r.rating, models.ReasonImport)
+		if errors.Is(err, store.ErrUserNotFound) && s.imports.config.CreateUsers {
+			err = s.importUser(r.username, r.rating)
+			if err == nil {
+				job.Created++
+				continue
+			}
+		}
+		if err != nil {
+			skip(r, err.Error())
+			continue
+		}
+		job.Updated++
+	}
+	return nil
+}
+
+// importUser adds a player named in the score file, unless the name still
+// holds role grants
+func (s *LeaderboardService) importUser(username string, rating int) error {
+	if s.nameHeld(username) {
+		return store.ErrUserExists
+	}
+	if err := s.store.CreateUser(username, rating); err != nil {
+		return err
+	}
+	s.mirrorShadow(username, rating, false)
+	s.events.Publish(events.Event{
+		Type:     events.TypeUserAdded,
+		Username: username,
+		Rating:   rating,
+		Source:   SourceImport,
+	})
+	return nil
+}
+
+// parseImportCSV reads a CSV file whose header names a username and a
+// rating column; other columns are ignored
+func parseImportCSV(body []byte) ([]importRow, error) {
+	r := csv.NewReader(bytes.NewReader(body))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	usernameCol, ratingCol := -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) {
+		case "username":
+			usernameCol = i
+		case "rating":
+			ratingCol = i
+		}
+	}
+	if usernameCol < 0 || ratingCol < 0 {
+		return nil, errors.New("CSV header must name a username and a rating column")
+	}
+
+	var rows []importRow
+	for n := 1; ; n++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		row := importRow{row: n}
+		if usernameCol < len(record) {
+			row.username = strings.TrimSpace(record[usernameCol])
+		}
+		if ratingCol >= len(record) {
+			row.invalid = "rating is required"
+		} else if row.rating, err = strconv.Atoi(strings.TrimSpace(record[ratingCol])); err != nil {
+			row.invalid = "rating must be a whole number"
+		}
+		rows = append(rows, row)
+	}
+}
+
+// parseImportJSON reads a JSON array of {"username": ..., "rating": ...}
+func parseImportJSON(body []byte) ([]importRow, error) {
+	var entries []models.User
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	rows := make([]importRow, len(entries))
+	for i, e := range entries {
+		rows[i] = importRow{row: i + 1, username: strings.TrimSpace(e.Username), rating: e.Rating}
+	}
+	return rows, nil
+}
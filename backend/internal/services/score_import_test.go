@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/clock"
+)
+
+// signedPartner publishes one score file and its signature, without
+// validators, so every check downloads both
+type signedPartner struct {
+	mu        sync.Mutex
+	body      string
+	signature string // Served at .sig; empty answers 404
+}
+
+func (p *signedPartner) publish(secret string, signedAt time.Time, nonce, body string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.body, p.signature = body, SignImportFile(secret, signedAt, nonce, []byte(body))
+}
+
+func (p *signedPartner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !strings.HasSuffix(r.URL.Path, ".sig") {
+		io.WriteString(w, p.body)
+	} else if p.signature != "" {
+		io.WriteString(w, p.signature+"\n")
+	} else {
+		http.NotFound(w, r)
+	}
+}
+
+func TestScoreImportNeedsASecret(t *testing.T) {
+	s := NewLeaderboardService(playersStore(t, 1))
+	if err := s.EnableScoreImport(ImportConfig{URL: "https://partner.example.com/scores.csv"}); !errors.Is(err, ErrImportUnsigned) {
+		t.Fatalf("EnableScoreImport without a secret = %v, want ErrImportUnsigned", err)
+	}
+	if _, err := s.RunImport(context.Background(), ImportTriggerManual); !errors.Is(err, ErrImportDisabled) {
+		t.Errorf("RunImport = %v, want ErrImportDisabled", err)
+	}
+}
+
+func TestScoreImportRejectsReplays(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	partner := &signedPartner{}
+	server := httptest.NewServer(partner)
+	defer server.Close()
+
+	st := playersStore(t, 1)
+	s := NewLeaderboardService(st)
+	s.SetClock(clock.NewFake(now))
+	config := ImportConfig{URL: server.URL + "/scores.csv", Secret: "partner-secret"}
+	if err := s.EnableScoreImport(config); err != nil {
+		t.Fatal(err)
+	}
+	first := "username,rating\nplayer_0,200\n"
+	partner.publish("partner-secret", now.Add(-time.Hour), "first", first)
+	if job, err := s.RunImport(context.Background(), ImportTriggerManual); err != nil || job == nil || job.Updated != 1 {
+		t.Fatalf("first file: job %+v, error %v; want it applied", job, err)
+	}
+
+	steps := []struct {
+		name     string
+		secret   string
+		signedAt time.Time
+		nonce    string
+		want     error // nil with applied false means the file is unchanged
+		applied  bool
+	}{
+		{name: "the same file again", signedAt: now.Add(-time.Hour), nonce: "first"},
+		{name: "an older file", signedAt: now.Add(-2 * time.Hour), nonce: "older", want: ErrReplayedImport},
+		{name: "the same timestamp with another nonce", signedAt: now.Add(-time.Hour), nonce: "other", want: ErrReplayedImport},
+		{name: "a later timestamp with the same nonce", signedAt: now, nonce: "first", want: ErrReplayedImport},
+		{name: "another secret", secret: "wrong-secret", signedAt: now, nonce: "second", want: ErrInvalidSignature},
+		{name: "signed too far ahead", signedAt: now.Add(importClockSkew + time.Minute), nonce: "second", want: ErrInvalidSignature},
+		{name: "a later file", signedAt: now.Add(time.Minute), nonce: "second", applied: true},
+	}
+	for _, step := range steps {
+		secret := step.secret
+		if secret == "" {
+			secret = "partner-secret"
+		}
+		partner.publish(secret, step.signedAt, step.nonce, "username,rating\nplayer_0,300\n")
+		job, err := s.RunImport(context.Background(), ImportTriggerManual)
+		if !errors.Is(err, step.want) || (job != nil) != step.applied {
+			t.Errorf("%s: job %+v, error %v; want applied %t, error %v", step.name, job, err, step.applied, step.want)
+		}
+	}
+	status, err := s.ImportStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.SignedAt == nil || !status.SignedAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("status signed at %v, want the last applied file's %s", status.SignedAt, now.Add(time.Minute))
+	}
+
+	// A restarted process or another replica on the store remembers it too
+	replica := NewLeaderboardService(st)
+	replica.SetClock(clock.NewFake(now))
+	if err := replica.EnableScoreImport(config); err != nil {
+		t.Fatal(err)
+	}
+	partner.publish("partner-secret", now.Add(-time.Hour), "first", first)
+	if _, err := replica.RunImport(context.Background(), ImportTriggerManual); !errors.Is(err, ErrReplayedImport) {
+		t.Errorf("replica replaying the first file: %v, want ErrReplayedImport", err)
+	}
+
+	// Files without a signature aren't applied
+	partner.mu.Lock()
+	partner.body, partner.signature = first, ""
+	partner.mu.Unlock()
+	if job, err := replica.RunImport(context.Background(), ImportTriggerManual); err == nil || job != nil {
+		t.Errorf("unsigned file: job %+v, error %v; want it refused", job, err)
+	}
+	if user, _ := st.GetUser("player_0"); user.Rating != 300 {
+		t.Errorf("player_0 at %d, want 300 from the last applied file", user.Rating)
+	}
+}
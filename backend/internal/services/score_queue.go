@@ -0,0 +1,392 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrQueueDisabled is returned for async submissions without a score queue
+	ErrQueueDisabled = errors.New("async score submissions are not enabled")
+	// ErrQueueFull is returned when a submission can't be queued without blocking
+	ErrQueueFull = errors.New("score queue is full")
+	// ErrQueueClosed is returned once the queue has stopped accepting submissions
+	ErrQueueClosed = errors.New("score queue is draining")
+	// ErrSubmissionNotFound is returned for unknown or forgotten submission IDs
+	ErrSubmissionNotFound = errors.New("submission not found")
+	// ErrDeadLettered is recorded for submissions given up on after repeated delivery failures
+	ErrDeadLettered = errors.New("submission was dead-lettered after repeated delivery failures")
+)
+
+// maxTrackedSubmissions bounds the submissions whose status can be looked up
+const maxTrackedSubmissions = 10000
+
+// DefaultRedisKeyPrefix starts the score queue's Redis keys unless
+// ScoreQueueConfig.KeyPrefix says otherwise
+const DefaultRedisKeyPrefix = "leaderboard:"
+
+// ScoreQueueConfig sizes the async score submission queue. With Redis set the
+// queue is a Redis stream shared by every replica; otherwise it is in memory.
+type ScoreQueueConfig struct {
+	Workers    int                   // Per replica, defaults to 4
+	Capacity   int                   // Submissions waiting across all workers, defaults to 10000
+	Redis      redis.UniversalClient // Queue on a Redis stream when set
+	KeyPrefix  string                // Prefix for Redis keys, defaults to DefaultRedisKeyPrefix
+	Shards     int                   // Redis streams submissions are split over by username, defaults to 16; the same on every replica
+	MaxRetries int                   // Redis deliveries before a submission is dead-lettered, defaults to 5
+	ClaimIdle  time.Duration         // How long a stalled worker holds a Redis shard before another takes over, defaults to 1m
+	LedgerTTL  time.Duration         // How long applied submission IDs are remembered, defaults to 24h
+
+	SlowCommandThreshold time.Duration // Log and count Redis commands slower than this, 0 disables
+
+	// Submissions held in memory while Redis is unreachable, flushed in order
+	// on recovery; 0 fails submissions instead
+	WriteBuffer       int
+	WriteBufferPolicy string // BufferReject (default) or BufferDropOldest when the buffer is full
+}
+
+// queuedScore is a submission waiting for a worker
+type queuedScore struct {
+	ID       string                    `json:"id"`
+	Username string                    `json:"username"`
+	Request  models.UpdateScoreRequest `json:"request"`
+	Source   string                    `json:"source"`
+	QueuedAt time.Time                 `json:"queued_at"`
+}
+
+func (item queuedScore) submission() models.Submission {
+	return models.Submission{
+		ID:       item.ID,
+		Username: item.Username,
+		Status:   models.SubmissionQueued,
+		QueuedAt: item.QueuedAt,
+	}
+}
+
+// SubmissionStatus is a queued submission's status plus the error it failed with
+type SubmissionStatus struct {
+	models.Submission
+	Err error // Why a failed submission was rejected
+}
+
+// applyFunc applies a queued submission to the board
+type applyFunc func(queuedScore) (*models.UpdateScoreResponse, error)
+
+// scoreQueueBackend carries async submissions to workers and tracks their outcome
+type scoreQueueBackend interface {
+	enqueue(ctx context.Context, item queuedScore) error
+	start(apply applyFunc, paused func() bool) // Workers hold off while paused reports true
+	status(ctx context.Context, id string) (*SubmissionStatus, error)
+	stats(ctx context.Context) (*models.ScoreQueueStats, error)
+	drain(ctx context.Context) error
+
+	// Dead letters are submissions given up on, addressed by entry ID
+	listDeadLetters(ctx context.Context) ([]models.DeadLetter, error)
+	getDeadLetter(ctx context.Context, entryID string) (*models.DeadLetter, error)
+	retryDeadLetter(ctx context.Context, entryID string) error
+	discardDeadLetter(ctx context.Context, entryID string) error
+}
+
+// EnableScoreQueue accepts async score submissions; call StartScoreWorkers
+// to begin applying them
+func (s *LeaderboardService) EnableScoreQueue(config ScoreQueueConfig) error {
+	if config.Workers <= 0 {
+		config.Workers = 4
+	}
+	if config.Capacity <= 0 {
+		config.Capacity = 10000
+	}
+	if config.ClaimIdle <= 0 {
+		config.ClaimIdle = time.Minute
+	}
+	if config.LedgerTTL <= 0 {
+		config.LedgerTTL = 24 * time.Hour
+	}
+	switch config.WriteBufferPolicy {
+	case "", BufferReject, BufferDropOldest:
+	default:
+		return fmt.Errorf("unknown write buffer policy %q (want %s or %s)", config.WriteBufferPolicy, BufferReject, BufferDropOldest)
+	}
+
+	// A claim outlives a shard lease so a slow worker's entry isn't applied
+	// again by the worker that takes the shard over
+	dedup := &submissionDedup{lease: 2 * config.ClaimIdle}
+	if config.Redis == nil {
+		dedup.ledger = NewMemorySubmissionLedger(config.LedgerTTL)
+		s.scoreQueue = newMemoryScoreQueue(config)
+		s.submissions = dedup
+		return nil
+	}
+
+	q, err := newRedisScoreQueue(config, s.ReportHealth)
+	if err != nil {
+		return err
+	}
+	dedup.ledger = NewRedisSubmissionLedger(config.Redis, q.config.KeyPrefix, config.LedgerTTL)
+	s.scoreQueue = q
+	s.submissions = dedup
+	return nil
+}
+
+// StartScoreWorkers starts the queue's workers. Workers run until the queue
+// is drained with DrainScoreQueue, so queued submissions outlive job shutdown.
+func (s *LeaderboardService) StartScoreWorkers() {
+	if s.scoreQueue == nil {
+		return
+	}
+	update := func(item queuedScore) (*models.UpdateScoreResponse, error) {
+		ctx := WithSource(context.Background(), item.Source)
+		return s.SubmitScore(ctx, item.Username, item.Request)
+	}
+	s.scoreQueue.start(func(item queuedScore) (*models.UpdateScoreResponse, error) {
+		return s.submissions.apply(item, update)
+	}, s.writesPaused)
+}
+
+// EnqueueScore queues a score submission and returns at once. The outcome
+// is available from GetSubmission.
+func (s *LeaderboardService) EnqueueScore(ctx context.Context, username string, req models.UpdateScoreRequest) (*models.Submission, error) {
+	if s.scoreQueue == nil {
+		return nil, ErrQueueDisabled
+	}
+
+	item := queuedScore{
+		ID:       newSubmissionID(),
+		Username: username,
+		Request:  req,
+		Source:   SourceFrom(ctx),
+		QueuedAt: time.Now().UTC(),
+	}
+	if err := s.scoreQueue.enqueue(ctx, item); err != nil {
+		return nil, err
+	}
+	submission := item.submission()
+	return &submission, nil
+}
+
+// GetSubmission returns a queued submission's status
+func (s *LeaderboardService) GetSubmission(ctx context.Context, id string) (*SubmissionStatus, error) {
+	if s.scoreQueue == nil {
+		return nil, ErrQueueDisabled
+	}
+	return s.scoreQueue.status(ctx, id)
+}
+
+// ScoreQueueStats reports queue depth and outcomes, with the ledger's
+// reconciliation counters
+func (s *LeaderboardService) ScoreQueueStats(ctx context.Context) (*models.ScoreQueueStats, error) {
+	if s.scoreQueue == nil {
+		return nil, ErrQueueDisabled
+	}
+	stats, err := s.scoreQueue.stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.Ledger = s.submissions.stats()
+	return stats, nil
+}
+
+// DrainScoreQueue stops accepting submissions and waits for the ones this
+// replica is applying, or for ctx to expire
+func (s *LeaderboardService) DrainScoreQueue(ctx context.Context) error {
+	if s.scoreQueue == nil {
+		return nil
+	}
+	return s.scoreQueue.drain(ctx)
+}
+
+// completed marks a submission applied or failed
+func completed(status *SubmissionStatus, result *models.UpdateScoreResponse, err error) {
+	completedAt := time.Now().UTC()
+	status.CompletedAt = &completedAt
+	status.Result = result
+	status.Err = err
+	status.Status = models.SubmissionApplied
+	if err != nil {
+		status.Status = models.SubmissionFailed
+	}
+}
+
+// memoryScoreQueue applies score submissions in the background. Submissions
+// are sharded by username, so each user's updates are applied in arrival order.
+type memoryScoreQueue struct {
+	config  ScoreQueueConfig
+	shards  []chan queuedScore
+	workers sync.WaitGroup
+
+	mu       sync.Mutex
+	closed   bool
+	tracked  map[string]*SubmissionStatus
+	order    []string // tracked IDs, oldest first
+	applied  int64
+	failed   int64
+	rejected int64
+}
+
+func newMemoryScoreQueue(config ScoreQueueConfig) *memoryScoreQueue {
+	q := &memoryScoreQueue{
+		config:  config,
+		shards:  make([]chan queuedScore, config.Workers),
+		tracked: make(map[string]*SubmissionStatus),
+	}
+	for i := range q.shards {
+		q.shards[i] = make(chan queuedScore, max(config.Capacity/config.Workers, 1))
+	}
+	return q
+}
+
+func (q *memoryScoreQueue) start(apply applyFunc, paused func() bool) {
+	for _, shard := range q.shards {
+		q.workers.Add(1)
+		go func() {
+			defer q.workers.Done()
+			for item := range shard {
+				for paused() {
+					time.Sleep(writesPausedPoll)
+				}
+				result, err := apply(item)
+				q.complete(item, result, err)
+			}
+		}()
+	}
+	log.Printf("📥 Started %d score queue worker(s)", len(q.shards))
+}
+
+func (q *memoryScoreQueue) enqueue(ctx context.Context, item queuedScore) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case q.shards[shardFor(item.Username, len(q.shards))] <- item:
+	default:
+		q.rejected++
+		return ErrQueueFull
+	}
+
+	q.tracked[item.ID] = &SubmissionStatus{Submission: item.submission()}
+	q.order = append(q.order, item.ID)
+	if len(q.order) > maxTrackedSubmissions {
+		delete(q.tracked, q.order[0])
+		q.order = append([]string(nil), q.order[1:]...)
+	}
+	return nil
+}
+
+func (q *memoryScoreQueue) complete(item queuedScore, result *models.UpdateScoreResponse, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err != nil {
+		q.failed++
+	} else {
+		q.applied++
+	}
+	if tracked, ok := q.tracked[item.ID]; ok {
+		completed(tracked, result, err)
+	}
+}
+
+func (q *memoryScoreQueue) status(ctx context.Context, id string) (*SubmissionStatus, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	tracked, ok := q.tracked[id]
+	if !ok {
+		return nil, ErrSubmissionNotFound
+	}
+	status := *tracked
+	return &status, nil
+}
+
+func (q *memoryScoreQueue) stats(ctx context.Context) (*models.ScoreQueueStats, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := &models.ScoreQueueStats{
+		Backend:  "memory",
+		Workers:  len(q.shards),
+		Capacity: q.config.Capacity,
+		Applied:  q.applied,
+		Failed:   q.failed,
+		Rejected: q.rejected,
+		Draining: q.closed,
+	}
+	for _, shard := range q.shards {
+		stats.Depth += len(shard)
+	}
+	return stats, nil
+}
+
+// The in-memory queue applies every submission once, so nothing is dead-lettered
+
+func (q *memoryScoreQueue) listDeadLetters(ctx context.Context) ([]models.DeadLetter, error) {
+	return nil, nil
+}
+
+func (q *memoryScoreQueue) getDeadLetter(ctx context.Context, entryID string) (*models.DeadLetter, error) {
+	return nil, ErrDeadLetterNotFound
+}
+
+func (q *memoryScoreQueue) retryDeadLetter(ctx context.Context, entryID string) error {
+	return ErrDeadLetterNotFound
+}
+
+func (q *memoryScoreQueue) discardDeadLetter(ctx context.Context, entryID string) error {
+	return ErrDeadLetterNotFound
+}
+
+// drain stops accepting submissions and waits for every queued one
+func (q *memoryScoreQueue) drain(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		for _, shard := range q.shards {
+			close(shard)
+		}
+	}
+	q.mu.Unlock()
+
+	return waitGroupDone(ctx, &q.workers)
+}
+
+// waitGroupDone waits for wg or ctx, whichever comes first
+func waitGroupDone(ctx context.Context, wg *sync.WaitGroup) error {
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func shardFor(username string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(username))
+	return int(h.Sum32() % uint32(shards))
+}
+
+func newSubmissionID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
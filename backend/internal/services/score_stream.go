@@ -0,0 +1,757 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+	"backend/pkg/store"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	scoreStreamGroup    = "score-workers"
+	submissionStatusTTL = 24 * time.Hour
+	scoreStreamBatch    = 10                     // Entries applied per turn on a shard
+	scoreStreamIdle     = 200 * time.Millisecond // Pause once every shard is found empty
+)
+
+// DefaultScoreQueueShards is how many streams the Redis score queue is split
+// into unless ScoreQueueConfig.Shards says otherwise
+const DefaultScoreQueueShards = 16
+
+// renewLeaseScript extends a shard lease by ARGV[2] milliseconds if ARGV[1]
+// still holds it
+var renewLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLeaseScript drops a shard lease if ARGV[1] still holds it
+var releaseLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// knownSubmissionErrors are restored by identity when a failed submission's
+// status is read back from Redis, so callers still see the right error code
+var knownSubmissionErrors = []error{
+	ErrUserFrozen,
+	ErrUserSuspended,
+	ErrMatchInProgress,
+	ErrDeadLettered,
+	store.ErrUserNotFound,
+}
+
+// storedSubmission is a submission status as kept in Redis
+type storedSubmission struct {
+	models.Submission
+	Error      string             `json:"failure,omitempty"`
+	FieldError *models.FieldError `json:"field_error,omitempty"`
+}
+
+func encodeSubmission(status *SubmissionStatus) ([]byte, error) {
+	stored := storedSubmission{Submission: status.Submission}
+	if status.Err != nil {
+		stored.Error = status.Err.Error()
+		var fieldErr models.FieldError
+		if errors.As(status.Err, &fieldErr) {
+			stored.FieldError = &fieldErr
+		}
+	}
+	return json.Marshal(stored)
+}
+
+func decodeSubmission(data []byte) (*SubmissionStatus, error) {
+	var stored storedSubmission
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+
+	status := &SubmissionStatus{Submission: stored.Submission}
+	switch {
+	case stored.FieldError != nil:
+		status.Err = *stored.FieldError
+	case stored.Error != "":
+		status.Err = errors.New(stored.Error)
+		for _, known := range knownSubmissionErrors {
+			if known.Error() == stored.Error {
+				status.Err = known
+			}
+		}
+	}
+	return status, nil
+}
+
+// redisScoreQueue queues submissions on Redis streams read by a consumer
+// group, so every replica shares the work. Submissions are split over
+// Shards streams by username, and a shard is served by one worker at a
+// time, across every replica, under a lease: the holder applies a batch in
+// stream order, then lets the shard go. So each user's submissions are
+// applied one at a time, in the order they were queued.
+//
+// A holder that stops part way, because it died or an entry must wait,
+// keeps the lease until it lapses after ClaimIdle. The next holder then
+// takes over the entries left pending before reading new ones, moving
+// entries delivered more than MaxRetries times to a dead-letter stream.
+type redisScoreQueue struct {
+	client       redis.UniversalClient
+	config       ScoreQueueConfig
+	streams      []string // One per shard
+	deadLetters  string
+	statusPrefix string
+	consumer     string
+	reportHealth func(component string, err error)
+	slowCommands *slowCommandLog // nil unless SlowCommandThreshold is set
+	buffer       *writeBuffer    // nil unless WriteBuffer is set
+
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+
+	mu           sync.Mutex
+	closed       bool
+	applied      int64
+	failed       int64
+	rejected     int64
+	recovered    int64
+	deadLettered int64
+}
+
+func newRedisScoreQueue(config ScoreQueueConfig, reportHealth func(string, error)) (*redisScoreQueue, error) {
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = DefaultRedisKeyPrefix
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = 5
+	}
+	if config.Shards <= 0 {
+		config.Shards = DefaultScoreQueueShards
+	}
+
+	hostname, _ := os.Hostname()
+	q := &redisScoreQueue{
+		client:       config.Redis,
+		config:       config,
+		streams:      make([]string, config.Shards),
+		deadLetters:  config.KeyPrefix + "scores:dead",
+		statusPrefix: config.KeyPrefix + "submission:",
+		consumer:     fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		reportHealth: reportHealth,
+	}
+	if config.SlowCommandThreshold > 0 {
+		q.slowCommands = newSlowCommandLog(config.SlowCommandThreshold)
+		q.client.AddHook(q.slowCommands)
+	}
+	if config.WriteBuffer > 0 {
+		q.buffer = newWriteBuffer(config.WriteBuffer, config.WriteBufferPolicy)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := range q.streams {
+		q.streams[i] = fmt.Sprintf("%sscores:%d", config.KeyPrefix, i)
+		err := q.client.XGroupCreateMkStream(ctx, q.streams[i], scoreStreamGroup, "0").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return nil, fmt.Errorf("create consumer group on %s: %w", q.streams[i], err)
+		}
+	}
+	return q, nil
+}
+
+// streamFor returns the stream of username's shard
+func (q *redisScoreQueue) streamFor(username string) string {
+	return q.streams[shardFor(username, len(q.streams))]
+}
+
+// shardCapacity is how many submissions each shard's stream holds
+func (q *redisScoreQueue) shardCapacity() int64 {
+	return int64(max(q.config.Capacity/len(q.streams), 1))
+}
+
+func (q *redisScoreQueue) enqueue(ctx context.Context, item queuedScore) error {
+	q.mu.Lock()
+	closed := q.closed
+	q.mu.Unlock()
+	if closed {
+		return ErrQueueClosed
+	}
+
+	// Queue behind buffered submissions so they reach the stream in order
+	if q.buffer != nil && q.buffer.active() {
+		return q.bufferItem(item, nil)
+	}
+
+	depth, err := q.client.XLen(ctx, q.streamFor(item.Username)).Result()
+	if err == nil && depth >= q.shardCapacity() {
+		q.mu.Lock()
+		q.rejected++
+		q.mu.Unlock()
+		return ErrQueueFull
+	}
+	if err == nil {
+		err = q.write(ctx, item)
+	}
+	if q.buffer != nil && transientRedisError(err) {
+		q.reportHealth("score_queue", err)
+		return q.bufferItem(item, err)
+	}
+	return err
+}
+
+// bufferItem holds a submission until Redis is back
+func (q *redisScoreQueue) bufferItem(item queuedScore, cause error) error {
+	err := q.buffer.add(item, cause)
+	if errors.Is(err, ErrQueueFull) {
+		q.mu.Lock()
+		q.rejected++
+		q.mu.Unlock()
+	}
+	return err
+}
+
+// write adds a submission to its shard's stream with its queued status
+func (q *redisScoreQueue) write(ctx context.Context, item queuedScore) error {
+	payload, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	status, err := encodeSubmission(&SubmissionStatus{Submission: item.submission()})
+	if err != nil {
+		return err
+	}
+
+	// The status is written first so it exists by the time any worker reads the entry
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, q.statusPrefix+item.ID, status, submissionStatusTTL)
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: q.streamFor(item.Username),
+			Values: map[string]any{"submission": payload},
+		})
+		return nil
+	})
+	return err
+}
+
+func (q *redisScoreQueue) start(apply applyFunc, paused func() bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+
+	for i := 0; i < q.config.Workers; i++ {
+		q.workers.Add(1)
+		go func() {
+			defer q.workers.Done()
+			q.work(ctx, fmt.Sprintf("%s/%d", q.consumer, i), i, apply, paused)
+		}()
+	}
+
+	if q.buffer != nil {
+		q.workers.Add(1)
+		go func() {
+			defer q.workers.Done()
+			q.buffer.run(ctx, q.write, q.reportHealth)
+		}()
+	}
+
+	log.Printf("📥 Started %d score queue worker(s) on %d Redis streams %sscores:* as %s",
+		q.config.Workers, len(q.streams), q.config.KeyPrefix, q.consumer)
+}
+
+// work serves shards with entries in turn, starting from first so workers
+// spread out, until ctx is cancelled. owner names this worker in leases.
+// While paused, entries are left on their streams.
+func (q *redisScoreQueue) work(ctx context.Context, owner string, first int, apply applyFunc, paused func() bool) {
+	next := first
+	for ctx.Err() == nil {
+		if paused() {
+			sleepCtx(ctx, writesPausedPoll)
+			continue
+		}
+		waiting, err := q.waiting(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				q.reportHealth("score_queue", err)
+				log.Printf("Failed to read score streams: %v", err)
+				sleepCtx(ctx, time.Second)
+			}
+			continue
+		}
+		q.reportHealth("score_queue", nil)
+
+		served := 0
+		for range q.streams {
+			i := next % len(q.streams)
+			next++
+			if !waiting[i] || ctx.Err() != nil {
+				continue
+			}
+			n, err := q.serve(ctx, q.streams[i], owner, apply)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Failed to serve score stream %s: %v", q.streams[i], err)
+			}
+			served += n
+		}
+		if served == 0 {
+			sleepCtx(ctx, scoreStreamIdle)
+		}
+	}
+}
+
+// waiting reports which shards' streams hold entries, in one round trip
+func (q *redisScoreQueue) waiting(ctx context.Context) ([]bool, error) {
+	lengths := make([]*redis.IntCmd, len(q.streams))
+	_, err := q.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, stream := range q.streams {
+			lengths[i] = pipe.XLen(ctx, stream)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	waiting := make([]bool, len(q.streams))
+	for i, length := range lengths {
+		waiting[i] = length.Val() > 0
+	}
+	return waiting, nil
+}
+
+// serve takes stream's lease and applies up to a batch of its entries in
+// order: first those a previous holder left pending, then new ones. It
+// returns how many were finished. The lease is let go once the batch is
+// done; if an entry is left pending it is kept until it lapses, so the
+// entry is retried after ClaimIdle and the ones behind it wait.
+func (q *redisScoreQueue) serve(ctx context.Context, stream, owner string, apply applyFunc) (int, error) {
+	lease := stream + ":owner"
+	held, err := q.client.SetNX(ctx, lease, owner, q.config.ClaimIdle).Result()
+	if err != nil || !held {
+		return 0, err
+	}
+
+	msgs, err := q.takeOver(ctx, stream)
+	if err == nil && len(msgs) == 0 {
+		msgs, err = q.readNew(ctx, stream)
+	}
+	finished := 0
+	for _, msg := range msgs {
+		if ctx.Err() != nil {
+			break
+		}
+		if !q.renew(lease, owner) {
+			// Another worker may hold the shard now and take over from here
+			return finished, errors.New("lease lapsed")
+		}
+		if !q.process(stream, msg, apply) {
+			return finished, nil
+		}
+		finished++
+	}
+	// Unstarted entries of the batch stay pending for the next holder
+	q.release(lease, owner)
+	return finished, err
+}
+
+// takeOver claims the entries a previous holder of stream's lease left
+// pending, oldest first, dead-lettering those delivered too often. Holding
+// the lease means nobody else is applying them, so they are claimed
+// however briefly they have been idle.
+func (q *redisScoreQueue) takeOver(ctx context.Context, stream string) ([]redis.XMessage, error) {
+	msgs, _, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   stream,
+		Group:    scoreStreamGroup,
+		Consumer: q.consumer,
+		Start:    "0-0",
+		Count:    scoreStreamBatch,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	live := msgs[:0]
+	for _, msg := range msgs {
+		q.mu.Lock()
+		q.recovered++
+		q.mu.Unlock()
+
+		if q.deliveries(stream, msg.ID) > int64(q.config.MaxRetries) {
+			if !q.deadLetter(stream, msg, ErrDeadLettered) {
+				// Still pending, so the entries behind it wait too
+				break
+			}
+			continue
+		}
+		live = append(live, msg)
+	}
+	return live, nil
+}
+
+// readNew reads entries of stream not yet delivered to anyone
+func (q *redisScoreQueue) readNew(ctx context.Context, stream string) ([]redis.XMessage, error) {
+	streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    scoreStreamGroup,
+		Consumer: q.consumer,
+		Streams:  []string{stream, ">"},
+		Count:    scoreStreamBatch,
+		Block:    -1, // Shards are polled, so don't wait on one
+	}).Result()
+	if errors.Is(err, redis.Nil) || len(streams) == 0 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return streams[0].Messages, nil
+}
+
+// renew extends owner's lease before it applies another entry, reporting
+// whether it still holds it
+func (q *redisScoreQueue) renew(lease, owner string) bool {
+	renewed, err := renewLeaseScript.Run(context.Background(), q.client, []string{lease}, owner, q.config.ClaimIdle.Milliseconds()).Int()
+	return err == nil && renewed == 1
+}
+
+// release lets the shard go if owner still holds it
+func (q *redisScoreQueue) release(lease, owner string) {
+	if err := releaseLeaseScript.Run(context.Background(), q.client, []string{lease}, owner).Err(); err != nil {
+		// It lapses after ClaimIdle instead
+		log.Printf("Failed to release score stream lease %s: %v", lease, err)
+	}
+}
+
+// deliveries returns how many times an entry of stream has been delivered
+func (q *redisScoreQueue) deliveries(stream, id string) int64 {
+	pending, err := q.client.XPendingExt(context.Background(), &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  scoreStreamGroup,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		return 0
+	}
+	return pending[0].RetryCount
+}
+
+// process applies one entry of stream and acknowledges it, reporting
+// whether it is done with. If the outcome can't be recorded the entry stays
+// pending for the shard's next holder.
+func (q *redisScoreQueue) process(stream string, msg redis.XMessage, apply applyFunc) bool {
+	item, err := decodeQueuedScore(msg)
+	if err != nil {
+		log.Printf("Dead-lettering unreadable score entry %s: %v", msg.ID, err)
+		return q.deadLetter(stream, msg, err)
+	}
+
+	result, applyErr := apply(item)
+	if retryLater(applyErr) {
+		return false
+	}
+
+	status := &SubmissionStatus{Submission: item.submission()}
+	completed(status, result, applyErr)
+	if err := q.finish(stream, msg.ID, status); err != nil {
+		q.reportHealth("score_queue", err)
+		log.Printf("Failed to record score submission %s: %v", item.ID, err)
+		return false
+	}
+
+	q.mu.Lock()
+	if applyErr != nil {
+		q.failed++
+	} else {
+		q.applied++
+	}
+	q.mu.Unlock()
+	return true
+}
+
+// finish records the outcome and removes the entry from stream
+func (q *redisScoreQueue) finish(stream, entryID string, status *SubmissionStatus) error {
+	ctx := context.Background()
+	encoded, err := encodeSubmission(status)
+	if err != nil {
+		return err
+	}
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, q.statusPrefix+status.ID, encoded, submissionStatusTTL)
+		pipe.XAck(ctx, stream, scoreStreamGroup, entryID)
+		pipe.XDel(ctx, stream, entryID)
+		return nil
+	})
+	return err
+}
+
+// deadLetter moves an entry of stream to the dead-letter stream and marks
+// its submission failed, reporting whether the entry left stream
+func (q *redisScoreQueue) deadLetter(stream string, msg redis.XMessage, reason error) bool {
+	ctx := context.Background()
+	values := map[string]any{"entry_id": msg.ID, "reason": reason.Error(), "deliveries": q.deliveries(stream, msg.ID)}
+	for key, value := range msg.Values {
+		values[key] = value
+	}
+	if err := q.client.XAdd(ctx, &redis.XAddArgs{Stream: q.deadLetters, Values: values}).Err(); err != nil {
+		log.Printf("Failed to dead-letter score entry %s: %v", msg.ID, err)
+		return false
+	}
+
+	if item, err := decodeQueuedScore(msg); err == nil {
+		status := &SubmissionStatus{Submission: item.submission()}
+		completed(status, nil, ErrDeadLettered)
+		if err := q.finish(stream, msg.ID, status); err != nil {
+			log.Printf("Failed to record dead-lettered submission %s: %v", item.ID, err)
+			return false
+		}
+	} else if err := q.client.XAck(ctx, stream, scoreStreamGroup, msg.ID).Err(); err == nil {
+		q.client.XDel(ctx, stream, msg.ID)
+	}
+
+	q.mu.Lock()
+	q.deadLettered++
+	q.mu.Unlock()
+	log.Printf("☠️  Dead-lettered score entry %s: %v", msg.ID, reason)
+	return true
+}
+
+// maxListedDeadLetters bounds the dead letters read for a listing
+const maxListedDeadLetters = 1000
+
+// listDeadLetters lists the oldest entries of the dead-letter stream
+func (q *redisScoreQueue) listDeadLetters(ctx context.Context) ([]models.DeadLetter, error) {
+	msgs, err := q.client.XRangeN(ctx, q.deadLetters, "-", "+", maxListedDeadLetters).Result()
+	if err != nil {
+		return nil, err
+	}
+	letters := make([]models.DeadLetter, 0, len(msgs))
+	for _, msg := range msgs {
+		letters = append(letters, describeDeadLetter(msg, false))
+	}
+	return letters, nil
+}
+
+func (q *redisScoreQueue) getDeadLetter(ctx context.Context, entryID string) (*models.DeadLetter, error) {
+	msg, err := q.readDeadLetter(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+	letter := describeDeadLetter(msg, true)
+	return &letter, nil
+}
+
+// retryDeadLetter moves an entry back onto its shard's stream, marking its
+// submission queued again. The submission ledger still guards against a
+// submission that was applied before it was dead-lettered.
+func (q *redisScoreQueue) retryDeadLetter(ctx context.Context, entryID string) error {
+	q.mu.Lock()
+	closed := q.closed
+	q.mu.Unlock()
+	if closed {
+		return ErrQueueClosed
+	}
+
+	msg, err := q.readDeadLetter(ctx, entryID)
+	if err != nil {
+		return err
+	}
+	item, err := decodeQueuedScore(msg)
+	if err != nil {
+		return fmt.Errorf("dead letter %s can't be retried: %w", entryID, err)
+	}
+	status, err := encodeSubmission(&SubmissionStatus{Submission: item.submission()})
+	if err != nil {
+		return err
+	}
+
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, q.statusPrefix+item.ID, status, submissionStatusTTL)
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: q.streamFor(item.Username),
+			Values: map[string]any{"submission": msg.Values["submission"]},
+		})
+		pipe.XDel(ctx, q.deadLetters, entryID)
+		return nil
+	})
+	if err == nil {
+		log.Printf("♻️  Requeued dead-lettered score entry %s", entryID)
+	}
+	return err
+}
+
+func (q *redisScoreQueue) discardDeadLetter(ctx context.Context, entryID string) error {
+	removed, err := q.client.XDel(ctx, q.deadLetters, entryID).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+func (q *redisScoreQueue) readDeadLetter(ctx context.Context, entryID string) (redis.XMessage, error) {
+	msgs, err := q.client.XRangeN(ctx, q.deadLetters, entryID, entryID, 1).Result()
+	if err != nil {
+		// Malformed IDs are rejected by Redis
+		if strings.Contains(err.Error(), "Invalid stream ID") {
+			return redis.XMessage{}, ErrDeadLetterNotFound
+		}
+		return redis.XMessage{}, err
+	}
+	if len(msgs) == 0 {
+		return redis.XMessage{}, ErrDeadLetterNotFound
+	}
+	return msgs[0], nil
+}
+
+// describeDeadLetter reads a dead-letter stream entry. The entry's ID is the
+// time it was dead-lettered.
+func describeDeadLetter(msg redis.XMessage, detail bool) models.DeadLetter {
+	reason, _ := msg.Values["reason"].(string)
+	letter := models.DeadLetter{
+		ID:     models.DeadLetterScore + ":" + msg.ID,
+		Kind:   models.DeadLetterScore,
+		Target: fmt.Sprint(msg.Values["entry_id"]),
+		Reason: reason,
+	}
+	if ms, _, ok := strings.Cut(msg.ID, "-"); ok {
+		if millis, err := strconv.ParseInt(ms, 10, 64); err == nil {
+			letter.FailedAt = time.UnixMilli(millis).UTC()
+		}
+	}
+	if deliveries, ok := msg.Values["deliveries"].(string); ok {
+		letter.Attempts, _ = strconv.Atoi(deliveries)
+	}
+
+	item, err := decodeQueuedScore(msg)
+	if err == nil {
+		letter.Target = item.ID
+	}
+	if detail {
+		if payload, ok := msg.Values["submission"].(string); ok && json.Valid([]byte(payload)) {
+			letter.Payload = json.RawMessage(payload)
+		}
+		letter.Errors = []models.DeliveryAttempt{{Error: reason, At: letter.FailedAt}}
+	}
+	return letter
+}
+
+func decodeQueuedScore(msg redis.XMessage) (queuedScore, error) {
+	var item queuedScore
+	payload, ok := msg.Values["submission"].(string)
+	if !ok {
+		return item, errors.New("missing submission field")
+	}
+	err := json.Unmarshal([]byte(payload), &item)
+	return item, err
+}
+
+func (q *redisScoreQueue) status(ctx context.Context, id string) (*SubmissionStatus, error) {
+	if q.buffer != nil {
+		if status := q.buffer.lookup(id); status != nil {
+			return status, nil
+		}
+	}
+	data, err := q.client.Get(ctx, q.statusPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrSubmissionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeSubmission(data)
+}
+
+func (q *redisScoreQueue) stats(ctx context.Context) (*models.ScoreQueueStats, error) {
+	q.mu.Lock()
+	stats := &models.ScoreQueueStats{
+		Backend:      "redis",
+		Workers:      q.config.Workers,
+		Capacity:     q.config.Capacity,
+		Applied:      q.applied,
+		Failed:       q.failed,
+		Rejected:     q.rejected,
+		Recovered:    q.recovered,
+		DeadLettered: q.deadLettered,
+		Draining:     q.closed,
+		SlowCommands: q.slowCommands.snapshot(),
+	}
+	q.mu.Unlock()
+
+	if q.buffer != nil {
+		counters := q.buffer.counters()
+		stats.WriteBuffer = &models.WriteBufferStats{
+			Capacity: q.buffer.capacity,
+			Policy:   q.buffer.policy,
+			Depth:    q.buffer.depth(),
+			Buffered: counters.buffered,
+			Flushed:  counters.flushed,
+			Dropped:  counters.dropped,
+		}
+	}
+
+	// Local counters are still reported while Redis is unreachable
+	depths := make([]*redis.IntCmd, len(q.streams))
+	pending := make([]*redis.XPendingCmd, len(q.streams))
+	var deadLetters *redis.IntCmd
+	_, err := q.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, stream := range q.streams {
+			depths[i] = pipe.XLen(ctx, stream)
+			pending[i] = pipe.XPending(ctx, stream, scoreStreamGroup)
+		}
+		deadLetters = pipe.XLen(ctx, q.deadLetters)
+		return nil
+	})
+	if err != nil {
+		stats.RedisError = err.Error()
+		return stats, nil
+	}
+	for i := range q.streams {
+		stats.Depth += int(depths[i].Val())
+		stats.Pending += pending[i].Val().Count
+	}
+	stats.DeadLetters = deadLetters.Val()
+	return stats, nil
+}
+
+// drain stops reading new entries and waits for those being applied.
+// Unread entries stay in the streams for the other replicas.
+func (q *redisScoreQueue) drain(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+
+	if q.cancel != nil {
+		q.cancel()
+	}
+	if err := waitGroupDone(ctx, &q.workers); err != nil {
+		return err
+	}
+
+	// A last attempt for submissions still waiting on Redis
+	if q.buffer != nil {
+		if err := q.buffer.flush(ctx, q.write); err != nil {
+			return fmt.Errorf("%d buffered submission(s) not written: %w", q.buffer.depth(), err)
+		}
+	}
+	return nil
+}
+
+// sleepCtx sleeps for d or until ctx is cancelled
+func sleepCtx(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// appliedLog records the ratings applied per user, in order, and fails the
+// test if one user's submissions are ever applied at the same time
+type appliedLog struct {
+	t        *testing.T
+	mu       sync.Mutex
+	ratings  map[string][]int
+	applying map[string]bool
+	total    int
+}
+
+func newAppliedLog(t *testing.T) *appliedLog {
+	return &appliedLog{t: t, ratings: make(map[string][]int), applying: make(map[string]bool)}
+}
+
+func (l *appliedLog) apply(item queuedScore) (*models.UpdateScoreResponse, error) {
+	l.mu.Lock()
+	if l.applying[item.Username] {
+		l.t.Errorf("two submissions of %s applied at once", item.Username)
+	}
+	l.applying[item.Username] = true
+	l.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.applying[item.Username] = false
+	l.ratings[item.Username] = append(l.ratings[item.Username], item.Request.Rating)
+	l.total++
+	return &models.UpdateScoreResponse{Rating: item.Request.Rating}, nil
+}
+
+// waitFor polls until n submissions were applied
+func (l *appliedLog) waitFor(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		l.mu.Lock()
+		total := l.total
+		l.mu.Unlock()
+		if total >= n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("only %d of %d submissions applied", l.total, n)
+}
+
+// testScoreStreams opens a Redis score queue for each replica, all on one
+// Redis, with their own consumer names
+func testScoreStreams(t *testing.T, mr *miniredis.Miniredis, replicas int) []*redisScoreQueue {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	config := ScoreQueueConfig{Workers: 3, Capacity: 1000, Shards: 4, Redis: client, ClaimIdle: time.Minute}
+
+	queues := make([]*redisScoreQueue, replicas)
+	for i := range queues {
+		q, err := newRedisScoreQueue(config, func(string, error) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		q.consumer = fmt.Sprintf("replica-%d", i)
+		queues[i] = q
+		t.Cleanup(func() { q.drain(context.Background()) })
+	}
+	return queues
+}
+
+func queueRating(t *testing.T, q *redisScoreQueue, username string, rating int) {
+	t.Helper()
+	item := queuedScore{ID: newSubmissionID(), Username: username, Request: models.UpdateScoreRequest{Rating: rating}}
+	if err := q.enqueue(context.Background(), item); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScoreStreamKeepsEachUsersOrder(t *testing.T) {
+	mr := miniredis.RunT(t)
+	queues := testScoreStreams(t, mr, 2)
+	users := []string{"alice", "bob", "carol", "dave", "erin"}
+	const perUser = 20
+	for rating := range perUser {
+		for _, username := range users {
+			queueRating(t, queues[rating%2], username, rating)
+		}
+	}
+
+	log := newAppliedLog(t)
+	for _, q := range queues {
+		q.start(log.apply, func() bool { return false })
+	}
+	log.waitFor(t, perUser*len(users))
+
+	want := make([]int, perUser)
+	for i := range want {
+		want[i] = i
+	}
+	for _, username := range users {
+		if got := log.ratings[username]; !slices.Equal(got, want) {
+			t.Errorf("%s's ratings applied as %v, want in queued order", username, got)
+		}
+	}
+}
+
+func TestScoreStreamTakesOverAfterTheLeaseLapses(t *testing.T) {
+	mr := miniredis.RunT(t)
+	q := testScoreStreams(t, mr, 1)[0]
+	for rating := 1; rating <= 3; rating++ {
+		queueRating(t, q, "alice", rating)
+	}
+
+	// A worker of another replica took the shard and read the first entry,
+	// then died before applying it
+	ctx := context.Background()
+	stream := q.streamFor("alice")
+	if err := q.client.Set(ctx, stream+":owner", "gone/0", q.config.ClaimIdle).Err(); err != nil {
+		t.Fatal(err)
+	}
+	err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: scoreStreamGroup, Consumer: "gone", Streams: []string{stream, ">"}, Count: 1, Block: -1,
+	}).Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := newAppliedLog(t)
+	q.start(log.apply, func() bool { return false })
+	time.Sleep(3 * scoreStreamIdle)
+	log.mu.Lock()
+	applied := log.total
+	log.mu.Unlock()
+	if applied != 0 {
+		t.Fatalf("%d submissions applied while another worker held the shard", applied)
+	}
+
+	mr.FastForward(q.config.ClaimIdle)
+	log.waitFor(t, 3)
+	if got := log.ratings["alice"]; !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("ratings applied as %v, want the abandoned one first", got)
+	}
+	// The last submission is acknowledged just after it is applied
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		stats, err := q.stats(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Recovered == 1 && stats.Pending == 0 && stats.Depth == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stats %+v, want 1 recovered and nothing left", stats)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
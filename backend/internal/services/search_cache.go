@@ -0,0 +1,169 @@
+package services
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/models"
+)
+
+// DefaultSearchCacheTTL is how long search results are served before searching again
+const DefaultSearchCacheTTL = 2 * time.Second
+
+// maxSearchCacheResults bounds the results held across all cached queries.
+// A search returning more is served but not cached.
+const maxSearchCacheResults = 100000
+
+type searchCacheKey struct {
+	query  string // normalized
+	limit  int
+	page   int // 0 when paging by cursor
+	cursor string
+}
+
+type searchCacheEntry struct {
+	page     *models.SearchResponse
+	keys     []int64 // Sort key of each result, to rank it when served
+	cachedAt time.Time
+}
+
+// searchCache keeps recent search results so autocomplete traffic, which
+// repeats the same prefixes, doesn't search again. Entries are dropped when
+// a write touches a username the query matches. Writes to other users only
+// move the results' ranks, so cached ranks aren't served: see rerank.
+type searchCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration // 0 disables caching
+	entries    map[searchCacheKey]*searchCacheEntry
+	size       int    // Results held across entries
+	generation uint64 // Bumped by every invalidation
+	hits       int64
+	misses     int64
+}
+
+func newSearchCache() *searchCache {
+	return &searchCache{
+		ttl:     DefaultSearchCacheTTL,
+		entries: make(map[searchCacheKey]*searchCacheEntry),
+	}
+}
+
+// normalizeSearchQuery maps queries that match the same users to one key:
+// matching is case-insensitive, and surrounding whitespace is trimmed
+// before the search runs
+func normalizeSearchQuery(query string) string {
+	return strings.ToLower(strings.TrimSpace(query))
+}
+
+// SetSearchCacheTTL changes how long search results are cached; 0 disables the cache
+func (s *LeaderboardService) SetSearchCacheTTL(ttl time.Duration) {
+	c := s.searchCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ttl = max(ttl, 0)
+	c.reset()
+}
+
+// lookup returns a cached entry for key and the generation a miss should be
+// stored under
+func (c *searchCache) lookup(key searchCacheKey, now time.Time) (*searchCacheEntry, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[key]; ok && now.Sub(entry.cachedAt) < c.ttl {
+		c.hits++
+		return entry, c.generation, true
+	}
+	c.misses++
+	return nil, c.generation, false
+}
+
+// rerank copies a cached page with each result ranked afresh from its sort
+// key, as writes to users outside the page move them without dropping it
+func (s *LeaderboardService) rerank(entry *searchCacheEntry) *models.SearchResponse {
+	page := *entry.page
+	page.Results = slices.Clone(entry.page.Results)
+	for i := range page.Results {
+		if i > 0 && entry.keys[i] == entry.keys[i-1] {
+			page.Results[i].Rank = page.Results[i-1].Rank
+			continue
+		}
+		page.Results[i].Rank = int64(s.store.RankForKey(entry.keys[i]))
+	}
+	return &page
+}
+
+// store caches a page unless a write invalidated the cache since the search
+// began, in which case it may already be stale
+func (c *searchCache) store(key searchCacheKey, page *models.SearchResponse, keys []int64, generation uint64, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ttl <= 0 || generation != c.generation {
+		return
+	}
+	if old, ok := c.entries[key]; ok {
+		c.size -= len(old.page.Results)
+		delete(c.entries, key)
+	}
+	if c.size+len(page.Results) > maxSearchCacheResults {
+		c.evictExpired(now)
+		if c.size+len(page.Results) > maxSearchCacheResults {
+			return
+		}
+	}
+	c.entries[key] = &searchCacheEntry{page: page, keys: keys, cachedAt: now}
+	c.size += len(page.Results)
+}
+
+// Handle drops cached queries matching the username of a membership or
+// rating change
+func (c *searchCache) Handle(e events.Event) {
+	switch e.Type {
+	case events.TypeUserAdded, events.TypeScoreUpdated, events.TypeUserEvicted, events.TypeUserExpired:
+	default:
+		return
+	}
+	username := strings.ToLower(e.Username)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for key, entry := range c.entries {
+		if strings.HasPrefix(username, key.query) {
+			c.size -= len(entry.page.Results)
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *searchCache) evictExpired(now time.Time) {
+	for key, entry := range c.entries {
+		if now.Sub(entry.cachedAt) >= c.ttl {
+			c.size -= len(entry.page.Results)
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *searchCache) reset() {
+	clear(c.entries)
+	c.size = 0
+	c.generation++
+}
+
+// cacheStats reports how often searches were served from cache
+func (c *searchCache) cacheStats() models.CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := models.CacheStats{Name: "search", Hits: c.hits, Misses: c.misses}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total)
+	}
+	return stats
+}
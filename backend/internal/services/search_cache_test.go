@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+func TestSearchCacheServesCurrentRanks(t *testing.T) {
+	s := NewLeaderboardService(store.NewMemoryStore())
+	ctx := context.Background()
+	for name, rating := range map[string]int{"alice": 2000, "alina": 2000, "bob": 1500, "carol": 1000} {
+		if err := s.store.AddUser(name, rating); err != nil {
+			t.Fatal(err)
+		}
+	}
+	search := func() string {
+		t.Helper()
+		page, err := s.SearchUser(ctx, "ali", SearchOptions{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ranks := make([]string, len(page.Results))
+		for i, r := range page.Results {
+			ranks[i] = fmt.Sprintf("%s:%d", r.Username, r.Rank)
+		}
+		return fmt.Sprint(ranks)
+	}
+
+	if got, want := search(), "[alice:1 alina:1]"; got != want {
+		t.Fatalf("first search %s, want %s", got, want)
+	}
+	// Users the query doesn't match move the ranks without dropping the entry
+	for _, name := range []string{"bob", "carol"} {
+		if _, err := s.SubmitScore(ctx, name, models.UpdateScoreRequest{Rating: 2500}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := search(), "[alice:3 alina:3]"; got != want {
+		t.Errorf("cached search %s, want %s", got, want)
+	}
+	if stats := s.searchCache.cacheStats(); stats.Hits != 1 {
+		t.Errorf("%d cache hits, want the second search served from the cache", stats.Hits)
+	}
+}
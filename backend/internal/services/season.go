@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/ranking"
+	"backend/pkg/store"
+)
+
+var (
+	// ErrNoSeason is returned when closing a season while none is running
+	ErrNoSeason = errors.New("no season is running")
+	// ErrSeasonActive is returned when starting a season while another is running
+	ErrSeasonActive = errors.New("a season is already running")
+)
+
+// SeasonConfig starts a season at startup
+type SeasonConfig struct {
+	ID     string
+	EndsAt time.Time // Closed automatically at this time; zero leaves closing to the admin API
+}
+
+// seasonState tracks the running season and the standings frozen when the
+// last one closed
+type seasonState struct {
+	mu      sync.Mutex
+	current *models.SeasonInfo
+	final   *models.SeasonResult // nil until a season closes
+}
+
+// StartSeason begins a season. Only one season runs at a time.
+func (s *LeaderboardService) StartSeason(ctx context.Context, id string, endsAt *time.Time) (*models.SeasonInfo, error) {
+	ss := s.season
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if ss.current != nil {
+		return nil, ErrSeasonActive
+	}
+	if endsAt != nil && !endsAt.After(s.clock.Now()) {
+		return nil, models.FieldError{Field: "ends_at", Rule: "gt", Param: "now"}
+	}
+
+	ss.current = &models.SeasonInfo{ID: id, StartsAt: s.clock.Now().UTC(), EndsAt: endsAt}
+	log.Printf("🏁 Started season %s", id)
+	season := *ss.current
+	return &season, nil
+}
+
+// RescheduleSeason moves the running season's end time; nil leaves closing
+// to the admin API
+func (s *LeaderboardService) RescheduleSeason(ctx context.Context, id string, endsAt *time.Time) error {
+	ss := s.season
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if ss.current == nil || ss.current.ID != id {
+		return ErrNoSeason
+	}
+	if endsAt != nil && !endsAt.After(s.clock.Now()) {
+		return models.FieldError{Field: "ends_at", Rule: "gt", Param: "now"}
+	}
+	ss.current.EndsAt = endsAt
+	return nil
+}
+
+// CurrentSeason returns the running season, or nil
+func (s *LeaderboardService) CurrentSeason() *models.SeasonInfo {
+	ss := s.season
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if ss.current == nil {
+		return nil
+	}
+	season := *ss.current
+	return &season
+}
+
+// CloseSeason freezes the final standings of the running season and
+// delivers them to the season webhooks
+func (s *LeaderboardService) CloseSeason(ctx context.Context) (*models.SeasonResult, error) {
+	ss := s.season
+	ss.mu.Lock()
+	if ss.current == nil {
+		ss.mu.Unlock()
+		return nil, ErrNoSeason
+	}
+
+	// Closing goes ahead even if the caller stops waiting for it
+	users, err := s.store.GetAllUsers(context.WithoutCancel(ctx))
+	if err != nil {
+		ss.mu.Unlock()
+		return nil, err
+	}
+
+	closedAt := s.clock.Now().UTC()
+	season := *ss.current
+	season.EndsAt = &closedAt
+	result := &models.SeasonResult{
+		BoardID:   s.boardID,
+		Season:    season,
+		ClosedAt:  closedAt,
+		Standings: rankEntries(users),
+	}
+	result.TotalUsers = len(result.Standings)
+	ss.current = nil
+	ss.final = result
+	ss.mu.Unlock()
+
+	log.Printf("🏁 Closed season %s with %d ranked users", season.ID, result.TotalUsers)
+	s.RecordIncident("season", IncidentLifecycle, fmt.Sprintf("season %s closed", season.ID))
+	s.deliverSeasonResult(result)
+	return result, nil
+}
+
+// LastSeasonResult returns the standings frozen when the last season closed, or nil
+func (s *LeaderboardService) LastSeasonResult() *models.SeasonResult {
+	ss := s.season
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.final
+}
+
+// StartSeasonScheduler closes the running season once its end time passes
+func (s *LeaderboardService) StartSeasonScheduler(ctx context.Context, interval time.Duration) {
+	ticker := s.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C():
+			s.closeEndedSeason(ctx, now)
+		}
+	}
+}
+
+// closeEndedSeason closes the running season if its end time passed by
+// now. While maintenance mode is on it waits, so the standings are frozen
+// once writes are back rather than mid-migration.
+func (s *LeaderboardService) closeEndedSeason(ctx context.Context, now time.Time) {
+	season := s.CurrentSeason()
+	if season == nil || season.EndsAt == nil || now.Before(*season.EndsAt) || s.writesPaused() {
+		return
+	}
+	if _, err := s.CloseSeason(ctx); err != nil && !errors.Is(err, ErrNoSeason) {
+		log.Printf("Failed to close season %s: %v", season.ID, err)
+	}
+}
+
+// listedStandings returns the first n entries of standings that are listed,
+// for readers outside the service. Users hidden from the leaderboard are
+// left out, still holding their ranks, and anonymized ones named by their
+// alias. Frozen standings keep real names, so settings changed after a
+// season closed still apply.
+func (s *LeaderboardService) listedStandings(standings []models.LeaderboardEntry, n int) []models.LeaderboardEntry {
+	listed := make([]models.LeaderboardEntry, 0, min(n, len(standings)))
+	for _, entry := range standings {
+		if len(listed) == n {
+			break
+		}
+		name, anonymous, ok := s.privacy.listed(entry.Username)
+		if !ok {
+			continue
+		}
+		entry.Username, entry.Anonymous = name, anonymous
+		listed = append(listed, entry)
+	}
+	return listed
+}
+
+// rankEntries assigns shared ranks to users sorted by rating
+func rankEntries(users []*store.User) []models.LeaderboardEntry {
+	entries := make([]models.LeaderboardEntry, 0, len(users))
+	var ranks ranking.Counter
+	for _, user := range users {
+		entries = append(entries, models.LeaderboardEntry{
+			Rank:     ranks.Next(user.Key),
+			Username: user.Username,
+			Rating:   user.Rating,
+			Bot:      user.Bot,
+		})
+	}
+	return entries
+}
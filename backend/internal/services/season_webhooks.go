@@ -0,0 +1,205 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+)
+
+// maxWebhookDeliveries bounds the delivery records kept for the admin API
+const maxWebhookDeliveries = 100
+
+// maxDeliveryHistory bounds the failed attempts kept per delivery
+const maxDeliveryHistory = 50
+
+// SeasonWebhookConfig delivers final standings to external systems when a season closes
+type SeasonWebhookConfig struct {
+	URLs        []string
+	Secret      string        // Signs payloads in X-Signature when set
+	TopN        int           // Standings included in the payload, defaults to 100
+	MaxAttempts int           // Defaults to 5
+	Backoff     time.Duration // Delay before the first retry, doubling after each; defaults to 2s
+	Timeout     time.Duration // Per attempt, defaults to 10s
+}
+
+// seasonWebhooks posts season results with retries and records each delivery
+type seasonWebhooks struct {
+	config     SeasonWebhookConfig
+	client     *http.Client
+	mu         sync.Mutex
+	nextID     int64
+	deliveries []*webhookDelivery // oldest first
+}
+
+// webhookDelivery is a delivery with the body it posts and its failed
+// attempts, kept so a failed delivery can be inspected and retried
+type webhookDelivery struct {
+	models.WebhookDelivery
+	body    []byte
+	history []models.DeliveryAttempt
+}
+
+// EnableSeasonWebhooks posts the final top standings to config.URLs whenever a season closes
+func (s *LeaderboardService) EnableSeasonWebhooks(config SeasonWebhookConfig) {
+	if config.TopN <= 0 {
+		config.TopN = 100
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = 2 * time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	s.webhooks = &seasonWebhooks{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// SignSeasonWebhook returns the hex HMAC-SHA256 sent in X-Signature: the
+// secret signs "<timestamp>.<body>"
+func SignSeasonWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliverSeasonResult posts the top of result to every webhook in the background
+func (s *LeaderboardService) deliverSeasonResult(result *models.SeasonResult) {
+	wh := s.webhooks
+	if wh == nil {
+		return
+	}
+
+	payload := *result
+	payload.Standings = s.listedStandings(result.Standings, wh.config.TopN)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to encode season webhook payload: %v", err)
+		return
+	}
+
+	for _, url := range wh.config.URLs {
+		delivery := wh.track(url, result.Season.ID, body)
+		go s.deliver(wh, delivery)
+	}
+}
+
+// track records a new pending delivery of body
+func (wh *seasonWebhooks) track(url, season string, body []byte) *webhookDelivery {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	wh.nextID++
+	delivery := &webhookDelivery{
+		WebhookDelivery: models.WebhookDelivery{
+			ID:        season + "-" + strconv.FormatInt(wh.nextID, 10),
+			URL:       url,
+			Season:    season,
+			Status:    models.DeliveryPending,
+			UpdatedAt: time.Now().UTC(),
+		},
+		body: body,
+	}
+	wh.deliveries = append(wh.deliveries, delivery)
+	if len(wh.deliveries) > maxWebhookDeliveries {
+		wh.deliveries = append([]*webhookDelivery(nil), wh.deliveries[len(wh.deliveries)-maxWebhookDeliveries:]...)
+	}
+	return delivery
+}
+
+// deliver posts the delivery's body until the receiver answers 2xx or
+// attempts run out. Every attempt carries the same X-Delivery-ID so
+// receivers can deduplicate, including attempts after a manual retry.
+func (s *LeaderboardService) deliver(wh *seasonWebhooks, delivery *webhookDelivery) {
+	backoff := wh.config.Backoff
+	for attempt := 1; ; attempt++ {
+		status, err := wh.post(delivery)
+
+		wh.mu.Lock()
+		delivery.Attempts++
+		delivery.StatusCode = status
+		delivery.UpdatedAt = time.Now().UTC()
+		delivery.LastError = ""
+		if err != nil {
+			delivery.LastError = err.Error()
+			delivery.history = append(delivery.history, models.DeliveryAttempt{StatusCode: status, Error: err.Error(), At: delivery.UpdatedAt})
+			if len(delivery.history) > maxDeliveryHistory {
+				delivery.history = delivery.history[len(delivery.history)-maxDeliveryHistory:]
+			}
+		}
+		switch {
+		case err == nil:
+			delivery.Status = models.DeliveryDelivered
+		case attempt == wh.config.MaxAttempts:
+			delivery.Status = models.DeliveryFailed
+		}
+		done := delivery.Status != models.DeliveryPending
+		wh.mu.Unlock()
+
+		if done {
+			s.ReportHealth("season_webhook", err)
+			if err != nil {
+				log.Printf("Season webhook to %s failed after %d attempt(s): %v", delivery.URL, attempt, err)
+			}
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (wh *seasonWebhooks) post(delivery *webhookDelivery) (int, error) {
+	body := delivery.body
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Delivery-ID", delivery.ID)
+	req.Header.Set("X-Timestamp", timestamp)
+	if wh.config.Secret != "" {
+		req.Header.Set("X-Signature", SignSeasonWebhook(wh.config.Secret, timestamp, body))
+	}
+
+	resp, err := wh.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// ListWebhookDeliveries returns season webhook deliveries, newest first
+func (s *LeaderboardService) ListWebhookDeliveries() []models.WebhookDelivery {
+	list := []models.WebhookDelivery{}
+	wh := s.webhooks
+	if wh == nil {
+		return list
+	}
+
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+	for i := len(wh.deliveries) - 1; i >= 0; i-- {
+		list = append(list, wh.deliveries[i].WebhookDelivery)
+	}
+	return list
+}
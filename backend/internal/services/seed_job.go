@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+var (
+	// ErrSeedRunning is returned when a seed is started while another runs
+	ErrSeedRunning = errors.New("a seed is already running")
+	// ErrSeedJobNotFound is returned for unknown or forgotten seed jobs
+	ErrSeedJobNotFound = errors.New("seed job not found")
+)
+
+const (
+	// seedBatchSize is how many generated users are written under one store
+	// lock. The pipeline's channels hold a batch each, so a seed's memory use
+	// doesn't grow with its count.
+	seedBatchSize = 500
+	seedJobsKept  = 20
+	// seedLogEvery is how often, in written users, a seed logs its progress
+	seedLogEvery = 10000
+)
+
+// SeedProgress is called with a snapshot of a seed job after each batch
+// and once more when it finishes
+type SeedProgress func(job models.SeedJob)
+
+// seedRow is a generated user on its way through the seed pipeline
+type seedRow struct {
+	bot     store.NewBot
+	invalid string // Why validation rejected it
+}
+
+// seedJobs runs one seed at a time and keeps recent jobs
+type seedJobs struct {
+	mu      sync.Mutex
+	jobs    []models.SeedJob // Newest first
+	running string           // ID of the running job, if any
+	cancel  context.CancelFunc
+	done    map[string]chan struct{} // Closed when a job finishes
+	errs    map[string]error         // Why failed jobs stopped
+}
+
+func newSeedJobs() *seedJobs {
+	return &seedJobs{
+		done: make(map[string]chan struct{}),
+		errs: make(map[string]error),
+	}
+}
+
+// StartSeed starts seeding count generated bot users in the background and
+// returns the job as it starts. progress, if set, is called after each
+// batch. The seed runs until it finishes, fails or is cancelled with
+// CancelSeed; it doesn't depend on the caller's context.
+func (s *LeaderboardService) StartSeed(count int, progress SeedProgress) (models.SeedJob, error) {
+	sj := s.seeds
+	sj.mu.Lock()
+	defer sj.mu.Unlock()
+
+	if sj.running != "" {
+		return models.SeedJob{}, ErrSeedRunning
+	}
+
+	job := models.SeedJob{
+		ID:        newSubmissionID(),
+		Status:    models.SeedRunning,
+		Requested: count,
+		StartedAt: s.clock.Now().UTC(),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	sj.running, sj.cancel = job.ID, cancel
+	sj.done[job.ID] = done
+	sj.recordLocked(job)
+
+	go func() {
+		defer close(done)
+		defer cancel()
+		s.runSeed(ctx, job, progress)
+	}()
+	return job, nil
+}
+
+// SeedData seeds count generated bot users and waits for the seed to finish.
+// If ctx ends first the seed is cancelled and ctx's error returned.
+func (s *LeaderboardService) SeedData(ctx context.Context, count int) (models.SeedJob, error) {
+	started, err := s.StartSeed(count, nil)
+	if err != nil {
+		return started, err
+	}
+	job, err := s.WaitSeed(ctx, started.ID)
+	if err != nil {
+		// The caller is gone, but the seed should be stopped before returning
+		s.CancelSeed(context.WithoutCancel(ctx), started.ID)
+		return job, err
+	}
+	if job.Status == models.SeedFailed {
+		s.seeds.mu.Lock()
+		err = s.seeds.errs[job.ID]
+		s.seeds.mu.Unlock()
+		if err == nil {
+			err = errors.New(job.Error)
+		}
+		return job, fmt.Errorf("seed %s: %w", job.ID, err)
+	}
+	return job, nil
+}
+
+// WaitSeed waits for a seed job to finish and returns it, or returns ctx's
+// error if ctx ends first
+func (s *LeaderboardService) WaitSeed(ctx context.Context, id string) (models.SeedJob, error) {
+	sj := s.seeds
+	sj.mu.Lock()
+	done := sj.done[id]
+	sj.mu.Unlock()
+
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			job, _ := s.SeedJob(id)
+			return job, ctx.Err()
+		}
+	}
+	return s.SeedJob(id)
+}
+
+// SeedJob returns a recent seed job
+func (s *LeaderboardService) SeedJob(id string) (models.SeedJob, error) {
+	sj := s.seeds
+	sj.mu.Lock()
+	defer sj.mu.Unlock()
+
+	for _, job := range sj.jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+	return models.SeedJob{}, ErrSeedJobNotFound
+}
+
+// SeedJobs returns recent seed jobs, newest first
+func (s *LeaderboardService) SeedJobs() []models.SeedJob {
+	sj := s.seeds
+	sj.mu.Lock()
+	defer sj.mu.Unlock()
+	return append([]models.SeedJob{}, sj.jobs...)
+}
+
+// CancelSeed stops a running seed job and waits for it to finish its
+// current batch. Users it already wrote are kept. Cancelling a finished job
+// does nothing.
+func (s *LeaderboardService) CancelSeed(ctx context.Context, id string) (models.SeedJob, error) {
+	sj := s.seeds
+	sj.mu.Lock()
+	if sj.running == id {
+		sj.cancel()
+	}
+	sj.mu.Unlock()
+	return s.WaitSeed(ctx, id)
+}
+
+// record adds job to the history, replacing its earlier snapshot
+func (sj *seedJobs) record(job models.SeedJob) {
+	sj.mu.Lock()
+	defer sj.mu.Unlock()
+	sj.recordLocked(job)
+}
+
+func (sj *seedJobs) recordLocked(job models.SeedJob) {
+	for i := range sj.jobs {
+		if sj.jobs[i].ID == job.ID {
+			sj.jobs[i] = job
+			return
+		}
+	}
+	sj.jobs = append([]models.SeedJob{job}, sj.jobs...)
+	if len(sj.jobs) > seedJobsKept {
+		for _, old := range sj.jobs[seedJobsKept:] {
+			delete(sj.done, old.ID)
+			delete(sj.errs, old.ID)
+		}
+		sj.jobs = sj.jobs[:seedJobsKept]
+	}
+}
+
+// finish records a job's final state and lets the next seed start
+func (sj *seedJobs) finish(job models.SeedJob, err error) {
+	sj.mu.Lock()
+	defer sj.mu.Unlock()
+	sj.recordLocked(job)
+	if err != nil {
+		sj.errs[job.ID] = err
+	}
+	if sj.running == job.ID {
+		sj.running, sj.cancel = "", nil
+	}
+}
+
+// runSeed streams generated users through validation into batched store
+// writes, recording progress after each batch
+func (s *LeaderboardService) runSeed(ctx context.Context, job models.SeedJob, progress SeedProgress) {
+	log.Printf("Seed %s: seeding %d users (rand seed %d)...", job.ID, job.Requested, s.RandSeed())
+
+	// Keep the simulator from racing with the bulk write
+	endJob := s.beginBulkJob("seed")
+	defer endJob()
+
+	// The pipeline stops with the writer, however it ends
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	generated := make(chan seedRow, seedBatchSize)
+	validated := make(chan seedRow, seedBatchSize)
+	go s.generateSeed(ctx, job.Requested, generated)
+	go validateSeed(generated, validated)
+
+	report := func() {
+		s.seeds.record(job)
+		if progress != nil {
+			progress(job)
+		}
+	}
+
+	err := s.writeSeed(ctx, validated, &job, report)
+	stop()
+	// Let the generator and validator finish so nothing is left blocked
+	for range validated {
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		job.Status = models.SeedCancelled
+	case err != nil:
+		job.Status = models.SeedFailed
+		job.Error = err.Error()
+	default:
+		job.Status = models.SeedSucceeded
+	}
+	finished := s.clock.Now().UTC()
+	job.FinishedAt = &finished
+	s.seeds.finish(job, err)
+	if progress != nil {
+		progress(job)
+	}
+
+	log.Printf("Seed %s %s: %d generated, %d written, %d skipped",
+		job.ID, job.Status, job.Generated, job.Written, job.Skipped)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		s.RecordIncident("seed", IncidentFailure, err.Error())
+	}
+}
+
+// generateSeed sends count generated users, stopping early if ctx ends
+func (s *LeaderboardService) generateSeed(ctx context.Context, count int, out chan<- seedRow) {
+	defer close(out)
+	for i := range count {
+		row := seedRow{bot: store.NewBot{
+			Username: fmt.Sprintf("user_%d", i+1),
+			Rating:   s.random.seedRating(),
+		}}
+		select {
+		case out <- row:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// validateSeed marks generated users the board wouldn't accept
+func validateSeed(in <-chan seedRow, out chan<- seedRow) {
+	defer close(out)
+	for row := range in {
+		switch {
+		case row.bot.Username == "":
+			row.invalid = "empty username"
+		case row.bot.Rating < MinRating || row.bot.Rating > MaxRating:
+			row.invalid = fmt.Sprintf("rating %d is outside %d..%d", row.bot.Rating, MinRating, MaxRating)
+		}
+		out <- row
+	}
+}
+
+// writeSeed writes validated users in batches until in is closed, ctx ends
+// or the store refuses a write
+func (s *LeaderboardService) writeSeed(ctx context.Context, in <-chan seedRow, job *models.SeedJob, report func()) error {
+	batch := make([]store.NewBot, 0, seedBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		written, err := s.store.AddBots(batch)
+		for _, bot := range written {
+			s.mirrorShadow(bot.Username, bot.Rating, true)
+			s.events.Publish(events.Event{
+				Type:     events.TypeUserAdded,
+				Username: bot.Username,
+				Rating:   bot.Rating,
+				Bot:      true,
+				Source:   SourceImport,
+			})
+		}
+		before := job.Written
+		job.Written += len(written)
+		if err == nil {
+			job.Skipped += len(batch) - len(written)
+		}
+		batch = batch[:0]
+		report()
+		if job.Written/seedLogEvery > before/seedLogEvery {
+			log.Printf("Seed %s: written %d of %d users...", job.ID, job.Written, job.Requested)
+		}
+
+		if errors.Is(err, store.ErrMemoryBudget) {
+			return fmt.Errorf("seeded %d of %d users: %w", job.Written, job.Requested, err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to add users: %w", err)
+		}
+		return nil
+	}
+
+	for row := range in {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		job.Generated++
+		if row.invalid != "" {
+			job.Skipped++
+			continue
+		}
+		batch = append(batch, row.bot)
+		if len(batch) == seedBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
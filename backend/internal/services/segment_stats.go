@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"sync"
+
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+// segmentTotals sums the ratings of a segment's members, bots apart so
+// they can be left out without a scan
+type segmentTotals struct {
+	users, bots int64
+	sum, botSum int64
+}
+
+// add counts a member rated rating in, or out with sign -1
+func (t *segmentTotals) add(rating int, bot bool, sign int64) {
+	t.users += sign
+	t.sum += sign * int64(rating)
+	if bot {
+		t.bots += sign
+		t.botSum += sign * int64(rating)
+	}
+}
+
+// stats reports the segment, without its bots if excludeBots is set
+func (t *segmentTotals) stats(excludeBots bool) models.SegmentStats {
+	users, sum := t.users, t.sum
+	if excludeBots {
+		users, sum = users-t.bots, sum-t.botSum
+	}
+	stats := models.SegmentStats{Users: users}
+	if users > 0 {
+		stats.AverageRating = math.Round(float64(sum)/float64(users)*100) / 100
+	}
+	return stats
+}
+
+// countryRecords keeps the country each user last gave, while derived
+// boards, which keep it in their profiles, are off
+const countryRecords = "countries"
+
+// placements is where users are placed. Segment stats read it rather than
+// keep a copy, so the two can't disagree, and it outlives the process.
+type placements interface {
+	// country returns where username is placed, "" if nowhere
+	country(ctx context.Context, username string) (string, error)
+	// all returns where every placed user is
+	all(ctx context.Context) (map[string]string, error)
+	// leave forgets where username, who left the board, was placed
+	leave(ctx context.Context, username string) error
+}
+
+// recordPlacements keeps placements in the store's records, so they are
+// shared by replicas on Redis and handed over with the board
+type recordPlacements struct {
+	store store.Store
+}
+
+func (p recordPlacements) country(ctx context.Context, username string) (string, error) {
+	country, _ := p.store.Record(countryRecords, username)
+	return country, nil
+}
+
+func (p recordPlacements) all(ctx context.Context) (map[string]string, error) {
+	return p.store.Records(countryRecords), nil
+}
+
+func (p recordPlacements) leave(ctx context.Context, username string) error {
+	_, err := p.store.UpdateRecord(countryRecords, username, func(string) (string, error) { return "", nil })
+	return err
+}
+
+// place moves username to country and returns where they were before
+func (p recordPlacements) place(username, country string) (string, error) {
+	var before string
+	_, err := p.store.UpdateRecord(countryRecords, username, func(current string) (string, error) {
+		before = current
+		return country, nil
+	})
+	return before, err
+}
+
+// profilePlacements reads placements from the derived boards' profiles,
+// which score updates move with the boards. Users leaving are taken off by
+// the derived boards themselves.
+type profilePlacements struct {
+	boards store.Boards
+}
+
+func (p profilePlacements) country(ctx context.Context, username string) (string, error) {
+	profile, err := p.boards.Profile(ctx, username)
+	return profile.Country, err
+}
+
+func (p profilePlacements) all(ctx context.Context) (map[string]string, error) {
+	profiles, err := p.boards.Profiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	countries := make(map[string]string, len(profiles))
+	for username, profile := range profiles {
+		if profile.Country != "" {
+			countries[username] = profile.Country
+		}
+	}
+	return countries, nil
+}
+
+func (p profilePlacements) leave(ctx context.Context, username string) error {
+	return nil
+}
+
+// segmentStats keeps member counts and rating sums per country and per
+// tier, moved by every event rather than rescanned per request. Countries
+// come from score submissions, which name one; a user is counted in the
+// last country they gave, as placements holds it.
+type segmentStats struct {
+	mu         sync.Mutex
+	placements placements
+	countries  map[string]*segmentTotals
+	unplaced   segmentTotals // Users without a country
+	tiers      []models.TierBoundary
+	byTier     []segmentTotals // Parallel to tiers
+	untiered   segmentTotals   // Users rated below the lowest tier
+}
+
+func newSegmentStats(st store.Store) *segmentStats {
+	s := &segmentStats{placements: recordPlacements{store: st}}
+	s.rebuild(st, nil)
+	return s
+}
+
+// rebuild recounts every segment from the board and placements under new
+// tier boundaries, and returns the users counted
+func (s *segmentStats) rebuild(st store.Store, tiers []models.TierBoundary) int {
+	ctx := context.Background()
+	users, _ := st.GetAllUsers(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	placed, err := s.placements.all(ctx)
+	if err != nil {
+		log.Printf("⚠️  Failed to read where users are placed, counting them unplaced: %v", err)
+	}
+	s.countries = make(map[string]*segmentTotals)
+	s.unplaced = segmentTotals{}
+	s.tiers = tiers
+	s.byTier = make([]segmentTotals, len(tiers))
+	s.untiered = segmentTotals{}
+
+	for _, user := range users {
+		s.count(placed[user.Username], user.Rating, user.Bot, 1)
+	}
+	return len(users)
+}
+
+// use switches where placements are read from and recounts the segments
+func (s *segmentStats) use(p placements, st store.Store) {
+	s.mu.Lock()
+	s.placements = p
+	tiers := s.tiers
+	s.mu.Unlock()
+	s.rebuild(st, tiers)
+}
+
+// count adds a member to their country and tier, or takes them off with
+// sign -1, dropping a country left empty; callers hold mu
+func (s *segmentStats) count(country string, rating int, bot bool, sign int64) {
+	totals := s.country(country)
+	totals.add(rating, bot, sign)
+	if country != "" && totals.users == 0 {
+		delete(s.countries, country)
+	}
+	s.tier(rating).add(rating, bot, sign)
+}
+
+// country returns a country's totals, or the unplaced users' for ""
+func (s *segmentStats) country(code string) *segmentTotals {
+	if code == "" {
+		return &s.unplaced
+	}
+	totals, ok := s.countries[code]
+	if !ok {
+		totals = &segmentTotals{}
+		s.countries[code] = totals
+	}
+	return totals
+}
+
+// tier returns the totals of the tier a rating falls in
+func (s *segmentStats) tier(rating int) *segmentTotals {
+	i := sort.Search(len(s.tiers), func(i int) bool { return s.tiers[i].MinScore > rating }) - 1
+	if i < 0 {
+		return &s.untiered
+	}
+	return &s.byTier[i]
+}
+
+// move counts a user rated rating in country to rather than from, once
+// placements holds the move. Callers pass the rating the user is still
+// counted at, as the event for their new one hasn't been handled yet.
+func (s *segmentStats) move(from, to string, rating int, bot bool) {
+	if from == to {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.country(from).add(rating, bot, -1)
+	if totals := s.countries[from]; totals != nil && totals.users == 0 {
+		delete(s.countries, from)
+	}
+	s.country(to).add(rating, bot, 1)
+}
+
+// Handle moves the segments; it is subscribed to the service's event bus
+// ahead of the derived boards, so a user leaving is still placed when
+// their event is handled
+func (s *segmentStats) Handle(e events.Event) {
+	switch e.Type {
+	case events.TypeUserAdded, events.TypeScoreUpdated, events.TypeUserEvicted, events.TypeUserExpired:
+	default:
+		return
+	}
+	ctx := context.Background()
+	s.mu.Lock()
+	p := s.placements
+	s.mu.Unlock()
+	country, err := p.country(ctx, e.Username)
+	if err != nil {
+		log.Printf("⚠️  Failed to read where %s is placed, counting them unplaced: %v", e.Username, err)
+	}
+
+	s.mu.Lock()
+	switch e.Type {
+	case events.TypeUserAdded:
+		s.count(country, e.Rating, e.Bot, 1)
+	case events.TypeScoreUpdated:
+		s.count(country, e.PreviousRating, e.Bot, -1)
+		s.count(country, e.Rating, e.Bot, 1)
+	case events.TypeUserEvicted, events.TypeUserExpired:
+		s.count(country, e.Rating, e.Bot, -1)
+	}
+	s.mu.Unlock()
+
+	if e.Type == events.TypeUserEvicted || e.Type == events.TypeUserExpired {
+		if err := p.leave(ctx, e.Username); err != nil {
+			log.Printf("⚠️  Failed to forget where %s was placed: %v", e.Username, err)
+		}
+	}
+}
+
+// placeSegments counts the user whose score was just stored in the country
+// their submission named, if any. previous is the rating they had before it.
+// With derived boards on, the write placed them already, in writeAcrossBoards.
+func (s *LeaderboardService) placeSegments(ctx context.Context, username string, previous int, bot bool) {
+	segments, ok := ctx.Value(segmentsKey{}).(store.BoardProfile)
+	if !ok || segments.Country == "" {
+		return
+	}
+	s.segments.mu.Lock()
+	records, ok := s.segments.placements.(recordPlacements)
+	s.segments.mu.Unlock()
+	if !ok {
+		return
+	}
+	before, err := records.place(username, segments.Country)
+	if err != nil {
+		log.Printf("⚠️  Failed to place %s in %s: %v", username, segments.Country, err)
+		return
+	}
+	s.segments.move(before, segments.Country, previous, bot)
+}
+
+// CountryStats breaks the board down by country. It is read from totals
+// kept up to date with every score change, so it costs no scan.
+func (s *LeaderboardService) CountryStats(ctx context.Context, opts ListOptions) *models.CountryStatsResponse {
+	g := s.segments
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	response := &models.CountryStatsResponse{
+		Countries:    make([]models.CountryStats, 0, len(g.countries)),
+		Unplaced:     g.unplaced.stats(opts.ExcludeBots),
+		ExcludesBots: opts.ExcludeBots,
+	}
+	response.TotalUsers = response.Unplaced.Users
+	for code, totals := range g.countries {
+		stats := totals.stats(opts.ExcludeBots)
+		if stats.Users == 0 {
+			continue
+		}
+		response.Countries = append(response.Countries, models.CountryStats{Country: code, SegmentStats: stats})
+		response.TotalUsers += stats.Users
+	}
+	sort.Slice(response.Countries, func(i, j int) bool {
+		a, b := response.Countries[i], response.Countries[j]
+		if a.Users != b.Users {
+			return a.Users > b.Users
+		}
+		return a.Country < b.Country
+	})
+	return response
+}
+
+// TierStats breaks the board down by tier, read from totals kept up to date
+// like CountryStats'. Tiers without members are listed too.
+func (s *LeaderboardService) TierStats(ctx context.Context, opts ListOptions) (*models.TierStatsResponse, error) {
+	g := s.segments
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.tiers) == 0 {
+		return nil, ErrTiersNotConfigured
+	}
+	response := &models.TierStatsResponse{
+		Tiers:        make([]models.TierStats, 0, len(g.tiers)),
+		Unplaced:     g.untiered.stats(opts.ExcludeBots),
+		ExcludesBots: opts.ExcludeBots,
+	}
+	response.TotalUsers = response.Unplaced.Users
+	for i, tier := range g.tiers {
+		stats := g.byTier[i].stats(opts.ExcludeBots)
+		response.Tiers = append(response.Tiers, models.TierStats{Tier: tier.Name, MinScore: tier.MinScore, SegmentStats: stats})
+		response.TotalUsers += stats.Users
+	}
+	return response, nil
+}
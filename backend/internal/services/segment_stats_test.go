@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"testing"
+
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+// fakePlacements places users from a map, recording who left
+type fakePlacements struct {
+	countries map[string]string
+	left      []string
+}
+
+func (p *fakePlacements) country(ctx context.Context, username string) (string, error) {
+	return p.countries[username], nil
+}
+
+func (p *fakePlacements) all(ctx context.Context) (map[string]string, error) {
+	return maps.Clone(p.countries), nil
+}
+
+func (p *fakePlacements) leave(ctx context.Context, username string) error {
+	p.left = append(p.left, username)
+	delete(p.countries, username)
+	return nil
+}
+
+// countryUsers returns how many users each country has, "" for the unplaced
+func countryUsers(stats *models.CountryStatsResponse) map[string]int64 {
+	users := map[string]int64{"": stats.Unplaced.Users}
+	for _, country := range stats.Countries {
+		users[country.Country] = country.Users
+	}
+	return users
+}
+
+func TestSegmentStatsHandle(t *testing.T) {
+	type counted struct {
+		country string
+		rating  int
+	}
+	cases := []struct {
+		name    string
+		counted map[string]counted // Users counted before the events
+		placed  map[string]string
+		move    [2]string // From, to, for alice, before the events
+		events  []events.Event
+		want    map[string]int64
+		left    []string
+	}{
+		{
+			name:   "joins are counted where they're placed",
+			placed: map[string]string{"alice": "US"},
+			events: []events.Event{
+				{Type: events.TypeUserAdded, Username: "alice", Rating: 1500},
+				{Type: events.TypeUserAdded, Username: "bob", Rating: 1400},
+			},
+			want: map[string]int64{"": 1, "US": 1},
+		},
+		{
+			name:    "score updates stay in the country",
+			counted: map[string]counted{"alice": {"US", 1500}},
+			placed:  map[string]string{"alice": "US"},
+			events:  []events.Event{{Type: events.TypeScoreUpdated, Username: "alice", Rating: 1600, PreviousRating: 1500}},
+			want:    map[string]int64{"": 0, "US": 1},
+		},
+		{
+			name:    "a move counts the user in the new country",
+			counted: map[string]counted{"alice": {"US", 1500}},
+			placed:  map[string]string{"alice": "GB"},
+			move:    [2]string{"US", "GB"},
+			events:  []events.Event{{Type: events.TypeScoreUpdated, Username: "alice", Rating: 1600, PreviousRating: 1500}},
+			want:    map[string]int64{"": 0, "GB": 1},
+		},
+		{
+			name:    "users leaving are taken off and forgotten",
+			counted: map[string]counted{"alice": {"US", 1500}, "bob": {"US", 1400}},
+			placed:  map[string]string{"alice": "US", "bob": "US"},
+			events: []events.Event{
+				{Type: events.TypeUserEvicted, Username: "alice", Rating: 1500},
+				{Type: events.TypeUserExpired, Username: "bob", Rating: 1400},
+			},
+			want: map[string]int64{"": 0},
+			left: []string{"alice", "bob"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := &fakePlacements{countries: maps.Clone(c.placed)}
+			segments := newSegmentStats(store.NewMemoryStore())
+			segments.placements = p
+			for _, user := range c.counted {
+				segments.count(user.country, user.rating, false, 1)
+			}
+			segments.move(c.move[0], c.move[1], 1500, false)
+			for _, e := range c.events {
+				segments.Handle(e)
+			}
+
+			s := &LeaderboardService{segments: segments}
+			if got := countryUsers(s.CountryStats(context.Background(), ListOptions{})); !maps.Equal(got, c.want) {
+				t.Errorf("country users %v, want %v", got, c.want)
+			}
+			if fmt.Sprint(p.left) != fmt.Sprint(c.left) {
+				t.Errorf("left %v, want %v", p.left, c.left)
+			}
+		})
+	}
+}
+
+func TestCountryStatsFollowPlacements(t *testing.T) {
+	for _, derived := range []bool{false, true} {
+		t.Run(fmt.Sprintf("derived boards %v", derived), func(t *testing.T) {
+			ctx := context.Background()
+			st := playersStore(t, 4)
+			boards := store.NewMemoryBoards()
+			newService := func() *LeaderboardService {
+				s := NewLeaderboardService(st)
+				if derived {
+					if err := s.EnableDerivedBoards(DerivedBoardsConfig{Store: boards}); err != nil {
+						t.Fatal(err)
+					}
+				}
+				return s
+			}
+			s := newService()
+
+			for i, country := range []string{"US", "GB", "US", ""} {
+				if _, err := s.SubmitScore(ctx, fmt.Sprintf("player_%d", i), models.UpdateScoreRequest{Rating: 1500 + i, Country: country}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// player_0 moves, and player_2 submits without a country, so stays
+			if _, err := s.SubmitScore(ctx, "player_0", models.UpdateScoreRequest{Rating: 1600, Country: "GB"}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.SubmitScore(ctx, "player_2", models.UpdateScoreRequest{Rating: 1700}); err != nil {
+				t.Fatal(err)
+			}
+
+			want := map[string]int64{"": 1, "GB": 2, "US": 1}
+			if got := countryUsers(s.CountryStats(ctx, ListOptions{})); !maps.Equal(got, want) {
+				t.Errorf("country users %v, want %v", got, want)
+			}
+			placed, err := s.segments.placements.all(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if wantPlaced := map[string]string{"player_0": "GB", "player_1": "GB", "player_2": "US"}; !maps.Equal(placed, wantPlaced) {
+				t.Errorf("placements hold %v, want %v", placed, wantPlaced)
+			}
+
+			// A new process on the same store and boards counts the same
+			if got := countryUsers(newService().CountryStats(ctx, ListOptions{})); !maps.Equal(got, want) {
+				t.Errorf("a new service counts %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"backend/internal/models"
+	"backend/internal/ranking"
+	"backend/pkg/store"
+)
+
+// ErrShadowDisabled is returned when shadow comparisons are requested without a shadow board
+var ErrShadowDisabled = errors.New("shadow leaderboard is not enabled")
+
+// shadowBoard is a parallel board fed by a candidate rating strategy. The live
+// board stays authoritative; the shadow only exists to measure divergence.
+type shadowBoard struct {
+	mu       sync.RWMutex
+	store    *store.MemoryStore
+	strategy RatingStrategy
+}
+
+// EnableShadow starts shadow-writing score submissions with strategy.
+// The shadow board starts as a copy of the live board.
+func (s *LeaderboardService) EnableShadow(strategy RatingStrategy) {
+	shadowStore := store.NewMemoryStore()
+	users, _ := s.store.GetAllUsers(context.Background())
+	for _, user := range users {
+		if user.Bot {
+			shadowStore.AddBot(user.Username, user.Rating)
+		} else {
+			shadowStore.AddUser(user.Username, user.Rating)
+		}
+	}
+
+	s.shadow.mu.Lock()
+	defer s.shadow.mu.Unlock()
+	s.shadow.store = shadowStore
+	s.shadow.strategy = strategy
+}
+
+// mirrorShadow copies an absolute rating write (seed, simulator) to the shadow board
+func (s *LeaderboardService) mirrorShadow(username string, rating int, bot bool) {
+	s.shadow.mu.RLock()
+	defer s.shadow.mu.RUnlock()
+
+	if s.shadow.store == nil {
+		return
+	}
+	if bot {
+		s.shadow.store.AddBot(username, rating)
+		return
+	}
+	s.shadow.store.AddUser(username, rating)
+}
+
+// calculateShadow runs the candidate strategy against the shadow board's
+// current rating. Failures are logged and never affect the live write.
+func (s *LeaderboardService) calculateShadow(username string, live *store.User, req models.UpdateScoreRequest) (int, bool) {
+	s.shadow.mu.RLock()
+	defer s.shadow.mu.RUnlock()
+
+	if s.shadow.store == nil {
+		return 0, false
+	}
+
+	current := live.Rating
+	if user, err := s.shadow.store.GetUser(username); err == nil {
+		current = user.Rating
+	}
+
+	rating, err := s.shadow.strategy.Calculate(current, req)
+	if err != nil {
+		log.Printf("Shadow %s strategy rejected update for %s: %v", s.shadow.strategy.Name(), username, err)
+		return 0, false
+	}
+	return rating, true
+}
+
+// CompareShadow reports how the shadow board's ranks diverge from the live
+// board for the top limit live users
+func (s *LeaderboardService) CompareShadow(ctx context.Context, limit int) (*models.ShadowComparisonResponse, error) {
+	s.shadow.mu.RLock()
+	shadowStore, strategy := s.shadow.store, s.shadow.strategy
+	s.shadow.mu.RUnlock()
+
+	if shadowStore == nil {
+		return nil, ErrShadowDisabled
+	}
+
+	liveUsers, err := s.store.GetAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	shadowUsers, err := shadowStore.GetAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	liveRanks := rankMap(liveUsers)
+	shadowRanks := rankMap(shadowUsers)
+
+	shadowRatings := make(map[string]int, len(shadowUsers))
+	for _, user := range shadowUsers {
+		shadowRatings[user.Username] = user.Rating
+	}
+
+	// Divergence summary covers every user; the entry list only the top of the live board
+	var totalShift, maxShift, diverged int
+	for _, user := range liveUsers {
+		shadowRank, ok := shadowRanks[user.Username]
+		if !ok {
+			continue
+		}
+		shift := abs(shadowRank - liveRanks[user.Username])
+		totalShift += shift
+		if shift > maxShift {
+			maxShift = shift
+		}
+		if shift > 0 {
+			diverged++
+		}
+	}
+
+	if limit > len(liveUsers) {
+		limit = len(liveUsers)
+	}
+
+	entries := make([]models.ShadowComparisonEntry, 0, limit)
+	for _, user := range liveUsers[:limit] {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		entry := models.ShadowComparisonEntry{
+			Username:     user.Username,
+			LiveRating:   user.Rating,
+			LiveRank:     liveRanks[user.Username],
+			ShadowRating: shadowRatings[user.Username],
+			ShadowRank:   shadowRanks[user.Username],
+		}
+		entry.RankDelta = entry.ShadowRank - entry.LiveRank
+		entries = append(entries, entry)
+	}
+
+	meanShift := 0.0
+	if len(liveUsers) > 0 {
+		meanShift = float64(totalShift) / float64(len(liveUsers))
+	}
+
+	return &models.ShadowComparisonResponse{
+		Strategy:      strategy.Name(),
+		UsersCompared: int64(len(liveUsers)),
+		UsersDiverged: int64(diverged),
+		MeanRankShift: meanShift,
+		MaxRankShift:  maxShift,
+		Entries:       entries,
+	}, nil
+}
+
+// rankMap assigns shared ranks by rating alone to users in leaderboard order,
+// so the comparison doesn't depend on when each store stamped its keys
+func rankMap(users []*store.User) map[string]int {
+	ranks := make(map[string]int, len(users))
+	var counter ranking.Counter
+	for _, user := range users {
+		ranks[user.Username] = counter.Next(ranking.RatingKey(user.Rating))
+	}
+	return ranks
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/pkg/store"
+)
+
+// DefaultSimulationInterval is how often the simulator updates a user unless configured
+const DefaultSimulationInterval = 5 * time.Second
+
+// simulationTopN is the pool the "top" target picks from
+const simulationTopN = 100
+
+// MaxSimulationBatch bounds the members the simulator updates per cycle
+const MaxSimulationBatch = 1000
+
+// ErrInvalidSimulationConfig is returned for an unknown target or out-of-range interval
+var ErrInvalidSimulationConfig = errors.New("invalid simulation config")
+
+// simulationTargets selects the users the simulator may pick from. users is
+// sorted by rating, highest first.
+var simulationTargets = map[string]func(users []*store.User) []*store.User{
+	"uniform": func(users []*store.User) []*store.User {
+		return users
+	},
+	"top": func(users []*store.User) []*store.User {
+		if len(users) > simulationTopN {
+			return users[:simulationTopN]
+		}
+		return users
+	},
+	"humans": func(users []*store.User) []*store.User {
+		return filterUsers(users, func(u *store.User) bool { return !u.Bot })
+	},
+	"bots": func(users []*store.User) []*store.User {
+		return filterUsers(users, func(u *store.User) bool { return u.Bot })
+	},
+}
+
+func filterUsers(users []*store.User, keep func(*store.User) bool) []*store.User {
+	kept := make([]*store.User, 0, len(users))
+	for _, u := range users {
+		if keep(u) {
+			kept = append(kept, u)
+		}
+	}
+	return kept
+}
+
+// SimulationConfig controls the random update simulator
+type SimulationConfig struct {
+	Enabled   bool
+	Interval  time.Duration
+	Target    string  // uniform, top, humans or bots
+	BatchSize int     // Members updated together per cycle, defaults to 1
+	Jitter    float64 // Each cycle's interval varies randomly by up to this fraction, 0 to 0.5
+}
+
+// simulationState tracks the random update simulator and any bulk jobs
+// that require it to stand still
+type simulationState struct {
+	mu             sync.Mutex
+	running        bool
+	enabled        bool
+	interval       time.Duration
+	target         string
+	batchSize      int
+	jitter         float64
+	changed        chan struct{}  // wakes the loop when the interval changes
+	bulkJobs       map[string]int // job name -> active count
+	updatesApplied int64
+	batchesApplied int64
+	lastBatch      int // Members touched by the last cycle
+	lastUpdateAt   time.Time
+}
+
+func newSimulationState() *simulationState {
+	return &simulationState{
+		enabled:   true,
+		interval:  DefaultSimulationInterval,
+		target:    "uniform",
+		batchSize: 1,
+		changed:   make(chan struct{}, 1),
+		bulkJobs:  make(map[string]int),
+	}
+}
+
+// Validate checks the target and interval
+func (cfg SimulationConfig) Validate() error {
+	if _, ok := simulationTargets[cfg.Target]; !ok {
+		return fmt.Errorf("%w: unknown target %q", ErrInvalidSimulationConfig, cfg.Target)
+	}
+	if cfg.Interval <= 0 {
+		return fmt.Errorf("%w: interval must be positive", ErrInvalidSimulationConfig)
+	}
+	if cfg.BatchSize < 0 || cfg.BatchSize > MaxSimulationBatch {
+		return fmt.Errorf("%w: batch size must be between 1 and %d", ErrInvalidSimulationConfig, MaxSimulationBatch)
+	}
+	if cfg.Jitter < 0 || cfg.Jitter > 0.5 {
+		return fmt.Errorf("%w: jitter must be between 0 and 0.5", ErrInvalidSimulationConfig)
+	}
+	return nil
+}
+
+// ConfigureSimulation applies cfg to the simulator, taking effect on the next
+// tick of a running loop
+func (s *LeaderboardService) ConfigureSimulation(cfg SimulationConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	sim := s.simulation
+	sim.mu.Lock()
+	sim.enabled = cfg.Enabled
+	sim.interval = cfg.Interval
+	sim.target = cfg.Target
+	sim.batchSize = max(cfg.BatchSize, 1)
+	sim.jitter = cfg.Jitter
+	sim.mu.Unlock()
+
+	select {
+	case sim.changed <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// SimulationConfig returns the current simulator settings
+func (s *LeaderboardService) SimulationConfig() SimulationConfig {
+	sim := s.simulation
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	return SimulationConfig{
+		Enabled:   sim.enabled,
+		Interval:  sim.interval,
+		Target:    sim.target,
+		BatchSize: sim.batchSize,
+		Jitter:    sim.jitter,
+	}
+}
+
+// pickSimulationBatch draws up to size random updates for users matching the
+// target. A user picked twice keeps the later rating. The usernames are
+// returned in the order they were first picked.
+func (s *LeaderboardService) pickSimulationBatch(ctx context.Context, target string, size int) ([]string, map[string]int) {
+	users, err := s.store.GetAllUsers(ctx)
+	if err != nil {
+		return nil, nil
+	}
+	candidates := simulationTargets[target](users)
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	order := make([]string, 0, size)
+	ratings := make(map[string]int, size)
+	for range size {
+		user := candidates[s.random.simulatorPick(len(candidates))]
+		if _, picked := ratings[user.Username]; !picked {
+			order = append(order, user.Username)
+		}
+		ratings[user.Username] = s.random.simulatorRating()
+	}
+	return order, ratings
+}
+
+// applySimulatedBatch stores a batch of simulated ratings in one store call
+// and publishes their score_updated events. Frozen and suspended users are
+// left alone. It returns how many members were updated.
+func (s *LeaderboardService) applySimulatedBatch(order []string, ratings map[string]int) int {
+	now := s.clock.Now()
+	usernames := order[:0:0]
+	for _, username := range order {
+		if s.anomalies != nil && s.anomalies.IsFrozen(username) {
+			continue
+		}
+		if s.moderation.isFrozen(username, now) {
+			continue
+		}
+		usernames = append(usernames, username)
+	}
+
+	changes := s.writeRatingsAcrossBoards(context.Background(), func() []store.RatingChange {
+		return s.store.UpdateRatings(usernames, ratings)
+	})
+	for _, change := range changes {
+		s.mirrorShadow(change.Username, change.Rating, change.Bot)
+		s.events.Publish(events.Event{
+			Type:           events.TypeScoreUpdated,
+			Username:       change.Username,
+			Rating:         change.Rating,
+			PreviousRating: change.Previous,
+			Bot:            change.Bot,
+			Source:         SourceSimulator,
+		})
+	}
+
+	if len(changes) == 1 {
+		log.Printf("Updated %s: %d -> %d", changes[0].Username, changes[0].Previous, changes[0].Rating)
+	} else if len(changes) > 1 {
+		log.Printf("🎲 Applied %d simulated updates", len(changes))
+	}
+	return len(changes)
+}
+
+// beginBulkJob pauses background mutators until the returned func is called
+func (s *LeaderboardService) beginBulkJob(name string) func() {
+	sim := s.simulation
+
+	sim.mu.Lock()
+	sim.bulkJobs[name]++
+	sim.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			sim.mu.Lock()
+			defer sim.mu.Unlock()
+
+			sim.bulkJobs[name]--
+			if sim.bulkJobs[name] <= 0 {
+				delete(sim.bulkJobs, name)
+			}
+		})
+	}
+}
+
+// simulationPaused reports whether a bulk job is currently running or
+// maintenance mode is on
+func (s *LeaderboardService) simulationPaused() bool {
+	if s.writesPaused() {
+		return true
+	}
+	s.simulation.mu.Lock()
+	defer s.simulation.mu.Unlock()
+	return len(s.simulation.bulkJobs) > 0
+}
+
+func (s *LeaderboardService) setSimulationRunning(running bool) {
+	s.simulation.mu.Lock()
+	defer s.simulation.mu.Unlock()
+	s.simulation.running = running
+}
+
+// recordSimulatedBatch counts a cycle that updated touched members
+func (s *LeaderboardService) recordSimulatedBatch(touched int) {
+	s.simulation.mu.Lock()
+	defer s.simulation.mu.Unlock()
+	s.simulation.batchesApplied++
+	s.simulation.lastBatch = touched
+	if touched > 0 {
+		s.simulation.updatesApplied += int64(touched)
+		s.simulation.lastUpdateAt = s.clock.Now()
+	}
+}
+
+// GetSimulationStatus returns the current state of the random update simulator
+func (s *LeaderboardService) GetSimulationStatus() *models.SimulationStatusResponse {
+	sim := s.simulation
+	maintenance := s.writesPaused()
+
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+
+	pausedBy := make([]string, 0, len(sim.bulkJobs)+1)
+	for name := range sim.bulkJobs {
+		pausedBy = append(pausedBy, name)
+	}
+	if maintenance {
+		pausedBy = append(pausedBy, "maintenance")
+	}
+	sort.Strings(pausedBy)
+
+	status := &models.SimulationStatusResponse{
+		Running:         sim.running && sim.enabled,
+		Enabled:         sim.enabled,
+		Target:          sim.target,
+		Paused:          len(pausedBy) > 0,
+		PausedBy:        pausedBy,
+		IntervalSeconds: sim.interval.Seconds(),
+		BatchSize:       sim.batchSize,
+		Jitter:          sim.jitter,
+		UpdatesApplied:  sim.updatesApplied,
+		BatchesApplied:  sim.batchesApplied,
+		LastBatch:       sim.lastBatch,
+	}
+	if !sim.lastUpdateAt.IsZero() {
+		lastUpdateAt := sim.lastUpdateAt
+		status.LastUpdateAt = &lastUpdateAt
+	}
+
+	return status
+}
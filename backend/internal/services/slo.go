@@ -0,0 +1,312 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+)
+
+// Endpoint groups latency objectives are set for. Routes outside them, such
+// as exports, WebSockets and feeds, aren't measured.
+const (
+	GroupLeaderboard  = "leaderboard"   // Leaderboard pages and board metadata
+	GroupUserRank     = "user_rank"     // Single user lookups
+	GroupScoreUpdates = "score_updates" // Score submissions from clients and platforms
+	GroupSearch       = "search"        // Search and listing users by name
+	GroupStats        = "stats"         // Statistics, counts and inflation
+	GroupHistory      = "history"       // Score history
+	GroupAdmin        = "admin"         // The admin API
+)
+
+// LatencyGroups lists the endpoint groups an objective can name
+var LatencyGroups = []string{GroupLeaderboard, GroupUserRank, GroupScoreUpdates, GroupSearch, GroupStats, GroupHistory, GroupAdmin}
+
+const (
+	// DefaultSLOAlertBurnRate spends a 30-day error budget in about two days
+	DefaultSLOAlertBurnRate = 14.4
+	// sloMinuteBuckets is how far back burn rates are measured, in minutes
+	sloMinuteBuckets = 60
+	// sloMinRequests is how many requests the short window needs before an
+	// alert fires, so one slow request on a quiet server doesn't page anyone
+	sloMinRequests = 20
+	// sloAlertTimeout bounds an alert webhook post
+	sloAlertTimeout = 10 * time.Second
+)
+
+// LatencySLO is an objective for one endpoint group: Percentile percent of
+// its requests are answered within Threshold, and without a server error
+type LatencySLO struct {
+	Group      string
+	Percentile float64 // e.g. 99 for p99
+	Threshold  time.Duration
+}
+
+// Objective formats the SLO without its group, e.g. "p99<50ms"
+func (o LatencySLO) Objective() string {
+	return "p" + strconv.FormatFloat(o.Percentile, 'f', -1, 64) + "<" + o.Threshold.String()
+}
+
+// budget is the fraction of requests allowed to breach the objective
+func (o LatencySLO) budget() float64 {
+	return (100 - o.Percentile) / 100
+}
+
+// Validate reports an unknown group or an objective that can't be met or
+// can't be missed
+func (o LatencySLO) Validate() error {
+	if !slices.Contains(LatencyGroups, o.Group) {
+		return fmt.Errorf("latency SLO: unknown endpoint group %q (available: %s)", o.Group, strings.Join(LatencyGroups, ", "))
+	}
+	if o.Percentile <= 0 || o.Percentile >= 100 {
+		return fmt.Errorf("latency SLO %s: percentile must be between 0 and 100", o.Group)
+	}
+	if o.Threshold <= 0 {
+		return fmt.Errorf("latency SLO %s: threshold must be positive", o.Group)
+	}
+	return nil
+}
+
+// ParseLatencySLOs reads objectives such as "leaderboard:p99<50ms,stats:p95<200ms"
+func ParseLatencySLOs(spec string) ([]LatencySLO, error) {
+	var objectives []LatencySLO
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		group, rule, _ := strings.Cut(item, ":")
+		percentile, threshold, ok := strings.Cut(strings.TrimPrefix(rule, "p"), "<")
+		if !ok || !strings.HasPrefix(rule, "p") {
+			return nil, fmt.Errorf("latency SLO %q: expected group:p99<50ms", item)
+		}
+		objective := LatencySLO{Group: group}
+		var err error
+		if objective.Percentile, err = strconv.ParseFloat(percentile, 64); err != nil {
+			return nil, fmt.Errorf("latency SLO %q: bad percentile: %w", item, err)
+		}
+		if objective.Threshold, err = time.ParseDuration(threshold); err != nil {
+			return nil, fmt.Errorf("latency SLO %q: bad threshold: %w", item, err)
+		}
+		if err := objective.Validate(); err != nil {
+			return nil, err
+		}
+		objectives = append(objectives, objective)
+	}
+	return objectives, nil
+}
+
+// SLOConfig sets latency objectives and when breaching them alerts
+type SLOConfig struct {
+	Objectives    []LatencySLO
+	AlertBurnRate float64 // Alert when the 5m and 1h burn rates both reach this, defaults to DefaultSLOAlertBurnRate
+	AlertURL      string  // POST alerts here when set, as well as recording them as health incidents
+}
+
+// sloMinute counts one minute of requests against an objective
+type sloMinute struct {
+	minute   int64 // Unix minute the counts are for
+	requests int64
+	breaches int64 // Slower than the threshold or failed with a server error
+}
+
+// sloObjective tracks one objective's recent requests and alert state
+type sloObjective struct {
+	LatencySLO
+	minutes       [sloMinuteBuckets]sloMinute
+	alertingSince *time.Time
+}
+
+// sloTracker measures endpoint groups against their objectives
+type sloTracker struct {
+	config     SLOConfig
+	client     *http.Client
+	mu         sync.Mutex
+	objectives map[string][]*sloObjective // By group
+}
+
+// EnableSLOs starts measuring the endpoint groups config sets objectives for
+func (s *LeaderboardService) EnableSLOs(config SLOConfig) {
+	if config.AlertBurnRate <= 0 {
+		config.AlertBurnRate = DefaultSLOAlertBurnRate
+	}
+	t := &sloTracker{
+		config:     config,
+		client:     &http.Client{Timeout: sloAlertTimeout},
+		objectives: make(map[string][]*sloObjective),
+	}
+	for _, objective := range config.Objectives {
+		t.objectives[objective.Group] = append(t.objectives[objective.Group], &sloObjective{LatencySLO: objective})
+	}
+	s.slos = t
+}
+
+// ObserveLatency records a request to group that took elapsed and answered
+// status, and alerts when an objective starts or stops burning its error
+// budget too fast
+func (s *LeaderboardService) ObserveLatency(group string, elapsed time.Duration, status int) {
+	t := s.slos
+	if t == nil || len(t.objectives[group]) == 0 {
+		return
+	}
+	now := s.clock.Now().UTC()
+	minute := now.Unix() / 60
+
+	var alerts []models.SLOAlert
+	t.mu.Lock()
+	for _, objective := range t.objectives[group] {
+		bucket := &objective.minutes[minute%sloMinuteBuckets]
+		if bucket.minute != minute {
+			*bucket = sloMinute{minute: minute}
+		}
+		bucket.requests++
+		if elapsed > objective.Threshold || status >= http.StatusInternalServerError {
+			bucket.breaches++
+		}
+		if alert, ok := t.evaluate(objective, now); ok {
+			alerts = append(alerts, alert)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, alert := range alerts {
+		s.raiseSLOAlert(alert)
+	}
+}
+
+// evaluate checks whether objective's alert starts or stops, and returns
+// the alert when it does. Must be called with the lock held.
+func (t *sloTracker) evaluate(objective *sloObjective, now time.Time) (models.SLOAlert, bool) {
+	status := objective.status(now)
+	threshold := t.config.AlertBurnRate
+	alert := models.SLOAlert{
+		Group:      objective.Group,
+		Objective:  objective.Objective(),
+		BurnRate5m: status.BurnRate5m,
+		BurnRate1h: status.BurnRate1h,
+		Threshold:  threshold,
+		At:         now,
+	}
+	switch {
+	case objective.alertingSince == nil && status.Requests5m >= sloMinRequests &&
+		status.BurnRate5m >= threshold && status.BurnRate1h >= threshold:
+		since := now
+		objective.alertingSince = &since
+		alert.State = models.SLOFiring
+		return alert, true
+	case objective.alertingSince != nil && status.BurnRate5m < threshold:
+		alert.State = models.SLOResolved
+		alert.DurationSeconds = now.Sub(*objective.alertingSince).Seconds()
+		objective.alertingSince = nil
+		return alert, true
+	}
+	return models.SLOAlert{}, false
+}
+
+// status sums the objective's requests over the burn rate windows
+func (o *sloObjective) status(now time.Time) models.SLOStatus {
+	minute := now.Unix() / 60
+	status := models.SLOStatus{
+		Group:         o.Group,
+		Objective:     o.Objective(),
+		Percentile:    o.Percentile,
+		ThresholdMs:   float64(o.Threshold) / float64(time.Millisecond),
+		AlertingSince: o.alertingSince,
+	}
+	for _, bucket := range o.minutes {
+		age := minute - bucket.minute
+		if bucket.requests == 0 || age < 0 || age >= sloMinuteBuckets {
+			continue
+		}
+		if age < 5 {
+			status.Requests5m += bucket.requests
+			status.Breaches5m += bucket.breaches
+		}
+		status.Requests1h += bucket.requests
+		status.Breaches1h += bucket.breaches
+	}
+	status.BurnRate5m = burnRate(status.Breaches5m, status.Requests5m, o.budget())
+	status.BurnRate1h = burnRate(status.Breaches1h, status.Requests1h, o.budget())
+	status.Alerting = o.alertingSince != nil
+	return status
+}
+
+// burnRate is how many times faster than sustainable breaches spend the
+// error budget: 1 spends exactly the budget, 0 when nothing was served
+func burnRate(breaches, requests int64, budget float64) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return float64(breaches) / float64(requests) / budget
+}
+
+// SLOStatuses reports every objective's recent requests and burn rates, in
+// the order they were configured, or nil if none are
+func (s *LeaderboardService) SLOStatuses() []models.SLOStatus {
+	t := s.slos
+	if t == nil {
+		return nil
+	}
+	now := s.clock.Now().UTC()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	statuses := make([]models.SLOStatus, 0, len(t.config.Objectives))
+	seen := make(map[string]int)
+	for _, configured := range t.config.Objectives {
+		// A group may have several objectives, kept in config order
+		objective := t.objectives[configured.Group][seen[configured.Group]]
+		seen[configured.Group]++
+		statuses = append(statuses, objective.status(now))
+	}
+	return statuses
+}
+
+// raiseSLOAlert records alert as a health incident and posts it to the
+// alert webhook, if one is configured, in the background
+func (s *LeaderboardService) raiseSLOAlert(alert models.SLOAlert) {
+	component := "slo:" + alert.Group
+	if alert.State == models.SLOFiring {
+		detail := fmt.Sprintf("%s burning error budget at %.1fx (5m) and %.1fx (1h), alerting at %.1fx",
+			alert.Objective, alert.BurnRate5m, alert.BurnRate1h, alert.Threshold)
+		log.Printf("🔥 SLO %s: %s", alert.Group, detail)
+		s.RecordIncident(component, IncidentAlert, detail)
+	} else {
+		detail := fmt.Sprintf("%s back under %.1fx burn rate after %s",
+			alert.Objective, alert.Threshold, time.Duration(alert.DurationSeconds*float64(time.Second)).Round(time.Second))
+		log.Printf("SLO %s: %s", alert.Group, detail)
+		s.RecordIncident(component, IncidentRecovered, detail)
+	}
+
+	t := s.slos
+	if t.config.AlertURL == "" {
+		return
+	}
+	body, err := json.Marshal(alert)
+	if err != nil {
+		log.Printf("Failed to encode SLO alert: %v", err)
+		return
+	}
+	go func() {
+		resp, err := t.client.Post(t.config.AlertURL, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				err = fmt.Errorf("alert webhook answered %s", resp.Status)
+			}
+		}
+		if err != nil {
+			log.Printf("Failed to post SLO alert for %s: %v", alert.Group, err)
+		}
+		s.ReportHealth("slo_alert_webhook", err)
+	}()
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/clock"
+)
+
+func TestParseLatencySLOs(t *testing.T) {
+	cases := []struct {
+		spec string
+		want string // The objectives as group:objective, or the error's start
+	}{
+		{spec: "", want: "[]"},
+		{spec: "leaderboard:p99<50ms", want: "[leaderboard:p99<50ms]"},
+		{spec: " leaderboard:p99.9<50ms , stats:p95<1.5s,", want: "[leaderboard:p99.9<50ms stats:p95<1.5s]"},
+		{spec: "search:p90<100ms,search:p99<1s", want: "[search:p90<100ms search:p99<1s]"},
+		{spec: "leaderboard:99<50ms", want: `latency SLO "leaderboard:99<50ms": expected group:p99<50ms`},
+		{spec: "leaderboard:p99", want: `latency SLO "leaderboard:p99": expected group:p99<50ms`},
+		{spec: "leaderboard", want: `latency SLO "leaderboard": expected group:p99<50ms`},
+		{spec: "leaderboard:pxx<50ms", want: `latency SLO "leaderboard:pxx<50ms": bad percentile`},
+		{spec: "leaderboard:p99<50", want: `latency SLO "leaderboard:p99<50": bad threshold`},
+		{spec: "exports:p99<50ms", want: `latency SLO: unknown endpoint group "exports"`},
+		{spec: "stats:p100<50ms", want: "latency SLO stats: percentile must be between 0 and 100"},
+		{spec: "stats:p0<50ms", want: "latency SLO stats: percentile must be between 0 and 100"},
+		{spec: "stats:p99<0s", want: "latency SLO stats: threshold must be positive"},
+	}
+	for _, c := range cases {
+		objectives, err := ParseLatencySLOs(c.spec)
+		var got string
+		if err != nil {
+			got = err.Error()
+		} else {
+			formatted := make([]string, len(objectives))
+			for i, o := range objectives {
+				formatted[i] = o.Group + ":" + o.Objective()
+			}
+			got = fmt.Sprint(formatted)
+		}
+		if !strings.HasPrefix(got, c.want) {
+			t.Errorf("ParseLatencySLOs(%q) = %s, want %s", c.spec, got, c.want)
+		}
+	}
+}
+
+func TestBurnRate(t *testing.T) {
+	cases := []struct {
+		breaches, requests int64
+		budget             float64
+		want               float64
+	}{
+		{breaches: 0, requests: 0, budget: 0.01, want: 0},
+		{breaches: 0, requests: 100, budget: 0.01, want: 0},
+		{breaches: 1, requests: 100, budget: 0.01, want: 1},
+		{breaches: 50, requests: 100, budget: 0.01, want: 50},
+		{breaches: 100, requests: 100, budget: 0.05, want: 20},
+		{breaches: 1, requests: 1000, budget: 0.001, want: 1},
+	}
+	for _, c := range cases {
+		if got := burnRate(c.breaches, c.requests, c.budget); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("burnRate(%d, %d, %g) = %g, want %g", c.breaches, c.requests, c.budget, got, c.want)
+		}
+	}
+}
+
+func TestSLOWindowsAndAlerts(t *testing.T) {
+	s := NewLeaderboardService(playersStore(t, 1))
+	fake := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	s.SetClock(fake)
+	s.EnableSLOs(SLOConfig{
+		Objectives:    []LatencySLO{{Group: GroupSearch, Percentile: 90, Threshold: 100 * time.Millisecond}},
+		AlertBurnRate: 10,
+	})
+	observe := func(n int, elapsed time.Duration, status int) {
+		for range n {
+			s.ObserveLatency(GroupSearch, elapsed, status)
+		}
+	}
+
+	steps := []struct {
+		name                   string
+		run                    func()
+		requests5m, requests1h int64
+		burn5m, burn1h         float64
+		alerting               bool
+	}{
+		{
+			name:       "within the objective",
+			run:        func() { observe(10, 50*time.Millisecond, http.StatusOK) },
+			requests5m: 10, requests1h: 10,
+		},
+		{
+			// 10 breaches in 20 requests burns a 10% budget at 5x, under the threshold
+			name: "slow and failed requests breach",
+			run: func() {
+				observe(5, time.Second, http.StatusOK)
+				observe(5, time.Millisecond, http.StatusServiceUnavailable)
+			},
+			requests5m: 20, requests1h: 20, burn5m: 5, burn1h: 5,
+		},
+		{
+			// The 5m burn rate reaches the threshold but the 1h one doesn't
+			name:       "the 5m window ages out first",
+			run:        func() { fake.Advance(5 * time.Minute); observe(10, time.Second, http.StatusOK) },
+			requests5m: 10, requests1h: 30, burn5m: 10, burn1h: 20.0 / 3,
+		},
+		{
+			// 20 breaches out of the last 20 burns at 10x in both windows once the rest age out
+			name:       "fires with enough requests over the threshold in both windows",
+			run:        func() { fake.Advance(time.Hour); observe(20, time.Second, http.StatusOK) },
+			requests5m: 20, requests1h: 20, burn5m: 10, burn1h: 10, alerting: true,
+		},
+		{
+			name:       "resolves once the 5m burn rate drops",
+			run:        func() { fake.Advance(5 * time.Minute); observe(100, time.Millisecond, http.StatusOK) },
+			requests5m: 100, requests1h: 120, burn5m: 0, burn1h: 20.0 / 12,
+		},
+	}
+	for _, step := range steps {
+		step.run()
+		status := s.SLOStatuses()[0]
+		if status.Requests5m != step.requests5m || status.Requests1h != step.requests1h ||
+			math.Abs(status.BurnRate5m-step.burn5m) > 1e-9 || math.Abs(status.BurnRate1h-step.burn1h) > 1e-9 ||
+			status.Alerting != step.alerting {
+			t.Errorf("%s: %d/%d requests burning %g/%g alerting %t, want %d/%d burning %g/%g alerting %t", step.name,
+				status.Requests5m, status.Requests1h, status.BurnRate5m, status.BurnRate1h, status.Alerting,
+				step.requests5m, step.requests1h, step.burn5m, step.burn1h, step.alerting)
+		}
+	}
+}
+
+func TestSLOAlertNeedsEnoughRequests(t *testing.T) {
+	s := NewLeaderboardService(playersStore(t, 1))
+	s.SetClock(clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)))
+	s.EnableSLOs(SLOConfig{Objectives: []LatencySLO{{Group: GroupStats, Percentile: 99, Threshold: time.Millisecond}}})
+
+	for range sloMinRequests - 1 {
+		s.ObserveLatency(GroupStats, time.Second, http.StatusOK)
+	}
+	if s.SLOStatuses()[0].Alerting {
+		t.Errorf("alerting after %d requests, want at least %d first", sloMinRequests-1, sloMinRequests)
+	}
+	s.ObserveLatency(GroupStats, time.Second, http.StatusOK)
+	if !s.SLOStatuses()[0].Alerting {
+		t.Errorf("not alerting after %d breaching requests", sloMinRequests)
+	}
+	// Other groups aren't measured
+	s.ObserveLatency(GroupSearch, time.Second, http.StatusOK)
+	if got := s.SLOStatuses(); len(got) != 1 || got[0].Requests1h != sloMinRequests {
+		t.Errorf("statuses %+v, want only the stats objective", got)
+	}
+}
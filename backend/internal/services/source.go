@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"backend/internal/events"
+)
+
+// Sources of leaderboard mutations, so synthetic traffic can be told apart
+// from real usage
+const (
+	SourceAPI       = "api"       // Clients of the public API and platform integrations
+	SourceSimulator = "simulator" // The random update simulator
+	SourceImport    = "import"    // Seeding and bulk loads
+	SourceDecay     = "decay"     // Scheduled rating decay
+	SourceAdmin     = "admin"     // Moderator and admin actions
+)
+
+// Sources lists every mutation source
+var Sources = []string{SourceAPI, SourceSimulator, SourceImport, SourceDecay, SourceAdmin}
+
+type sourceKey struct{}
+
+// WithSource tags mutations made with ctx as coming from source
+func WithSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// SourceFrom returns the mutation source carried by ctx, defaulting to api
+func SourceFrom(ctx context.Context) string {
+	if source, ok := ctx.Value(sourceKey{}).(string); ok {
+		return source
+	}
+	return SourceAPI
+}
+
+// rateWindowSeconds is how far back update rates can be measured
+const rateWindowSeconds = 300
+
+// sourceCounters counts score updates by source since startup, plus
+// per-second totals over the last few minutes for update rates
+type sourceCounters struct {
+	mu      sync.Mutex
+	counts  map[string]int64
+	buckets [rateWindowSeconds]int64
+	seconds [rateWindowSeconds]int64 // Unix second each bucket counts
+}
+
+func newSourceCounters() *sourceCounters {
+	return &sourceCounters{counts: make(map[string]int64)}
+}
+
+// Handle counts score_updated events; it is subscribed to the event bus
+func (c *sourceCounters) Handle(e events.Event) {
+	if e.Type != events.TypeScoreUpdated || e.Source == "" {
+		return
+	}
+	now := time.Now().Unix()
+	i := now % rateWindowSeconds
+
+	c.mu.Lock()
+	c.counts[e.Source]++
+	if c.seconds[i] != now {
+		c.seconds[i] = now
+		c.buckets[i] = 0
+	}
+	c.buckets[i]++
+	c.mu.Unlock()
+}
+
+// rate returns updates per second over the last window, which is capped at
+// rateWindowSeconds
+func (c *sourceCounters) rate(window time.Duration) float64 {
+	seconds := min(int64(window.Seconds()), rateWindowSeconds)
+	if seconds <= 0 {
+		return 0
+	}
+	since := time.Now().Unix() - seconds
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var total int64
+	for i, second := range c.seconds {
+		if second > since {
+			total += c.buckets[i]
+		}
+	}
+	return float64(total) / float64(seconds)
+}
+
+// snapshot returns the counts, with every source present
+func (c *sourceCounters) snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[string]int64, len(Sources))
+	for _, source := range Sources {
+		counts[source] = c.counts[source]
+	}
+	return counts
+}
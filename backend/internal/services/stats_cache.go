@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+)
+
+// DefaultStatsCacheTTL is how long computed stats are served before recomputing
+const DefaultStatsCacheTTL = time.Second
+
+// statsCache keeps the last computed stats per view so frequent polling
+// doesn't rescan the board. Misses are computed under the lock, so a burst
+// of pollers triggers a single scan.
+type statsCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration                  // 0 disables caching
+	entries map[bool]*models.StatsResponse // keyed by ExcludeBots
+	hits    int64
+	misses  int64
+}
+
+func newStatsCache() *statsCache {
+	return &statsCache{
+		ttl:     DefaultStatsCacheTTL,
+		entries: make(map[bool]*models.StatsResponse),
+	}
+}
+
+// SetStatsCacheTTL changes how long stats are cached; 0 disables the cache
+func (s *LeaderboardService) SetStatsCacheTTL(ttl time.Duration) {
+	c := s.statsCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ttl = max(ttl, 0)
+	clear(c.entries)
+}
+
+// GetStats returns board statistics, served from cache while younger than
+// the cache TTL; ComputedAt tells callers how stale they are
+func (s *LeaderboardService) GetStats(ctx context.Context, opts ListOptions) (*models.StatsResponse, error) {
+	c := s.statsCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.entries[opts.ExcludeBots]; ok && s.clock.Now().Sub(cached.ComputedAt) < c.ttl {
+		c.hits++
+		stats := *cached
+		return &stats, nil
+	}
+	c.misses++
+
+	stats, err := s.computeStats(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if c.ttl > 0 {
+		cached := *stats
+		c.entries[opts.ExcludeBots] = &cached
+	}
+	return stats, nil
+}
+
+// ComputeStats recomputes statistics, bypassing and refreshing the cache
+func (s *LeaderboardService) ComputeStats(ctx context.Context, opts ListOptions) (*models.StatsResponse, error) {
+	c := s.statsCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats, err := s.computeStats(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if c.ttl > 0 {
+		cached := *stats
+		c.entries[opts.ExcludeBots] = &cached
+	}
+	return stats, nil
+}
+
+// cacheStats reports how often GetStats was served from cache
+func (c *statsCache) cacheStats() models.CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := models.CacheStats{Name: "stats", Hits: c.hits, Misses: c.misses}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total)
+	}
+	return stats
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// errSubmissionBusy is returned by a worker when another worker holds a
+	// live claim on the same submission
+	errSubmissionBusy = errors.New("submission is being applied by another worker")
+	// errLedgerUnavailable is returned when the ledger can't be consulted, so
+	// the submission wasn't applied
+	errLedgerUnavailable = errors.New("submission ledger unavailable")
+)
+
+// Attempts at recording an applied submission in the ledger, the first
+// retry after ledgerRetryDelay and each later one twice as long after
+const (
+	ledgerRecordAttempts = 3
+	ledgerRetryDelay     = 100 * time.Millisecond
+)
+
+// retryLater reports whether a queued submission was left unapplied and
+// should stay queued for another attempt
+func retryLater(err error) bool {
+	return errors.Is(err, errSubmissionBusy) || errors.Is(err, errLedgerUnavailable)
+}
+
+// SubmissionLedger remembers which queued submissions have been applied so a
+// redelivered stream entry or a retried worker doesn't apply a delta twice.
+// Claim behaves like SETNX: only the first caller for an ID applies it.
+type SubmissionLedger interface {
+	// Claim reserves id for lease. If it was already applied the stored
+	// result is returned with claimed=false; if another worker holds a live
+	// claim both are nil/false.
+	Claim(ctx context.Context, id string, lease time.Duration) (result *models.UpdateScoreResponse, claimed bool, err error)
+	// Complete records the result of a claimed submission
+	Complete(ctx context.Context, id string, result *models.UpdateScoreResponse) error
+	// Release drops a claim whose update failed so it can be retried
+	Release(ctx context.Context, id string) error
+}
+
+type submissionEntry struct {
+	result    *models.UpdateScoreResponse // nil while claimed
+	expiresAt time.Time
+}
+
+// memorySubmissionLedger is a process-local SubmissionLedger
+type memorySubmissionLedger struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[string]*submissionEntry
+	lastSweep time.Time
+}
+
+// NewMemorySubmissionLedger creates an in-memory ledger that forgets applied submissions after ttl
+func NewMemorySubmissionLedger(ttl time.Duration) SubmissionLedger {
+	return &memorySubmissionLedger{
+		ttl:       ttl,
+		entries:   make(map[string]*submissionEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *memorySubmissionLedger) Claim(ctx context.Context, id string, lease time.Duration) (*models.UpdateScoreResponse, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	if entry, ok := l.entries[id]; ok && now.Before(entry.expiresAt) {
+		return entry.result, false, nil
+	}
+	l.entries[id] = &submissionEntry{expiresAt: now.Add(lease)}
+	return nil, true, nil
+}
+
+func (l *memorySubmissionLedger) Complete(ctx context.Context, id string, result *models.UpdateScoreResponse) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[id] = &submissionEntry{result: result, expiresAt: time.Now().Add(l.ttl)}
+	return nil
+}
+
+func (l *memorySubmissionLedger) Release(ctx context.Context, id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, id)
+	return nil
+}
+
+// sweep drops expired entries at most once per TTL
+func (l *memorySubmissionLedger) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.ttl {
+		return
+	}
+	l.lastSweep = now
+
+	for id, entry := range l.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(l.entries, id)
+		}
+	}
+}
+
+// redisSubmissionLedger keeps the ledger in Redis so every replica sees it.
+// A claim is an empty value set with NX for the lease; a completed entry
+// holds the result as JSON for ttl.
+type redisSubmissionLedger struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisSubmissionLedger creates a ledger under "<prefix>applied:<id>" keys
+func NewRedisSubmissionLedger(client redis.UniversalClient, prefix string, ttl time.Duration) SubmissionLedger {
+	return &redisSubmissionLedger{client: client, prefix: prefix + "applied:", ttl: ttl}
+}
+
+func (l *redisSubmissionLedger) Claim(ctx context.Context, id string, lease time.Duration) (*models.UpdateScoreResponse, bool, error) {
+	claimed, err := l.client.SetNX(ctx, l.prefix+id, "", lease).Result()
+	if err != nil || claimed {
+		return nil, claimed, err
+	}
+
+	data, err := l.client.Get(ctx, l.prefix+id).Bytes()
+	if errors.Is(err, redis.Nil) || (err == nil && len(data) == 0) {
+		// Held by another worker, or its lease lapsed between the two calls
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var result models.UpdateScoreResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, false, err
+	}
+	return &result, false, nil
+}
+
+func (l *redisSubmissionLedger) Complete(ctx context.Context, id string, result *models.UpdateScoreResponse) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return l.client.Set(ctx, l.prefix+id, data, l.ttl).Err()
+}
+
+func (l *redisSubmissionLedger) Release(ctx context.Context, id string) error {
+	return l.client.Del(ctx, l.prefix+id).Err()
+}
+
+// submissionDedup skips queued submissions the ledger shows were applied,
+// and counts how often it had to step in.
+//
+// Delivery is at least once, deduplicated: the update and the ledger
+// record are separate writes, to the store and to the ledger, so they can't
+// be made atomic. If the record is lost, because the ledger stays
+// unreachable through every attempt or the process dies between the two,
+// a redelivery after the claim's lease lapses applies the submission
+// again. Such submissions are counted as unrecorded.
+type submissionDedup struct {
+	ledger SubmissionLedger
+	lease  time.Duration
+	retry  time.Duration // First pause between attempts at recording, ledgerRetryDelay if zero
+
+	mu         sync.Mutex
+	claimed    int64
+	duplicates int64
+	deferred   int64
+	released   int64
+	errors     int64
+	unrecorded int64
+}
+
+// apply runs update unless the ledger shows the submission was already
+// applied, in which case the earlier result is returned instead
+func (d *submissionDedup) apply(item queuedScore, update applyFunc) (*models.UpdateScoreResponse, error) {
+	ctx := context.Background()
+	prior, claimed, err := d.ledger.Claim(ctx, item.ID, d.lease)
+	if err != nil {
+		d.count(&d.errors)
+		return nil, fmt.Errorf("%w: %v", errLedgerUnavailable, err)
+	}
+	if !claimed {
+		if prior == nil {
+			d.count(&d.deferred)
+			return nil, errSubmissionBusy
+		}
+		d.count(&d.duplicates)
+		return prior, nil
+	}
+	d.count(&d.claimed)
+
+	result, err := update(item)
+	if err != nil {
+		d.count(&d.released)
+		if releaseErr := d.ledger.Release(ctx, item.ID); releaseErr != nil {
+			d.count(&d.errors)
+		}
+		return nil, err
+	}
+	d.record(ctx, item, result)
+	return result, nil
+}
+
+// record stores an applied submission's result, retrying a ledger that is
+// briefly unreachable. The update stands either way; without the record, a
+// redelivery is only caught until the claim's lease lapses.
+func (d *submissionDedup) record(ctx context.Context, item queuedScore, result *models.UpdateScoreResponse) {
+	delay := d.retry
+	if delay <= 0 {
+		delay = ledgerRetryDelay
+	}
+	var err error
+	for attempt := range ledgerRecordAttempts {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err = d.ledger.Complete(ctx, item.ID, result); err == nil {
+			return
+		}
+		d.count(&d.errors)
+	}
+	d.count(&d.unrecorded)
+	log.Printf("⚠️  Applied submission %s of %s but couldn't record it, so a redelivery may apply it again: %v", item.ID, item.Username, err)
+}
+
+func (d *submissionDedup) count(counter *int64) {
+	d.mu.Lock()
+	*counter++
+	d.mu.Unlock()
+}
+
+func (d *submissionDedup) stats() *models.SubmissionLedgerStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return &models.SubmissionLedgerStats{
+		Claimed:    d.claimed,
+		Duplicates: d.duplicates,
+		Deferred:   d.deferred,
+		Released:   d.released,
+		Errors:     d.errors,
+		Unrecorded: d.unrecorded,
+	}
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+)
+
+// flakyLedger fails its next failures calls to Complete
+type flakyLedger struct {
+	SubmissionLedger
+	failures int
+}
+
+func (l *flakyLedger) Complete(ctx context.Context, id string, result *models.UpdateScoreResponse) error {
+	if l.failures > 0 {
+		l.failures--
+		return errors.New("connection refused")
+	}
+	return l.SubmissionLedger.Complete(ctx, id, result)
+}
+
+func TestSubmissionDedup(t *testing.T) {
+	cases := []struct {
+		name       string
+		failures   int // Calls to Complete that fail
+		applied    int // Times the submission is applied over two deliveries
+		unrecorded int64
+	}{
+		{"recorded", 0, 1, 0},
+		{"recorded on a retry", ledgerRecordAttempts - 1, 1, 0},
+		{"never recorded", ledgerRecordAttempts, 2, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ledger := &flakyLedger{SubmissionLedger: NewMemorySubmissionLedger(time.Hour), failures: c.failures}
+			// A lease shorter than the pauses between attempts, so the claim lapses if the record is lost
+			d := &submissionDedup{ledger: ledger, lease: time.Millisecond, retry: 2 * time.Millisecond}
+			item := queuedScore{ID: "s1", Username: "alice"}
+			applied := 0
+			update := func(queuedScore) (*models.UpdateScoreResponse, error) {
+				applied++
+				return &models.UpdateScoreResponse{Rating: 1000 + applied}, nil
+			}
+
+			for delivery := range 2 {
+				result, err := d.apply(item, update)
+				if err != nil {
+					t.Fatalf("delivery %d: %v", delivery+1, err)
+				}
+				if result.Rating != 1001 && c.unrecorded == 0 {
+					t.Errorf("delivery %d answered %d, want the first result", delivery+1, result.Rating)
+				}
+			}
+			if applied != c.applied {
+				t.Errorf("applied %d times, want %d", applied, c.applied)
+			}
+			stats := d.stats()
+			if stats.Unrecorded != c.unrecorded || stats.Errors != int64(min(c.failures, ledgerRecordAttempts)) {
+				t.Errorf("stats %+v, want %d unrecorded and %d errors", stats, c.unrecorded, c.failures)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"slices"
+
+	"backend/internal/events"
+	"backend/internal/models"
+)
+
+// EventMatcher decides whether an event passes a subscription filter. Rank
+// lookups are only done for top-N filters.
+type EventMatcher struct {
+	service   *LeaderboardService
+	filter    models.SubscriptionFilter
+	usernames map[string]struct{}
+}
+
+// NewEventMatcher compiles filter for repeated matching
+func (s *LeaderboardService) NewEventMatcher(filter models.SubscriptionFilter) *EventMatcher {
+	m := &EventMatcher{service: s, filter: filter}
+	if len(filter.Usernames) > 0 {
+		m.usernames = make(map[string]struct{}, len(filter.Usernames))
+		for _, username := range filter.Usernames {
+			m.usernames[username] = struct{}{}
+		}
+	}
+	return m
+}
+
+// Match reports whether e should be delivered
+func (m *EventMatcher) Match(e events.Event) bool {
+	f := m.filter
+
+	// There is a single all-time board, so any other board never matches
+	if f.Board != "" && f.Board != m.service.boardID {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, e.Type) {
+		return false
+	}
+	// Events name their user, so hidden and anonymized users' aren't sent
+	if e.Username != "" && !m.service.privacy.public(e.Username) {
+		return false
+	}
+	if m.usernames != nil {
+		if _, ok := m.usernames[e.Username]; !ok {
+			return false
+		}
+	}
+	if f.Top > 0 {
+		return m.touchesTop(e, f.Top)
+	}
+	return true
+}
+
+// WantsCutoffs reports whether watched cutoff changes should be delivered
+func (m *EventMatcher) WantsCutoffs() bool {
+	return m.filter.Cutoffs
+}
+
+// touchesTop reports whether the event's old or new rating ranks within the top n
+func (m *EventMatcher) touchesTop(e events.Event, n int) bool {
+	if m.service.store.RankForRating(e.Rating) <= n {
+		return true
+	}
+	return e.Type == events.TypeScoreUpdated && m.service.store.RankForRating(e.PreviousRating) <= n
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"backend/internal/models"
+)
+
+// ErrTiersNotConfigured is returned when tier stats are requested without tier boundaries
+var ErrTiersNotConfigured = errors.New("no tier boundaries are configured")
+
+// ParseTierBoundaries reads tier boundaries such as
+// "bronze:100,silver:1200,gold:1800,diamond:2400", lowest tier first
+func ParseTierBoundaries(spec string) ([]models.TierBoundary, error) {
+	var tiers []models.TierBoundary
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, score, ok := strings.Cut(item, ":")
+		minScore, err := strconv.Atoi(score)
+		if !ok || name == "" || err != nil {
+			return nil, fmt.Errorf("tier %q: expected name:min_score", item)
+		}
+		tiers = append(tiers, models.TierBoundary{Name: name, MinScore: minScore})
+	}
+	if err := validateTierBoundaries(tiers); err != nil {
+		return nil, err
+	}
+	return tiers, nil
+}
+
+// formatTierBoundaries writes tiers the way ParseTierBoundaries reads them
+func formatTierBoundaries(tiers []models.TierBoundary) string {
+	items := make([]string, 0, len(tiers))
+	for _, tier := range tiers {
+		items = append(items, fmt.Sprintf("%s:%d", tier.Name, tier.MinScore))
+	}
+	return strings.Join(items, ",")
+}
+
+// validateTierBoundaries checks tiers are named once each and ordered by
+// rising scores within the board's bounds
+func validateTierBoundaries(tiers []models.TierBoundary) error {
+	seen := make(map[string]bool, len(tiers))
+	for i, tier := range tiers {
+		switch {
+		case tier.Name == "":
+			return fmt.Errorf("tier %d: a name is required", i+1)
+		case seen[tier.Name]:
+			return fmt.Errorf("tier %q is listed twice", tier.Name)
+		case tier.MinScore < MinRating || tier.MinScore > MaxRating:
+			return fmt.Errorf("tier %q: min score %d is outside %d..%d", tier.Name, tier.MinScore, MinRating, MaxRating)
+		case i > 0 && tier.MinScore <= tiers[i-1].MinScore:
+			return fmt.Errorf("tier %q: min score %d must be above %q's %d", tier.Name, tier.MinScore, tiers[i-1].Name, tiers[i-1].MinScore)
+		}
+		seen[tier.Name] = true
+	}
+	return nil
+}
+
+// SetTierBoundaries places users in tiers by rating, lowest tier first.
+// The tier stats are recounted from the board, so call it before the
+// service is used.
+func (s *LeaderboardService) SetTierBoundaries(tiers []models.TierBoundary) error {
+	if err := validateTierBoundaries(tiers); err != nil {
+		return err
+	}
+	s.segments.rebuild(s.store, append([]models.TierBoundary(nil), tiers...))
+	return nil
+}
+
+// TierBoundaries returns the configured tiers, lowest first; empty if none are
+func (s *LeaderboardService) TierBoundaries() []models.TierBoundary {
+	g := s.segments
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]models.TierBoundary{}, g.tiers...)
+}
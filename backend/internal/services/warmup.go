@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/models"
+)
+
+// Warmup primes the stats cache for both views and checks the board's
+// integrity, so the first requests after startup don't pay for a full scan
+func (s *LeaderboardService) Warmup(ctx context.Context) (*models.WarmupReport, error) {
+	start := time.Now()
+	report := &models.WarmupReport{
+		StartedAt:    start.UTC(),
+		Users:        s.store.GetUserCount(),
+		CachesWarmed: []string{},
+	}
+
+	for _, excludeBots := range []bool{false, true} {
+		if _, err := s.ComputeStats(ctx, ListOptions{ExcludeBots: excludeBots}); err != nil {
+			return nil, err
+		}
+	}
+	report.CachesWarmed = append(report.CachesWarmed, "stats")
+
+	issues, err := s.CheckIntegrity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.Issues = issues
+	report.DurationMs = float64(time.Since(start)) / float64(time.Millisecond)
+	return report, nil
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Write buffer overflow policies
+const (
+	BufferReject     = "reject"      // Turn new submissions away with ErrQueueFull
+	BufferDropOldest = "drop_oldest" // Drop the oldest buffered submission to make room
+)
+
+// ErrSubmissionDropped is recorded for buffered submissions dropped to make room
+var ErrSubmissionDropped = errors.New("submission was dropped from a full write buffer while Redis was unavailable")
+
+// writeBufferRetry is how often a non-empty buffer retries Redis
+const writeBufferRetry = 500 * time.Millisecond
+
+// writeBuffer holds submissions in memory, in arrival order, while Redis
+// commands fail transiently. Once anything is buffered, later submissions
+// queue behind it so they reach the stream in order.
+type writeBuffer struct {
+	capacity int
+	policy   string
+
+	mu      sync.Mutex
+	items   []queuedScore
+	ids     map[string]queuedScore
+	dropped map[string]queuedScore // Dropped submissions, for status lookups
+	order   []string               // Dropped IDs, oldest first
+	since   time.Time              // When buffering began, zero while empty
+	stats   writeBufferStats
+	wake    chan struct{}
+}
+
+type writeBufferStats struct {
+	buffered int64
+	flushed  int64
+	dropped  int64
+}
+
+func newWriteBuffer(capacity int, policy string) *writeBuffer {
+	if policy == "" {
+		policy = BufferReject
+	}
+	return &writeBuffer{
+		capacity: capacity,
+		policy:   policy,
+		ids:      make(map[string]queuedScore),
+		dropped:  make(map[string]queuedScore),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// active reports whether submissions are waiting for Redis
+func (b *writeBuffer) active() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.items) > 0
+}
+
+// add buffers a submission, applying the overflow policy when full
+func (b *writeBuffer) add(item queuedScore, cause error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.items) >= b.capacity {
+		if b.policy != BufferDropOldest {
+			return ErrQueueFull
+		}
+		oldest := b.items[0]
+		b.items = b.items[1:]
+		delete(b.ids, oldest.ID)
+		b.forget(oldest)
+		b.stats.dropped++
+		log.Printf("Dropped buffered score submission %s for %s: write buffer full", oldest.ID, oldest.Username)
+	}
+
+	if len(b.items) == 0 {
+		b.since = time.Now()
+		log.Printf("⏸️  Redis unavailable, buffering score submissions in memory: %v", cause)
+	}
+	b.items = append(b.items, item)
+	b.ids[item.ID] = item
+	b.stats.buffered++
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// forget remembers a dropped submission, bounded like tracked submissions.
+// Must be called with the lock held.
+func (b *writeBuffer) forget(item queuedScore) {
+	b.dropped[item.ID] = item
+	b.order = append(b.order, item.ID)
+	if len(b.order) > maxTrackedSubmissions {
+		delete(b.dropped, b.order[0])
+		b.order = b.order[1:]
+	}
+}
+
+// lookup returns a submission's status if it is still buffered or was
+// dropped, or nil if the buffer doesn't know it
+func (b *writeBuffer) lookup(id string) *SubmissionStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if item, ok := b.ids[id]; ok {
+		return &SubmissionStatus{Submission: item.submission()}
+	}
+	if item, ok := b.dropped[id]; ok {
+		status := &SubmissionStatus{Submission: item.submission()}
+		completed(status, nil, ErrSubmissionDropped)
+		return status
+	}
+	return nil
+}
+
+// flush writes buffered submissions oldest first until push fails, leaving
+// the rest for the next attempt
+func (b *writeBuffer) flush(ctx context.Context, push func(context.Context, queuedScore) error) error {
+	for {
+		b.mu.Lock()
+		if len(b.items) == 0 {
+			b.mu.Unlock()
+			return nil
+		}
+		item := b.items[0]
+		b.mu.Unlock()
+
+		if err := push(ctx, item); err != nil {
+			return err
+		}
+
+		b.mu.Lock()
+		if len(b.items) > 0 && b.items[0].ID == item.ID {
+			b.items = b.items[1:]
+		} else if _, ok := b.dropped[item.ID]; ok {
+			// Dropped while it was being written, but it made it after all
+			delete(b.dropped, item.ID)
+			b.stats.dropped--
+		}
+		delete(b.ids, item.ID)
+		b.stats.flushed++
+		if len(b.items) == 0 {
+			log.Printf("▶️  Redis recovered, flushed buffered score submissions after %s", time.Since(b.since).Round(time.Millisecond))
+			b.since = time.Time{}
+		}
+		b.mu.Unlock()
+	}
+}
+
+// run retries Redis while anything is buffered, until ctx is cancelled
+func (b *writeBuffer) run(ctx context.Context, push func(context.Context, queuedScore) error, reportHealth func(string, error)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.wake:
+		}
+
+		for b.active() {
+			sleepCtx(ctx, writeBufferRetry)
+			if ctx.Err() != nil {
+				return
+			}
+			err := b.flush(ctx, push)
+			if ctx.Err() != nil {
+				return
+			}
+			reportHealth("score_queue", err)
+		}
+	}
+}
+
+// depth returns the number of buffered submissions
+func (b *writeBuffer) depth() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.items)
+}
+
+func (b *writeBuffer) counters() writeBufferStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stats
+}
+
+// transientRedisError reports whether err means Redis is briefly
+// unreachable or unable to take writes, rather than rejecting the command
+func transientRedisError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, redis.ErrPoolTimeout):
+		return true
+	}
+	return redis.IsLoadingError(err) ||
+		redis.IsReadOnlyError(err) ||
+		redis.IsClusterDownError(err) ||
+		redis.IsTryAgainError(err) ||
+		redis.IsMasterDownError(err)
+}
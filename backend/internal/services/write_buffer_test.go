@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"syscall"
+	"testing"
+
+	"backend/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestWriteBuffer(t *testing.T) {
+	errRedis := errors.New("connection refused")
+	cases := []struct {
+		name     string
+		capacity int
+		policy   string
+		add      []string
+		failOn   string // Submission whose push fails
+		rejected []string
+		dropped  []string
+		pushed   []string
+		left     int
+	}{
+		{name: "flushes in arrival order", capacity: 3, add: []string{"a", "b", "c"}, pushed: []string{"a", "b", "c"}},
+		{name: "rejects when full", capacity: 2, add: []string{"a", "b", "c"}, rejected: []string{"c"}, pushed: []string{"a", "b"}},
+		{name: "drops the oldest when full", capacity: 2, policy: BufferDropOldest, add: []string{"a", "b", "c", "d"}, dropped: []string{"a", "b"}, pushed: []string{"c", "d"}},
+		{name: "stops at a failed push", capacity: 3, add: []string{"a", "b", "c"}, failOn: "b", pushed: []string{"a"}, left: 2},
+		{name: "fails on the first push", capacity: 3, add: []string{"a"}, failOn: "a", left: 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := newWriteBuffer(c.capacity, c.policy)
+			var rejected []string
+			for _, id := range c.add {
+				err := b.add(queuedScore{ID: id, Username: "player_0"}, errRedis)
+				if errors.Is(err, ErrQueueFull) {
+					rejected = append(rejected, id)
+				} else if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !slices.Equal(rejected, c.rejected) {
+				t.Errorf("rejected %v, want %v", rejected, c.rejected)
+			}
+			for _, id := range c.dropped {
+				if status := b.lookup(id); status == nil || status.Status != models.SubmissionFailed || !errors.Is(status.Err, ErrSubmissionDropped) {
+					t.Errorf("dropped %s has status %+v, want failed as dropped", id, status)
+				}
+			}
+			if !b.active() {
+				t.Error("buffer with submissions isn't active")
+			}
+
+			var pushed []string
+			err := b.flush(context.Background(), func(ctx context.Context, item queuedScore) error {
+				if item.ID == c.failOn {
+					return errRedis
+				}
+				pushed = append(pushed, item.ID)
+				return nil
+			})
+			if (err != nil) != (c.failOn != "") {
+				t.Errorf("flush: %v", err)
+			}
+			if !slices.Equal(pushed, c.pushed) {
+				t.Errorf("pushed %v, want %v", pushed, c.pushed)
+			}
+			if got := b.depth(); got != c.left {
+				t.Errorf("%d left buffered, want %d", got, c.left)
+			}
+			if b.active() != (c.left > 0) {
+				t.Errorf("active %t with %d left", b.active(), c.left)
+			}
+			for _, id := range pushed {
+				if status := b.lookup(id); status != nil {
+					t.Errorf("flushed %s still has buffer status %+v", id, status)
+				}
+			}
+
+			stats := b.counters()
+			want := writeBufferStats{
+				buffered: int64(len(c.add) - len(c.rejected)),
+				flushed:  int64(len(c.pushed)),
+				dropped:  int64(len(c.dropped)),
+			}
+			if stats != want {
+				t.Errorf("stats %+v, want %+v", stats, want)
+			}
+		})
+	}
+}
+
+func TestWriteBufferKeepsDroppedSubmissionsThatFlushed(t *testing.T) {
+	b := newWriteBuffer(1, BufferDropOldest)
+	if err := b.add(queuedScore{ID: "a"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	// "a" is dropped for "b" while its push is in flight, and the push succeeds
+	err := b.flush(context.Background(), func(ctx context.Context, item queuedScore) error {
+		if item.ID == "a" {
+			if err := b.add(queuedScore{ID: "b"}, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := b.lookup("a"); status != nil {
+		t.Errorf("a made it to Redis but has status %+v", status)
+	}
+	if stats := b.counters(); stats.dropped != 0 || stats.flushed != 2 {
+		t.Errorf("stats %+v, want 2 flushed and none dropped", stats)
+	}
+}
+
+func TestTransientRedisError(t *testing.T) {
+	cases := []struct {
+		err       error
+		transient bool
+	}{
+		{nil, false},
+		{context.Canceled, false},
+		{context.DeadlineExceeded, true},
+		{io.EOF, true},
+		{fmt.Errorf("dial: %w", syscall.ECONNREFUSED), true},
+		{redis.ErrPoolTimeout, true},
+		{redis.Nil, false},
+		{errors.New("LOADING Redis is loading the dataset in memory"), true},
+		{errors.New("READONLY You can't write against a read only replica."), true},
+		{errors.New("WRONGTYPE Operation against a key holding the wrong kind of value"), false},
+	}
+	for _, c := range cases {
+		if got := transientRedisError(c.err); got != c.transient {
+			t.Errorf("transientRedisError(%v) = %t, want %t", c.err, got, c.transient)
+		}
+	}
+}
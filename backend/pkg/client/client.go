@@ -0,0 +1,194 @@
+// Package client is a Go SDK for the leaderboard HTTP API.
+//
+//	c := client.New("http://localhost:8080")
+//	for entry, err := range c.Leaderboard.Iter(ctx, client.IterOptions{}) {
+//		if err != nil { ... }
+//		fmt.Println(entry.Rank, entry.Username)
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+)
+
+// Aliases so SDK consumers can name the API's payload types
+type (
+	Entry          = models.LeaderboardEntry
+	Page           = models.LeaderboardResponse
+	UserRank       = models.UserRankResponse
+	SearchPage     = models.SearchResponse
+	UpdateRequest  = models.UpdateScoreRequest
+	UpdateResponse = models.UpdateScoreResponse
+	OfflineEvent   = models.OfflineScoreEvent
+	SyncRequest    = models.OfflineSyncRequest
+	SyncResponse   = models.OfflineSyncResponse
+	Stats          = models.StatsResponse
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultBackoff    = 500 * time.Millisecond
+	maxBackoff        = 30 * time.Second
+)
+
+// APIError is returned for non-2xx responses
+type APIError struct {
+	StatusCode int
+	Code       string `json:"error"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("leaderboard api: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Client talks to a leaderboard server
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+	token      string
+
+	Leaderboard *LeaderboardService
+	Search      *SearchService
+	Users       *UsersService
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how often rate-limited or unavailable requests are retried
+// and the initial backoff, which doubles on every attempt
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// WithToken sends token as a bearer token, for servers with auth enabled
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// New creates a client for the server at baseURL (e.g. "http://localhost:8080")
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.Leaderboard = &LeaderboardService{client: c}
+	c.Search = &SearchService{client: c}
+	c.Users = &UsersService{client: c}
+	return c
+}
+
+// do sends a request, retrying 429 and 503 responses after Retry-After or an
+// exponential backoff. The caller must close the returned body.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body any) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+		if !retryable || attempt >= c.maxRetries {
+			defer resp.Body.Close()
+			return nil, decodeError(resp)
+		}
+
+		wait := retryAfter(resp, backoff)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// getJSON performs a GET and decodes the JSON response into v
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, v any) error {
+	resp, err := c.do(ctx, http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// retryAfter honours a Retry-After header in seconds, falling back to def
+func retryAfter(resp *http.Response, def time.Duration) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return min(time.Duration(seconds)*time.Second, maxBackoff)
+	}
+	return def
+}
+
+func decodeError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil {
+		apiErr.Code = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
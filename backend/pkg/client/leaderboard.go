@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// maxPageSize is the largest page GET /api/leaderboard serves
+const maxPageSize = 100
+
+// PageOptions selects a single leaderboard page
+type PageOptions struct {
+	Page        int // 1-based, defaults to 1
+	Limit       int // Defaults to 50, at most 100
+	ExcludeBots bool
+}
+
+func (o PageOptions) query() url.Values {
+	q := url.Values{}
+	if o.Page > 0 {
+		q.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.ExcludeBots {
+		q.Set("exclude_bots", "true")
+	}
+	return q
+}
+
+// IterOptions controls a walk over the whole leaderboard
+type IterOptions struct {
+	PageSize    int // Entries fetched per request, defaults to 100
+	ExcludeBots bool
+}
+
+// LeaderboardService covers the leaderboard endpoints
+type LeaderboardService struct {
+	client *Client
+}
+
+// Page fetches a single leaderboard page
+func (s *LeaderboardService) Page(ctx context.Context, opts PageOptions) (*Page, error) {
+	var page Page
+	if err := s.client.getJSON(ctx, "/api/leaderboard", opts.query(), &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Iter yields every entry in rank order, fetching pages as needed. Iteration
+// stops at the first error, which is yielded with a zero Entry. Pages are
+// read at different moments, so entries may repeat or be skipped when
+// ratings change mid-walk; use Export for a consistent snapshot.
+func (s *LeaderboardService) Iter(ctx context.Context, opts IterOptions) iter.Seq2[Entry, error] {
+	pageSize := opts.PageSize
+	if pageSize <= 0 || pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	return func(yield func(Entry, error) bool) {
+		for pageNum := 1; ; pageNum++ {
+			page, err := s.Page(ctx, PageOptions{Page: pageNum, Limit: pageSize, ExcludeBots: opts.ExcludeBots})
+			if err != nil {
+				yield(Entry{}, err)
+				return
+			}
+
+			for _, entry := range page.Entries {
+				if !yield(entry, nil) {
+					return
+				}
+			}
+
+			if !page.HasMore || len(page.Entries) == 0 {
+				return
+			}
+		}
+	}
+}
+
+// Export streams the full board from GET /api/export as one consistent snapshot
+func (s *LeaderboardService) Export(ctx context.Context, excludeBots bool) iter.Seq2[Entry, error] {
+	q := url.Values{"format": {"jsonl"}}
+	if excludeBots {
+		q.Set("exclude_bots", "true")
+	}
+	return streamLines[Entry](ctx, s.client, "/api/export", q)
+}
+
+// Stats fetches board statistics. They may be cached for a moment on the
+// server; fresh recomputes them, which requires the admin role when auth is
+// enabled.
+func (s *LeaderboardService) Stats(ctx context.Context, excludeBots, fresh bool) (*Stats, error) {
+	q := url.Values{}
+	if excludeBots {
+		q.Set("exclude_bots", "true")
+	}
+	if fresh {
+		q.Set("fresh", "true")
+	}
+	var stats Stats
+	if err := s.client.getJSON(ctx, "/api/stats", q, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// streamLines yields each JSON Lines element of a streaming endpoint
+func streamLines[T any](ctx context.Context, c *Client, path string, query url.Values) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		resp, err := c.do(ctx, http.MethodGet, path, query, nil)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		dec := json.NewDecoder(resp.Body)
+		for dec.More() {
+			var v T
+			if err := dec.Decode(&v); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// SearchService covers GET /api/search
+type SearchService struct {
+	client *Client
+}
+
+// Iter yields users whose name starts with query, in rank order, from the
+// first page of results. A limit of 0 uses the server default.
+func (s *SearchService) Iter(ctx context.Context, query string, limit int) iter.Seq2[UserRank, error] {
+	q := url.Values{"q": {query}, "format": {"jsonl"}}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	return streamLines[UserRank](ctx, s.client, "/api/search", q)
+}
+
+// SearchOptions selects a single page of search results
+type SearchOptions struct {
+	Page   int    // 1-based, defaults to 1; ignored when Cursor is set
+	Limit  int    // 0 uses the server default
+	Cursor string // NextCursor of the previous page
+}
+
+// Page fetches one page of users whose name starts with query, in rank order,
+// with the total number of matches
+func (s *SearchService) Page(ctx context.Context, query string, opts SearchOptions) (*SearchPage, error) {
+	q := url.Values{"q": {query}}
+	if opts.Page > 0 {
+		q.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		q.Set("cursor", opts.Cursor)
+	}
+	var page SearchPage
+	if err := s.client.getJSON(ctx, "/api/search", q, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// All collects every search result
+func (s *SearchService) All(ctx context.Context, query string, limit int) ([]UserRank, error) {
+	results := make([]UserRank, 0)
+	for result, err := range s.Iter(ctx, query, limit) {
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// UsersService covers the per-user endpoints
+type UsersService struct {
+	client *Client
+}
+
+// Get fetches a user's rank
+func (s *UsersService) Get(ctx context.Context, username string) (*UserRank, error) {
+	var rank UserRank
+	if err := s.client.getJSON(ctx, "/api/users/"+url.PathEscape(username), nil, &rank); err != nil {
+		return nil, err
+	}
+	return &rank, nil
+}
+
+// UpdateScore submits a score for a user
+func (s *UsersService) UpdateScore(ctx context.Context, username string, req UpdateRequest) (*UpdateResponse, error) {
+	resp, err := s.client.do(ctx, http.MethodPost, "/api/users/"+url.PathEscape(username)+"/score", nil, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result UpdateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SyncScores uploads score events journaled while offline and returns how
+// each was reconciled. Events keep their IDs across retries, so a sync can
+// be retried safely.
+func (s *UsersService) SyncScores(ctx context.Context, username string, events []OfflineEvent) (*SyncResponse, error) {
+	return s.SyncScoresFrom(ctx, username, "", events)
+}
+
+// SyncScoresFrom is SyncScores for the device clientID, whose earlier syncs
+// the server doesn't count as changes made since its events happened
+func (s *UsersService) SyncScoresFrom(ctx context.Context, username, clientID string, events []OfflineEvent) (*SyncResponse, error) {
+	body := SyncRequest{ClientID: clientID, Events: events}
+	resp, err := s.client.do(ctx, http.MethodPost, "/api/users/"+url.PathEscape(username)+"/score/sync", nil, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result SyncResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
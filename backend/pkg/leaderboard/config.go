@@ -0,0 +1,373 @@
+package leaderboard
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"backend/internal/events"
+	"backend/internal/ranking"
+	"backend/internal/services"
+	"backend/pkg/store"
+)
+
+// redacted replaces secrets in the resolved configuration
+const redacted = "<redacted>"
+
+// secretFields are redacted wherever they appear in Options
+var secretFields = map[string]bool{
+	"Secret":       true,
+	"Secrets":      true,
+	"Token":        true,
+	"Password":     true,
+	"APIKeys":      true,
+	"ClientSecret": true,
+}
+
+// withDefaults fills in the defaults New applies
+func (o Options) withDefaults() Options {
+	if o.BoardID == "" {
+		o.BoardID = services.DefaultBoardID
+	}
+	if o.RatingStrategy == "" {
+		o.RatingStrategy = "absolute"
+	}
+	if o.TiePolicy == "" {
+		o.TiePolicy = ranking.SharedRank
+	}
+	if o.SlowConsumerPolicy == "" {
+		o.SlowConsumerPolicy = events.PolicyDrop
+	}
+	if o.ExpirySweepInterval <= 0 {
+		o.ExpirySweepInterval = 30 * time.Second
+	}
+	if o.InflationInterval <= 0 {
+		o.InflationInterval = services.DefaultInflationInterval
+	}
+	if o.HistoryCompactInterval <= 0 {
+		o.HistoryCompactInterval = services.DefaultHistoryCompactInterval
+	}
+	if o.SimulationInterval <= 0 {
+		o.SimulationInterval = services.DefaultSimulationInterval
+	}
+	if o.SimulationTarget == "" {
+		o.SimulationTarget = "uniform"
+	}
+	return o
+}
+
+// Validate reports every problem New would reject opts for, without opening
+// files or connecting to anything, so deploy pipelines can fail fast
+func (o Options) Validate() error {
+	o = o.withDefaults()
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if _, err := services.RatingStrategyByName(o.RatingStrategy); err != nil {
+		fail("rating strategy: %w", err)
+	}
+	if o.ShadowStrategy != "" {
+		if _, err := services.RatingStrategyByName(o.ShadowStrategy); err != nil {
+			fail("shadow rating strategy: %w", err)
+		}
+	}
+	if o.TiePolicy != ranking.SharedRank && o.TiePolicy != ranking.MostRecentFirst {
+		fail("unknown tie policy %q", o.TiePolicy)
+	}
+	if o.SlowConsumerPolicy != events.PolicyDrop && o.SlowConsumerPolicy != events.PolicyDisconnect {
+		fail("unknown slow consumer policy %q", o.SlowConsumerPolicy)
+	}
+	simulation := services.SimulationConfig{
+		Interval:  o.SimulationInterval,
+		Target:    o.SimulationTarget,
+		BatchSize: o.SimulationBatchSize,
+		Jitter:    o.SimulationJitter,
+	}
+	if err := simulation.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if o.MaxMembers < 0 {
+		fail("max members %d must not be negative", o.MaxMembers)
+	}
+	if o.MemoryBudget < 0 {
+		fail("memory budget %d must not be negative", o.MemoryBudget)
+	}
+	if _, ok := o.Store.(*store.ShardedStore); ok {
+		// Neither is kept across the shards
+		if o.MemoryBudget > 0 {
+			fail("memory budget isn't supported by a sharded store")
+		}
+		if o.TiePolicy == ranking.MostRecentFirst {
+			fail("tie policy %q isn't supported by a sharded store", o.TiePolicy)
+		}
+	}
+	if o.RateLimit.Reads < 0 || o.RateLimit.Writes < 0 || o.RateLimit.Window < 0 {
+		fail("rate limits must not be negative")
+	}
+	if o.QueryCostBudget < 0 {
+		fail("query cost budget %d must not be negative", o.QueryCostBudget)
+	}
+	switch o.MemoryPolicy {
+	case "", store.MemoryReject, store.MemoryEvictLowest:
+	default:
+		fail("unknown memory policy %q (want %s or %s)", o.MemoryPolicy, store.MemoryReject, store.MemoryEvictLowest)
+	}
+	if strings.IndexFunc(o.RedisKeyPrefix, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		fail("redis key prefix %q must not contain spaces or control characters", o.RedisKeyPrefix)
+	}
+	for _, principal := range o.Admins {
+		if err := services.CheckBootstrapAdmin(principal); err != nil {
+			fail("%w", err)
+		}
+	}
+
+	if o.Auth != nil {
+		if err := o.Auth.Validate(); err != nil {
+			fail("auth: %w", err)
+		}
+	}
+	if o.DoubleWrite != nil {
+		if o.DoubleWrite.Target == nil {
+			fail("double write: target store is required")
+		}
+		if o.DoubleWrite.SampleRate < 0 || o.DoubleWrite.SampleRate > 1 {
+			fail("double write: sample rate %v must be between 0 and 1", o.DoubleWrite.SampleRate)
+		}
+	}
+	if o.DerivedBoards != nil {
+		for _, kind := range o.DerivedBoards.Kinds {
+			if !slices.Contains(services.BoardKinds, kind) {
+				fail("derived boards: unknown kind %q (want %s)", kind, strings.Join(services.BoardKinds, ", "))
+			}
+		}
+		if o.DerivedBoards.DailyRetention < 0 {
+			fail("derived boards: daily retention %d must not be negative", o.DerivedBoards.DailyRetention)
+		}
+	}
+	if o.Season != nil {
+		if o.Season.ID == "" {
+			fail("season: ID is required")
+		}
+		now := time.Now()
+		if o.Clock != nil {
+			now = o.Clock.Now()
+		}
+		if !o.Season.EndsAt.IsZero() && !o.Season.EndsAt.After(now) {
+			fail("season: ends at %s, which has passed", o.Season.EndsAt.Format(time.RFC3339))
+		}
+	}
+	if err := o.CachePolicy.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if o.SLOs != nil {
+		for _, objective := range o.SLOs.Objectives {
+			if err := objective.Validate(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if o.SLOs.AlertBurnRate < 0 {
+			fail("latency SLO alert burn rate %v must not be negative", o.SLOs.AlertBurnRate)
+		}
+		if o.SLOs.AlertURL != "" {
+			if err := validateURL(o.SLOs.AlertURL); err != nil {
+				fail("SLO alert webhook: %w", err)
+			}
+		}
+	}
+	if o.SeasonWebhooks != nil {
+		for _, target := range o.SeasonWebhooks.URLs {
+			if err := validateURL(target); err != nil {
+				fail("season webhook: %w", err)
+			}
+		}
+	}
+	if o.Reports != nil {
+		if o.Reports.WebhookURL != "" {
+			if err := validateURL(o.Reports.WebhookURL); err != nil {
+				fail("report webhook: %w", err)
+			}
+		}
+		if email := o.Reports.Email; email != nil && (email.Addr == "" || email.From == "") {
+			fail("report email: an SMTP address and a from address are required")
+		}
+	}
+	if o.Import != nil {
+		if err := validateURL(o.Import.URL); err != nil {
+			fail("import: %w", err)
+		}
+		switch o.Import.Format {
+		case "", services.ImportCSV, services.ImportJSON:
+		default:
+			fail("import: unknown format %q (available: csv, json)", o.Import.Format)
+		}
+		if o.Import.Secret == "" {
+			fail("import: a secret is required to verify the file's signature (IMPORT_SECRET)")
+		}
+	}
+	if o.Bots != nil {
+		if o.Bots.Discord != nil && o.Bots.Discord.Token == "" {
+			fail("discord bot: token is required")
+		}
+		if o.Bots.Telegram != nil && o.Bots.Telegram.Token == "" {
+			fail("telegram bot: token is required")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateURL accepts absolute http and https URLs
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		// url.Error quotes the whole URL, credentials and all
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("URL is invalid: %w", urlErr.Err)
+		}
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http(s) URL", redactURL(u))
+	}
+	return nil
+}
+
+// redactURL hides a URL's credentials and query values, which often carry
+// tokens, keeping its host, path and query keys
+func redactURL(u *url.URL) string {
+	redactedURL := *u
+	if u.User != nil {
+		redactedURL.User = url.User(redacted)
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query[key] = []string{redacted}
+		}
+		redactedURL.RawQuery = query.Encode()
+	}
+	// Encode would escape the brackets of <redacted>
+	text := redactedURL.String()
+	return strings.ReplaceAll(text, url.QueryEscape(redacted), redacted)
+}
+
+// Resolved describes opts with defaults filled in and secrets redacted, as
+// served at GET /api/admin/config. URLs keep their host and path but not
+// their credentials or query values. Settings left unset are omitted.
+func (o Options) Resolved() map[string]any {
+	resolved, _ := describe(reflect.ValueOf(o.withDefaults())).(map[string]any)
+	return resolved
+}
+
+// describe turns a configuration value into JSON-friendly values, returning
+// nil for zero values
+func describe(v reflect.Value) any {
+	if !v.IsValid() || v.IsZero() {
+		return nil
+	}
+	switch value := v.Interface().(type) {
+	case time.Duration:
+		return value.String()
+	case time.Time:
+		return value.Format(time.RFC3339)
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if elem := v.Elem(); elem.Kind() == reflect.Struct && hasExportedFields(elem.Type()) {
+			return describe(elem)
+		}
+		// Stores, clocks and clients are named by type
+		return fmt.Sprintf("%T", v.Interface())
+	case reflect.Func, reflect.Chan:
+		return fmt.Sprintf("%T", v.Interface())
+	case reflect.Struct:
+		fields := make(map[string]any)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			var value any
+			if secretFields[field.Name] {
+				value = redact(v.Field(i))
+			} else {
+				value = describe(v.Field(i))
+			}
+			if value != nil {
+				fields[snakeCase(field.Name)] = value
+			}
+		}
+		return fields
+	case reflect.String:
+		// URLs such as webhooks may carry credentials or tokens
+		if u, err := url.Parse(v.String()); err == nil && u.Scheme != "" && u.Host != "" {
+			return redactURL(u)
+		}
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		items := make([]any, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, describe(v.Index(i)))
+		}
+		return items
+	case reflect.Map:
+		entries := make(map[string]any, v.Len())
+		for _, key := range v.MapKeys() {
+			entries[fmt.Sprint(key.Interface())] = describe(v.MapIndex(key))
+		}
+		return entries
+	}
+	return v.Interface()
+}
+
+// redact hides a secret, keeping the names in maps of secrets
+func redact(v reflect.Value) any {
+	if v.IsZero() {
+		return nil
+	}
+	if v.Kind() != reflect.Map {
+		return redacted
+	}
+	entries := make(map[string]any, v.Len())
+	for _, key := range v.MapKeys() {
+		entries[fmt.Sprint(key.Interface())] = redacted
+	}
+	return entries
+}
+
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// snakeCase turns field names such as APIKeys, TopMovers and URLs into
+// api_keys, top_movers and urls
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1])
+			// A trailing s pluralizes an acronym, as in URLs
+			plural := i+2 == len(runes) && runes[i+1] == 's'
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1]) && !plural
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,588 @@
+// Package leaderboard runs the whole leaderboard backend in-process, for Go
+// programs that want to embed it instead of talking to a separate service.
+//
+//	lb, err := leaderboard.New(leaderboard.Options{RatingStrategy: "elo"})
+//	if err != nil { ... }
+//	defer lb.Close()
+//	go lb.Start(ctx)
+//	mux.Handle("/", lb.Handler())
+package leaderboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/clock"
+	"backend/internal/events"
+	"backend/internal/handlers"
+	"backend/internal/integrations"
+	"backend/internal/models"
+	"backend/internal/services"
+	"backend/pkg/store"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Aliases so embedding programs can name the service and its payload types
+type (
+	Service             = services.LeaderboardService
+	AnomalyConfig       = services.AnomalyConfig
+	IntegrationConfig   = services.IntegrationConfig
+	DoubleWriteConfig   = services.DoubleWriteConfig
+	DerivedBoardsConfig = services.DerivedBoardsConfig
+	SeasonConfig        = services.SeasonConfig
+	SeasonWebhookConfig = services.SeasonWebhookConfig
+	GuestConfig         = services.GuestConfig
+	PrizeBand           = services.PrizeBand
+	WatchedCutoff       = services.WatchedCutoff
+	TierBoundary        = models.TierBoundary
+	ScoreQueueConfig    = services.ScoreQueueConfig
+	ReportConfig        = services.ReportConfig
+	EmailConfig         = services.EmailConfig
+	ImportConfig        = services.ImportConfig
+	MatchLedger         = services.MatchLedger
+	StateSnapshot       = services.StateSnapshot
+	BotConfig           = integrations.Config
+	DiscordBotConfig    = integrations.DiscordConfig
+	TelegramBotConfig   = integrations.TelegramConfig
+	AuthConfig          = auth.Config
+	AuthProvider        = auth.Provider
+	LockoutConfig       = auth.LockoutConfig
+	RateLimit           = services.RateLimit
+	SLOConfig           = services.SLOConfig
+	CachePolicy         = handlers.CachePolicy
+	LatencySLO          = services.LatencySLO
+	RatingStrategy      = services.RatingStrategy
+	Enricher            = services.Enricher
+	EnricherFunc        = services.EnricherFunc
+	ListOptions         = services.ListOptions
+	Route               = handlers.Route
+	Event               = events.Event
+	Entry               = models.LeaderboardEntry
+	UserRank            = models.UserRankResponse
+	Stats               = models.StatsResponse
+	Clock               = clock.Clock
+	FakeClock           = clock.Fake
+)
+
+// NewRedisMatchLedger keeps applied match IDs in Redis for ttl, under
+// prefix, so a retry is recognised by every replica and after a restart
+func NewRedisMatchLedger(client redis.UniversalClient, prefix string, ttl time.Duration) MatchLedger {
+	return services.NewRedisMatchLedger(client, prefix, ttl)
+}
+
+// NewFakeClock returns a clock stopped at start that only moves with Advance,
+// so embedding programs can test time-dependent behaviour without sleeping
+func NewFakeClock(start time.Time) *FakeClock {
+	return clock.NewFake(start)
+}
+
+// GoogleLogin configures Google as a login provider
+func GoogleLogin(clientID, clientSecret, redirectURL string) AuthProvider {
+	return auth.Google(clientID, clientSecret, redirectURL)
+}
+
+// DiscordLogin configures Discord as a login provider
+func DiscordLogin(clientID, clientSecret, redirectURL string) AuthProvider {
+	return auth.Discord(clientID, clientSecret, redirectURL)
+}
+
+// ParsePrizeBands reads prize bands such as "gold:1,silver:2-3,top10:10%"
+func ParsePrizeBands(spec string) ([]PrizeBand, error) {
+	return services.ParsePrizeBands(spec)
+}
+
+// ParseTierBoundaries reads tiers such as "bronze:100,silver:1200,gold:1800",
+// lowest first
+func ParseTierBoundaries(spec string) ([]TierBoundary, error) {
+	return services.ParseTierBoundaries(spec)
+}
+
+// ParseWatchedCutoffs reads watched cutoffs such as "100:25,1000:50", each a
+// rank and the move in rating worth announcing
+func ParseWatchedCutoffs(spec string) ([]WatchedCutoff, error) {
+	return services.ParseWatchedCutoffs(spec)
+}
+
+// ParseLatencySLOs reads latency objectives such as
+// "leaderboard:p99<50ms,stats:p95<200ms"
+func ParseLatencySLOs(spec string) ([]LatencySLO, error) {
+	return services.ParseLatencySLOs(spec)
+}
+
+// DefaultCachePolicy returns the max age of each cache class when
+// Options.CachePolicy leaves it out
+func DefaultCachePolicy() CachePolicy {
+	return handlers.DefaultCachePolicy()
+}
+
+// DefaultAnomalyConfig returns the detector thresholds used by cmd/server
+func DefaultAnomalyConfig() AnomalyConfig {
+	return services.DefaultAnomalyConfig()
+}
+
+// Options configures an embedded leaderboard. The zero value is a plain
+// in-memory board with the absolute rating strategy and the simulator disabled.
+type Options struct {
+	Store                  store.Store          // Defaults to a new in-memory store; see store.NewRedisStore
+	BoardID                string               // Served at /api/leaderboards/{id}, "default" if empty
+	RatingStrategy         string               // Built-in strategy name, "absolute" if empty
+	ShadowStrategy         string               // Shadow-write with this strategy when set
+	TiePolicy              string               // How equal ratings are ordered: "shared_rank" (default) or "most_recent_first"
+	MaxMembers             int                  // Member cap with lowest-rank eviction, 0 for none
+	MemoryBudget           int64                // Approximate bytes for members and their score history, 0 for none
+	MemoryPolicy           string               // reject (default) or evict_lowest once MemoryBudget is reached
+	EventLogPath           string               // Append events to this JSON Lines file when set
+	Anomaly                *AnomalyConfig       // Enable anomaly detection when set
+	Integrations           *IntegrationConfig   // Accept signed platform scores when set
+	DoubleWrite            *DoubleWriteConfig   // Mirror writes to a migration target and compare samples when set
+	Season                 *SeasonConfig        // Start a season at startup when set
+	SeasonWebhooks         *SeasonWebhookConfig // Post final standings when a season closes
+	ScoreQueue             *ScoreQueueConfig    // Accept ?async=true score submissions when set
+	MatchLedger            MatchLedger          // Where applied match IDs are kept, in memory for 24 hours if nil; see NewRedisMatchLedger
+	RedisKeyPrefix         string               // Prepended to every Redis key, e.g. "app:staging:", so environments can share one Redis
+	PrizeBands             []PrizeBand          // Served at /api/leaderboards/{id}/prizes, see ParsePrizeBands
+	TierBoundaries         []TierBoundary       // Served in board metadata and broken down at /api/stats/by-tier, see ParseTierBoundaries
+	Auth                   *AuthConfig          // Enable social login, access tokens and role checks when set
+	Admins                 []string             // Principals ("key:<name>", "identity:<provider>:<subject>") granted admin at startup
+	Bots                   *BotConfig           // Connect Discord and Telegram bots when set
+	Reports                *ReportConfig        // Generate a summary after each UTC day when set
+	Import                 *ImportConfig        // Pull and apply a partner's score file on a schedule when set
+	ApprovalThreshold      int                  // Adjustments moving a rating by more than this wait for a second approver, 0 for none
+	Guests                 bool                 // Accept scores from unregistered players keyed by a device token
+	GuestLimits            GuestConfig          // Caps on new guests when Guests is set; zero fields take defaults
+	Cutoffs                []WatchedCutoff      // Track the rating at these ranks and announce large moves, see ParseWatchedCutoffs
+	SimulateUpdates        bool                 // Start the random score update simulator enabled
+	SimulationInterval     time.Duration        // Defaults to 5s
+	SimulationTarget       string               // uniform (default), top, humans or bots
+	SimulationBatchSize    int                  // Members the simulator updates together per cycle, defaults to 1
+	SimulationJitter       float64              // Vary each simulator cycle by up to this fraction of the interval
+	ExpirySweepInterval    time.Duration        // Defaults to 30s
+	InflationInterval      time.Duration        // Sample ratings for the inflation index this often, defaults to 1h
+	HistoryCompactInterval time.Duration        // Downsample aged score history this often, defaults to 10m
+	StatsCacheTTL          time.Duration        // Serve cached stats this long, defaults to 1s; negative disables
+	SearchCacheTTL         time.Duration        // Serve cached search results this long, defaults to 2s; negative disables
+	Warmup                 bool                 // Warm caches and check integrity in Start before /readyz passes
+	RealtimeQueueSize      int                  // Events buffered per WebSocket, defaults to 256
+	SlowConsumerPolicy     string               // "drop" (default) or "disconnect" when a WebSocket falls behind
+	MaxInFlight            int                  // Shed requests without X-Priority: high beyond this many in flight, 0 for no limit
+	RateLimit              RateLimit            // Per-caller request limits; zero limits are off
+	QueryCostBudget        int                  // Reject reads walking more board entries than this unless the caller is an admin, 0 for no limit
+	CachePolicy            CachePolicy          // Max age per cache class ("leaderboard", "stats", "users"), overriding DefaultCachePolicy
+	SLOs                   *SLOConfig           // Track latency objectives per endpoint group and alert on fast budget burn when set
+	DerivedBoards          *DerivedBoardsConfig // Keep daily, country and team boards updated atomically with the main board when set
+	Clock                  Clock                // Defaults to the system clock; see NewFakeClock
+	RandSeed               int64                // Seed for seed data and the simulator, 0 picks one from the clock
+}
+
+// Leaderboard is an embedded leaderboard backend
+type Leaderboard struct {
+	service  *Service
+	handler  *handlers.LeaderboardHandler
+	eventLog *events.FileLog
+	auth     *auth.Auth // nil unless auth is enabled
+	opts     Options
+}
+
+// New builds a leaderboard from opts
+func New(opts Options) (*Leaderboard, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts = opts.withDefaults()
+	if opts.Store == nil {
+		opts.Store = store.NewMemoryStore()
+	}
+
+	service := services.NewLeaderboardService(opts.Store)
+	service.SetResolvedConfig(opts.Resolved())
+	if opts.Clock != nil {
+		service.SetClock(opts.Clock)
+	}
+	if opts.RandSeed != 0 {
+		service.SetRandSeed(opts.RandSeed)
+	}
+	if opts.MatchLedger != nil {
+		service.SetMatchLedger(opts.MatchLedger)
+	}
+	lb := &Leaderboard{
+		service: service,
+		handler: handlers.NewLeaderboardHandler(service),
+		opts:    opts,
+	}
+
+	switch opts.SlowConsumerPolicy {
+	case "", events.PolicyDrop, events.PolicyDisconnect:
+		service.Realtime().Configure(events.HubConfig{
+			QueueSize: opts.RealtimeQueueSize,
+			Policy:    opts.SlowConsumerPolicy,
+		})
+	default:
+		lb.Close()
+		return nil, fmt.Errorf("unknown slow consumer policy %q", opts.SlowConsumerPolicy)
+	}
+
+	if opts.BoardID != "" {
+		service.SetBoardID(opts.BoardID)
+	}
+
+	if opts.StatsCacheTTL != 0 {
+		service.SetStatsCacheTTL(opts.StatsCacheTTL)
+	}
+
+	if opts.SearchCacheTTL != 0 {
+		service.SetSearchCacheTTL(opts.SearchCacheTTL)
+	}
+
+	if opts.MaxMembers > 0 {
+		service.SetCapacity(opts.MaxMembers)
+	}
+	if opts.MemoryBudget > 0 {
+		service.SetMemoryBudget(opts.MemoryBudget, opts.MemoryPolicy)
+	}
+
+	if opts.Warmup {
+		lb.handler.SetWarmingUp(true)
+	}
+	if opts.MaxInFlight > 0 {
+		lb.handler.SetMaxInFlight(opts.MaxInFlight)
+	}
+	if opts.RateLimit.Reads > 0 || opts.RateLimit.Writes > 0 {
+		service.SetRateLimit(opts.RateLimit)
+	}
+	if opts.QueryCostBudget > 0 {
+		service.SetQueryCostBudget(opts.QueryCostBudget)
+	}
+	if len(opts.CachePolicy) > 0 {
+		lb.handler.SetCachePolicy(opts.CachePolicy)
+	}
+	if opts.SLOs != nil {
+		service.EnableSLOs(*opts.SLOs)
+	}
+
+	if opts.EventLogPath != "" {
+		eventLog, err := events.OpenFileLog(opts.EventLogPath)
+		if err != nil {
+			lb.Close()
+			return nil, err
+		}
+		lb.eventLog = eventLog
+		service.Events().Subscribe(func(e events.Event) {
+			err := eventLog.Append(e)
+			if err != nil {
+				log.Printf("Failed to append event: %v", err)
+			}
+			service.ReportHealth("event_log", err)
+		})
+	}
+
+	if opts.Anomaly != nil {
+		service.EnableAnomalyDetection(*opts.Anomaly)
+	}
+
+	if opts.Integrations != nil {
+		service.EnableIntegrations(*opts.Integrations)
+	}
+
+	if opts.DoubleWrite != nil {
+		if err := service.EnableDoubleWrite(*opts.DoubleWrite); err != nil {
+			lb.Close()
+			return nil, fmt.Errorf("double write: %w", err)
+		}
+	}
+
+	if opts.DerivedBoards != nil {
+		if err := service.EnableDerivedBoards(*opts.DerivedBoards); err != nil {
+			lb.Close()
+			return nil, fmt.Errorf("derived boards: %w", err)
+		}
+	}
+
+	if opts.Guests {
+		service.EnableGuests(opts.GuestLimits)
+	}
+
+	if len(opts.Cutoffs) > 0 {
+		if err := service.EnableCutoffWatch(opts.Cutoffs); err != nil {
+			lb.Close()
+			return nil, fmt.Errorf("cutoffs: %w", err)
+		}
+	}
+
+	if opts.SeasonWebhooks != nil {
+		service.EnableSeasonWebhooks(*opts.SeasonWebhooks)
+	}
+
+	if opts.ScoreQueue != nil {
+		queue := *opts.ScoreQueue
+		if opts.RedisKeyPrefix != "" {
+			if queue.KeyPrefix == "" {
+				queue.KeyPrefix = services.DefaultRedisKeyPrefix
+			}
+			queue.KeyPrefix = opts.RedisKeyPrefix + queue.KeyPrefix
+		}
+		if err := service.EnableScoreQueue(queue); err != nil {
+			lb.Close()
+			return nil, fmt.Errorf("score queue: %w", err)
+		}
+	}
+
+	if opts.Reports != nil {
+		service.EnableDailyReports(*opts.Reports)
+	}
+
+	if opts.Import != nil {
+		if err := service.EnableScoreImport(*opts.Import); err != nil {
+			lb.Close()
+			return nil, fmt.Errorf("score import: %w", err)
+		}
+	}
+
+	if opts.ApprovalThreshold > 0 {
+		service.SetApprovalThreshold(opts.ApprovalThreshold)
+	}
+
+	if len(opts.PrizeBands) > 0 {
+		service.SetPrizeBands(opts.PrizeBands)
+	}
+
+	if len(opts.TierBoundaries) > 0 {
+		if err := service.SetTierBoundaries(opts.TierBoundaries); err != nil {
+			lb.Close()
+			return nil, fmt.Errorf("tier boundaries: %w", err)
+		}
+	}
+
+	if opts.Season != nil {
+		var endsAt *time.Time
+		if !opts.Season.EndsAt.IsZero() {
+			endsAt = &opts.Season.EndsAt
+		}
+		if _, err := service.StartSeason(context.Background(), opts.Season.ID, endsAt); err != nil {
+			lb.Close()
+			return nil, fmt.Errorf("season: %w", err)
+		}
+	}
+
+	if opts.Auth != nil {
+		a, err := auth.New(*opts.Auth)
+		if err != nil {
+			lb.Close()
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+		lb.handler.EnableAuth(a)
+		lb.auth = a
+	}
+
+	// Bootstrap admins are granted before they log in, to the provider's
+	// identity rather than the username it will get
+	for _, principal := range opts.Admins {
+		if err := services.CheckBootstrapAdmin(principal); err != nil {
+			lb.Close()
+			return nil, err
+		}
+		opts.Store.GrantRole(principal, services.RoleAdmin)
+	}
+
+	if err := service.ConfigureSimulation(services.SimulationConfig{
+		Enabled:   opts.SimulateUpdates,
+		Interval:  opts.SimulationInterval,
+		Target:    opts.SimulationTarget,
+		BatchSize: opts.SimulationBatchSize,
+		Jitter:    opts.SimulationJitter,
+	}); err != nil {
+		lb.Close()
+		return nil, err
+	}
+
+	strategy, err := services.RatingStrategyByName(opts.RatingStrategy)
+	if err != nil {
+		lb.Close()
+		return nil, fmt.Errorf("rating strategy: %w", err)
+	}
+	service.SetRatingStrategy(strategy)
+	service.SetTiePolicy(opts.TiePolicy)
+
+	if opts.ShadowStrategy != "" {
+		shadowStrategy, err := services.RatingStrategyByName(opts.ShadowStrategy)
+		if err != nil {
+			lb.Close()
+			return nil, fmt.Errorf("shadow rating strategy: %w", err)
+		}
+		service.EnableShadow(shadowStrategy)
+	}
+
+	return lb, nil
+}
+
+// Service returns the typed leaderboard service for in-process calls
+func (lb *Leaderboard) Service() *Service {
+	return lb.service
+}
+
+// Routes returns the HTTP routes for mounting on another router
+func (lb *Leaderboard) Routes() []Route {
+	return lb.handler.Routes()
+}
+
+// PublicRoutes returns every route outside the admin surface (/api/admin,
+// /api/moderation, identity linking, /metrics and /debug), for the listener
+// clients reach
+func (lb *Leaderboard) PublicRoutes() []Route {
+	public, _ := handlers.SplitRoutes(lb.Routes())
+	return public
+}
+
+// AdminRoutes returns the admin surface, for a listener bound to an internal
+// interface. Role checks still apply when auth is enabled.
+func (lb *Leaderboard) AdminRoutes() []Route {
+	_, admin := handlers.SplitRoutes(lb.Routes())
+	return admin
+}
+
+// StoreBackend names where the board is kept, "memory", "redis" or "sharded"
+func (lb *Leaderboard) StoreBackend() string {
+	return lb.service.StoreBackend()
+}
+
+// HealthStoreName is how /health names a store backend: "in-memory",
+// "redis" or "sharded"
+func HealthStoreName(backend string) string {
+	if backend == "memory" {
+		return "in-memory"
+	}
+	return backend
+}
+
+// Handler returns the full HTTP API, including /health
+func (lb *Leaderboard) Handler() http.Handler {
+	mux := lb.handler.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(map[string]string{
+			"status": "ok",
+			"store":  HealthStoreName(lb.StoreBackend()),
+		})
+	})
+	return mux
+}
+
+// Start runs background jobs (expiry sweeper, season scheduler, inflation
+// monitor, history compactor, daily reports, score file import, chat bots and the update
+// simulator loop, which only writes while enabled) until ctx is cancelled.
+// With Options.Warmup set, the warm-up phase runs first and /readyz passes
+// once it is done.
+func (lb *Leaderboard) Start(ctx context.Context) {
+	lb.service.RecordIncident("server", services.IncidentLifecycle, "started")
+
+	if lb.opts.Warmup {
+		lb.warmup(ctx)
+	}
+
+	// Score workers stop in Drain rather than with ctx, so queued submissions are applied
+	lb.service.StartScoreWorkers()
+
+	// The simulator loop always runs so it can be enabled through the admin API
+	go lb.service.StartRandomUpdates(ctx)
+	go lb.service.StartSeasonScheduler(ctx, time.Second)
+	go lb.service.StartInflationMonitor(ctx, lb.opts.InflationInterval)
+	go lb.service.StartHistoryCompactor(ctx, lb.opts.HistoryCompactInterval)
+	go lb.service.StartReportScheduler(ctx, time.Minute)
+	go lb.service.StartImportScheduler(ctx)
+	if lb.opts.Bots != nil {
+		// One replica connects the bots, so messages are answered and
+		// announcements posted once
+		go lb.service.RunElected(ctx, "bots", func(ctx context.Context) {
+			integrations.Run(ctx, lb.service, *lb.opts.Bots)
+		})
+	}
+	lb.service.StartExpirySweeper(ctx, lb.opts.ExpirySweepInterval)
+}
+
+// maxLoggedIssues bounds the integrity issues listed in the warm-up log
+const maxLoggedIssues = 20
+
+// warmup primes caches and checks integrity, logging a report. Integrity
+// issues are reported but don't keep the instance out of rotation.
+func (lb *Leaderboard) warmup(ctx context.Context) {
+	defer lb.handler.SetWarmingUp(false)
+
+	report, err := lb.service.Warmup(ctx)
+	if err != nil {
+		log.Printf("Warm-up failed: %v", err)
+		lb.service.RecordIncident("warmup", services.IncidentFailure, err.Error())
+		return
+	}
+
+	log.Printf("✓ Warmed up in %.1fms: %d users, caches [%s], %d integrity issue(s)",
+		report.DurationMs, report.Users, strings.Join(report.CachesWarmed, ", "), len(report.Issues))
+	for i, issue := range report.Issues {
+		if i == maxLoggedIssues {
+			log.Printf("  ... and %d more", len(report.Issues)-i)
+			break
+		}
+		log.Printf("  integrity: %s %s (rating %d)", issue.Kind, issue.Username, issue.Rating)
+	}
+
+	detail := fmt.Sprintf("warm-up complete, %d integrity issue(s)", len(report.Issues))
+	lb.service.RecordIncident("warmup", services.IncidentLifecycle, detail)
+}
+
+// Drain prepares for shutdown: /readyz starts failing, new streaming requests
+// and async submissions are rejected, active streams are ended, queued scores
+// are applied and buffered events are flushed.
+// It returns once streams and queued scores have finished or ctx expires.
+func (lb *Leaderboard) Drain(ctx context.Context) error {
+	err := lb.handler.Drain(ctx)
+	if queueErr := lb.service.DrainScoreQueue(ctx); queueErr != nil && err == nil {
+		err = fmt.Errorf("score queue: %w", queueErr)
+	}
+
+	if lb.eventLog != nil {
+		if flushErr := lb.eventLog.Flush(); flushErr != nil {
+			lb.service.RecordIncident("event_log", services.IncidentFailure, "flush failed: "+flushErr.Error())
+			if err == nil {
+				err = flushErr
+			}
+		}
+	}
+	return err
+}
+
+// Snapshot copies the board and the records kept beside it, for a restarted
+// process to take over with Restore. Take it after Drain so no writes are missed.
+func (lb *Leaderboard) Snapshot() *StateSnapshot {
+	snap := lb.service.Snapshot()
+	if lb.auth != nil {
+		snap.Sessions = lb.auth.SessionStates()
+	}
+	return snap
+}
+
+// Restore replaces the state with a snapshot from the process being
+// replaced. Call it before Start and before serving requests.
+func (lb *Leaderboard) Restore(snap *StateSnapshot) error {
+	if err := lb.service.RestoreSnapshot(snap); err != nil {
+		return err
+	}
+	if lb.auth != nil {
+		lb.auth.RestoreSessions(snap.Sessions)
+	}
+	return nil
+}
+
+// Close releases resources such as the event log file
+func (lb *Leaderboard) Close() error {
+	lb.service.Realtime().Close()
+
+	if lb.eventLog != nil {
+		return lb.eventLog.Close()
+	}
+	return nil
+}
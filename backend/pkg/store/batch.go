@@ -0,0 +1,111 @@
+package store
+
+import (
+	"errors"
+	"slices"
+)
+
+// RatingChange is one user's rating as changed by UpdateRatings
+type RatingChange struct {
+	Username string
+	Bot      bool
+	Previous int
+	Rating   int
+
+	PreviousKey int64 // Sort key before the change, to revert it with RevertRating
+}
+
+// UpdateRatings sets the ratings of existing users under a single lock, for
+// background jobs that touch many members at once. Users that no longer
+// exist are skipped; nobody is added or evicted. Changes are returned in
+// the order of usernames.
+func (s *MemoryStore) UpdateRatings(usernames []string, ratings map[string]int) []RatingChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes := make([]RatingChange, 0, len(usernames))
+	for _, username := range usernames {
+		rating, ok := ratings[username]
+		if !ok {
+			continue
+		}
+		existing, exists := s.users[username]
+		if !exists {
+			continue
+		}
+
+		// Users are replaced rather than mutated so readers holding the old pointer stay consistent
+		user := *existing
+		user.Rating = rating
+		user.Key = s.keyFor(rating)
+		s.unindex(existing)
+		s.users[username] = &user
+		s.index(&user)
+
+		changes = append(changes, RatingChange{
+			Username: username,
+			Bot:      user.Bot,
+			Previous: existing.Rating,
+			Rating:   rating,
+
+			PreviousKey: existing.Key,
+		})
+	}
+	return changes
+}
+
+// NewBot is a bot user to add with AddBots
+type NewBot struct {
+	Username string
+	Rating   int
+}
+
+// AddBots adds or updates bot users under a single lock, for bulk jobs such
+// as seeding. Bots below a capped board's cutoff are skipped. Writing stops
+// at the first other error, which is returned with the bots written so far,
+// in the order of bots.
+func (s *MemoryStore) AddBots(bots []NewBot) (written []NewBot, err error) {
+	var evicted []*User
+	created := make(map[string]bool, len(bots))
+
+	s.mu.Lock()
+	written = make([]NewBot, 0, len(bots))
+	for _, bot := range bots {
+		_, exists := s.users[bot.Username]
+		out, werr := s.write(bot.Username, bot.Rating, true, false)
+		evicted = append(evicted, out...)
+		if errors.Is(werr, ErrBelowCutoff) {
+			continue
+		}
+		if werr != nil {
+			err = werr
+			break
+		}
+		if !exists {
+			created[bot.Username] = true
+		}
+		written = append(written, bot)
+	}
+	onEvict := s.onEvict
+	s.mu.Unlock()
+
+	// A bot created and then evicted within the batch is reported as neither
+	gone := make(map[string]bool)
+	evicted = slices.DeleteFunc(evicted, func(user *User) bool {
+		if created[user.Username] {
+			gone[user.Username] = true
+			return true
+		}
+		return false
+	})
+	if len(gone) > 0 {
+		written = slices.DeleteFunc(written, func(bot NewBot) bool { return gone[bot.Username] })
+	}
+
+	if onEvict != nil {
+		for _, user := range evicted {
+			onEvict(user)
+		}
+	}
+	return written, err
+}
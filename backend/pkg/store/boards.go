@@ -0,0 +1,364 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+
+	"backend/internal/ranking"
+)
+
+// Changes a BoardOp makes to a derived board member
+const (
+	OpSet    = "set"    // Set the member's score to Value
+	OpAdd    = "add"    // Add Value to the member's score, from 0 for a new member
+	OpRemove = "remove" // Remove the member
+	OpPlace  = "place"  // Place the member on Profile's country and team; needs no board
+)
+
+// BoardOp is one change to a member of a derived board
+type BoardOp struct {
+	Op      string
+	Board   string // e.g. "daily:2025-01-01", "country:US" or "teams"
+	Member  string
+	Value   int
+	Profile BoardProfile // For OpPlace; the zero profile places the member nowhere
+}
+
+// BoardProfile is what places a user on the country and team boards
+type BoardProfile struct {
+	Country string `json:"country,omitempty"`
+	Team    string `json:"team,omitempty"`
+}
+
+// validate reports ops that can't be applied, before any is
+func (op BoardOp) validate() error {
+	switch {
+	case op.Op == OpPlace:
+		if op.Member == "" {
+			return errors.New("board op needs a member")
+		}
+		if strings.Contains(op.Profile.Country, profileSeparator) {
+			return fmt.Errorf("country %q can't be stored", op.Profile.Country)
+		}
+	case op.Op != OpSet && op.Op != OpAdd && op.Op != OpRemove:
+		return fmt.Errorf("unknown board op %q", op.Op)
+	case op.Board == "" || op.Member == "":
+		return errors.New("board op needs a board and a member")
+	}
+	return nil
+}
+
+// Boards holds derived boards, kept alongside the main board: daily windows,
+// countries and team aggregates. Each is ranked by score like the main board.
+// Apply makes a set of ops atomically, so a match result lands on every
+// board it touches or on none. Where each user is placed is kept with the
+// boards, and moved by the same ops, so the two can't disagree.
+type Boards interface {
+	Apply(ctx context.Context, ops []BoardOp) error
+	// Profile returns where member is placed, the zero profile if nowhere
+	Profile(ctx context.Context, member string) (BoardProfile, error)
+	// Profiles returns where every placed member is
+	Profiles(ctx context.Context) (map[string]BoardProfile, error)
+	// TeamSize returns how many members are placed on team
+	TeamSize(ctx context.Context, team string) (int, error)
+	// Page returns limit members of board from offset, with the board's size
+	Page(ctx context.Context, board string, offset, limit int) (page []RankedUser, total int, err error)
+	// Rank returns a member's score and rank, or ErrUserNotFound
+	Rank(ctx context.Context, board, member string) (RankedUser, error)
+	// List returns every board with its member count
+	List(ctx context.Context) (map[string]int, error)
+	// Drop deletes boards, such as daily windows past retention
+	Drop(ctx context.Context, boards ...string) error
+}
+
+// MemoryBoards keeps derived boards in process memory. Memory has no
+// transactions, so Apply compensates instead: if an op fails, the ones
+// already made are undone in reverse order.
+type MemoryBoards struct {
+	mu        sync.RWMutex
+	boards    map[string]*MemoryStore
+	profiles  map[string]BoardProfile
+	teamSizes map[string]int
+}
+
+// NewMemoryBoards creates an empty set of in-memory derived boards
+func NewMemoryBoards() *MemoryBoards {
+	return &MemoryBoards{
+		boards:    make(map[string]*MemoryStore),
+		profiles:  make(map[string]BoardProfile),
+		teamSizes: make(map[string]int),
+	}
+}
+
+// Apply makes ops in order under one lock, so readers see all of them or
+// none, and puts every member back as it was if one fails
+func (b *MemoryBoards) Apply(ctx context.Context, ops []BoardOp) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	undo := make([]func(), 0, len(ops))
+	for _, op := range ops {
+		revert, err := b.apply(op)
+		if err != nil {
+			for i := len(undo) - 1; i >= 0; i-- {
+				undo[i]()
+			}
+			return fmt.Errorf("%s %s on %s: %w", op.Op, op.Member, op.Board, err)
+		}
+		undo = append(undo, revert)
+	}
+	return nil
+}
+
+// apply makes one op and returns how to undo it. Must be called with the
+// lock held.
+func (b *MemoryBoards) apply(op BoardOp) (func(), error) {
+	if err := op.validate(); err != nil {
+		return nil, err
+	}
+	if op.Op == OpPlace {
+		previous := b.profiles[op.Member]
+		b.place(op.Member, op.Profile)
+		return func() { b.place(op.Member, previous) }, nil
+	}
+	previous, existed := b.score(op.Board, op.Member)
+
+	var err error
+	switch op.Op {
+	case OpSet:
+		err = b.put(op.Board, op.Member, op.Value)
+	case OpAdd:
+		err = b.put(op.Board, op.Member, previous+op.Value)
+	case OpRemove:
+		if existed {
+			err = b.remove(op.Board, op.Member)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		if existed {
+			b.put(op.Board, op.Member, previous)
+		} else {
+			b.remove(op.Board, op.Member)
+		}
+	}, nil
+}
+
+// place moves member to profile, counting team sizes. Must be called with
+// the lock held.
+func (b *MemoryBoards) place(member string, profile BoardProfile) {
+	previous := b.profiles[member]
+	if profile == (BoardProfile{}) {
+		delete(b.profiles, member)
+	} else {
+		b.profiles[member] = profile
+	}
+	if previous.Team == profile.Team {
+		return
+	}
+	if previous.Team != "" {
+		b.teamSizes[previous.Team]--
+		if b.teamSizes[previous.Team] <= 0 {
+			delete(b.teamSizes, previous.Team)
+		}
+	}
+	if profile.Team != "" {
+		b.teamSizes[profile.Team]++
+	}
+}
+
+// score returns a member's score on board, if they're on it. Must be called
+// with the lock held.
+func (b *MemoryBoards) score(board, member string) (int, bool) {
+	s, ok := b.boards[board]
+	if !ok {
+		return 0, false
+	}
+	user, err := s.GetUser(member)
+	if err != nil {
+		return 0, false
+	}
+	return user.Rating, true
+}
+
+// put sets a member's score, creating the board if it's new. Must be called
+// with the lock held.
+func (b *MemoryBoards) put(board, member string, value int) error {
+	s, ok := b.boards[board]
+	if !ok {
+		s = NewMemoryStore()
+		b.boards[board] = s
+	}
+	err := s.AddUser(member, value)
+	b.dropEmpty(board)
+	return err
+}
+
+// remove takes a member off board, forgetting the board once it's empty.
+// Must be called with the lock held.
+func (b *MemoryBoards) remove(board, member string) error {
+	s, ok := b.boards[board]
+	if !ok {
+		return nil
+	}
+	err := s.RemoveUser(member)
+	b.dropEmpty(board)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil
+	}
+	return err
+}
+
+// dropEmpty forgets a board once its last member leaves. Must be called with
+// the lock held.
+func (b *MemoryBoards) dropEmpty(board string) {
+	if s, ok := b.boards[board]; ok && s.GetUserCount() == 0 {
+		delete(b.boards, board)
+	}
+}
+
+// Page returns limit members of board from offset, ties sharing a rank
+func (b *MemoryBoards) Page(ctx context.Context, board string, offset, limit int) ([]RankedUser, int, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	s, ok := b.boards[board]
+	if !ok {
+		return []RankedUser{}, 0, nil
+	}
+	members, err := s.GetAllUsers(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	end := min(offset+limit, len(members))
+	if offset >= end {
+		return []RankedUser{}, len(members), nil
+	}
+	page := make([]RankedUser, 0, end-offset)
+	var ranks ranking.Counter
+	for i, member := range members[:end] {
+		rank := ranks.Next(member.Key)
+		if i >= offset {
+			page = append(page, RankedUser{User: member, Rank: rank})
+		}
+	}
+	return page, len(members), nil
+}
+
+// Rank returns a member's score and rank on board
+func (b *MemoryBoards) Rank(ctx context.Context, board, member string) (RankedUser, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	s, ok := b.boards[board]
+	if !ok {
+		return RankedUser{}, ErrUserNotFound
+	}
+	user, err := s.GetUser(member)
+	if err != nil {
+		return RankedUser{}, err
+	}
+	rank, err := s.GetUserRank(ctx, member)
+	if err != nil {
+		return RankedUser{}, err
+	}
+	return RankedUser{User: user, Rank: rank}, nil
+}
+
+// Profile returns where member is placed
+func (b *MemoryBoards) Profile(ctx context.Context, member string) (BoardProfile, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.profiles[member], nil
+}
+
+// BoardsSnapshot copies in-memory derived boards and placements, for a
+// restarted process to take over
+type BoardsSnapshot struct {
+	Boards   map[string]map[string]int `json:"boards,omitempty"` // Board -> member -> score
+	Profiles map[string]BoardProfile   `json:"profiles,omitempty"`
+}
+
+// Snapshot copies every board and placement
+func (b *MemoryBoards) Snapshot() BoardsSnapshot {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	snap := BoardsSnapshot{
+		Boards:   make(map[string]map[string]int, len(b.boards)),
+		Profiles: maps.Clone(b.profiles),
+	}
+	for board, s := range b.boards {
+		members := make(map[string]int)
+		for _, user := range s.Snapshot().Users {
+			members[user.Username] = user.Rating
+		}
+		snap.Boards[board] = members
+	}
+	return snap
+}
+
+// Restore replaces every board and placement with snap's, recounting team
+// sizes
+func (b *MemoryBoards) Restore(snap BoardsSnapshot) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.boards = make(map[string]*MemoryStore, len(snap.Boards))
+	b.profiles = make(map[string]BoardProfile, len(snap.Profiles))
+	b.teamSizes = make(map[string]int)
+	for board, members := range snap.Boards {
+		for member, score := range members {
+			if err := b.put(board, member, score); err != nil {
+				return fmt.Errorf("restore %s on %s: %w", member, board, err)
+			}
+		}
+	}
+	for member, profile := range snap.Profiles {
+		b.place(member, profile)
+	}
+	return nil
+}
+
+// Profiles returns where every placed member is
+func (b *MemoryBoards) Profiles(ctx context.Context) (map[string]BoardProfile, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return maps.Clone(b.profiles), nil
+}
+
+// TeamSize returns how many members are placed on team
+func (b *MemoryBoards) TeamSize(ctx context.Context, team string) (int, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.teamSizes[team], nil
+}
+
+// List returns every board with its member count
+func (b *MemoryBoards) List(ctx context.Context) (map[string]int, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	boards := make(map[string]int, len(b.boards))
+	for name, s := range b.boards {
+		boards[name] = s.GetUserCount()
+	}
+	return boards, nil
+}
+
+// Drop deletes boards
+func (b *MemoryBoards) Drop(ctx context.Context, boards ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, board := range boards {
+		delete(b.boards, board)
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+
+	"backend/internal/ranking"
+)
+
+// RedisBoards keeps derived boards on Redis, each a sorted set of members by
+// negated score like ShardedStore's, with a set naming the boards that have
+// members. Where members are placed is a hash of member to country and team,
+// beside a hash counting each team's members. Apply runs every op in one Lua
+// script, which Redis runs without interleaving other commands. Redis
+// doesn't roll a script back when a command fails partway, so the ops and
+// the type of every key they touch are checked before any is made: a bad op,
+// or a key holding something else, changes nothing.
+type RedisBoards struct {
+	client    redis.UniversalClient
+	prefix    string // Of each board's key
+	registry  string // Set of the boards with members
+	profiles  string // Hash of member -> country and team, joined by profileSeparator
+	teamSizes string // Hash of team -> members placed on it
+}
+
+// profileSeparator joins a profile's country and team. Countries are codes,
+// so never contain it; teams may, as the country is split off first.
+const profileSeparator = "\t"
+
+// NewRedisBoards keeps derived boards on client, under keys starting with
+// prefix
+func NewRedisBoards(client redis.UniversalClient, prefix string) *RedisBoards {
+	return &RedisBoards{
+		client:    client,
+		prefix:    prefix + "board:",
+		registry:  prefix + "boards",
+		profiles:  prefix + "board_profiles",
+		teamSizes: prefix + "board_team_sizes",
+	}
+}
+
+func (b *RedisBoards) key(board string) string {
+	return b.prefix + board
+}
+
+// applyBoardsScript makes board ops atomically.
+// KEYS: the registry, profiles and team sizes, then each op's board key
+// (the profiles again for place ops).
+// ARGV: op, board, member, value, country and team for each op in turn.
+var applyBoardsScript = redis.NewScript(`
+local function check(key, want)
+	local have = redis.call('TYPE', key).ok
+	if have ~= 'none' and have ~= want then
+		return redis.error_reply('WRONGTYPE ' .. key .. ' holds a ' .. have .. ', not a ' .. want)
+	end
+end
+local err = check(KEYS[1], 'set') or check(KEYS[2], 'hash') or check(KEYS[3], 'hash')
+if err then return err end
+
+local n = #KEYS - 3
+for i = 1, n do
+	local op = ARGV[i * 6 - 5]
+	if op ~= 'set' and op ~= 'add' and op ~= 'remove' and op ~= 'place' then
+		return redis.error_reply('unknown board op ' .. op)
+	end
+	if not tonumber(ARGV[i * 6 - 2]) then
+		return redis.error_reply('board op value must be a number')
+	end
+	if op ~= 'place' then
+		err = check(KEYS[i + 3], 'zset')
+		if err then return err end
+	end
+end
+
+for i = 1, n do
+	local op, key, board, member = ARGV[i * 6 - 5], KEYS[i + 3], ARGV[i * 6 - 4], ARGV[i * 6 - 3]
+	if op == 'place' then
+		local country, team = ARGV[i * 6 - 1], ARGV[i * 6]
+		local previous = redis.call('HGET', KEYS[2], member)
+		local previousTeam = ''
+		if previous then
+			previousTeam = string.sub(previous, string.find(previous, '\t', 1, true) + 1)
+		end
+		if country == '' and team == '' then
+			redis.call('HDEL', KEYS[2], member)
+		else
+			redis.call('HSET', KEYS[2], member, country .. '\t' .. team)
+		end
+		if previousTeam ~= team then
+			if previousTeam ~= '' and redis.call('HINCRBY', KEYS[3], previousTeam, -1) <= 0 then
+				redis.call('HDEL', KEYS[3], previousTeam)
+			end
+			if team ~= '' then
+				redis.call('HINCRBY', KEYS[3], team, 1)
+			end
+		end
+	else
+		local score = -tonumber(ARGV[i * 6 - 2])
+		if op == 'set' then
+			redis.call('ZADD', key, score, member)
+		elseif op == 'add' then
+			redis.call('ZINCRBY', key, score, member)
+		else
+			redis.call('ZREM', key, member)
+		end
+		if redis.call('EXISTS', key) == 1 then
+			redis.call('SADD', KEYS[1], board)
+		else
+			redis.call('SREM', KEYS[1], board)
+		end
+	end
+end
+return n
+`)
+
+// Apply makes every op or, if one is invalid or Redis fails, none of them
+func (b *RedisBoards) Apply(ctx context.Context, ops []BoardOp) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(ops)+3)
+	keys = append(keys, b.registry, b.profiles, b.teamSizes)
+	args := make([]any, 0, len(ops)*6)
+	for _, op := range ops {
+		if err := op.validate(); err != nil {
+			return err
+		}
+		key := b.profiles
+		if op.Op != OpPlace {
+			key = b.key(op.Board)
+		}
+		keys = append(keys, key)
+		args = append(args, op.Op, op.Board, op.Member, op.Value, op.Profile.Country, op.Profile.Team)
+	}
+	return applyBoardsScript.Run(ctx, b.client, keys, args...).Err()
+}
+
+// Profile returns where member is placed
+func (b *RedisBoards) Profile(ctx context.Context, member string) (BoardProfile, error) {
+	joined, err := b.client.HGet(ctx, b.profiles, member).Result()
+	if errors.Is(err, redis.Nil) {
+		return BoardProfile{}, nil
+	}
+	if err != nil {
+		return BoardProfile{}, err
+	}
+	return splitProfile(joined), nil
+}
+
+// Profiles returns where every placed member is
+func (b *RedisBoards) Profiles(ctx context.Context) (map[string]BoardProfile, error) {
+	profiles := make(map[string]BoardProfile)
+	iter := b.client.HScan(ctx, b.profiles, 0, "", redisLoadBatch).Iterator()
+	for iter.Next(ctx) {
+		member := iter.Val()
+		if !iter.Next(ctx) {
+			break
+		}
+		profiles[member] = splitProfile(iter.Val())
+	}
+	return profiles, iter.Err()
+}
+
+func splitProfile(joined string) BoardProfile {
+	country, team, _ := strings.Cut(joined, profileSeparator)
+	return BoardProfile{Country: country, Team: team}
+}
+
+// TeamSize returns how many members are placed on team
+func (b *RedisBoards) TeamSize(ctx context.Context, team string) (int, error) {
+	n, err := b.client.HGet(ctx, b.teamSizes, team).Int()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return n, err
+}
+
+// Page returns limit members of board from offset, ties sharing a rank
+func (b *RedisBoards) Page(ctx context.Context, board string, offset, limit int) ([]RankedUser, int, error) {
+	key := b.key(board)
+	// Read the member just ahead of the page too, to see if they tie
+	from := max(offset-1, 0)
+	var entries *redis.ZSliceCmd
+	var total *redis.IntCmd
+	_, err := b.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		entries = pipe.ZRangeWithScores(ctx, key, int64(from), int64(offset+limit-1))
+		total = pipe.ZCard(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	members := make([]*User, 0, len(entries.Val()))
+	for _, z := range entries.Val() {
+		members = append(members, newShardedUser(z.Member.(string), int(-z.Score), false))
+	}
+	var before *User
+	if from < offset && len(members) > 0 {
+		before, members = members[0], members[1:]
+	}
+	if len(members) == 0 {
+		return []RankedUser{}, int(total.Val()), nil
+	}
+	page, err := rankPage(ctx, members, offset, before, func(ctx context.Context, rating int) (int, error) {
+		n, err := b.client.ZCount(ctx, key, "-inf", below(rating)).Result()
+		return int(n), err
+	})
+	return page, int(total.Val()), err
+}
+
+// Rank returns a member's score and rank on board
+func (b *RedisBoards) Rank(ctx context.Context, board, member string) (RankedUser, error) {
+	key := b.key(board)
+	score, err := b.client.ZScore(ctx, key, member).Result()
+	if errors.Is(err, redis.Nil) {
+		return RankedUser{}, ErrUserNotFound
+	}
+	if err != nil {
+		return RankedUser{}, err
+	}
+	user := newShardedUser(member, int(-score), false)
+	higher, err := b.client.ZCount(ctx, key, "-inf", below(user.Rating)).Result()
+	if err != nil {
+		return RankedUser{}, err
+	}
+	return RankedUser{User: user, Rank: ranking.FromHigher(int(higher))}, nil
+}
+
+// List returns every board with its member count
+func (b *RedisBoards) List(ctx context.Context) (map[string]int, error) {
+	names, err := b.client.SMembers(ctx, b.registry).Result()
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]*redis.IntCmd, len(names))
+	_, err = b.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, name := range names {
+			counts[i] = pipe.ZCard(ctx, b.key(name))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	boards := make(map[string]int, len(names))
+	for i, name := range names {
+		if n := counts[i].Val(); n > 0 {
+			boards[name] = int(n)
+		}
+	}
+	return boards, nil
+}
+
+// Drop deletes boards
+func (b *RedisBoards) Drop(ctx context.Context, boards ...string) error {
+	if len(boards) == 0 {
+		return nil
+	}
+	keys := make([]string, len(boards))
+	names := make([]any, len(boards))
+	for i, board := range boards {
+		keys[i] = b.key(board)
+		names[i] = board
+	}
+	_, err := b.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, keys...)
+		pipe.SRem(ctx, b.registry, names...)
+		return nil
+	})
+	return err
+}
+
+// Close closes the Redis client
+func (b *RedisBoards) Close() error {
+	return b.client.Close()
+}
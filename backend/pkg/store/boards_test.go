@@ -0,0 +1,227 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"backend/internal/ranking"
+)
+
+// boardsBackends are the Boards implementations every test runs against
+var boardsBackends = []struct {
+	name string
+	open func(t *testing.T) (Boards, *miniredis.Miniredis)
+}{
+	{"memory", func(t *testing.T) (Boards, *miniredis.Miniredis) {
+		return NewMemoryBoards(), nil
+	}},
+	{"redis", func(t *testing.T) (Boards, *miniredis.Miniredis) {
+		mr := miniredis.RunT(t)
+		b := NewRedisBoards(redis.NewClient(&redis.Options{Addr: mr.Addr()}), testPrefix)
+		t.Cleanup(func() { b.Close() })
+		return b, mr
+	}},
+}
+
+// scores returns every member of board with their score
+func scores(t *testing.T, b Boards, board string) map[string]int {
+	t.Helper()
+	page, _, err := b.Page(context.Background(), board, 0, 1000)
+	if err != nil {
+		t.Fatalf("Page(%s): %v", board, err)
+	}
+	got := make(map[string]int, len(page))
+	for _, member := range page {
+		got[member.User.Username] = member.User.Rating
+	}
+	return got
+}
+
+func TestBoardsApply(t *testing.T) {
+	for _, backend := range boardsBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			b, _ := backend.open(t)
+			ctx := context.Background()
+
+			err := b.Apply(ctx, []BoardOp{
+				{Op: OpSet, Board: "country:US", Member: "alice", Value: 1500},
+				{Op: OpAdd, Board: "daily:2025-01-01", Member: "alice", Value: 40},
+				{Op: OpAdd, Board: "daily:2025-01-01", Member: "alice", Value: -10},
+				{Op: OpAdd, Board: "teams", Member: "red", Value: 1500},
+				{Op: OpPlace, Member: "alice", Profile: BoardProfile{Country: "US", Team: "red"}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := scores(t, b, "daily:2025-01-01"); got["alice"] != 30 {
+				t.Errorf("daily score %d, want 30", got["alice"])
+			}
+			if got, _ := b.Profile(ctx, "alice"); got != (BoardProfile{Country: "US", Team: "red"}) {
+				t.Errorf("alice placed on %+v", got)
+			}
+			if got, _ := b.TeamSize(ctx, "red"); got != 1 {
+				t.Errorf("red has %d members, want 1", got)
+			}
+
+			// Moving team recounts both
+			if err := b.Apply(ctx, []BoardOp{{Op: OpPlace, Member: "alice", Profile: BoardProfile{Country: "US", Team: "blue\tgreen"}}}); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := b.TeamSize(ctx, "red"); got != 0 {
+				t.Errorf("red has %d members after alice left, want 0", got)
+			}
+			if got, _ := b.Profile(ctx, "alice"); got.Team != "blue\tgreen" {
+				t.Errorf("alice's team is %q", got.Team)
+			}
+
+			// The zero profile places nobody, and removing the last member drops a board
+			err = b.Apply(ctx, []BoardOp{
+				{Op: OpRemove, Board: "country:US", Member: "alice"},
+				{Op: OpPlace, Member: "alice"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			profiles, _ := b.Profiles(ctx)
+			if len(profiles) != 0 {
+				t.Errorf("profiles left: %v", profiles)
+			}
+			boards, _ := b.List(ctx)
+			if _, ok := boards["country:US"]; ok {
+				t.Errorf("empty board still listed: %v", boards)
+			}
+		})
+	}
+}
+
+func TestBoardsApplyIsAllOrNothing(t *testing.T) {
+	for _, backend := range boardsBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			b, _ := backend.open(t)
+			ctx := context.Background()
+			before := []BoardOp{
+				{Op: OpSet, Board: "country:US", Member: "alice", Value: 1500},
+				{Op: OpAdd, Board: "teams", Member: "red", Value: 1500},
+				{Op: OpPlace, Member: "alice", Profile: BoardProfile{Country: "US", Team: "red"}},
+			}
+			if err := b.Apply(ctx, before); err != nil {
+				t.Fatal(err)
+			}
+
+			// Every op before the bad one is undone
+			err := b.Apply(ctx, []BoardOp{
+				{Op: OpSet, Board: "country:US", Member: "alice", Value: 1700},
+				{Op: OpRemove, Board: "country:US", Member: "alice"},
+				{Op: OpSet, Board: "country:FR", Member: "bob", Value: 1200},
+				{Op: OpAdd, Board: "teams", Member: "red", Value: 200},
+				{Op: OpPlace, Member: "alice", Profile: BoardProfile{Country: "FR", Team: "blue"}},
+				{Op: "double", Board: "teams", Member: "red"},
+			})
+			if err == nil {
+				t.Fatal("Apply succeeded with an unknown op")
+			}
+			if got := scores(t, b, "country:US"); !maps.Equal(got, map[string]int{"alice": 1500}) {
+				t.Errorf("country:US is %v, want alice at 1500", got)
+			}
+			if got := scores(t, b, "teams"); !maps.Equal(got, map[string]int{"red": 1500}) {
+				t.Errorf("teams is %v, want red at 1500", got)
+			}
+			boards, _ := b.List(ctx)
+			if _, ok := boards["country:FR"]; ok {
+				t.Error("country:FR was created by a failed Apply")
+			}
+			if got, _ := b.Profile(ctx, "alice"); got != (BoardProfile{Country: "US", Team: "red"}) {
+				t.Errorf("alice moved to %+v by a failed Apply", got)
+			}
+			if got, _ := b.TeamSize(ctx, "blue"); got != 0 {
+				t.Errorf("blue has %d members after a failed Apply", got)
+			}
+		})
+	}
+}
+
+func TestRedisBoardsCheckKeyTypesBeforeWriting(t *testing.T) {
+	b, mr := boardsBackends[1].open(t)
+	ctx := context.Background()
+	mr.Set(testPrefix+"board:teams", "not a sorted set")
+
+	err := b.Apply(ctx, []BoardOp{
+		{Op: OpSet, Board: "country:US", Member: "alice", Value: 1500},
+		{Op: OpPlace, Member: "alice", Profile: BoardProfile{Country: "US", Team: "red"}},
+		{Op: OpAdd, Board: "teams", Member: "red", Value: 1500},
+	})
+	if err == nil {
+		t.Fatal("Apply succeeded with a board key holding a string")
+	}
+	if mr.Exists(testPrefix + "board:country:US") {
+		t.Error("country:US was written before the failing op")
+	}
+	if got, _ := b.Profile(ctx, "alice"); got != (BoardProfile{}) {
+		t.Errorf("alice placed on %+v by a failed Apply", got)
+	}
+}
+
+func TestBoardOpValidate(t *testing.T) {
+	cases := []struct {
+		name string
+		op   BoardOp
+		ok   bool
+	}{
+		{"set", BoardOp{Op: OpSet, Board: "teams", Member: "red"}, true},
+		{"place needs no board", BoardOp{Op: OpPlace, Member: "alice"}, true},
+		{"unknown op", BoardOp{Op: "double", Board: "teams", Member: "red"}, false},
+		{"no board", BoardOp{Op: OpAdd, Member: "red"}, false},
+		{"no member", BoardOp{Op: OpRemove, Board: "teams"}, false},
+		{"place without member", BoardOp{Op: OpPlace}, false},
+		{"country with separator", BoardOp{Op: OpPlace, Member: "alice", Profile: BoardProfile{Country: "U\tS"}}, false},
+	}
+	for _, c := range cases {
+		if err := c.op.validate(); (err == nil) != c.ok {
+			t.Errorf("%s: validate() = %v, want ok %v", c.name, err, c.ok)
+		}
+	}
+}
+
+func TestRevertRating(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.SetTiePolicy(ranking.MostRecentFirst, func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	})
+	if err := s.AddUser("alice", 1500); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := s.GetUser("alice")
+	copied := *before
+
+	if err := s.AddUser("alice", 1700); err != nil {
+		t.Fatal(err)
+	}
+	if reverted, err := s.RevertRating(&copied, 1700); err != nil || !reverted {
+		t.Fatalf("RevertRating = %v, %v; want true", reverted, err)
+	}
+	if got, _ := s.GetUser("alice"); got.Rating != 1500 || got.Key != copied.Key {
+		t.Errorf("alice reverted to %+v, want %+v with its earlier stamp", got, copied)
+	}
+
+	// A write made after the one being reverted is kept
+	if err := s.AddUser("alice", 1800); err != nil {
+		t.Fatal(err)
+	}
+	if reverted, _ := s.RevertRating(&copied, 1700); reverted {
+		t.Error("RevertRating overwrote a later write")
+	}
+	if got, _ := s.GetUser("alice"); got.Rating != 1800 {
+		t.Errorf("alice is at %d, want 1800", got.Rating)
+	}
+	if _, err := s.RevertRating(&User{Username: "nobody"}, 1); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("RevertRating of a missing user = %v", err)
+	}
+}
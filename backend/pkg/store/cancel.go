@@ -0,0 +1,15 @@
+package store
+
+import "context"
+
+// cancelCheckInterval is how many users a scan visits between checks of its context
+const cancelCheckInterval = 1024
+
+// scanCancelled returns ctx's error on every cancelCheckInterval-th step of
+// a scan, so long scans stop soon after their caller gives up
+func scanCancelled(ctx context.Context, step int) error {
+	if step%cancelCheckInterval != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
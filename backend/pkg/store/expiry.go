@@ -0,0 +1,66 @@
+package store
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryItem is a scheduled expiry. Items are not removed when a user's
+// expiry changes; stale items are skipped when they reach the front.
+type expiryItem struct {
+	username  string
+	expiresAt time.Time
+}
+
+// expiryIndex is a min-heap of expiry items ordered by time
+type expiryIndex []expiryItem
+
+func (h expiryIndex) Len() int           { return len(h) }
+func (h expiryIndex) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h expiryIndex) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *expiryIndex) Push(x any)        { *h = append(*h, x.(expiryItem)) }
+func (h *expiryIndex) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// SetExpiry schedules a user's entry to be removed at expiresAt
+func (s *MemoryStore) SetExpiry(username string, expiresAt time.Time) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.users[username]
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	// Users are replaced rather than mutated so readers holding the old pointer stay consistent
+	user := *existing
+	user.ExpiresAt = expiresAt
+	s.users[username] = &user
+	heap.Push(&s.expiry, expiryItem{username: username, expiresAt: expiresAt})
+	return &user, nil
+}
+
+// RemoveExpired deletes every entry whose expiry is at or before now and returns them
+func (s *MemoryStore) RemoveExpired(now time.Time) []*User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed []*User
+	for s.expiry.Len() > 0 && !s.expiry[0].expiresAt.After(now) {
+		item := heap.Pop(&s.expiry).(expiryItem)
+
+		user, exists := s.users[item.username]
+		if !exists || !user.ExpiresAt.Equal(item.expiresAt) {
+			continue // stale: user removed or expiry rescheduled
+		}
+
+		s.remove(user)
+		removed = append(removed, user)
+	}
+
+	return removed
+}
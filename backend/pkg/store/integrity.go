@@ -0,0 +1,79 @@
+package store
+
+import "sort"
+
+// Integrity issue kinds reported by CheckIntegrity
+const (
+	IssueKeyMismatch   = "key_mismatch"   // A user stored under another username
+	IssueOrphanedIndex = "orphaned_index" // A rating index entry without a user
+	IssueUnindexed     = "unindexed_user" // A user missing from the rating index
+)
+
+// IntegrityIssue is an inconsistency between the user table and its indexes
+type IntegrityIssue struct {
+	Kind     string
+	Username string
+	Rating   int
+}
+
+// CheckIntegrity cross-checks users against the rating index and returns
+// every inconsistency, sorted by username
+func (s *MemoryStore) CheckIntegrity() []IntegrityIssue {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.integrityIssues()
+}
+
+// RepairIntegrity returns the same issues as CheckIntegrity and fixes them:
+// a user's table key is taken as its username and the rating index is
+// rebuilt from the user table
+func (s *MemoryStore) RepairIntegrity() []IntegrityIssue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issues := s.integrityIssues()
+	if len(issues) == 0 {
+		return issues
+	}
+
+	s.resetIndex()
+	for key, user := range s.users {
+		if user.Username != key {
+			// Users are replaced rather than mutated so readers holding the old pointer stay consistent
+			fixed := *user
+			fixed.Username = key
+			user = &fixed
+			s.users[key] = user
+		}
+		s.index(user)
+	}
+	return issues
+}
+
+// integrityIssues must be called with the lock held
+func (s *MemoryStore) integrityIssues() []IntegrityIssue {
+	var issues []IntegrityIssue
+	for key, user := range s.users {
+		if user.Username != key {
+			issues = append(issues, IntegrityIssue{Kind: IssueKeyMismatch, Username: key, Rating: user.Rating})
+		}
+		if _, ok := s.byRating[user.Rating][key]; !ok {
+			issues = append(issues, IntegrityIssue{Kind: IssueUnindexed, Username: key, Rating: user.Rating})
+		}
+	}
+	for rating, bucket := range s.byRating {
+		for username := range bucket {
+			if user, ok := s.users[username]; !ok || user.Rating != rating {
+				issues = append(issues, IntegrityIssue{Kind: IssueOrphanedIndex, Username: username, Rating: rating})
+			}
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Username != issues[j].Username {
+			return issues[i].Username < issues[j].Username
+		}
+		return issues[i].Kind < issues[j].Kind
+	})
+	return issues
+}
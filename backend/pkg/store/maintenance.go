@@ -0,0 +1,33 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// Maintenance is the API's maintenance mode. Like roles it is kept beside
+// the users but not touched by Clear.
+type Maintenance struct {
+	Mode    string // Empty when the API is fully up
+	Message string
+	Since   time.Time
+}
+
+type maintenanceState struct {
+	mu      sync.RWMutex
+	current Maintenance
+}
+
+// SetMaintenance replaces the maintenance mode
+func (s *MemoryStore) SetMaintenance(m Maintenance) {
+	s.maintenance.mu.Lock()
+	defer s.maintenance.mu.Unlock()
+	s.maintenance.current = m
+}
+
+// Maintenance returns the maintenance mode
+func (s *MemoryStore) Maintenance() Maintenance {
+	s.maintenance.mu.RLock()
+	defer s.maintenance.mu.RUnlock()
+	return s.maintenance.current
+}
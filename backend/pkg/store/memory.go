@@ -1,43 +1,420 @@
 package store
 
 import (
-	"fmt"
-	"sort"
-	"strings"
+	"container/heap"
+	"context"
+	"errors"
+	"slices"
 	"sync"
+	"time"
+
+	"backend/internal/ranking"
 )
 
 // User represents a user in the leaderboard
 type User struct {
-	Username string
-	Rating   int
+	Username  string
+	Rating    int
+	Key       int64     // Sort key from ranking.Key, set whenever Rating changes
+	Bot       bool      // Seeded or simulated user
+	ExpiresAt time.Time // Zero means the entry never expires
 }
 
+// ErrUserNotFound is returned when a username is not on the board
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrUserExists is returned when creating a username that is already taken
+var ErrUserExists = errors.New("user already exists")
+
+// ErrBelowCutoff is returned when a capped store is full and a new user
+// would rank below every current member
+var ErrBelowCutoff = errors.New("rating below capacity cutoff")
+
 // MemoryStore is an in-memory leaderboard store
 type MemoryStore struct {
-	mu    sync.RWMutex
-	users map[string]*User // username -> User
+	mu          sync.RWMutex
+	users       map[string]*User            // username -> User
+	byRating    map[int]map[string]struct{} // rating -> usernames
+	ratings     []int                       // byRating's ratings, highest first
+	names       nameIndex                   // usernames in lexicographic order
+	folded      nameIndex                   // foldedNames, for searching by prefix
+	expiry      expiryIndex                 // entries ordered by expiry time
+	capacity    int                         // 0 means unlimited
+	recency     func() time.Time            // Stamps keys under ranking.MostRecentFirst; nil otherwise
+	budget      MemoryBudget
+	bytes       int64 // Approximate memory used by users
+	onEvict     func(*User)
+	roles       roleTable
+	maintenance maintenanceState
+	records     recordTable
 }
 
 // NewMemoryStore creates a new in-memory store
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
-		users: make(map[string]*User),
+		users:    make(map[string]*User),
+		byRating: make(map[int]map[string]struct{}),
 	}
 }
 
-// AddUser adds or updates a user
+// SetCapacity caps the number of users (0 removes the cap). When a new user
+// arrives at a full store the lowest-ranked member is evicted and passed to onEvict.
+func (s *MemoryStore) SetCapacity(capacity int, onEvict func(*User)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.capacity = capacity
+	s.onEvict = onEvict
+}
+
+// Capacity returns the configured member cap (0 means unlimited)
+func (s *MemoryStore) Capacity() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.capacity
+}
+
+// SetTiePolicy sets how users with equal ratings are ordered, one of
+// ranking.Policies. Under ranking.MostRecentFirst each new rating is stamped
+// with now() in its key; users who already held their rating count as having
+// reached it before anyone stamped. Switching back to ranking.SharedRank
+// drops the stamps.
+func (s *MemoryStore) SetTiePolicy(policy string, now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if policy == ranking.MostRecentFirst {
+		s.recency = now
+		return
+	}
+	s.recency = nil
+	for username, existing := range s.users {
+		if key := ranking.RatingKey(existing.Rating); existing.Key != key {
+			user := *existing
+			user.Key = key
+			s.users[username] = &user
+		}
+	}
+}
+
+// TiePolicy returns how users with equal ratings are ordered
+func (s *MemoryStore) TiePolicy() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.recency != nil {
+		return ranking.MostRecentFirst
+	}
+	return ranking.SharedRank
+}
+
+// keyFor returns the sort key of a rating reached now. Must be called with
+// the lock held.
+func (s *MemoryStore) keyFor(rating int) int64 {
+	if s.recency == nil {
+		return ranking.RatingKey(rating)
+	}
+	return ranking.Key(rating, s.recency())
+}
+
+// AddUser adds or updates a user, keeping an existing user's bot flag
 func (s *MemoryStore) AddUser(username string, rating int) error {
+	return s.put(username, rating, false, false)
+}
+
+// AddBot adds or updates a user flagged as a bot
+func (s *MemoryStore) AddBot(username string, rating int) error {
+	return s.put(username, rating, true, false)
+}
+
+// CreateUser adds a new user, failing with ErrUserExists if the name is taken
+func (s *MemoryStore) CreateUser(username string, rating int) error {
+	return s.put(username, rating, false, true)
+}
+
+// RaiseRating sets an existing user's rating only if it is higher than the
+// current one, comparing and writing under one lock so concurrent calls can't
+// lower it. It returns the rating before the call and whether it changed.
+func (s *MemoryStore) RaiseRating(username string, rating int) (previous int, raised bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.users[username]
+	if !exists {
+		return 0, false, ErrUserNotFound
+	}
+	if rating <= existing.Rating {
+		return existing.Rating, false, nil
+	}
+
+	// Users are replaced rather than mutated so readers holding the old pointer stay consistent
+	user := *existing
+	user.Rating = rating
+	user.Key = s.keyFor(rating)
+	s.unindex(existing)
+	s.users[username] = &user
+	s.index(&user)
+	return existing.Rating, true, nil
+}
+
+// put writes a user, evicting the lowest-ranked members if the store is full.
+// With create set, an existing user is left untouched and ErrUserExists returned.
+func (s *MemoryStore) put(username string, rating int, bot, create bool) error {
+	s.mu.Lock()
+	evicted, err := s.write(username, rating, bot, create)
+	onEvict := s.onEvict
+	s.mu.Unlock()
+
+	if onEvict != nil {
+		for _, user := range evicted {
+			onEvict(user)
+		}
+	}
+	return err
+}
+
+// write is put with the lock held. Evicted members are returned for the
+// caller to pass to onEvict once the lock is released.
+func (s *MemoryStore) write(username string, rating int, bot, create bool) ([]*User, error) {
+	var expiresAt time.Time
+	existing, exists := s.users[username]
+	if exists && create {
+		return nil, ErrUserExists
+	}
+	// Rewriting the same rating doesn't count as reaching it again
+	key := s.keyFor(rating)
+	if exists {
+		bot = bot || existing.Bot
+		expiresAt = existing.ExpiresAt
+		if existing.Rating == rating {
+			key = existing.Key
+		}
+		s.unindex(existing)
+	}
+
+	var evicted []*User
+	evict := func() error {
+		lowest := s.lowest()
+		if lowest == nil {
+			return nil
+		}
+		// The newcomer would be the lowest-ranked member itself
+		if ranking.Before(lowest.Key, lowest.Username, key, username) {
+			return ErrBelowCutoff
+		}
+		s.remove(lowest)
+		evicted = append(evicted, lowest)
+		return nil
+	}
+	if !exists && s.capacity > 0 {
+		for len(s.users) >= s.capacity && len(s.users) > 0 {
+			if err := evict(); err != nil {
+				return evicted, err
+			}
+		}
+	}
+	if !exists && s.budget.Limit > 0 {
+		need, external := userBytes(username), s.externalBytes()
+		for s.bytes+external+need > s.budget.Limit {
+			if s.budget.Policy != MemoryEvictLowest || len(s.users) == 0 {
+				return evicted, ErrMemoryBudget
+			}
+			// An evicted member's share of the external bytes is freed with it
+			external -= external / int64(len(s.users))
+			if err := evict(); err != nil {
+				return evicted, err
+			}
+		}
+	}
+
+	user := &User{
+		Username:  username,
+		Rating:    rating,
+		Key:       key,
+		Bot:       bot,
+		ExpiresAt: expiresAt,
+	}
+	s.users[username] = user
+	s.index(user)
+	if !exists {
+		s.addName(username)
+		s.bytes += userBytes(username)
+	}
+	return evicted, nil
+}
+
+// RemoveUser deletes a user
+func (s *MemoryStore) RemoveUser(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.users[username]
+	if !exists {
+		return ErrUserNotFound
+	}
+	s.remove(user)
+	return nil
+}
+
+// MergeUsers sets into's rating and removes from in one step, so no reader
+// sees both users or neither. It returns into's rating before the merge and
+// the user removed.
+func (s *MemoryStore) MergeUsers(from, into string, rating int) (previous int, removed *User, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed, exists := s.users[from]
+	if !exists {
+		return 0, nil, ErrUserNotFound
+	}
+	existing, exists := s.users[into]
+	if !exists {
+		return 0, nil, ErrUserNotFound
+	}
+	// into already exists, so the write can't evict anyone
+	if _, err := s.write(into, rating, false, false); err != nil {
+		return existing.Rating, nil, err
+	}
+	s.remove(removed)
+	return existing.Rating, removed, nil
+}
+
+// RestoreUser puts back a user removed by MergeUsers as they were, for when
+// the rest of the merge fails. It fails with ErrUserExists if the name has
+// been taken since.
+func (s *MemoryStore) RestoreUser(user *User) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.users[username] = &User{
-		Username: username,
-		Rating:   rating,
+	if _, exists := s.users[user.Username]; exists {
+		return ErrUserExists
+	}
+	restored := *user
+	s.users[restored.Username] = &restored
+	s.index(&restored)
+	s.addName(restored.Username)
+	s.bytes += userBytes(restored.Username)
+	if !restored.ExpiresAt.IsZero() {
+		heap.Push(&s.expiry, expiryItem{username: restored.Username, expiresAt: restored.ExpiresAt})
 	}
 	return nil
 }
 
+// RevertRating puts an existing user's rating and sort key back as before
+// holds them, undoing a write that set the rating to written, so they keep
+// the tie-break stamp they had. A user whose rating has changed again since
+// is left alone and false returned, so a later write isn't overwritten.
+func (s *MemoryStore) RevertRating(before *User, written int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.users[before.Username]
+	if !exists {
+		return false, ErrUserNotFound
+	}
+	if existing.Rating != written {
+		return false, nil
+	}
+	user := *existing
+	user.Rating, user.Key = before.Rating, before.Key
+	if rating, _ := ranking.Decode(user.Key); rating != user.Rating || s.recency == nil {
+		user.Key = ranking.RatingKey(user.Rating)
+	}
+	s.unindex(existing)
+	s.users[user.Username] = &user
+	s.index(&user)
+	return true, nil
+}
+
+// setUser makes username's entry a copy of user, or removes it when user is
+// nil, without applying the member cap or memory budget. It keeps the index
+// in step with a board held elsewhere, which has already applied them.
+func (s *MemoryStore) setUser(username string, user *User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.users[username]
+	if user == nil {
+		if exists {
+			s.remove(existing)
+		}
+		return
+	}
+
+	updated := *user
+	if rating, _ := ranking.Decode(updated.Key); rating != updated.Rating || s.recency == nil {
+		updated.Key = ranking.RatingKey(updated.Rating)
+	}
+	if exists {
+		s.unindex(existing)
+	} else {
+		s.addName(username)
+		s.bytes += userBytes(username)
+	}
+	s.users[username] = &updated
+	s.index(&updated)
+	if !updated.ExpiresAt.IsZero() && (!exists || !existing.ExpiresAt.Equal(updated.ExpiresAt)) {
+		heap.Push(&s.expiry, expiryItem{username: username, expiresAt: updated.ExpiresAt})
+	}
+}
+
+// remove deletes a user from every index. Must be called with the lock held.
+func (s *MemoryStore) remove(user *User) {
+	s.unindex(user)
+	s.dropName(user.Username)
+	delete(s.users, user.Username)
+	s.bytes -= userBytes(user.Username)
+}
+
+func (s *MemoryStore) index(user *User) {
+	bucket, ok := s.byRating[user.Rating]
+	if !ok {
+		bucket = make(map[string]struct{})
+		s.byRating[user.Rating] = bucket
+		i, _ := s.ratingIndex(user.Rating)
+		s.ratings = slices.Insert(s.ratings, i, user.Rating)
+	}
+	bucket[user.Username] = struct{}{}
+}
+
+func (s *MemoryStore) unindex(user *User) {
+	bucket := s.byRating[user.Rating]
+	delete(bucket, user.Username)
+	if len(bucket) == 0 {
+		delete(s.byRating, user.Rating)
+		if i, found := s.ratingIndex(user.Rating); found {
+			s.ratings = slices.Delete(s.ratings, i, i+1)
+		}
+	}
+}
+
+// ratingIndex finds rating's place in ratings, and whether it's there
+func (s *MemoryStore) ratingIndex(rating int) (int, bool) {
+	return slices.BinarySearchFunc(s.ratings, rating, func(r, target int) int { return target - r })
+}
+
+// resetIndex empties the rating index, for every user to be indexed again.
+// Must be called with the lock held.
+func (s *MemoryStore) resetIndex() {
+	s.byRating = make(map[int]map[string]struct{})
+	s.ratings = nil
+}
+
+// lowest returns the last user in leaderboard order. Must be called with the
+// lock held.
+func (s *MemoryStore) lowest() *User {
+	if len(s.ratings) == 0 {
+		return nil
+	}
+	minRating := s.ratings[len(s.ratings)-1]
+
+	var last *User
+	for username := range s.byRating[minRating] {
+		if user := s.users[username]; last == nil || ranking.Before(last.Key, last.Username, user.Key, user.Username) {
+			last = user
+		}
+	}
+	return last
+}
+
 // GetUser retrieves a user by username
 func (s *MemoryStore) GetUser(username string) (*User, error) {
 	s.mu.RLock()
@@ -45,30 +422,30 @@ func (s *MemoryStore) GetUser(username string) (*User, error) {
 
 	user, exists := s.users[username]
 	if !exists {
-		return nil, fmt.Errorf("user not found")
+		return nil, ErrUserNotFound
 	}
 	return user, nil
 }
 
-// GetAllUsers returns all users sorted by rating (descending)
-func (s *MemoryStore) GetAllUsers() []*User {
+// GetAllUsers returns all users in leaderboard order. It stops
+// with ctx's error if ctx ends before the users are collected.
+func (s *MemoryStore) GetAllUsers(ctx context.Context) ([]*User, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	users := make([]*User, 0, len(s.users))
 	for _, user := range s.users {
+		if err := scanCancelled(ctx, len(users)); err != nil {
+			return nil, err
+		}
 		users = append(users, user)
 	}
 
-	// Sort by rating descending, then by username ascending (for stable sort)
-	sort.Slice(users, func(i, j int) bool {
-		if users[i].Rating != users[j].Rating {
-			return users[i].Rating > users[j].Rating
-		}
-		return users[i].Username < users[j].Username
+	slices.SortFunc(users, func(a, b *User) int {
+		return ranking.Compare(a.Key, a.Username, b.Key, b.Username)
 	})
 
-	return users
+	return users, nil
 }
 
 // GetUserCount returns total number of users
@@ -78,69 +455,154 @@ func (s *MemoryStore) GetUserCount() int {
 	return len(s.users)
 }
 
-// GetUserRank calculates a user's rank (handles ties correctly)
-func (s *MemoryStore) GetUserRank(username string) (int, error) {
+// GetUserRank returns a user's rank: one more than the number of users with
+// a strictly higher key, so tied users share a rank
+func (s *MemoryStore) GetUserRank(ctx context.Context, username string) (int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	user, exists := s.users[username]
 	if !exists {
-		return 0, fmt.Errorf("user not found")
+		return 0, ErrUserNotFound
 	}
+	return s.rankForKey(user.Key), nil
+}
 
-	rank := 1
-	for _, u := range s.users {
-		if u.Rating > user.Rating {
-			rank++
-		}
-	}
+// RankForKey returns the rank of a user whose sort key is key: one more than
+// the number of users with a strictly higher key
+func (s *MemoryStore) RankForKey(key int64) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rankForKey(key)
+}
 
-	return rank, nil
+// rankForKey must be called with the lock held
+func (s *MemoryStore) rankForKey(key int64) int {
+	rating, _ := ranking.Decode(key)
+	return ranking.FromHigher(s.ratedHigher(rating) + s.aheadInBucket(rating, key))
 }
 
-// SearchUsers searches for users by username prefix
-func (s *MemoryStore) SearchUsers(query string, limit int) []*User {
+// RankForRating returns the rank a user reaching rating now would hold: one
+// more than the number of users rated strictly higher. Under
+// ranking.MostRecentFirst nobody else with that rating is ahead of them.
+func (s *MemoryStore) RankForRating(rating int) int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return ranking.FromHigher(s.ratedHigher(rating))
+}
 
-	query = strings.ToLower(query)
-	results := make([]*User, 0)
+// RatingAt returns the rating of the user in the given 1-based position on
+// the board, 0 while fewer users are on it. It walks the rating index from
+// the top, so it costs the number of distinct ratings down to the position
+// rather than users.
+func (s *MemoryStore) RatingAt(position int) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	for _, user := range s.users {
-		if strings.Contains(strings.ToLower(user.Username), query) {
-			results = append(results, user)
-			if len(results) >= limit {
-				break
-			}
+	if position < 1 || position > len(s.users) {
+		return 0
+	}
+	seen := 0
+	for _, rating := range s.ratings {
+		seen += len(s.byRating[rating])
+		if seen >= position {
+			return rating
 		}
 	}
+	return 0
+}
 
-	// Sort results by rating
-	sort.Slice(results, func(i, j int) bool {
-		if results[i].Rating != results[j].Rating {
-			return results[i].Rating > results[j].Rating
+// ratedHigher walks the rating index, so it costs the number of distinct
+// ratings rather than users. Must be called with the lock held.
+func (s *MemoryStore) ratedHigher(rating int) int {
+	higher := 0
+	for _, r := range s.ratings {
+		if r <= rating {
+			break
 		}
-		return results[i].Username < results[j].Username
-	})
+		higher += len(s.byRating[r])
+	}
+	return higher
+}
 
-	return results
+// aheadInBucket counts the users with rating and a key higher than key.
+// Keys only differ within a rating under ranking.MostRecentFirst, so
+// otherwise the bucket isn't walked. Must be called with the lock held.
+func (s *MemoryStore) aheadInBucket(rating int, key int64) int {
+	if s.recency == nil {
+		return 0
+	}
+	ahead := 0
+	for username := range s.byRating[rating] {
+		if s.users[username].Key > key {
+			ahead++
+		}
+	}
+	return ahead
 }
 
-// GetStats calculates leaderboard statistics
-func (s *MemoryStore) GetStats() (total int, minRating, maxRating int, avgRating float64) {
+// CountInRange returns how many users are rated between minRating and
+// maxRating inclusive, optionally ignoring bots. It walks the rating index,
+// so it costs the number of distinct ratings rather than users.
+func (s *MemoryStore) CountInRange(minRating, maxRating int, excludeBots bool) int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if len(s.users) == 0 {
-		return 0, 0, 0, 0
+	count := 0
+	for rating, bucket := range s.byRating {
+		if rating < minRating || rating > maxRating {
+			continue
+		}
+		if !excludeBots {
+			count += len(bucket)
+			continue
+		}
+		for username := range bucket {
+			if !s.users[username].Bot {
+				count++
+			}
+		}
 	}
+	return count
+}
+
+// GetBotCount returns the number of users flagged as bots
+func (s *MemoryStore) GetBotCount(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count, step := 0, 0
+	for _, user := range s.users {
+		if err := scanCancelled(ctx, step); err != nil {
+			return 0, err
+		}
+		step++
+		if user.Bot {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetStats calculates leaderboard statistics, optionally ignoring bots
+func (s *MemoryStore) GetStats(ctx context.Context, excludeBots bool) (total int, minRating, maxRating int, avgRating float64, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	total = len(s.users)
 	minRating = 5000
 	maxRating = 100
-	sum := 0
+	sum, step := 0, 0
 
 	for _, user := range s.users {
+		if err := scanCancelled(ctx, step); err != nil {
+			return 0, 0, 0, 0, err
+		}
+		step++
+		if excludeBots && user.Bot {
+			continue
+		}
+		total++
+
 		if user.Rating < minRating {
 			minRating = user.Rating
 		}
@@ -150,13 +612,50 @@ func (s *MemoryStore) GetStats() (total int, minRating, maxRating int, avgRating
 		sum += user.Rating
 	}
 
+	if total == 0 {
+		return 0, 0, 0, 0, nil
+	}
+
 	avgRating = float64(sum) / float64(total)
 	return
 }
 
+// RatingCounts returns how many users hold each rating, optionally ignoring
+// bots, read from the rating index
+func (s *MemoryStore) RatingCounts(ctx context.Context, excludeBots bool) (map[int]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[int]int, len(s.byRating))
+	step := 0
+	for rating, bucket := range s.byRating {
+		n := len(bucket)
+		if excludeBots {
+			n = 0
+			for username := range bucket {
+				if err := scanCancelled(ctx, step); err != nil {
+					return nil, err
+				}
+				step++
+				if !s.users[username].Bot {
+					n++
+				}
+			}
+		}
+		if n > 0 {
+			counts[rating] = n
+		}
+	}
+	return counts, nil
+}
+
 // Clear removes all users
 func (s *MemoryStore) Clear() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.users = make(map[string]*User)
+	s.resetIndex()
+	s.names, s.folded = nil, nil
+	s.expiry = nil
+	s.bytes = 0
 }
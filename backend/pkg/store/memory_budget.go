@@ -0,0 +1,60 @@
+package store
+
+import "errors"
+
+// ErrMemoryBudget is returned when a new user would take a store over its
+// memory budget and the budget's policy is to reject
+var ErrMemoryBudget = errors.New("memory budget exceeded")
+
+// Memory budget policies
+const (
+	MemoryReject      = "reject"       // Turn new users away with ErrMemoryBudget
+	MemoryEvictLowest = "evict_lowest" // Evict the lowest-ranked members, as a member cap does
+)
+
+// userBaseBytes approximates what a user costs besides its name: the User
+// value, its entries in the user map and the rating index, and its slot in
+// the name index
+const userBaseBytes = 160
+
+// userBytes approximates the memory a user takes up in the store
+func userBytes(username string) int64 {
+	return userBaseBytes + int64(len(username))
+}
+
+// MemoryBudget bounds a store's approximate memory use
+type MemoryBudget struct {
+	Limit    int64        // Bytes, 0 for unlimited
+	Policy   string       // MemoryReject (default) or MemoryEvictLowest
+	External func() int64 // Bytes held outside the store for its users, e.g. score history; must not block on the store
+}
+
+// SetMemoryBudget bounds the approximate bytes used by the store plus those
+// reported by budget.External. When a new user would exceed the budget it
+// is rejected with ErrMemoryBudget or, under MemoryEvictLowest, the
+// lowest-ranked members are evicted and passed to onEvict.
+func (s *MemoryStore) SetMemoryBudget(budget MemoryBudget, onEvict func(*User)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if budget.Policy == "" {
+		budget.Policy = MemoryReject
+	}
+	s.budget = budget
+	s.onEvict = onEvict
+}
+
+// MemoryUsage returns the approximate bytes used by the store's users, and
+// the budget they count against (0 when unlimited)
+func (s *MemoryStore) MemoryUsage() (used, limit int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bytes, s.budget.Limit
+}
+
+// externalBytes returns the bytes the budget counts outside the store
+func (s *MemoryStore) externalBytes() int64 {
+	if s.budget.External == nil {
+		return 0
+	}
+	return s.budget.External()
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	u := userBytes("a") // Every name here is one letter long
+	type add struct {
+		username string
+		rating   int // Negative removes the user instead
+		err      error
+	}
+	cases := []struct {
+		name    string
+		budget  MemoryBudget
+		adds    []add
+		evicted []string
+		users   int
+	}{
+		{
+			name:  "unlimited",
+			adds:  []add{{"a", 100, nil}, {"b", 200, nil}, {"c", 300, nil}},
+			users: 3,
+		},
+		{
+			name:   "rejects a newcomer over the budget",
+			budget: MemoryBudget{Limit: 2 * u},
+			adds:   []add{{"a", 100, nil}, {"b", 200, nil}, {"c", 300, ErrMemoryBudget}},
+			users:  2,
+		},
+		{
+			name:   "lets members change their rating when full",
+			budget: MemoryBudget{Limit: 2 * u},
+			adds:   []add{{"a", 100, nil}, {"b", 200, nil}, {"a", 900, nil}},
+			users:  2,
+		},
+		{
+			name:   "frees a removed member's bytes",
+			budget: MemoryBudget{Limit: 2 * u},
+			adds:   []add{{"a", 100, nil}, {"b", 200, nil}, {"a", -1, nil}, {"c", 300, nil}},
+			users:  2,
+		},
+		{
+			name:    "evicts the lowest",
+			budget:  MemoryBudget{Limit: 2 * u, Policy: MemoryEvictLowest},
+			adds:    []add{{"a", 100, nil}, {"b", 200, nil}, {"c", 300, nil}, {"d", 250, nil}},
+			evicted: []string{"a", "b"},
+			users:   2,
+		},
+		{
+			name:   "turns away a newcomer who would be the lowest",
+			budget: MemoryBudget{Limit: 2 * u, Policy: MemoryEvictLowest},
+			adds:   []add{{"a", 100, nil}, {"b", 200, nil}, {"c", 50, ErrBelowCutoff}},
+			users:  2,
+		},
+		{
+			name:   "counts external bytes",
+			budget: MemoryBudget{Limit: 2 * u, External: func() int64 { return u }},
+			adds:   []add{{"a", 100, nil}, {"b", 200, ErrMemoryBudget}},
+			users:  1,
+		},
+		{
+			// An eviction frees its member's share of the external bytes too
+			name:    "evicts for external bytes",
+			budget:  MemoryBudget{Limit: 4 * u, Policy: MemoryEvictLowest, External: func() int64 { return 2 * u }},
+			adds:    []add{{"a", 100, nil}, {"b", 200, nil}, {"c", 300, nil}},
+			evicted: []string{"a"},
+			users:   2,
+		},
+		{
+			name:   "rejects even an empty store's first user",
+			budget: MemoryBudget{Limit: u - 1, Policy: MemoryEvictLowest},
+			adds:   []add{{"a", 100, ErrMemoryBudget}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := NewMemoryStore()
+			var evicted []string
+			s.SetMemoryBudget(c.budget, func(user *User) { evicted = append(evicted, user.Username) })
+			for _, a := range c.adds {
+				var err error
+				if a.rating < 0 {
+					err = s.RemoveUser(a.username)
+				} else {
+					err = s.AddUser(a.username, a.rating)
+				}
+				if !errors.Is(err, a.err) {
+					t.Errorf("adding %s at %d: %v, want %v", a.username, a.rating, err, a.err)
+				}
+			}
+			if !slices.Equal(evicted, c.evicted) {
+				t.Errorf("evicted %v, want %v", evicted, c.evicted)
+			}
+			if got := s.GetUserCount(); got != c.users {
+				t.Errorf("%d users, want %d", got, c.users)
+			}
+			used, limit := s.MemoryUsage()
+			if used != int64(c.users)*u || limit != c.budget.Limit {
+				t.Errorf("usage %d of %d, want %d of %d", used, limit, int64(c.users)*u, c.budget.Limit)
+			}
+		})
+	}
+}
+
+func TestMemoryUsageAfterRestoreAndClear(t *testing.T) {
+	s := NewMemoryStore()
+	if err := s.AddUser("gone", 100); err != nil {
+		t.Fatal(err)
+	}
+	s.Restore(Snapshot{Users: []User{{Username: "ada", Rating: 1400}, {Username: "bo", Rating: 1450}}})
+	if used, _ := s.MemoryUsage(); used != userBytes("ada")+userBytes("bo") {
+		t.Errorf("usage after a restore %d, want %d", used, userBytes("ada")+userBytes("bo"))
+	}
+	s.Clear()
+	if used, _ := s.MemoryUsage(); used != 0 {
+		t.Errorf("usage after a clear %d, want 0", used)
+	}
+}
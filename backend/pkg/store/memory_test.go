@@ -0,0 +1,161 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestMemoryStoreRatingAt(t *testing.T) {
+	cases := []struct {
+		name  string
+		write func(s *MemoryStore) error
+		want  []int // RatingAt for positions 1 on, then 0 past the end
+	}{
+		{
+			name:  "empty",
+			write: func(s *MemoryStore) error { return nil },
+			want:  []int{0},
+		},
+		{
+			name: "ties share a rating",
+			write: func(s *MemoryStore) error {
+				for i, rating := range []int{1500, 1700, 1500, 1600} {
+					if err := s.AddUser(fmt.Sprintf("user_%d", i), rating); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			want: []int{1700, 1600, 1500, 1500, 0},
+		},
+		{
+			name: "rating changes move users between ratings",
+			write: func(s *MemoryStore) error {
+				for i, rating := range []int{1500, 1600, 1700} {
+					if err := s.AddUser(fmt.Sprintf("user_%d", i), rating); err != nil {
+						return err
+					}
+				}
+				// 1700 empties and 1800 is new
+				if err := s.AddUser("user_2", 1800); err != nil {
+					return err
+				}
+				return s.AddUser("user_0", 1600)
+			},
+			want: []int{1800, 1600, 1600, 0},
+		},
+		{
+			name: "removals drop emptied ratings",
+			write: func(s *MemoryStore) error {
+				for i, rating := range []int{1500, 1600, 1600} {
+					if err := s.AddUser(fmt.Sprintf("user_%d", i), rating); err != nil {
+						return err
+					}
+				}
+				if err := s.RemoveUser("user_0"); err != nil {
+					return err
+				}
+				return s.RemoveUser("user_1")
+			},
+			want: []int{1600, 0},
+		},
+		{
+			name: "restored",
+			write: func(s *MemoryStore) error {
+				if err := s.AddUser("gone", 2000); err != nil {
+					return err
+				}
+				s.Restore(Snapshot{Users: []User{{Username: "a", Rating: 1400}, {Username: "b", Rating: 1450}}})
+				return nil
+			},
+			want: []int{1450, 1400, 0},
+		},
+		{
+			name: "cleared",
+			write: func(s *MemoryStore) error {
+				if err := s.AddUser("gone", 2000); err != nil {
+					return err
+				}
+				s.Clear()
+				return s.AddUser("a", 1200)
+			},
+			want: []int{1200, 0},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := NewMemoryStore()
+			if err := c.write(s); err != nil {
+				t.Fatal(err)
+			}
+			for i, want := range c.want {
+				if got := s.RatingAt(i + 1); got != want {
+					t.Errorf("RatingAt(%d) = %d, want %d", i+1, got, want)
+				}
+			}
+			if issues := s.CheckIntegrity(); len(issues) > 0 {
+				t.Errorf("integrity issues %v", issues)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreSearchRanked(t *testing.T) {
+	s := NewMemoryStore()
+	for _, u := range []struct {
+		name   string
+		rating int
+	}{
+		{"Bob", 2000}, {"bobby", 1800}, {"alice", 1900}, {"bo", 1800}, {"BOBCAT", 1500}, {"rob", 2100},
+	} {
+		if err := s.AddUser(u.name, u.rating); err != nil {
+			t.Fatal(err)
+		}
+	}
+	position := func(username string) *SearchPosition {
+		user, _ := s.GetUser(username)
+		return &SearchPosition{Key: user.Key, Username: username}
+	}
+
+	cases := []struct {
+		name  string
+		q     SearchQuery
+		want  string // Matches as username:rank
+		total int
+	}{
+		{"prefix in any case", SearchQuery{Text: "BO", Limit: 10}, "[Bob:2 bo:4 bobby:4 BOBCAT:6]", 4},
+		{"not inside names", SearchQuery{Text: "ob", Limit: 10}, "[]", 0},
+		{"longer prefix", SearchQuery{Text: "bobb", Limit: 10}, "[bobby:4]", 1},
+		{"offset", SearchQuery{Text: "bo", Offset: 1, Limit: 2}, "[bo:4 bobby:4]", 4},
+		{"offset past the matches", SearchQuery{Text: "bo", Offset: 9, Limit: 2}, "[]", 4},
+		{"after a position", SearchQuery{Text: "bo", After: position("bo"), Limit: 10}, "[bobby:4 BOBCAT:6]", 4},
+		{"excluded", SearchQuery{Text: "bo", Limit: 10, Exclude: func(u string) bool { return u == "bobby" }}, "[Bob:2 bo:4 BOBCAT:6]", 3},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			matches, total, err := s.SearchRanked(context.Background(), c.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(matches))
+			for i, m := range matches {
+				got[i] = fmt.Sprintf("%s:%d", m.User.Username, m.Rank)
+			}
+			if fmt.Sprint(got) != c.want || total != c.total {
+				t.Errorf("got %v of %d, want %s of %d", got, total, c.want, c.total)
+			}
+			if c.q.Exclude == nil && s.CountPrefix(c.q.Text) != c.total {
+				t.Errorf("CountPrefix(%q) = %d, want %d", c.q.Text, s.CountPrefix(c.q.Text), c.total)
+			}
+		})
+	}
+
+	// Leaving users leave the name index
+	if err := s.RemoveUser("BOBCAT"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.CountPrefix("bo"); got != 3 {
+		t.Errorf("CountPrefix after a removal = %d, want 3", got)
+	}
+}
@@ -0,0 +1,14 @@
+package store
+
+import "context"
+
+// Mirror is a store board writes can be copied to while migrating, and
+// checked against the live board. MemoryStore and ShardedStore are mirrors.
+type Mirror interface {
+	AddUser(username string, rating int) error
+	AddBot(username string, rating int) error
+	RemoveUser(username string) error
+	GetUser(username string) (*User, error)
+	GetUserRank(ctx context.Context, username string) (int, error)
+	GetUserCount() int
+}
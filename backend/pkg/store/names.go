@@ -0,0 +1,81 @@
+package store
+
+import (
+	"sort"
+	"strings"
+)
+
+// nameIndex keeps every username in lexicographic order
+type nameIndex []string
+
+func (n *nameIndex) insert(username string) {
+	i := sort.SearchStrings(*n, username)
+	if i < len(*n) && (*n)[i] == username {
+		return
+	}
+	*n = append(*n, "")
+	copy((*n)[i+1:], (*n)[i:])
+	(*n)[i] = username
+}
+
+func (n *nameIndex) remove(username string) {
+	i := sort.SearchStrings(*n, username)
+	if i < len(*n) && (*n)[i] == username {
+		*n = append((*n)[:i], (*n)[i+1:]...)
+	}
+}
+
+// foldedName is how username is kept in the folded index: lower-cased, so a
+// search prefix finds it whatever its case, then the name itself
+func foldedName(username string) string {
+	return strings.ToLower(username) + "\x00" + username
+}
+
+// addName indexes a new username. Must be called with the lock held.
+func (s *MemoryStore) addName(username string) {
+	s.names.insert(username)
+	s.folded.insert(foldedName(username))
+}
+
+// dropName unindexes a username. Must be called with the lock held.
+func (s *MemoryStore) dropName(username string) {
+	s.names.remove(username)
+	s.folded.remove(foldedName(username))
+}
+
+// withPrefix returns the folded index entries of the usernames starting with
+// prefix, which must be lower case. Must be called with the lock held.
+func (s *MemoryStore) withPrefix(prefix string) nameIndex {
+	start := sort.SearchStrings(s.folded, prefix)
+	// No byte of a UTF-8 string is 0xff, so every entry with the prefix sorts
+	// before prefix+"\xff"
+	end := start + sort.SearchStrings(s.folded[start:], prefix+"\xff")
+	return s.folded[start:end]
+}
+
+// CountPrefix returns how many usernames start with prefix, ignoring case,
+// from the folded name index
+func (s *MemoryStore) CountPrefix(prefix string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.withPrefix(strings.ToLower(prefix)))
+}
+
+// UsersAfter returns up to limit users whose usernames sort after cursor, in
+// username order. An empty cursor starts from the first username.
+func (s *MemoryStore) UsersAfter(cursor string, limit int) []*User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := sort.SearchStrings(s.names, cursor)
+	if start < len(s.names) && s.names[start] == cursor {
+		start++
+	}
+	end := min(start+limit, len(s.names))
+
+	users := make([]*User, 0, end-start)
+	for _, username := range s.names[start:end] {
+		users = append(users, s.users[username])
+	}
+	return users
+}
@@ -0,0 +1,118 @@
+package store
+
+import "sync"
+
+// recordTable holds small values services keep beside the board, by kind
+// and key, such as a user's privacy settings or a guest's device. Like roles
+// they are not touched by Clear, and they go wherever the board goes: a
+// RedisStore keeps them in Redis and hands them to every process on it.
+type recordTable struct {
+	mu    sync.RWMutex
+	kinds map[string]map[string]string // kind -> key -> value
+}
+
+// UpdateRecord replaces the record kind/key with what update returns for its
+// current value, "" when there is none, and returns the new value. Returning
+// "" deletes the record; returning an error leaves it as it was. update may
+// be called more than once, so it must not have side effects.
+func (s *MemoryStore) UpdateRecord(kind, key string, update func(current string) (string, error)) (string, error) {
+	t := &s.records
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	value, err := update(t.kinds[kind][key])
+	if err != nil {
+		return "", err
+	}
+	t.set(kind, key, value)
+	return value, nil
+}
+
+// set writes a record, deleting it when value is "". Must be called with the
+// lock held.
+func (t *recordTable) set(kind, key, value string) {
+	if value == "" {
+		delete(t.kinds[kind], key)
+		if len(t.kinds[kind]) == 0 {
+			delete(t.kinds, kind)
+		}
+		return
+	}
+	if t.kinds == nil {
+		t.kinds = make(map[string]map[string]string)
+	}
+	if t.kinds[kind] == nil {
+		t.kinds[kind] = make(map[string]string)
+	}
+	t.kinds[kind][key] = value
+}
+
+// setRecord writes a record as it is elsewhere, deleting it when value is ""
+func (s *MemoryStore) setRecord(kind, key, value string) {
+	s.records.mu.Lock()
+	defer s.records.mu.Unlock()
+	s.records.set(kind, key, value)
+}
+
+// Record returns the record kind/key, and whether there is one
+func (s *MemoryStore) Record(kind, key string) (string, bool) {
+	t := &s.records
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	value, ok := t.kinds[kind][key]
+	return value, ok
+}
+
+// Records returns a copy of every record of kind
+func (s *MemoryStore) Records(kind string) map[string]string {
+	t := &s.records
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	records := make(map[string]string, len(t.kinds[kind]))
+	for key, value := range t.kinds[kind] {
+		records[key] = value
+	}
+	return records
+}
+
+// RecordCount returns how many records of kind there are
+func (s *MemoryStore) RecordCount(kind string) int {
+	t := &s.records
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.kinds[kind])
+}
+
+// allRecords copies every record of every kind, for snapshots
+func (s *MemoryStore) allRecords() map[string]map[string]string {
+	t := &s.records
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.kinds) == 0 {
+		return nil
+	}
+	all := make(map[string]map[string]string, len(t.kinds))
+	for kind, records := range t.kinds {
+		all[kind] = make(map[string]string, len(records))
+		for key, value := range records {
+			all[kind][key] = value
+		}
+	}
+	return all
+}
+
+// replaceRecords replaces every record with records
+func (s *MemoryStore) replaceRecords(records map[string]map[string]string) {
+	t := &s.records
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.kinds = nil
+	for kind, values := range records {
+		for key, value := range values {
+			t.set(kind, key, value)
+		}
+	}
+}
@@ -0,0 +1,1217 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"backend/internal/ranking"
+)
+
+const (
+	// redisLoadBatch is how many users are read or written per Redis round
+	// trip while loading, catching up or rewriting the board
+	redisLoadBatch = 1000
+	// redisChangesMax is roughly how many changes the stream keeps. A process
+	// that falls further behind than that reloads the whole board.
+	redisChangesMax = 100_000
+	// redisWriteAttempts bounds how often a write is retried when another
+	// process wrote first
+	redisWriteAttempts = 16
+	// redisFollowBlock is how long the follower waits for new changes per read
+	redisFollowBlock = 5 * time.Second
+)
+
+// errStale is returned by commit when another process wrote to the board
+// since the index last caught up
+var errStale = errors.New("index is behind the board on Redis")
+
+// RedisStore keeps the board in Redis, which is its source of truth, so it
+// outlives the process and every process on the same keys serves the same
+// board. Ranks, pages and search are served from a MemoryStore index of it.
+//
+// Each write is decided on the index, then committed to Redis by a script
+// that only applies it if nothing else has been written since the index
+// caught up, and logs it to a stream of changes in the same step. When
+// another process wrote first, the write is undone on the index, which then
+// catches up from the stream, and tried again. A background follower reads
+// the stream so other processes' writes reach the index within moments.
+//
+// Role grants, the maintenance mode and records are kept in Redis the same
+// way. Redis Cluster isn't supported, as a write spans several keys.
+type RedisStore struct {
+	*MemoryStore
+	client redis.UniversalClient
+	keys   redisKeys
+
+	// zaddLT is set when the server takes ZADD's LT flag (Redis 6.2), which
+	// raises a rating in one command; otherwise a script does
+	zaddLT bool
+
+	mu       sync.Mutex // Held across each write, from deciding it to committing it
+	lastID   string     // Last change on the stream the index has caught up with
+	onEvict  func(*User)
+	evicting []*User // Evicted by the write in progress
+
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+// redisKeys are the keys a RedisStore keeps the board under
+type redisKeys struct {
+	prefix      string
+	ratings     string // Sorted set of usernames by negated rating
+	bots        string // Set of bot usernames
+	expiries    string // Sorted set of usernames by unix expiry time in milliseconds
+	sortKeys    string // Hash of username -> sort key, for keys stamped under ranking.MostRecentFirst
+	roles       string // Hash of principal -> comma-separated roles
+	maintenance string // JSON of the maintenance mode
+	changes     string // Stream of changes, each naming what a write touched
+}
+
+// records is the hash of the records of kind
+func (k redisKeys) records(kind string) string {
+	return k.prefix + "records:" + kind
+}
+
+// NewRedisStore loads the board kept on client under keys starting with
+// prefix, creating it empty if there is none, and follows the changes other
+// processes make to it until Close
+func NewRedisStore(ctx context.Context, client redis.UniversalClient, prefix string) (*RedisStore, error) {
+	r := &RedisStore{
+		MemoryStore: NewMemoryStore(),
+		client:      client,
+		keys: redisKeys{
+			prefix:      prefix,
+			ratings:     prefix + "ratings",
+			bots:        prefix + "bots",
+			expiries:    prefix + "expiries",
+			sortKeys:    prefix + "keys",
+			roles:       prefix + "roles",
+			maintenance: prefix + "maintenance",
+			changes:     prefix + "changes",
+		},
+		done: make(chan struct{}),
+	}
+	if err := r.reload(ctx); err != nil {
+		return nil, err
+	}
+	r.zaddLT = supportsZaddLT(ctx, client)
+
+	followCtx, stop := context.WithCancel(context.Background())
+	r.stop = stop
+	go r.follow(followCtx)
+	return r, nil
+}
+
+// Close stops following changes and closes the Redis client
+func (r *RedisStore) Close() error {
+	r.stop()
+	// Closing the client ends a blocked read, which the context may not
+	err := r.client.Close()
+	<-r.done
+	return err
+}
+
+// change names what a write touched, as logged on the stream
+type change struct {
+	Users       []string    `json:"users,omitempty"`
+	Principals  []string    `json:"principals,omitempty"`
+	Records     [][2]string `json:"records,omitempty"` // Kind and key
+	Maintenance bool        `json:"maintenance,omitempty"`
+	Reload      bool        `json:"reload,omitempty"` // The whole board was replaced
+}
+
+// before is what a write's touched entries held before it, to undo it
+type before struct {
+	users       map[string]*User
+	roles       map[string][]string
+	records     map[[2]string]string
+	maintenance *Maintenance // Set when the write touches the maintenance mode
+}
+
+// capture copies what c's entries hold now
+func (r *RedisStore) capture(c change) before {
+	b := before{
+		users:   make(map[string]*User, len(c.Users)),
+		roles:   make(map[string][]string, len(c.Principals)),
+		records: make(map[[2]string]string, len(c.Records)),
+	}
+	for _, username := range c.Users {
+		b.users[username] = nil
+		if user, err := r.MemoryStore.GetUser(username); err == nil {
+			copied := *user
+			b.users[username] = &copied
+		}
+	}
+	for _, principal := range c.Principals {
+		b.roles[principal] = r.MemoryStore.Roles(principal)
+	}
+	for _, record := range c.Records {
+		b.records[record], _ = r.MemoryStore.Record(record[0], record[1])
+	}
+	if c.Maintenance {
+		m := r.MemoryStore.Maintenance()
+		b.maintenance = &m
+	}
+	return b
+}
+
+// changed reports whether the index differs from b on any of c's entries
+func (r *RedisStore) changed(b before) bool {
+	for username, was := range b.users {
+		user, err := r.MemoryStore.GetUser(username)
+		if (err == nil) != (was != nil) || (was != nil && *user != *was) {
+			return true
+		}
+	}
+	for principal, was := range b.roles {
+		if !slices.Equal(r.MemoryStore.Roles(principal), was) {
+			return true
+		}
+	}
+	for record, was := range b.records {
+		if value, _ := r.MemoryStore.Record(record[0], record[1]); value != was {
+			return true
+		}
+	}
+	return b.maintenance != nil && r.MemoryStore.Maintenance() != *b.maintenance
+}
+
+// undo puts c's entries back as b holds them, and users removed along the way
+func (r *RedisStore) undo(b before, removed []*User) {
+	for username, user := range b.users {
+		r.MemoryStore.setUser(username, user)
+	}
+	for _, user := range removed {
+		if _, listed := b.users[user.Username]; !listed {
+			r.MemoryStore.setUser(user.Username, user)
+		}
+	}
+	for principal, roles := range b.roles {
+		r.MemoryStore.setRoles(principal, roles)
+	}
+	for record, value := range b.records {
+		r.MemoryStore.setRecord(record[0], record[1], value)
+	}
+	if b.maintenance != nil {
+		r.MemoryStore.SetMaintenance(*b.maintenance)
+	}
+}
+
+// write runs apply on the index and commits what it changed to Redis: the
+// entries c names, and any users apply removes or the index evicts. If
+// another process wrote first, or apply found the index behind, the write is
+// undone, the index caught up and the write tried again, so apply may run
+// more than once. If Redis can't be written, the write is undone and the
+// Redis error returned. Evictions are passed on once the lock is released.
+func (r *RedisStore) write(c change, apply func() (removed []*User, err error)) error {
+	ctx := context.Background()
+	var evicted []*User
+	err := func() error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		for attempt := 1; ; attempt++ {
+			b := r.capture(c)
+			r.evicting = nil
+			removed, applyErr := apply()
+			removed = append(removed, r.evicting...)
+
+			var err error
+			if len(removed) == 0 && !r.changed(b) {
+				// Nothing to write, but the index may only have refused the
+				// write because it hadn't seen another process's yet
+				err = r.checkFresh(ctx)
+			} else {
+				touched := c
+				for _, user := range removed {
+					touched.Users = append(touched.Users, user.Username)
+				}
+				err = r.commit(ctx, touched)
+				if err != nil {
+					r.undo(b, removed)
+				}
+			}
+			switch {
+			case err == nil:
+				evicted = r.evicting
+				return applyErr
+			case !errors.Is(err, errStale) || attempt == redisWriteAttempts:
+				return err
+			}
+			// Back off a little, so processes writing the same entries take turns
+			time.Sleep(time.Duration(rand.Int64N(int64(attempt) * int64(time.Millisecond))))
+			if err := r.catchUp(ctx); err != nil {
+				return err
+			}
+		}
+	}()
+
+	if r.onEvict != nil {
+		for _, user := range evicted {
+			r.onEvict(user)
+		}
+	}
+	return err
+}
+
+// commitScript applies a write's ops, unless the stream's last change isn't
+// ARGV[1], and logs ARGV[2] as a change, trimming the stream to about
+// ARGV[3] entries. Ops come four arguments at a time: the command, the index
+// of its key in KEYS and up to two arguments. The type of every key is
+// checked first, as Redis doesn't roll a script back when a command fails
+// partway, so a write lands whole or not at all.
+var commitScript = redis.NewScript(`
+local last = redis.call('XREVRANGE', KEYS[1], '+', '-', 'COUNT', 1)
+local lastID = '0-0'
+if last[1] then lastID = last[1][1] end
+if lastID ~= ARGV[1] then return {0, lastID} end
+
+local types = {zadd = 'zset', zrem = 'zset', sadd = 'set', srem = 'set', hset = 'hash', hdel = 'hash', set = 'string', del = 'string'}
+for i = 4, #ARGV, 4 do
+  local key = KEYS[tonumber(ARGV[i + 1])]
+  local have = redis.call('TYPE', key).ok
+  if have ~= 'none' and have ~= types[ARGV[i]] then
+    return redis.error_reply('WRONGTYPE ' .. key .. ' holds a ' .. have)
+  end
+end
+for i = 4, #ARGV, 4 do
+  local op, key, a, b = ARGV[i], KEYS[tonumber(ARGV[i + 1])], ARGV[i + 2], ARGV[i + 3]
+  if op == 'zadd' then redis.call('ZADD', key, a, b)
+  elseif op == 'zrem' then redis.call('ZREM', key, a)
+  elseif op == 'sadd' then redis.call('SADD', key, a)
+  elseif op == 'srem' then redis.call('SREM', key, a)
+  elseif op == 'hset' then redis.call('HSET', key, a, b)
+  elseif op == 'hdel' then redis.call('HDEL', key, a)
+  elseif op == 'set' then redis.call('SET', key, a)
+  elseif op == 'del' then redis.call('DEL', key)
+  end
+end
+return {1, redis.call('XADD', KEYS[1], 'MAXLEN', '~', ARGV[3], '*', 'change', ARGV[2])}
+`)
+
+// ops builds a script's KEYS and ops
+type ops struct {
+	keys []string
+	args []any
+}
+
+func (o *ops) add(op, key string, a, b any) {
+	index := slices.Index(o.keys, key)
+	if index < 0 {
+		o.keys = append(o.keys, key)
+		index = len(o.keys) - 1
+	}
+	o.args = append(o.args, op, index+1, a, b)
+}
+
+// commit writes c's entries to Redis as the index now holds them, if
+// nothing has been written since the index caught up, and logs c
+func (r *RedisStore) commit(ctx context.Context, c change) error {
+	o := ops{keys: []string{r.keys.changes}}
+	for _, username := range c.Users {
+		r.userOps(&o, username)
+	}
+	for _, principal := range c.Principals {
+		if roles := r.MemoryStore.Roles(principal); len(roles) > 0 {
+			o.add("hset", r.keys.roles, principal, strings.Join(roles, ","))
+		} else {
+			o.add("hdel", r.keys.roles, principal, "")
+		}
+	}
+	for _, record := range c.Records {
+		if value, ok := r.MemoryStore.Record(record[0], record[1]); ok {
+			o.add("hset", r.keys.records(record[0]), record[1], value)
+		} else {
+			o.add("hdel", r.keys.records(record[0]), record[1], "")
+		}
+	}
+	if c.Maintenance {
+		encoded, err := json.Marshal(r.MemoryStore.Maintenance())
+		if err != nil {
+			return err
+		}
+		o.add("set", r.keys.maintenance, string(encoded), "")
+	}
+
+	logged, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	args := append([]any{r.lastID, string(logged), redisChangesMax}, o.args...)
+	result, err := commitScript.Run(ctx, r.client, o.keys, args...).Slice()
+	if err != nil {
+		return err
+	}
+	if len(result) != 2 {
+		return fmt.Errorf("unexpected commit result %v", result)
+	}
+	if applied, _ := result[0].(int64); applied == 0 {
+		return errStale
+	}
+	r.lastID, _ = result[1].(string)
+	return nil
+}
+
+// userOps adds the ops that write username to Redis as the index holds them
+func (r *RedisStore) userOps(o *ops, username string) {
+	user, err := r.MemoryStore.GetUser(username)
+	if err != nil {
+		o.add("zrem", r.keys.ratings, username, "")
+		o.add("srem", r.keys.bots, username, "")
+		o.add("zrem", r.keys.expiries, username, "")
+		o.add("hdel", r.keys.sortKeys, username, "")
+		return
+	}
+	o.add("zadd", r.keys.ratings, score(user.Rating), username)
+	if user.Bot {
+		o.add("sadd", r.keys.bots, username, "")
+	} else {
+		o.add("srem", r.keys.bots, username, "")
+	}
+	if user.ExpiresAt.IsZero() {
+		o.add("zrem", r.keys.expiries, username, "")
+	} else {
+		o.add("zadd", r.keys.expiries, user.ExpiresAt.UnixMilli(), username)
+	}
+	if user.Key == ranking.RatingKey(user.Rating) {
+		o.add("hdel", r.keys.sortKeys, username, "")
+	} else {
+		o.add("hset", r.keys.sortKeys, username, user.Key)
+	}
+}
+
+// lastChange returns the ID of the stream's last change, "0-0" if it has none
+func (r *RedisStore) lastChange(ctx context.Context) (string, error) {
+	last, err := r.client.XRevRangeN(ctx, r.keys.changes, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(last) == 0 {
+		return "0-0", nil
+	}
+	return last[0].ID, nil
+}
+
+// checkFresh returns errStale if the stream has changes the index hasn't seen
+func (r *RedisStore) checkFresh(ctx context.Context) error {
+	last, err := r.lastChange(ctx)
+	if err != nil {
+		return err
+	}
+	if last != r.lastID {
+		return errStale
+	}
+	return nil
+}
+
+// catchUp applies the changes logged since the index last caught up, by
+// reading the entries they name from Redis as they are now. If the stream no
+// longer holds the last change seen, as when the process fell too far behind,
+// the whole board is reloaded. Must be called with mu held.
+func (r *RedisStore) catchUp(ctx context.Context) error {
+	var pending change
+	from := r.lastID
+	for {
+		entries, err := r.client.XRangeN(ctx, r.keys.changes, from, "+", redisLoadBatch).Result()
+		if err != nil {
+			return err
+		}
+		// XRANGE includes from, which must still be there
+		if from != "0-0" {
+			if len(entries) == 0 || entries[0].ID != from {
+				return r.reloadLocked(ctx)
+			}
+			entries = entries[1:]
+		}
+		for _, entry := range entries {
+			var c change
+			if encoded, ok := entry.Values["change"].(string); !ok || json.Unmarshal([]byte(encoded), &c) != nil || c.Reload {
+				return r.reloadLocked(ctx)
+			}
+			pending.Users = append(pending.Users, c.Users...)
+			pending.Principals = append(pending.Principals, c.Principals...)
+			pending.Records = append(pending.Records, c.Records...)
+			pending.Maintenance = pending.Maintenance || c.Maintenance
+			from = entry.ID
+		}
+		if len(entries) < redisLoadBatch-1 {
+			break
+		}
+	}
+	if err := r.refresh(ctx, pending); err != nil {
+		return err
+	}
+	r.lastID = from
+	return nil
+}
+
+// refresh reads c's entries from Redis into the index
+func (r *RedisStore) refresh(ctx context.Context, c change) error {
+	slices.Sort(c.Users)
+	users := slices.Compact(c.Users)
+	for start := 0; start < len(users); start += redisLoadBatch {
+		batch := users[start:min(start+redisLoadBatch, len(users))]
+		type read struct {
+			rating, expiry *redis.FloatCmd
+			bot            *redis.BoolCmd
+			key            *redis.StringCmd
+		}
+		reads := make([]read, len(batch))
+		_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, username := range batch {
+				reads[i] = read{
+					rating: pipe.ZScore(ctx, r.keys.ratings, username),
+					bot:    pipe.SIsMember(ctx, r.keys.bots, username),
+					expiry: pipe.ZScore(ctx, r.keys.expiries, username),
+					key:    pipe.HGet(ctx, r.keys.sortKeys, username),
+				}
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		for i, username := range batch {
+			negated, err := reads[i].rating.Result()
+			if errors.Is(err, redis.Nil) {
+				r.MemoryStore.setUser(username, nil)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			user := &User{Username: username, Rating: int(-negated), Bot: reads[i].bot.Val()}
+			user.Key, _ = strconv.ParseInt(reads[i].key.Val(), 10, 64)
+			if ms, err := reads[i].expiry.Result(); err == nil {
+				user.ExpiresAt = time.UnixMilli(int64(ms)).UTC()
+			}
+			r.MemoryStore.setUser(username, user)
+		}
+	}
+
+	for _, principal := range c.Principals {
+		roles, err := r.client.HGet(ctx, r.keys.roles, principal).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		r.MemoryStore.setRoles(principal, splitRoles(roles))
+	}
+	for _, record := range c.Records {
+		value, err := r.client.HGet(ctx, r.keys.records(record[0]), record[1]).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		r.MemoryStore.setRecord(record[0], record[1], value)
+	}
+	if c.Maintenance {
+		m, err := r.readMaintenance(ctx)
+		if err != nil {
+			return err
+		}
+		r.MemoryStore.SetMaintenance(m)
+	}
+	return nil
+}
+
+func splitRoles(roles string) []string {
+	if roles == "" {
+		return nil
+	}
+	return strings.Split(roles, ",")
+}
+
+func (r *RedisStore) readMaintenance(ctx context.Context) (Maintenance, error) {
+	var m Maintenance
+	encoded, err := r.client.Get(ctx, r.keys.maintenance).Result()
+	if errors.Is(err, redis.Nil) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	return m, json.Unmarshal([]byte(encoded), &m)
+}
+
+// reload reads the whole board from Redis into the index
+func (r *RedisStore) reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked(ctx)
+}
+
+// reloadLocked is reload with mu held. The stream's last change is read
+// first, so writes made while the board loads are caught up with after.
+func (r *RedisStore) reloadLocked(ctx context.Context) error {
+	lastID, err := r.lastChange(ctx)
+	if err != nil {
+		return err
+	}
+	snap, err := r.read(ctx)
+	if err != nil {
+		return err
+	}
+	// Restoring indexes the whole board at once, and rebuilds the sort keys
+	r.MemoryStore.Restore(snap)
+	r.lastID = lastID
+	return nil
+}
+
+// read reads everything the store keeps in Redis
+func (r *RedisStore) read(ctx context.Context) (Snapshot, error) {
+	var snap Snapshot
+	bots, err := r.client.SMembers(ctx, r.keys.bots).Result()
+	if err != nil {
+		return snap, err
+	}
+	isBot := make(map[string]bool, len(bots))
+	for _, username := range bots {
+		isBot[username] = true
+	}
+	expiries, err := r.client.ZRangeWithScores(ctx, r.keys.expiries, 0, -1).Result()
+	if err != nil {
+		return snap, err
+	}
+	expiresAt := make(map[string]time.Time, len(expiries))
+	for _, z := range expiries {
+		username, _ := z.Member.(string)
+		expiresAt[username] = time.UnixMilli(int64(z.Score)).UTC()
+	}
+	sortKeys, err := r.client.HGetAll(ctx, r.keys.sortKeys).Result()
+	if err != nil {
+		return snap, err
+	}
+
+	for start := int64(0); ; start += redisLoadBatch {
+		page, err := r.client.ZRangeWithScores(ctx, r.keys.ratings, start, start+redisLoadBatch-1).Result()
+		if err != nil {
+			return snap, err
+		}
+		for _, z := range page {
+			username, _ := z.Member.(string)
+			user := User{Username: username, Rating: int(-z.Score), Bot: isBot[username], ExpiresAt: expiresAt[username]}
+			user.Key, _ = strconv.ParseInt(sortKeys[username], 10, 64)
+			snap.Users = append(snap.Users, user)
+		}
+		if len(page) < redisLoadBatch {
+			break
+		}
+	}
+
+	roles, err := r.client.HGetAll(ctx, r.keys.roles).Result()
+	if err != nil {
+		return snap, err
+	}
+	snap.Roles = make(map[string][]string, len(roles))
+	for principal, joined := range roles {
+		snap.Roles[principal] = splitRoles(joined)
+	}
+	if snap.Maintenance, err = r.readMaintenance(ctx); err != nil {
+		return snap, err
+	}
+
+	kinds, err := r.recordKeys(ctx)
+	if err != nil {
+		return snap, err
+	}
+	snap.Records = make(map[string]map[string]string, len(kinds))
+	for _, key := range kinds {
+		records, err := r.client.HGetAll(ctx, key).Result()
+		if err != nil {
+			return snap, err
+		}
+		snap.Records[strings.TrimPrefix(key, r.keys.records(""))] = records
+	}
+	return snap, nil
+}
+
+// recordKeys returns the hashes records are kept in on Redis
+func (r *RedisStore) recordKeys(ctx context.Context) ([]string, error) {
+	var keys []string
+	iter := r.client.Scan(ctx, 0, r.keys.records("*"), redisLoadBatch).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// follow catches the index up whenever another process logs a change, until
+// ctx ends. Failures are logged and retried.
+func (r *RedisStore) follow(ctx context.Context) {
+	defer close(r.done)
+	for {
+		r.mu.Lock()
+		last := r.lastID
+		r.mu.Unlock()
+
+		_, err := r.client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{r.keys.changes, last},
+			Count:   1,
+			Block:   redisFollowBlock,
+		}).Result()
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err == nil {
+			r.mu.Lock()
+			err = r.catchUp(ctx)
+			r.mu.Unlock()
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to follow the board's changes on Redis: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+// evicted collects the users the index evicts during a write, for write to
+// commit and pass on
+func (r *RedisStore) evicted(user *User) {
+	r.evicting = append(r.evicting, user)
+}
+
+// AddUser adds or updates a user, keeping an existing user's bot flag
+func (r *RedisStore) AddUser(username string, rating int) error {
+	return r.write(change{Users: []string{username}}, func() ([]*User, error) {
+		return nil, r.MemoryStore.AddUser(username, rating)
+	})
+}
+
+// AddBot adds or updates a user flagged as a bot
+func (r *RedisStore) AddBot(username string, rating int) error {
+	return r.write(change{Users: []string{username}}, func() ([]*User, error) {
+		return nil, r.MemoryStore.AddBot(username, rating)
+	})
+}
+
+// CreateUser adds a new user, failing with ErrUserExists if the name is taken
+func (r *RedisStore) CreateUser(username string, rating int) error {
+	return r.write(change{Users: []string{username}}, func() ([]*User, error) {
+		return nil, r.MemoryStore.CreateUser(username, rating)
+	})
+}
+
+// AddBots adds or updates bot users in one write. If Redis refuses it, none
+// of them are written.
+func (r *RedisStore) AddBots(bots []NewBot) (written []NewBot, err error) {
+	usernames := make([]string, len(bots))
+	for i, bot := range bots {
+		usernames[i] = bot.Username
+	}
+	var writeErr error
+	err = r.write(change{Users: usernames}, func() ([]*User, error) {
+		written, writeErr = r.MemoryStore.AddBots(bots)
+		return nil, writeErr
+	})
+	if err != writeErr {
+		written = nil
+	}
+	return written, err
+}
+
+// RaiseRating sets an existing user's rating only if it is higher than the
+// current one. Under ranking.SharedRank, Redis compares and raises in one
+// step, so raises from any number of processes never contend: with ZADD's
+// XX LT CH flags where the server has them (ratings are stored negated),
+// else with a script. Under ranking.MostRecentFirst the raise also stamps
+// the user's sort key, so it goes through the usual write.
+func (r *RedisStore) RaiseRating(username string, rating int) (previous int, raised bool, err error) {
+	if r.MemoryStore.TiePolicy() == ranking.MostRecentFirst {
+		return r.raiseByWrite(username, rating)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ctx := context.Background()
+	var result raiseResult
+	if r.zaddLT {
+		result, err = r.raiseWithZadd(ctx, username, rating)
+	} else {
+		result, err = r.raiseWithScript(ctx, username, rating)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	// The raise can be applied to the index only if it had seen everything
+	// before it; otherwise catching up reads the user as Redis now holds them
+	if result.lastBefore == r.lastID {
+		if result.found {
+			r.MemoryStore.setUser(username, r.raised(username, result, rating))
+		}
+		r.lastID = result.id
+	}
+	if !result.found {
+		return 0, false, ErrUserNotFound
+	}
+	return result.previous, result.raised, nil
+}
+
+// raiseByWrite raises a rating through the usual write
+func (r *RedisStore) raiseByWrite(username string, rating int) (previous int, raised bool, err error) {
+	err = r.write(change{Users: []string{username}}, func() ([]*User, error) {
+		var err error
+		previous, raised, err = r.MemoryStore.RaiseRating(username, rating)
+		return nil, err
+	})
+	if err != nil {
+		raised = false
+	}
+	return previous, raised, err
+}
+
+// raised is username's entry after a raise, for an index that was current
+// before it
+func (r *RedisStore) raised(username string, result raiseResult, rating int) *User {
+	user := &User{Username: username}
+	if current, err := r.MemoryStore.GetUser(username); err == nil {
+		copied := *current
+		user = &copied
+	}
+	user.Rating = result.previous
+	if result.raised {
+		user.Rating = rating
+	}
+	user.Key = ranking.RatingKey(user.Rating)
+	return user
+}
+
+// raiseResult is what a raise found and did on Redis
+type raiseResult struct {
+	found      bool
+	previous   int
+	raised     bool
+	lastBefore string // Last change on the stream before the raise's own
+	id         string // The raise's change
+}
+
+// raiseWithZadd raises a rating with ZADD XX LT CH, in a transaction that
+// logs the change. The change is logged even when the rating isn't raised,
+// as a transaction can't branch; processes reading it find the user as is.
+func (r *RedisStore) raiseWithZadd(ctx context.Context, username string, rating int) (raiseResult, error) {
+	logged, err := json.Marshal(change{Users: []string{username}})
+	if err != nil {
+		return raiseResult{}, err
+	}
+
+	var last *redis.XMessageSliceCmd
+	var current *redis.FloatCmd
+	var changed *redis.IntCmd
+	var added *redis.StringCmd
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		last = pipe.XRevRangeN(ctx, r.keys.changes, "+", "-", 1)
+		current = pipe.ZScore(ctx, r.keys.ratings, username)
+		changed = pipe.ZAddArgs(ctx, r.keys.ratings, redis.ZAddArgs{
+			XX:      true,
+			LT:      true,
+			Ch:      true,
+			Members: []redis.Z{{Score: score(rating), Member: username}},
+		})
+		added = pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: r.keys.changes,
+			MaxLen: redisChangesMax,
+			Approx: true,
+			Values: map[string]any{"change": string(logged)},
+		})
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return raiseResult{}, err
+	}
+
+	result := raiseResult{lastBefore: "0-0", id: added.Val()}
+	if messages := last.Val(); len(messages) > 0 {
+		result.lastBefore = messages[0].ID
+	}
+	negated, err := current.Result()
+	if errors.Is(err, redis.Nil) {
+		return result, nil
+	}
+	if err != nil {
+		return raiseResult{}, err
+	}
+	result.found = true
+	result.previous = int(-negated)
+	result.raised = changed.Val() == 1
+	return result, nil
+}
+
+// raiseScript raises ARGV[1]'s negated rating to ARGV[2] if that is lower,
+// logging ARGV[3] as a change trimmed to about ARGV[4] entries if it was.
+// It returns whether the user was found and raised, their previous negated
+// rating, the stream's last change before and the raise's own change.
+var raiseScript = redis.NewScript(`
+local last = redis.call('XREVRANGE', KEYS[1], '+', '-', 'COUNT', 1)
+local lastID = '0-0'
+if last[1] then lastID = last[1][1] end
+local current = redis.call('ZSCORE', KEYS[2], ARGV[1])
+if not current then return {0, '', lastID, lastID} end
+if tonumber(ARGV[2]) >= tonumber(current) then return {1, current, lastID, lastID} end
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+return {2, current, lastID, redis.call('XADD', KEYS[1], 'MAXLEN', '~', ARGV[4], '*', 'change', ARGV[3])}
+`)
+
+// raiseWithScript raises a rating with raiseScript, for servers older than
+// Redis 6.2
+func (r *RedisStore) raiseWithScript(ctx context.Context, username string, rating int) (raiseResult, error) {
+	logged, err := json.Marshal(change{Users: []string{username}})
+	if err != nil {
+		return raiseResult{}, err
+	}
+	reply, err := raiseScript.Run(ctx, r.client, []string{r.keys.changes, r.keys.ratings},
+		username, score(rating), string(logged), redisChangesMax).Slice()
+	if err != nil {
+		return raiseResult{}, err
+	}
+	if len(reply) != 4 {
+		return raiseResult{}, fmt.Errorf("unexpected raise result %v", reply)
+	}
+
+	status, _ := reply[0].(int64)
+	result := raiseResult{found: status > 0, raised: status == 2}
+	result.lastBefore, _ = reply[2].(string)
+	result.id, _ = reply[3].(string)
+	if result.found {
+		current, _ := reply[1].(string)
+		negated, err := strconv.ParseFloat(current, 64)
+		if err != nil {
+			return raiseResult{}, fmt.Errorf("unexpected rating %q: %w", current, err)
+		}
+		result.previous = int(-negated)
+	}
+	return result, nil
+}
+
+// supportsZaddLT reports whether the server is Redis 6.2 or later, which
+// added ZADD's GT and LT flags. Servers that don't say are assumed not to.
+func supportsZaddLT(ctx context.Context, client redis.UniversalClient) bool {
+	info, err := client.Info(ctx, "server").Result()
+	if err != nil {
+		return false
+	}
+	return redisVersionAtLeast(info, 6, 2)
+}
+
+// redisVersionAtLeast reports whether the redis_version in an INFO reply is
+// at least major.minor
+func redisVersionAtLeast(info string, major, minor int) bool {
+	for _, line := range strings.Split(info, "\n") {
+		version, ok := strings.CutPrefix(strings.TrimSpace(line), "redis_version:")
+		if !ok {
+			continue
+		}
+		parts := strings.SplitN(version, ".", 3)
+		if len(parts) < 2 {
+			return false
+		}
+		gotMajor, err1 := strconv.Atoi(parts[0])
+		gotMinor, err2 := strconv.Atoi(parts[1])
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		return gotMajor > major || (gotMajor == major && gotMinor >= minor)
+	}
+	return false
+}
+
+// UpdateRatings sets the ratings of existing users in one write, as
+// MemoryStore's does. If Redis refuses it, the failure is logged and no
+// rating changes.
+func (r *RedisStore) UpdateRatings(usernames []string, ratings map[string]int) []RatingChange {
+	var changes []RatingChange
+	err := r.write(change{Users: usernames}, func() ([]*User, error) {
+		changes = r.MemoryStore.UpdateRatings(usernames, ratings)
+		return nil, nil
+	})
+	if err != nil {
+		log.Printf("Failed to update %d rating(s) on Redis: %v", len(usernames), err)
+		return nil
+	}
+	return changes
+}
+
+// MergeUsers sets into's rating and removes from in one step
+func (r *RedisStore) MergeUsers(from, into string, rating int) (previous int, removed *User, err error) {
+	err = r.write(change{Users: []string{from, into}}, func() ([]*User, error) {
+		var err error
+		previous, removed, err = r.MemoryStore.MergeUsers(from, into, rating)
+		return nil, err
+	})
+	if err != nil {
+		removed = nil
+	}
+	return previous, removed, err
+}
+
+// RestoreUser puts back a user removed by MergeUsers as they were
+func (r *RedisStore) RestoreUser(user *User) error {
+	return r.write(change{Users: []string{user.Username}}, func() ([]*User, error) {
+		return nil, r.MemoryStore.RestoreUser(user)
+	})
+}
+
+// RevertRating puts a user's rating and sort key back, as MemoryStore's does
+func (r *RedisStore) RevertRating(before *User, written int) (reverted bool, err error) {
+	err = r.write(change{Users: []string{before.Username}}, func() ([]*User, error) {
+		var err error
+		reverted, err = r.MemoryStore.RevertRating(before, written)
+		return nil, err
+	})
+	if err != nil {
+		reverted = false
+	}
+	return reverted, err
+}
+
+// RemoveUser deletes a user
+func (r *RedisStore) RemoveUser(username string) error {
+	return r.write(change{Users: []string{username}}, func() ([]*User, error) {
+		return nil, r.MemoryStore.RemoveUser(username)
+	})
+}
+
+// SetExpiry schedules a user's entry to be removed at expiresAt
+func (r *RedisStore) SetExpiry(username string, expiresAt time.Time) (user *User, err error) {
+	err = r.write(change{Users: []string{username}}, func() ([]*User, error) {
+		var err error
+		user, err = r.MemoryStore.SetExpiry(username, expiresAt)
+		return nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// RemoveExpired deletes every entry whose expiry is at or before now and
+// returns them. Only one process removes each entry; if Redis refuses the
+// removal it is logged and nothing is removed, to be tried on the next sweep.
+func (r *RedisStore) RemoveExpired(now time.Time) []*User {
+	var removed []*User
+	err := r.write(change{}, func() ([]*User, error) {
+		removed = r.MemoryStore.RemoveExpired(now)
+		return removed, nil
+	})
+	if err != nil {
+		log.Printf("Failed to remove %d expired entries on Redis: %v", len(removed), err)
+		return nil
+	}
+	return removed
+}
+
+// SetCapacity caps the number of users; evicted members leave Redis with
+// the write that evicted them
+func (r *RedisStore) SetCapacity(capacity int, onEvict func(*User)) {
+	r.mu.Lock()
+	r.onEvict = onEvict
+	r.mu.Unlock()
+	r.MemoryStore.SetCapacity(capacity, r.evicted)
+}
+
+// SetMemoryBudget bounds the index's approximate bytes; evicted members
+// leave Redis with the write that evicted them
+func (r *RedisStore) SetMemoryBudget(budget MemoryBudget, onEvict func(*User)) {
+	r.mu.Lock()
+	r.onEvict = onEvict
+	r.mu.Unlock()
+	r.MemoryStore.SetMemoryBudget(budget, r.evicted)
+}
+
+// GrantRole gives principal a role, reporting whether it was newly granted.
+// A grant Redis refuses is logged and not made.
+func (r *RedisStore) GrantRole(principal, role string) bool {
+	var granted bool
+	err := r.write(change{Principals: []string{principal}}, func() ([]*User, error) {
+		granted = r.MemoryStore.GrantRole(principal, role)
+		return nil, nil
+	})
+	if err != nil {
+		log.Printf("Failed to grant %s to %s on Redis: %v", role, principal, err)
+		return false
+	}
+	return granted
+}
+
+// RevokeRole removes a role from principal, reporting whether it was held.
+// A revocation Redis refuses is logged and not made.
+func (r *RedisStore) RevokeRole(principal, role string) bool {
+	var revoked bool
+	err := r.write(change{Principals: []string{principal}}, func() ([]*User, error) {
+		revoked = r.MemoryStore.RevokeRole(principal, role)
+		return nil, nil
+	})
+	if err != nil {
+		log.Printf("Failed to revoke %s from %s on Redis: %v", role, principal, err)
+		return false
+	}
+	return revoked
+}
+
+// SetMaintenance replaces the maintenance mode for every process on the
+// board. A change Redis refuses is logged and not made.
+func (r *RedisStore) SetMaintenance(m Maintenance) {
+	err := r.write(change{Maintenance: true}, func() ([]*User, error) {
+		r.MemoryStore.SetMaintenance(m)
+		return nil, nil
+	})
+	if err != nil {
+		log.Printf("Failed to set the maintenance mode on Redis: %v", err)
+	}
+}
+
+// UpdateRecord replaces a record, as MemoryStore's does, for every process
+// on the board
+func (r *RedisStore) UpdateRecord(kind, key string, update func(current string) (string, error)) (value string, err error) {
+	err = r.write(change{Records: [][2]string{{kind, key}}}, func() ([]*User, error) {
+		var err error
+		value, err = r.MemoryStore.UpdateRecord(kind, key, update)
+		return nil, err
+	})
+	return value, err
+}
+
+// replaceScript moves each of the first ARGV[1] KEYS onto the key that
+// follows it, deleting the target when there is nothing to move, then logs
+// ARGV[2] as a change. EXISTS is checked first, as RENAME fails on a
+// missing key and Redis wouldn't undo the renames before it.
+var replaceScript = redis.NewScript(`
+for i = 1, tonumber(ARGV[1]) * 2, 2 do
+  if redis.call('EXISTS', KEYS[i]) == 1 then
+    redis.call('RENAME', KEYS[i], KEYS[i + 1])
+  else
+    redis.call('DEL', KEYS[i + 1])
+  end
+end
+return redis.call('XADD', KEYS[#KEYS], 'MAXLEN', '~', ARGV[3], '*', 'change', ARGV[2])
+`)
+
+// replace writes snap to temporary keys, then swaps them for the board's in
+// one step, so other processes see the old board or the new one. They
+// reload it when they see the change.
+func (r *RedisStore) replace(ctx context.Context, snap Snapshot) error {
+	temp := func(key string) string {
+		return r.keys.prefix + "replacing:" + strings.TrimPrefix(key, r.keys.prefix)
+	}
+	recordKeys, err := r.recordKeys(ctx)
+	if err != nil {
+		return err
+	}
+	targets := []string{r.keys.ratings, r.keys.bots, r.keys.expiries, r.keys.sortKeys, r.keys.roles, r.keys.maintenance}
+	targets = append(targets, recordKeys...)
+	for kind := range snap.Records {
+		if key := r.keys.records(kind); !slices.Contains(targets, key) {
+			targets = append(targets, key)
+		}
+	}
+
+	var keys, temps []string
+	for _, target := range targets {
+		keys = append(keys, temp(target), target)
+		temps = append(temps, temp(target))
+	}
+	// Left behind if a replace failed partway
+	if err := r.client.Del(ctx, temps...).Err(); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(snap.Users); start += redisLoadBatch {
+		batch := snap.Users[start:min(start+redisLoadBatch, len(snap.Users))]
+		_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, user := range batch {
+				pipe.ZAdd(ctx, temp(r.keys.ratings), redis.Z{Score: score(user.Rating), Member: user.Username})
+				if user.Bot {
+					pipe.SAdd(ctx, temp(r.keys.bots), user.Username)
+				}
+				if !user.ExpiresAt.IsZero() {
+					pipe.ZAdd(ctx, temp(r.keys.expiries), redis.Z{Score: float64(user.ExpiresAt.UnixMilli()), Member: user.Username})
+				}
+				if user.Key != 0 && user.Key != ranking.RatingKey(user.Rating) {
+					pipe.HSet(ctx, temp(r.keys.sortKeys), user.Username, user.Key)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for principal, roles := range snap.Roles {
+			if len(roles) > 0 {
+				pipe.HSet(ctx, temp(r.keys.roles), principal, strings.Join(roles, ","))
+			}
+		}
+		encoded, err := json.Marshal(snap.Maintenance)
+		if err != nil {
+			return err
+		}
+		pipe.Set(ctx, temp(r.keys.maintenance), encoded, 0)
+		for kind, records := range snap.Records {
+			for key, value := range records {
+				pipe.HSet(ctx, temp(r.keys.records(kind)), key, value)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logged, err := json.Marshal(change{Reload: true})
+	if err != nil {
+		return err
+	}
+	id, err := replaceScript.Run(ctx, r.client, append(keys, r.keys.changes), len(targets), string(logged), redisChangesMax).Text()
+	if err != nil {
+		return err
+	}
+	r.MemoryStore.Restore(snap)
+	r.lastID = id
+	return nil
+}
+
+// Clear removes all users, keeping role grants, records and the maintenance
+// mode, as MemoryStore's does. If Redis refuses, the failure is logged and
+// nobody is removed.
+func (r *RedisStore) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ctx := context.Background()
+	snap := r.MemoryStore.Snapshot()
+	snap.Users = nil
+	if err := r.replace(ctx, snap); err != nil {
+		log.Printf("Failed to clear the board on Redis: %v", err)
+	}
+}
+
+// Restore replaces the board with snap, in Redis and then in the index, in
+// one step for every process on it. If Redis refuses, the failure is logged
+// and the board left as it was.
+func (r *RedisStore) Restore(snap Snapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.replace(context.Background(), snap); err != nil {
+		log.Printf("Failed to restore the board on Redis: %v", err)
+	}
+}
@@ -0,0 +1,470 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const testPrefix = "test:leaderboard:"
+
+// newTestRedisStores starts a Redis and opens n stores on the same board
+func newTestRedisStores(t *testing.T, n int) (*miniredis.Miniredis, []*RedisStore) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	stores := make([]*RedisStore, n)
+	for i := range stores {
+		stores[i] = openTestRedisStore(t, mr)
+	}
+	return mr, stores
+}
+
+func openTestRedisStore(t *testing.T, mr *miniredis.Miniredis) *RedisStore {
+	t.Helper()
+	r, err := NewRedisStore(context.Background(), redis.NewClient(&redis.Options{Addr: mr.Addr()}), testPrefix)
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+// caughtUp catches r up with the board, as its follower soon would
+func caughtUp(t *testing.T, r *RedisStore) *RedisStore {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.catchUp(context.Background()); err != nil {
+		t.Fatalf("catchUp: %v", err)
+	}
+	return r
+}
+
+func rating(t *testing.T, s Store, username string) int {
+	t.Helper()
+	user, err := s.GetUser(username)
+	if err != nil {
+		t.Fatalf("GetUser(%s): %v", username, err)
+	}
+	return user.Rating
+}
+
+func TestRedisStoreSharesWrites(t *testing.T) {
+	_, stores := newTestRedisStores(t, 2)
+	a, b := stores[0], stores[1]
+
+	if err := a.AddUser("alice", 1500); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddBot("bot_1", 1400); err != nil {
+		t.Fatal(err)
+	}
+	if got := rating(t, caughtUp(t, b), "alice"); got != 1500 {
+		t.Errorf("b sees alice at %d, want 1500", got)
+	}
+	if user, _ := b.GetUser("bot_1"); user == nil || !user.Bot {
+		t.Errorf("b sees bot_1 as %+v, want a bot", user)
+	}
+
+	if err := b.RemoveUser("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := caughtUp(t, a).GetUser("alice"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("a still sees alice after b removed her: %v", err)
+	}
+}
+
+func TestRedisStoreWriteOnStaleIndexRetries(t *testing.T) {
+	_, stores := newTestRedisStores(t, 2)
+	a, b := stores[0], stores[1]
+	b.stop() // Keep b behind until it writes
+
+	if err := a.AddUser("alice", 1500); err != nil {
+		t.Fatal(err)
+	}
+	// b's index hasn't seen alice, so its first attempt fails and is retried
+	previous, raised, err := b.RaiseRating("alice", 1600)
+	if err != nil || !raised || previous != 1500 {
+		t.Fatalf("RaiseRating = %d, %v, %v; want 1500, true, nil", previous, raised, err)
+	}
+	if got := rating(t, caughtUp(t, a), "alice"); got != 1600 {
+		t.Errorf("a sees alice at %d, want 1600", got)
+	}
+}
+
+// raiseModes are the ways a RedisStore can raise a rating
+var raiseModes = []struct {
+	name   string
+	zaddLT bool
+}{
+	{"zadd", true},
+	{"script", false},
+}
+
+func TestRedisStoreRaiseRating(t *testing.T) {
+	for _, mode := range raiseModes {
+		t.Run(mode.name, func(t *testing.T) {
+			_, stores := newTestRedisStores(t, 2)
+			a, b := stores[0], stores[1]
+			a.zaddLT = mode.zaddLT
+			if err := a.AddBot("alice", 1500); err != nil {
+				t.Fatal(err)
+			}
+
+			cases := []struct {
+				name         string
+				username     string
+				rating       int
+				wantPrevious int
+				wantRaised   bool
+				wantErr      error
+				wantRating   int
+			}{
+				{"higher raises", "alice", 1600, 1500, true, nil, 1600},
+				{"equal doesn't", "alice", 1600, 1600, false, nil, 1600},
+				{"lower doesn't", "alice", 1200, 1600, false, nil, 1600},
+				{"missing user", "bob", 1700, 0, false, ErrUserNotFound, 0},
+			}
+			for _, c := range cases {
+				previous, raised, err := a.RaiseRating(c.username, c.rating)
+				if previous != c.wantPrevious || raised != c.wantRaised || !errors.Is(err, c.wantErr) {
+					t.Errorf("%s: RaiseRating = %d, %v, %v; want %d, %v, %v",
+						c.name, previous, raised, err, c.wantPrevious, c.wantRaised, c.wantErr)
+				}
+				if c.wantErr != nil {
+					continue
+				}
+				if got := rating(t, a, c.username); got != c.wantRating {
+					t.Errorf("%s: a indexes %s at %d, want %d", c.name, c.username, got, c.wantRating)
+				}
+			}
+			if _, err := b.GetUser("bob"); !errors.Is(err, ErrUserNotFound) {
+				t.Errorf("raising a missing user created them: %v", err)
+			}
+			user, err := caughtUp(t, b).GetUser("alice")
+			if err != nil || user.Rating != 1600 || !user.Bot {
+				t.Errorf("b sees alice as %+v, %v; want a bot at 1600", user, err)
+			}
+			// a's index followed its own raises, so its next write isn't stale
+			if err := a.AddUser("carol", 1000); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRedisStoreRaiseOnStaleIndex(t *testing.T) {
+	for _, mode := range raiseModes {
+		t.Run(mode.name, func(t *testing.T) {
+			_, stores := newTestRedisStores(t, 2)
+			a, b := stores[0], stores[1]
+			b.zaddLT = mode.zaddLT
+			b.stop()
+
+			if err := a.AddUser("alice", 1500); err != nil {
+				t.Fatal(err)
+			}
+			// Redis decides, whatever b's index holds
+			previous, raised, err := b.RaiseRating("alice", 1600)
+			if err != nil || !raised || previous != 1500 {
+				t.Fatalf("RaiseRating = %d, %v, %v; want 1500, true, nil", previous, raised, err)
+			}
+			if got := rating(t, caughtUp(t, b), "alice"); got != 1600 {
+				t.Errorf("b sees alice at %d after catching up, want 1600", got)
+			}
+		})
+	}
+}
+
+func TestRedisStoreConcurrentRaisesKeepTheHighest(t *testing.T) {
+	for _, mode := range raiseModes {
+		t.Run(mode.name, func(t *testing.T) {
+			_, stores := newTestRedisStores(t, 3)
+			if err := stores[0].AddUser("alice", 1000); err != nil {
+				t.Fatal(err)
+			}
+
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			raises := 0
+			for i, r := range stores {
+				r.zaddLT = mode.zaddLT
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for step := range 20 {
+						_, raised, err := r.RaiseRating("alice", 1000+step*len(stores)+i)
+						if err != nil {
+							t.Error(err)
+						}
+						if raised {
+							mu.Lock()
+							raises++
+							mu.Unlock()
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			want := 1000 + 19*len(stores) + len(stores) - 1
+			for i, r := range stores {
+				if got := rating(t, caughtUp(t, r), "alice"); got != want {
+					t.Errorf("store %d sees alice at %d, want %d", i, got, want)
+				}
+			}
+			if raises == 0 || raises > 20*len(stores) {
+				t.Errorf("%d raises reported", raises)
+			}
+		})
+	}
+}
+
+func TestRedisVersionAtLeast(t *testing.T) {
+	cases := []struct {
+		info string
+		want bool
+	}{
+		{"# Server\r\nredis_version:7.2.4\r\nredis_mode:standalone\r\n", true},
+		{"# Server\r\nredis_version:6.2.0\r\n", true},
+		{"# Server\r\nredis_version:6.0.16\r\n", false},
+		{"# Server\r\nredis_version:5.0.7\r\n", false},
+		{"# Server\r\nredis_version:10.0.0\r\n", true},
+		{"# Server\r\nredis_version:unknown\r\n", false},
+		{"# Clients\r\nconnected_clients:1\r\n", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		if got := redisVersionAtLeast(c.info, 6, 2); got != c.want {
+			t.Errorf("redisVersionAtLeast(%q, 6, 2) = %v, want %v", c.info, got, c.want)
+		}
+	}
+}
+
+func TestRedisStoreFailedWriteLeavesIndexUnchanged(t *testing.T) {
+	mr, stores := newTestRedisStores(t, 1)
+	r := stores[0]
+	if err := r.AddUser("alice", 1500); err != nil {
+		t.Fatal(err)
+	}
+
+	mr.SetError("READONLY You can't write against a read only replica")
+	if err := r.AddUser("bob", 1400); err == nil {
+		t.Error("AddUser succeeded with Redis failing")
+	}
+	if changes := r.UpdateRatings([]string{"alice"}, map[string]int{"alice": 1700}); changes != nil {
+		t.Errorf("UpdateRatings = %v with Redis failing, want nil", changes)
+	}
+	if removed := r.RemoveExpired(time.Now().Add(time.Hour)); removed != nil {
+		t.Errorf("RemoveExpired = %v with Redis failing, want nil", removed)
+	}
+	mr.SetError("")
+
+	if _, err := r.GetUser("bob"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("bob is indexed after a failed write: %v", err)
+	}
+	if got := rating(t, r, "alice"); got != 1500 {
+		t.Errorf("alice is indexed at %d after a failed update, want 1500", got)
+	}
+}
+
+func TestRedisStoreChecksKeyTypesBeforeWriting(t *testing.T) {
+	mr, stores := newTestRedisStores(t, 1)
+	r := stores[0]
+	mr.Set(testPrefix+"bots", "not a set")
+
+	if err := r.AddBot("bot_1", 1400); err == nil {
+		t.Fatal("AddBot succeeded with its bots key holding a string")
+	}
+	if mr.Exists(testPrefix + "ratings") {
+		t.Error("the rating was written though the write failed")
+	}
+	if _, err := r.GetUser("bot_1"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("bot_1 is indexed after a failed write: %v", err)
+	}
+}
+
+func TestRedisStoreEvictionsLeaveRedis(t *testing.T) {
+	_, stores := newTestRedisStores(t, 2)
+	a, b := stores[0], stores[1]
+
+	var evicted []string
+	a.SetCapacity(2, func(user *User) { evicted = append(evicted, user.Username) })
+	for i, username := range []string{"alice", "bob", "carol"} {
+		if err := a.AddUser(username, 1500+i*100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !slices.Equal(evicted, []string{"alice"}) {
+		t.Errorf("evicted %v, want [alice]", evicted)
+	}
+	if _, err := caughtUp(t, b).GetUser("alice"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("b still sees evicted alice: %v", err)
+	}
+	if got := len(b.Snapshot().Users); got != 2 {
+		t.Errorf("b sees %d users, want 2", got)
+	}
+}
+
+func TestRedisStoreRemoveExpiredOnce(t *testing.T) {
+	_, stores := newTestRedisStores(t, 2)
+	a, b := stores[0], stores[1]
+	if err := a.AddUser("alice", 1500); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	if _, err := a.SetExpiry("alice", now); err != nil {
+		t.Fatal(err)
+	}
+	caughtUp(t, b)
+
+	if removed := a.RemoveExpired(now); len(removed) != 1 {
+		t.Fatalf("a removed %d, want 1", len(removed))
+	}
+	if removed := b.RemoveExpired(now); len(removed) != 0 {
+		t.Errorf("b removed %v again", removed)
+	}
+}
+
+func TestRedisStoreSharesRolesRecordsAndMaintenance(t *testing.T) {
+	_, stores := newTestRedisStores(t, 2)
+	a, b := stores[0], stores[1]
+
+	a.GrantRole("key:ops", "admin")
+	a.SetMaintenance(Maintenance{Mode: "read_only", Message: "migrating"})
+	if _, err := a.UpdateRecord("privacy", "alice", func(string) (string, error) { return `{"anonymize":true}`, nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	caughtUp(t, b)
+	if got := b.Roles("key:ops"); !slices.Equal(got, []string{"admin"}) {
+		t.Errorf("b sees roles %v, want [admin]", got)
+	}
+	if got := b.Maintenance().Mode; got != "read_only" {
+		t.Errorf("b sees maintenance %q, want read_only", got)
+	}
+	if got, _ := b.Record("privacy", "alice"); got != `{"anonymize":true}` {
+		t.Errorf("b sees record %q", got)
+	}
+
+	// A counter incremented from both stores hands out each value once
+	for _, r := range []*RedisStore{a, b, a, b} {
+		if _, err := r.UpdateRecord("counters", "n", func(current string) (string, error) {
+			return current + "x", nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, _ := caughtUp(t, a).Record("counters", "n"); got != "xxxx" {
+		t.Errorf("counter is %q, want xxxx", got)
+	}
+
+	// Roles, records and maintenance survive Clear
+	a.Clear()
+	if got := caughtUp(t, b).Roles("key:ops"); len(got) != 1 {
+		t.Errorf("Clear dropped roles: %v", got)
+	}
+	if b.RecordCount("privacy") != 1 {
+		t.Error("Clear dropped records")
+	}
+}
+
+func TestRedisStoreRestoreReplacesTheBoard(t *testing.T) {
+	mr, stores := newTestRedisStores(t, 2)
+	a, b := stores[0], stores[1]
+	if err := a.AddUser("alice", 1500); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.UpdateRecord("old", "x", func(string) (string, error) { return "1", nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	a.Restore(Snapshot{
+		Users:   []User{{Username: "bob", Rating: 1400, Bot: true}, {Username: "carol", Rating: 1300}},
+		Roles:   map[string][]string{"key:ops": {"admin"}},
+		Records: map[string]map[string]string{"new": {"y": "2"}},
+	})
+
+	for _, key := range mr.Keys() {
+		if len(key) > len(testPrefix+"replacing:") && key[:len(testPrefix+"replacing:")] == testPrefix+"replacing:" {
+			t.Errorf("temporary key %s left behind", key)
+		}
+	}
+	if mr.Exists(testPrefix + "records:old") {
+		t.Error("records missing from the snapshot were kept")
+	}
+
+	for name, r := range map[string]*RedisStore{"a": a, "b": caughtUp(t, b)} {
+		if _, err := r.GetUser("alice"); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("%s still sees alice: %v", name, err)
+		}
+		if user, _ := r.GetUser("bob"); user == nil || !user.Bot || user.Rating != 1400 {
+			t.Errorf("%s sees bob as %+v", name, user)
+		}
+		if got, _ := r.Record("new", "y"); got != "2" {
+			t.Errorf("%s sees record %q, want 2", name, got)
+		}
+		if got := r.Roles("key:ops"); len(got) != 1 {
+			t.Errorf("%s sees roles %v", name, got)
+		}
+	}
+
+	// A new process loads the restored board
+	if got := rating(t, openTestRedisStore(t, mr), "carol"); got != 1300 {
+		t.Errorf("a new store sees carol at %d, want 1300", got)
+	}
+}
+
+func TestRedisStoreReloadsWhenTheStreamWasTrimmed(t *testing.T) {
+	_, stores := newTestRedisStores(t, 2)
+	a, b := stores[0], stores[1]
+	if err := a.AddUser("zed", 1000); err != nil {
+		t.Fatal(err)
+	}
+	caughtUp(t, b).stop()
+
+	for _, username := range []string{"alice", "amy"} {
+		if err := a.AddUser(username, 1500); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The change b saw last is trimmed away, along with alice's
+	if err := a.client.XTrimMaxLen(context.Background(), a.keys.changes, 1).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddUser("bob", 1400); err != nil {
+		t.Fatal(err)
+	}
+
+	caughtUp(t, b)
+	for _, username := range []string{"zed", "alice", "amy", "bob"} {
+		if _, err := b.GetUser(username); err != nil {
+			t.Errorf("b doesn't see %s after reloading: %v", username, err)
+		}
+	}
+}
+
+func TestRedisStoreFollowsOtherProcesses(t *testing.T) {
+	_, stores := newTestRedisStores(t, 2)
+	a, b := stores[0], stores[1]
+
+	if err := a.AddUser("alice", 1500); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := b.GetUser("alice"); err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("b's follower never picked up alice")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
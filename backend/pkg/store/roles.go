@@ -0,0 +1,102 @@
+package store
+
+import (
+	"sort"
+	"sync"
+)
+
+// roleTable holds role grants keyed by principal, such as "user:<name>" or "key:<name>".
+// It lives beside the users but is not touched by Clear, so reseeding the
+// board keeps everyone's access.
+type roleTable struct {
+	mu     sync.RWMutex
+	grants map[string]map[string]struct{} // principal -> roles
+}
+
+// GrantRole gives principal a role, reporting whether it was newly granted
+func (s *MemoryStore) GrantRole(principal, role string) bool {
+	t := &s.roles
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.grants == nil {
+		t.grants = make(map[string]map[string]struct{})
+	}
+	if t.grants[principal] == nil {
+		t.grants[principal] = make(map[string]struct{})
+	}
+	if _, ok := t.grants[principal][role]; ok {
+		return false
+	}
+	t.grants[principal][role] = struct{}{}
+	return true
+}
+
+// RevokeRole removes a role from principal, reporting whether it was held
+func (s *MemoryStore) RevokeRole(principal, role string) bool {
+	t := &s.roles
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.grants[principal][role]; !ok {
+		return false
+	}
+	delete(t.grants[principal], role)
+	if len(t.grants[principal]) == 0 {
+		delete(t.grants, principal)
+	}
+	return true
+}
+
+// Roles returns principal's roles, sorted
+func (s *MemoryStore) Roles(principal string) []string {
+	t := &s.roles
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	roles := make([]string, 0, len(t.grants[principal]))
+	for role := range t.grants[principal] {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// RoleGrants returns every principal's roles
+func (s *MemoryStore) RoleGrants() map[string][]string {
+	t := &s.roles
+	t.mu.RLock()
+	principals := make([]string, 0, len(t.grants))
+	for principal := range t.grants {
+		principals = append(principals, principal)
+	}
+	t.mu.RUnlock()
+
+	grants := make(map[string][]string, len(principals))
+	for _, principal := range principals {
+		if roles := s.Roles(principal); len(roles) > 0 {
+			grants[principal] = roles
+		}
+	}
+	return grants
+}
+
+// setRoles replaces principal's roles, keeping the table in step with grants
+// held elsewhere
+func (s *MemoryStore) setRoles(principal string, roles []string) {
+	t := &s.roles
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(roles) == 0 {
+		delete(t.grants, principal)
+		return
+	}
+	if t.grants == nil {
+		t.grants = make(map[string]map[string]struct{})
+	}
+	t.grants[principal] = make(map[string]struct{}, len(roles))
+	for _, role := range roles {
+		t.grants[principal][role] = struct{}{}
+	}
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+
+	"backend/internal/ranking"
+)
+
+// SearchPosition is a place in leaderboard order (highest key first, then
+// username), used to resume a search after the last user of a page
+type SearchPosition struct {
+	Key      int64 // See ranking.Key
+	Username string
+}
+
+// before reports whether a user comes before p in leaderboard order, or is p
+func (p SearchPosition) before(key int64, username string) bool {
+	return !ranking.Before(p.Key, p.Username, key, username)
+}
+
+// SearchQuery selects a page of users whose names start with Text
+type SearchQuery struct {
+	Text   string          // Matched case-insensitively against the start of the username
+	After  *SearchPosition // Resume after this position; Offset is ignored when set
+	Offset int             // Matches to skip
+	Limit  int
+	// Exclude leaves users out of the matches and their total, such as
+	// those hidden from search; nil leaves nobody out
+	Exclude func(username string) bool
+}
+
+// SearchMatch is a user matching a search and its rank on the whole board
+type SearchMatch struct {
+	User *User
+	Rank int
+}
+
+// SearchRanked returns a page of users matching q in leaderboard order, each
+// with its rank, and the total number of matches. The matches are read from
+// the folded name index, so a search costs the users it matches rather than
+// the board. Ranks are counted for the page alone, in one walk down the
+// rating index.
+func (s *MemoryStore) SearchRanked(ctx context.Context, q SearchQuery) (matches []SearchMatch, total int, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	candidates := s.withPrefix(strings.ToLower(q.Text))
+	found := make([]*User, 0, len(candidates))
+	for i, entry := range candidates {
+		if err := scanCancelled(ctx, i); err != nil {
+			return nil, 0, err
+		}
+		_, username, _ := strings.Cut(entry, "\x00")
+		if q.Exclude == nil || !q.Exclude(username) {
+			found = append(found, s.users[username])
+		}
+	}
+	slices.SortFunc(found, func(a, b *User) int {
+		return ranking.Compare(a.Key, a.Username, b.Key, b.Username)
+	})
+
+	first := q.Offset
+	if q.After != nil {
+		after := *q.After
+		first = sort.Search(len(found), func(i int) bool {
+			return !after.before(found[i].Key, found[i].Username)
+		})
+	}
+	first = min(max(first, 0), len(found))
+	page := found[first:min(first+max(q.Limit, 0), len(found))]
+
+	matches = make([]SearchMatch, 0, len(page))
+	above, next := 0, 0 // Users rated higher than the current match, and the next rating to count
+	var ahead func(key int64) int
+	for i, user := range page {
+		for next < len(s.ratings) && s.ratings[next] > user.Rating {
+			above += len(s.byRating[s.ratings[next]])
+			next++
+		}
+		if i == 0 || page[i-1].Rating != user.Rating {
+			ahead = s.bucketRanks(s.byRating[user.Rating])
+		}
+		matches = append(matches, SearchMatch{User: user, Rank: ranking.FromHigher(above + ahead(user.Key))})
+	}
+	return matches, len(found), nil
+}
+
+// bucketRanks returns a function counting the users of a rating bucket with
+// a key higher than key. Keys only differ within a rating under
+// ranking.MostRecentFirst, so otherwise it always returns 0. Must be called
+// with the lock held.
+func (s *MemoryStore) bucketRanks(bucket map[string]struct{}) func(key int64) int {
+	if s.recency == nil {
+		return func(int64) int { return 0 }
+	}
+	keys := make([]int64, 0, len(bucket))
+	for username := range bucket {
+		keys = append(keys, s.users[username].Key)
+	}
+	slices.Sort(keys)
+	return func(key int64) int {
+		i, _ := slices.BinarySearch(keys, key+1)
+		return len(keys) - i
+	}
+}
@@ -0,0 +1,799 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"backend/internal/ranking"
+)
+
+// shardReplicas is how many points each shard gets on the hash ring. More
+// points spread users more evenly; 160 keeps each shard within about 15% of
+// an even share.
+const shardReplicas = 160
+
+// Shard is one Redis instance a sharded board is partitioned across
+type Shard struct {
+	Name   string // Identifies the shard on the hash ring, usually its address
+	Client redis.UniversalClient
+}
+
+// RankedUser is a user with its rank on the whole board
+type RankedUser struct {
+	User *User
+	Rank int
+}
+
+// ShardedStore partitions a board across several Redis instances by
+// consistent hashing on the username, for boards too big or hot for one.
+// Each shard keeps its users in a sorted set, so a user's writes touch one
+// shard while ranks and pages gather from every shard and merge. Unlike
+// RedisStore it keeps no index in process memory: every read asks the
+// shards, so every process on them serves the same board.
+//
+// Users are ranked by rating alone, as under ranking.SharedRank: the sorted
+// set's float scores can't hold the recency of ranking.MostRecentFirst keys.
+// For the same reason SetTiePolicy is ignored, and as the board isn't held
+// in process memory there is no memory budget; Options.Validate rejects both.
+// Naming shards by address keeps users on the same shard when the address
+// list is reordered; adding a shard moves only the users it takes over.
+//
+// The first topK users are also kept in one global sorted set, so the first
+// pages and the ranks of the users on them are read from a single shard.
+// Role grants, the maintenance mode and records aren't partitioned; they are
+// kept on that same shard, the home shard.
+//
+// A write to one user is atomic on its shard. Writes spanning several
+// users, such as merges, evictions under the member cap and Restore, may be
+// seen halfway by other processes. Methods of Store that can't return an
+// error log a shard that fails and answer without it.
+type ShardedStore struct {
+	shards []Shard
+	ring   []ringPoint // Sorted by hash
+	keys   shardKeys
+	home   redis.UniversalClient // Holds the global top K and what isn't partitioned by user
+	top    globalTop
+
+	mu       sync.RWMutex
+	capacity int
+	onEvict  func(*User)
+}
+
+// shardKeys are the keys a ShardedStore keeps the board under
+type shardKeys struct {
+	prefix   string
+	ratings  string // Each shard's sorted set of usernames by negated rating
+	bots     string // Each shard's set of bot usernames
+	expiries string // Each shard's sorted set of usernames by unix expiry time in milliseconds
+	names    string // Each shard's usernames, all scored 0 so they sort by name
+
+	roles       string // Home shard's hash of principal -> comma-separated roles
+	maintenance string // Home shard's JSON of the maintenance mode
+	kinds       string // Home shard's set of the kinds of records kept
+}
+
+// records is the home shard's hash of the records of kind
+func (k shardKeys) records(kind string) string {
+	return k.prefix + "records:" + kind
+}
+
+// user lists the keys holding a user on its shard, in the order the scripts
+// take them
+func (k shardKeys) user() []string {
+	return []string{k.ratings, k.bots, k.expiries, k.names}
+}
+
+type ringPoint struct {
+	hash  uint64
+	shard int
+}
+
+// NewShardedStore creates a store over shards, keeping its keys under prefix
+func NewShardedStore(shards []Shard, prefix string) (*ShardedStore, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("sharded store: at least one shard is required")
+	}
+	s := &ShardedStore{
+		shards: shards,
+		ring:   make([]ringPoint, 0, len(shards)*shardReplicas),
+		keys: shardKeys{
+			prefix:      prefix,
+			ratings:     prefix + "ratings",
+			bots:        prefix + "bots",
+			expiries:    prefix + "expiries",
+			names:       prefix + "names",
+			roles:       prefix + "roles",
+			maintenance: prefix + "maintenance",
+			kinds:       prefix + "records",
+		},
+		top: globalTop{k: DefaultShardTopK, key: prefix + "top", built: prefix + "top:built"},
+	}
+	seen := make(map[string]bool, len(shards))
+	for i, shard := range shards {
+		if seen[shard.Name] {
+			return nil, fmt.Errorf("sharded store: shard %q is listed twice", shard.Name)
+		}
+		seen[shard.Name] = true
+		for replica := range shardReplicas {
+			s.ring = append(s.ring, ringPoint{hash: hashKey(shard.Name + "#" + strconv.Itoa(replica)), shard: i})
+		}
+	}
+	slices.SortFunc(s.ring, func(a, b ringPoint) int {
+		if a.hash < b.hash {
+			return -1
+		}
+		if a.hash > b.hash {
+			return 1
+		}
+		return 0
+	})
+	s.home = s.shardFor(s.top.key)
+	s.top.client = s.home
+	return s, nil
+}
+
+// hashKey places key on the ring. FNV alone leaves near-identical keys such
+// as "redis-1:6379#0" and "#1" close together, so its result is mixed with
+// MurmurHash3's finalizer to spread them around the ring.
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// shardFor returns the shard owning username: the first ring point at or
+// after its hash, wrapping around
+func (s *ShardedStore) shardFor(username string) redis.UniversalClient {
+	return s.shards[s.shardIndex(username)].Client
+}
+
+func (s *ShardedStore) shardIndex(username string) int {
+	hash := hashKey(username)
+	i, _ := slices.BinarySearchFunc(s.ring, hash, func(p ringPoint, hash uint64) int {
+		if p.hash < hash {
+			return -1
+		}
+		if p.hash > hash {
+			return 1
+		}
+		return 0
+	})
+	if i == len(s.ring) {
+		i = 0
+	}
+	return s.ring[i].shard
+}
+
+// Shards returns how many shards the board is partitioned across
+func (s *ShardedStore) Shards() int {
+	return len(s.shards)
+}
+
+// Close closes every shard's client
+func (s *ShardedStore) Close() error {
+	var errs []error
+	for _, shard := range s.shards {
+		errs = append(errs, shard.Client.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// score is a rating as a sorted set score. Ratings are negated so the sets'
+// ascending order, ties broken by username, is leaderboard order.
+func score(rating int) float64 {
+	return float64(-rating)
+}
+
+// shardPutScript sets a user's rating, adding them if needed, and returns
+// their rating before, "" if they are new, and whether they are a bot. With
+// create set an existing user is left as they were. The bot flag is only
+// ever added, as MemoryStore.AddUser keeps it, and an expiry, when given,
+// replaces theirs.
+// KEYS: ratings, bots, expiries, names. ARGV: username, score, "1" for a
+// bot, "1" to create, expiry in unix milliseconds or "".
+var shardPutScript = redis.NewScript(`
+local old = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not (old and ARGV[4] == '1') then
+	redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+	redis.call('ZADD', KEYS[4], 0, ARGV[1])
+	if ARGV[3] == '1' then
+		redis.call('SADD', KEYS[2], ARGV[1])
+	end
+	if ARGV[5] ~= '' then
+		redis.call('ZADD', KEYS[3], ARGV[5], ARGV[1])
+	end
+end
+return {old or '', redis.call('SISMEMBER', KEYS[2], ARGV[1])}
+`)
+
+// shardSetScript changes an existing user's rating and returns it as it was
+// before, with whether they are a bot, or nil if they aren't on the shard.
+// Under "raise" only a higher rating is written, and under "revert" only
+// while the rating is still the one written.
+// KEYS: ratings, bots. ARGV: username, score, "set", "raise" or "revert",
+// score written.
+var shardSetScript = redis.NewScript(`
+local old = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not old then
+	return false
+end
+local mode = ARGV[3]
+if mode == 'set' or (mode == 'raise' and tonumber(ARGV[2]) < tonumber(old))
+	or (mode == 'revert' and tonumber(old) == tonumber(ARGV[4])) then
+	redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+end
+return {old, redis.call('SISMEMBER', KEYS[2], ARGV[1])}
+`)
+
+// shardRemoveScript removes a user and returns their rating, whether they
+// were a bot and their expiry ("" for none), or nil if they aren't on the
+// shard. Given a time, only a user expiring by then is removed.
+// KEYS: ratings, bots, expiries, names. ARGV: username, unix milliseconds
+// or "".
+var shardRemoveScript = redis.NewScript(`
+local old = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not old then
+	return false
+end
+local expires = redis.call('ZSCORE', KEYS[3], ARGV[1])
+if ARGV[2] ~= '' and not (expires and tonumber(expires) <= tonumber(ARGV[2])) then
+	return false
+end
+local bot = redis.call('SREM', KEYS[2], ARGV[1])
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('ZREM', KEYS[3], ARGV[1])
+redis.call('ZREM', KEYS[4], ARGV[1])
+return {old, bot, expires or ''}
+`)
+
+// shardExpireScript sets an existing user's expiry and returns 1, or 0 if
+// they aren't on the shard.
+// KEYS: ratings, expiries. ARGV: username, unix milliseconds.
+var shardExpireScript = redis.NewScript(`
+if not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	return 0
+end
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+return 1
+`)
+
+// scriptUser reads a script's {score, bot[, expiry]} reply about username
+func scriptUser(username string, reply []any) *User {
+	rating, _ := strconv.ParseFloat(reply[0].(string), 64)
+	user := newShardedUser(username, int(-rating), reply[1].(int64) == 1)
+	if len(reply) > 2 {
+		if ms, err := strconv.ParseFloat(reply[2].(string), 64); err == nil {
+			user.ExpiresAt = time.UnixMilli(int64(ms))
+		}
+	}
+	return user
+}
+
+func flag(set bool) string {
+	if set {
+		return "1"
+	}
+	return "0"
+}
+
+func millis(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+// SetCapacity caps the number of users (0 removes the cap). When a new user
+// arrives at a full board the lowest-ranked member is evicted and passed to
+// onEvict. Processes joining users at once each evict for their own, so the
+// board can briefly hold a few more or fewer than the cap.
+func (s *ShardedStore) SetCapacity(capacity int, onEvict func(*User)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.capacity = capacity
+	s.onEvict = onEvict
+}
+
+// Capacity returns the configured member cap (0 means unlimited)
+func (s *ShardedStore) Capacity() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.capacity
+}
+
+// SetMemoryBudget does nothing: the board is held in Redis, whose own
+// maxmemory bounds it
+func (s *ShardedStore) SetMemoryBudget(budget MemoryBudget, onEvict func(*User)) {}
+
+// MemoryUsage reports nothing used and no budget, as the board isn't held in
+// process memory
+func (s *ShardedStore) MemoryUsage() (used, limit int64) {
+	return 0, 0
+}
+
+// SetTiePolicy does nothing: users are ranked by rating alone
+func (s *ShardedStore) SetTiePolicy(policy string, now func() time.Time) {}
+
+// TiePolicy returns ranking.SharedRank, the only order the shards keep
+func (s *ShardedStore) TiePolicy() string {
+	return ranking.SharedRank
+}
+
+// AddUser sets a user's rating, adding them if needed and keeping an
+// existing user's bot flag. Redis calls are bounded by the shard clients'
+// timeouts.
+func (s *ShardedStore) AddUser(username string, rating int) error {
+	return s.put(context.Background(), username, rating, false, false)
+}
+
+// AddBot sets a seeded or simulated user's rating, adding them if needed
+func (s *ShardedStore) AddBot(username string, rating int) error {
+	return s.put(context.Background(), username, rating, true, false)
+}
+
+// CreateUser adds a new user, failing with ErrUserExists if the name is taken
+func (s *ShardedStore) CreateUser(username string, rating int) error {
+	return s.put(context.Background(), username, rating, false, true)
+}
+
+func (s *ShardedStore) put(ctx context.Context, username string, rating int, bot, create bool) error {
+	_, evicted, err := s.write(ctx, username, rating, bot, create)
+	s.evicted(evicted)
+	return err
+}
+
+// write is put leaving the evicted members for the caller to pass to
+// onEvict, and reporting whether the user existed
+func (s *ShardedStore) write(ctx context.Context, username string, rating int, bot, create bool) (existed bool, evicted []*User, err error) {
+	if capacity := s.Capacity(); capacity > 0 {
+		if _, err := s.getUser(ctx, username); errors.Is(err, ErrUserNotFound) {
+			if evicted, err = s.makeRoom(ctx, username, rating, capacity); err != nil {
+				return false, evicted, err
+			}
+		} else if err != nil {
+			return false, nil, err
+		}
+	}
+
+	reply, err := shardPutScript.Run(ctx, s.shardFor(username), s.keys.user(),
+		username, score(rating), flag(bot), flag(create), "").Slice()
+	if err != nil {
+		return false, evicted, err
+	}
+	existed = reply[0].(string) != ""
+	if existed && create {
+		return true, evicted, ErrUserExists
+	}
+	s.offerTop(ctx, username, rating)
+	return existed, evicted, nil
+}
+
+// makeRoom evicts the lowest-ranked members until a new user fits under the
+// member cap, failing with ErrBelowCutoff if they would be the lowest
+// themselves
+func (s *ShardedStore) makeRoom(ctx context.Context, username string, rating, capacity int) ([]*User, error) {
+	var evicted []*User
+	for {
+		count, err := s.Count(ctx)
+		if err != nil || count < capacity {
+			return evicted, err
+		}
+		lowest, err := s.lowest(ctx)
+		if err != nil || lowest == nil {
+			return evicted, err
+		}
+		// The newcomer would be the lowest-ranked member itself
+		if ranking.Before(lowest.Key, lowest.Username, ranking.RatingKey(rating), username) {
+			return evicted, ErrBelowCutoff
+		}
+		removed, err := s.remove(ctx, lowest.Username, time.Time{})
+		if err != nil {
+			return evicted, err
+		}
+		if removed != nil {
+			evicted = append(evicted, removed)
+		}
+	}
+}
+
+// lowest returns the last user in leaderboard order, nil on an empty board
+func (s *ShardedStore) lowest(ctx context.Context) (*User, error) {
+	tails, err := gather(ctx, s, func(ctx context.Context, client redis.UniversalClient) ([]redis.Z, error) {
+		return client.ZRevRangeWithScores(ctx, s.keys.ratings, 0, 0).Result()
+	})
+	if err != nil {
+		return nil, err
+	}
+	var last *User
+	for _, tail := range tails {
+		for _, z := range tail {
+			user := newShardedUser(z.Member.(string), int(-z.Score), false)
+			if last == nil || ranking.Before(last.Key, last.Username, user.Key, user.Username) {
+				last = user
+			}
+		}
+	}
+	return last, nil
+}
+
+// evicted passes members evicted for the member cap to onEvict
+func (s *ShardedStore) evicted(users []*User) {
+	s.mu.RLock()
+	onEvict := s.onEvict
+	s.mu.RUnlock()
+	if onEvict != nil {
+		for _, user := range users {
+			onEvict(user)
+		}
+	}
+}
+
+// AddBots adds or updates bot users, for bulk jobs such as seeding. Bots
+// below a capped board's cutoff are skipped. Writing stops at the first
+// other error, which is returned with the bots written so far, in the order
+// of bots. Each bot is a write of its own, not one step for the batch.
+func (s *ShardedStore) AddBots(bots []NewBot) (written []NewBot, err error) {
+	ctx := context.Background()
+	var evicted []*User
+	created := make(map[string]bool, len(bots))
+
+	written = make([]NewBot, 0, len(bots))
+	for _, bot := range bots {
+		existed, out, werr := s.write(ctx, bot.Username, bot.Rating, true, false)
+		evicted = append(evicted, out...)
+		if errors.Is(werr, ErrBelowCutoff) {
+			continue
+		}
+		if werr != nil {
+			err = werr
+			break
+		}
+		if !existed {
+			created[bot.Username] = true
+		}
+		written = append(written, bot)
+	}
+
+	// A bot created and then evicted within the batch is reported as neither
+	gone := make(map[string]bool)
+	evicted = slices.DeleteFunc(evicted, func(user *User) bool {
+		if created[user.Username] {
+			gone[user.Username] = true
+			return true
+		}
+		return false
+	})
+	if len(gone) > 0 {
+		written = slices.DeleteFunc(written, func(bot NewBot) bool { return gone[bot.Username] })
+	}
+	s.evicted(evicted)
+	return written, err
+}
+
+// setRating runs shardSetScript under mode, returning the user as they were
+// before
+func (s *ShardedStore) setRating(ctx context.Context, username string, rating int, mode string, written int) (*User, error) {
+	reply, err := shardSetScript.Run(ctx, s.shardFor(username), []string{s.keys.ratings, s.keys.bots},
+		username, score(rating), mode, score(written)).Slice()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return scriptUser(username, reply), nil
+}
+
+// RaiseRating sets an existing user's rating only if it is higher than the
+// current one, comparing and writing in one script so concurrent calls
+// can't lower it. It returns the rating before the call and whether it
+// changed.
+func (s *ShardedStore) RaiseRating(username string, rating int) (previous int, raised bool, err error) {
+	ctx := context.Background()
+	before, err := s.setRating(ctx, username, rating, "raise", 0)
+	if err != nil {
+		return 0, false, err
+	}
+	if rating <= before.Rating {
+		return before.Rating, false, nil
+	}
+	s.offerTop(ctx, username, rating)
+	return before.Rating, true, nil
+}
+
+// UpdateRatings sets the ratings of existing users, for background jobs
+// that touch many members at once, in one pipeline per shard. Users that no
+// longer exist are skipped; nobody is added or evicted. Changes are returned
+// in the order of usernames.
+func (s *ShardedStore) UpdateRatings(usernames []string, ratings map[string]int) []RatingChange {
+	ctx := context.Background()
+	byShard := make(map[int][]string)
+	for _, username := range usernames {
+		if _, ok := ratings[username]; ok {
+			i := s.shardIndex(username)
+			byShard[i] = append(byShard[i], username)
+		}
+	}
+
+	replies := make(map[string]*redis.Cmd, len(usernames))
+	for i, owned := range byShard {
+		_, err := s.shards[i].Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, username := range owned {
+				replies[username] = shardSetScript.Eval(ctx, pipe, []string{s.keys.ratings, s.keys.bots},
+					username, score(ratings[username]), "set", 0)
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, redis.Nil) {
+			log.Printf("Failed to update ratings on shard %s: %v", s.shards[i].Name, err)
+		}
+	}
+
+	changes := make([]RatingChange, 0, len(replies))
+	for _, username := range usernames {
+		reply, ok := replies[username]
+		if !ok {
+			continue
+		}
+		values, err := reply.Slice()
+		if err != nil {
+			continue
+		}
+		before, rating := scriptUser(username, values), ratings[username]
+		s.offerTop(ctx, username, rating)
+		changes = append(changes, RatingChange{
+			Username: username,
+			Bot:      before.Bot,
+			Previous: before.Rating,
+			Rating:   rating,
+
+			PreviousKey: before.Key,
+		})
+	}
+	return changes
+}
+
+// MergeUsers sets into's rating and removes from. The two may be on
+// different shards, so a reader can see both users for a moment; if from
+// can't be removed, into's rating is put back. It returns into's rating
+// before the merge and the user removed.
+func (s *ShardedStore) MergeUsers(from, into string, rating int) (previous int, removed *User, err error) {
+	ctx := context.Background()
+	if _, err := s.getUser(ctx, from); err != nil {
+		return 0, nil, err
+	}
+	before, err := s.setRating(ctx, into, rating, "set", 0)
+	if err != nil {
+		return 0, nil, err
+	}
+	removed, err = s.remove(ctx, from, time.Time{})
+	if err == nil && removed == nil {
+		err = ErrUserNotFound
+	}
+	if err != nil {
+		if _, undoErr := s.setRating(ctx, into, before.Rating, "revert", rating); undoErr != nil {
+			log.Printf("Failed to put back %s's rating after a failed merge: %v", into, undoErr)
+		}
+		return before.Rating, nil, err
+	}
+	s.offerTop(ctx, into, rating)
+	return before.Rating, removed, nil
+}
+
+// RestoreUser puts back a user removed by MergeUsers as they were, for when
+// the rest of the merge fails. It fails with ErrUserExists if the name has
+// been taken since.
+func (s *ShardedStore) RestoreUser(user *User) error {
+	ctx := context.Background()
+	reply, err := shardPutScript.Run(ctx, s.shardFor(user.Username), s.keys.user(),
+		user.Username, score(user.Rating), flag(user.Bot), "1", millis(user.ExpiresAt)).Slice()
+	if err != nil {
+		return err
+	}
+	if reply[0].(string) != "" {
+		return ErrUserExists
+	}
+	s.offerTop(ctx, user.Username, user.Rating)
+	return nil
+}
+
+// RevertRating puts an existing user's rating back as before holds it,
+// undoing a write that set the rating to written. A user whose rating has
+// changed again since is left alone and false returned, so a later write
+// isn't overwritten.
+func (s *ShardedStore) RevertRating(before *User, written int) (bool, error) {
+	ctx := context.Background()
+	current, err := s.setRating(ctx, before.Username, before.Rating, "revert", written)
+	if err != nil {
+		return false, err
+	}
+	if current.Rating != written {
+		return false, nil
+	}
+	s.offerTop(ctx, before.Username, before.Rating)
+	return true, nil
+}
+
+// RemoveUser removes a user from the board
+func (s *ShardedStore) RemoveUser(username string) error {
+	removed, err := s.remove(context.Background(), username, time.Time{})
+	if err != nil {
+		return err
+	}
+	if removed == nil {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// remove removes username, only if they expire by expiring when it's set,
+// and returns them as they were, or nil if there was nobody to remove
+func (s *ShardedStore) remove(ctx context.Context, username string, expiring time.Time) (*User, error) {
+	reply, err := shardRemoveScript.Run(ctx, s.shardFor(username), s.keys.user(), username, millis(expiring)).Slice()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.dropTop(ctx, username)
+	return scriptUser(username, reply), nil
+}
+
+// SetExpiry schedules a user's entry to be removed at expiresAt
+func (s *ShardedStore) SetExpiry(username string, expiresAt time.Time) (*User, error) {
+	ctx := context.Background()
+	set, err := shardExpireScript.Run(ctx, s.shardFor(username), []string{s.keys.ratings, s.keys.expiries},
+		username, expiresAt.UnixMilli()).Int()
+	if err != nil {
+		return nil, err
+	}
+	if set == 0 {
+		return nil, ErrUserNotFound
+	}
+	return s.getUser(ctx, username)
+}
+
+// RemoveExpired deletes every entry whose expiry is at or before now and
+// returns them
+func (s *ShardedStore) RemoveExpired(now time.Time) []*User {
+	ctx := context.Background()
+	due := make([][]string, len(s.shards))
+	for i, shard := range s.shards {
+		names, err := shard.Client.ZRangeByScore(ctx, s.keys.expiries, &redis.ZRangeBy{Min: "-inf", Max: millis(now)}).Result()
+		if err != nil {
+			log.Printf("Failed to read expiries on shard %s: %v", shard.Name, err)
+			continue
+		}
+		due[i] = names
+	}
+
+	var removed []*User
+	for _, names := range due {
+		for _, username := range names {
+			// Rescheduled or removed since it was read, if nil
+			user, err := s.remove(ctx, username, now)
+			if err != nil {
+				log.Printf("Failed to remove expired user %s: %v", username, err)
+			}
+			if user != nil {
+				removed = append(removed, user)
+			}
+		}
+	}
+	return removed
+}
+
+// GetUser retrieves a user by username
+func (s *ShardedStore) GetUser(username string) (*User, error) {
+	return s.getUser(context.Background(), username)
+}
+
+func (s *ShardedStore) getUser(ctx context.Context, username string) (*User, error) {
+	var rating *redis.FloatCmd
+	var bot *redis.BoolCmd
+	var expires *redis.FloatSliceCmd
+	_, err := s.shardFor(username).Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		rating = pipe.ZScore(ctx, s.keys.ratings, username)
+		bot = pipe.SIsMember(ctx, s.keys.bots, username)
+		expires = pipe.ZMScore(ctx, s.keys.expiries, username)
+		return nil
+	})
+	if errors.Is(rating.Err(), redis.Nil) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	user := newShardedUser(username, int(-rating.Val()), bot.Val())
+	if ms := expires.Val(); len(ms) == 1 && ms[0] != 0 {
+		user.ExpiresAt = time.UnixMilli(int64(ms[0]))
+	}
+	return user, nil
+}
+
+func newShardedUser(username string, rating int, bot bool) *User {
+	return &User{Username: username, Rating: rating, Key: ranking.RatingKey(rating), Bot: bot}
+}
+
+// getUsers reads the users named, in order, one pipeline per shard. Names
+// not on the board are left out.
+func (s *ShardedStore) getUsers(ctx context.Context, usernames []string) ([]*User, error) {
+	users := make([]*User, len(usernames))
+	for i, username := range usernames {
+		users[i] = &User{Username: username}
+	}
+	present, err := s.fill(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(users, func(user *User) bool { return !present[user.Username] }), nil
+}
+
+// fill reads the rating, bot flag and expiry of users from the shards
+// holding them, asking each shard once, and reports who was found
+func (s *ShardedStore) fill(ctx context.Context, users []*User) (map[string]bool, error) {
+	byShard := make(map[int][]*User)
+	for _, user := range users {
+		i := s.shardIndex(user.Username)
+		byShard[i] = append(byShard[i], user)
+	}
+	present := make(map[string]bool, len(users))
+	for i, owned := range byShard {
+		names := make([]string, len(owned))
+		members := make([]any, len(owned))
+		for j, user := range owned {
+			names[j], members[j] = user.Username, user.Username
+		}
+		var ratings *redis.Cmd
+		var bots *redis.BoolSliceCmd
+		var expiries *redis.FloatSliceCmd
+		_, err := s.shards[i].Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			// Sent as is, since ZMScore reads a missing member as a score of 0
+			ratings = pipe.Do(ctx, append([]any{"ZMSCORE", s.keys.ratings}, members...)...)
+			bots = pipe.SMIsMember(ctx, s.keys.bots, members...)
+			expiries = pipe.ZMScore(ctx, s.keys.expiries, names...)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("shard %s: %w", s.shards[i].Name, err)
+		}
+		scores, err := ratings.Slice()
+		if err != nil {
+			return nil, fmt.Errorf("shard %s: %w", s.shards[i].Name, err)
+		}
+		for j, user := range owned {
+			var negated float64
+			switch value := scores[j].(type) {
+			case float64: // RESP3
+				negated = value
+			case string:
+				negated, _ = strconv.ParseFloat(value, 64)
+			default: // Missing
+				continue
+			}
+			rating := int(-negated)
+			user.Rating, user.Key, user.Bot = rating, ranking.RatingKey(rating), bots.Val()[j]
+			if ms := expiries.Val()[j]; ms != 0 {
+				user.ExpiresAt = time.UnixMilli(int64(ms))
+			}
+			present[user.Username] = true
+		}
+	}
+	return present, nil
+}
@@ -0,0 +1,451 @@
+package store
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"backend/internal/ranking"
+)
+
+// GetUserCount returns the total number of users, or 0 if a shard can't be
+// reached; Count reports why
+func (s *ShardedStore) GetUserCount() int {
+	count, _ := s.Count(context.Background())
+	return count
+}
+
+// Count returns the total number of users across every shard
+func (s *ShardedStore) Count(ctx context.Context) (int, error) {
+	counts, err := gather(ctx, s, func(ctx context.Context, client redis.UniversalClient) (int64, error) {
+		return client.ZCard(ctx, s.keys.ratings).Result()
+	})
+	return int(sum(counts)), err
+}
+
+// GetUserRank returns a user's rank: one more than the number of users rated
+// higher on any shard. Users in the global top K are ranked from it alone.
+func (s *ShardedStore) GetUserRank(ctx context.Context, username string) (int, error) {
+	if rank, ok, err := s.topRank(ctx, username); err != nil || ok {
+		return rank, err
+	}
+	user, err := s.getUser(ctx, username)
+	if err != nil {
+		return 0, err
+	}
+	higher, err := s.ratedHigher(ctx, user.Rating)
+	if err != nil {
+		return 0, err
+	}
+	return ranking.FromHigher(higher), nil
+}
+
+// ratedHigher counts the users rated strictly higher than rating
+func (s *ShardedStore) ratedHigher(ctx context.Context, rating int) (int, error) {
+	counts, err := gather(ctx, s, func(ctx context.Context, client redis.UniversalClient) (int64, error) {
+		return client.ZCount(ctx, s.keys.ratings, "-inf", below(rating)).Result()
+	})
+	return int(sum(counts)), err
+}
+
+// below is the exclusive score bound for users rated strictly higher than rating
+func below(rating int) string {
+	return "(" + strconv.FormatFloat(score(rating), 'f', -1, 64)
+}
+
+// Page returns limit users from offset in leaderboard order, with their
+// ranks, and how many users the board has. Pages within the global top K are
+// read from it; deeper pages gather each shard's first offset+limit users
+// and merge them, so they cost each shard more the deeper they are. Either
+// way the page's bot flags and expiries are read from the shards holding its
+// users.
+func (s *ShardedStore) Page(ctx context.Context, offset, limit int) (page []RankedUser, total int, err error) {
+	if page, err = s.page(ctx, offset, limit); err != nil {
+		return nil, 0, err
+	}
+	total, err = s.Count(ctx)
+	return page, total, err
+}
+
+func (s *ShardedStore) page(ctx context.Context, offset, limit int) ([]RankedUser, error) {
+	if offset < 0 || limit <= 0 {
+		return []RankedUser{}, nil
+	}
+	if page, ok, err := s.topPage(ctx, offset, limit); err != nil || ok {
+		return page, err
+	}
+	heads, err := gather(ctx, s, func(ctx context.Context, client redis.UniversalClient) ([]redis.Z, error) {
+		return client.ZRangeWithScores(ctx, s.keys.ratings, 0, int64(offset+limit-1)).Result()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := mergeShards(heads, offset+limit)
+	if len(merged) <= offset {
+		return []RankedUser{}, nil
+	}
+	page := merged[offset:]
+	if _, err := s.fill(ctx, page); err != nil {
+		return nil, err
+	}
+	var before *User
+	if offset > 0 {
+		before = merged[offset-1]
+	}
+	return rankPage(ctx, page, offset, before, s.ratedHigher)
+}
+
+// rankPage ranks page, the users from offset on. Users ahead of the page are
+// all rated at least as high as its first user, so higher only counts them
+// when before, the user just ahead, ties with it.
+func rankPage(ctx context.Context, page []*User, offset int, before *User, higher func(context.Context, int) (int, error)) ([]RankedUser, error) {
+	rank := ranking.FromHigher(offset)
+	if before != nil && before.Rating == page[0].Rating {
+		n, err := higher(ctx, page[0].Rating)
+		if err != nil {
+			return nil, err
+		}
+		rank = ranking.FromHigher(n)
+	}
+	ranked := make([]RankedUser, len(page))
+	for i, user := range page {
+		if i > 0 && user.Rating != page[i-1].Rating {
+			rank = ranking.FromHigher(offset + i)
+		}
+		ranked[i] = RankedUser{User: user, Rank: rank}
+	}
+	return ranked, nil
+}
+
+// mergeShards merges each shard's users, already in leaderboard order, into
+// the first n users of the board: a k-way merge on rating, then username
+func mergeShards(heads [][]redis.Z, n int) []*User {
+	h := make(shardHeap, 0, len(heads))
+	for _, head := range heads {
+		if len(head) > 0 {
+			h = append(h, shardCursor{users: head})
+		}
+	}
+	heap.Init(&h)
+
+	merged := make([]*User, 0, n)
+	for len(merged) < n && h.Len() > 0 {
+		cursor := &h[0]
+		z := cursor.users[cursor.next]
+		merged = append(merged, newShardedUser(z.Member.(string), int(-z.Score), false))
+		cursor.next++
+		if cursor.next == len(cursor.users) {
+			heap.Pop(&h)
+		} else {
+			heap.Fix(&h, 0)
+		}
+	}
+	return merged
+}
+
+// shardCursor walks one shard's users in leaderboard order
+type shardCursor struct {
+	users []redis.Z
+	next  int
+}
+
+// shardHeap orders shard cursors by their next user
+type shardHeap []shardCursor
+
+func (h shardHeap) Len() int { return len(h) }
+
+func (h shardHeap) Less(i, j int) bool {
+	a, b := h[i].users[h[i].next], h[j].users[h[j].next]
+	return ranking.Before(
+		ranking.RatingKey(int(-a.Score)), a.Member.(string),
+		ranking.RatingKey(int(-b.Score)), b.Member.(string),
+	)
+}
+
+func (h shardHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *shardHeap) Push(x any) { *h = append(*h, x.(shardCursor)) }
+
+func (h *shardHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// shardContents is everything a shard holds of the board
+type shardContents struct {
+	ratings  []redis.Z // In leaderboard order
+	bots     []string
+	expiries []redis.Z // Scored by unix milliseconds
+}
+
+// contents reads a shard's whole share of the board
+func (s *ShardedStore) contents(ctx context.Context, client redis.UniversalClient) (shardContents, error) {
+	var ratings, expiries *redis.ZSliceCmd
+	var bots *redis.StringSliceCmd
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		ratings = pipe.ZRangeWithScores(ctx, s.keys.ratings, 0, -1)
+		bots = pipe.SMembers(ctx, s.keys.bots)
+		expiries = pipe.ZRangeWithScores(ctx, s.keys.expiries, 0, -1)
+		return nil
+	})
+	return shardContents{ratings: ratings.Val(), bots: bots.Val(), expiries: expiries.Val()}, err
+}
+
+// GetAllUsers returns all users in leaderboard order, reading every shard's
+// whole share of the board
+func (s *ShardedStore) GetAllUsers(ctx context.Context) ([]*User, error) {
+	parts, err := gather(ctx, s, s.contents)
+	if err != nil {
+		return nil, err
+	}
+	heads := make([][]redis.Z, len(parts))
+	bots := make(map[string]bool)
+	expiries := make(map[string]time.Time)
+	total := 0
+	for i, part := range parts {
+		heads[i] = part.ratings
+		total += len(part.ratings)
+		for _, username := range part.bots {
+			bots[username] = true
+		}
+		for _, z := range part.expiries {
+			expiries[z.Member.(string)] = time.UnixMilli(int64(z.Score))
+		}
+	}
+
+	users := mergeShards(heads, total)
+	for _, user := range users {
+		user.Bot = bots[user.Username]
+		user.ExpiresAt = expiries[user.Username]
+	}
+	return users, ctx.Err()
+}
+
+// UsersAfter returns up to limit users whose usernames sort after cursor,
+// in username order, merging each shard's next limit names
+func (s *ShardedStore) UsersAfter(cursor string, limit int) []*User {
+	ctx := context.Background()
+	from := "-"
+	if cursor != "" {
+		from = "(" + cursor
+	}
+	parts, err := gather(ctx, s, func(ctx context.Context, client redis.UniversalClient) ([]string, error) {
+		return client.ZRangeByLex(ctx, s.keys.names, &redis.ZRangeBy{Min: from, Max: "+", Count: int64(limit)}).Result()
+	})
+	if err != nil {
+		log.Printf("Failed to list users by name: %v", err)
+		return nil
+	}
+	var names []string
+	for _, part := range parts {
+		names = append(names, part...)
+	}
+	slices.Sort(names)
+	users, err := s.getUsers(ctx, names[:min(limit, len(names))])
+	if err != nil {
+		log.Printf("Failed to list users by name: %v", err)
+		return nil
+	}
+	return users
+}
+
+// SearchRanked returns a page of users matching q in leaderboard order,
+// each with its rank, and the total number of matches. The shards index
+// names by exact case only, so the whole board is read and walked.
+func (s *ShardedStore) SearchRanked(ctx context.Context, q SearchQuery) (matches []SearchMatch, total int, err error) {
+	users, err := s.GetAllUsers(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	text := strings.ToLower(q.Text)
+	matches = make([]SearchMatch, 0, min(q.Limit, 1024))
+	var ranks ranking.Counter
+	for i, user := range users {
+		if err := scanCancelled(ctx, i); err != nil {
+			return nil, 0, err
+		}
+		rank := ranks.Next(user.Key)
+		if !strings.HasPrefix(strings.ToLower(user.Username), text) || (q.Exclude != nil && q.Exclude(user.Username)) {
+			continue
+		}
+		total++
+		if len(matches) == q.Limit {
+			continue
+		}
+		if q.After != nil {
+			if q.After.before(user.Key, user.Username) {
+				continue
+			}
+		} else if total <= q.Offset {
+			continue
+		}
+		matches = append(matches, SearchMatch{User: user, Rank: rank})
+	}
+	return matches, total, nil
+}
+
+// RankForKey returns the rank of a user whose sort key is key: one more than
+// the number of users rated strictly higher, as keys only hold ratings here
+func (s *ShardedStore) RankForKey(key int64) int {
+	rating, _ := ranking.Decode(key)
+	return s.RankForRating(rating)
+}
+
+// RankForRating returns the rank a user reaching rating now would hold: one
+// more than the number of users rated strictly higher
+func (s *ShardedStore) RankForRating(rating int) int {
+	higher, err := s.ratedHigher(context.Background(), rating)
+	if err != nil {
+		log.Printf("Failed to rank rating %d: %v", rating, err)
+	}
+	return ranking.FromHigher(higher)
+}
+
+// RatingAt returns the rating of the user in the given 1-based position on
+// the board, 0 while fewer users are on it. Positions within the global top
+// K are read from it.
+func (s *ShardedStore) RatingAt(position int) int {
+	if position < 1 {
+		return 0
+	}
+	page, err := s.page(context.Background(), position-1, 1)
+	if err != nil {
+		log.Printf("Failed to read position %d: %v", position, err)
+		return 0
+	}
+	if len(page) == 0 {
+		return 0
+	}
+	return page[0].User.Rating
+}
+
+// GetBotCount returns the number of users flagged as bots
+func (s *ShardedStore) GetBotCount(ctx context.Context) (int, error) {
+	counts, err := gather(ctx, s, func(ctx context.Context, client redis.UniversalClient) (int64, error) {
+		return client.SCard(ctx, s.keys.bots).Result()
+	})
+	return int(sum(counts)), err
+}
+
+// GetStats calculates leaderboard statistics, optionally ignoring bots,
+// from RatingCounts
+func (s *ShardedStore) GetStats(ctx context.Context, excludeBots bool) (total int, minRating, maxRating int, avgRating float64, err error) {
+	counts, err := s.RatingCounts(ctx, excludeBots)
+	if err != nil || len(counts) == 0 {
+		return 0, 0, 0, 0, err
+	}
+	minRating, maxRating = math.MaxInt, math.MinInt
+	ratingSum := 0
+	for rating, n := range counts {
+		total += n
+		ratingSum += rating * n
+		minRating, maxRating = min(minRating, rating), max(maxRating, rating)
+	}
+	return total, minRating, maxRating, float64(ratingSum) / float64(total), nil
+}
+
+// RatingCounts returns how many users hold each rating, optionally ignoring
+// bots, reading every shard's whole share of the board
+func (s *ShardedStore) RatingCounts(ctx context.Context, excludeBots bool) (map[int]int, error) {
+	parts, err := gather(ctx, s, func(ctx context.Context, client redis.UniversalClient) (shardContents, error) {
+		if !excludeBots {
+			ratings, err := client.ZRangeWithScores(ctx, s.keys.ratings, 0, -1).Result()
+			return shardContents{ratings: ratings}, err
+		}
+		return s.contents(ctx, client)
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int)
+	for _, part := range parts {
+		bots := make(map[string]bool, len(part.bots))
+		for _, username := range part.bots {
+			bots[username] = true
+		}
+		for _, z := range part.ratings {
+			if !bots[z.Member.(string)] {
+				counts[int(-z.Score)]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+// CountInRange returns how many users are rated between minRating and
+// maxRating inclusive, optionally ignoring bots. Ignoring bots reads the
+// users in range from every shard.
+func (s *ShardedStore) CountInRange(minRating, maxRating int, excludeBots bool) int {
+	bounds := &redis.ZRangeBy{
+		Min: strconv.FormatFloat(score(maxRating), 'f', -1, 64),
+		Max: strconv.FormatFloat(score(minRating), 'f', -1, 64),
+	}
+	counts, err := gather(context.Background(), s, func(ctx context.Context, client redis.UniversalClient) (int64, error) {
+		if !excludeBots {
+			return client.ZCount(ctx, s.keys.ratings, bounds.Min, bounds.Max).Result()
+		}
+		names, err := client.ZRangeByScore(ctx, s.keys.ratings, bounds).Result()
+		if err != nil || len(names) == 0 {
+			return 0, err
+		}
+		members := make([]any, len(names))
+		for i, username := range names {
+			members[i] = username
+		}
+		bots, err := client.SMIsMember(ctx, s.keys.bots, members...).Result()
+		var n int64
+		for _, bot := range bots {
+			if !bot {
+				n++
+			}
+		}
+		return n, err
+	})
+	if err != nil {
+		log.Printf("Failed to count users rated %d to %d: %v", minRating, maxRating, err)
+	}
+	return int(sum(counts))
+}
+
+// gather calls query on every shard at once and returns the results in
+// shard order, or the first error
+func gather[T any](ctx context.Context, s *ShardedStore, query func(context.Context, redis.UniversalClient) (T, error)) ([]T, error) {
+	results := make([]T, len(s.shards))
+	errs := make([]error, len(s.shards))
+	done := make(chan int, len(s.shards))
+	for i, shard := range s.shards {
+		go func() {
+			results[i], errs[i] = query(ctx, shard.Client)
+			done <- i
+		}()
+	}
+	for range s.shards {
+		<-done
+	}
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("shard %s: %w", s.shards[i].Name, err)
+		}
+	}
+	return results, nil
+}
+
+func sum(counts []int64) int64 {
+	var total int64
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
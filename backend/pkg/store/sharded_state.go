@@ -0,0 +1,545 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// IssueMisplaced is reported by a ShardedStore's CheckIntegrity for a user
+// kept on a shard the hash ring doesn't give them to, as after shards were
+// added without moving anyone
+const IssueMisplaced = "misplaced_user"
+
+// grantRoleScript adds a role to a principal's comma-separated roles and
+// returns 1, or 0 if they already held it.
+// KEYS: roles. ARGV: principal, role.
+var grantRoleScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], ARGV[1])
+if not current then
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+	return 1
+end
+for role in string.gmatch(current, '[^,]+') do
+	if role == ARGV[2] then
+		return 0
+	end
+end
+redis.call('HSET', KEYS[1], ARGV[1], current .. ',' .. ARGV[2])
+return 1
+`)
+
+// revokeRoleScript removes a role from a principal's comma-separated roles
+// and returns 1, or 0 if they didn't hold it.
+// KEYS: roles. ARGV: principal, role.
+var revokeRoleScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], ARGV[1])
+if not current then
+	return 0
+end
+local kept, found = {}, false
+for role in string.gmatch(current, '[^,]+') do
+	if role == ARGV[2] then
+		found = true
+	else
+		table.insert(kept, role)
+	end
+end
+if not found then
+	return 0
+end
+if #kept == 0 then
+	redis.call('HDEL', KEYS[1], ARGV[1])
+else
+	redis.call('HSET', KEYS[1], ARGV[1], table.concat(kept, ','))
+end
+return 1
+`)
+
+// GrantRole gives principal a role, reporting whether it was newly granted.
+// A grant Redis refuses is logged and not made.
+func (s *ShardedStore) GrantRole(principal, role string) bool {
+	granted, err := grantRoleScript.Run(context.Background(), s.home, []string{s.keys.roles}, principal, role).Int()
+	if err != nil {
+		log.Printf("Failed to grant %s to %s on Redis: %v", role, principal, err)
+		return false
+	}
+	return granted == 1
+}
+
+// RevokeRole removes a role from principal, reporting whether it was held.
+// A revocation Redis refuses is logged and not made.
+func (s *ShardedStore) RevokeRole(principal, role string) bool {
+	revoked, err := revokeRoleScript.Run(context.Background(), s.home, []string{s.keys.roles}, principal, role).Int()
+	if err != nil {
+		log.Printf("Failed to revoke %s from %s on Redis: %v", role, principal, err)
+		return false
+	}
+	return revoked == 1
+}
+
+// Roles returns principal's roles, sorted
+func (s *ShardedStore) Roles(principal string) []string {
+	roles, err := s.home.HGet(context.Background(), s.keys.roles, principal).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Printf("Failed to read %s's roles: %v", principal, err)
+	}
+	split := splitRoles(roles)
+	sort.Strings(split)
+	return split
+}
+
+// RoleGrants returns every principal's roles
+func (s *ShardedStore) RoleGrants() map[string][]string {
+	all, err := s.home.HGetAll(context.Background(), s.keys.roles).Result()
+	if err != nil {
+		log.Printf("Failed to read role grants: %v", err)
+	}
+	grants := make(map[string][]string, len(all))
+	for principal, roles := range all {
+		if split := splitRoles(roles); len(split) > 0 {
+			sort.Strings(split)
+			grants[principal] = split
+		}
+	}
+	return grants
+}
+
+// SetMaintenance replaces the maintenance mode for every process on the
+// board. A change Redis refuses is logged and not made.
+func (s *ShardedStore) SetMaintenance(m Maintenance) {
+	encoded, err := json.Marshal(m)
+	if err == nil {
+		err = s.home.Set(context.Background(), s.keys.maintenance, encoded, 0).Err()
+	}
+	if err != nil {
+		log.Printf("Failed to set the maintenance mode on Redis: %v", err)
+	}
+}
+
+// Maintenance returns the maintenance mode, or the API fully up if it can't
+// be read
+func (s *ShardedStore) Maintenance() Maintenance {
+	var m Maintenance
+	encoded, err := s.home.Get(context.Background(), s.keys.maintenance).Bytes()
+	if err == nil {
+		err = json.Unmarshal(encoded, &m)
+	}
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Printf("Failed to read the maintenance mode: %v", err)
+	}
+	return m
+}
+
+// UpdateRecord replaces a record, as MemoryStore's does, for every process
+// on the board. The record is watched while update runs, which is called
+// again if another process wrote it first.
+func (s *ShardedStore) UpdateRecord(kind, key string, update func(current string) (string, error)) (string, error) {
+	ctx := context.Background()
+	hash := s.keys.records(kind)
+	var value string
+	for range redisWriteAttempts {
+		err := s.home.Watch(ctx, func(tx *redis.Tx) error {
+			current, err := tx.HGet(ctx, hash, key).Result()
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return err
+			}
+			if value, err = update(current); err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				if value == "" {
+					pipe.HDel(ctx, hash, key)
+				} else {
+					pipe.HSet(ctx, hash, key, value)
+					pipe.SAdd(ctx, s.keys.kinds, kind)
+				}
+				return nil
+			})
+			return err
+		}, hash)
+		if !errors.Is(err, redis.TxFailedErr) {
+			if err != nil {
+				return "", err
+			}
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("record %s/%s: %w", kind, key, redis.TxFailedErr)
+}
+
+// Record returns the record kind/key, and whether there is one
+func (s *ShardedStore) Record(kind, key string) (string, bool) {
+	value, err := s.home.HGet(context.Background(), s.keys.records(kind), key).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Printf("Failed to read record %s/%s: %v", kind, key, err)
+	}
+	return value, err == nil
+}
+
+// Records returns a copy of every record of kind
+func (s *ShardedStore) Records(kind string) map[string]string {
+	records, err := s.home.HGetAll(context.Background(), s.keys.records(kind)).Result()
+	if err != nil {
+		log.Printf("Failed to read %s records: %v", kind, err)
+		return map[string]string{}
+	}
+	return records
+}
+
+// RecordCount returns how many records of kind there are
+func (s *ShardedStore) RecordCount(kind string) int {
+	n, err := s.home.HLen(context.Background(), s.keys.records(kind)).Result()
+	if err != nil {
+		log.Printf("Failed to count %s records: %v", kind, err)
+	}
+	return int(n)
+}
+
+// allRecords reads every record of every kind, for snapshots
+func (s *ShardedStore) allRecords(ctx context.Context) (map[string]map[string]string, error) {
+	kinds, err := s.home.SMembers(ctx, s.keys.kinds).Result()
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]map[string]string, len(kinds))
+	for _, kind := range kinds {
+		records, err := s.home.HGetAll(ctx, s.keys.records(kind)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(records) > 0 {
+			all[kind] = records
+		}
+	}
+	return all, nil
+}
+
+// shardState is a shard's share of the board with its name index, for
+// integrity checks
+type shardState struct {
+	shardContents
+	names []string
+}
+
+func (s *ShardedStore) state(ctx context.Context, client redis.UniversalClient) (shardState, error) {
+	contents, err := s.contents(ctx, client)
+	if err != nil {
+		return shardState{}, err
+	}
+	names, err := client.ZRange(ctx, s.keys.names, 0, -1).Result()
+	return shardState{shardContents: contents, names: names}, err
+}
+
+// CheckIntegrity cross-checks each shard's users against its name, bot and
+// expiry indexes and the hash ring, and returns every inconsistency, sorted
+// by username. A shard that can't be read is logged and left out.
+func (s *ShardedStore) CheckIntegrity() []IntegrityIssue {
+	issues, _, err := s.integrityIssues(context.Background())
+	if err != nil {
+		log.Printf("Failed to check the sharded board: %v", err)
+	}
+	return issues
+}
+
+// RepairIntegrity returns the same issues as CheckIntegrity and fixes them:
+// users missing from the name index are added to it, index entries without
+// a user are dropped, and misplaced users are moved to the shard the ring
+// gives them to, unless that shard has them already. The global top K is
+// rebuilt on its next read.
+func (s *ShardedStore) RepairIntegrity() []IntegrityIssue {
+	ctx := context.Background()
+	issues, states, err := s.integrityIssues(ctx)
+	if err != nil {
+		log.Printf("Failed to check the sharded board: %v", err)
+		return issues
+	}
+	if len(issues) == 0 {
+		return issues
+	}
+
+	for i, state := range states {
+		if err := s.repairShard(ctx, i, state); err != nil {
+			log.Printf("Failed to repair shard %s: %v", s.shards[i].Name, err)
+		}
+	}
+	if err := s.staleTop(ctx); err != nil {
+		log.Printf("Failed to mark the global top K stale: %v", err)
+	}
+	return issues
+}
+
+// repairShard fixes the issues found in state, shard i's contents
+func (s *ShardedStore) repairShard(ctx context.Context, i int, state shardState) error {
+	client := s.shards[i].Client
+	rated := make(map[string]bool, len(state.ratings))
+	for _, z := range state.ratings {
+		rated[z.Member.(string)] = true
+	}
+	bots := make(map[string]bool, len(state.bots))
+	for _, username := range state.bots {
+		bots[username] = true
+	}
+	expiries := make(map[string]time.Time, len(state.expiries))
+	for _, z := range state.expiries {
+		expiries[z.Member.(string)] = time.UnixMilli(int64(z.Score))
+	}
+
+	for _, z := range state.ratings {
+		username := z.Member.(string)
+		if s.shardIndex(username) == i {
+			if err := client.ZAdd(ctx, s.keys.names, redis.Z{Member: username}).Err(); err != nil {
+				return err
+			}
+			continue
+		}
+		// Moved only if its owner hasn't got a newer copy
+		err := shardPutScript.Run(ctx, s.shardFor(username), s.keys.user(),
+			username, z.Score, flag(bots[username]), "1", millis(expiries[username])).Err()
+		if err != nil {
+			return err
+		}
+		if err := shardRemoveScript.Run(ctx, client, s.keys.user(), username, "").Err(); err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+	}
+
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, username := range state.names {
+			if !rated[username] {
+				pipe.ZRem(ctx, s.keys.names, username)
+			}
+		}
+		for username := range bots {
+			if !rated[username] {
+				pipe.SRem(ctx, s.keys.bots, username)
+			}
+		}
+		for username := range expiries {
+			if !rated[username] {
+				pipe.ZRem(ctx, s.keys.expiries, username)
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// integrityIssues reads every shard and returns the issues found, with what
+// each shard holds
+func (s *ShardedStore) integrityIssues(ctx context.Context) ([]IntegrityIssue, []shardState, error) {
+	states, err := gather(ctx, s, s.state)
+	if err != nil {
+		return nil, nil, err
+	}
+	var issues []IntegrityIssue
+	for i, state := range states {
+		rated := make(map[string]bool, len(state.ratings))
+		named := make(map[string]bool, len(state.names))
+		for _, username := range state.names {
+			named[username] = true
+		}
+		for _, z := range state.ratings {
+			username, rating := z.Member.(string), int(-z.Score)
+			rated[username] = true
+			if !named[username] {
+				issues = append(issues, IntegrityIssue{Kind: IssueUnindexed, Username: username, Rating: rating})
+			}
+			if s.shardIndex(username) != i {
+				issues = append(issues, IntegrityIssue{Kind: IssueMisplaced, Username: username, Rating: rating})
+			}
+		}
+		orphans := slices.Clone(state.names)
+		orphans = append(orphans, state.bots...)
+		for _, z := range state.expiries {
+			orphans = append(orphans, z.Member.(string))
+		}
+		slices.Sort(orphans)
+		for _, username := range slices.Compact(orphans) {
+			if !rated[username] {
+				issues = append(issues, IntegrityIssue{Kind: IssueOrphanedIndex, Username: username})
+			}
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Username != issues[j].Username {
+			return issues[i].Username < issues[j].Username
+		}
+		return issues[i].Kind < issues[j].Kind
+	})
+	return issues, states, nil
+}
+
+// Snapshot copies every user, role grant and record, and the maintenance
+// mode. If a shard can't be read, the failure is logged and the snapshot
+// marked Partial, so Restore leaves the board's users as they are.
+func (s *ShardedStore) Snapshot() Snapshot {
+	ctx := context.Background()
+	snap := Snapshot{Roles: s.RoleGrants(), Maintenance: s.Maintenance()}
+
+	users, err := s.GetAllUsers(ctx)
+	if err != nil {
+		log.Printf("Failed to read the sharded board for a snapshot: %v", err)
+		snap.Partial = true
+	}
+	snap.Users = make([]User, len(users))
+	for i, user := range users {
+		snap.Users[i] = *user
+	}
+	if snap.Records, err = s.allRecords(ctx); err != nil {
+		log.Printf("Failed to read records for a snapshot: %v", err)
+		snap.Partial = true
+	}
+	return snap
+}
+
+// swapScript moves each of the first ARGV[1] KEYS onto the key that follows
+// it, deleting the target when there is nothing to move. EXISTS is checked
+// first, as RENAME fails on a missing key and Redis wouldn't undo the
+// renames before it.
+var swapScript = redis.NewScript(`
+for i = 1, tonumber(ARGV[1]) * 2, 2 do
+	if redis.call('EXISTS', KEYS[i]) == 1 then
+		redis.call('RENAME', KEYS[i], KEYS[i + 1])
+	else
+		redis.call('DEL', KEYS[i + 1])
+	end
+end
+return 1
+`)
+
+// Restore replaces the board with snap. Each shard's share is written to
+// temporary keys and swapped in one step, but shards are swapped one after
+// another, so other processes may briefly see some shards restored and
+// others not. A Partial snapshot replaces the role grants, records and
+// maintenance mode and leaves the users alone. Failures are logged.
+func (s *ShardedStore) Restore(snap Snapshot) {
+	ctx := context.Background()
+	if !snap.Partial {
+		shares := make([][]User, len(s.shards))
+		for _, user := range snap.Users {
+			i := s.shardIndex(user.Username)
+			shares[i] = append(shares[i], user)
+		}
+		for i, share := range shares {
+			if err := s.replaceShard(ctx, i, share); err != nil {
+				log.Printf("Failed to restore shard %s: %v", s.shards[i].Name, err)
+			}
+		}
+		if err := s.staleTop(ctx); err != nil {
+			log.Printf("Failed to mark the global top K stale: %v", err)
+		}
+	}
+	if err := s.replaceHome(ctx, snap); err != nil {
+		log.Printf("Failed to restore role grants and records: %v", err)
+	}
+}
+
+// temp is the key a replaced key is written under before it is swapped in
+func (s *ShardedStore) temp(key string) string {
+	return s.keys.prefix + "replacing:" + strings.TrimPrefix(key, s.keys.prefix)
+}
+
+// swap replaces targets on client with their temporary keys in one step
+func (s *ShardedStore) swap(ctx context.Context, client redis.UniversalClient, targets []string) error {
+	keys := make([]string, 0, 2*len(targets))
+	for _, target := range targets {
+		keys = append(keys, s.temp(target), target)
+	}
+	return swapScript.Run(ctx, client, keys, len(targets)).Err()
+}
+
+// replaceShard replaces shard i's users with users
+func (s *ShardedStore) replaceShard(ctx context.Context, i int, users []User) error {
+	client := s.shards[i].Client
+	targets := s.keys.user()
+	temps := make([]string, len(targets))
+	for j, target := range targets {
+		temps[j] = s.temp(target)
+	}
+	// Left behind if a restore failed partway
+	if err := client.Del(ctx, temps...).Err(); err != nil {
+		return err
+	}
+	for start := 0; start < len(users); start += redisLoadBatch {
+		batch := users[start:min(start+redisLoadBatch, len(users))]
+		_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, user := range batch {
+				pipe.ZAdd(ctx, s.temp(s.keys.ratings), redis.Z{Score: score(user.Rating), Member: user.Username})
+				pipe.ZAdd(ctx, s.temp(s.keys.names), redis.Z{Member: user.Username})
+				if user.Bot {
+					pipe.SAdd(ctx, s.temp(s.keys.bots), user.Username)
+				}
+				if !user.ExpiresAt.IsZero() {
+					pipe.ZAdd(ctx, s.temp(s.keys.expiries), redis.Z{Score: float64(user.ExpiresAt.UnixMilli()), Member: user.Username})
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return s.swap(ctx, client, targets)
+}
+
+// replaceHome replaces the role grants, records and maintenance mode with
+// snap's
+func (s *ShardedStore) replaceHome(ctx context.Context, snap Snapshot) error {
+	kinds, err := s.home.SMembers(ctx, s.keys.kinds).Result()
+	if err != nil {
+		return err
+	}
+	for kind := range snap.Records {
+		if !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	targets := []string{s.keys.roles, s.keys.maintenance, s.keys.kinds}
+	for _, kind := range kinds {
+		targets = append(targets, s.keys.records(kind))
+	}
+	temps := make([]string, len(targets))
+	for i, target := range targets {
+		temps[i] = s.temp(target)
+	}
+	if err := s.home.Del(ctx, temps...).Err(); err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(snap.Maintenance)
+	if err != nil {
+		return err
+	}
+	_, err = s.home.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for principal, roles := range snap.Roles {
+			if len(roles) > 0 {
+				pipe.HSet(ctx, s.temp(s.keys.roles), principal, strings.Join(roles, ","))
+			}
+		}
+		pipe.Set(ctx, s.temp(s.keys.maintenance), encoded, 0)
+		for kind, records := range snap.Records {
+			if len(records) == 0 {
+				continue
+			}
+			pipe.SAdd(ctx, s.temp(s.keys.kinds), kind)
+			for key, value := range records {
+				pipe.HSet(ctx, s.temp(s.keys.records(kind)), key, value)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.swap(ctx, s.home, targets)
+}
@@ -0,0 +1,506 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"backend/internal/ranking"
+)
+
+// newTestShards starts n Redis instances to shard a board across
+func newTestShards(t *testing.T, n int) ([]*miniredis.Miniredis, []Shard) {
+	t.Helper()
+	mrs := make([]*miniredis.Miniredis, n)
+	shards := make([]Shard, n)
+	for i := range shards {
+		mrs[i] = miniredis.RunT(t)
+		shards[i] = Shard{Name: mrs[i].Addr(), Client: redis.NewClient(&redis.Options{Addr: mrs[i].Addr()})}
+	}
+	return mrs, shards
+}
+
+func newTestShardedStore(t *testing.T, shards []Shard) *ShardedStore {
+	t.Helper()
+	s, err := NewShardedStore(shards, testPrefix)
+	if err != nil {
+		t.Fatalf("NewShardedStore: %v", err)
+	}
+	return s
+}
+
+func TestHashKey(t *testing.T) {
+	if hashKey("alice") != hashKey("alice") {
+		t.Fatal("hashKey isn't deterministic")
+	}
+
+	shards := make([]Shard, 4)
+	for i := range shards {
+		shards[i] = Shard{Name: fmt.Sprintf("redis-%d:6379", i)}
+	}
+	s := newTestShardedStore(t, shards)
+	grown := newTestShardedStore(t, append(slices.Clone(shards), Shard{Name: "redis-4:6379"}))
+
+	const users = 20000
+	counts := make([]int, len(shards))
+	moved := 0
+	for i := range users {
+		username := fmt.Sprintf("user_%d", i)
+		from, to := s.shardIndex(username), grown.shardIndex(username)
+		counts[from]++
+		if from != to {
+			moved++
+			if to != len(shards) {
+				t.Fatalf("%s moved from shard %d to %d, not to the new shard", username, from, to)
+			}
+		}
+	}
+	for i, n := range counts {
+		if n < users/len(shards)*3/4 || n > users/len(shards)*5/4 {
+			t.Errorf("shard %d holds %d of %d users, want about a quarter", i, n, users)
+		}
+	}
+	if moved < users/10 || moved > users*3/10 {
+		t.Errorf("adding a fifth shard moved %d of %d users, want about a fifth", moved, users)
+	}
+}
+
+func TestMergeShards(t *testing.T) {
+	z := func(username string, rating int) redis.Z { return redis.Z{Score: score(rating), Member: username} }
+	cases := []struct {
+		name  string
+		heads [][]redis.Z
+		n     int
+		want  []string
+	}{
+		{name: "no shards", n: 3, want: []string{}},
+		{name: "empty shards", heads: [][]redis.Z{nil, {}}, n: 3, want: []string{}},
+		{
+			name:  "interleaved",
+			heads: [][]redis.Z{{z("a", 1500), z("c", 1300)}, {z("b", 1400), z("d", 1200)}},
+			n:     4,
+			want:  []string{"a", "b", "c", "d"},
+		},
+		{
+			name:  "ties by username",
+			heads: [][]redis.Z{{z("bob", 1500)}, {z("alice", 1500), z("zed", 1500)}, {z("carol", 1500)}},
+			n:     4,
+			want:  []string{"alice", "bob", "carol", "zed"},
+		},
+		{
+			name:  "first n",
+			heads: [][]redis.Z{{z("a", 1500), z("c", 1300)}, {z("b", 1400), z("d", 1200)}},
+			n:     3,
+			want:  []string{"a", "b", "c"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			merged := mergeShards(c.heads, c.n)
+			got := make([]string, len(merged))
+			for i, user := range merged {
+				got[i] = user.Username
+			}
+			if !slices.Equal(got, c.want) {
+				t.Errorf("mergeShards = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRankPage(t *testing.T) {
+	users := func(ratings ...int) []*User {
+		page := make([]*User, len(ratings))
+		for i, rating := range ratings {
+			page[i] = newShardedUser(fmt.Sprintf("u%d", i), rating, false)
+		}
+		return page
+	}
+	cases := []struct {
+		name   string
+		page   []*User
+		offset int
+		before *User
+		higher int // Users rated above the page's first user
+		want   []int
+	}{
+		{name: "top", page: users(1500, 1400, 1400, 1300), want: []int{1, 2, 2, 4}},
+		{name: "after a higher user", page: users(1400, 1400), offset: 10, before: newShardedUser("x", 1500, false), want: []int{11, 11}},
+		{name: "tied with the user before", page: users(1400, 1300), offset: 10, before: newShardedUser("x", 1400, false), higher: 7, want: []int{8, 12}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			called := false
+			higher := func(_ context.Context, rating int) (int, error) {
+				called = true
+				if rating != c.page[0].Rating {
+					t.Errorf("higher(%d), want the page's first rating %d", rating, c.page[0].Rating)
+				}
+				return c.higher, nil
+			}
+			ranked, err := rankPage(context.Background(), c.page, c.offset, c.before, higher)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]int, len(ranked))
+			for i, user := range ranked {
+				got[i] = user.Rank
+			}
+			if !slices.Equal(got, c.want) {
+				t.Errorf("ranks %v, want %v", got, c.want)
+			}
+			if tied := c.before != nil && c.before.Rating == c.page[0].Rating; called != tied {
+				t.Errorf("higher called: %v, want %v", called, tied)
+			}
+		})
+	}
+
+	failing := func(context.Context, int) (int, error) { return 0, errors.New("shard down") }
+	if _, err := rankPage(context.Background(), users(1400), 1, newShardedUser("x", 1400, false), failing); err == nil {
+		t.Error("rankPage hid a failed count")
+	}
+}
+
+func TestShardedStorePagesMatchMemoryStore(t *testing.T) {
+	_, shards := newTestShards(t, 3)
+	s := newTestShardedStore(t, shards)
+	s.SetTopK(10)
+	m := NewMemoryStore()
+
+	rng := rand.New(rand.NewSource(1))
+	for i := range 60 {
+		username, rating := fmt.Sprintf("user_%02d", i), 1000+rng.Intn(20)*10
+		if i%5 == 0 {
+			s.AddBot(username, rating)
+			m.AddBot(username, rating)
+		} else if err := errors.Join(s.AddUser(username, rating), m.AddUser(username, rating)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Lower some users in the top K, so it is rebuilt
+	for _, username := range []string{"user_01", "user_02", "user_03"} {
+		if _, err := s.RevertRating(&User{Username: username, Rating: 900}, rating(t, s, username)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.RevertRating(&User{Username: username, Rating: 900}, rating(t, m, username)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	want, err := m.GetAllUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, offset := range []int{0, 5, 9, 10, 25, 55, 60} {
+		page, total, err := s.Page(ctx, offset, 7)
+		if err != nil {
+			t.Fatalf("Page(%d): %v", offset, err)
+		}
+		if total != len(want) {
+			t.Errorf("Page(%d) total %d, want %d", offset, total, len(want))
+		}
+		if wantLen := min(7, max(len(want)-offset, 0)); len(page) != wantLen {
+			t.Fatalf("Page(%d) has %d users, want %d", offset, len(page), wantLen)
+		}
+		for i, got := range page {
+			user := want[offset+i]
+			if got.User.Username != user.Username || got.User.Rating != user.Rating || got.User.Bot != user.Bot {
+				t.Errorf("Page(%d)[%d] = %+v, want %+v", offset, i, *got.User, *user)
+			}
+			if wantRank := m.RankForKey(user.Key); got.Rank != wantRank {
+				t.Errorf("Page(%d)[%d] ranked %d, want %d", offset, i, got.Rank, wantRank)
+			}
+		}
+	}
+
+	all, err := s.GetAllUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != len(want) {
+		t.Fatalf("GetAllUsers returned %d users, want %d", len(all), len(want))
+	}
+	for i := range all {
+		if all[i].Username != want[i].Username || all[i].Bot != want[i].Bot {
+			t.Errorf("GetAllUsers[%d] = %+v, want %+v", i, *all[i], *want[i])
+		}
+	}
+	if got, want := s.RankForRating(1100), m.RankForRating(1100); got != want {
+		t.Errorf("RankForRating(1100) = %d, want %d", got, want)
+	}
+	if got, want := s.RatingAt(17), m.RatingAt(17); got != want {
+		t.Errorf("RatingAt(17) = %d, want %d", got, want)
+	}
+	if got, want := s.CountInRange(1050, 1120, true), m.CountInRange(1050, 1120, true); got != want {
+		t.Errorf("CountInRange = %d, want %d", got, want)
+	}
+	if got, want := s.UsersAfter("user_07", 4), m.UsersAfter("user_07", 4); len(got) != len(want) || got[0].Username != want[0].Username || got[3].Username != want[3].Username {
+		t.Errorf("UsersAfter(user_07) = %v, want %v", got, want)
+	}
+}
+
+func TestShardedStoreWrites(t *testing.T) {
+	_, shards := newTestShards(t, 2)
+	s := newTestShardedStore(t, shards)
+
+	// Joining again under a bot's name keeps it a bot, as in MemoryStore
+	s.AddBot("bot_1", 1200)
+	if err := s.AddUser("bot_1", 1250); err != nil {
+		t.Fatal(err)
+	}
+	if user, err := s.GetUser("bot_1"); err != nil || !user.Bot || user.Rating != 1250 {
+		t.Errorf("bot_1 is %+v (%v), want a bot at 1250", user, err)
+	}
+	if err := s.CreateUser("bot_1", 1300); !errors.Is(err, ErrUserExists) {
+		t.Errorf("CreateUser on a taken name = %v, want ErrUserExists", err)
+	}
+
+	// The member cap evicts the lowest user across shards
+	var evicted []string
+	s.SetCapacity(3, func(user *User) { evicted = append(evicted, user.Username) })
+	for username, rating := range map[string]int{"alice": 1500, "bob": 1400} {
+		if err := s.AddUser(username, rating); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.AddUser("carol", 1450); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(evicted, []string{"bot_1"}) || s.GetUserCount() != 3 {
+		t.Errorf("evicted %v with %d users left, want bot_1 and 3", evicted, s.GetUserCount())
+	}
+	if err := s.AddUser("dave", 1000); !errors.Is(err, ErrBelowCutoff) {
+		t.Errorf("AddUser below every member = %v, want ErrBelowCutoff", err)
+	}
+
+	// Merging moves the rating and removes the source
+	if _, removed, err := s.MergeUsers("bob", "alice", 1600); err != nil || removed == nil || removed.Username != "bob" {
+		t.Fatalf("MergeUsers = %v, %v", removed, err)
+	}
+	if got := rating(t, s, "alice"); got != 1600 {
+		t.Errorf("alice at %d after the merge, want 1600", got)
+	}
+	if _, err := s.GetUser("bob"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("bob still there after the merge: %v", err)
+	}
+
+	// Users expire once
+	now := time.Now()
+	if _, err := s.SetExpiry("carol", now.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if expired := s.RemoveExpired(now); len(expired) != 1 || expired[0].Username != "carol" {
+		t.Errorf("RemoveExpired = %v, want carol", expired)
+	}
+	if expired := s.RemoveExpired(now); len(expired) != 0 {
+		t.Errorf("RemoveExpired again = %v, want nobody", expired)
+	}
+	if issues := s.CheckIntegrity(); len(issues) != 0 {
+		t.Errorf("CheckIntegrity = %v, want none", issues)
+	}
+}
+
+func TestShardedStoreRolesRecordsAndMaintenance(t *testing.T) {
+	_, shards := newTestShards(t, 2)
+	a, b := newTestShardedStore(t, shards), newTestShardedStore(t, shards)
+
+	if !a.GrantRole("key:ops", "viewer") || !a.GrantRole("key:ops", "admin") || a.GrantRole("key:ops", "admin") {
+		t.Error("GrantRole reported the wrong grants")
+	}
+	if got := b.Roles("key:ops"); !slices.Equal(got, []string{"admin", "viewer"}) {
+		t.Errorf("b sees roles %v, want [admin viewer]", got)
+	}
+	if !b.RevokeRole("key:ops", "viewer") || b.RevokeRole("key:ops", "viewer") {
+		t.Error("RevokeRole reported the wrong revocations")
+	}
+	if got := a.RoleGrants(); len(got) != 1 || !slices.Equal(got["key:ops"], []string{"admin"}) {
+		t.Errorf("RoleGrants = %v", got)
+	}
+
+	a.SetMaintenance(Maintenance{Mode: "read_only", Message: "migrating"})
+	if got := b.Maintenance(); got.Mode != "read_only" || got.Message != "migrating" {
+		t.Errorf("b sees maintenance %+v", got)
+	}
+
+	for _, s := range []*ShardedStore{a, b, a} {
+		if _, err := s.UpdateRecord("counters", "n", func(current string) (string, error) { return current + "x", nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, ok := b.Record("counters", "n"); !ok || got != "xxx" {
+		t.Errorf("counter is %q, want xxx", got)
+	}
+	if _, err := a.UpdateRecord("counters", "n", func(string) (string, error) { return "", nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.Record("counters", "n"); ok || b.RecordCount("counters") != 0 {
+		t.Error("an emptied record was kept")
+	}
+}
+
+func TestShardedStoreSnapshotRestore(t *testing.T) {
+	_, shards := newTestShards(t, 3)
+	s := newTestShardedStore(t, shards)
+	for i := range 20 {
+		if err := s.AddUser(fmt.Sprintf("user_%02d", i), 1000+i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.GrantRole("key:ops", "admin")
+	if _, err := s.UpdateRecord("privacy", "user_01", func(string) (string, error) { return "{}", nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	snap := s.Snapshot()
+	if snap.Partial || len(snap.Users) != 20 {
+		t.Fatalf("snapshot of %d users (partial: %v), want 20", len(snap.Users), snap.Partial)
+	}
+
+	s.Restore(Snapshot{Users: []User{{Username: "zoe", Rating: 2000, Bot: true}}})
+	if s.GetUserCount() != 1 || len(s.RoleGrants()) != 0 || s.RecordCount("privacy") != 0 {
+		t.Fatalf("restore left %d users, %v and %d records", s.GetUserCount(), s.RoleGrants(), s.RecordCount("privacy"))
+	}
+	if user, err := s.GetUser("zoe"); err != nil || !user.Bot {
+		t.Errorf("zoe restored as %+v (%v)", user, err)
+	}
+	if page, _, err := s.Page(context.Background(), 0, 1); err != nil || len(page) != 1 || page[0].User.Username != "zoe" {
+		t.Errorf("top page after restore = %v (%v), want zoe", page, err)
+	}
+
+	s.Restore(snap)
+	if s.GetUserCount() != 20 || !slices.Equal(s.Roles("key:ops"), []string{"admin"}) || s.RecordCount("privacy") != 1 {
+		t.Errorf("restoring the snapshot gave %d users and roles %v", s.GetUserCount(), s.Roles("key:ops"))
+	}
+	for _, shard := range shards {
+		if keys, _ := shard.Client.Keys(context.Background(), testPrefix+"replacing:*").Result(); len(keys) > 0 {
+			t.Errorf("temporary keys %v left behind on %s", keys, shard.Name)
+		}
+	}
+
+	// A partial snapshot leaves the users alone
+	s.Restore(Snapshot{Partial: true, Roles: map[string][]string{"key:new": {"viewer"}}})
+	if s.GetUserCount() != 20 || len(s.Roles("key:new")) != 1 {
+		t.Errorf("partial restore left %d users and roles %v", s.GetUserCount(), s.RoleGrants())
+	}
+}
+
+func TestShardedStoreRepairsMisplacedUsers(t *testing.T) {
+	_, shards := newTestShards(t, 3)
+	small := newTestShardedStore(t, shards[:2])
+	for i := range 30 {
+		if err := small.AddUser(fmt.Sprintf("user_%02d", i), 1000+i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A third shard takes over some users, who are left where they were
+	s := newTestShardedStore(t, shards)
+	misplaced := 0
+	for _, issue := range s.CheckIntegrity() {
+		if issue.Kind != IssueMisplaced {
+			t.Errorf("unexpected issue %+v", issue)
+		}
+		misplaced++
+	}
+	if misplaced == 0 {
+		t.Fatal("no users reported misplaced after adding a shard")
+	}
+
+	if repaired := s.RepairIntegrity(); len(repaired) != misplaced {
+		t.Errorf("RepairIntegrity fixed %d issues, want %d", len(repaired), misplaced)
+	}
+	if issues := s.CheckIntegrity(); len(issues) != 0 {
+		t.Errorf("issues after repair: %v", issues)
+	}
+	if s.GetUserCount() != 30 {
+		t.Errorf("%d users after repair, want 30", s.GetUserCount())
+	}
+	for i := range 30 {
+		if got := rating(t, s, fmt.Sprintf("user_%02d", i)); got != 1000+i {
+			t.Errorf("user_%02d at %d after repair, want %d", i, got, 1000+i)
+		}
+	}
+	if got := s.RankForRating(1029); got != ranking.FromHigher(0) {
+		t.Errorf("RankForRating(1029) = %d, want 1", got)
+	}
+}
+
+func TestShardedStoreTopKAfterFailedWrites(t *testing.T) {
+	mrs, shards := newTestShards(t, 2)
+	s := newTestShardedStore(t, shards)
+	s.SetTopK(3)
+	home := mrs[s.shardIndex(s.top.key)]
+
+	// Users kept off the top K's shard, so their writes succeed while it fails
+	var away []string
+	for i := 0; len(away) < 4; i++ {
+		if username := fmt.Sprintf("user_%d", i); s.shardFor(username) != s.home {
+			away = append(away, username)
+		}
+	}
+	for i, username := range away {
+		if err := s.AddUser(username, 1000+i*100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	top := func() []string {
+		t.Helper()
+		page, _, err := s.Page(context.Background(), 0, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, len(page))
+		for i, user := range page {
+			names[i] = user.User.Username
+		}
+		return names
+	}
+	if got, want := top(), []string{away[3], away[2], away[1]}; !slices.Equal(got, want) {
+		t.Fatalf("top page %v, want %v", got, want)
+	}
+
+	cases := []struct {
+		name  string
+		write func() error
+		want  []string
+	}{
+		{
+			name:  "raise",
+			write: func() error { _, _, err := s.RaiseRating(away[0], 2000); return err },
+			want:  []string{away[0], away[3], away[2]},
+		},
+		{
+			name:  "remove",
+			write: func() error { return s.RemoveUser(away[3]) },
+			want:  []string{away[0], away[2], away[1]},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// The write lands on the user's shard but can't reach the top K,
+			// nor mark it stale
+			home.SetError("LOADING Redis is loading the dataset in memory")
+			if err := c.write(); err != nil {
+				t.Fatalf("write failed with the top K down: %v", err)
+			}
+			home.SetError("")
+			if !s.top.unmarked.Load() {
+				t.Error("the top K wasn't left to be marked stale")
+			}
+			if got := top(); !slices.Equal(got, c.want) {
+				t.Errorf("top page %v, want %v", got, c.want)
+			}
+			if s.top.unmarked.Load() {
+				t.Error("the top K is still to be marked stale after a read")
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+
+	"backend/internal/ranking"
+)
+
+const (
+	// DefaultShardTopK keeps the first 1000 users, ten 100-user pages, in the
+	// global top K
+	DefaultShardTopK = 1000
+	// topRebuildAttempts bounds how often a rebuild retries when writes keep
+	// changing the top K under it, before the read falls back to the shards
+	topRebuildAttempts = 3
+)
+
+// globalTop is the board's first k users, copied onto one shard so the first
+// pages don't need every shard. Writes offer their user to it; when a user in
+// it falls or leaves, whoever takes their place may be on any shard, so it's
+// marked stale and the next read rebuilds it from the shards.
+//
+// A user's shard and the top K are usually on different shards, so they
+// can't be written in one script. If the top K can't be updated after the
+// shard was written, it is marked stale too; if even that fails, unmarked
+// is set and this process marks it before its next read of the top K.
+type globalTop struct {
+	k        int
+	client   redis.UniversalClient // The shard holding it, chosen by its key
+	key      string                // Sorted set of the first k usernames by negated rating
+	built    string                // Holds k while the sorted set is complete
+	unmarked atomic.Bool           // Set while the top K is stale but couldn't be marked so
+}
+
+// offerTopScript adds or moves a user in the top K and trims it back to k,
+// marking it stale if a user already in it fell.
+// KEYS: top, built. ARGV: score, username, k.
+var offerTopScript = redis.NewScript(`
+local old = redis.call('ZSCORE', KEYS[1], ARGV[2])
+if old and tonumber(ARGV[1]) > tonumber(old) then
+	redis.call('DEL', KEYS[2])
+end
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+local out = redis.call('ZRANGE', KEYS[1], tonumber(ARGV[3]), -1)
+if #out > 0 then
+	redis.call('ZREM', KEYS[1], unpack(out))
+end
+return 1
+`)
+
+// dropTopScript removes a user from the top K, marking it stale if they
+// were in it.
+// KEYS: top, built. ARGV: username.
+var dropTopScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 1 then
+	redis.call('DEL', KEYS[2])
+end
+return 1
+`)
+
+// SetTopK sets how many users the global top K holds; 0 turns it off and
+// serves every page from the shards. A top K built for another size is
+// rebuilt on its next read.
+func (s *ShardedStore) SetTopK(k int) {
+	s.top.k = max(k, 0)
+}
+
+// TopK returns how many users the global top K holds, 0 if it's off
+func (s *ShardedStore) TopK() int {
+	return s.top.k
+}
+
+// offerTop updates the top K after a user's rating was set. The write has
+// already been made on the user's shard, so a failure isn't returned: the
+// top K is marked stale instead.
+func (s *ShardedStore) offerTop(ctx context.Context, username string, rating int) {
+	t := &s.top
+	if t.k == 0 {
+		return
+	}
+	if err := offerTopScript.Run(ctx, t.client, []string{t.key, t.built}, score(rating), username, t.k).Err(); err != nil {
+		log.Printf("Failed to offer %s to the global top K: %v", username, err)
+		s.staleTop(ctx)
+	}
+}
+
+// dropTop updates the top K after a user was removed, marking it stale if
+// that fails
+func (s *ShardedStore) dropTop(ctx context.Context, username string) {
+	t := &s.top
+	if t.k == 0 {
+		return
+	}
+	if err := dropTopScript.Run(ctx, t.client, []string{t.key, t.built}, username).Err(); err != nil {
+		log.Printf("Failed to drop %s from the global top K: %v", username, err)
+		s.staleTop(ctx)
+	}
+}
+
+// staleTop marks the top K stale, so the next read rebuilds it. If Redis
+// refuses, the mark is left to this process's next read of the top K.
+func (s *ShardedStore) staleTop(ctx context.Context) error {
+	t := &s.top
+	if err := t.client.Del(ctx, t.built).Err(); err != nil {
+		t.unmarked.Store(true)
+		return err
+	}
+	t.unmarked.Store(false)
+	return nil
+}
+
+// readTop runs read against the top K in a transaction, rebuilding it first
+// if it's stale. It reports false when the top K is off or couldn't be
+// rebuilt, and the caller should ask the shards instead.
+func (s *ShardedStore) readTop(ctx context.Context, read func(redis.Pipeliner)) (bool, error) {
+	t := &s.top
+	if t.k == 0 {
+		return false, nil
+	}
+	if t.unmarked.Load() {
+		if err := s.staleTop(ctx); err != nil {
+			return false, err
+		}
+	}
+	for attempt := range 2 {
+		var built *redis.StringCmd
+		_, err := t.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			built = pipe.Get(ctx, t.built)
+			read(pipe)
+			return nil
+		})
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return false, err
+		}
+		if built.Val() == strconv.Itoa(t.k) {
+			return true, nil
+		}
+		if attempt == 0 {
+			if ok, err := s.rebuildTop(ctx); err != nil || !ok {
+				return false, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// rebuildTop replaces the top K with the first k users gathered from every
+// shard. A write to the top K while the shards are read aborts the rebuild,
+// which is retried; it reports false if writes kept winning.
+func (s *ShardedStore) rebuildTop(ctx context.Context) (bool, error) {
+	t := &s.top
+	for range topRebuildAttempts {
+		err := t.client.Watch(ctx, func(tx *redis.Tx) error {
+			heads, err := gather(ctx, s, func(ctx context.Context, client redis.UniversalClient) ([]redis.Z, error) {
+				return client.ZRangeWithScores(ctx, s.keys.ratings, 0, int64(t.k-1)).Result()
+			})
+			if err != nil {
+				return err
+			}
+			users := mergeShards(heads, t.k)
+			members := make([]redis.Z, len(users))
+			for i, user := range users {
+				members[i] = redis.Z{Score: score(user.Rating), Member: user.Username}
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Del(ctx, t.key)
+				if len(members) > 0 {
+					pipe.ZAdd(ctx, t.key, members...)
+				}
+				pipe.Set(ctx, t.built, t.k, 0)
+				return nil
+			})
+			return err
+		}, t.key, t.built)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err == nil, err
+		}
+	}
+	return false, nil
+}
+
+// topPage serves a page that lies within the top K from it, reporting false
+// if the page is deeper or the top K can't be used
+func (s *ShardedStore) topPage(ctx context.Context, offset, limit int) ([]RankedUser, bool, error) {
+	t := &s.top
+	if offset+limit > t.k {
+		return nil, false, nil
+	}
+	// Read the user just ahead of the page too, to see if they tie
+	from := max(offset-1, 0)
+	var entries *redis.ZSliceCmd
+	ok, err := s.readTop(ctx, func(pipe redis.Pipeliner) {
+		entries = pipe.ZRangeWithScores(ctx, t.key, int64(from), int64(offset+limit-1))
+	})
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	users := make([]*User, 0, len(entries.Val()))
+	for _, z := range entries.Val() {
+		users = append(users, newShardedUser(z.Member.(string), int(-z.Score), false))
+	}
+	var before *User
+	if from < offset && len(users) > 0 {
+		before, users = users[0], users[1:]
+	}
+	if len(users) == 0 {
+		return []RankedUser{}, true, nil
+	}
+
+	if _, err := s.fill(ctx, users); err != nil {
+		return nil, false, err
+	}
+	ranked, err := rankPage(ctx, users, offset, before, s.topRatedHigher)
+	return ranked, err == nil, err
+}
+
+// topRank ranks a user from the top K, reporting false if they aren't in it
+// or it can't be used
+func (s *ShardedStore) topRank(ctx context.Context, username string) (int, bool, error) {
+	var rating *redis.FloatCmd
+	ok, err := s.readTop(ctx, func(pipe redis.Pipeliner) {
+		rating = pipe.ZScore(ctx, s.top.key, username)
+	})
+	if err != nil || !ok || rating.Err() != nil {
+		return 0, false, err
+	}
+	higher, err := s.topRatedHigher(ctx, int(-rating.Val()))
+	if err != nil {
+		return 0, false, err
+	}
+	return ranking.FromHigher(higher), true, nil
+}
+
+// topRatedHigher counts the users rated strictly higher than rating, which
+// is in the top K: everyone rated higher is in it too
+func (s *ShardedStore) topRatedHigher(ctx context.Context, rating int) (int, error) {
+	n, err := s.top.client.ZCount(ctx, s.top.key, "-inf", below(rating)).Result()
+	return int(n), err
+}
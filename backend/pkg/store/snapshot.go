@@ -0,0 +1,84 @@
+package store
+
+import (
+	"container/heap"
+	"sort"
+
+	"backend/internal/ranking"
+)
+
+// Snapshot is a copy of a store's contents, used to hand the board over to
+// a restarted process
+type Snapshot struct {
+	Users       []User                       `json:"users"`
+	Roles       map[string][]string          `json:"roles,omitempty"`
+	Maintenance Maintenance                  `json:"maintenance"`
+	Records     map[string]map[string]string `json:"records,omitempty"` // Kind -> key -> value
+	// Partial is set when some users couldn't be read, so Restore leaves the
+	// board's users as they are rather than replacing them with too few
+	Partial bool `json:"partial,omitempty"`
+}
+
+// Snapshot copies every user, role grant and record, and the maintenance mode
+func (s *MemoryStore) Snapshot() Snapshot {
+	s.mu.RLock()
+	users := make([]User, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, *user)
+	}
+	s.mu.RUnlock()
+
+	return Snapshot{
+		Users:       users,
+		Roles:       s.RoleGrants(),
+		Maintenance: s.Maintenance(),
+		Records:     s.allRecords(),
+	}
+}
+
+// Restore replaces the store's contents with snap. The member cap and memory
+// budget are not applied and nobody is evicted: the snapshot came from a
+// store that held it.
+func (s *MemoryStore) Restore(snap Snapshot) {
+	s.mu.Lock()
+	s.users = make(map[string]*User, len(snap.Users))
+	s.resetIndex()
+	s.names = make(nameIndex, 0, len(snap.Users))
+	s.folded = make(nameIndex, 0, len(snap.Users))
+	s.expiry = nil
+	s.bytes = 0
+	for _, restored := range snap.Users {
+		user := restored
+		// Keys that don't match the rating, as in snapshots from before sort
+		// keys, are rebuilt, as are recency stamps the store won't rank by
+		if rating, _ := ranking.Decode(user.Key); rating != user.Rating || s.recency == nil {
+			user.Key = ranking.RatingKey(user.Rating)
+		}
+		s.users[user.Username] = &user
+		s.index(&user)
+		s.names = append(s.names, user.Username)
+		s.folded = append(s.folded, foldedName(user.Username))
+		s.bytes += userBytes(user.Username)
+		if !user.ExpiresAt.IsZero() {
+			s.expiry = append(s.expiry, expiryItem{username: user.Username, expiresAt: user.ExpiresAt})
+		}
+	}
+	sort.Strings(s.names)
+	sort.Strings(s.folded)
+	heap.Init(&s.expiry)
+	s.mu.Unlock()
+
+	grants := make(map[string]map[string]struct{}, len(snap.Roles))
+	for principal, roles := range snap.Roles {
+		grants[principal] = make(map[string]struct{}, len(roles))
+		for _, role := range roles {
+			grants[principal][role] = struct{}{}
+		}
+	}
+	s.roles.mu.Lock()
+	s.roles.grants = grants
+	s.roles.mu.Unlock()
+
+	s.SetMaintenance(snap.Maintenance)
+	s.replaceRecords(snap.Records)
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Store is the board a leaderboard service runs on. MemoryStore keeps it in
+// process memory; RedisStore also keeps it in Redis, so it outlives the
+// process and can be taken over by the next one.
+type Store interface {
+	Mirror
+
+	// Writes
+	CreateUser(username string, rating int) error
+	AddBots(bots []NewBot) (written []NewBot, err error)
+	RaiseRating(username string, rating int) (previous int, raised bool, err error)
+	UpdateRatings(usernames []string, ratings map[string]int) []RatingChange
+	MergeUsers(from, into string, rating int) (previous int, removed *User, err error)
+	RestoreUser(user *User) error
+	RevertRating(before *User, written int) (reverted bool, err error)
+	SetExpiry(username string, expiresAt time.Time) (*User, error)
+	RemoveExpired(now time.Time) []*User
+
+	// Reads
+	GetAllUsers(ctx context.Context) ([]*User, error)
+	UsersAfter(cursor string, limit int) []*User
+	SearchRanked(ctx context.Context, q SearchQuery) (matches []SearchMatch, total int, err error)
+	RankForKey(key int64) int
+	RankForRating(rating int) int
+	RatingAt(position int) int
+
+	// Stats
+	GetBotCount(ctx context.Context) (int, error)
+	GetStats(ctx context.Context, excludeBots bool) (total int, minRating, maxRating int, avgRating float64, err error)
+	CountInRange(minRating, maxRating int, excludeBots bool) int
+	RatingCounts(ctx context.Context, excludeBots bool) (map[int]int, error)
+
+	// Limits and ordering
+	SetCapacity(capacity int, onEvict func(*User))
+	Capacity() int
+	SetMemoryBudget(budget MemoryBudget, onEvict func(*User))
+	MemoryUsage() (used, limit int64)
+	SetTiePolicy(policy string, now func() time.Time)
+	TiePolicy() string
+
+	// Roles and maintenance mode
+	GrantRole(principal, role string) bool
+	RevokeRole(principal, role string) bool
+	Roles(principal string) []string
+	RoleGrants() map[string][]string
+	SetMaintenance(m Maintenance)
+	Maintenance() Maintenance
+
+	// Records services keep beside the board
+	UpdateRecord(kind, key string, update func(current string) (string, error)) (string, error)
+	Record(kind, key string) (string, bool)
+	Records(kind string) map[string]string
+	RecordCount(kind string) int
+
+	// Integrity and handover
+	CheckIntegrity() []IntegrityIssue
+	RepairIntegrity() []IntegrityIssue
+	Snapshot() Snapshot
+	Restore(snap Snapshot)
+}
+
+// Pager is a Store that can read a page of the board by rank without
+// reading the users above it, as a ShardedStore does from its shards
+type Pager interface {
+	Page(ctx context.Context, offset, limit int) (page []RankedUser, total int, err error)
+}
+
+// PrefixCounter is a Store that can count the usernames starting with a
+// prefix, ignoring case, without walking the board, as a MemoryStore does
+// from its folded name index
+type PrefixCounter interface {
+	CountPrefix(prefix string) int
+}
@@ -52,7 +52,7 @@ export const searchUser = async (
 
 export const seedData = async (count: number): Promise<void> => {
   try {
-    await apiClient.post("/api/seed", { count });
+    await apiClient.post("/api/seed", { count, wait: true });
   } catch (error) {
     console.error("Error seeding data:", error);
     throw error;